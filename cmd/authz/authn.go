@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+)
+
+// authnFlags selects and configures the providers authenticating the callers of the administration API
+// and of the gRPC services.
+type authnFlags struct {
+	methods       *string
+	apiKeys       *string
+	oidcIssuer    *string
+	oidcAudience  *string
+	oidcUserClaim *string
+	tlsCert       *string
+	tlsKey        *string
+	clientCA      *string
+}
+
+// authenticationFlags registers the authentication flags of the serve command.
+func authenticationFlags(flags *flag.FlagSet) *authnFlags {
+	return &authnFlags{
+		methods:       flags.String("authn", "proxy", "comma separated authentication providers tried in order: proxy, apikey, oidc, mtls"),
+		apiKeys:       flags.String("api-keys", "", "JSON file with the SHA-256 digests of the accepted API keys, for the apikey provider"),
+		oidcIssuer:    flags.String("oidc-issuer", "", "issuer URL of the OpenID Connect provider, for the oidc provider"),
+		oidcAudience:  flags.String("oidc-audience", "", "audience the OpenID Connect tokens must be issued for"),
+		oidcUserClaim: flags.String("oidc-user-claim", "sub", "OpenID Connect token claim naming the user"),
+		tlsCert:       flags.String("tls-cert", "", "certificate file to serve the API over TLS"),
+		tlsKey:        flags.String("tls-key", "", "private key file of the TLS certificate"),
+		clientCA:      flags.String("client-ca", "", "file with the authorities client certificates are verified against, for the mtls provider"),
+	}
+}
+
+// providers creates the authentication providers in the configured order.
+func (flags *authnFlags) providers() ([]authn.Provider, error) {
+	var providers []authn.Provider
+	for _, method := range strings.Split(*flags.methods, ",") {
+		switch strings.TrimSpace(method) {
+		case "proxy":
+			providers = append(providers, authn.NewProxyHeaders(api.UserHeader, api.MFAHeader))
+		case "apikey":
+			if *flags.apiKeys == "" {
+				return nil, errors.New("the apikey provider requires -api-keys")
+			}
+			keys, err := authn.LoadAPIKeys(*flags.apiKeys)
+			if err != nil {
+				return nil, fmt.Errorf("load api keys: %w", err)
+			}
+			providers = append(providers, keys)
+		case "oidc":
+			if *flags.oidcIssuer == "" || *flags.oidcAudience == "" {
+				return nil, errors.New("the oidc provider requires -oidc-issuer and -oidc-audience")
+			}
+			providers = append(providers, authn.NewOIDC(*flags.oidcIssuer, *flags.oidcAudience, http.DefaultClient,
+				authn.WithUserClaim(*flags.oidcUserClaim)))
+		case "mtls":
+			if *flags.clientCA == "" || *flags.tlsCert == "" {
+				return nil, errors.New("the mtls provider requires -client-ca, -tls-cert and -tls-key")
+			}
+			providers = append(providers, authn.NewMTLS())
+		default:
+			return nil, fmt.Errorf("unknown authentication provider %q", method)
+		}
+	}
+	return providers, nil
+}
+
+// tlsConfig returns the TLS configuration of the API server, or nil when it is served over plain HTTP.
+// Client certificates are requested and verified when -client-ca is set, but not required,
+// so other providers keep working for callers without one.
+func (flags *authnFlags) tlsConfig() (*tls.Config, error) {
+	if *flags.tlsCert == "" {
+		if *flags.clientCA != "" {
+			return nil, errors.New("-client-ca requires -tls-cert and -tls-key")
+		}
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(*flags.tlsCert, *flags.tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("load tls certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+
+	if *flags.clientCA != "" {
+		content, err := os.ReadFile(*flags.clientCA)
+		if err != nil {
+			return nil, err
+		}
+		authorities := x509.NewCertPool()
+		if !authorities.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", *flags.clientCA)
+		}
+		config.ClientCAs = authorities
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
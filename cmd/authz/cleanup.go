@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/cleanup"
+)
+
+// runCleanup removes the directory entries and group ownerships of users who belong to no group,
+// printing the removed records. With -dry-run it only prints the records it would remove.
+func runCleanup(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	gracePeriod := flags.Duration("grace", 30*24*time.Hour, "keep the directory entries synchronized during this period")
+	dryRun := flags.Bool("dry-run", false, "only report the orphaned records, leaving them in place")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	pool, err := openPool(ctx, *databaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	var options []cleanup.JobOption
+	if *dryRun {
+		options = append(options, cleanup.WithDryRun())
+	}
+	report, err := cleanup.NewJob(cleanup.NewPostgresStore(pool), *gracePeriod, logger, options...).Clean(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
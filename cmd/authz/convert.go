@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/iam"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+)
+
+// runConvert converts an AWS IAM or GCP IAM policy into a policy document to review and load
+// with import, as YAML when -out names a YAML file. The constructs left out are printed on stderr.
+func runConvert(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := flags.String("from", "", "format of the input: aws for the output of aws iam get-account-authorization-details, gcp for an IAM policy of gcloud get-iam-policy")
+	gcpRoles := flags.String("gcp-roles", "", "comma separated files with the definitions of the GCP roles, as printed by gcloud iam roles describe")
+	out := flags.String("out", "", "output file (defaults to stdout)")
+	strict := flags.Bool("strict", false, "fail when constructs of the input are left out")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: authz convert -from aws|gcp [flags] <file>")
+	}
+
+	conversion, err := convertIAM(*from, flags.Arg(0), *gcpRoles)
+	if err != nil {
+		return err
+	}
+	for _, unsupported := range conversion.Unsupported {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", unsupported.Location, unsupported.Message)
+	}
+
+	var buf bytes.Buffer
+	if err := policyfile.WriteExport(&buf, conversion.Document, policyfile.IsYAML(*out)); err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*out, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *strict && len(conversion.Unsupported) > 0 {
+		return fmt.Errorf("%d constructs left out", len(conversion.Unsupported))
+	}
+	return nil
+}
+
+// convertIAM reads the input file in the given format and converts it.
+func convertIAM(format string, path string, roleFiles string) (*iam.Conversion, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch format {
+	case "aws":
+		details, err := iam.ReadAWS(file)
+		if err != nil {
+			return nil, err
+		}
+		return iam.ConvertAWS(details), nil
+	case "gcp":
+		policy, err := iam.ReadGCP(file)
+		if err != nil {
+			return nil, err
+		}
+		roles, err := loadGCPRoles(roleFiles)
+		if err != nil {
+			return nil, err
+		}
+		return iam.ConvertGCP(policy, roles), nil
+	default:
+		return nil, fmt.Errorf("invalid -from %q, expected aws or gcp", format)
+	}
+}
+
+// loadGCPRoles reads the role definitions of the comma separated files.
+func loadGCPRoles(files string) ([]iam.GCPRole, error) {
+	roles := []iam.GCPRole{}
+	if strings.TrimSpace(files) == "" {
+		return roles, nil
+	}
+
+	for _, path := range strings.Split(files, ",") {
+		file, err := os.Open(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := iam.ReadGCPRoles(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, loaded...)
+	}
+	return roles, nil
+}
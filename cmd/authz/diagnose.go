@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/diagnostics"
+)
+
+// runDiagnose downloads the goroutines, heap profiles, pool statistics and recent errors
+// of a server started with -diagnostics into a single support bundle.
+func runDiagnose(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("diagnose", flag.ContinueOnError)
+	apiURL := flags.String("api", "http://localhost:8080", "base URL of the server")
+	user := flags.String("user", "", "user to authenticate as through the reverse proxy headers")
+	apiKey := flags.String("api-key", os.Getenv("AUTHZ_API_KEY"), "API key to authenticate with (defaults to $AUTHZ_API_KEY)")
+	cpuProfile := flags.Duration("cpu-profile", 0, "also record a CPU profile of this duration, such as 30s")
+	output := flags.String("o", "", "file to write the bundle to (defaults to authz-diagnostics-<time>.tar.gz)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("authz-diagnostics-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	}
+
+	var options []diagnostics.BundlerOption
+	if *user != "" {
+		options = append(options, diagnostics.WithHeader(api.UserHeader, *user))
+	}
+	if *apiKey != "" {
+		options = append(options, diagnostics.WithHeader(authn.APIKeyHeader, *apiKey))
+	}
+	if *cpuProfile > 0 {
+		options = append(options, diagnostics.WithCPUProfile(*cpuProfile))
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := diagnostics.NewBundler(*apiURL, nil, options...).Write(ctx, file); err != nil {
+		file.Close()
+		os.Remove(*output)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "wrote %s\n", *output)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/failover"
+	"github.com/salmarsumi/recipes/internal/authz/standby"
+	"github.com/salmarsumi/recipes/internal/authz/store/postgres"
+	"github.com/salmarsumi/recipes/internal/config"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// runFailover fails the policy store over to its standby database: it promotes the standby,
+// checks it holds the policy of the standby file or of the former primary, re-points the service
+// at it and waits for the service to resume, printing the outcome of every step as JSON.
+// It stops at the first failed step and can be run again once the cause is fixed.
+func runFailover(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("failover", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	promoteTimeout := flags.Duration("promote-timeout", time.Minute, "how long to wait for the standby database to be promoted")
+	standbyFile := flags.String("standby-file", "", "standby file holding the expected policy")
+	referenceURL := flags.String("reference-db", "", "connection string of the former primary holding the expected policy, when reachable")
+	envFile := flags.String("env-file", "", "environment file of the service to re-point at the promoted database")
+	envVar := flags.String("env-var", config.DatabaseURLEnv, "variable of the environment file holding the connection string")
+	serviceURL := flags.String("service-db", "", "connection string the service uses for the promoted database (defaults to -db)")
+	restartCommand := flags.String("restart-command", "", "shell command restarting the service, such as \"systemctl restart authz\"")
+	healthURL := flags.String("health-url", "", "health endpoint of the service to wait for, such as https://authz.internal/api/health")
+	awaitTimeout := flags.Duration("await-timeout", 2*time.Minute, "how long to wait for the service to report ok")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var reference failover.PolicyReader
+	switch {
+	case *standbyFile != "":
+		reference = failover.PolicyReaderFunc(func(ctx context.Context) (*authz.Policy, error) {
+			policy, _, err := standby.Load(*standbyFile)
+			return policy, err
+		})
+	case *referenceURL != "":
+		reference = failover.PolicyReaderFunc(func(ctx context.Context) (*authz.Policy, error) {
+			return loadPolicy(ctx, "", *referenceURL, logger)
+		})
+	default:
+		return errors.New("the expected policy is needed, set -standby-file or -reference-db")
+	}
+
+	pool, err := openPool(ctx, *databaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	promoted := postgres.NewPostgresPolicyManager(pool, logger)
+
+	steps := []failover.Step{
+		failover.Promote(pool, *promoteTimeout),
+		{Name: "check-schema", Run: func(ctx context.Context) (string, error) {
+			return "schema version supported", checkSchema(ctx, promoted)
+		}},
+		failover.VerifyPolicy(promoted, reference),
+	}
+	if *envFile != "" {
+		if *serviceURL == "" {
+			serviceURL = databaseURL
+		}
+		steps = append(steps, failover.Repoint(*envFile, *envVar, *serviceURL))
+	}
+	if *restartCommand != "" {
+		steps = append(steps, failover.Restart(*restartCommand))
+	}
+	if *healthURL != "" {
+		steps = append(steps, failover.AwaitService(http.DefaultClient, clock.System(), *healthURL, 2*time.Second, *awaitTimeout))
+	}
+
+	report, failure := failover.NewRunbook(logger, steps...).Run(ctx)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	return failure
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/codegen"
+)
+
+// runGenerate emits a Go package of typed permission constants.
+// It is meant to be used from a go:generate directive, for example:
+//
+//	//go:generate go run github.com/salmarsumi/recipes/cmd/authz generate -file policy.json -package permissions -out permissions.go
+//
+// With -catalog or -application the constants are generated from a permission catalog
+// instead of the policy, and documented with the catalog descriptions.
+func runGenerate(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to read the permissions from instead of the store")
+	catalogFile := flags.String("catalog", "", "permission catalog file to read the permissions from")
+	application := flags.String("application", "", "application whose registered permission catalog to read the permissions from")
+	packageName := flags.String("package", "permissions", "name of the generated package")
+	out := flags.String("out", "", "output file (defaults to stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	constants, err := loadConstants(ctx, *file, *catalogFile, *application, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	source, err := codegen.DocumentedConstants(*packageName, constants)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(source)
+		return err
+	}
+
+	return os.WriteFile(*out, source, 0o644)
+}
+
+// loadConstants reads the permissions to generate constants for from a catalog file,
+// a registered catalog or the policy, in that order of preference.
+func loadConstants(ctx context.Context, file string, catalogFile string, application string, databaseURL string, logger *slog.Logger) ([]codegen.Constant, error) {
+	var entries []catalog.Entry
+	switch {
+	case catalogFile != "":
+		loaded, err := catalog.Load(catalogFile)
+		if err != nil {
+			return nil, err
+		}
+		entries = loaded.Permissions
+	case application != "":
+		pool, err := openPool(ctx, databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		defer pool.Close()
+
+		registered, err := catalog.NewPostgresStore(pool).Get(ctx, application)
+		if err != nil {
+			return nil, err
+		}
+		entries = registered.Permissions
+	default:
+		policy, err := loadPolicy(ctx, file, databaseURL, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		constants := make([]codegen.Constant, 0, len(policy.Permissions))
+		for _, permission := range policy.Permissions {
+			constants = append(constants, codegen.Constant{Name: permission.Name})
+		}
+		return constants, nil
+	}
+
+	constants := make([]codegen.Constant, 0, len(entries))
+	for _, entry := range entries {
+		constants = append(constants, codegen.Constant{Name: entry.Name, Description: entry.Description})
+	}
+	return constants, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/salmarsumi/recipes/internal/authz/report"
+)
+
+// runGraph renders the policy as a graph of users, groups and permissions in DOT or Mermaid.
+func runGraph(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to render instead of the store")
+	format := flags.String("format", string(report.GraphDOT), "output format, dot or mermaid")
+	users := flags.Bool("users", true, "include the users in the graph")
+	out := flags.String("out", "", "output file (defaults to stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	graphFormat, err := report.ParseGraphFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	policy, err := loadPolicy(ctx, *file, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	var document bytes.Buffer
+	if err := report.WriteGraph(&document, policy, report.GraphOptions{Format: graphFormat, OmitUsers: !*users}); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(document.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, document.Bytes(), 0o644)
+}
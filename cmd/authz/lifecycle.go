@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lifecycle runs the HTTP server and the background jobs of serve until the context is done,
+// which main does on SIGINT or SIGTERM, and then shuts them down gracefully: the readiness probe
+// fails first so load balancers stop routing to the instance, then the server stops accepting
+// connections and drains the in-flight requests, and the background jobs finish their work.
+type lifecycle struct {
+	logger *slog.Logger
+	// How long the readiness probe fails before the server stops accepting connections.
+	shutdownDelay time.Duration
+	// How long the in-flight requests and the background jobs have to complete.
+	drainTimeout time.Duration
+	draining     atomic.Bool
+	jobs         sync.WaitGroup
+}
+
+func newLifecycle(logger *slog.Logger, shutdownDelay time.Duration, drainTimeout time.Duration) *lifecycle {
+	return &lifecycle{logger: logger, shutdownDelay: shutdownDelay, drainTimeout: drainTimeout}
+}
+
+// Go runs a background job, which must return once the context of the lifecycle is done.
+// The shutdown waits for it, so the job can flush what it holds.
+func (l *lifecycle) Go(job func()) {
+	l.jobs.Add(1)
+	go func() {
+		defer l.jobs.Done()
+		job()
+	}()
+}
+
+// Ready wraps the readiness probe, failing it with 503 once the shutdown started.
+func (l *lifecycle) Ready(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.draining.Load() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "draining"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Serve serves HTTP until the context is done or the server fails, over TLS when the server has
+// a TLS configuration. When the context is done it shuts the server down, calls the stop functions,
+// such as the one stopping the gRPC server, and waits for the background jobs, returning once
+// everything stopped or the drain timeout expired.
+func (l *lifecycle) Serve(ctx context.Context, server *http.Server, stops ...func()) error {
+	failed := make(chan error, 1)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		failed <- err
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	l.draining.Store(true)
+	l.logger.Info("shutting down", "shutdown_delay", l.shutdownDelay, "drain_timeout", l.drainTimeout)
+	time.Sleep(l.shutdownDelay)
+
+	drainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.drainTimeout)
+	defer cancel()
+	err := server.Shutdown(drainCtx)
+	if err != nil {
+		l.logger.Warn("in-flight requests did not complete before the drain timeout", "error", err)
+	}
+	if serveErr := <-failed; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	for _, stop := range stops {
+		stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		l.logger.Info("shut down")
+	case <-drainCtx.Done():
+		l.logger.Warn("background jobs did not complete before the drain timeout")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		// the requests cut short were logged, the shutdown itself went as planned
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+)
+
+// runLint checks the policy against the permission catalogs, printing a warning for every
+// granted permission no catalog declares, for every risk level differing from its catalog and
+// for every permission a folder inherits without opting in.
+// Catalogs are read from the given files or, when none is set, from the store.
+func runLint(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to lint instead of the store")
+	catalogFiles := flags.String("catalog", "", "comma separated permission catalog files to lint against instead of the registered catalogs")
+	strict := flags.Bool("strict", false, "fail when there are warnings")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	policy, err := loadPolicy(ctx, *file, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	catalogs, err := loadCatalogs(ctx, *catalogFiles, *databaseURL)
+	if err != nil {
+		return err
+	}
+
+	warnings := catalog.Lint(policy, catalogs)
+	for _, warning := range warnings {
+		fmt.Printf("%s: %s\n", warning.Permission, warning.Message)
+	}
+
+	if *strict && len(warnings) > 0 {
+		return fmt.Errorf("%d lint warnings", len(warnings))
+	}
+	return nil
+}
+
+// loadCatalogs reads the catalogs from the comma separated files, or every registered catalog when no file is set.
+func loadCatalogs(ctx context.Context, files string, databaseURL string) ([]catalog.Catalog, error) {
+	if strings.TrimSpace(files) == "" {
+		pool, err := openPool(ctx, databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		defer pool.Close()
+
+		return catalog.NewPostgresStore(pool).List(ctx)
+	}
+
+	catalogs := []catalog.Catalog{}
+	for _, path := range strings.Split(files, ",") {
+		loaded, err := catalog.Load(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, *loaded)
+	}
+	return catalogs, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/loadtest"
+	"github.com/salmarsumi/recipes/internal/authz/seed"
+)
+
+// runLoadTest loads a generated policy into the store, as the seed command does, and drives
+// concurrent evaluation and mutation traffic against the store, or against a running server
+// with -api, then reports the latency percentiles. It adds groups, permissions and users to
+// the store, named after the start time unless -prefix is set, so it is meant to run against
+// a disposable database.
+func runLoadTest(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	apiURL := flags.String("api", "", "base URL of the server to send the traffic to, such as http://localhost:8080; the store is used directly when empty")
+	service := flags.String("service", "loadtest", "identity the traffic is sent as, granted authz.evaluate and authz.write")
+	cacheSize := flags.Int("cache-size", 0, "decisions cached by the client with -api, 0 disables the cache")
+	seedOptions := seedFlags(flags)
+	var options loadtest.Options
+	flags.IntVar(&options.Workers, "workers", 8, "number of concurrent workers")
+	flags.DurationVar(&options.Duration, "duration", 30*time.Second, "how long the traffic is sent")
+	flags.Float64Var(&options.MutationRatio, "mutation-ratio", 0.05, "share of requests changing group memberships")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if options.MutationRatio < 0 || options.MutationRatio > 1 {
+		return errors.New("mutation ratio must be between 0 and 1")
+	}
+	if seedOptions.Prefix == "" {
+		seedOptions.Prefix = fmt.Sprintf("loadtest-%d-", time.Now().Unix())
+	}
+	plan, err := seed.Generate(*seedOptions)
+	if err != nil {
+		return err
+	}
+	options.Seed = seedOptions.Seed
+
+	manager, closeStore, err := openPolicyManager(ctx, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	logger.Info("loading synthetic policy", "groups", len(plan.Groups), "users", len(plan.Users), "permissions", len(plan.Permissions))
+	dataset, err := seed.Load(ctx, manager, plan)
+	if err != nil {
+		return err
+	}
+
+	var target loadtest.Target = loadtest.NewStoreTarget(manager)
+	if *apiURL != "" {
+		target = loadtest.NewAPITarget(*apiURL, nil, *service, *cacheSize)
+	}
+
+	logger.Info("sending traffic", "workers", options.Workers, "duration", options.Duration)
+	report := loadtest.Run(ctx, target, dataset, options)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "OPERATION\tREQUESTS\tERRORS\tP50\tP90\tP99\tMAX\tREQ/S")
+	for _, stats := range report.Stats {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%.1f\n", stats.Operation, stats.Requests, stats.Errors,
+			stats.P50, stats.P90, stats.P99, stats.Max, stats.Throughput)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if report.FirstError != "" {
+		fmt.Fprintf(os.Stdout, "\nfirst error: %s\n", report.FirstError)
+	}
+	return nil
+}
@@ -1,6 +1,120 @@
 package main
 
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/salmarsumi/recipes/internal/authz/logging"
+	"github.com/salmarsumi/recipes/internal/config"
+)
+
+// command is a single subcommand of the authz binary.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, logger *slog.Logger, args []string) error
+}
+
+// commands lists every subcommand supported by the authz binary.
+var commands = []command{
+	{name: "cleanup", summary: "remove the directory entries and group ownerships of users in no group", run: runCleanup},
+	{name: "convert", summary: "convert an AWS IAM or GCP IAM policy into a document for import", run: runConvert},
+	{name: "diagnose", summary: "download the diagnostics of a running server into a support bundle", run: runDiagnose},
+	{name: "export", summary: "export the canonical policy document or check it against a snapshot", run: runExport},
+	{name: "failover", summary: "promote the standby database and re-point the service at it", run: runFailover},
+	{name: "generate", summary: "generate Go constants for the policy permissions", run: runGenerate},
+	{name: "graph", summary: "render the policy as a DOT or Mermaid graph", run: runGraph},
+	{name: "import", summary: "replace the policy of the store with a document written by export -full", run: runImport},
+	{name: "lint", summary: "check the policy grants against the permission catalogs", run: runLint},
+	{name: "loadtest", summary: "measure the latency of the store or server under synthetic traffic", run: runLoadTest},
+	{name: "matrix", summary: "export the matrix of users by effective permissions for audits", run: runMatrix},
+	{name: "seed", summary: "generate a reproducible policy for demos and benchmarks", run: runSeed},
+	{name: "serve", summary: "serve the administration API and web console", run: runServe},
+	{name: "sync-reports", summary: "list the reports of sync connector runs", run: runSyncReports},
+	{name: "status", summary: "check the policy store is reachable and its schema is usable", run: runStatus},
+	{name: "tenants", summary: "list the tenants sharing the database or provision new ones", run: runTenants},
+	{name: "test", summary: "check the policy against a file of expected user access", run: runTest},
+	{name: "tuples", summary: "export the policy as Zanzibar relation tuples or a SpiceDB validation file", run: runTuples},
+	{name: "verify", summary: "check the policy store for integrity problems its schema cannot prevent", run: runVerify},
+	{name: "versions", summary: "validate the group versions against the audit history and repair their drift", run: runVersions},
+}
+
+// logLevels holds the log level of every component, the configured log level unless changed with serve -log-levels.
+var logLevels = logging.NewLevels()
+
+// settings holds the configuration loaded from $AUTHZ_CONFIG_FILE and the environment,
+// which the subcommands use as the defaults of their flags.
+var settings = config.Default()
+
 // main is the entry point for the authorization application.
 func main() {
+	logger := slog.New(logLevels.Handler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	loaded, err := config.Load(os.Getenv(config.FileEnv), os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "authz: load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	settings = *loaded
+	// the loaded configuration is validated, so the level parses
+	level, _ := settings.Level()
+	_ = logLevels.Set(logging.Config{Default: level})
+
+	// the context is done on SIGINT or SIGTERM, so the commands stop gracefully, and a second
+	// signal terminates the process at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+
+		err := cmd.run(ctx, logger, os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "authz %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "authz: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+// parseFlags parses the subcommand arguments. The flag package already reports
+// parse failures, so every failure is returned as flag.ErrHelp to avoid printing it twice.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	return nil
+}
 
+// usage prints the list of available subcommands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: authz <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
 }
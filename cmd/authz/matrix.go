@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/report"
+)
+
+// runMatrix writes the matrix of users by effective permissions as CSV or XLSX, for audits.
+func runMatrix(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to report on instead of the store")
+	groups := flags.String("groups", "", "comma separated groups whose members to include (defaults to every user)")
+	permissions := flags.String("permissions", "", "comma separated permissions to include (defaults to every permission)")
+	format := flags.String("format", "csv", "output format, csv or xlsx")
+	out := flags.String("out", "", "output file (defaults to stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "xlsx" {
+		return fmt.Errorf("unknown format %q, expected csv or xlsx", *format)
+	}
+
+	policy, err := loadPolicy(ctx, *file, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	matrix, err := report.BuildMatrix(policy, report.MatrixFilter{Groups: splitList(*groups), Permissions: splitList(*permissions)})
+	if err != nil {
+		return err
+	}
+
+	var document bytes.Buffer
+	if *format == "xlsx" {
+		err = matrix.WriteXLSX(&document)
+	} else {
+		err = matrix.WriteCSV(&document)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(document.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, document.Bytes(), 0o644)
+}
+
+// splitList splits a comma separated flag value, dropping blanks.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/cache"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/cleanup"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/console"
+	"github.com/salmarsumi/recipes/internal/authz/decisionlog"
+	"github.com/salmarsumi/recipes/internal/authz/decorate"
+	"github.com/salmarsumi/recipes/internal/authz/diagnostics"
+	"github.com/salmarsumi/recipes/internal/authz/directory"
+	"github.com/salmarsumi/recipes/internal/authz/distribution"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/hooks"
+	"github.com/salmarsumi/recipes/internal/authz/logging"
+	"github.com/salmarsumi/recipes/internal/authz/relationship"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/salmarsumi/recipes/internal/authz/standby"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/store/postgres"
+	"github.com/salmarsumi/recipes/internal/authz/syncreport"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+	"github.com/salmarsumi/recipes/internal/authz/watch"
+	"github.com/salmarsumi/recipes/internal/authz/webguard"
+	"github.com/salmarsumi/recipes/internal/config"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// runServe starts the administration API, the embedded web console and, with -grpc-addr, the gRPC
+// services, along with the background jobs its flags enable. The policy and every other record of
+// the tenant setting are served from the schema of that tenant, one tenant per process, see the tenant
+// package. On SIGINT or SIGTERM the server drains as set by -shutdown-delay and -drain-timeout before
+// the database connections are closed.
+func runServe(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	addr := flags.String("addr", settings.ListenAddr, "address to listen on (defaults to $"+config.ListenAddrEnv+" or listen_addr of the config file)")
+	grpcAddr := flags.String("grpc-addr", "", "address to serve the gRPC services on, with health checking, reflection and the Watch service streaming membership and grant changes, authenticated with the -authn providers and over TLS like the API, disabled when empty")
+	guardrailsFile := flags.String("guardrails", "", "JSON file with the guardrails to enforce on changes")
+	sourcePrecedence := flags.String("source-precedence", "", "membership sources from highest to lowest precedence, such as ldap,scim,manual")
+	standbyFile := flags.String("standby-file", "", "file keeping a copy of the policy, served in degraded read-only mode when the database is unreachable at startup")
+	standbyInterval := flags.Duration("standby-interval", time.Minute, "interval between writes of the standby file")
+	publishURL := flags.String("publish", "", "key-value store to publish every policy change to for remote evaluators, such as consul://localhost:8500/authz/policy or etcd://localhost:2379/authz/policy")
+	publishInterval := flags.Duration("publish-interval", 10*time.Second, "interval between checks for policy changes to publish")
+	syncReportRetention := flags.Duration("sync-report-retention", 90*24*time.Hour, "how long sync reports are kept, 0 keeps them forever")
+	changeLogRetention := flags.Duration("change-log-retention", 30*24*time.Hour, "how long the membership, grant and rename changes streamed by the Watch service are kept, 0 keeps them forever")
+	orphanCleanup := flags.Duration("orphan-cleanup", 0, "interval between removals of the directory entries and group ownerships of users in no group, 0 disables it")
+	orphanGrace := flags.Duration("orphan-grace", 30*24*time.Hour, "keep the directory entries synchronized during this period")
+	orphanDryRun := flags.Bool("orphan-dry-run", false, "only log the orphaned records the cleanup would remove")
+	approvalRouting := flags.String("approval-routing", "", "comma separated approvers of access requests, such as owners,manager for the group owners or the manager of the requester recorded in the directory; any approver decides when empty")
+	catalogCascade := flags.String("catalog-cascade", "orphan", "what happens to the grants of the permissions removed from a catalog: block, revoke or orphan, comma separated with per application overrides such as orphan,recipes=block")
+	approvalEscalation := flags.Duration("approval-escalation", 48*time.Hour, "how long routed access requests wait before any approver may decide, 0 never escalates")
+	enableDiagnostics := flags.Bool("diagnostics", false, "expose runtime diagnostics and pprof profiles under /api/debug/ to the holders of authz.diagnose")
+	policyCacheTTL := flags.Duration("policy-cache-ttl", 5*time.Second, "how long the cached policy is served before checking the store for changes the database did not notify, 0 disables the cache")
+	logLevelsFile := flags.String("log-levels", "", "JSON file with the log level of each component (store, api, cache, sync), reloaded when it changes")
+	authentication := authenticationFlags(flags)
+	trustedOrigins := flags.String("trusted-origins", "", "comma separated origins allowed to change the policy from another site, such as https://admin.example.org")
+	trustForwardedHost := flags.Bool("trust-forwarded-host", false, "check request origins against the X-Forwarded-Host header set by the reverse proxy")
+	decisionTTLs := api.DefaultDecisionTTLs
+	flags.DurationVar(&decisionTTLs.Allow, "decision-ttl", decisionTTLs.Allow, "how long callers may cache granted decisions")
+	flags.DurationVar(&decisionTTLs.HighRisk, "decision-high-risk-ttl", decisionTTLs.HighRisk, "how long callers may cache granted decisions on high risk permissions")
+	flags.DurationVar(&decisionTTLs.Deny, "decision-deny-ttl", decisionTTLs.Deny, "how long callers may cache denied decisions")
+	decisionLog := flags.String("decision-log", "", "where to record the decisions made: postgres, a ClickHouse table such as clickhouse://localhost:8123/authz.decision_logs or the path of a JSON lines file, disabled when empty")
+	decisionLogRetention := flags.Duration("decision-log-retention", 90*24*time.Hour, "how long decisions recorded in ClickHouse are kept, 0 keeps them forever")
+	decisionLogSample := flags.Float64("decision-log-sample-allows", 100, "percentage of the allowed decisions recorded, denials are always recorded")
+	decisionLogAggregate := flags.Bool("decision-log-aggregate", false, "record the identical decisions of each minute once with their count")
+	auditLog := flags.String("audit-log", "", "where to also record the audit events: postgres, which also serves the audit history to the holders of authz.audit, or the path of a JSON lines file, disabled when empty")
+	auditSIEM := flags.String("audit-siem", "", "syslog collector of a SIEM to send the audit events to as CEF or LEEF lines, such as syslog+tls://siem.example.org:6514?format=leef, disabled when empty")
+	auditSIEMCA := flags.String("audit-siem-ca", "", "PEM file with the authorities trusted to sign the certificate of the syslog collector, the system ones when empty")
+	requestLinkKey := flags.String("request-link-key", "", "file with the secret key of at least 32 bytes signing the self-service request links, disabled when empty")
+	requestLinkURL := flags.String("request-link-url", "", "frontend page opening the self-service request links, such as https://access.example.org/request; links point at the API when empty")
+	evaluationMode := flags.String("evaluation-mode", string(authz.ModeDefaultDeny), "how decisions treat the permissions users are not granted: default-deny, default-allow or shadow")
+	shutdownDelay := flags.Duration("shutdown-delay", 0, "how long /readyz fails before the server stops accepting connections on shutdown, leaving load balancers time to notice; /healthz keeps succeeding")
+	slowQuery := flags.Duration("slow-query", 500*time.Millisecond, "SQL statements taking at least this long are logged as warnings and the others at debug level, with their arguments redacted, 0 logs none as warnings")
+	drainTimeout := flags.Duration("drain-timeout", 30*time.Second, "how long the in-flight requests and the background jobs have to complete on shutdown")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	mode, err := authz.ParseEvaluationMode(*evaluationMode)
+	if err != nil {
+		return err
+	}
+	precedence, err := store.ParseSourcePrecedence(*sourcePrecedence)
+	if err != nil {
+		return err
+	}
+	routes, err := directory.ParseRoutes(*approvalRouting)
+	if err != nil {
+		return err
+	}
+	cascades, err := catalog.ParseCascades(*catalogCascade)
+	if err != nil {
+		return err
+	}
+	providers, err := authentication.providers()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := authentication.tlsConfig()
+	if err != nil {
+		return err
+	}
+	var errorLog *diagnostics.ErrorLog
+	if *enableDiagnostics {
+		errorLog = diagnostics.NewErrorLog(logger.Handler(), 100)
+		logger = slog.New(errorLog)
+	}
+	service := newLifecycle(logger, *shutdownDelay, *drainTimeout)
+	if *logLevelsFile != "" {
+		levelFile, err := logging.NewLevelFile(*logLevelsFile, logLevels, logger)
+		if err != nil {
+			return err
+		}
+		service.Go(func() { levelFile.Watch(ctx, 5*time.Second) })
+	}
+	storeLogger := logging.For(logger, logging.ComponentStore)
+	apiLogger := logging.For(logger, logging.ComponentAPI)
+	cacheLogger := logging.For(logger, logging.ComponentCache)
+	syncLogger := logging.For(logger, logging.ComponentSync)
+	protection := crossOriginProtection(apiLogger, *trustedOrigins, *trustForwardedHost)
+
+	queryTracer := postgres.NewQueryTracer(storeLogger, *slowQuery)
+	pool, err := openPool(ctx, *databaseURL, queryTracer)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	var backend distribution.Backend
+	if *publishURL != "" {
+		if backend, err = distribution.Open(*publishURL, http.DefaultClient); err != nil {
+			return err
+		}
+	}
+
+	if *standbyFile != "" {
+		if err := pool.Ping(ctx); err != nil {
+			logger.Warn("database is unavailable, serving the standby file in degraded read-only mode", "path", *standbyFile, "error", err)
+			return serveStandby(ctx, service, apiLogger, clock.System(), *addr, *grpcAddr, *standbyFile, tlsConfig, protection, providers)
+		}
+	}
+
+	var rules []guardrail.Rule
+	if *guardrailsFile != "" {
+		if rules, err = guardrail.Load(*guardrailsFile); err != nil {
+			return err
+		}
+	}
+
+	// the reviewer only submits requests, while approved grants go through the guardrails
+	// like any other change, so each needs its own view of the workflow
+	approvalStore := approval.NewPostgresStore(pool)
+	reviews := approval.NewWorkflow(approvalStore, nil)
+	auditSink, err := openAuditSink(logger, *auditLog, pool, *auditSIEM, *auditSIEMCA)
+	if err != nil {
+		return err
+	}
+	actor := func(ctx context.Context) string {
+		identity, _ := api.IdentityFromContext(ctx)
+		return identity.User
+	}
+	reviewer := guardrail.NewApprovalReviewer(reviews, actor)
+	postgresManager := postgres.NewPostgresPolicyManager(pool, storeLogger, postgres.WithSourcePrecedence(precedence))
+	if err := checkSchema(ctx, postgresManager); err != nil {
+		return err
+	}
+	metrics := hooks.NewMetrics()
+	decorators := []decorate.Option{
+		decorate.WithGuardrails(rules, reviewer, storeLogger, guardrail.WithNotifier(guardrail.NewAuditNotifier(auditSink, actor))),
+		decorate.WithHooks(metrics, hooks.NewAudit(auditSink, actor, storeLogger)),
+	}
+	// changes committed by any instance are notified, so the cache and the publisher act on them at once
+	changes := postgres.NewListener(pool, storeLogger, postgres.WithTenantChannel(tenant.ID(settings.Tenant)))
+	var reader consistency.Reader = consistency.NewDirect(postgresManager)
+	var versions api.PolicyVersions
+	if *policyCacheTTL > 0 {
+		provider := cache.NewCachedPolicyProvider(postgresManager, cacheLogger, cache.WithTTL(*policyCacheTTL))
+		reader, versions = provider, provider
+		service.Go(func() { provider.Run(ctx, *policyCacheTTL) })
+		changes.OnChange(func(ctx context.Context, revision int64) {
+			if err := provider.Refresh(ctx); err != nil {
+				cacheLogger.Warn("failed to refresh the cached policy", "revision", revision, "error", err)
+			}
+		})
+		decorators = append(decorators, decorate.WithCache(provider))
+	}
+	manager := decorate.Chain(postgresManager, decorators...)
+	directoryStore := directory.NewPostgresStore(pool)
+	var workflowOptions []approval.WorkflowOption
+	if len(routes) > 0 {
+		workflowOptions = append(workflowOptions, approval.WithRouter(directory.NewRouter(directoryStore, routes)))
+	}
+	approvals := approval.NewWorkflow(approvalStore, manager, workflowOptions...)
+	syncReports := syncreport.NewPostgresStore(pool)
+	catalogs := catalog.NewCascadingStore(catalog.NewPostgresStore(pool), manager, cascades, auditSink, apiLogger)
+	selfService := selfservice.NewPostgresStore(pool)
+	options := []api.Option{api.WithApprovals(approvals), api.WithSyncReports(syncReports), api.WithCatalogs(catalogs),
+		api.WithSelfService(selfService), api.WithDirectory(directoryStore), api.WithFolders(folder.NewPostgresStore(pool)),
+		api.WithRelationships(relationship.NewPostgresStore(pool)), api.WithConsistency(reader),
+		api.WithDecisionTTLs(decisionTTLs), api.WithPolicyVersions(versions),
+		api.WithEvaluationMode(mode), api.WithAuthenticators(providers...), api.WithAuditSink(auditSink)}
+	if rules != nil {
+		options = append(options, api.WithGuardrails(rules))
+	}
+	if *auditLog == "postgres" {
+		options = append(options, api.WithAuditLog(audit.NewPostgresSink(pool)))
+	}
+	if *requestLinkKey != "" {
+		key, err := os.ReadFile(*requestLinkKey)
+		if err != nil {
+			return err
+		}
+		signer, err := selfservice.NewSigner(bytes.TrimSpace(key))
+		if err != nil {
+			return err
+		}
+		options = append(options, api.WithRequestLinks(selfService, signer, *requestLinkURL))
+	}
+	if *enableDiagnostics {
+		collected := diagnostics.New(errorLog)
+		collected.Register("pool", diagnostics.Pool(pool))
+		collected.Register("store", metrics.Collect)
+		collected.Register("sql", queryTracer.Collect)
+		options = append(options, api.WithDiagnostics(collected))
+	}
+	if *decisionLog != "" {
+		sink, err := decisionSink(ctx, *decisionLog, pool)
+		if err != nil {
+			return err
+		}
+		if pruner, ok := sink.(decisionlog.Pruner); ok && *decisionLogRetention > 0 {
+			retention := decisionlog.NewRetention(pruner, *decisionLogRetention, syncLogger)
+			service.Go(func() { retention.Run(ctx, time.Hour) })
+		}
+		batcher := decisionlog.NewBatcher(sink, apiLogger)
+		var recorder decisionlog.Recorder = batcher
+		if *decisionLogAggregate {
+			// the batcher outlives the aggregator, so the decisions counted when stopping are written
+			batcherCtx, stopBatcher := context.WithCancel(context.WithoutCancel(ctx))
+			service.Go(func() { batcher.Run(batcherCtx) })
+			aggregator := decisionlog.NewAggregator(batcher, apiLogger)
+			service.Go(func() {
+				aggregator.Run(ctx)
+				stopBatcher()
+			})
+			recorder = aggregator
+		} else {
+			service.Go(func() { batcher.Run(ctx) })
+		}
+		if *decisionLogSample < 100 {
+			recorder = decisionlog.NewSampler(recorder, *decisionLogSample)
+		}
+		options = append(options, api.WithDecisionLog(recorder))
+	}
+	apiServer := api.NewServer(manager, apiLogger, options...)
+
+	if *standbyFile != "" {
+		exporter := standby.NewExporter(postgresManager, *standbyFile, *standbyInterval, cacheLogger)
+		service.Go(func() { exporter.Run(ctx) })
+	}
+	if len(routes) > 0 && *approvalEscalation > 0 {
+		escalator := approval.NewEscalator(approvals, *approvalEscalation, apiLogger)
+		service.Go(func() { escalator.Run(ctx, time.Hour) })
+	}
+	if *syncReportRetention > 0 {
+		retention := syncreport.NewRetention(syncReports, *syncReportRetention, syncLogger)
+		service.Go(func() { retention.Run(ctx, time.Hour) })
+	}
+	changeLog := watch.NewPostgresStore(pool)
+	if *changeLogRetention > 0 {
+		retention := watch.NewRetention(changeLog, *changeLogRetention, syncLogger)
+		service.Go(func() { retention.Run(ctx, time.Hour) })
+	}
+	if *orphanCleanup > 0 {
+		var cleanupOptions []cleanup.JobOption
+		if *orphanDryRun {
+			cleanupOptions = append(cleanupOptions, cleanup.WithDryRun())
+		}
+		job := cleanup.NewJob(cleanup.NewPostgresStore(pool), *orphanGrace, syncLogger, cleanupOptions...)
+		service.Go(func() { job.Run(ctx, *orphanCleanup) })
+	}
+	if backend != nil {
+		publisher := distribution.NewPublisher(postgresManager, backend, *publishInterval, syncLogger)
+		changes.OnChange(func(ctx context.Context, revision int64) { publisher.Trigger() })
+		service.Go(func() { publisher.Run(ctx) })
+	}
+	notifier := watch.NewNotifier()
+	if *grpcAddr != "" {
+		changes.OnChange(func(ctx context.Context, revision int64) { notifier.Notify() })
+	}
+	if *policyCacheTTL > 0 || backend != nil || *grpcAddr != "" {
+		service.Go(func() { changes.Run(ctx) })
+	}
+	stopGRPC, err := listenGRPC(ctx, service, apiLogger, *grpcAddr, manager, reader, changeLog, notifier, tlsConfig, providers)
+	if err != nil {
+		return err
+	}
+
+	return listen(ctx, service, apiLogger, *addr, apiServer, tlsConfig, protection, stopGRPC)
+}
+
+// serveStandby serves the policy stored in the standby file without approvals or guardrails,
+// as every change is refused. The age of the file is logged as measured by the clock.
+func serveStandby(ctx context.Context, service *lifecycle, logger *slog.Logger, clock clock.Clock, addr string, grpcAddr string, standbyFile string,
+	tlsConfig *tls.Config, protection *webguard.CrossOrigin, providers []authn.Provider) error {
+	policy, writtenAt, err := standby.Load(standbyFile)
+	if err != nil {
+		return fmt.Errorf("load standby file: %w", err)
+	}
+
+	logger.Warn("serving standby policy", "written_at", writtenAt, "age", clock.Now().Sub(writtenAt).Round(time.Second))
+	manager := standby.NewManager(policy)
+	stopGRPC, err := listenGRPC(ctx, service, logger, grpcAddr, manager, nil, nil, nil, tlsConfig, providers)
+	if err != nil {
+		return err
+	}
+	return listen(ctx, service, logger, addr, api.NewServer(manager, logger, api.WithAuthenticators(providers...)), tlsConfig, protection, stopGRPC)
+}
+
+// listenGRPC serves gRPC health checking, reflection and the policy evaluation and management
+// services on the given address in the background, reporting the health of the policy store.
+// Changes return the consistency tokens of the reader, when there is one, and evaluations honor them.
+// The Watch service is served too when there is a change log, woken up by the notifier.
+// Callers are authenticated by the providers of the administration API, over TLS when it is configured.
+// The returned function reports the server as not serving and stops it once the pending calls
+// completed. It does nothing when the address is empty.
+func listenGRPC(ctx context.Context, service *lifecycle, logger *slog.Logger, addr string, manager grpcapi.PolicyManager,
+	reader consistency.Reader, changes watch.Store, notifier *watch.Notifier, tlsConfig *tls.Config, providers []authn.Provider) (func(), error) {
+	if addr == "" {
+		return func() {}, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	options := grpcapi.Authentication(providers...)
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpcapi.NewServer(manager, logger, options...)
+	server.RegisterPolicy(manager, reader)
+	if changes != nil {
+		server.RegisterWatch(manager, changes, notifier, 30*time.Second)
+	}
+	service.Go(func() { server.Watch(ctx, 10*time.Second) })
+	go func() {
+		logger.Info("listening for gRPC", "addr", addr, "tls", tlsConfig != nil)
+		if err := server.Serve(listener); err != nil {
+			logger.Error("gRPC server failed", "error", err)
+		}
+	}()
+	return server.Stop, nil
+}
+
+// decisionSink opens the sink recording decisions: the decision_logs table for "postgres", the ClickHouse
+// table of a clickhouse URL, created if missing, otherwise the file at the given path.
+func decisionSink(ctx context.Context, target string, pool *pgxpool.Pool) (decisionlog.Sink, error) {
+	if target == "postgres" {
+		return decisionlog.NewPostgresSink(pool), nil
+	}
+	if strings.HasPrefix(target, "clickhouse") && strings.Contains(target, "://") {
+		sink, err := decisionlog.OpenClickHouse(target, http.DefaultClient)
+		if err != nil {
+			return nil, err
+		}
+		if err := sink.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		return sink, nil
+	}
+	return decisionlog.OpenFile(target)
+}
+
+// openAuditSink opens the sink of the audit events: the logger, the audit_events table for "postgres" or
+// otherwise the file at the given path when a target is given, and the syslog collector of a SIEM when
+// a syslog URL is given, trusting the authorities of the PEM file if any.
+func openAuditSink(logger *slog.Logger, target string, pool *pgxpool.Pool, siemURL string, caFile string) (audit.Sink, error) {
+	sinks := audit.MultiSink{audit.NewLogSink(logger)}
+	switch target {
+	case "":
+	case "postgres":
+		sinks = append(sinks, audit.NewPostgresSink(pool))
+	default:
+		file, err := audit.OpenFile(target)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, file)
+	}
+	if siemURL == "" {
+		return sinks, nil
+	}
+
+	var options []audit.SyslogOption
+	if caFile != "" {
+		content, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read siem authorities: %w", err)
+		}
+		authorities := x509.NewCertPool()
+		if !authorities.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		options = append(options, audit.WithTLSConfig(&tls.Config{RootCAs: authorities, MinVersion: tls.VersionTLS12}))
+	}
+	siem, err := audit.OpenSyslog(siemURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	return append(sinks, siem), nil
+}
+
+// crossOriginProtection creates the protection against cross-origin changes from the serve flags.
+func crossOriginProtection(logger *slog.Logger, trustedOrigins string, trustForwardedHost bool) *webguard.CrossOrigin {
+	var options []webguard.Option
+	for _, origin := range strings.Split(trustedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			options = append(options, webguard.WithTrustedOrigins(origin))
+		}
+	}
+	if trustForwardedHost {
+		options = append(options, webguard.WithForwardedHost())
+	}
+	return webguard.NewCrossOrigin(logger, options...)
+}
+
+// listen serves the API, the web console and the health probes on the given address until
+// the context is done, over TLS when a configuration is given, then drains the server and calls
+// the stop functions. The API and the console refuse cross-origin changes, and the console pages
+// are served with hardened headers and cookies.
+func listen(ctx context.Context, service *lifecycle, logger *slog.Logger, addr string, apiServer *api.Server,
+	tlsConfig *tls.Config, protection *webguard.CrossOrigin, stops ...func()) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux.Handle("GET /readyz", service.Ready(apiServer.HealthHandler()))
+	mux.Handle("/api/", protection.Handler(apiServer))
+	consoleHandler := apiServer.RequirePermission(api.PermissionRead, console.Handler())
+	mux.Handle("/console/", webguard.SecureHeaders(webguard.SecureCookies(protection.Handler(http.StripPrefix("/console", consoleHandler)))))
+
+	server := &http.Server{Addr: addr, Handler: mux, TLSConfig: tlsConfig}
+	logger.Info("listening", "addr", addr, "tls", tlsConfig != nil)
+	return service.Serve(ctx, server, stops...)
+}
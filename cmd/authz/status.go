@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// runStatus checks that the policy store is reachable and its schema is usable.
+func runStatus(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	manager, closeStore, err := openPolicyManager(ctx, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	health, err := manager.Health(ctx)
+	if err != nil {
+		return fmt.Errorf("policy store is unhealthy: %w", err)
+	}
+
+	fmt.Fprintf(os.Stdout, "policy store: ok (%s)\n", health.Latency)
+	fmt.Fprintf(os.Stdout, "schema version: %d\n", health.SchemaVersion)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/store/postgres"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+	"github.com/salmarsumi/recipes/internal/config"
+)
+
+// databaseFlag registers the -db flag shared by every subcommand talking to the store.
+func databaseFlag(flags *flag.FlagSet) *string {
+	return flags.String("db", settings.DatabaseURL, "PostgreSQL connection string (defaults to $"+config.DatabaseURLEnv+" or database_url of the config file)")
+}
+
+// openPool connects to the database using the given connection string, with the configured pool sizes,
+// to the schema of the configured tenant, the only one the process uses. The statements run on the pool are traced by the given tracer, if any.
+func openPool(ctx context.Context, databaseURL string, tracer ...pgx.QueryTracer) (*pgxpool.Pool, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database connection string is empty")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.MaxConns = settings.MaxConns
+	poolConfig.MinConns = settings.MinConns
+	if settings.Tenant != "" {
+		poolConfig = postgres.TenantPoolConfig(poolConfig, tenant.ID(settings.Tenant))
+	}
+	if len(tracer) > 0 {
+		poolConfig.ConnConfig.Tracer = tracer[0]
+	}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// openPolicyManager connects to the database and creates a PostgresPolicyManager,
+// refusing to use a database whose schema version is not supported.
+// The returned function closes the underlying connection pool.
+func openPolicyManager(ctx context.Context, databaseURL string, logger *slog.Logger) (*postgres.PostgresPolicyManager, func(), error) {
+	pool, err := openPool(ctx, databaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manager := postgres.NewPostgresPolicyManager(pool, logger)
+	if err := checkSchema(ctx, manager); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	return manager, pool.Close, nil
+}
+
+// checkSchema verifies the database schema version is supported by this binary.
+func checkSchema(ctx context.Context, manager *postgres.PostgresPolicyManager) error {
+	if _, err := manager.CheckSchema(ctx); err != nil {
+		var versionErr *store.SchemaVersionError
+		if errors.As(err, &versionErr) {
+			return versionErr
+		}
+		return fmt.Errorf("check database schema: %w", err)
+	}
+	return nil
+}
+
+// loadPolicy reads the policy from the given file, or from the store when no file is set.
+func loadPolicy(ctx context.Context, file string, databaseURL string, logger *slog.Logger) (*authz.Policy, error) {
+	if file != "" {
+		return policyfile.Load(file)
+	}
+
+	manager, closeStore, err := openPolicyManager(ctx, databaseURL, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore()
+
+	return manager.ReadPolicy(ctx)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/syncreport"
+)
+
+// runSyncReports lists the recent sync connector runs, or prints a single report with -id.
+func runSyncReports(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("sync-reports", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	connector := flags.String("connector", "", "only list the reports of this connector")
+	limit := flags.Int("limit", 20, "maximum number of reports to list")
+	id := flags.Int("id", 0, "print the report with this id, including its entries")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	pool, err := openPool(ctx, *databaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	reports := syncreport.NewPostgresStore(pool)
+
+	if *id != 0 {
+		report, err := reports.Get(ctx, *id)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	list, err := reports.List(ctx, syncreport.Filter{Connector: *connector, Limit: *limit})
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCONNECTOR\tFINISHED\tADDED\tREMOVED\tSKIPPED\tERRORS\tSUBMITTED BY")
+	for _, report := range list {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", report.ID, report.Connector, report.FinishedAt.Format(time.RFC3339),
+			report.Summary.Added, report.Summary.Removed, report.Summary.Skipped, report.Summary.Errors, report.SubmittedBy)
+	}
+	return writer.Flush()
+}
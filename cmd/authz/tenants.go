@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz/store/postgres"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+)
+
+// runTenants lists the tenants provisioned in the database, one per line. With -provision it instead
+// creates the schemas of the tenants given as arguments and runs the schema script in them, upgrading
+// the schemas of the tenants already provisioned. The other subcommands use the schema of the tenant
+// set by $AUTHZ_TENANT or the tenant setting of the config file.
+func runTenants(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("tenants", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	provision := flags.String("provision", "", "schema script to provision the tenants given as arguments with, such as sql/authz_postgres.sql")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *databaseURL == "" {
+		return errors.New("database connection string is empty")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(*databaseURL)
+	if err != nil {
+		return err
+	}
+	poolConfig.MaxConns = settings.MaxConns
+	poolConfig.MinConns = settings.MinConns
+	tenants := postgres.NewTenants(poolConfig, logger)
+	defer tenants.Close()
+
+	if *provision == "" {
+		ids, err := tenants.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	}
+
+	if flags.NArg() == 0 {
+		return errors.New("no tenant to provision")
+	}
+	ids := make([]tenant.ID, flags.NArg())
+	for i, arg := range flags.Args() {
+		if ids[i], err = tenant.Parse(arg); err != nil {
+			return err
+		}
+	}
+	script, err := os.ReadFile(*provision)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := tenants.Provision(ctx, id, string(script)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/salmarsumi/recipes/internal/authz/policytest"
+)
+
+// runTest checks the policy against a YAML assertions file and fails when an expectation is broken,
+// so it can gate policy changes in CI.
+func runTest(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to test instead of the store")
+	assertions := flags.String("assertions", "", "YAML file with the expected user access")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *assertions == "" {
+		return errors.New("assertions file is required")
+	}
+
+	suite, err := policytest.Load(*assertions)
+	if err != nil {
+		return err
+	}
+
+	policy, err := loadPolicy(ctx, *file, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	failures, err := policytest.Run(policy, suite)
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stdout, "FAIL %s\n", failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d expectations failed", len(failures))
+	}
+
+	fmt.Fprintf(os.Stdout, "ok %d tests\n", len(suite.Tests))
+	return nil
+}
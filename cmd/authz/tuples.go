@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/salmarsumi/recipes/internal/authz/report"
+)
+
+// runTuples exports the policy as Zanzibar relation tuples or, with -schema, as a SpiceDB
+// validation file with its schema, to bootstrap relationship-based access control systems.
+func runTuples(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("tuples", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	file := flags.String("file", "", "policy file to export instead of the store")
+	schema := flags.Bool("schema", false, "write a SpiceDB validation file holding the schema and the tuples")
+	out := flags.String("out", "", "output file (defaults to stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	policy, err := loadPolicy(ctx, *file, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+
+	var document bytes.Buffer
+	if err := report.WriteTuples(&document, policy, *schema); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(document.Bytes())
+		return err
+	}
+	return os.WriteFile(*out, document.Bytes(), 0o644)
+}
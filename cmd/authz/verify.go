@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// runVerify checks the policy store for the integrity problems its schema cannot prevent and
+// prints the report as JSON. It fails when a problem is found, so monitoring can alert on it.
+func runVerify(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	manager, closeStore, err := openPolicyManager(ctx, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	report, err := manager.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("%d integrity problems", len(report.Problems))
+	}
+	return nil
+}
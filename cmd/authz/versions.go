@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// runVersions validates the group versions against the audit history, finding the groups changed
+// outside the application, such as through manual SQL, and the invalid versions, and prints the report
+// as JSON. With -repair it repairs their versions, otherwise it fails when a drift is found.
+func runVersions(ctx context.Context, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("versions", flag.ContinueOnError)
+	databaseURL := databaseFlag(flags)
+	since := flags.Duration("since", 30*24*time.Hour, "validate the changes made during this period")
+	window := flags.Duration("window", time.Minute, "the time an audit event may be recorded after the change it records")
+	repair := flags.Bool("repair", false, "reset the invalid versions and bump the versions of the groups changed outside the application")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	manager, closeStore, err := openPolicyManager(ctx, *databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	report, err := manager.ValidateGroupVersions(ctx, time.Now().Add(-*since), *window)
+	if err != nil {
+		return err
+	}
+	if report.AuditEvents == 0 && !report.OK() {
+		logger.Warn("no audit event recorded during the period, every change is reported as unaudited; is serve running with -audit-log postgres?")
+	}
+	if *repair {
+		if err := manager.RepairGroupVersions(ctx, report); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if !report.OK() && !*repair {
+		return fmt.Errorf("%d groups with drifted versions, run with -repair to repair them", len(report.Drifts))
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// requestablePermission is a permission granted by joining a self-service group.
+type requestablePermission struct {
+	ID   int             `json:"id"`
+	Name string          `json:"name"`
+	Risk authz.RiskLevel `json:"risk"`
+	// The self-service groups granting the permission, in GET /api/access/permissions.
+	Groups []int `json:"groups,omitempty"`
+}
+
+// requestableGroup is a group end users may request to join.
+type requestableGroup struct {
+	GroupID     int                     `json:"group_id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Permissions []requestablePermission `json:"permissions"`
+	// Whether the caller already is a member of the group.
+	Member bool `json:"member"`
+}
+
+// accessRequest is the body of POST /api/access/requests. Either the group to join,
+// a permission granted by a self-service group or the token of a request link is requested.
+type accessRequest struct {
+	GroupID      int    `json:"group_id"`
+	PermissionID int    `json:"permission_id"`
+	Link         string `json:"link"`
+	// Defaults to the justification pre-filled by the request link.
+	Justification string `json:"justification"`
+}
+
+// setSelfServiceRequest is the body of PUT /api/groups/{id}/self-service.
+type setSelfServiceRequest struct {
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description"`
+}
+
+// listRequestableGroups returns the self-service groups with the permissions they grant.
+func (server *Server) listRequestableGroups(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	groups, err := server.requestableGroups(r.Context(), identity.User)
+	if err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// listRequestablePermissions returns the permissions granted by self-service groups,
+// with the groups granting each of them.
+func (server *Server) listRequestablePermissions(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	groups, err := server.requestableGroups(r.Context(), identity.User)
+	if err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+
+	permissions := []requestablePermission{}
+	for _, group := range groups {
+		for _, permission := range group.Permissions {
+			index := slices.IndexFunc(permissions, func(listed requestablePermission) bool { return listed.ID == permission.ID })
+			if index < 0 {
+				permissions = append(permissions, permission)
+				index = len(permissions) - 1
+			}
+			permissions[index].Groups = append(permissions[index].Groups, group.GroupID)
+		}
+	}
+	slices.SortFunc(permissions, func(a, b requestablePermission) int { return strings.Compare(a.Name, b.Name) })
+
+	writeJSON(w, http.StatusOK, permissions)
+}
+
+// submitAccessRequest submits a request of the caller to join a self-service group.
+// A requested permission is resolved to the first self-service group granting it,
+// and a request link to its group, pre-filling the justification.
+// The request waits in the approval workflow and the membership is applied once approved.
+func (server *Server) submitAccessRequest(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	var request accessRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if request.Link != "" {
+		if request.GroupID != 0 || request.PermissionID != 0 {
+			writeError(w, http.StatusBadRequest, "a request link cannot be combined with a group or a permission")
+			return
+		}
+		if !server.requireRequestLinks(w) {
+			return
+		}
+		link, err := server.openRequestLink(r.Context(), request.Link)
+		if err != nil {
+			server.writeRequestLinkError(w, err)
+			return
+		}
+		request.GroupID = link.GroupID
+		if strings.TrimSpace(request.Justification) == "" {
+			request.Justification = link.Justification
+		}
+	}
+	if (request.GroupID == 0) == (request.PermissionID == 0) {
+		writeError(w, http.StatusBadRequest, "either a group or a permission must be requested")
+		return
+	}
+	if strings.TrimSpace(request.Justification) == "" {
+		writeError(w, http.StatusBadRequest, "justification is required")
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	groups, err := server.requestableGroups(r.Context(), identity.User)
+	if err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+
+	index := slices.IndexFunc(groups, func(group requestableGroup) bool {
+		if request.GroupID != 0 {
+			return group.GroupID == request.GroupID
+		}
+		return slices.ContainsFunc(group.Permissions, func(permission requestablePermission) bool {
+			return permission.ID == request.PermissionID
+		})
+	})
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "no self-service group grants the requested access")
+		return
+	}
+	group := groups[index]
+	if group.Member {
+		writeError(w, http.StatusConflict, "already a member of the group")
+		return
+	}
+
+	requests, err := server.approvals.ListRequestedBy(r.Context(), identity.User)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+	if slices.ContainsFunc(requests, func(existing approval.Request) bool {
+		return existing.Kind == approval.KindGroupMembership && existing.GroupID == group.GroupID && existing.Status == approval.StatusPending
+	}) {
+		writeError(w, http.StatusConflict, "a request to join the group is already pending")
+		return
+	}
+
+	pending, err := server.approvals.Submit(r.Context(), approval.Request{
+		Kind:          approval.KindGroupMembership,
+		GroupID:       group.GroupID,
+		UserID:        identity.User,
+		RequestedBy:   identity.User,
+		Justification: request.Justification,
+	})
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, pending)
+}
+
+// listAccessRequests returns the requests submitted by the caller, most recent first.
+func (server *Server) listAccessRequests(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	requests, err := server.approvals.ListRequestedBy(r.Context(), identity.User)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// getAccessRequest returns a request submitted by the caller. The requests of other
+// users are reported as missing.
+func (server *Server) getAccessRequest(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid approval request id")
+		return
+	}
+
+	request, err := server.approvals.Get(r.Context(), id)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+	identity, _ := IdentityFromContext(r.Context())
+	if request.RequestedBy != identity.User {
+		server.writeApprovalError(w, approval.ErrNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+// setSelfService offers a group for self-service, or stops offering it.
+func (server *Server) setSelfService(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSelfService(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	var request setSelfServiceRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if request.Enabled {
+		identity, _ := IdentityFromContext(r.Context())
+		err = server.selfService.Enable(r.Context(), selfservice.Group{
+			GroupID:     groupId,
+			Description: strings.TrimSpace(request.Description),
+			EnabledBy:   identity.User,
+			EnabledAt:   server.clock.Now().UTC(),
+		})
+	} else {
+		err = server.selfService.Disable(r.Context(), groupId)
+	}
+	if err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestableGroups returns the groups offered for self-service, ordered by group id, with
+// the permissions they grant directly and whether the user already is a member.
+// Groups offered for self-service but missing from the policy are left out.
+func (server *Server) requestableGroups(ctx context.Context, user string) ([]requestableGroup, error) {
+	offered, err := server.selfService.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.manager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupInfos, err := server.manager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissionInfos, err := server.manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames := make(map[int]string, len(groupInfos))
+	for _, info := range groupInfos {
+		groupNames[info.ID] = info.Name
+	}
+	grantedTo := shared.NewMultiMap[string, string](len(policy.Permissions))
+	for _, permission := range policy.Permissions {
+		grantedTo.Add(permission.Name, permission.Groups...)
+	}
+	members := shared.NewMultiMap[string, string](len(policy.Groups))
+	for _, group := range policy.Groups {
+		members.Add(group.Name, group.Users...)
+	}
+
+	groups := []requestableGroup{}
+	for _, offer := range offered {
+		name, ok := groupNames[offer.GroupID]
+		if !ok {
+			continue
+		}
+
+		group := requestableGroup{GroupID: offer.GroupID, Name: name, Description: offer.Description, Permissions: []requestablePermission{}}
+		for _, info := range permissionInfos {
+			if slices.Contains(grantedTo.Get(info.Name), name) {
+				group.Permissions = append(group.Permissions, requestablePermission{ID: info.ID, Name: info.Name, Risk: info.Risk})
+			}
+		}
+		group.Member = slices.Contains(members.Get(name), user)
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (server *Server) requireSelfService(w http.ResponseWriter) bool {
+	if server.selfService == nil || server.approvals == nil {
+		writeError(w, http.StatusNotFound, "self-service access requests are not configured")
+		return false
+	}
+	return true
+}
+
+// writeSelfServiceError maps a self-service store error to the matching HTTP status code.
+func (server *Server) writeSelfServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, selfservice.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	server.writeApprovalError(w, err)
+}
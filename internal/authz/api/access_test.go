@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockSelfServiceStore is a mock implementation of the selfservice.Store interface
+type mockSelfServiceStore struct {
+	mock.Mock
+}
+
+func (m *mockSelfServiceStore) Enable(ctx context.Context, group selfservice.Group) error {
+	return m.Called(ctx, group).Error(0)
+}
+func (m *mockSelfServiceStore) Disable(ctx context.Context, groupId int) error {
+	return m.Called(ctx, groupId).Error(0)
+}
+func (m *mockSelfServiceStore) Get(ctx context.Context, groupId int) (*selfservice.Group, error) {
+	args := m.Called(ctx, groupId)
+	group, _ := args.Get(0).(*selfservice.Group)
+	return group, args.Error(1)
+}
+func (m *mockSelfServiceStore) List(ctx context.Context) ([]selfservice.Group, error) {
+	args := m.Called(ctx)
+	groups, _ := args.Get(0).([]selfservice.Group)
+	return groups, args.Error(1)
+}
+func (m *mockSelfServiceStore) CreateLink(ctx context.Context, link selfservice.Link) error {
+	return m.Called(ctx, link).Error(0)
+}
+func (m *mockSelfServiceStore) GetLink(ctx context.Context, id string) (*selfservice.Link, error) {
+	args := m.Called(ctx, id)
+	link, _ := args.Get(0).(*selfservice.Link)
+	return link, args.Error(1)
+}
+func (m *mockSelfServiceStore) ListLinks(ctx context.Context, groupId int) ([]selfservice.Link, error) {
+	args := m.Called(ctx, groupId)
+	links, _ := args.Get(0).([]selfservice.Link)
+	return links, args.Error(1)
+}
+func (m *mockSelfServiceStore) RevokeLink(ctx context.Context, id string, revokedBy string, revokedAt time.Time) error {
+	return m.Called(ctx, id, revokedBy, revokedAt).Error(0)
+}
+
+// setupSelfServiceServer serves the risk policy with the group "cooks" (id 10) offered for self-service.
+func setupSelfServiceServer() (*MockPolicyManager, *mockApprovalStore, *mockSelfServiceStore, *Server) {
+	manager := new(MockPolicyManager)
+	approvals := new(mockApprovalStore)
+	groups := new(mockSelfServiceStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WithApprovals(approval.NewWorkflow(approvals, manager)), WithSelfService(groups))
+
+	setupRiskPolicy(manager)
+	groups.On("List", mock.Anything).Return([]selfservice.Group{{GroupID: 10, Description: "Recipe authors"}}, nil).Maybe()
+	return manager, approvals, groups, server
+}
+
+func TestListRequestableGroups(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodGet, "/api/access/groups", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"group_id":10,"name":"cooks","description":"Recipe authors","member":false,"permissions":[
+			{"id":1,"name":"recipes.read","risk":"low"},
+			{"id":2,"name":"recipes.delete","risk":"high"}
+		]}]`, response.Body.String())
+	})
+
+	t.Run("member", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodGet, "/api/access/groups", "alice", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"member":true`)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodGet, "/api/access/groups", "", "")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		_, server := setupMockManagerAndServer()
+
+		response := serve(server, http.MethodGet, "/api/access/groups", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestListRequestablePermissions(t *testing.T) {
+	_, _, _, server := setupSelfServiceServer()
+
+	response := serve(server, http.MethodGet, "/api/access/permissions", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[
+		{"id":2,"name":"recipes.delete","risk":"high","groups":[10]},
+		{"id":1,"name":"recipes.read","risk":"low","groups":[10]}
+	]`, response.Body.String())
+}
+
+func TestSubmitAccessRequest(t *testing.T) {
+	isMembership := mock.MatchedBy(func(request approval.Request) bool {
+		return request.Kind == approval.KindGroupMembership && request.GroupID == 10 &&
+			request.UserID == "viewer" && request.RequestedBy == "viewer" && request.Justification == "writing recipes"
+	})
+
+	t.Run("group", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+		approvals.On("ListRequestedBy", mock.Anything, "viewer").Return([]approval.Request{}, nil)
+		approvals.On("Create", mock.Anything, isMembership).Return(7, nil)
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"group_id":10,"justification":"writing recipes"}`)
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		assert.Contains(t, response.Body.String(), `"id":7`)
+		assert.Contains(t, response.Body.String(), `"status":"pending"`)
+
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("permission", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+		approvals.On("ListRequestedBy", mock.Anything, "viewer").Return([]approval.Request{}, nil)
+		approvals.On("Create", mock.Anything, isMembership).Return(7, nil)
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"permission_id":1,"justification":"writing recipes"}`)
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("already pending", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+		approvals.On("ListRequestedBy", mock.Anything, "viewer").Return([]approval.Request{
+			{ID: 3, Kind: approval.KindGroupMembership, GroupID: 10, UserID: "viewer", RequestedBy: "viewer", Status: approval.StatusPending},
+		}, nil)
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"group_id":10,"justification":"writing recipes"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		approvals.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("already a member", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "alice", `{"group_id":10,"justification":"writing recipes"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		approvals.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not offered", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"permission_id":3,"justification":"purging recipes"}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("missing justification", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"group_id":10}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("group and permission", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"group_id":10,"permission_id":1,"justification":"writing recipes"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestGetAccessRequest(t *testing.T) {
+	request := &approval.Request{ID: 7, Kind: approval.KindGroupMembership, GroupID: 10, UserID: "viewer", RequestedBy: "viewer", Status: approval.StatusPending}
+
+	t.Run("own request", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+		approvals.On("Get", mock.Anything, 7).Return(request, nil)
+
+		response := serve(server, http.MethodGet, "/api/access/requests/7", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"id":7`)
+	})
+
+	t.Run("request of another user", func(t *testing.T) {
+		_, approvals, _, server := setupSelfServiceServer()
+		approvals.On("Get", mock.Anything, 7).Return(request, nil)
+
+		response := serve(server, http.MethodGet, "/api/access/requests/7", "alice", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestListAccessRequests(t *testing.T) {
+	_, approvals, _, server := setupSelfServiceServer()
+	approvals.On("ListRequestedBy", mock.Anything, "viewer").Return([]approval.Request{
+		{ID: 7, Kind: approval.KindGroupMembership, GroupID: 10, UserID: "viewer", RequestedBy: "viewer", Status: approval.StatusApproved},
+	}, nil)
+
+	response := serve(server, http.MethodGet, "/api/access/requests", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"status":"approved"`)
+}
+
+func TestSetSelfService(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("enable", func(t *testing.T) {
+		_, _, groups, server := setupSelfServiceServer()
+		server.clock = NewFakeClock(now)
+		groups.On("Enable", mock.Anything, selfservice.Group{GroupID: 10, Description: "Recipe authors", EnabledBy: "admin", EnabledAt: now}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/self-service", "admin", `{"enabled":true,"description":" Recipe authors "}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("disable not offered", func(t *testing.T) {
+		_, _, groups, server := setupSelfServiceServer()
+		groups.On("Disable", mock.Anything, 11).Return(selfservice.ErrNotFound)
+
+		response := serve(server, http.MethodPut, "/api/groups/11/self-service", "admin", `{"enabled":false}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		_, _, groups, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPut, "/api/groups/10/self-service", "viewer", `{"enabled":true}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		groups.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything)
+	})
+}
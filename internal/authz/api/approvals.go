@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+func (server *Server) listApprovals(w http.ResponseWriter, r *http.Request) {
+	if !server.requireApprovals(w) {
+		return
+	}
+
+	status := approval.Status(r.URL.Query().Get("status"))
+	if status == "" {
+		status = approval.StatusPending
+	}
+
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+
+	requests, err := server.approvals.List(r.Context(), status)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	requests, next, err := paging.Page(requests, page, func(request approval.Request) paging.Key { return paging.Key{request.ID} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, requests, next)
+}
+
+func (server *Server) getApproval(w http.ResponseWriter, r *http.Request) {
+	if !server.requireApprovals(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid approval request id")
+		return
+	}
+
+	request, err := server.approvals.Get(r.Context(), id)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+// approve applies a pending change. Only high risk changes and access requests go through
+// approvals, so the approver must have completed multi-factor authentication.
+func (server *Server) approve(w http.ResponseWriter, r *http.Request) {
+	server.decide(w, r, func(identity Identity, id int) (*approval.Request, error) {
+		return server.approvals.Approve(r.Context(), id, identity.User)
+	})
+}
+
+func (server *Server) reject(w http.ResponseWriter, r *http.Request) {
+	server.decide(w, r, func(identity Identity, id int) (*approval.Request, error) {
+		return server.approvals.Reject(r.Context(), id, identity.User)
+	})
+}
+
+func (server *Server) decide(w http.ResponseWriter, r *http.Request, decision func(Identity, int) (*approval.Request, error)) {
+	if !server.requireApprovals(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid approval request id")
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	if !server.requireMFA(w, r, identity, "decide_approval", "multi-factor authentication is required to decide on approval requests") {
+		return
+	}
+	if !server.mayDecide(w, r, identity, id) {
+		return
+	}
+
+	request, err := decision(identity, id)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
+}
+
+// listAssignedApprovals returns the pending requests routed to the caller, such as the
+// access requests of the users they manage. Routed approvers need no meta-policy permission.
+func (server *Server) listAssignedApprovals(w http.ResponseWriter, r *http.Request) {
+	if !server.requireApprovals(w) {
+		return
+	}
+
+	requests, err := server.approvals.List(r.Context(), approval.StatusPending)
+	if err != nil {
+		server.writeApprovalError(w, err)
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	assigned := slices.DeleteFunc(requests, func(request approval.Request) bool {
+		return !slices.Contains(request.Approvers, identity.User)
+	})
+	writeJSON(w, http.StatusOK, assigned)
+}
+
+// mayDecide reports whether the caller may decide on the request, or writes the error response.
+// Holders of the approve permission may decide, and so may the approvers the request was
+// routed to, such as the manager of the requester, without holding it.
+func (server *Server) mayDecide(w http.ResponseWriter, r *http.Request, identity Identity, id int) bool {
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return false
+	}
+	if session, err := policy.Session(identity.User); err == nil && session.HasPermission(PermissionApprove) {
+		return true
+	}
+
+	request, err := server.approvals.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, approval.ErrNotFound) {
+		server.writeApprovalError(w, err)
+		return false
+	}
+	if request != nil && slices.Contains(request.Approvers, identity.User) {
+		return true
+	}
+
+	server.logger.Warn("access to the administration API denied", "user", identity.User, "permission", PermissionApprove, "path", r.URL.Path)
+	writeError(w, http.StatusForbidden, "permission denied")
+	return false
+}
+
+func (server *Server) requireApprovals(w http.ResponseWriter) bool {
+	if server.approvals == nil {
+		writeError(w, http.StatusNotFound, "approval workflow is not configured")
+		return false
+	}
+	return true
+}
+
+// writeApprovalError maps an approval workflow error to the matching HTTP status code.
+func (server *Server) writeApprovalError(w http.ResponseWriter, err error) {
+	var storeErr *store.PolicyStoreError
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, approval.ErrNotPending):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, approval.ErrSelfApproval), errors.Is(err, approval.ErrNotAssigned):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.As(err, &storeErr):
+		server.writeStoreError(w, err)
+	default:
+		server.logger.Error("approval workflow failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
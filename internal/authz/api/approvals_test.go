@@ -0,0 +1,307 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockApprovalStore is a mock implementation of the approval.Store interface
+type mockApprovalStore struct {
+	mock.Mock
+}
+
+func (m *mockApprovalStore) Create(ctx context.Context, request approval.Request) (int, error) {
+	args := m.Called(ctx, request)
+	return args.Int(0), args.Error(1)
+}
+func (m *mockApprovalStore) Get(ctx context.Context, id int) (*approval.Request, error) {
+	args := m.Called(ctx, id)
+	request, _ := args.Get(0).(*approval.Request)
+	return request, args.Error(1)
+}
+func (m *mockApprovalStore) List(ctx context.Context, status approval.Status) ([]approval.Request, error) {
+	args := m.Called(ctx, status)
+	requests, _ := args.Get(0).([]approval.Request)
+	return requests, args.Error(1)
+}
+func (m *mockApprovalStore) ListRequestedBy(ctx context.Context, requester string) ([]approval.Request, error) {
+	args := m.Called(ctx, requester)
+	requests, _ := args.Get(0).([]approval.Request)
+	return requests, args.Error(1)
+}
+func (m *mockApprovalStore) Escalate(ctx context.Context, id int, escalatedAt time.Time) error {
+	return m.Called(ctx, id, escalatedAt).Error(0)
+}
+func (m *mockApprovalStore) Decide(ctx context.Context, id int, status approval.Status, decidedBy string, decidedAt time.Time) error {
+	return m.Called(ctx, id, status, decidedBy, decidedAt).Error(0)
+}
+
+var mfa = map[string]string{MFAHeader: "true"}
+
+func setupApprovalServer() (*MockPolicyManager, *mockApprovalStore, *Server) {
+	manager := new(MockPolicyManager)
+	approvals := new(mockApprovalStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithApprovals(approval.NewWorkflow(approvals, manager)))
+	return manager, approvals, server
+}
+
+// riskPolicy extends the meta-policy with a group "cooks" (id 10) holding the low risk
+// permission "recipes.read" (id 1) and the high risk permission "recipes.delete" (id 2),
+// while "recipes.purge" (id 3) is high risk and not granted.
+func setupRiskPolicy(manager *MockPolicyManager) {
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups, *authz.NewGroup("cooks", []string{"alice"}))
+	policy.Permissions = append(policy.Permissions,
+		authz.Permission{Name: "authz.approve", Groups: []string{"admins"}},
+		authz.Permission{Name: "recipes.read", Groups: []string{"cooks"}},
+		authz.Permission{Name: "recipes.delete", Groups: []string{"cooks"}, Risk: authz.RiskHigh},
+		authz.Permission{Name: "recipes.purge", Groups: []string{}, Risk: authz.RiskHigh})
+
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{{ID: 10, Name: "cooks", Version: 1}}, nil).Maybe()
+	manager.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{
+		{ID: 1, Name: "recipes.read", Version: 1, Risk: authz.RiskLow},
+		{ID: 2, Name: "recipes.delete", Version: 1, Risk: authz.RiskHigh},
+		{ID: 3, Name: "recipes.purge", Version: 1, Risk: authz.RiskHigh},
+	}, nil).Maybe()
+}
+
+func TestUpdateGroupPermissions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, _, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		manager.On("UpdateGroupPermissions", mock.Anything, 10, []int{1, 2}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/permissions", "admin", `{"permissions":[1,2]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		manager, _, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/groups/11/permissions", "admin", `{"permissions":[1]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		manager, _, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		manager.On("UpdateGroupPermissions", mock.Anything, 10, []int{1}).Return(store.NewConcurrencyError())
+
+		response := serve(server, http.MethodPut, "/api/groups/10/permissions", "admin", `{"permissions":[1]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("high risk grant requires mfa", func(t *testing.T) {
+		manager, _, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/permissions", "admin", `{"permissions":[1,3]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		manager.AssertNotCalled(t, "UpdateGroupPermissions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("high risk grant requires approval workflow", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serveWithHeaders(server, http.MethodPut, "/api/groups/10/permissions", "admin", `{"permissions":[1,3]}`, mfa)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		manager.AssertNotCalled(t, "UpdateGroupPermissions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("high risk grant waits for approval", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		manager.On("UpdateGroupPermissions", mock.Anything, 10, []int{1, 2}).Return(nil)
+		approvals.On("Create", mock.Anything, mock.MatchedBy(func(request approval.Request) bool {
+			return request.Kind == approval.KindPermissionGrant && request.GroupID == 10 && request.PermissionID == 3 &&
+				request.RequestedBy == "admin" && request.Justification == "cleanup" && request.Status == approval.StatusPending
+		})).Return(5, nil)
+
+		response := serveWithHeaders(server, http.MethodPut, "/api/groups/10/permissions", "admin", `{"permissions":[1,2,3],"justification":"cleanup"}`, mfa)
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		assert.Contains(t, response.Body.String(), `"id":5`)
+
+		manager.AssertExpectations(t)
+		approvals.AssertExpectations(t)
+	})
+}
+
+func TestApprove(t *testing.T) {
+	pending := func() *approval.Request {
+		return &approval.Request{ID: 5, Kind: approval.KindPermissionGrant, GroupID: 10, PermissionID: 3, RequestedBy: "alice", Status: approval.StatusPending}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		approvals.On("Get", mock.Anything, 5).Return(pending(), nil)
+		manager.On("GrantPermission", mock.Anything, 10, 3).Return(nil)
+		approvals.On("Decide", mock.Anything, 5, approval.StatusApproved, "admin", mock.Anything).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "admin", "", mfa)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"status":"approved"`)
+
+		manager.AssertExpectations(t)
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("requires mfa", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPost, "/api/approvals/5/approve", "admin", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		approvals.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires approve permission", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		approvals.On("Get", mock.Anything, 5).Return(pending(), nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "viewer", "", mfa)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		approvals.AssertNotCalled(t, "Decide", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wildcard approve permission", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		policy := metaPolicy()
+		policy.Permissions = append(policy.Permissions, authz.Permission{Name: "authz.*", Groups: []string{"viewers"}})
+		manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+		manager.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{
+			{ID: 3, Name: "recipes.purge", Version: 1, Risk: authz.RiskHigh},
+		}, nil).Maybe()
+		approvals.On("Get", mock.Anything, 5).Return(pending(), nil)
+		manager.On("GrantPermission", mock.Anything, 10, 3).Return(nil)
+		approvals.On("Decide", mock.Anything, 5, approval.StatusApproved, "viewer", mock.Anything).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "viewer", "", mfa)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		manager.AssertExpectations(t)
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("routed approver", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		routed := &approval.Request{ID: 5, Kind: approval.KindGroupMembership, GroupID: 10, UserID: "alice", RequestedBy: "alice",
+			Approvers: []string{"viewer"}, Status: approval.StatusPending}
+		approvals.On("Get", mock.Anything, 5).Return(routed, nil)
+		manager.On("AddGroupUser", mock.Anything, 10, "alice").Return(nil)
+		approvals.On("Decide", mock.Anything, 5, approval.StatusApproved, "viewer", mock.Anything).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "viewer", "", mfa)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		manager.AssertExpectations(t)
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("not assigned", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		routed := &approval.Request{ID: 5, Kind: approval.KindGroupMembership, GroupID: 10, UserID: "alice", RequestedBy: "alice",
+			Approvers: []string{"bob"}, Status: approval.StatusPending}
+		approvals.On("Get", mock.Anything, 5).Return(routed, nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "admin", "", mfa)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		assert.Contains(t, response.Body.String(), "assigned")
+
+		approvals.AssertNotCalled(t, "Decide", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		approvals.On("Get", mock.Anything, 5).Return(nil, approval.ErrNotFound)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "admin", "", mfa)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("already decided", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		decided := pending()
+		decided.Status = approval.StatusRejected
+		approvals.On("Get", mock.Anything, 5).Return(decided, nil)
+
+		response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/approve", "admin", "", mfa)
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+}
+
+func TestReject(t *testing.T) {
+	manager, approvals, server := setupApprovalServer()
+	setupRiskPolicy(manager)
+	approvals.On("Get", mock.Anything, 5).Return(&approval.Request{ID: 5, Kind: approval.KindPermissionGrant, RequestedBy: "admin", Status: approval.StatusPending}, nil)
+
+	response := serveWithHeaders(server, http.MethodPost, "/api/approvals/5/reject", "admin", "", mfa)
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.Contains(t, response.Body.String(), "requester")
+}
+
+func TestListApprovals(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, approvals, server := setupApprovalServer()
+		setupRiskPolicy(manager)
+		approvals.On("List", mock.Anything, approval.StatusApproved).Return([]approval.Request{{ID: 5, Status: approval.StatusApproved}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/approvals?status=approved", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"id":5`)
+
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/approvals", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestListAssignedApprovals(t *testing.T) {
+	manager, approvals, server := setupApprovalServer()
+	setupRiskPolicy(manager)
+	approvals.On("List", mock.Anything, approval.StatusPending).Return([]approval.Request{
+		{ID: 5, Status: approval.StatusPending, Approvers: []string{"carol"}},
+		{ID: 6, Status: approval.StatusPending, Approvers: []string{"dave"}},
+		{ID: 7, Status: approval.StatusPending},
+	}, nil)
+
+	response := serve(server, http.MethodGet, "/api/access/approvals", "carol", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"id":5`)
+	assert.NotContains(t, response.Body.String(), `"id":6`)
+	assert.NotContains(t, response.Body.String(), `"id":7`)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// auditLimits bounds the pages of audit events, listed most recent first.
+var auditLimits = paging.Limits{Default: 100, Max: 1000}
+
+// listAuditEvents serves the audit history, optionally filtered by actor, action and subject.
+func (server *Server) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if server.auditLog == nil {
+		writeError(w, http.StatusNotFound, "audit history is not configured")
+		return
+	}
+
+	page, ok := parsePage(w, r, auditLimits)
+	if !ok {
+		return
+	}
+
+	// fetch one more event than asked for to know whether there is a next page
+	query := r.URL.Query()
+	filter := audit.Filter{Actor: query.Get("actor"), Action: query.Get("action"), Subject: query.Get("subject"),
+		Limit: page.Limit + 1, After: page.After}
+	events, err := server.auditLog.List(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, paging.ErrInvalidCursor) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		server.logger.Error("audit log failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	events, next := paging.Trim(events, page.Limit, audit.ListKey)
+	writePage(w, r, events, next)
+}
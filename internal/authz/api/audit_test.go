@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockAuditReader is a mock implementation of the audit.Reader interface
+type mockAuditReader struct {
+	mock.Mock
+}
+
+func (m *mockAuditReader) List(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	args := m.Called(ctx, filter)
+	events, _ := args.Get(0).([]audit.Event)
+	return events, args.Error(1)
+}
+
+// setupAuditServer serves the audit history to the auditors, who hold authz.audit, and not to the viewers.
+func setupAuditServer(options ...Option) (*mockAuditReader, *Server) {
+	manager := new(MockPolicyManager)
+	reader := new(mockAuditReader)
+	policy := metaPolicy()
+	policy.Permissions = append(policy.Permissions, *authz.NewPermission(PermissionAudit, []string{"auditors"}))
+	policy.Groups = append(policy.Groups, *authz.NewGroup("auditors", []string{"auditor"}))
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), append([]Option{WithAuditLog(reader)}, options...)...)
+	return reader, server
+}
+
+func TestListAuditEvents(t *testing.T) {
+	recorded := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("filters", func(t *testing.T) {
+		reader, server := setupAuditServer()
+		reader.On("List", mock.Anything, audit.Filter{Actor: "admin", Action: "policy.create_group", Subject: "group 3", Limit: 101}).Return([]audit.Event{
+			{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", Time: recorded, Actor: "admin", Action: "policy.create_group", Subject: "group 3"},
+		}, nil)
+
+		response := serve(server, http.MethodGet, "/api/audit/events?actor=admin&action=policy.create_group&subject=group+3", "auditor", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"id":"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e","time":"2025-01-02T03:04:05Z","actor":"admin",
+			"action":"policy.create_group","subject":"group 3"}]`, response.Body.String())
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		reader, server := setupAuditServer()
+		reader.On("List", mock.Anything, audit.Filter{Limit: 2}).Return([]audit.Event{
+			{ID: "b", Time: recorded, Actor: "admin", Action: "policy.delete_group"}, {ID: "a", Time: recorded, Actor: "admin", Action: "policy.create_group"},
+		}, nil)
+
+		response := serve(server, http.MethodGet, "/api/audit/events?limit=1", "auditor", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		cursor := paging.Encode(paging.Key{recorded, "b"})
+		assert.Equal(t, `</api/audit/events?cursor=`+cursor+`&limit=1>; rel="next"`, response.Header().Get("Link"))
+		assert.NotContains(t, response.Body.String(), `"id":"a"`)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		reader, server := setupAuditServer()
+		reader.On("List", mock.Anything, mock.Anything).Return(nil, paging.ErrInvalidCursor)
+
+		response := serve(server, http.MethodGet, "/api/audit/events?cursor="+paging.Encode(paging.Key{"recipes"}), "auditor", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		reader, server := setupAuditServer()
+		reader.On("List", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+
+		response := serve(server, http.MethodGet, "/api/audit/events", "auditor", "")
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		reader, server := setupAuditServer()
+
+		response := serve(server, http.MethodGet, "/api/audit/events", "viewer", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		reader.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		_, server := setupAuditServer(WithAuditLog(nil))
+
+		response := serve(server, http.MethodGet, "/api/audit/events", "auditor", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// registerCatalogRequest is the body of PUT /api/catalogs/{application}.
+type registerCatalogRequest struct {
+	Permissions []catalog.Entry `json:"permissions"`
+}
+
+// registerCatalogResponse is the response of PUT /api/catalogs/{application}.
+type registerCatalogResponse struct {
+	Catalog  catalog.Catalog   `json:"catalog"`
+	Warnings []catalog.Warning `json:"warnings"`
+}
+
+// registerCatalog replaces the permission catalog of an application. Applications register
+// their catalog when they are deployed, so the response includes the lint warnings of the
+// policy against the updated catalogs. A catalog store cascading the permissions removed from
+// the catalog to the policy may refuse it with 409, see catalog.CascadingStore.
+func (server *Server) registerCatalog(w http.ResponseWriter, r *http.Request) {
+	if !server.requireCatalogs(w) {
+		return
+	}
+
+	var request registerCatalogRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	registered := catalog.Catalog{
+		Application:  r.PathValue("application"),
+		Permissions:  request.Permissions,
+		RegisteredBy: identity.User,
+		RegisteredAt: server.clock.Now().UTC(),
+	}
+	if registered.Permissions == nil {
+		registered.Permissions = []catalog.Entry{}
+	}
+	if err := registered.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := server.catalogs.Register(r.Context(), registered); err != nil {
+		server.writeCatalogError(w, err)
+		return
+	}
+
+	warnings, ok := server.lint(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registerCatalogResponse{Catalog: registered, Warnings: warnings})
+}
+
+func (server *Server) listCatalogs(w http.ResponseWriter, r *http.Request) {
+	if !server.requireCatalogs(w) {
+		return
+	}
+
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+
+	catalogs, err := server.catalogs.List(r.Context())
+	if err != nil {
+		server.writeCatalogError(w, err)
+		return
+	}
+
+	catalogs, next, err := paging.Page(catalogs, page, func(registered catalog.Catalog) paging.Key { return paging.Key{registered.Application} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, catalogs, next)
+}
+
+func (server *Server) getCatalog(w http.ResponseWriter, r *http.Request) {
+	if !server.requireCatalogs(w) {
+		return
+	}
+
+	registered, err := server.catalogs.Get(r.Context(), r.PathValue("application"))
+	if err != nil {
+		server.writeCatalogError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, registered)
+}
+
+// lintReport lists the permissions granted without being declared in a catalog,
+// whose risk level differs from their catalog, or inherited by folders not opting in.
+func (server *Server) lintReport(w http.ResponseWriter, r *http.Request) {
+	if !server.requireCatalogs(w) {
+		return
+	}
+
+	warnings, ok := server.lint(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, warnings)
+}
+
+// lint checks the policy against every registered catalog, writing the error response on failure.
+func (server *Server) lint(w http.ResponseWriter, r *http.Request) ([]catalog.Warning, bool) {
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return nil, false
+	}
+
+	catalogs, err := server.catalogs.List(r.Context())
+	if err != nil {
+		server.writeCatalogError(w, err)
+		return nil, false
+	}
+
+	return catalog.Lint(policy, catalogs), true
+}
+
+func (server *Server) requireCatalogs(w http.ResponseWriter) bool {
+	if server.catalogs == nil {
+		writeError(w, http.StatusNotFound, "permission catalogs are not configured")
+		return false
+	}
+	return true
+}
+
+// writeCatalogError maps a catalog store error to the matching HTTP status code. The errors of
+// the policy store, returned when a catalog change cascades to the policy, are mapped as usual.
+func (server *Server) writeCatalogError(w http.ResponseWriter, err error) {
+	if errors.Is(err, catalog.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var inUseErr *catalog.InUseError
+	if errors.As(err, &inUseErr) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	var storeErr *store.PolicyStoreError
+	if errors.As(err, &storeErr) {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	server.logger.Error("permission catalog store failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
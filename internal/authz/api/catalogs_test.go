@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockCatalogStore is a mock implementation of the catalog.Store interface
+type mockCatalogStore struct {
+	mock.Mock
+}
+
+func (m *mockCatalogStore) Register(ctx context.Context, registered catalog.Catalog) error {
+	return m.Called(ctx, registered).Error(0)
+}
+func (m *mockCatalogStore) Get(ctx context.Context, application string) (*catalog.Catalog, error) {
+	args := m.Called(ctx, application)
+	registered, _ := args.Get(0).(*catalog.Catalog)
+	return registered, args.Error(1)
+}
+func (m *mockCatalogStore) List(ctx context.Context) ([]catalog.Catalog, error) {
+	args := m.Called(ctx)
+	catalogs, _ := args.Get(0).([]catalog.Catalog)
+	return catalogs, args.Error(1)
+}
+
+// authzCatalog declares the permissions of the meta policy, so only the test permissions produce warnings.
+var authzCatalog = catalog.Catalog{Application: "authz", Permissions: []catalog.Entry{
+	{Name: PermissionRead, Risk: authz.RiskLow},
+	{Name: PermissionWrite, Risk: authz.RiskLow},
+}}
+
+func setupCatalogServer() (*MockPolicyManager, *mockCatalogStore, *Server) {
+	manager := new(MockPolicyManager)
+	catalogs := new(mockCatalogStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithCatalogs(catalogs))
+
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups, *authz.NewGroup("cooks", []string{"alice"}))
+	policy.Permissions = append(policy.Permissions,
+		authz.Permission{Name: "recipes.read", Groups: []string{"cooks"}},
+		authz.Permission{Name: "recipes.legacy", Groups: []string{"cooks"}})
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	return manager, catalogs, server
+}
+
+func TestRegisterCatalog(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		recipes := catalog.Catalog{Application: "recipes", Permissions: []catalog.Entry{{Name: "recipes.read", Description: "Read recipes", Risk: authz.RiskLow}}}
+		catalogs.On("Register", mock.Anything, mock.MatchedBy(func(registered catalog.Catalog) bool {
+			return registered.Application == "recipes" && registered.RegisteredBy == "admin" && !registered.RegisteredAt.IsZero() &&
+				assert.ObjectsAreEqual(recipes.Permissions, registered.Permissions)
+		})).Return(nil)
+		catalogs.On("List", mock.Anything).Return([]catalog.Catalog{authzCatalog, recipes}, nil)
+
+		response := serve(server, http.MethodPut, "/api/catalogs/recipes", "admin", `{"permissions":[{"name":"recipes.read","description":"Read recipes"}]}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"warnings":[{"permission":"recipes.legacy","message":"granted to cooks but not declared in any catalog"}]`)
+
+		catalogs.AssertExpectations(t)
+	})
+
+	t.Run("invalid catalog", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+
+		response := serve(server, http.MethodPut, "/api/catalogs/recipes", "admin", `{"permissions":[{"name":"recipes.read","risk":"critical"}]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		catalogs.AssertNotCalled(t, "Register", mock.Anything, mock.Anything)
+	})
+
+	t.Run("removed permission in use", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		catalogs.On("Register", mock.Anything, mock.Anything).Return(&catalog.InUseError{Grants: map[string][]string{"recipes.legacy": {"cooks"}}})
+
+		response := serve(server, http.MethodPut, "/api/catalogs/recipes", "admin", `{"permissions":[]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assert.Contains(t, response.Body.String(), "recipes.legacy (granted to cooks)")
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+
+		response := serve(server, http.MethodPut, "/api/catalogs/recipes", "viewer", `{"permissions":[]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		catalogs.AssertNotCalled(t, "Register", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/catalogs/recipes", "admin", `{"permissions":[]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestGetCatalog(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		catalogs.On("Get", mock.Anything, "authz").Return(&authzCatalog, nil)
+
+		response := serve(server, http.MethodGet, "/api/catalogs/authz", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"application":"authz"`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		catalogs.On("Get", mock.Anything, "recipes").Return(nil, catalog.ErrNotFound)
+
+		response := serve(server, http.MethodGet, "/api/catalogs/recipes", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestLintReport(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		catalogs.On("List", mock.Anything).Return([]catalog.Catalog{authzCatalog}, nil)
+
+		response := serve(server, http.MethodGet, "/api/reports/lint", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[
+			{"permission":"recipes.legacy","message":"granted to cooks but not declared in any catalog"},
+			{"permission":"recipes.read","message":"granted to cooks but not declared in any catalog"}
+		]`, response.Body.String())
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		_, catalogs, server := setupCatalogServer()
+		catalogs.On("List", mock.Anything).Return(nil, errors.New("db error"))
+
+		response := serve(server, http.MethodGet, "/api/reports/lint", "viewer", "")
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
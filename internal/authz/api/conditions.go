@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// setPermissionConditionRequest is the body of PUT /api/permissions/{id}/condition.
+type setPermissionConditionRequest struct {
+	Condition *string `json:"condition"`
+}
+
+// setPermissionCondition replaces the condition the attributes of a request must meet for the grants of
+// a permission to apply, see authz.Permission.Condition. An empty condition makes the grants unconditional,
+// a missing one is rejected so a typo cannot lift it.
+func (server *Server) setPermissionCondition(w http.ResponseWriter, r *http.Request) {
+	permissionId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid permission id")
+		return
+	}
+
+	var request setPermissionConditionRequest
+	if err := decodeJSON(w, r, &request); err != nil || request.Condition == nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := server.manager.SetPermissionCondition(r.Context(), permissionId, *request.Condition); err != nil {
+		server.writePermissionError(w, r, permissionId, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetPermissionCondition(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("SetPermissionCondition", mock.Anything, 3, "resource.owner == user").Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/condition", "admin", `{"condition":"resource.owner == user"}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("invalid condition", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("SetPermissionCondition", mock.Anything, 3, "resource.owner ==").Return(store.NewInvalidArgumentError())
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/condition", "admin", `{"condition":"resource.owner =="}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("missing condition", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/condition", "admin", `{}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		manager.AssertNotCalled(t, "SetPermissionCondition", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/condition", "bob", `{"condition":""}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// groupState is the current state of a group, returned with conflicts.
+type groupState struct {
+	store.GroupInfo[int]
+	Users       []string `json:"users"`
+	Permissions []int    `json:"permissions"`
+}
+
+// permissionState is the current state of a permission, returned with conflicts.
+type permissionState struct {
+	store.PermissionInfo[int]
+	Implies []int `json:"implies"`
+}
+
+// conflictResponse is the body returned when a change raced with another one. It carries the
+// current state of the entity, so clients can rebase their edit without reading it again.
+type conflictResponse struct {
+	Error   string          `json:"error"`
+	Code    store.ErrorCode `json:"code"`
+	Current any             `json:"current"`
+}
+
+// writeGroupError writes the error of a change to the group, returning the current state
+// of the group when the change raced with another one.
+func (server *Server) writeGroupError(w http.ResponseWriter, r *http.Request, groupId int, err error) {
+	if !store.IsConcurrency(err) {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	current, stateErr := server.groupState(r.Context(), groupId)
+	if stateErr != nil {
+		server.logger.Warn("failed to read the state of the conflicting group", "group_id", groupId, "error", stateErr)
+		server.writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusConflict, conflictResponse{Error: err.Error(), Code: store.Concurrency, Current: current})
+}
+
+// writePermissionError writes the error of a change to the permission, like writeGroupError.
+func (server *Server) writePermissionError(w http.ResponseWriter, r *http.Request, permissionId int, err error) {
+	if !store.IsConcurrency(err) {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	current, stateErr := server.permissionState(r.Context(), permissionId)
+	if stateErr != nil {
+		server.logger.Warn("failed to read the state of the conflicting permission", "permission_id", permissionId, "error", stateErr)
+		server.writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusConflict, conflictResponse{Error: err.Error(), Code: store.Concurrency, Current: current})
+}
+
+// groupState reads the version, members and permission ids of the group.
+func (server *Server) groupState(ctx context.Context, groupId int) (*groupState, error) {
+	groups, err := server.manager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.IndexFunc(groups, func(group store.GroupInfo[int]) bool { return group.ID == groupId })
+	if index < 0 {
+		return nil, store.NewGroupNotFoundError()
+	}
+
+	permissions, err := server.manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.manager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissionIds := permissionIdsByName(permissions)
+
+	state := &groupState{GroupInfo: groups[index], Users: []string{}, Permissions: []int{}}
+	for _, group := range policy.Groups {
+		if group.Name == state.Name {
+			state.Users = append(state.Users, group.Users...)
+		}
+	}
+	for _, permission := range policy.Permissions {
+		if slices.Contains(permission.Groups, state.Name) {
+			state.Permissions = append(state.Permissions, permissionIds[permission.Name])
+		}
+	}
+	slices.Sort(state.Permissions)
+	return state, nil
+}
+
+// permissionState reads the version, risk and implied permission ids of the permission.
+func (server *Server) permissionState(ctx context.Context, permissionId int) (*permissionState, error) {
+	permissions, err := server.manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.IndexFunc(permissions, func(permission store.PermissionInfo[int]) bool { return permission.ID == permissionId })
+	if index < 0 {
+		return nil, store.NewPermissionNotFoundError()
+	}
+
+	policy, err := server.manager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissionIds := permissionIdsByName(permissions)
+
+	state := &permissionState{PermissionInfo: permissions[index], Implies: []int{}}
+	for _, permission := range policy.Permissions {
+		if permission.Name != state.Name {
+			continue
+		}
+		for _, implied := range permission.Implies {
+			state.Implies = append(state.Implies, permissionIds[implied])
+		}
+	}
+	slices.Sort(state.Implies)
+	return state, nil
+}
+
+func permissionIdsByName(permissions []store.PermissionInfo[int]) map[string]int {
+	ids := make(map[string]int, len(permissions))
+	for _, permission := range permissions {
+		ids[permission.Name] = permission.ID
+	}
+	return ids
+}
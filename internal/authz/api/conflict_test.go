@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConflict(t *testing.T) {
+	t.Run("group conflict returns the current group", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("UpdateGroupUsers", mock.Anything, 10, []string{"bob"}).Return(store.NewConcurrencyError())
+
+		response := serve(server, http.MethodPut, "/api/groups/10/users", "admin", `{"users":["bob"]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		var body struct {
+			Code    store.ErrorCode `json:"code"`
+			Current struct {
+				ID          int      `json:"id"`
+				Version     int      `json:"version"`
+				Users       []string `json:"users"`
+				Permissions []int    `json:"permissions"`
+			} `json:"current"`
+		}
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Equal(t, store.Concurrency, body.Code)
+		assert.Equal(t, 10, body.Current.ID)
+		assert.Equal(t, 1, body.Current.Version)
+		assert.Equal(t, []string{"alice"}, body.Current.Users)
+		assert.Equal(t, []int{1, 2}, body.Current.Permissions)
+	})
+
+	t.Run("permission conflict returns the current permission", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionImplications", mock.Anything, 3, []int{1}).Return(store.NewConcurrencyError())
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[1]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		var body struct {
+			Current struct {
+				ID      int    `json:"id"`
+				Name    string `json:"name"`
+				Implies []int  `json:"implies"`
+			} `json:"current"`
+		}
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Equal(t, 3, body.Current.ID)
+		assert.Equal(t, "recipes.purge", body.Current.Name)
+		assert.Equal(t, []int{}, body.Current.Implies)
+	})
+
+	t.Run("conflict without current state when it cannot be read", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("ListGroups", mock.Anything).Return(nil, store.NewDataBaseError())
+		manager.On("UpdateGroupUsers", mock.Anything, 10, []string{"bob"}).Return(store.NewConcurrencyError())
+
+		response := serve(server, http.MethodPut, "/api/groups/10/users", "admin", `{"users":["bob"]}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assert.NotContains(t, response.Body.String(), "current")
+	})
+}
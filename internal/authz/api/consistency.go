@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+)
+
+// ConsistencyHeader carries consistency tokens, see the consistency package. It is set on the successful
+// responses of changes, and an evaluation request passing it back is answered with a policy reflecting
+// the change. Relationship checks always read the store, so they need no token.
+const ConsistencyHeader = "Consistency-Token"
+
+// tokenWriter sets ConsistencyHeader on a successful response before its header is written,
+// once the handler made its change.
+type tokenWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	server      *Server
+	wroteHeader bool
+}
+
+func (writer *tokenWriter) WriteHeader(status int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		if status < http.StatusMultipleChoices {
+			writer.server.issueToken(writer.ctx, writer.Header())
+		}
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *tokenWriter) Write(content []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (writer *tokenWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// withTokens returns the writer of the response, issuing a consistency token for a change.
+// Reads are served as they are.
+func (server *Server) withTokens(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if server.consistency == nil {
+		return w
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return w
+	}
+	return &tokenWriter{ResponseWriter: w, ctx: r.Context(), server: server}
+}
+
+// issueToken sets the token of the current policy revision. A revision that cannot be read only
+// leaves the token out, the change itself succeeded.
+func (server *Server) issueToken(ctx context.Context, header http.Header) {
+	revision, err := server.consistency.PolicyRevision(ctx)
+	if err != nil {
+		server.logger.Warn("failed to read the policy revision of a consistency token", "error", err)
+		return
+	}
+	header.Set(ConsistencyHeader, consistency.Encode(revision))
+}
+
+// readEvaluationPolicy reads the policy an evaluation is made with, at least as recent as the
+// consistency token of the request if any. It writes the error response and returns false on failure.
+func (server *Server) readEvaluationPolicy(w http.ResponseWriter, r *http.Request) (*authz.Policy, bool) {
+	token := r.Header.Get(ConsistencyHeader)
+	if token == "" || server.consistency == nil {
+		policy, err := server.manager.ReadPolicy(r.Context())
+		if err != nil {
+			server.writeStoreError(w, err)
+			return nil, false
+		}
+		return policy, true
+	}
+
+	revision, err := consistency.Decode(token)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	policy, err := server.consistency.ReadPolicyAt(r.Context(), revision)
+	if errors.Is(err, consistency.ErrNotYetAvailable) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		server.writeStoreError(w, err)
+		return nil, false
+	}
+	return policy, true
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeReader is a consistency.Reader at a fixed revision, whose policy adds bob to the cooks.
+type fakeReader struct {
+	revision int64
+}
+
+func (reader *fakeReader) PolicyRevision(ctx context.Context) (int64, error) {
+	return reader.revision, nil
+}
+
+func (reader *fakeReader) ReadPolicyAt(ctx context.Context, revision int64) (*authz.Policy, error) {
+	if revision > reader.revision {
+		return nil, consistency.ErrNotYetAvailable
+	}
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups, *authz.NewGroup("cooks", []string{"alice", "bob"}))
+	policy.Permissions = append(policy.Permissions, authz.Permission{Name: "recipes.read", Groups: []string{"cooks"}})
+	return policy, nil
+}
+
+func TestConsistencyToken(t *testing.T) {
+	t.Run("issued by changes", func(t *testing.T) {
+		manager := new(MockPolicyManager)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithConsistency(&fakeReader{revision: 12}))
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("CreateGroup", mock.Anything, "cooks").Return(7, nil)
+		manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{}, nil)
+
+		response := serve(server, http.MethodPost, "/api/groups", "admin", `{"name":"cooks"}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, consistency.Encode(12), response.Header().Get(ConsistencyHeader))
+
+		response = serve(server, http.MethodGet, "/api/groups", "admin", "")
+		assert.Empty(t, response.Header().Get(ConsistencyHeader))
+	})
+
+	t.Run("not issued by failed changes", func(t *testing.T) {
+		manager := new(MockPolicyManager)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithConsistency(&fakeReader{revision: 12}))
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPost, "/api/groups", "admin", `{"name":""}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Empty(t, response.Header().Get(ConsistencyHeader))
+	})
+
+	t.Run("honored by decisions", func(t *testing.T) {
+		// the policy of the manager is stale and does not grant bob yet
+		server := setupDecisionServer(WithConsistency(&fakeReader{revision: 12}))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"allowed":false`)
+
+		response = serveWithHeaders(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "",
+			map[string]string{ConsistencyHeader: consistency.Encode(12)})
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"allowed":true`)
+	})
+
+	t.Run("revision not available yet", func(t *testing.T) {
+		server := setupDecisionServer(WithConsistency(&fakeReader{revision: 12}))
+
+		response := serveWithHeaders(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "",
+			map[string]string{ConsistencyHeader: consistency.Encode(13)})
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.Equal(t, "1", response.Header().Get("Retry-After"))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		server := setupDecisionServer(WithConsistency(&fakeReader{revision: 12}))
+
+		response := serveWithHeaders(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "",
+			map[string]string{ConsistencyHeader: "12"})
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
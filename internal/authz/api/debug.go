@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+)
+
+// getDiagnostics returns a snapshot of the runtime state of the server.
+func (server *Server) getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDiagnostics(w) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, server.diagnostics.Snapshot(r.Context()))
+}
+
+// profileIndex lists the available profiles.
+func (server *Server) profileIndex(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDiagnostics(w) {
+		return
+	}
+
+	pprof.Index(w, r)
+}
+
+// profile serves a pprof profile, such as the heap or a CPU profile.
+func (server *Server) profile(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDiagnostics(w) {
+		return
+	}
+
+	switch name := r.PathValue("profile"); name {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		if runtimepprof.Lookup(name) == nil {
+			writeError(w, http.StatusNotFound, "unknown profile")
+			return
+		}
+		pprof.Handler(name).ServeHTTP(w, r)
+	}
+}
+
+func (server *Server) requireDiagnostics(w http.ResponseWriter) bool {
+	if server.diagnostics == nil {
+		writeError(w, http.StatusNotFound, "diagnostics are not enabled")
+		return false
+	}
+	return true
+}
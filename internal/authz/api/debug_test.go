@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/diagnostics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// setupDiagnosticsServer grants "operator" the diagnose permission and "viewer" read access.
+func setupDiagnosticsServer(options ...Option) *Server {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(authz.NewPolicy(
+		[]authz.Permission{
+			*authz.NewPermission(PermissionRead, []string{"viewers"}),
+			*authz.NewPermission(PermissionDiagnose, []string{"operators"}),
+		},
+		[]authz.Group{
+			*authz.NewGroup("viewers", []string{"viewer"}),
+			*authz.NewGroup("operators", []string{"operator"}),
+		},
+	), nil)
+	return NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+}
+
+func TestGetDiagnostics(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		collected := diagnostics.New(nil)
+		collected.Register("pool", func(ctx context.Context) (any, error) { return map[string]int{"total_conns": 4}, nil })
+		collected.Register("cache", func(ctx context.Context) (any, error) { return nil, errors.New("unavailable") })
+		server := setupDiagnosticsServer(WithDiagnostics(collected))
+
+		response := serve(server, http.MethodGet, "/api/debug/diagnostics", "operator", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"pool":{"total_conns":4}`)
+		assert.Contains(t, response.Body.String(), `"collector_errors":{"cache":"unavailable"}`)
+		assert.Contains(t, response.Body.String(), `"goroutines":`)
+	})
+
+	t.Run("requires diagnose permission", func(t *testing.T) {
+		server := setupDiagnosticsServer(WithDiagnostics(diagnostics.New(nil)))
+
+		response := serve(server, http.MethodGet, "/api/debug/diagnostics", "viewer", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		server := setupDiagnosticsServer()
+
+		response := serve(server, http.MethodGet, "/api/debug/diagnostics", "operator", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestProfile(t *testing.T) {
+	t.Run("goroutines", func(t *testing.T) {
+		server := setupDiagnosticsServer(WithDiagnostics(diagnostics.New(nil)))
+
+		response := serve(server, http.MethodGet, "/api/debug/pprof/goroutine?debug=2", "operator", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "goroutine ")
+	})
+
+	t.Run("index", func(t *testing.T) {
+		server := setupDiagnosticsServer(WithDiagnostics(diagnostics.New(nil)))
+
+		response := serve(server, http.MethodGet, "/api/debug/pprof/", "operator", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "heap")
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		server := setupDiagnosticsServer(WithDiagnostics(diagnostics.New(nil)))
+
+		response := serve(server, http.MethodGet, "/api/debug/pprof/unknown", "operator", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("requires diagnose permission", func(t *testing.T) {
+		server := setupDiagnosticsServer(WithDiagnostics(diagnostics.New(nil)))
+
+		response := serve(server, http.MethodGet, "/api/debug/pprof/heap", "viewer", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/salmarsumi/recipes/internal/authz/decisionlog"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// DecisionTTLs are the cache lifetimes suggested to callers of the decision endpoint.
+// Shorter lifetimes make revocations take effect sooner at the cost of more requests;
+// a zero lifetime asks callers not to cache the decision.
+type DecisionTTLs struct {
+	// Allow is the lifetime of granted decisions.
+	Allow time.Duration
+	// HighRisk is the lifetime of granted decisions on high risk permissions.
+	HighRisk time.Duration
+	// Deny is the lifetime of denied decisions, kept short so new grants are picked up quickly.
+	Deny time.Duration
+}
+
+// DefaultDecisionTTLs are the lifetimes used unless WithDecisionTTLs says otherwise.
+var DefaultDecisionTTLs = DecisionTTLs{Allow: time.Minute, HighRisk: 0, Deny: 10 * time.Second}
+
+// DecisionRecorder records the decisions made by the decision endpoint.
+type DecisionRecorder interface {
+	Record(ctx context.Context, decision decisionlog.Decision) error
+}
+
+// decisionResponse is the body returned by GET /api/decisions.
+type decisionResponse struct {
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+	// Why the permission was denied, see authz.DenialReason. An allowed decision with a reason
+	// was allowed by the evaluation mode rather than by the policy.
+	Reason authz.DenialReason `json:"reason,omitempty"`
+	// The evaluation mode the decision was made in.
+	Mode authz.EvaluationMode `json:"mode"`
+	// The version of the policy the decision was made with, see policyfile.Version.
+	PolicyVersion string `json:"policy_version"`
+	// How long the caller may reuse the decision.
+	TTLSeconds int `json:"ttl_seconds"`
+	// The steps of the evaluation, when the request asked for a trace.
+	Trace []authz.TraceStep `json:"trace,omitempty"`
+}
+
+// getDecision tells whether a user is granted a permission. Services check decisions for their
+// end users, so it requires the evaluate permission rather than impersonation.
+// The response carries the policy version and a cache lifetime, also sent as Cache-Control
+// and ETag headers, so high traffic callers can cache decisions safely.
+// With trace=true the response also explains the decision, see authz.TraceStep; tracing reveals
+// the groups of the user so it requires the diagnose permission, and traced decisions are not cached.
+// The attributes of the request the conditions of the permissions are evaluated against, such as the
+// owner of the resource, are passed as a JSON object, e.g. attributes={"resource.owner":"alice"}.
+// A request passing the ConsistencyHeader returned by a change is decided with a policy reflecting it.
+// Every decision is recorded as a span, the parent of the spans of the policy store reads.
+func (server *Server) getDecision(w http.ResponseWriter, r *http.Request) {
+	user := r.URL.Query().Get("user")
+	permission := r.URL.Query().Get("permission")
+	if user == "" || permission == "" {
+		writeError(w, http.StatusBadRequest, "user and permission are required")
+		return
+	}
+	attributes, err := decisionAttributes(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, span := server.tracer.Start(r.Context(), "Server.getDecision")
+	span.SetAttributes(tracing.PermissionKey.String(permission))
+	defer func() { tracing.End(span, err) }()
+	r = r.WithContext(ctx)
+
+	policy, ok := server.readEvaluationPolicy(w, r)
+	if !ok {
+		return
+	}
+
+	trace := false
+	if value := r.URL.Query().Get("trace"); value != "" {
+		trace, err = strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "trace must be a boolean")
+			return
+		}
+	}
+	if trace {
+		identity, _ := IdentityFromContext(r.Context())
+		session, sessionErr := policy.Session(identity.User)
+		if sessionErr != nil || !session.HasPermission(PermissionDiagnose) {
+			writeError(w, http.StatusForbidden, "tracing requires the "+PermissionDiagnose+" permission")
+			return
+		}
+	}
+
+	result, steps, err := server.evaluateDecision(policy, user, permission, attributes, trace)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	version, err := server.policyVersion(policy)
+	if err != nil {
+		server.logger.Error("failed to compute policy version", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	check := server.check(policy, user, permission, result)
+	span.SetAttributes(tracing.AllowedKey.Bool(check.Allowed))
+	if check.Reason != "" {
+		span.SetAttributes(tracing.ReasonKey.String(string(check.Reason)))
+	}
+	server.recordDecision(r.Context(), user, permission, check, version)
+	ttl := server.decisionTTLs.Deny
+	if check.Allowed {
+		ttl = server.decisionTTLs.Allow
+		// a permission granted through a high risk wildcard is as sensitive as the wildcard
+		if isHighRisk(policy, permission) || isHighRisk(policy, check.GrantedBy) {
+			ttl = server.decisionTTLs.HighRisk
+		}
+	}
+
+	if trace {
+		ttl = 0
+	}
+
+	seconds := int(ttl / time.Second)
+	if seconds > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", seconds))
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	w.Header().Set("ETag", fmt.Sprintf("%q", version))
+
+	writeJSON(w, http.StatusOK, decisionResponse{
+		User:          user,
+		Permission:    permission,
+		Allowed:       check.Allowed,
+		Reason:        check.Reason,
+		Mode:          check.Mode,
+		PolicyVersion: version,
+		TTLSeconds:    seconds,
+		Trace:         steps,
+	})
+}
+
+// PolicyVersions returns the versions of the policies it handed out, computed once per policy,
+// see cache.CachedPolicyProvider.Version.
+type PolicyVersions interface {
+	Version(policy *authz.Policy) (string, bool)
+}
+
+// policyVersion returns the version of the policy, see policyfile.Version, reusing the one
+// computed by the policy versions when the policy is theirs.
+func (server *Server) policyVersion(policy *authz.Policy) (string, error) {
+	if server.versionSource != nil {
+		if version, ok := server.versionSource.Version(policy); ok {
+			return version, nil
+		}
+	}
+	return policyfile.Version(policy)
+}
+
+// isHighRisk tells whether the policy defines the permission as high risk.
+func isHighRisk(policy *authz.Policy, permission string) bool {
+	index := slices.IndexFunc(policy.Permissions, func(candidate authz.Permission) bool { return candidate.Name == permission })
+	return index >= 0 && policy.Permissions[index].Risk == authz.RiskHigh
+}
+
+// decisionAttributes decodes the attributes of a decision request, none when the query has no attributes.
+func decisionAttributes(r *http.Request) (condition.Attributes, error) {
+	value := r.URL.Query().Get("attributes")
+	if value == "" {
+		return nil, nil
+	}
+
+	var attributes condition.Attributes
+	if err := json.Unmarshal([]byte(value), &attributes); err != nil || attributes == nil {
+		return nil, errors.New("attributes must be a JSON object")
+	}
+	return attributes, nil
+}
+
+// recordDecision records the decision in the decision log, if any. A decision that cannot be
+// recorded does not fail the request, the recorder counts the decisions it drops.
+func (server *Server) recordDecision(ctx context.Context, user string, permission string, check *authz.CheckResult, version string) {
+	if server.decisionLog == nil {
+		return
+	}
+
+	identity, _ := IdentityFromContext(ctx)
+	err := server.decisionLog.Record(ctx, decisionlog.Decision{
+		ID:            id.New(),
+		Time:          server.clock.Now(),
+		Caller:        identity.User,
+		User:          user,
+		Permission:    permission,
+		Allowed:       check.Allowed,
+		Reason:        check.Reason,
+		Mode:          check.Mode,
+		PolicyVersion: version,
+	})
+	if err != nil {
+		server.logger.Debug("decision not recorded", "user", user, "permission", permission, "error", err)
+	}
+}
+
+// check derives the decision from the evaluation of the user, in the mode set with WithEvaluationMode
+// if any. Decisions allowed by the mode only are logged, so their effect can be reviewed before enforcing the policy.
+func (server *Server) check(policy *authz.Policy, user string, permission string, result *authz.PolicyEvaluationResult) *authz.CheckResult {
+	if server.mode != "" {
+		scoped := *policy
+		scoped.Mode = server.mode
+		policy = &scoped
+	}
+
+	check := policy.CheckEvaluated(user, permission, result)
+	if check.Allowed && check.Reason != "" {
+		server.logger.Info("decision allowed by the evaluation mode", "user", user, "permission", permission,
+			"reason", check.Reason, "mode", check.Mode)
+	}
+	return check
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/decisionlog"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/codes"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupDecisionServer(options ...Option) *Server {
+	manager := new(MockPolicyManager)
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups,
+		*authz.NewGroup("services", []string{"recipes"}),
+		*authz.NewGroup("cooks", []string{"alice"}),
+		*authz.NewGroup("bakers", []string{"carol"}))
+	policy.Permissions = append(policy.Permissions,
+		authz.Permission{Name: PermissionEvaluate, Groups: []string{"services"}},
+		authz.Permission{Name: "recipes.read", Groups: []string{"cooks"}},
+		authz.Permission{Name: "recipes.delete", Groups: []string{"cooks"}, Risk: authz.RiskHigh},
+		authz.Permission{Name: "recipes.edit", Groups: []string{"cooks"}, Condition: "resource.owner == user"},
+		authz.Permission{Name: "bakery.*", Groups: []string{"bakers"}, Risk: authz.RiskHigh})
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	return NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+}
+
+// fixedVersions is a PolicyVersions knowing a single version for every policy.
+type fixedVersions string
+
+func (version fixedVersions) Version(policy *authz.Policy) (string, bool) {
+	return string(version), true
+}
+
+func TestGetDecision(t *testing.T) {
+	t.Run("allowed", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "private, max-age=60", response.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, response.Header().Get("ETag"))
+		assert.Contains(t, response.Body.String(), `"allowed":true`)
+		assert.Contains(t, response.Body.String(), `"ttl_seconds":60`)
+	})
+
+	t.Run("high risk is not cached", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.delete", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "no-store", response.Header().Get("Cache-Control"))
+		assert.Contains(t, response.Body.String(), `"ttl_seconds":0`)
+	})
+
+	t.Run("high risk wildcard is not cached", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=carol&permission=bakery.ovens.delete", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "no-store", response.Header().Get("Cache-Control"))
+		assert.Contains(t, response.Body.String(), `"allowed":true`)
+		assert.Contains(t, response.Body.String(), `"ttl_seconds":0`)
+	})
+
+	t.Run("cached policy version", func(t *testing.T) {
+		server := setupDecisionServer(WithPolicyVersions(fixedVersions("0123456789abcdef")))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"0123456789abcdef"`, response.Header().Get("ETag"))
+		assert.Contains(t, response.Body.String(), `"policy_version":"0123456789abcdef"`)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		server := setupDecisionServer(WithDecisionTTLs(DecisionTTLs{Allow: time.Minute, Deny: 5 * time.Second}))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "private, max-age=5", response.Header().Get("Cache-Control"))
+		assert.Contains(t, response.Body.String(), `"allowed":false`)
+		assert.Contains(t, response.Body.String(), `"reason":"user_unknown"`)
+	})
+
+	t.Run("denial reasons", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=authz.write", "recipes", "")
+		assert.Contains(t, response.Body.String(), `"reason":"no_matching_group"`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.purge", "recipes", "")
+		assert.Contains(t, response.Body.String(), `"reason":"permission_unknown"`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+		assert.NotContains(t, response.Body.String(), `"reason"`)
+	})
+
+	t.Run("evaluation mode", func(t *testing.T) {
+		server := setupDecisionServer(WithEvaluationMode(authz.ModeDefaultAllow))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.purge", "recipes", "")
+		assert.Contains(t, response.Body.String(), `"allowed":true,"reason":"permission_unknown","mode":"default-allow"`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "")
+		assert.Contains(t, response.Body.String(), `"allowed":false,"reason":"user_unknown","mode":"default-allow"`)
+	})
+
+	t.Run("request attributes", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.edit&attributes="+
+			url.QueryEscape(`{"resource":{"owner":"alice"}}`), "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"allowed":true`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.edit&attributes="+
+			url.QueryEscape(`{"resource.owner":"bob"}`), "recipes", "")
+		assert.Contains(t, response.Body.String(), `"allowed":false,"reason":"condition_not_met"`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.edit", "recipes", "")
+		assert.Contains(t, response.Body.String(), `"reason":"condition_not_met"`)
+
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.edit&attributes=%5B%5D", "recipes", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("missing permission", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice", "recipes", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "viewer", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("decision log", func(t *testing.T) {
+		recorder := &recordingDecisions{}
+		now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		server := setupDecisionServer(WithDecisionLog(recorder), WithClock(NewFakeClock(now)))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Len(t, recorder.decisions, 1)
+		decision := recorder.decisions[0]
+		assert.NotEmpty(t, decision.ID)
+		assert.NotEmpty(t, decision.PolicyVersion)
+		decision.ID, decision.PolicyVersion = "", ""
+		assert.Equal(t, decisionlog.Decision{Time: now, Caller: "recipes", User: "bob", Permission: "recipes.read",
+			Reason: authz.DenialUserUnknown, Mode: authz.ModeDefaultDeny}, decision)
+
+		// a decision that cannot be recorded is still answered
+		recorder.err = decisionlog.ErrQueueFull
+		response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
+// recordingDecisions is a DecisionRecorder keeping the recorded decisions.
+type recordingDecisions struct {
+	decisions []decisionlog.Decision
+	err       error
+}
+
+func (recorder *recordingDecisions) Record(ctx context.Context, decision decisionlog.Decision) error {
+	recorder.decisions = append(recorder.decisions, decision)
+	return recorder.err
+}
+
+func TestGetDecision_Tracing(t *testing.T) {
+	recorder := NewSpanRecorder()
+	server := setupDecisionServer(WithTracerProvider(recorder))
+
+	response := serve(server, http.MethodGet, "/api/decisions?user=bob&permission=recipes.read", "recipes", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	span := recorder.Span("Server.getDecision")
+	if assert.NotNil(t, span) {
+		assert.True(t, span.Ended)
+		assert.Equal(t, "recipes.read", span.Attributes[tracing.PermissionKey].AsString())
+		assert.False(t, span.Attributes[tracing.AllowedKey].AsBool())
+		assert.Equal(t, "user_unknown", span.Attributes[tracing.ReasonKey].AsString())
+		assert.Equal(t, codes.Unset, span.Status)
+	}
+
+	response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read&trace=maybe", "recipes", "")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	spans := recorder.Spans()
+	assert.Equal(t, codes.Error, spans[len(spans)-1].Status)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// setPermissionDenialsRequest is the body of PUT /api/permissions/{id}/denials.
+type setPermissionDenialsRequest struct {
+	Groups []int    `json:"groups"`
+	Users  []string `json:"users"`
+}
+
+// setPermissionDenials replaces the groups and users explicitly denied a permission. An empty list
+// lifts the denials it covers, a missing one is rejected so a typo cannot lift them all.
+func (server *Server) setPermissionDenials(w http.ResponseWriter, r *http.Request) {
+	permissionId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid permission id")
+		return
+	}
+
+	var request setPermissionDenialsRequest
+	if err := decodeJSON(w, r, &request); err != nil || request.Groups == nil || request.Users == nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := server.manager.SetPermissionDenials(r.Context(), permissionId, request.Groups, request.Users); err != nil {
+		server.writePermissionError(w, r, permissionId, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
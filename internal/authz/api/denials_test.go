@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetPermissionDenials(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("SetPermissionDenials", mock.Anything, 3, []int{1}, []string{"mallory"}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/denials", "admin", `{"groups":[1],"users":["mallory"]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("lift every denial", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("SetPermissionDenials", mock.Anything, 3, []int{}, []string{}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/denials", "admin", `{"groups":[],"users":[]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("SetPermissionDenials", mock.Anything, 3, []int{9}, []string{}).Return(store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/denials", "admin", `{"groups":[9],"users":[]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("missing users", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/denials", "admin", `{"groups":[1]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		manager.AssertNotCalled(t, "SetPermissionDenials", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/denials", "bob", `{"groups":[],"users":[]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
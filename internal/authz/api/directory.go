@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/directory"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// directoryUser is a user of PUT /api/directory/users.
+type directoryUser struct {
+	ID      string `json:"id"`
+	Manager string `json:"manager"`
+}
+
+// putDirectoryUsersRequest is the body of PUT /api/directory/users.
+type putDirectoryUsersRequest struct {
+	Users []directoryUser `json:"users"`
+}
+
+// setGroupOwnersRequest is the body of PUT /api/groups/{id}/owners.
+type setGroupOwnersRequest struct {
+	Owners []string `json:"owners"`
+}
+
+// putDirectoryUsers records the attributes of users, typically pushed by the sync
+// connector of the user registry or identity provider.
+func (server *Server) putDirectoryUsers(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDirectory(w) {
+		return
+	}
+
+	var request putDirectoryUsersRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	now := server.clock.Now().UTC()
+	users := make([]directory.User, 0, len(request.Users))
+	for _, user := range request.Users {
+		id, err := store.NormalizeUserId(user.ID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "user id is empty")
+			return
+		}
+		manager := strings.TrimSpace(user.Manager)
+		if manager == id {
+			writeError(w, http.StatusBadRequest, "user "+id+" cannot be their own manager")
+			return
+		}
+		users = append(users, directory.User{ID: id, Manager: manager, UpdatedAt: now})
+	}
+
+	if err := server.directory.PutUsers(r.Context(), users); err != nil {
+		server.writeDirectoryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) getDirectoryUser(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDirectory(w) {
+		return
+	}
+
+	user, err := server.directory.GetUser(r.Context(), r.PathValue("user"))
+	if err != nil {
+		server.writeDirectoryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// setGroupOwners replaces the owners of the group, who approve the requests to join it.
+func (server *Server) setGroupOwners(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDirectory(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	var request setGroupOwnersRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	owners, err := store.NormalizeUserIds(request.Owners)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "owner user id is empty")
+		return
+	}
+
+	if err := server.directory.SetGroupOwners(r.Context(), groupId, owners); err != nil {
+		server.writeDirectoryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) getGroupOwners(w http.ResponseWriter, r *http.Request) {
+	if !server.requireDirectory(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	owners, err := server.directory.GroupOwners(r.Context(), groupId)
+	if err != nil {
+		server.writeDirectoryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]string{"owners": owners})
+}
+
+func (server *Server) requireDirectory(w http.ResponseWriter) bool {
+	if server.directory == nil {
+		writeError(w, http.StatusNotFound, "user directory is not configured")
+		return false
+	}
+	return true
+}
+
+// writeDirectoryError maps a directory store error to the matching HTTP status code.
+func (server *Server) writeDirectoryError(w http.ResponseWriter, err error) {
+	var storeErr *store.PolicyStoreError
+	switch {
+	case errors.Is(err, directory.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &storeErr):
+		server.writeStoreError(w, err)
+	default:
+		server.logger.Error("user directory failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
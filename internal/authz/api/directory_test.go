@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/directory"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockDirectoryStore is a mock implementation of the directory.Store interface
+type mockDirectoryStore struct {
+	mock.Mock
+}
+
+func (m *mockDirectoryStore) PutUsers(ctx context.Context, users []directory.User) error {
+	return m.Called(ctx, users).Error(0)
+}
+func (m *mockDirectoryStore) GetUser(ctx context.Context, id string) (*directory.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*directory.User)
+	return user, args.Error(1)
+}
+func (m *mockDirectoryStore) SetGroupOwners(ctx context.Context, groupId int, owners []string) error {
+	return m.Called(ctx, groupId, owners).Error(0)
+}
+func (m *mockDirectoryStore) GroupOwners(ctx context.Context, groupId int) ([]string, error) {
+	args := m.Called(ctx, groupId)
+	owners, _ := args.Get(0).([]string)
+	return owners, args.Error(1)
+}
+
+func setupDirectoryServer(now time.Time) (*MockPolicyManager, *mockDirectoryStore, *Server) {
+	manager := new(MockPolicyManager)
+	users := new(mockDirectoryStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithDirectory(users), WithClock(NewFakeClock(now)))
+
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	return manager, users, server
+}
+
+func TestPutDirectoryUsers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("PutUsers", mock.Anything, []directory.User{
+			{ID: "alice", Manager: "carol", UpdatedAt: now},
+			{ID: "carol", UpdatedAt: now},
+		}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/directory/users", "admin",
+			`{"users":[{"id":" alice ","manager":"carol"},{"id":"carol"}]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		users.AssertExpectations(t)
+	})
+
+	t.Run("own manager", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+
+		response := serve(server, http.MethodPut, "/api/directory/users", "admin", `{"users":[{"id":"alice","manager":"alice"}]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		users.AssertNotCalled(t, "PutUsers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires write permission", func(t *testing.T) {
+		_, _, server := setupDirectoryServer(now)
+
+		response := serve(server, http.MethodPut, "/api/directory/users", "viewer", `{"users":[]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/directory/users", "admin", `{"users":[]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestGetDirectoryUser(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("GetUser", mock.Anything, "alice").Return(&directory.User{ID: "alice", Manager: "carol", UpdatedAt: now}, nil)
+
+		response := serve(server, http.MethodGet, "/api/directory/users/alice", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"manager":"carol"`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("GetUser", mock.Anything, "nobody").Return(nil, directory.ErrNotFound)
+
+		response := serve(server, http.MethodGet, "/api/directory/users/nobody", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestGroupOwners(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("set", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("SetGroupOwners", mock.Anything, 10, []string{"carol", "dave"}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/owners", "admin", `{"owners":["carol"," dave"]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		users.AssertExpectations(t)
+	})
+
+	t.Run("set unknown group", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("SetGroupOwners", mock.Anything, 99, []string{"carol"}).Return(store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodPut, "/api/groups/99/owners", "admin", `{"owners":["carol"]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		_, users, server := setupDirectoryServer(now)
+		users.On("GroupOwners", mock.Anything, 10).Return([]string{"carol"}, nil)
+
+		response := serve(server, http.MethodGet, "/api/groups/10/owners", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"owners":["carol"]}`, response.Body.String())
+	})
+}
@@ -0,0 +1,483 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// folderRequest is the body of POST /api/folders and PUT /api/folders/{id}.
+type folderRequest struct {
+	Name     string            `json:"name"`
+	ParentID int               `json:"parent_id"`
+	Labels   map[string]string `json:"labels"`
+	Admins   []string          `json:"admins"`
+	// Whether the folder receives the permissions granted by its parent folders: inherit, block or empty.
+	Inheritance string `json:"inheritance"`
+}
+
+// setFolderPermissionsRequest is the body of PUT /api/folders/{id}/permissions.
+type setFolderPermissionsRequest struct {
+	Permissions []int `json:"permissions"`
+}
+
+// setFolderRequest is the body of PUT /api/groups/{id}/folder and PUT /api/catalogs/{application}/folder.
+type setFolderRequest struct {
+	// The folder to move into, 0 to move out of any folder.
+	FolderID int `json:"folder_id"`
+}
+
+// folderResponse is a folder returned by GET /api/folders, with what it inherits from its parents.
+type folderResponse struct {
+	folder.Folder
+	EffectiveLabels map[string]string `json:"effective_labels"`
+	EffectiveAdmins []string          `json:"effective_admins"`
+	Groups          []int             `json:"groups"`
+	Catalogs        []string          `json:"catalogs"`
+}
+
+// folderRef names a folder in the path and the subfolders of a folder.
+type folderRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// folderGroup is a group of a folder with the labels it inherits from the folder.
+type folderGroup struct {
+	store.GroupInfo[int]
+	EffectiveLabels map[string]string `json:"effective_labels"`
+}
+
+// folderDetails is the body returned by GET /api/folders/{id}.
+type folderDetails struct {
+	folder.Folder
+	Path            []folderRef       `json:"path"`
+	EffectiveLabels map[string]string `json:"effective_labels"`
+	EffectiveAdmins []string          `json:"effective_admins"`
+	Subfolders      []folderRef       `json:"subfolders"`
+	Groups          []folderGroup     `json:"groups"`
+	Catalogs        []string          `json:"catalogs"`
+}
+
+// listFolders returns every folder ordered by id, with the groups and catalogs it holds directly.
+func (server *Server) listFolders(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folders, err := server.folders.List(r.Context())
+	if err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+	assignments, err := server.folders.Assignments(r.Context())
+	if err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	tree := folder.NewTree(folders)
+	response := make([]folderResponse, 0, len(folders))
+	for _, f := range folders {
+		response = append(response, folderResponse{
+			Folder:          f,
+			EffectiveLabels: tree.Labels(f.ID),
+			EffectiveAdmins: tree.Admins(f.ID),
+			Groups:          folderGroups(assignments, f.ID),
+			Catalogs:        folderCatalogs(assignments, f.ID),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// getFolder returns a folder with its path from the top-level folder, its subfolders and its groups,
+// whose labels are merged with those inherited from the folder.
+func (server *Server) getFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folderId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return
+	}
+
+	tree, assignments, err := server.loadFolders(r.Context())
+	if err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+	f, ok := tree.Get(folderId)
+	if !ok {
+		server.writeFolderError(w, folder.ErrNotFound)
+		return
+	}
+
+	details := folderDetails{
+		Folder:          f,
+		Path:            []folderRef{},
+		EffectiveLabels: tree.Labels(folderId),
+		EffectiveAdmins: tree.Admins(folderId),
+		Subfolders:      []folderRef{},
+		Groups:          []folderGroup{},
+		Catalogs:        folderCatalogs(assignments, folderId),
+	}
+	for _, parent := range tree.Path(folderId) {
+		details.Path = append(details.Path, folderRef{ID: parent.ID, Name: parent.Name})
+	}
+	for _, childId := range tree.Children(folderId) {
+		child, _ := tree.Get(childId)
+		details.Subfolders = append(details.Subfolders, folderRef{ID: child.ID, Name: child.Name})
+	}
+
+	if groupIds := folderGroups(assignments, folderId); len(groupIds) > 0 {
+		groups, err := server.manager.GetGroups(r.Context(), groupIds)
+		if err != nil {
+			server.writeStoreError(w, err)
+			return
+		}
+		for _, group := range groups {
+			labels := maps.Clone(details.EffectiveLabels)
+			maps.Copy(labels, group.Labels)
+			details.Groups = append(details.Groups, folderGroup{GroupInfo: group, EffectiveLabels: labels})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+// createFolder creates a folder, top-level unless a parent folder is given.
+func (server *Server) createFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	f, ok := decodeFolderRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := server.folders.Create(r.Context(), f)
+	if err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+	f.ID = id
+
+	writeJSON(w, http.StatusCreated, f)
+}
+
+// updateFolder replaces the name, parent, labels, admins and inheritance of a folder.
+// Moving a folder into itself or one of its subfolders is refused with 409.
+func (server *Server) updateFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folderId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return
+	}
+	f, ok := decodeFolderRequest(w, r)
+	if !ok {
+		return
+	}
+	f.ID = folderId
+
+	if err := server.folders.Update(r.Context(), f); err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, f)
+}
+
+// deleteFolder deletes a folder. Folders still holding subfolders, groups or catalogs are refused with 409.
+func (server *Server) deleteFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folderId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return
+	}
+
+	if err := server.folders.Delete(r.Context(), folderId); err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setFolderPermissions replaces the permissions granted to the groups of a folder and, unless they
+// block inheritance, to the groups of its subfolders. High risk permissions are refused, since they
+// are only granted to groups once approved.
+func (server *Server) setFolderPermissions(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folderId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return
+	}
+	var request setFolderPermissionsRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// high risk grants go through the approval workflow, which folders would bypass
+	if len(request.Permissions) > 0 {
+		permissions, err := server.manager.GetPermissions(r.Context(), request.Permissions)
+		if err != nil {
+			server.writeStoreError(w, err)
+			return
+		}
+		if slices.ContainsFunc(permissions, func(permission store.PermissionInfo[int]) bool { return permission.Risk == authz.RiskHigh }) {
+			writeError(w, http.StatusBadRequest, "high risk permissions must be granted to groups directly")
+			return
+		}
+	}
+
+	if err := server.folders.SetPermissions(r.Context(), folderId, request.Permissions); err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setGroupFolder moves a group into a folder, or out of any folder. Delegated admins may only
+// move the groups they manage into another folder they administer.
+func (server *Server) setGroupFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+	folderId, ok := decodeSetFolderRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if IsDelegatedAdmin(r.Context()) {
+		tree, _, err := server.loadFolders(r.Context())
+		if err != nil {
+			server.writeFolderError(w, err)
+			return
+		}
+		identity, _ := IdentityFromContext(r.Context())
+		if !tree.IsAdmin(folderId, identity.User) {
+			writeError(w, http.StatusForbidden, "delegated admins may only move groups into folders they administer")
+			return
+		}
+	}
+
+	if err := server.folders.AssignGroup(r.Context(), groupId, folderId); err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setCatalogFolder moves the permission catalog of an application into a folder, or out of any folder.
+func (server *Server) setCatalogFolder(w http.ResponseWriter, r *http.Request) {
+	if !server.requireFolders(w) {
+		return
+	}
+
+	folderId, ok := decodeSetFolderRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := server.folders.AssignCatalog(r.Context(), r.PathValue("application"), folderId); err != nil {
+		server.writeFolderError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// administersGroup reports whether the user administers the folder holding the group in the id
+// path value. It delegates the management of groups for RequireGroupAdmin.
+func (server *Server) administersGroup(r *http.Request, user string) (bool, error) {
+	if server.folders == nil {
+		return false, nil
+	}
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return false, nil
+	}
+
+	tree, assignments, err := server.loadFolders(r.Context())
+	if err != nil {
+		return false, err
+	}
+	folderId, ok := assignments.Groups[groupId]
+	return ok && tree.IsAdmin(folderId, user), nil
+}
+
+// groupFolderFilter returns the ids of the groups held by the folder in the folder query parameter
+// or by its subfolders, nil when the parameter is not set.
+func (server *Server) groupFolderFilter(w http.ResponseWriter, r *http.Request) (map[int]bool, bool) {
+	value := r.URL.Query().Get("folder")
+	if value == "" {
+		return nil, true
+	}
+	if !server.requireFolders(w) {
+		return nil, false
+	}
+	folderId, err := strconv.Atoi(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return nil, false
+	}
+
+	tree, assignments, err := server.loadFolders(r.Context())
+	if err != nil {
+		server.writeFolderError(w, err)
+		return nil, false
+	}
+	if _, ok := tree.Get(folderId); !ok {
+		server.writeFolderError(w, folder.ErrNotFound)
+		return nil, false
+	}
+
+	subtree := tree.Subtree(folderId)
+	groups := map[int]bool{}
+	for groupId, holder := range assignments.Groups {
+		if slices.Contains(subtree, holder) {
+			groups[groupId] = true
+		}
+	}
+	return groups, true
+}
+
+func (server *Server) loadFolders(ctx context.Context) (*folder.Tree, *folder.Assignments, error) {
+	folders, err := server.folders.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignments, err := server.folders.Assignments(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return folder.NewTree(folders), assignments, nil
+}
+
+// folderGroups returns the sorted ids of the groups held directly by the folder.
+func folderGroups(assignments *folder.Assignments, folderId int) []int {
+	groups := []int{}
+	for groupId, holder := range assignments.Groups {
+		if holder == folderId {
+			groups = append(groups, groupId)
+		}
+	}
+	slices.Sort(groups)
+	return groups
+}
+
+// folderCatalogs returns the sorted applications whose catalogs are held directly by the folder.
+func folderCatalogs(assignments *folder.Assignments, folderId int) []string {
+	catalogs := []string{}
+	for application, holder := range assignments.Catalogs {
+		if holder == folderId {
+			catalogs = append(catalogs, application)
+		}
+	}
+	slices.Sort(catalogs)
+	return catalogs
+}
+
+func decodeFolderRequest(w http.ResponseWriter, r *http.Request) (folder.Folder, bool) {
+	var request folderRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return folder.Folder{}, false
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return folder.Folder{}, false
+	}
+	// slashes separate the names of the folders in the paths of authz.Folder
+	if strings.Contains(name, "/") {
+		writeError(w, http.StatusBadRequest, "name must not contain a slash")
+		return folder.Folder{}, false
+	}
+	inheritance, err := authz.ParseInheritance(request.Inheritance)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return folder.Folder{}, false
+	}
+	if request.ParentID < 0 {
+		writeError(w, http.StatusBadRequest, "invalid parent folder id")
+		return folder.Folder{}, false
+	}
+	admins, err := store.NormalizeUserIds(request.Admins)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "admin user id is empty")
+		return folder.Folder{}, false
+	}
+	return folder.Folder{Name: name, ParentID: request.ParentID, Labels: request.Labels, Admins: admins, Inheritance: inheritance}, true
+}
+
+func decodeSetFolderRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	var request setFolderRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return 0, false
+	}
+	if request.FolderID < 0 {
+		writeError(w, http.StatusBadRequest, "invalid folder id")
+		return 0, false
+	}
+	return request.FolderID, true
+}
+
+func (server *Server) requireFolders(w http.ResponseWriter) bool {
+	if server.folders == nil {
+		writeError(w, http.StatusNotFound, "folders are not configured")
+		return false
+	}
+	return true
+}
+
+// writeFolderError maps a folder store error to the matching HTTP status code.
+func (server *Server) writeFolderError(w http.ResponseWriter, err error) {
+	var storeErr *store.PolicyStoreError
+	switch {
+	case errors.Is(err, folder.ErrNotFound), errors.Is(err, catalog.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, folder.ErrNotEmpty), errors.Is(err, folder.ErrNameExists), errors.Is(err, folder.ErrCycle):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.As(err, &storeErr):
+		server.writeStoreError(w, err)
+	default:
+		server.logger.Error("folder store failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
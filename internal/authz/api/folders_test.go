@@ -0,0 +1,343 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockFolderStore is a mock implementation of the folder.Store interface
+type mockFolderStore struct {
+	mock.Mock
+}
+
+func (m *mockFolderStore) Create(ctx context.Context, f folder.Folder) (int, error) {
+	args := m.Called(ctx, f)
+	return args.Int(0), args.Error(1)
+}
+func (m *mockFolderStore) List(ctx context.Context) ([]folder.Folder, error) {
+	args := m.Called(ctx)
+	folders, _ := args.Get(0).([]folder.Folder)
+	return folders, args.Error(1)
+}
+func (m *mockFolderStore) Update(ctx context.Context, f folder.Folder) error {
+	return m.Called(ctx, f).Error(0)
+}
+func (m *mockFolderStore) Delete(ctx context.Context, id int) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *mockFolderStore) AssignGroup(ctx context.Context, groupId int, folderId int) error {
+	return m.Called(ctx, groupId, folderId).Error(0)
+}
+func (m *mockFolderStore) AssignCatalog(ctx context.Context, application string, folderId int) error {
+	return m.Called(ctx, application, folderId).Error(0)
+}
+func (m *mockFolderStore) SetPermissions(ctx context.Context, folderId int, permissionIds []int) error {
+	return m.Called(ctx, folderId, permissionIds).Error(0)
+}
+func (m *mockFolderStore) Assignments(ctx context.Context) (*folder.Assignments, error) {
+	args := m.Called(ctx)
+	assignments, _ := args.Get(0).(*folder.Assignments)
+	return assignments, args.Error(1)
+}
+
+// setupFolderServer stores the kitchen folder administered by chef, its pastry subfolder
+// administered by baker and the billing folder. Group 10 is in pastry and group 20 in billing.
+func setupFolderServer() (*MockPolicyManager, *mockFolderStore, *Server) {
+	manager := new(MockPolicyManager)
+	folders := new(mockFolderStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithFolders(folders))
+
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	folders.On("List", mock.Anything).Return([]folder.Folder{
+		{ID: 1, Name: "kitchen", Labels: map[string]string{"team": "kitchen", "tier": "1"}, Admins: []string{"chef"}},
+		{ID: 2, Name: "pastry", ParentID: 1, Labels: map[string]string{"tier": "2"}, Admins: []string{"baker"}},
+		{ID: 3, Name: "billing", Admins: []string{}},
+	}, nil).Maybe()
+	folders.On("Assignments", mock.Anything).Return(&folder.Assignments{
+		Groups:   map[int]int{10: 2, 20: 3},
+		Catalogs: map[string]int{"recipes": 1},
+	}, nil).Maybe()
+	return manager, folders, server
+}
+
+func TestListFolders(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodGet, "/api/folders", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[
+			{"id":1,"name":"kitchen","labels":{"team":"kitchen","tier":"1"},"admins":["chef"],
+			 "effective_labels":{"team":"kitchen","tier":"1"},"effective_admins":["chef"],"groups":[],"catalogs":["recipes"]},
+			{"id":2,"name":"pastry","parent_id":1,"labels":{"tier":"2"},"admins":["baker"],
+			 "effective_labels":{"team":"kitchen","tier":"2"},"effective_admins":["baker","chef"],"groups":[10],"catalogs":[]},
+			{"id":3,"name":"billing","admins":[],"effective_labels":{},"effective_admins":[],"groups":[20],"catalogs":[]}
+		]`, response.Body.String())
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager := new(MockPolicyManager)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodGet, "/api/folders", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestGetFolder(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, _, server := setupFolderServer()
+		manager.On("GetGroups", mock.Anything, []int{10}).Return([]store.GroupInfo[int]{
+			{ID: 10, Name: "bakers", Version: 1, Metadata: store.Metadata{Labels: map[string]string{"tier": "3"}}},
+		}, nil)
+
+		response := serve(server, http.MethodGet, "/api/folders/2", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{
+			"id":2,"name":"pastry","parent_id":1,"labels":{"tier":"2"},"admins":["baker"],
+			"path":[{"id":1,"name":"kitchen"},{"id":2,"name":"pastry"}],
+			"effective_labels":{"team":"kitchen","tier":"2"},"effective_admins":["baker","chef"],
+			"subfolders":[],
+			"groups":[{"id":10,"name":"bakers","version":1,"labels":{"tier":"3"},"effective_labels":{"team":"kitchen","tier":"3"}}],
+			"catalogs":[]
+		}`, response.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodGet, "/api/folders/42", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestCreateFolder(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+		folders.On("Create", mock.Anything, folder.Folder{Name: "bread", ParentID: 2, Admins: []string{"sous"}}).Return(4, nil)
+
+		response := serve(server, http.MethodPost, "/api/folders", "admin", `{"name":" bread ","parent_id":2,"admins":[" sous ","sous"]}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.JSONEq(t, `{"id":4,"name":"bread","parent_id":2,"admins":["sous"]}`, response.Body.String())
+	})
+
+	t.Run("name required", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+
+		response := serve(server, http.MethodPost, "/api/folders", "admin", `{"name":" "}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		folders.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("name exists", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+		folders.On("Create", mock.Anything, mock.Anything).Return(0, folder.ErrNameExists)
+
+		response := serve(server, http.MethodPost, "/api/folders", "admin", `{"name":"kitchen"}`)
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+
+	t.Run("requires write permission", func(t *testing.T) {
+		_, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodPost, "/api/folders", "chef", `{"name":"bread"}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+func TestUpdateFolder(t *testing.T) {
+	_, folders, server := setupFolderServer()
+	folders.On("Update", mock.Anything, folder.Folder{ID: 1, Name: "kitchen", ParentID: 2, Admins: []string{}}).Return(folder.ErrCycle)
+
+	response := serve(server, http.MethodPut, "/api/folders/1", "admin", `{"name":"kitchen","parent_id":2}`)
+	assert.Equal(t, http.StatusConflict, response.Code)
+	folders.AssertExpectations(t)
+}
+
+func TestUpdateFolder_Validation(t *testing.T) {
+	_, folders, server := setupFolderServer()
+
+	for _, body := range []string{`{"name":"kitchen/line"}`, `{"name":"kitchen","inheritance":"always"}`} {
+		response := serve(server, http.MethodPut, "/api/folders/1", "admin", body)
+		assert.Equal(t, http.StatusBadRequest, response.Code, body)
+	}
+	folders.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestSetFolderPermissions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, folders, server := setupFolderServer()
+		manager.On("GetPermissions", mock.Anything, []int{3, 5}).Return([]store.PermissionInfo[int]{
+			{ID: 3, Name: "recipes.read", Risk: authz.RiskLow},
+			{ID: 5, Name: "recipes.write", Risk: authz.RiskMedium},
+		}, nil)
+		folders.On("SetPermissions", mock.Anything, 1, []int{3, 5}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/folders/1/permissions", "admin", `{"permissions":[3,5]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		folders.AssertExpectations(t)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		manager, folders, server := setupFolderServer()
+		folders.On("SetPermissions", mock.Anything, 1, []int{}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/folders/1/permissions", "admin", `{"permissions":[]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		manager.AssertNotCalled(t, "GetPermissions", mock.Anything, mock.Anything)
+	})
+
+	t.Run("high risk", func(t *testing.T) {
+		manager, folders, server := setupFolderServer()
+		manager.On("GetPermissions", mock.Anything, []int{7}).Return([]store.PermissionInfo[int]{
+			{ID: 7, Name: "recipes.delete", Risk: authz.RiskHigh},
+		}, nil)
+
+		response := serve(server, http.MethodPut, "/api/folders/1/permissions", "admin", `{"permissions":[7]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		folders.AssertNotCalled(t, "SetPermissions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires write permission", func(t *testing.T) {
+		_, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodPut, "/api/folders/1/permissions", "chef", `{"permissions":[3]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+func TestDeleteFolder(t *testing.T) {
+	for name, test := range map[string]struct {
+		err      error
+		expected int
+	}{
+		"success":   {expected: http.StatusNoContent},
+		"not empty": {err: folder.ErrNotEmpty, expected: http.StatusConflict},
+		"not found": {err: folder.ErrNotFound, expected: http.StatusNotFound},
+		"failure":   {err: errors.New("db error"), expected: http.StatusInternalServerError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, folders, server := setupFolderServer()
+			folders.On("Delete", mock.Anything, 2).Return(test.err)
+
+			response := serve(server, http.MethodDelete, "/api/folders/2", "admin", "")
+			assert.Equal(t, test.expected, response.Code)
+		})
+	}
+}
+
+func TestSetGroupFolder(t *testing.T) {
+	t.Run("admin", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+		folders.On("AssignGroup", mock.Anything, 20, 0).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/20/folder", "admin", `{"folder_id":0}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		folders.AssertExpectations(t)
+	})
+
+	t.Run("delegated admin", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+		folders.On("AssignGroup", mock.Anything, 10, 1).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/folder", "chef", `{"folder_id":1}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		folders.AssertExpectations(t)
+	})
+
+	t.Run("delegated admin moving out of their folders", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+
+		for _, target := range []string{`{"folder_id":1}`, `{"folder_id":3}`, `{"folder_id":0}`} {
+			response := serve(server, http.MethodPut, "/api/groups/10/folder", "baker", target)
+			assert.Equal(t, http.StatusForbidden, response.Code, target)
+		}
+		folders.AssertNotCalled(t, "AssignGroup", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		_, folders, server := setupFolderServer()
+		folders.On("AssignGroup", mock.Anything, 99, 1).Return(store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodPut, "/api/groups/99/folder", "admin", `{"folder_id":1}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestSetCatalogFolder(t *testing.T) {
+	_, folders, server := setupFolderServer()
+	folders.On("AssignCatalog", mock.Anything, "recipes", 3).Return(nil)
+
+	response := serve(server, http.MethodPut, "/api/catalogs/recipes/folder", "admin", `{"folder_id":3}`)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	folders.AssertExpectations(t)
+}
+
+func TestRequireGroupAdmin(t *testing.T) {
+	t.Run("admin of a parent folder", func(t *testing.T) {
+		manager, _, server := setupFolderServer()
+		manager.On("UpdateGroupUsers", mock.Anything, 10, []string{"alice"}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/users", "chef", `{"users":["alice"]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("admin of another folder", func(t *testing.T) {
+		manager, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodPut, "/api/groups/20/users", "chef", `{"users":["alice"]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		manager.AssertNotCalled(t, "UpdateGroupUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("folder store failure", func(t *testing.T) {
+		manager := new(MockPolicyManager)
+		folders := new(mockFolderStore)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithFolders(folders))
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		folders.On("List", mock.Anything).Return(nil, errors.New("db error"))
+
+		response := serve(server, http.MethodPut, "/api/groups/10/users", "chef", `{"users":["alice"]}`)
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+
+	t.Run("folders not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/10/users", "chef", `{"users":["alice"]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+func TestListGroups_Folder(t *testing.T) {
+	t.Run("subtree", func(t *testing.T) {
+		manager, _, server := setupFolderServer()
+		manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{{ID: 10, Name: "bakers"}, {ID: 20, Name: "billing"}, {ID: 30, Name: "misc"}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/groups?folder=1", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"id":10,"name":"bakers","version":0}]`, response.Body.String())
+	})
+
+	t.Run("folder not found", func(t *testing.T) {
+		_, _, server := setupFolderServer()
+
+		response := serve(server, http.MethodGet, "/api/groups?folder=42", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
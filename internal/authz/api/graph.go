@@ -0,0 +1,53 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz/report"
+)
+
+// graphReport serves the policy graph in DOT or Mermaid, picked with the format query parameter
+// and DOT by default. Setting the users query parameter to false leaves the users out.
+func (server *Server) graphReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := report.GraphOptions{Format: report.GraphDOT}
+	if value := query.Get("format"); value != "" {
+		format, err := report.ParseGraphFormat(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		options.Format = format
+	}
+	if value := query.Get("users"); value != "" {
+		users, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid users value")
+			return
+		}
+		options.OmitUsers = !users
+	}
+
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	var document bytes.Buffer
+	if err := report.WriteGraph(&document, policy, options); err != nil {
+		server.logger.Error("failed to write policy graph", "format", options.Format, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	contentType := "text/vnd.graphviz; charset=utf-8"
+	if options.Format == report.GraphMermaid {
+		contentType = "text/vnd.mermaid; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(document.Bytes())
+}
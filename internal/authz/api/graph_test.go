@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphReport(t *testing.T) {
+	t.Run("dot", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/graph", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "text/vnd.graphviz; charset=utf-8", response.Header().Get("Content-Type"))
+		assert.Contains(t, response.Body.String(), `"user:alice" -> "group:cooks";`)
+	})
+
+	t.Run("mermaid without users", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/graph?format=mermaid&users=false", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "flowchart LR\n")
+		assert.NotContains(t, response.Body.String(), `"alice"`)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/graph?format=svg", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+)
+
+// guardrailUsage reports how close the users and groups of the policy are to the limits of the
+// guardrails, see guardrail.Usage. With status=warning or status=exceeded only the subjects
+// with that status are listed, and the rules without any are left out.
+func (server *Server) guardrailUsage(w http.ResponseWriter, r *http.Request) {
+	if server.guardrails == nil {
+		writeError(w, http.StatusNotFound, "guardrails are not configured")
+		return
+	}
+
+	status := guardrail.Status(r.URL.Query().Get("status"))
+	switch status {
+	case "", guardrail.StatusOK, guardrail.StatusWarning, guardrail.StatusExceeded:
+	default:
+		writeError(w, http.StatusBadRequest, "status must be ok, warning or exceeded")
+		return
+	}
+
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	usages, err := guardrail.Usage(server.guardrails, policy)
+	if err != nil {
+		server.logger.Error("failed to compute guardrail usage", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if status != "" {
+		filtered := []guardrail.RuleUsage{}
+		for _, usage := range usages {
+			usage.Subjects = slices.DeleteFunc(usage.Subjects, func(subject guardrail.SubjectUsage) bool { return subject.Status != status })
+			if len(usage.Subjects) > 0 {
+				filtered = append(filtered, usage)
+			}
+		}
+		usages = filtered
+	}
+
+	writeJSON(w, http.StatusOK, usages)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupGuardrailServer() *Server {
+	manager := new(MockPolicyManager)
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups, *authz.NewGroup("cooks", []string{"alice", "bob", "carol"}))
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	rules := []guardrail.Rule{
+		{Name: "members", Kind: guardrail.KindMaxGroupMembers, Limit: 2, Action: guardrail.ActionBlock, WarnPercent: 50},
+		{Name: "high-risk", Kind: guardrail.KindMaxHighRiskPermissions, Limit: 1, Action: guardrail.ActionBlock},
+	}
+	return NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithGuardrails(rules))
+}
+
+func TestGuardrailUsage(t *testing.T) {
+	t.Run("usage", func(t *testing.T) {
+		server := setupGuardrailServer()
+
+		response := serve(server, http.MethodGet, "/api/guardrails/usage", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var usages []guardrail.RuleUsage
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &usages))
+		assert.Equal(t, []guardrail.RuleUsage{
+			{Rule: "members", Kind: guardrail.KindMaxGroupMembers, Limit: 2, SoftLimit: 1, Subjects: []guardrail.SubjectUsage{
+				{Group: "cooks", Count: 3, Status: guardrail.StatusExceeded},
+				{Group: "admins", Count: 1, Status: guardrail.StatusWarning},
+				{Group: "viewers", Count: 1, Status: guardrail.StatusWarning},
+			}},
+			{Rule: "high-risk", Kind: guardrail.KindMaxHighRiskPermissions, Limit: 1, Subjects: []guardrail.SubjectUsage{}},
+		}, usages)
+	})
+
+	t.Run("by status", func(t *testing.T) {
+		server := setupGuardrailServer()
+
+		response := serve(server, http.MethodGet, "/api/guardrails/usage?status=exceeded", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"rule":"members","kind":"max_group_members","limit":2,"soft_limit":1,
+			"subjects":[{"group":"cooks","count":3,"status":"exceeded"}]}]`, response.Body.String())
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		server := setupGuardrailServer()
+
+		response := serve(server, http.MethodGet, "/api/guardrails/usage?status=full", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodGet, "/api/guardrails/usage", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("requires read", func(t *testing.T) {
+		server := setupGuardrailServer()
+
+		response := serve(server, http.MethodGet, "/api/guardrails/usage", "mallory", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
@@ -0,0 +1,429 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// createRequest is the body of POST /api/groups and POST /api/permissions.
+type createRequest struct {
+	Name string `json:"name"`
+}
+
+// createResponse is returned when a group or a permission is created.
+type createResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// updateUserGroupsRequest is the body of PUT /api/users/{user}/groups.
+type updateUserGroupsRequest struct {
+	Groups []int `json:"groups"`
+	// The source the memberships are attributed to, manual by default.
+	Source string `json:"source,omitempty"`
+}
+
+// updateGroupUsersRequest is the body of PUT /api/groups/{id}/users.
+type updateGroupUsersRequest struct {
+	Users []string `json:"users"`
+	// The source the memberships are attributed to, manual by default.
+	Source string `json:"source,omitempty"`
+}
+
+// updateGroupPermissionsRequest is the body of PUT /api/groups/{id}/permissions.
+type updateGroupPermissionsRequest struct {
+	Permissions   []int  `json:"permissions"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// updateGroupPermissionsResponse is returned when part of the update waits for approval.
+type updateGroupPermissionsResponse struct {
+	Approvals []approval.Request `json:"approvals"`
+}
+
+func (server *Server) getPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, policy)
+}
+
+func (server *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	ids, ok := parseIds(w, r)
+	if !ok {
+		return
+	}
+	inFolder, ok := server.groupFolderFilter(w, r)
+	if !ok {
+		return
+	}
+	outside := func(group store.GroupInfo[int]) bool { return inFolder != nil && !inFolder[group.ID] }
+
+	if ids != nil {
+		groups, err := server.manager.GetGroups(r.Context(), ids)
+		if err != nil {
+			server.writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, slices.DeleteFunc(groups, outside))
+		return
+	}
+
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+	groups, err := server.manager.ListGroups(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+	// the folder filter applies before paging, so pages are full
+	groups = slices.DeleteFunc(groups, outside)
+
+	groups, next, err := paging.Page(groups, page, func(group store.GroupInfo[int]) paging.Key { return paging.Key{group.ID} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, groups, next)
+}
+
+func (server *Server) listPermissions(w http.ResponseWriter, r *http.Request) {
+	ids, ok := parseIds(w, r)
+	if !ok {
+		return
+	}
+
+	if ids != nil {
+		permissions, err := server.manager.GetPermissions(r.Context(), ids)
+		if err != nil {
+			server.writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, permissions)
+		return
+	}
+
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+	permissions, err := server.manager.ListPermissions(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	permissions, next, err := paging.Page(permissions, page, func(permission store.PermissionInfo[int]) paging.Key { return paging.Key{permission.ID} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, permissions, next)
+}
+
+// maxBatchIds is the most ids a single batch read may ask for.
+const maxBatchIds = 500
+
+// parseIds parses the comma separated ids query parameter, such as ids=1,2,3, used to read
+// several groups or permissions at once. It returns nil when the parameter is missing, and writes
+// a bad request response and returns false when it is malformed.
+func parseIds(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	if !r.URL.Query().Has("ids") {
+		return nil, true
+	}
+
+	ids := []int{}
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid id "+strconv.Quote(part))
+			return nil, false
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) > maxBatchIds {
+		writeError(w, http.StatusBadRequest, "too many ids, the maximum is "+strconv.Itoa(maxBatchIds))
+		return nil, false
+	}
+	return ids, true
+}
+
+// decodeCreateRequest decodes the body of a creation, writing a bad request response
+// and returning false when it is malformed or the name is empty.
+func decodeCreateRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request createRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return "", false
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return "", false
+	}
+	return name, true
+}
+
+func (server *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeCreateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := server.manager.CreateGroup(r.Context(), name)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createResponse{ID: id, Name: name})
+}
+
+func (server *Server) createPermission(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeCreateRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := authz.ValidateWildcard(name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := server.manager.CreatePermission(r.Context(), name)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createResponse{ID: id, Name: name})
+}
+
+// deleteGroup deletes the group with its memberships and grants, returning how many were removed.
+func (server *Server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	deletion, err := server.manager.DeleteGroup(r.Context(), groupId)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deletion)
+}
+
+// getGroup returns the group with its members and the permissions granted to it.
+func (server *Server) getGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	group, err := server.manager.GetGroup(r.Context(), groupId)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+// getUserGroups returns the groups the user is a member of, none for an unknown user.
+func (server *Server) getUserGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := server.manager.GetUserGroups(r.Context(), r.PathValue("user"))
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// deleteUser removes the user from every group.
+func (server *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := server.manager.DeleteUser(r.Context(), r.PathValue("user")); err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) updateUserGroups(w http.ResponseWriter, r *http.Request) {
+	var request updateUserGroupsRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	source, err := store.ParseMembershipSource(request.Source)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := store.WithMembershipSource(r.Context(), source)
+	if err := server.manager.UpdateUserGroups(ctx, r.PathValue("user"), request.Groups); err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) updateGroupUsers(w http.ResponseWriter, r *http.Request) {
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	var request updateGroupUsersRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	source, err := store.ParseMembershipSource(request.Source)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := store.WithMembershipSource(r.Context(), source)
+	if err := server.manager.UpdateGroupUsers(ctx, groupId, request.Users); err != nil {
+		server.writeGroupError(w, r, groupId, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) updateGroupPermissions(w http.ResponseWriter, r *http.Request) {
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	var request updateGroupPermissionsRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// high risk permissions the group does not hold yet are only granted once approved
+	additions, err := server.highRiskAdditions(r.Context(), groupId, request.Permissions)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	if len(additions) > 0 {
+		if !server.requireMFA(w, r, identity, "grant_high_risk", "multi-factor authentication is required to grant high risk permissions") {
+			return
+		}
+		if server.approvals == nil {
+			writeError(w, http.StatusForbidden, "granting high risk permissions requires an approval workflow")
+			return
+		}
+	}
+
+	permissions := slices.DeleteFunc(slices.Clone(request.Permissions), func(permissionId int) bool {
+		return slices.Contains(additions, permissionId)
+	})
+	if err := server.manager.UpdateGroupPermissions(r.Context(), groupId, permissions); err != nil {
+		server.writeGroupError(w, r, groupId, err)
+		return
+	}
+
+	if len(additions) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response := updateGroupPermissionsResponse{Approvals: []approval.Request{}}
+	for _, permissionId := range additions {
+		pending, err := server.approvals.Submit(r.Context(), approval.Request{
+			Kind:          approval.KindPermissionGrant,
+			GroupID:       groupId,
+			PermissionID:  permissionId,
+			RequestedBy:   identity.User,
+			Justification: request.Justification,
+		})
+		if err != nil {
+			server.logger.Error("failed to submit approval request", "group_id", groupId, "permission_id", permissionId, "error", err)
+			writeError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		response.Approvals = append(response.Approvals, *pending)
+	}
+
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// highRiskAdditions returns the requested high risk permissions the group is not granted yet.
+func (server *Server) highRiskAdditions(ctx context.Context, groupId int, requested []int) ([]int, error) {
+	groups, err := server.manager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := slices.IndexFunc(groups, func(group store.GroupInfo[int]) bool { return group.ID == groupId })
+	if index < 0 {
+		return nil, store.NewGroupNotFoundError()
+	}
+	groupName := groups[index].Name
+
+	permissions, err := server.manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.manager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := shared.NewSet[string]()
+	for _, permission := range policy.Permissions {
+		if slices.Contains(permission.Groups, groupName) {
+			granted.Add(permission.Name)
+		}
+	}
+
+	additions := []int{}
+	for _, permission := range permissions {
+		if permission.Risk != authz.RiskHigh || !slices.Contains(requested, permission.ID) {
+			continue
+		}
+		if !granted.Contains(permission.Name) {
+			additions = append(additions, permission.ID)
+		}
+	}
+
+	return additions, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// healthResponse is the body returned by the readiness probe.
+type healthResponse struct {
+	Status        string `json:"status"`
+	LatencyMs     int64  `json:"latency_ms,omitempty"`
+	SchemaVersion int    `json:"schema_version,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// HealthHandler returns the readiness probe handler. It reports 200 when the policy store
+// is reachable and its schema is usable, and 503 otherwise. A store serving a read-only copy
+// of the policy is reported as degraded with 200, as it can still answer evaluations.
+// It requires no authentication.
+func (server *Server) HealthHandler() http.Handler {
+	return http.HandlerFunc(server.health)
+}
+
+func (server *Server) health(w http.ResponseWriter, r *http.Request) {
+	health, err := server.manager.Health(r.Context())
+	if err != nil {
+		response := healthResponse{Status: "unavailable", Error: "policy store is unavailable"}
+		var versionErr *store.SchemaVersionError
+		var storeErr *store.PolicyStoreError
+		if errors.As(err, &versionErr) {
+			response.Error = versionErr.Error()
+		} else if errors.As(err, &storeErr) {
+			response.Error = storeErr.Error()
+		}
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	status := "ok"
+	if health.Degraded {
+		status = "degraded"
+	}
+	writeJSON(w, http.StatusOK, healthResponse{Status: status, LatencyMs: health.Latency.Milliseconds(), SchemaVersion: health.SchemaVersion})
+}
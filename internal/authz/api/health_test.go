@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("Health", mock.Anything).Return(&store.Health{Latency: 3 * time.Millisecond, SchemaVersion: 1}, nil)
+
+		response := serve(server, http.MethodGet, "/api/health", "", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"status":"ok","latency_ms":3,"schema_version":1}`, response.Body.String())
+
+		manager.AssertNotCalled(t, "ReadPolicy", mock.Anything)
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("Health", mock.Anything).Return(&store.Health{Degraded: true}, nil)
+
+		response := serve(server, http.MethodGet, "/api/health", "", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"status":"degraded"}`, response.Body.String())
+	})
+
+	t.Run("schema mismatch", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("Health", mock.Anything).Return(nil, store.NewSchemaMismatchError())
+
+		response := serve(server, http.MethodGet, "/api/health", "", "")
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.Contains(t, response.Body.String(), "schema")
+	})
+
+	t.Run("unsupported schema version", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("Health", mock.Anything).Return(nil, &store.SchemaVersionError{Found: 3, Min: 1, Max: 2})
+
+		response := serve(server, http.MethodGet, "/api/health", "", "")
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.Contains(t, response.Body.String(), "database schema version 3 is newer")
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// impersonationResponse is the body returned by GET /api/users/{user}/evaluation.
+type impersonationResponse struct {
+	User string `json:"user"`
+	*authz.PolicyEvaluationResult
+}
+
+// impersonateEvaluation evaluates the policy as the user in the request path, so support
+// engineers can see what that user would be granted without using their credentials.
+// Every impersonated evaluation is audited and the request fails if the audit event cannot be recorded.
+func (server *Server) impersonateEvaluation(w http.ResponseWriter, r *http.Request) {
+	identity, _ := IdentityFromContext(r.Context())
+	actor := identity.User
+	user := r.PathValue("user")
+
+	policy, ok := server.readEvaluationPolicy(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := policy.Evaluate(user)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = server.audit.Record(r.Context(), audit.Event{
+		ID:      id.New(),
+		Time:    server.clock.Now(),
+		Actor:   actor,
+		Action:  "impersonate.evaluate",
+		Subject: user,
+		Details: map[string]any{"groups": result.Groups, "permissions": result.Permissions},
+	})
+	if err != nil {
+		server.logger.Error("failed to record impersonation audit event", "actor", actor, "user", user, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, impersonationResponse{User: user, PolicyEvaluationResult: result})
+}
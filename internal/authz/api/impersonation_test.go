@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockAuditSink is a mock implementation of the audit.Sink interface
+type mockAuditSink struct {
+	mock.Mock
+}
+
+func (m *mockAuditSink) Record(ctx context.Context, event audit.Event) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func impersonationPolicy() *authz.Policy {
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups,
+		*authz.NewGroup("support", []string{"engineer"}),
+		*authz.NewGroup("cooks", []string{"alice"}))
+	policy.Permissions = append(policy.Permissions,
+		*authz.NewPermission(PermissionImpersonate, []string{"support"}),
+		*authz.NewPermission("recipes.read", []string{"cooks"}))
+	return policy
+}
+
+func TestImpersonateEvaluation(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	setup := func() (*MockPolicyManager, *mockAuditSink, *Server) {
+		manager := new(MockPolicyManager)
+		sink := new(mockAuditSink)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithAuditSink(sink), WithClock(NewFakeClock(now)))
+		return manager, sink, server
+	}
+
+	t.Run("success", func(t *testing.T) {
+		manager, sink, server := setup()
+		manager.On("ReadPolicy", mock.Anything).Return(impersonationPolicy(), nil)
+		sink.On("Record", mock.Anything, mock.MatchedBy(func(event audit.Event) bool {
+			_, err := id.Time(event.ID)
+			event.ID = ""
+			return err == nil && assert.ObjectsAreEqual(audit.Event{
+				Time:    now,
+				Actor:   "engineer",
+				Action:  "impersonate.evaluate",
+				Subject: "alice",
+				Details: map[string]any{"groups": []string{"cooks"}, "permissions": []string{"recipes.read"}},
+			}, event)
+		})).Return(nil)
+
+		response := serve(server, http.MethodGet, "/api/users/alice/evaluation", "engineer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"user":"alice","groups":["cooks"],"permissions":["recipes.read"]}`, response.Body.String())
+
+		manager.AssertExpectations(t)
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		manager, sink, server := setup()
+		manager.On("ReadPolicy", mock.Anything).Return(impersonationPolicy(), nil)
+		sink.On("Record", mock.Anything, mock.MatchedBy(func(event audit.Event) bool {
+			return event.Action == "access.denied"
+		})).Return(nil)
+
+		response := serve(server, http.MethodGet, "/api/users/alice/evaluation", "admin", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		// only the denial is audited, nothing was impersonated
+		sink.AssertNumberOfCalls(t, "Record", 1)
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("audit failure", func(t *testing.T) {
+		manager, sink, server := setup()
+		manager.On("ReadPolicy", mock.Anything).Return(impersonationPolicy(), nil)
+		sink.On("Record", mock.Anything, mock.Anything).Return(errors.New("sink error"))
+
+		response := serve(server, http.MethodGet, "/api/users/alice/evaluation", "engineer", "")
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+		assert.NotContains(t, response.Body.String(), "recipes.read")
+
+		sink.AssertExpectations(t)
+	})
+}
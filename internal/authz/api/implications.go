@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// setPermissionImplicationsRequest is the body of PUT /api/permissions/{id}/implies.
+type setPermissionImplicationsRequest struct {
+	Implies []int `json:"implies"`
+}
+
+// setPermissionImplications replaces the permissions implied by a permission. Implying a high
+// risk permission grants it to every holder of the implying one, so it requires multi-factor authentication.
+func (server *Server) setPermissionImplications(w http.ResponseWriter, r *http.Request) {
+	permissionId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid permission id")
+		return
+	}
+
+	var request setPermissionImplicationsRequest
+	if err := decodeJSON(w, r, &request); err != nil || request.Implies == nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	permissions, err := server.manager.ListPermissions(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+	risks := make(map[int]authz.RiskLevel, len(permissions))
+	for _, permission := range permissions {
+		risks[permission.ID] = permission.Risk
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	for _, implied := range request.Implies {
+		if risks[implied] == authz.RiskHigh &&
+			!server.requireMFA(w, r, identity, "imply_high_risk", "multi-factor authentication is required to imply high risk permissions") {
+			return
+		}
+	}
+
+	if err := server.manager.SetPermissionImplications(r.Context(), permissionId, request.Implies); err != nil {
+		server.writePermissionError(w, r, permissionId, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetPermissionImplications(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionImplications", mock.Anything, 3, []int{1}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[1]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("implying high risk requires mfa", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[1,2]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		manager.AssertNotCalled(t, "SetPermissionImplications", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("high risk with mfa", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionImplications", mock.Anything, 3, []int{2}).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[2]}`, mfa)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionImplications", mock.Anything, 2, []int{1}).Return(store.NewInvalidArgumentError())
+
+		response := serve(server, http.MethodPut, "/api/permissions/2/implies", "admin", `{"implies":[1]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("missing implies", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/1/implies", "admin", `{}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
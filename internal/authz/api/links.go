@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+const (
+	// defaultRequestLinkTTL is how long request links are valid when the creator sets no lifetime.
+	defaultRequestLinkTTL = 7 * 24 * time.Hour
+	// maxRequestLinkTTL bounds the lifetime of request links, so forgotten links do not stay usable.
+	maxRequestLinkTTL = 30 * 24 * time.Hour
+)
+
+// createRequestLinkRequest is the body of POST /api/groups/{id}/request-links.
+type createRequestLinkRequest struct {
+	// How long the link is valid, such as 72h, 7 days when empty.
+	TTL           string `json:"ttl"`
+	Justification string `json:"justification"`
+}
+
+// requestLinkResponse is the body returned by POST /api/groups/{id}/request-links.
+type requestLinkResponse struct {
+	selfservice.Link
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// resolvedRequestLink is the body returned by GET /api/access/links/{token}: the access
+// request pre-filled by the link, to be submitted with POST /api/access/requests.
+type resolvedRequestLink struct {
+	LinkID        string           `json:"link_id"`
+	Group         requestableGroup `json:"group"`
+	Justification string           `json:"justification,omitempty"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+}
+
+// createRequestLink creates a shareable link asking its recipients to request access to a self-service group.
+func (server *Server) createRequestLink(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRequestLinks(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	var request createRequestLinkRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	ttl := defaultRequestLinkTTL
+	if request.TTL != "" {
+		ttl, err = time.ParseDuration(request.TTL)
+		if err != nil || ttl <= 0 || ttl > maxRequestLinkTTL {
+			writeError(w, http.StatusBadRequest, "ttl must be a positive duration of at most "+maxRequestLinkTTL.String())
+			return
+		}
+	}
+
+	// only groups offered for self-service can be requested through a link
+	if _, err := server.selfService.Get(r.Context(), groupId); err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	now := server.clock.Now().UTC()
+	link := selfservice.Link{
+		ID:            id.New(),
+		GroupID:       groupId,
+		Justification: strings.TrimSpace(request.Justification),
+		CreatedBy:     identity.User,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	}
+	if err := server.links.CreateLink(r.Context(), link); err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+	server.auditRequestLink(r.Context(), identity.User, "request_link.create", link)
+
+	token := server.linkSigner.Sign(link)
+	writeJSON(w, http.StatusCreated, requestLinkResponse{Link: link, Token: token, URL: server.requestLinkURL(token)})
+}
+
+// listRequestLinks returns the request links to a group, most recent first, including the expired and revoked ones.
+func (server *Server) listRequestLinks(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRequestLinks(w) {
+		return
+	}
+
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	links, err := server.links.ListLinks(r.Context(), groupId)
+	if err != nil {
+		server.writeRequestLinkError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, links)
+}
+
+// revokeRequestLink revokes a request link, so it can no longer pre-fill or submit access requests.
+// Requests already submitted through the link are left for the approvers to decide.
+func (server *Server) revokeRequestLink(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRequestLinks(w) {
+		return
+	}
+
+	linkId := r.PathValue("id")
+	link, err := server.links.GetLink(r.Context(), linkId)
+	if err != nil {
+		server.writeRequestLinkError(w, err)
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	if err := server.links.RevokeLink(r.Context(), linkId, identity.User, server.clock.Now().UTC()); err != nil {
+		server.writeRequestLinkError(w, err)
+		return
+	}
+	server.auditRequestLink(r.Context(), identity.User, "request_link.revoke", *link)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveRequestLink returns the access request pre-filled by the link with the given token,
+// for the caller to review and submit.
+func (server *Server) resolveRequestLink(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRequestLinks(w) {
+		return
+	}
+
+	link, err := server.openRequestLink(r.Context(), r.PathValue("token"))
+	if err != nil {
+		server.writeRequestLinkError(w, err)
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	groups, err := server.requestableGroups(r.Context(), identity.User)
+	if err != nil {
+		server.writeSelfServiceError(w, err)
+		return
+	}
+	index := slices.IndexFunc(groups, func(group requestableGroup) bool { return group.GroupID == link.GroupID })
+	if index < 0 {
+		server.writeSelfServiceError(w, selfservice.ErrNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resolvedRequestLink{
+		LinkID:        link.ID,
+		Group:         groups[index],
+		Justification: link.Justification,
+		ExpiresAt:     link.ExpiresAt,
+	})
+}
+
+// openRequestLink verifies the token and returns its link, failing when the link expired or was revoked.
+func (server *Server) openRequestLink(ctx context.Context, token string) (*selfservice.Link, error) {
+	now := server.clock.Now()
+	linkId, groupId, err := server.linkSigner.Verify(token, now)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := server.links.GetLink(ctx, linkId)
+	if err != nil {
+		return nil, err
+	}
+	if link.GroupID != groupId {
+		return nil, selfservice.ErrInvalidToken
+	}
+	if err := link.Check(now); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// requestLinkURL returns the shareable URL of the link with the given token: the configured
+// frontend page with the token in the link query parameter, or the API resolving it.
+func (server *Server) requestLinkURL(token string) string {
+	if server.linkURL == "" {
+		return "/api/access/links/" + token
+	}
+
+	page, err := url.Parse(server.linkURL)
+	if err != nil {
+		return server.linkURL + "?link=" + token
+	}
+	query := page.Query()
+	query.Set("link", token)
+	page.RawQuery = query.Encode()
+	return page.String()
+}
+
+// auditRequestLink records the creation or the revocation of a request link. A failure is only logged.
+func (server *Server) auditRequestLink(ctx context.Context, actor string, action string, link selfservice.Link) {
+	err := server.audit.Record(ctx, audit.Event{
+		ID:      id.New(),
+		Time:    server.clock.Now(),
+		Actor:   actor,
+		Action:  action,
+		Subject: link.ID,
+		Details: map[string]any{"group_id": link.GroupID, "expires_at": link.ExpiresAt},
+	})
+	if err != nil {
+		server.logger.Error("failed to record request link audit event", "action", action, "link_id", link.ID, "error", err)
+	}
+}
+
+func (server *Server) requireRequestLinks(w http.ResponseWriter) bool {
+	if !server.requireSelfService(w) {
+		return false
+	}
+	if server.links == nil || server.linkSigner == nil {
+		writeError(w, http.StatusNotFound, "request links are not configured")
+		return false
+	}
+	return true
+}
+
+// writeRequestLinkError maps a request link error to the matching HTTP status code.
+// Links that can no longer be used are reported as gone.
+func (server *Server) writeRequestLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, selfservice.ErrInvalidToken), errors.Is(err, selfservice.ErrLinkNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, selfservice.ErrLinkExpired), errors.Is(err, selfservice.ErrLinkRevoked):
+		writeError(w, http.StatusGone, err.Error())
+	default:
+		server.writeSelfServiceError(w, err)
+	}
+}
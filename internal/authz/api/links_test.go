@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var linkTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// setupRequestLinkServer serves the self-service group "cooks" (id 10) with request links
+// signed by the returned signer, and a link "link-1" to it pre-filling a justification.
+func setupRequestLinkServer(t *testing.T) (*mockApprovalStore, *mockSelfServiceStore, *selfservice.Signer, *Server) {
+	_, approvals, groups, server := setupSelfServiceServer()
+	signer, err := selfservice.NewSigner([]byte(strings.Repeat("k", selfservice.MinSignerKeySize)))
+	assert.NoError(t, err)
+	WithRequestLinks(groups, signer, "https://access.example.org/request?source=mail")(server)
+	server.clock = NewFakeClock(linkTime)
+
+	groups.On("GetLink", mock.Anything, "link-1").Return(&selfservice.Link{
+		ID: "link-1", GroupID: 10, Justification: "new cooks", CreatedBy: "admin", CreatedAt: linkTime, ExpiresAt: linkTime.Add(time.Hour),
+	}, nil).Maybe()
+	return approvals, groups, signer, server
+}
+
+func TestCreateRequestLink(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, groups, signer, server := setupRequestLinkServer(t)
+		groups.On("Get", mock.Anything, 10).Return(&selfservice.Group{GroupID: 10}, nil)
+		groups.On("CreateLink", mock.Anything, mock.MatchedBy(func(link selfservice.Link) bool {
+			return link.ID != "" && link.GroupID == 10 && link.Justification == "new cooks" && link.CreatedBy == "admin" &&
+				link.CreatedAt.Equal(linkTime) && link.ExpiresAt.Equal(linkTime.Add(72*time.Hour))
+		})).Return(nil)
+
+		response := serve(server, http.MethodPost, "/api/groups/10/request-links", "admin", `{"ttl":"72h","justification":" new cooks "}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+
+		var body requestLinkResponse
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Equal(t, signer.Sign(body.Link), body.Token)
+		assert.Equal(t, "https://access.example.org/request?link="+body.Token+"&source=mail", body.URL)
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("default ttl and api url", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+		server.linkURL = ""
+		groups.On("Get", mock.Anything, 10).Return(&selfservice.Group{GroupID: 10}, nil)
+		groups.On("CreateLink", mock.Anything, mock.MatchedBy(func(link selfservice.Link) bool {
+			return link.ExpiresAt.Equal(linkTime.Add(defaultRequestLinkTTL))
+		})).Return(nil)
+
+		response := serve(server, http.MethodPost, "/api/groups/10/request-links", "admin", `{}`)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Contains(t, response.Body.String(), `"url":"/api/access/links/`)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+
+		for _, ttl := range []string{"forever", "-1h", "721h"} {
+			response := serve(server, http.MethodPost, "/api/groups/10/request-links", "admin", `{"ttl":"`+ttl+`"}`)
+			assert.Equal(t, http.StatusBadRequest, response.Code, ttl)
+		}
+		groups.AssertNotCalled(t, "CreateLink", mock.Anything, mock.Anything)
+	})
+
+	t.Run("group not offered", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+		groups.On("Get", mock.Anything, 11).Return(nil, selfservice.ErrNotFound)
+
+		response := serve(server, http.MethodPost, "/api/groups/11/request-links", "admin", `{}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		groups.AssertNotCalled(t, "CreateLink", mock.Anything, mock.Anything)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		_, _, _, server := setupRequestLinkServer(t)
+
+		response := serve(server, http.MethodPost, "/api/groups/10/request-links", "viewer", `{}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		_, _, _, server := setupSelfServiceServer()
+
+		response := serve(server, http.MethodPost, "/api/groups/10/request-links", "admin", `{}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestListRequestLinks(t *testing.T) {
+	_, groups, _, server := setupRequestLinkServer(t)
+	groups.On("ListLinks", mock.Anything, 10).Return([]selfservice.Link{{ID: "link-1", GroupID: 10, CreatedBy: "admin", CreatedAt: linkTime, ExpiresAt: linkTime}}, nil)
+
+	response := serve(server, http.MethodGet, "/api/groups/10/request-links", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":"link-1","group_id":10,"created_by":"admin","created_at":"2024-03-01T12:00:00Z","expires_at":"2024-03-01T12:00:00Z"}]`, response.Body.String())
+}
+
+func TestRevokeRequestLink(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+		groups.On("RevokeLink", mock.Anything, "link-1", "admin", linkTime).Return(nil)
+
+		response := serve(server, http.MethodDelete, "/api/request-links/link-1", "admin", "")
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		groups.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+		groups.On("GetLink", mock.Anything, "link-2").Return(nil, selfservice.ErrLinkNotFound)
+
+		response := serve(server, http.MethodDelete, "/api/request-links/link-2", "admin", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestResolveRequestLink(t *testing.T) {
+	link := selfservice.Link{ID: "link-1", GroupID: 10, ExpiresAt: linkTime.Add(time.Hour)}
+
+	t.Run("success", func(t *testing.T) {
+		_, _, signer, server := setupRequestLinkServer(t)
+
+		response := serve(server, http.MethodGet, "/api/access/links/"+signer.Sign(link), "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"link_id":"link-1","justification":"new cooks","expires_at":"2024-03-01T13:00:00Z",
+			"group":{"group_id":10,"name":"cooks","description":"Recipe authors","member":false,"permissions":[
+				{"id":1,"name":"recipes.read","risk":"low"},
+				{"id":2,"name":"recipes.delete","risk":"high"}
+			]}}`, response.Body.String())
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		_, groups, signer, server := setupRequestLinkServer(t)
+		revoked := link
+		revoked.ID, revoked.RevokedAt = "link-2", linkTime
+		groups.On("GetLink", mock.Anything, "link-2").Return(&revoked, nil)
+
+		response := serve(server, http.MethodGet, "/api/access/links/"+signer.Sign(revoked), "viewer", "")
+		assert.Equal(t, http.StatusGone, response.Code)
+		assert.Contains(t, response.Body.String(), "request link revoked")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, groups, signer, server := setupRequestLinkServer(t)
+		token := signer.Sign(link)
+		server.clock.(*FakeClock).Advance(time.Hour)
+
+		response := serve(server, http.MethodGet, "/api/access/links/"+token, "viewer", "")
+		assert.Equal(t, http.StatusGone, response.Code)
+		groups.AssertNotCalled(t, "GetLink", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forged", func(t *testing.T) {
+		_, groups, _, server := setupRequestLinkServer(t)
+		other, _ := selfservice.NewSigner([]byte(strings.Repeat("o", selfservice.MinSignerKeySize)))
+
+		response := serve(server, http.MethodGet, "/api/access/links/"+other.Sign(link), "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		groups.AssertNotCalled(t, "GetLink", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, _, signer, server := setupRequestLinkServer(t)
+
+		response := serve(server, http.MethodGet, "/api/access/links/"+signer.Sign(link), "", "")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+}
+
+func TestSubmitAccessRequest_Link(t *testing.T) {
+	link := selfservice.Link{ID: "link-1", GroupID: 10, ExpiresAt: linkTime.Add(time.Hour)}
+
+	t.Run("pre-filled justification", func(t *testing.T) {
+		approvals, _, signer, server := setupRequestLinkServer(t)
+		approvals.On("ListRequestedBy", mock.Anything, "viewer").Return([]approval.Request{}, nil)
+		approvals.On("Create", mock.Anything, mock.MatchedBy(func(request approval.Request) bool {
+			return request.GroupID == 10 && request.UserID == "viewer" && request.Justification == "new cooks"
+		})).Return(7, nil)
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"link":"`+signer.Sign(link)+`"}`)
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		approvals.AssertExpectations(t)
+	})
+
+	t.Run("combined with a group", func(t *testing.T) {
+		_, _, signer, server := setupRequestLinkServer(t)
+
+		response := serve(server, http.MethodPost, "/api/access/requests", "viewer", `{"group_id":10,"link":"`+signer.Sign(link)+`"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
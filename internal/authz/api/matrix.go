@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/report"
+)
+
+// matrixReport serves the users by effective permissions matrix as JSON, CSV or XLSX, picked with
+// the format query parameter. The groups and permissions query parameters take comma separated
+// names to narrow the matrix down.
+func (server *Server) matrixReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "xlsx" {
+		writeError(w, http.StatusBadRequest, "unknown format, expected json, csv or xlsx")
+		return
+	}
+
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	filter := report.MatrixFilter{Groups: splitNames(query.Get("groups")), Permissions: splitNames(query.Get("permissions"))}
+	matrix, err := report.BuildMatrix(policy, filter)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if format == "json" {
+		writeJSON(w, http.StatusOK, matrix)
+		return
+	}
+
+	// render the whole document first, so a failure can still be reported with an error status
+	var document bytes.Buffer
+	contentType := "text/csv"
+	if format == "csv" {
+		err = matrix.WriteCSV(&document)
+	} else {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		err = matrix.WriteXLSX(&document)
+	}
+	if err != nil {
+		server.logger.Error("failed to write membership matrix", "format", format, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="membership-matrix.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(document.Bytes())
+}
+
+// splitNames splits a comma separated list of names, dropping blanks.
+func splitNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrixReport(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/matrix?groups=cooks&permissions=recipes.read,recipes.purge", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"permissions":["recipes.purge","recipes.read"],"rows":[{"user":"alice","granted":[false,true]}]}`, response.Body.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/matrix?format=csv&groups=cooks&permissions=recipes.read", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "text/csv", response.Header().Get("Content-Type"))
+		assert.Contains(t, response.Header().Get("Content-Disposition"), "membership-matrix.csv")
+		assert.Equal(t, "user,recipes.read\nalice,x\n", response.Body.String())
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/matrix?format=xlsx", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", response.Header().Get("Content-Type"))
+		assert.Equal(t, "PK", response.Body.String()[:2])
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/matrix?groups=bakers", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodGet, "/api/reports/matrix?format=pdf", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// The meta-policy: permissions defined in the managed policy itself
+// that govern who may use the administration API.
+const (
+	// PermissionRead allows browsing groups, permissions and memberships.
+	PermissionRead = "authz.read"
+	// PermissionWrite allows changing groups, permissions and memberships.
+	PermissionWrite = "authz.write"
+	// PermissionImpersonate allows evaluating the policy as another user.
+	PermissionImpersonate = "authz.impersonate"
+	// PermissionEvaluate allows services to check the decisions of their users.
+	PermissionEvaluate = "authz.evaluate"
+	// PermissionApprove allows deciding on approval requests.
+	PermissionApprove = "authz.approve"
+	// PermissionDiagnose allows reading the runtime diagnostics and profiles of the server.
+	PermissionDiagnose = "authz.diagnose"
+	// PermissionAudit allows reading the audit history of the changes and accesses to the administration API.
+	PermissionAudit = "authz.audit"
+)
+
+// Request headers set by the reverse proxy in front of the service.
+// They are trusted unless other authentication providers are configured, see WithAuthenticators.
+const (
+	// UserHeader carries the authenticated user.
+	UserHeader = "X-Forwarded-User"
+	// MFAHeader is set to "true" when the user completed multi-factor authentication.
+	MFAHeader = "X-Forwarded-MFA"
+)
+
+// Identity is the authenticated caller of the administration API.
+type Identity struct {
+	User string
+	MFA  bool
+}
+
+type identityContextKey struct{}
+
+// IdentityFromContext returns the authenticated caller stored in the context.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(Identity)
+	return identity, ok
+}
+
+// RequireAuthentication wraps the handler so it only runs for authenticated callers,
+// whatever their meta-policy permissions. It serves the endpoints of end users.
+func (server *Server) RequireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := server.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+	})
+}
+
+// RequirePermission wraps the handler so it only runs when the authenticated
+// user is granted the given meta-policy permission.
+func (server *Server) RequirePermission(permission string, next http.Handler) http.Handler {
+	return server.requirePermission(permission, nil, next)
+}
+
+// RequireGroupAdmin wraps the handler of an endpoint managing the group in the id path value, so it
+// runs for the holders of the authz.write permission and for the admins of the folder holding the
+// group, see folder.Tree.IsAdmin. The handler tells the latter apart with IsDelegatedAdmin.
+func (server *Server) RequireGroupAdmin(next http.Handler) http.Handler {
+	return server.requirePermission(PermissionWrite, server.administersGroup, next)
+}
+
+type delegatedContextKey struct{}
+
+// IsDelegatedAdmin reports whether the caller was let through RequireGroupAdmin as an admin of the
+// folder holding the group rather than as a holder of the authz.write permission.
+func IsDelegatedAdmin(ctx context.Context) bool {
+	delegated, _ := ctx.Value(delegatedContextKey{}).(bool)
+	return delegated
+}
+
+// requirePermission wraps the handler so it only runs when the authenticated user is granted the
+// permission, or when delegate, if set, grants the user access to the requested resource.
+func (server *Server) requirePermission(permission string, delegate func(r *http.Request, user string) (bool, error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := server.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		policy, err := server.manager.ReadPolicy(r.Context())
+		if err != nil {
+			server.writeStoreError(w, err)
+			return
+		}
+
+		session, err := policy.Session(identity.User)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), identityContextKey{}, identity)
+		if !session.HasPermission(permission) {
+			delegated := false
+			if delegate != nil {
+				delegated, err = delegate(r, identity.User)
+				if err != nil {
+					server.logger.Error("failed to check delegated access to the administration API", "user", identity.User, "path", r.URL.Path, "error", err)
+					writeError(w, http.StatusInternalServerError, "internal server error")
+					return
+				}
+			}
+			if !delegated {
+				check := policy.CheckEvaluated(identity.User, permission, session.Result())
+				server.logger.Warn("access to the administration API denied", "user", identity.User, "permission", permission,
+					"reason", check.Reason, "path", r.URL.Path)
+				server.auditDenial(r, identity.User, permission, check.Reason)
+				writeError(w, http.StatusForbidden, "permission denied")
+				return
+			}
+			server.logger.Debug("delegated access to the administration API granted", "user", identity.User, "path", r.URL.Path)
+			ctx = context.WithValue(ctx, delegatedContextKey{}, true)
+		} else {
+			server.logger.Debug("access to the administration API granted", "user", identity.User, "permission", permission, "path", r.URL.Path)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// auditDenial records a denied access to the administration API as an audit event, so SIEMs see
+// the denials along with the changes. A denial that cannot be recorded is logged only.
+func (server *Server) auditDenial(r *http.Request, user string, permission string, reason authz.DenialReason) {
+	err := server.audit.Record(r.Context(), audit.Event{
+		ID:      id.New(),
+		Time:    server.clock.Now(),
+		Actor:   user,
+		Action:  "access.denied",
+		Subject: permission,
+		Details: map[string]any{"reason": string(reason), "method": r.Method, "path": r.URL.Path},
+	})
+	if err != nil {
+		server.logger.Error("failed to record access denial audit event", "user", user, "permission", permission, "error", err)
+	}
+}
+
+// authenticate returns the caller of the request, or writes a 401 response when it cannot be authenticated.
+func (server *Server) authenticate(w http.ResponseWriter, r *http.Request) (Identity, bool) {
+	principal, err := server.authenticator.Authenticate(r)
+	if err != nil {
+		server.logger.Warn("authentication to the administration API failed", "error", err, "path", r.URL.Path)
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return Identity{}, false
+	}
+	if principal == nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return Identity{}, false
+	}
+
+	return Identity{User: principal.User, MFA: principal.MFA}, true
+}
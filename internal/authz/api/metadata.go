@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// mergePatchContentType is the media type of JSON merge patches (RFC 7396).
+const mergePatchContentType = "application/merge-patch+json"
+
+// patchGroup changes the description or labels of a group with a JSON merge patch, such as
+// {"description": "Recipe authors", "labels": {"team": "kitchen", "legacy": null}}.
+// Fields left out of the patch are unchanged, so clients do not race on the whole group.
+func (server *Server) patchGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid group id")
+		return
+	}
+
+	patch, err := parseMetadataPatch(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	group, err := server.manager.UpdateGroupMetadata(r.Context(), groupId, patch)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+// patchPermission changes the description or labels of a permission with a JSON merge patch, like patchGroup.
+func (server *Server) patchPermission(w http.ResponseWriter, r *http.Request) {
+	permissionId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid permission id")
+		return
+	}
+
+	patch, err := parseMetadataPatch(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	permission, err := server.manager.UpdatePermissionMetadata(r.Context(), permissionId, patch)
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, permission)
+}
+
+// parseMetadataPatch reads a JSON merge patch of the description and labels. A null description
+// clears it, a null label removes it and null labels remove every label. Other fields, such as
+// the name or the risk level, have their own endpoints and are rejected.
+func parseMetadataPatch(w http.ResponseWriter, r *http.Request) (store.MetadataPatch, error) {
+	var patch store.MetadataPatch
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			return patch, errors.New("patches must be sent as " + mergePatchContentType)
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := decodeJSON(w, r, &fields); err != nil || fields == nil {
+		return patch, errors.New("invalid request body")
+	}
+
+	for name, value := range fields {
+		switch name {
+		case "description":
+			description := ""
+			if err := json.Unmarshal(value, &description); err != nil {
+				return patch, errors.New("description must be a string")
+			}
+			patch.Description = &description
+		case "labels":
+			if string(value) == "null" {
+				patch.ClearLabels = true
+				continue
+			}
+			if err := json.Unmarshal(value, &patch.Labels); err != nil {
+				return patch, errors.New("labels must be an object of strings")
+			}
+		default:
+			return patch, fmt.Errorf("field %q cannot be patched", name)
+		}
+	}
+
+	if err := patch.Validate(); err != nil {
+		return patch, errors.New("label keys must not be empty")
+	}
+	return patch, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mergePatch = map[string]string{"Content-Type": mergePatchContentType}
+
+func TestPatchGroup(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		description := "Recipe authors"
+		team := "kitchen"
+		manager.On("UpdateGroupMetadata", mock.Anything, 10, store.MetadataPatch{
+			Description: &description,
+			Labels:      map[string]*string{"team": &team, "legacy": nil},
+		}).Return(&store.GroupInfo[int]{ID: 10, Name: "cooks", Version: 2,
+			Metadata: store.Metadata{Description: description, Labels: map[string]string{"team": team}}}, nil)
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/10", "admin",
+			`{"description":"Recipe authors","labels":{"team":"kitchen","legacy":null}}`, mergePatch)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"id":10,"name":"cooks","version":2,"description":"Recipe authors","labels":{"team":"kitchen"}}`, response.Body.String())
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		empty := ""
+		manager.On("UpdateGroupMetadata", mock.Anything, 10, store.MetadataPatch{Description: &empty, ClearLabels: true}).
+			Return(&store.GroupInfo[int]{ID: 10, Name: "cooks"}, nil)
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/10", "admin", `{"description":null,"labels":null}`, mergePatch)
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("unsupported field", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/10", "admin", `{"name":"bakers"}`, mergePatch)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), `field \"name\" cannot be patched`)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/10", "admin", `{}`,
+			map[string]string{"Content-Type": "application/json-patch+json"})
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("UpdateGroupMetadata", mock.Anything, 99, mock.Anything).Return(nil, store.NewGroupNotFoundError())
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/99", "admin", `{"description":"x"}`, mergePatch)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("requires write permission", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serveWithHeaders(server, http.MethodPatch, "/api/groups/10", "viewer", `{"description":"x"}`, mergePatch)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+func TestPatchPermission(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	audited := "audited"
+	manager.On("UpdatePermissionMetadata", mock.Anything, 3, store.MetadataPatch{Labels: map[string]*string{"compliance": &audited}}).
+		Return(&store.PermissionInfo[int]{ID: 3, Name: "recipes.delete", Version: 1, Risk: authz.RiskHigh,
+			Metadata: store.Metadata{Labels: map[string]string{"compliance": audited}}}, nil)
+
+	response := serveWithHeaders(server, http.MethodPatch, "/api/permissions/3", "admin", `{"labels":{"compliance":"audited"}}`, mergePatch)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"labels":{"compliance":"audited"}`)
+
+	manager.AssertExpectations(t)
+}
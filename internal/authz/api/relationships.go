@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/relationship"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// maxTuples is the most tuples a single write or delete may carry.
+const maxTuples = 1000
+
+// relationshipsRequest is the body of POST /api/relationships and DELETE /api/relationships.
+type relationshipsRequest struct {
+	// The tuples in the object#relation@subject form, such as document:readme#viewer@group:cooks#member.
+	Tuples []string `json:"tuples"`
+}
+
+// checkResponse is the body returned by GET /api/relationships/check.
+type checkResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// listRelationships returns the tuples matching the object_type, object_id, relation, subject_type,
+// subject_id and subject_relation query parameters, a page at a time.
+func (server *Server) listRelationships(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	tuples, err := server.relationships.Read(r.Context(), relationship.Filter{
+		ObjectType: query.Get("object_type"), ObjectID: query.Get("object_id"), Relation: query.Get("relation"),
+		SubjectType: query.Get("subject_type"), SubjectID: query.Get("subject_id"), SubjectRelation: query.Get("subject_relation"),
+	})
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+
+	tuples, next, err := paging.Page(tuples, page, func(tuple relationship.Tuple) paging.Key { return paging.Key{tuple.String()} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, tuples, next)
+}
+
+// writeRelationships records the tuples of the request and returns how many were added.
+func (server *Server) writeRelationships(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	tuples, ok := decodeTuples(w, r)
+	if !ok {
+		return
+	}
+
+	written, err := server.relationships.Write(r.Context(), tuples)
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+
+	server.auditRelationships(r.Context(), "relationship.write", tuples, written)
+	writeJSON(w, http.StatusOK, map[string]int{"written": written})
+}
+
+// deleteRelationships removes the tuples of the request and returns how many were removed.
+func (server *Server) deleteRelationships(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	tuples, ok := decodeTuples(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := server.relationships.Delete(r.Context(), tuples)
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+
+	server.auditRelationships(r.Context(), "relationship.delete", tuples, deleted)
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+// checkRelationship reports whether the subject query parameter holds the relation on the object,
+// such as ?object=document:readme&relation=viewer&subject=user:alice.
+func (server *Server) checkRelationship(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	query := r.URL.Query()
+	object, err := relationship.ParseObject(query.Get("object"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	subject, err := relationship.ParseSubject(query.Get("subject"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := relationship.ValidateRelation(query.Get("relation")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowed, err := relationship.NewEvaluator(server.relationships).Check(r.Context(), object, query.Get("relation"), subject)
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, checkResponse{Allowed: allowed})
+}
+
+// expandRelationship returns the tree of the subjects holding the relation on the object,
+// such as ?object=document:readme&relation=viewer.
+func (server *Server) expandRelationship(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	query := r.URL.Query()
+	object, err := relationship.ParseObject(query.Get("object"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := relationship.ValidateRelation(query.Get("relation")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tree, err := relationship.NewEvaluator(server.relationships).Expand(r.Context(), object, query.Get("relation"))
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// lookupSubjects returns the subjects holding the relation on the object, directly or through nested
+// usersets, a page at a time, such as ?object=document:readme&relation=viewer&subject_type=user for
+// a sharing dialog. The optional depth parameter lowers the number of nested usersets followed.
+func (server *Server) lookupSubjects(w http.ResponseWriter, r *http.Request) {
+	if !server.requireRelationships(w) {
+		return
+	}
+	page, ok := parsePage(w, r, listLimits)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	object, err := relationship.ParseObject(query.Get("object"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := relationship.ValidateRelation(query.Get("relation")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	subjectType := query.Get("subject_type")
+	if subjectType != "" {
+		if err := relationship.ValidateType(subjectType); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	depth := relationship.DefaultMaxDepth
+	if value := query.Get("depth"); value != "" {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 1 || depth > relationship.DefaultMaxDepth {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("depth must be between 1 and %d", relationship.DefaultMaxDepth))
+			return
+		}
+	}
+
+	evaluator := relationship.NewEvaluator(server.relationships, relationship.WithMaxDepth(depth))
+	subjects, err := evaluator.LookupSubjects(r.Context(), object, query.Get("relation"), subjectType)
+	if err != nil {
+		server.writeRelationshipError(w, err)
+		return
+	}
+
+	subjects, next, err := paging.Page(subjects, page, func(subject relationship.Subject) paging.Key { return paging.Key{subject.String()} })
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writePage(w, r, subjects, next)
+}
+
+// decodeTuples reads and parses the tuples of a write or delete request. It writes a bad request
+// response naming the first invalid tuple and returns false when the body is malformed.
+func decodeTuples(w http.ResponseWriter, r *http.Request) ([]relationship.Tuple, bool) {
+	var request relationshipsRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return nil, false
+	}
+	if len(request.Tuples) > maxTuples {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("too many tuples, the maximum is %d", maxTuples))
+		return nil, false
+	}
+
+	tuples := make([]relationship.Tuple, 0, len(request.Tuples))
+	for _, text := range request.Tuples {
+		tuple, err := relationship.Parse(text)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, true
+}
+
+// auditRelationships records a write or a delete of tuples. A failure is only logged.
+func (server *Server) auditRelationships(ctx context.Context, action string, tuples []relationship.Tuple, changed int) {
+	identity, _ := IdentityFromContext(ctx)
+	err := server.audit.Record(ctx, audit.Event{
+		ID:      id.New(),
+		Time:    server.clock.Now(),
+		Actor:   identity.User,
+		Action:  action,
+		Details: map[string]any{"tuples": tuples, "changed": changed},
+	})
+	if err != nil {
+		server.logger.Error("failed to record relationship audit event", "action", action, "error", err)
+	}
+}
+
+func (server *Server) requireRelationships(w http.ResponseWriter) bool {
+	if server.relationships == nil {
+		writeError(w, http.StatusNotFound, "relationships are not configured")
+		return false
+	}
+	return true
+}
+
+// writeRelationshipError writes the response for an error of the relationship store or evaluator.
+func (server *Server) writeRelationshipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, relationship.ErrInvalidTuple):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, relationship.ErrDepthExceeded):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		server.logger.Error("relationship store failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
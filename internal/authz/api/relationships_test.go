@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/relationship"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRelationshipServer shares the readme with the cooks, whose members are alice and the chefs.
+func setupRelationshipServer(t *testing.T) (*relationship.MemoryStore, *Server) {
+	t.Helper()
+	relationships := relationship.NewMemoryStore()
+	tuples := []relationship.Tuple{}
+	for _, text := range []string{
+		"document:readme#viewer@group:cooks#member",
+		"group:cooks#member@user:alice",
+		"group:cooks#member@group:chefs#member",
+		"group:chefs#member@user:carol",
+	} {
+		tuple, err := relationship.Parse(text)
+		assert.NoError(t, err)
+		tuples = append(tuples, tuple)
+	}
+	_, err := relationships.Write(context.Background(), tuples)
+	assert.NoError(t, err)
+	return relationships, setupDecisionServer(WithRelationships(relationships))
+}
+
+func TestListRelationships(t *testing.T) {
+	t.Run("filtered", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships?object_type=group&object_id=cooks", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"group:cooks#member@group:chefs#member","group:cooks#member@user:alice"`)
+		assert.NotContains(t, response.Body.String(), "document:readme")
+	})
+
+	t.Run("paged", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships?limit=3", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NotEmpty(t, response.Header().Get("Link"))
+		assert.NotContains(t, response.Body.String(), "user:alice")
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		server := setupDecisionServer()
+
+		response := serve(server, http.MethodGet, "/api/relationships", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestWriteRelationships(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		relationships, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodPost, "/api/relationships", "admin",
+			`{"tuples":["document:changelog#viewer@user:bob","group:cooks#member@user:alice"]}`)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"written":1}`, response.Body.String())
+
+		tuples, err := relationships.Read(context.Background(), relationship.Filter{ObjectID: "changelog"})
+		assert.NoError(t, err)
+		assert.Len(t, tuples, 1)
+	})
+
+	t.Run("invalid tuple", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodPost, "/api/relationships", "admin", `{"tuples":["document:changelog#viewer"]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), "has no subject")
+	})
+
+	t.Run("too many tuples", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+		tuples := strings.Repeat(`"group:cooks#member@user:alice",`, maxTuples)
+
+		response := serve(server, http.MethodPost, "/api/relationships", "admin", `{"tuples":[`+tuples+`"group:cooks#member@user:bob"]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodPost, "/api/relationships", "viewer", `{"tuples":["group:cooks#member@user:bob"]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+func TestDeleteRelationships(t *testing.T) {
+	relationships, server := setupRelationshipServer(t)
+
+	response := serve(server, http.MethodDelete, "/api/relationships", "admin", `{"tuples":["group:cooks#member@user:alice"]}`)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"deleted":1}`, response.Body.String())
+
+	tuples, err := relationships.Read(context.Background(), relationship.Filter{SubjectID: "alice"})
+	assert.NoError(t, err)
+	assert.Empty(t, tuples)
+}
+
+func TestCheckRelationship(t *testing.T) {
+	t.Run("allowed through nested groups", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/check?object=document:readme&relation=viewer&subject=user:carol", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"allowed":true}`, response.Body.String())
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/check?object=document:readme&relation=viewer&subject=user:bob", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"allowed":false}`, response.Body.String())
+	})
+
+	t.Run("invalid subject", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/check?object=document:readme&relation=viewer&subject=bob", "recipes", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestExpandRelationship(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/expand?object=document:readme&relation=viewer", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{
+			"userset": "document:readme#viewer",
+			"subjects": [],
+			"children": [{
+				"userset": "group:cooks#member",
+				"subjects": ["user:alice"],
+				"children": [{"userset": "group:chefs#member", "subjects": ["user:carol"]}]
+			}]
+		}`, response.Body.String())
+	})
+
+	t.Run("invalid relation", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/expand?object=document:readme&relation=Viewer", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestLookupSubjects(t *testing.T) {
+	t.Run("paged", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/subjects?object=document:readme&relation=viewer&subject_type=user&limit=1", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `["user:alice"]`, response.Body.String())
+		assert.NotEmpty(t, response.Header().Get("Link"))
+	})
+
+	t.Run("all", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/subjects?object=document:readme&relation=viewer", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `["user:alice","user:carol"]`, response.Body.String())
+	})
+
+	t.Run("depth exceeded", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/subjects?object=document:readme&relation=viewer&depth=2", "viewer", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/subjects?object=document:readme&relation=viewer&depth=100", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("invalid subject type", func(t *testing.T) {
+		_, server := setupRelationshipServer(t)
+
+		response := serve(server, http.MethodGet, "/api/relationships/subjects?object=document:readme&relation=viewer&subject_type=User", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// maxBodyBytes limits the size of request bodies accepted by the API.
+const maxBodyBytes = 1 << 20
+
+// errorResponse is the body returned for every failed request.
+type errorResponse struct {
+	Error string          `json:"error"`
+	Code  store.ErrorCode `json:"code,omitempty"`
+}
+
+// guardrailErrorResponse is the body returned when a change is blocked by guardrails.
+type guardrailErrorResponse struct {
+	Error      string                `json:"error"`
+	Violations []guardrail.Violation `json:"violations"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// listLimits bounds the pages of the lists that were returned whole before they were paginated:
+// they are still returned whole unless the client asks for a page.
+var listLimits = paging.Limits{Max: 1000}
+
+// parsePage reads the page asked for by a list request. It writes a bad request response
+// and returns false when the limit or the cursor is malformed.
+func parsePage(w http.ResponseWriter, r *http.Request, limits paging.Limits) (paging.Request, bool) {
+	page, err := paging.Parse(r.URL.Query(), limits)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return paging.Request{}, false
+	}
+	return page, true
+}
+
+// writePage writes a page of a list, linking to the next page when there is one.
+func writePage(w http.ResponseWriter, r *http.Request, items any, next string) {
+	if next != "" {
+		w.Header().Set("Link", paging.NextLink(r.URL, next))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// writeStoreError maps a policy store error to the matching HTTP status code.
+// Changes blocked by guardrails are reported with the violations.
+func (server *Server) writeStoreError(w http.ResponseWriter, err error) {
+	var violationErr *guardrail.ViolationError
+	if errors.As(err, &violationErr) {
+		writeJSON(w, http.StatusUnprocessableEntity, guardrailErrorResponse{Error: "change exceeds guardrails", Violations: violationErr.Violations})
+		return
+	}
+
+	var storeErr *store.PolicyStoreError
+	if !errors.As(err, &storeErr) {
+		server.logger.Error("unexpected store error", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	writeJSON(w, statusFromCode(storeErr.Code), errorResponse{Error: storeErr.Error(), Code: storeErr.Code})
+}
+
+// statusFromCode returns the HTTP status code matching a policy store error code.
+func statusFromCode(code store.ErrorCode) int {
+	switch code {
+	case store.GroupNotFound, store.NoUserRecordsDeleted, store.PermissionNotFound:
+		return http.StatusNotFound
+	case store.Concurrency, store.NameAlreadyExist, store.NoChanges:
+		return http.StatusConflict
+	case store.InvalidArgument:
+		return http.StatusBadRequest
+	case store.ReadOnly:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeJSON decodes the request body into the given value, rejecting unknown fields.
+func decodeJSON(w http.ResponseWriter, r *http.Request, value any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
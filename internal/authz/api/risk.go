@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/report"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// setPermissionRiskRequest is the body of PUT /api/permissions/{id}/risk.
+type setPermissionRiskRequest struct {
+	Risk authz.RiskLevel `json:"risk"`
+}
+
+// setPermissionRisk changes the risk level of a permission. Raising a permission to
+// or lowering it from high risk requires multi-factor authentication.
+func (server *Server) setPermissionRisk(w http.ResponseWriter, r *http.Request) {
+	permissionId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid permission id")
+		return
+	}
+
+	var request setPermissionRiskRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	risk, err := authz.ParseRiskLevel(string(request.Risk))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	permissions, err := server.manager.ListPermissions(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+	index := slices.IndexFunc(permissions, func(permission store.PermissionInfo[int]) bool { return permission.ID == permissionId })
+	if index < 0 {
+		server.writeStoreError(w, store.NewPermissionNotFoundError())
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	if (risk == authz.RiskHigh || permissions[index].Risk == authz.RiskHigh) &&
+		!server.requireMFA(w, r, identity, "change_high_risk", "multi-factor authentication is required to change high risk permissions") {
+		return
+	}
+
+	if err := server.manager.SetPermissionRisk(r.Context(), permissionId, risk); err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) riskReport(w http.ResponseWriter, r *http.Request) {
+	policy, err := server.manager.ReadPolicy(r.Context())
+	if err != nil {
+		server.writeStoreError(w, err)
+		return
+	}
+
+	risk, err := report.Risk(policy)
+	if err != nil {
+		server.logger.Error("failed to build risk report", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, risk)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetPermissionRisk(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionRisk", mock.Anything, 1, authz.RiskMedium).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/permissions/1/risk", "admin", `{"risk":"medium"}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("raising to high requires mfa", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/1/risk", "admin", `{"risk":"high"}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		manager.AssertNotCalled(t, "SetPermissionRisk", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lowering from high requires mfa", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/2/risk", "admin", `{"risk":"low"}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("high with mfa", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+		manager.On("SetPermissionRisk", mock.Anything, 1, authz.RiskHigh).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPut, "/api/permissions/1/risk", "admin", `{"risk":"high"}`, mfa)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+
+	t.Run("unknown risk", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/1/risk", "admin", `{"risk":"critical"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("permission not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		setupRiskPolicy(manager)
+
+		response := serve(server, http.MethodPut, "/api/permissions/9/risk", "admin", `{"risk":"low"}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestRiskReport(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	setupRiskPolicy(manager)
+
+	response := serve(server, http.MethodGet, "/api/reports/risk", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"high_risk_holders":[{"user":"alice","permissions":["recipes.delete"]}]`)
+}
@@ -0,0 +1,339 @@
+// Package api exposes the policy store over a JSON HTTP API.
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/diagnostics"
+	"github.com/salmarsumi/recipes/internal/authz/directory"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/relationship"
+	"github.com/salmarsumi/recipes/internal/authz/selfservice"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/syncreport"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Manager is the policy store backing the API.
+type Manager = store.PolicyManager[int, int, string]
+
+// Server is an http.Handler serving the administration API.
+type Server struct {
+	manager       Manager
+	logger        *slog.Logger
+	mux           *http.ServeMux
+	versions      []apiVersion
+	audit         audit.Sink
+	auditLog      audit.Reader
+	approvals     *approval.Workflow
+	syncReports   syncreport.Store
+	catalogs      catalog.Store
+	selfService   selfservice.Store
+	links         selfservice.LinkStore
+	linkSigner    *selfservice.Signer
+	linkURL       string
+	directory     directory.Store
+	folders       folder.Store
+	relationships relationship.Store
+	diagnostics   *diagnostics.Diagnostics
+	guardrails    []guardrail.Rule
+	decisionTTLs  DecisionTTLs
+	decisionLog   DecisionRecorder
+	versionSource PolicyVersions
+	mode          authz.EvaluationMode
+	authenticator authn.Provider
+	traces        *traceSwitch
+	stepUp        StepUpInitiator
+	consistency   consistency.Reader
+	clock         clock.Clock
+	tracer        trace.Tracer
+}
+
+// Option configures optional Server dependencies.
+type Option func(*Server)
+
+// WithAuditSink sets the sink receiving audit events.
+// By default audit events are written to the server logger.
+func WithAuditSink(sink audit.Sink) Option {
+	return func(server *Server) {
+		server.audit = sink
+	}
+}
+
+// WithApprovals sets the workflow used for changes requiring a second person's approval,
+// such as granting high risk permissions. Without it such changes are refused.
+func WithApprovals(workflow *approval.Workflow) Option {
+	return func(server *Server) {
+		server.approvals = workflow
+	}
+}
+
+// WithAuditLog sets the reader of the recorded audit events, served as the audit history.
+// Without it the audit history endpoint responds with 404.
+func WithAuditLog(reader audit.Reader) Option {
+	return func(server *Server) {
+		server.auditLog = reader
+	}
+}
+
+// WithSyncReports sets the store recording the reports of sync connector runs.
+// Without it the sync report endpoints respond with 404.
+func WithSyncReports(reports syncreport.Store) Option {
+	return func(server *Server) {
+		server.syncReports = reports
+	}
+}
+
+// WithCatalogs sets the store recording the permission catalogs registered by applications.
+// Without it the catalog and lint endpoints respond with 404.
+func WithCatalogs(catalogs catalog.Store) Option {
+	return func(server *Server) {
+		server.catalogs = catalogs
+	}
+}
+
+// WithSelfService sets the store recording the groups end users may request to join.
+// Requests go through the approval workflow, so without both the self-service endpoints respond with 404.
+func WithSelfService(groups selfservice.Store) Option {
+	return func(server *Server) {
+		server.selfService = groups
+	}
+}
+
+// WithRequestLinks sets the store and the signer of the shareable links pre-filling requests to join
+// self-service groups. The URL of the frontend page opening the links is optional: without it the
+// links point at the API resolving them. Without this option the request link endpoints respond with 404.
+func WithRequestLinks(links selfservice.LinkStore, signer *selfservice.Signer, pageURL string) Option {
+	return func(server *Server) {
+		server.links = links
+		server.linkSigner = signer
+		server.linkURL = pageURL
+	}
+}
+
+// WithDirectory sets the store recording the user attributes and group owners used to route
+// access requests. Without it the directory and group owner endpoints respond with 404.
+func WithDirectory(users directory.Store) Option {
+	return func(server *Server) {
+		server.directory = users
+	}
+}
+
+// WithFolders sets the store organizing groups and permission catalogs in folders, whose admins
+// manage the groups of their folders without the authz.write permission. Without it the folder
+// endpoints respond with 404 and only the holders of authz.write manage groups.
+func WithFolders(folders folder.Store) Option {
+	return func(server *Server) {
+		server.folders = folders
+	}
+}
+
+// WithRelationships sets the store of the relationship tuples sharing single objects, written,
+// checked, expanded and looked up under /api/relationships. Without it those endpoints return 404.
+func WithRelationships(relationships relationship.Store) Option {
+	return func(server *Server) {
+		server.relationships = relationships
+	}
+}
+
+// WithDiagnostics exposes the runtime diagnostics and the pprof profiles of the server to the
+// holders of the authz.diagnose permission. Without it the debug endpoints respond with 404.
+func WithDiagnostics(diagnostics *diagnostics.Diagnostics) Option {
+	return func(server *Server) {
+		server.diagnostics = diagnostics
+	}
+}
+
+// WithGuardrails reports the current usage of the guardrail rules under /api/guardrails/usage.
+// The rules are enforced by the policy manager, see guardrail.NewManager. Without it the endpoint returns 404.
+func WithGuardrails(rules []guardrail.Rule) Option {
+	return func(server *Server) {
+		server.guardrails = rules
+	}
+}
+
+// WithDecisionTTLs sets the cache lifetimes suggested with every decision.
+// By default DefaultDecisionTTLs are used.
+func WithDecisionTTLs(ttls DecisionTTLs) Option {
+	return func(server *Server) {
+		server.decisionTTLs = ttls
+	}
+}
+
+// WithDecisionLog sets the recorder of the decisions made by the decision endpoint, see decisionlog.Recorder.
+// By default decisions are not recorded.
+func WithDecisionLog(recorder DecisionRecorder) Option {
+	return func(server *Server) {
+		server.decisionLog = recorder
+	}
+}
+
+// WithPolicyVersions sets the source of the versions of the cached policies, so decisions reuse the
+// version computed when the policy was cached. By default the version is computed for every decision.
+func WithPolicyVersions(versions PolicyVersions) Option {
+	return func(server *Server) {
+		server.versionSource = versions
+	}
+}
+
+// WithStepUp sets the initiator of the step-up challenges returned with the requests refused until
+// the user completes multi-factor authentication, so frontends can trigger it and retry.
+// By default such requests are refused without a challenge.
+func WithStepUp(initiator StepUpInitiator) Option {
+	return func(server *Server) {
+		server.stepUp = initiator
+	}
+}
+
+// WithEvaluationMode sets the mode decisions are made in, see authz.EvaluationMode.
+// The meta-policy protecting the API itself is always enforced in the default-deny mode.
+// By default the mode of the policy is used.
+func WithEvaluationMode(mode authz.EvaluationMode) Option {
+	return func(server *Server) {
+		server.mode = mode
+	}
+}
+
+// WithConsistency issues consistency tokens with the changes made through the API and honors them
+// in evaluations, see ConsistencyHeader. By default no token is issued and tokens are ignored.
+func WithConsistency(reader consistency.Reader) Option {
+	return func(server *Server) {
+		server.consistency = reader
+	}
+}
+
+// WithClock sets the clock timestamping the changes made through the API. The default is the system clock.
+func WithClock(clock clock.Clock) Option {
+	return func(server *Server) {
+		server.clock = clock
+	}
+}
+
+// WithAuthenticators sets the providers authenticating the callers of the API, tried in order.
+// By default the user and MFA headers set by the reverse proxy are trusted.
+func WithAuthenticators(providers ...authn.Provider) Option {
+	return func(server *Server) {
+		server.authenticator = authn.Chain(providers)
+	}
+}
+
+// WithTracerProvider sets the provider of the tracer recording a span for every decision.
+// By default the global provider is used, see otel.SetTracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(server *Server) {
+		server.tracer = tracing.Tracer(provider)
+	}
+}
+
+// NewServer creates a new Server backed by the given policy manager.
+func NewServer(manager Manager, logger *slog.Logger, options ...Option) *Server {
+	server := &Server{
+		manager:       manager,
+		logger:        logger,
+		mux:           http.NewServeMux(),
+		audit:         audit.NewLogSink(logger),
+		decisionTTLs:  DefaultDecisionTTLs,
+		authenticator: authn.NewProxyHeaders(UserHeader, MFAHeader),
+		traces:        newTraceSwitch(),
+		clock:         clock.System(),
+		tracer:        tracing.Tracer(nil),
+	}
+	for _, option := range options {
+		option(server)
+	}
+
+	server.versions = []apiVersion{{name: Version1, mux: server.mux}}
+	server.routes()
+	return server
+}
+
+// ServeHTTP dispatches the request to the matching API handler of the requested version.
+// See Versions for the compatibility policy.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.serveVersion(server.withTokens(w, r), r)
+}
+
+func (server *Server) routes() {
+	server.mux.Handle("GET /api/health", server.HealthHandler())
+	server.mux.Handle("GET /api/policy", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getPolicy)))
+	server.mux.Handle("GET /api/groups", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listGroups)))
+	server.mux.Handle("GET /api/permissions", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listPermissions)))
+	server.mux.Handle("POST /api/groups", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.createGroup)))
+	server.mux.Handle("POST /api/permissions", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.createPermission)))
+	server.mux.Handle("GET /api/groups/{id}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getGroup)))
+	server.mux.Handle("DELETE /api/groups/{id}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.deleteGroup)))
+	server.mux.Handle("DELETE /api/users/{user}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.deleteUser)))
+	server.mux.Handle("GET /api/users/{user}/groups", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getUserGroups)))
+	server.mux.Handle("PUT /api/users/{user}/groups", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.updateUserGroups)))
+	server.mux.Handle("PATCH /api/groups/{id}", server.RequireGroupAdmin(http.HandlerFunc(server.patchGroup)))
+	server.mux.Handle("PATCH /api/permissions/{id}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.patchPermission)))
+	server.mux.Handle("PUT /api/groups/{id}/users", server.RequireGroupAdmin(http.HandlerFunc(server.updateGroupUsers)))
+	server.mux.Handle("PUT /api/groups/{id}/permissions", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.updateGroupPermissions)))
+	server.mux.Handle("PUT /api/permissions/{id}/risk", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setPermissionRisk)))
+	server.mux.Handle("PUT /api/permissions/{id}/implies", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setPermissionImplications)))
+	server.mux.Handle("PUT /api/permissions/{id}/denials", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setPermissionDenials)))
+	server.mux.Handle("PUT /api/permissions/{id}/condition", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setPermissionCondition)))
+	server.mux.Handle("PUT /api/groups/{id}/self-service", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setSelfService)))
+	server.mux.Handle("POST /api/groups/{id}/request-links", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.createRequestLink)))
+	server.mux.Handle("GET /api/groups/{id}/request-links", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listRequestLinks)))
+	server.mux.Handle("DELETE /api/request-links/{id}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.revokeRequestLink)))
+	server.mux.Handle("GET /api/approvals", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listApprovals)))
+	server.mux.Handle("GET /api/approvals/{id}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getApproval)))
+	server.mux.Handle("POST /api/approvals/{id}/approve", server.RequireAuthentication(http.HandlerFunc(server.approve)))
+	server.mux.Handle("POST /api/approvals/{id}/reject", server.RequireAuthentication(http.HandlerFunc(server.reject)))
+	server.mux.Handle("POST /api/sync/reports", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.submitSyncReport)))
+	server.mux.Handle("GET /api/audit/events", server.RequirePermission(PermissionAudit, http.HandlerFunc(server.listAuditEvents)))
+	server.mux.Handle("GET /api/sync/reports", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listSyncReports)))
+	server.mux.Handle("GET /api/sync/reports/{id}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getSyncReport)))
+	server.mux.Handle("PUT /api/catalogs/{application}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.registerCatalog)))
+	server.mux.Handle("GET /api/catalogs", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listCatalogs)))
+	server.mux.Handle("GET /api/catalogs/{application}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getCatalog)))
+	server.mux.Handle("GET /api/reports/lint", server.RequirePermission(PermissionRead, http.HandlerFunc(server.lintReport)))
+	server.mux.Handle("GET /api/reports/risk", server.RequirePermission(PermissionRead, http.HandlerFunc(server.riskReport)))
+	server.mux.Handle("GET /api/reports/matrix", server.RequirePermission(PermissionRead, http.HandlerFunc(server.matrixReport)))
+	server.mux.Handle("GET /api/reports/graph", server.RequirePermission(PermissionRead, http.HandlerFunc(server.graphReport)))
+	server.mux.Handle("GET /api/guardrails/usage", server.RequirePermission(PermissionRead, http.HandlerFunc(server.guardrailUsage)))
+	server.mux.Handle("GET /api/debug/diagnostics", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.getDiagnostics)))
+	server.mux.Handle("GET /api/debug/pprof/{$}", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.profileIndex)))
+	server.mux.Handle("GET /api/debug/pprof/{profile}", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.profile)))
+	server.mux.Handle("GET /api/debug/trace/users", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.listTracedUsers)))
+	server.mux.Handle("PUT /api/debug/trace/users/{user}", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.traceUser)))
+	server.mux.Handle("DELETE /api/debug/trace/users/{user}", server.RequirePermission(PermissionDiagnose, http.HandlerFunc(server.untraceUser)))
+	server.mux.Handle("GET /api/decisions", server.RequirePermission(PermissionEvaluate, http.HandlerFunc(server.getDecision)))
+	server.mux.Handle("GET /api/users/{user}/evaluation", server.RequirePermission(PermissionImpersonate, http.HandlerFunc(server.impersonateEvaluation)))
+	server.mux.Handle("GET /api/access/groups", server.RequireAuthentication(http.HandlerFunc(server.listRequestableGroups)))
+	server.mux.Handle("GET /api/access/permissions", server.RequireAuthentication(http.HandlerFunc(server.listRequestablePermissions)))
+	server.mux.Handle("POST /api/access/requests", server.RequireAuthentication(http.HandlerFunc(server.submitAccessRequest)))
+	server.mux.Handle("GET /api/access/requests", server.RequireAuthentication(http.HandlerFunc(server.listAccessRequests)))
+	server.mux.Handle("GET /api/access/requests/{id}", server.RequireAuthentication(http.HandlerFunc(server.getAccessRequest)))
+	server.mux.Handle("GET /api/access/links/{token}", server.RequireAuthentication(http.HandlerFunc(server.resolveRequestLink)))
+	server.mux.Handle("GET /api/access/approvals", server.RequireAuthentication(http.HandlerFunc(server.listAssignedApprovals)))
+	server.mux.Handle("PUT /api/directory/users", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.putDirectoryUsers)))
+	server.mux.Handle("GET /api/directory/users/{user}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getDirectoryUser)))
+	server.mux.Handle("PUT /api/groups/{id}/owners", server.RequireGroupAdmin(http.HandlerFunc(server.setGroupOwners)))
+	server.mux.Handle("GET /api/groups/{id}/owners", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getGroupOwners)))
+	server.mux.Handle("GET /api/folders", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listFolders)))
+	server.mux.Handle("POST /api/folders", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.createFolder)))
+	server.mux.Handle("GET /api/folders/{id}", server.RequirePermission(PermissionRead, http.HandlerFunc(server.getFolder)))
+	server.mux.Handle("PUT /api/folders/{id}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.updateFolder)))
+	server.mux.Handle("DELETE /api/folders/{id}", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.deleteFolder)))
+	server.mux.Handle("PUT /api/folders/{id}/permissions", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setFolderPermissions)))
+	server.mux.Handle("GET /api/relationships", server.RequirePermission(PermissionRead, http.HandlerFunc(server.listRelationships)))
+	server.mux.Handle("POST /api/relationships", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.writeRelationships)))
+	server.mux.Handle("DELETE /api/relationships", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.deleteRelationships)))
+	server.mux.Handle("GET /api/relationships/check", server.RequirePermission(PermissionEvaluate, http.HandlerFunc(server.checkRelationship)))
+	server.mux.Handle("GET /api/relationships/expand", server.RequirePermission(PermissionRead, http.HandlerFunc(server.expandRelationship)))
+	server.mux.Handle("GET /api/relationships/subjects", server.RequirePermission(PermissionRead, http.HandlerFunc(server.lookupSubjects)))
+	server.mux.Handle("PUT /api/groups/{id}/folder", server.RequireGroupAdmin(http.HandlerFunc(server.setGroupFolder)))
+	server.mux.Handle("PUT /api/catalogs/{application}/folder", server.RequirePermission(PermissionWrite, http.HandlerFunc(server.setCatalogFolder)))
+}
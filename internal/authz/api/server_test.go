@@ -0,0 +1,540 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupMockManagerAndServer() (*MockPolicyManager, *Server) {
+	manager := new(MockPolicyManager)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return manager, NewServer(manager, logger)
+}
+
+// metaPolicy returns a policy granting "admin" read and write access and "viewer" read access.
+func metaPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			*authz.NewPermission(PermissionRead, []string{"admins", "viewers"}),
+			*authz.NewPermission(PermissionWrite, []string{"admins"}),
+		},
+		[]authz.Group{
+			*authz.NewGroup("admins", []string{"admin"}),
+			*authz.NewGroup("viewers", []string{"viewer"}),
+		},
+	)
+}
+
+func serve(server *Server, method string, target string, user string, body string) *httptest.ResponseRecorder {
+	return serveWithHeaders(server, method, target, user, body, nil)
+}
+
+func serveWithHeaders(server *Server, method string, target string, user string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if user != "" {
+		request.Header.Set(UserHeader, user)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRequirePermission(t *testing.T) {
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, server := setupMockManagerAndServer()
+
+		response := serve(server, http.MethodGet, "/api/groups", "", "")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "viewer", `{"users":["user1"]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("denial is audited", func(t *testing.T) {
+		now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		manager := new(MockPolicyManager)
+		sink := new(mockAuditSink)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithAuditSink(sink), WithClock(NewFakeClock(now)))
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		sink.On("Record", mock.Anything, mock.MatchedBy(func(event audit.Event) bool {
+			event.ID = ""
+			return assert.ObjectsAreEqual(audit.Event{
+				Time:    now,
+				Actor:   "viewer",
+				Action:  "access.denied",
+				Subject: PermissionWrite,
+				Details: map[string]any{"reason": "no_matching_group", "method": http.MethodPut, "path": "/api/groups/1/users"},
+			}, event)
+		})).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "viewer", `{"users":["user1"]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		sink.AssertExpectations(t)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(nil, store.NewDataBaseError())
+
+		response := serve(server, http.MethodGet, "/api/groups", "admin", "")
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("stores the identity in the context", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		var identity Identity
+		handler := server.RequirePermission(PermissionRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, _ = IdentityFromContext(r.Context())
+		}))
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(UserHeader, "viewer")
+		request.Header.Set(MFAHeader, "true")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.Equal(t, Identity{User: "viewer", MFA: true}, identity)
+	})
+
+	t.Run("configured authenticators", func(t *testing.T) {
+		manager := new(MockPolicyManager)
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		sum := sha256.Sum256([]byte("secret"))
+		keys, err := authn.NewAPIKeys([]authn.APIKey{{User: "viewer", SHA256: hex.EncodeToString(sum[:])}})
+		assert.NoError(t, err)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithAuthenticators(keys))
+
+		response := serveWithHeaders(server, http.MethodGet, "/api/policy", "", "", map[string]string{authn.APIKeyHeader: "secret"})
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		response = serveWithHeaders(server, http.MethodGet, "/api/policy", "", "", map[string]string{authn.APIKeyHeader: "wrong"})
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Contains(t, response.Body.String(), "invalid credentials")
+
+		// the proxy headers are no longer trusted
+		response = serve(server, http.MethodGet, "/api/policy", "admin", "")
+		assert.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Contains(t, response.Body.String(), "authentication required")
+	})
+}
+
+func TestGetPolicy(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+	response := serve(server, http.MethodGet, "/api/policy", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"name":"admins"`)
+
+	manager.AssertExpectations(t)
+}
+
+func TestListGroups(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{{ID: 1, Name: "admins", Version: 1}}, nil)
+
+	response := serve(server, http.MethodGet, "/api/groups", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":1,"name":"admins","version":1}]`, response.Body.String())
+
+	manager.AssertExpectations(t)
+}
+
+// TestListGroups_Pages lists the groups a page at a time, checking every group is listed once in id order.
+func TestListGroups_Pages(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{
+		{ID: 3, Name: "cooks", Version: 1}, {ID: 1, Name: "admins", Version: 1}, {ID: 2, Name: "bakers", Version: 1},
+	}, nil)
+
+	response := serve(server, http.MethodGet, "/api/groups?limit=2", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":1,"name":"admins","version":1},{"id":2,"name":"bakers","version":1}]`, response.Body.String())
+	link := response.Header().Get("Link")
+	assert.Equal(t, `</api/groups?cursor=`+paging.Encode(paging.Key{2})+`&limit=2>; rel="next"`, link)
+
+	response = serve(server, http.MethodGet, link[1:strings.Index(link, ">")], "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":3,"name":"cooks","version":1}]`, response.Body.String())
+	assert.Empty(t, response.Header().Get("Link"))
+
+	response = serve(server, http.MethodGet, "/api/groups?cursor="+paging.Encode(paging.Key{"admins"}), "viewer", "")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	response = serve(server, http.MethodGet, "/api/groups?limit=0", "viewer", "")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestListPermissions(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	manager.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{{ID: 1, Name: "authz.read", Version: 1, Risk: authz.RiskLow}}, nil)
+
+	response := serve(server, http.MethodGet, "/api/permissions", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":1,"name":"authz.read","version":1,"risk":"low"}]`, response.Body.String())
+
+	manager.AssertExpectations(t)
+}
+
+func TestGetGroupsAndPermissions(t *testing.T) {
+	t.Run("groups by id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("GetGroups", mock.Anything, []int{2, 1}).Return([]store.GroupInfo[int]{{ID: 1, Name: "admins", Version: 1}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/groups?ids=2,1,2", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"id":1,"name":"admins","version":1}]`, response.Body.String())
+
+		manager.AssertExpectations(t)
+		manager.AssertNotCalled(t, "ListGroups", mock.Anything)
+	})
+
+	t.Run("permissions by id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("GetPermissions", mock.Anything, []int{1}).Return([]store.PermissionInfo[int]{{ID: 1, Name: "authz.read", Version: 1, Risk: authz.RiskLow}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/permissions?ids=1", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `[{"id":1,"name":"authz.read","version":1,"risk":"low"}]`, response.Body.String())
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodGet, "/api/groups?ids=1,two", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("too many ids", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		ids := make([]string, maxBatchIds+1)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i)
+		}
+
+		response := serve(server, http.MethodGet, "/api/permissions?ids="+strings.Join(ids, ","), "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestUpdateGroupUsers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("UpdateGroupUsers", mock.Anything, 1, []string{"user1"}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "admin", `{"users":["user1"]}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("membership source", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("UpdateGroupUsers", mock.MatchedBy(func(ctx context.Context) bool {
+			return store.MembershipSourceFromContext(ctx) == store.SourceLDAP
+		}), 1, []string{"user1"}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "admin", `{"users":["user1"],"source":"ldap"}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("invalid membership source", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "admin", `{"users":["user1"],"source":"nis"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		manager.AssertNotCalled(t, "UpdateGroupUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid group id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/abc/users", "admin", `{"users":["user1"]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "admin", `{"members":["user1"]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("UpdateGroupUsers", mock.Anything, 1, []string{"user1"}).Return(store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodPut, "/api/groups/1/users", "admin", `{"users":["user1"]}`)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.JSONEq(t, `{"error":"The group was not found","code":2}`, response.Body.String())
+
+		manager.AssertExpectations(t)
+	})
+}
+
+// TestCreatePermission_Wildcard creates wildcard permissions, checking a wildcard is only accepted as the last segment.
+func TestCreatePermission_Wildcard(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	manager.On("CreatePermission", mock.Anything, "recipes.*").Return(7, nil)
+
+	response := serve(server, http.MethodPost, "/api/permissions", "admin", `{"name":"recipes.*"}`)
+	assert.Equal(t, http.StatusCreated, response.Code)
+
+	response = serve(server, http.MethodPost, "/api/permissions", "admin", `{"name":"recipes.*.read"}`)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	manager.AssertNumberOfCalls(t, "CreatePermission", 1)
+}
+
+func TestCreateGroupAndPermission(t *testing.T) {
+	for _, kind := range []struct{ path, method string }{{"/api/groups", "CreateGroup"}, {"/api/permissions", "CreatePermission"}} {
+		t.Run(kind.method, func(t *testing.T) {
+			t.Run("success", func(t *testing.T) {
+				manager, server := setupMockManagerAndServer()
+				manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+				manager.On(kind.method, mock.Anything, "cooks").Return(7, nil)
+
+				response := serve(server, http.MethodPost, kind.path, "admin", `{"name":" cooks "}`)
+				assert.Equal(t, http.StatusCreated, response.Code)
+				assert.JSONEq(t, `{"id":7,"name":"cooks"}`, response.Body.String())
+
+				manager.AssertExpectations(t)
+			})
+
+			t.Run("empty name", func(t *testing.T) {
+				manager, server := setupMockManagerAndServer()
+				manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+				response := serve(server, http.MethodPost, kind.path, "admin", `{"name":"  "}`)
+				assert.Equal(t, http.StatusBadRequest, response.Code)
+				manager.AssertNotCalled(t, kind.method, mock.Anything, mock.Anything)
+			})
+
+			t.Run("name exists", func(t *testing.T) {
+				manager, server := setupMockManagerAndServer()
+				manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+				manager.On(kind.method, mock.Anything, "cooks").Return(0, store.NewNameExistsError())
+
+				response := serve(server, http.MethodPost, kind.path, "admin", `{"name":"cooks"}`)
+				assert.Equal(t, http.StatusConflict, response.Code)
+			})
+
+			t.Run("permission denied", func(t *testing.T) {
+				manager, server := setupMockManagerAndServer()
+				manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+				response := serve(server, http.MethodPost, kind.path, "viewer", `{"name":"cooks"}`)
+				assert.Equal(t, http.StatusForbidden, response.Code)
+				manager.AssertNotCalled(t, kind.method, mock.Anything, mock.Anything)
+			})
+		})
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("DeleteGroup", mock.Anything, 3).Return(&store.GroupDeletion{Members: 2, Grants: 1}, nil)
+
+		response := serve(server, http.MethodDelete, "/api/groups/3", "admin", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"members":2,"grants":1}`, response.Body.String())
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("DeleteGroup", mock.Anything, 3).Return(nil, store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodDelete, "/api/groups/3", "admin", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("invalid group id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodDelete, "/api/groups/abc", "admin", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestGetGroup(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("GetGroup", mock.Anything, 3).Return(&store.GroupDetails[int, int, string]{
+			GroupInfo:   store.GroupInfo[int]{ID: 3, Name: "cooks", Version: 2},
+			Users:       []string{"alice"},
+			Permissions: []store.PermissionInfo[int]{{ID: 1, Name: "recipes.read", Version: 1, Risk: authz.RiskLow}},
+		}, nil)
+
+		response := serve(server, http.MethodGet, "/api/groups/3", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"id":3,"name":"cooks","version":2,"users":["alice"],
+			"permissions":[{"id":1,"name":"recipes.read","version":1,"risk":"low"}]}`, response.Body.String())
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("GetGroup", mock.Anything, 3).Return(nil, store.NewGroupNotFoundError())
+
+		response := serve(server, http.MethodGet, "/api/groups/3", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("invalid group id", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodGet, "/api/groups/three", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		manager.AssertNotCalled(t, "GetGroup", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetUserGroups(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	manager.On("GetUserGroups", mock.Anything, "alice").Return([]store.GroupInfo[int]{{ID: 3, Name: "cooks", Version: 2}}, nil)
+
+	response := serve(server, http.MethodGet, "/api/users/alice/groups", "viewer", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `[{"id":3,"name":"cooks","version":2}]`, response.Body.String())
+
+	manager.AssertExpectations(t)
+}
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("DeleteUser", mock.Anything, "alice").Return(nil)
+
+		response := serve(server, http.MethodDelete, "/api/users/alice", "admin", "")
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("DeleteUser", mock.Anything, "alice").Return(store.NewNoUserRecordsDeletedError())
+
+		response := serve(server, http.MethodDelete, "/api/users/alice", "admin", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestUpdateUserGroups(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("UpdateUserGroups", mock.MatchedBy(func(ctx context.Context) bool {
+			return store.MembershipSourceFromContext(ctx) == store.SourceSCIM
+		}), "alice", []int{1, 2}).Return(nil)
+
+		response := serve(server, http.MethodPut, "/api/users/alice/groups", "admin", `{"groups":[1,2],"source":"scim"}`)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+
+		manager.AssertExpectations(t)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPut, "/api/users/alice/groups", "admin", `{"groups":["one"]}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		manager.AssertNotCalled(t, "UpdateUserGroups", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestStatusFromCode(t *testing.T) {
+	tests := []struct {
+		code     store.ErrorCode
+		expected int
+	}{
+		{code: store.DefaultError, expected: http.StatusInternalServerError},
+		{code: store.Concurrency, expected: http.StatusConflict},
+		{code: store.GroupNotFound, expected: http.StatusNotFound},
+		{code: store.NameAlreadyExist, expected: http.StatusConflict},
+		{code: store.NoUserRecordsDeleted, expected: http.StatusNotFound},
+		{code: store.DatabaseError, expected: http.StatusInternalServerError},
+		{code: store.PermissionNotFound, expected: http.StatusNotFound},
+		{code: store.InvalidArgument, expected: http.StatusBadRequest},
+		{code: store.NoChanges, expected: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, statusFromCode(tt.code))
+	}
+}
+
+func TestUpdateGroupUsers_GuardrailViolation(t *testing.T) {
+	manager, server := setupMockManagerAndServer()
+	setupRiskPolicy(manager)
+	manager.On("UpdateGroupUsers", mock.Anything, 10, []string{"alice", "bob"}).Return(&guardrail.ViolationError{
+		Violations: []guardrail.Violation{{Rule: "cooks", Action: guardrail.ActionBlock, Group: "cooks", Count: 2, Limit: 1}},
+	})
+
+	response := serve(server, http.MethodPut, "/api/groups/10/users", "admin", `{"users":["alice","bob"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	assert.Contains(t, response.Body.String(), `"violations":[{"rule":"cooks"`)
+}
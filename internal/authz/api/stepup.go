@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// StepUpRequest describes a request refused until the user completes multi-factor authentication.
+type StepUpRequest struct {
+	User string
+	// The action requiring the step-up, such as "grant_high_risk".
+	Action string
+	// The method and path of the refused request, to retry once the challenge is completed.
+	Method string
+	Path   string
+}
+
+// Challenge references a step-up challenge started by the embedding application, such as a FIDO
+// assertion, returned with the refusal so the frontend can have the user complete it and retry.
+type Challenge struct {
+	ID string `json:"id"`
+	// Where the frontend sends the user to complete the challenge, if anywhere.
+	URL string `json:"url,omitempty"`
+	// When the challenge expires, if it does.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// StepUpInitiator starts a step-up challenge for a refused request.
+type StepUpInitiator interface {
+	InitiateStepUp(ctx context.Context, request StepUpRequest) (*Challenge, error)
+}
+
+// StepUpFunc adapts a function to the StepUpInitiator interface.
+type StepUpFunc func(ctx context.Context, request StepUpRequest) (*Challenge, error)
+
+// InitiateStepUp calls the function.
+func (initiate StepUpFunc) InitiateStepUp(ctx context.Context, request StepUpRequest) (*Challenge, error) {
+	return initiate(ctx, request)
+}
+
+// stepUpErrorResponse is the body returned when a request requires multi-factor authentication.
+type stepUpErrorResponse struct {
+	Error     string     `json:"error"`
+	Challenge *Challenge `json:"challenge,omitempty"`
+}
+
+// requireMFA tells whether the user completed multi-factor authentication. When they did not, it
+// writes a forbidden response carrying a challenge started by the step-up initiator, if any, and
+// returns false. A challenge that cannot be started is logged and the request refused without one.
+func (server *Server) requireMFA(w http.ResponseWriter, r *http.Request, identity Identity, action string, message string) bool {
+	if identity.MFA {
+		return true
+	}
+
+	response := stepUpErrorResponse{Error: message}
+	if server.stepUp != nil {
+		challenge, err := server.stepUp.InitiateStepUp(r.Context(), StepUpRequest{
+			User:   identity.User,
+			Action: action,
+			Method: r.Method,
+			Path:   r.URL.Path,
+		})
+		if err != nil {
+			server.logger.Error("failed to initiate step-up challenge", "user", identity.User, "action", action, "error", err)
+		} else {
+			response.Challenge = challenge
+		}
+	}
+	writeJSON(w, http.StatusForbidden, response)
+	return false
+}
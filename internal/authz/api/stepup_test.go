@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestStepUp(t *testing.T) {
+	setup := func(initiate StepUpFunc) (*MockPolicyManager, *Server) {
+		manager := new(MockPolicyManager)
+		setupRiskPolicy(manager)
+		return manager, NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithStepUp(initiate))
+	}
+
+	t.Run("challenge", func(t *testing.T) {
+		var requested StepUpRequest
+		_, server := setup(func(ctx context.Context, request StepUpRequest) (*Challenge, error) {
+			requested = request
+			return &Challenge{ID: "ch-1", URL: "https://login.example.org/step-up/ch-1", ExpiresAt: time.Date(2025, 1, 2, 3, 9, 5, 0, time.UTC)}, nil
+		})
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[1,2]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		assert.JSONEq(t, `{"error":"multi-factor authentication is required to imply high risk permissions",
+			"challenge":{"id":"ch-1","url":"https://login.example.org/step-up/ch-1","expires_at":"2025-01-02T03:09:05Z"}}`, response.Body.String())
+		assert.Equal(t, StepUpRequest{User: "admin", Action: "imply_high_risk", Method: http.MethodPut, Path: "/api/permissions/3/implies"}, requested)
+	})
+
+	t.Run("initiator error", func(t *testing.T) {
+		_, server := setup(func(ctx context.Context, request StepUpRequest) (*Challenge, error) {
+			return nil, errors.New("mfa provider down")
+		})
+
+		response := serve(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[1,2]}`)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		assert.JSONEq(t, `{"error":"multi-factor authentication is required to imply high risk permissions"}`, response.Body.String())
+	})
+
+	t.Run("mfa completed", func(t *testing.T) {
+		manager, server := setup(func(ctx context.Context, request StepUpRequest) (*Challenge, error) {
+			t.Fatal("no challenge expected")
+			return nil, nil
+		})
+		manager.On("SetPermissionImplications", mock.Anything, 3, []int{2}).Return(nil)
+
+		response := serveWithHeaders(server, http.MethodPut, "/api/permissions/3/implies", "admin", `{"implies":[2]}`, mfa)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/salmarsumi/recipes/internal/authz/syncreport"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// submitSyncReport records the report of a sync run. Connectors submit their reports
+// with the credentials they use to apply the changes, so it requires write access.
+func (server *Server) submitSyncReport(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSyncReports(w) {
+		return
+	}
+
+	var report syncreport.Report
+	if err := decodeJSON(w, r, &report); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := report.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	identity, _ := IdentityFromContext(r.Context())
+	report.SubmittedBy = identity.User
+
+	id, err := server.syncReports.Create(r.Context(), report)
+	if err != nil {
+		server.writeSyncReportError(w, err)
+		return
+	}
+
+	report.ID = id
+	writeJSON(w, http.StatusCreated, report)
+}
+
+// syncReportLimits bounds the pages of sync reports, listed most recent first.
+var syncReportLimits = paging.Limits{Default: 100, Max: 1000}
+
+func (server *Server) listSyncReports(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSyncReports(w) {
+		return
+	}
+
+	page, ok := parsePage(w, r, syncReportLimits)
+	if !ok {
+		return
+	}
+
+	// fetch one more report than asked for to know whether there is a next page
+	filter := syncreport.Filter{Connector: r.URL.Query().Get("connector"), Limit: page.Limit + 1, After: page.After}
+	reports, err := server.syncReports.List(r.Context(), filter)
+	if err != nil {
+		server.writeSyncReportError(w, err)
+		return
+	}
+
+	reports, next := paging.Trim(reports, page.Limit, syncreport.ListKey)
+	writePage(w, r, reports, next)
+}
+
+func (server *Server) getSyncReport(w http.ResponseWriter, r *http.Request) {
+	if !server.requireSyncReports(w) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid sync report id")
+		return
+	}
+
+	report, err := server.syncReports.Get(r.Context(), id)
+	if err != nil {
+		server.writeSyncReportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (server *Server) requireSyncReports(w http.ResponseWriter) bool {
+	if server.syncReports == nil {
+		writeError(w, http.StatusNotFound, "sync reports are not configured")
+		return false
+	}
+	return true
+}
+
+// writeSyncReportError maps a sync report store error to the matching HTTP status code.
+func (server *Server) writeSyncReportError(w http.ResponseWriter, err error) {
+	if errors.Is(err, syncreport.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, paging.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	server.logger.Error("sync report store failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/syncreport"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockSyncReportStore is a mock implementation of the syncreport.Store interface
+type mockSyncReportStore struct {
+	mock.Mock
+}
+
+func (m *mockSyncReportStore) Create(ctx context.Context, report syncreport.Report) (int, error) {
+	args := m.Called(ctx, report)
+	return args.Int(0), args.Error(1)
+}
+func (m *mockSyncReportStore) Get(ctx context.Context, id int) (*syncreport.Report, error) {
+	args := m.Called(ctx, id)
+	report, _ := args.Get(0).(*syncreport.Report)
+	return report, args.Error(1)
+}
+func (m *mockSyncReportStore) List(ctx context.Context, filter syncreport.Filter) ([]syncreport.Report, error) {
+	args := m.Called(ctx, filter)
+	reports, _ := args.Get(0).([]syncreport.Report)
+	return reports, args.Error(1)
+}
+func (m *mockSyncReportStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+func setupSyncReportServer() (*MockPolicyManager, *mockSyncReportStore, *Server) {
+	manager := new(MockPolicyManager)
+	reports := new(mockSyncReportStore)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), WithSyncReports(reports))
+	manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+	return manager, reports, server
+}
+
+const syncReportBody = `{
+	"connector": "ldap",
+	"started_at": "2024-05-01T12:00:00Z",
+	"finished_at": "2024-05-01T12:01:00Z",
+	"entries": [
+		{"action": "added", "user": "alice", "group": "cooks"},
+		{"action": "skipped", "user": "bob", "group": "cooks", "reason": "protected manual membership"}
+	],
+	"errors": ["group bakers not found"]
+}`
+
+func TestSubmitSyncReport(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("Create", mock.Anything, mock.MatchedBy(func(report syncreport.Report) bool {
+			return report.SubmittedBy == "admin" && report.Summary == syncreport.Summary{Added: 1, Skipped: 1, Errors: 1}
+		})).Return(7, nil)
+
+		response := serve(server, http.MethodPost, "/api/sync/reports", "admin", syncReportBody)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Contains(t, response.Body.String(), `"id":7`)
+
+		reports.AssertExpectations(t)
+	})
+
+	t.Run("invalid report", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+
+		response := serve(server, http.MethodPost, "/api/sync/reports", "admin", `{"connector": "", "started_at": "2024-05-01T12:00:00Z", "finished_at": "2024-05-01T12:01:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		reports.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+
+		response := serve(server, http.MethodPost, "/api/sync/reports", "viewer", syncReportBody)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		reports.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+
+		response := serve(server, http.MethodPost, "/api/sync/reports", "admin", syncReportBody)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestListSyncReports(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("List", mock.Anything, syncreport.Filter{Connector: "ldap", Limit: 6}).Return([]syncreport.Report{{ID: 1, Connector: "ldap"}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/sync/reports?connector=ldap&limit=5", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"connector":"ldap"`)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		reports.On("List", mock.Anything, syncreport.Filter{Limit: 3}).Return([]syncreport.Report{
+			{ID: 3, FinishedAt: finished}, {ID: 2, FinishedAt: finished}, {ID: 1, FinishedAt: finished},
+		}, nil)
+		reports.On("List", mock.Anything, syncreport.Filter{Limit: 3, After: paging.Key{finished, 2}}).Return([]syncreport.Report{
+			{ID: 1, FinishedAt: finished},
+		}, nil)
+
+		response := serve(server, http.MethodGet, "/api/sync/reports?limit=2", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		cursor := paging.Encode(paging.Key{finished, 2})
+		assert.Equal(t, `</api/sync/reports?cursor=`+cursor+`&limit=2>; rel="next"`, response.Header().Get("Link"))
+		assert.NotContains(t, response.Body.String(), `"id":1`)
+
+		response = serve(server, http.MethodGet, "/api/sync/reports?limit=2&cursor="+cursor, "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, response.Header().Get("Link"))
+		assert.Contains(t, response.Body.String(), `"id":1`)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("List", mock.Anything, syncreport.Filter{Limit: 101, After: paging.Key{"recipes"}}).Return(nil, paging.ErrInvalidCursor)
+
+		response := serve(server, http.MethodGet, "/api/sync/reports?cursor=not-a-cursor", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = serve(server, http.MethodGet, "/api/sync/reports?cursor="+paging.Encode(paging.Key{"recipes"}), "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		_, _, server := setupSyncReportServer()
+
+		response := serve(server, http.MethodGet, "/api/sync/reports?limit=-1", "viewer", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("List", mock.Anything, syncreport.Filter{Limit: 101}).Return(nil, errors.New("db error"))
+
+		response := serve(server, http.MethodGet, "/api/sync/reports", "viewer", "")
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
+
+func TestGetSyncReport(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("Get", mock.Anything, 1).Return(&syncreport.Report{ID: 1, Connector: "scim"}, nil)
+
+		response := serve(server, http.MethodGet, "/api/sync/reports/1", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"connector":"scim"`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, reports, server := setupSyncReportServer()
+		reports.On("Get", mock.Anything, 2).Return(nil, syncreport.ErrNotFound)
+
+		response := serve(server, http.MethodGet, "/api/sync/reports/2", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
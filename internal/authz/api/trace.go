@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+)
+
+// traceSwitch holds the users whose decisions are traced to the logs. It is switched at runtime
+// through the debug API, so denials can be investigated in production without redeploying,
+// and is reset when the server restarts.
+type traceSwitch struct {
+	mu    sync.RWMutex
+	users map[string]struct{}
+}
+
+func newTraceSwitch() *traceSwitch {
+	return &traceSwitch{users: map[string]struct{}{}}
+}
+
+func (traces *traceSwitch) enabled(user string) bool {
+	traces.mu.RLock()
+	defer traces.mu.RUnlock()
+	_, ok := traces.users[user]
+	return ok
+}
+
+func (traces *traceSwitch) set(user string, enabled bool) {
+	traces.mu.Lock()
+	defer traces.mu.Unlock()
+	if enabled {
+		traces.users[user] = struct{}{}
+	} else {
+		delete(traces.users, user)
+	}
+}
+
+func (traces *traceSwitch) list() []string {
+	traces.mu.RLock()
+	defer traces.mu.RUnlock()
+	users := make([]string, 0, len(traces.users))
+	for user := range traces.users {
+		users = append(users, user)
+	}
+	slices.Sort(users)
+	return users
+}
+
+// tracedUsersResponse is the body returned by GET /api/debug/trace/users.
+type tracedUsersResponse struct {
+	Users []string `json:"users"`
+}
+
+// listTracedUsers returns the users whose decisions are traced.
+func (server *Server) listTracedUsers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, tracedUsersResponse{Users: server.traces.list()})
+}
+
+// traceUser starts tracing the decisions of the user in the request path.
+func (server *Server) traceUser(w http.ResponseWriter, r *http.Request) {
+	server.setTrace(w, r, true)
+}
+
+// untraceUser stops tracing the decisions of the user in the request path.
+func (server *Server) untraceUser(w http.ResponseWriter, r *http.Request) {
+	server.setTrace(w, r, false)
+}
+
+func (server *Server) setTrace(w http.ResponseWriter, r *http.Request, enabled bool) {
+	identity, _ := IdentityFromContext(r.Context())
+	user := r.PathValue("user")
+
+	server.traces.set(user, enabled)
+	server.logger.Info("decision tracing switched", "actor", identity.User, "user", user, "enabled", enabled)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// evaluateDecision evaluates the user for a decision on a request with the given attributes, collecting the steps of the evaluation
+// when the request asks for them or the user is traced, and logging them for traced users.
+func (server *Server) evaluateDecision(policy *authz.Policy, user string, permission string, attributes condition.Attributes, trace bool) (*authz.PolicyEvaluationResult, []authz.TraceStep, error) {
+	logged := server.traces.enabled(user)
+	if !trace && !logged {
+		result, err := policy.EvaluateWith(user, attributes)
+		return result, nil, err
+	}
+
+	var steps []authz.TraceStep
+	result, err := policy.EvaluateTracedWith(user, attributes, func(step authz.TraceStep) {
+		steps = append(steps, step)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if logged {
+		server.logger.Info("decision trace", "user", user, "permission", permission,
+			"allowed", policy.CheckEvaluated(user, permission, result).Allowed, "steps", steps)
+	}
+	if !trace {
+		return result, nil, nil
+	}
+	return result, steps, nil
+}
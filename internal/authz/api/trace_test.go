@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupTraceServer(logs *bytes.Buffer) *Server {
+	manager := new(MockPolicyManager)
+	policy := metaPolicy()
+	policy.Groups = append(policy.Groups,
+		*authz.NewGroup("services", []string{"recipes"}),
+		*authz.NewGroup("cooks", []string{"alice"}))
+	policy.Permissions = append(policy.Permissions,
+		authz.Permission{Name: PermissionEvaluate, Groups: []string{"services", "admins"}},
+		authz.Permission{Name: PermissionDiagnose, Groups: []string{"admins"}},
+		authz.Permission{Name: "recipes.read", Groups: []string{"cooks"}},
+		authz.Permission{Name: "recipes.delete", Groups: []string{"admins"}},
+		authz.Permission{Name: "recipes.*", Groups: []string{"services"}})
+	manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+	return NewServer(manager, slog.New(slog.NewTextHandler(logs, nil)))
+}
+
+func TestGetDecision_Trace(t *testing.T) {
+	t.Run("explains the decision", func(t *testing.T) {
+		server := setupTraceServer(new(bytes.Buffer))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.delete&trace=true", "admin", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "no-store", response.Header().Get("Cache-Control"))
+		assert.Contains(t, response.Body.String(), `"allowed":false`)
+		assert.Contains(t, response.Body.String(), `{"kind":"group","name":"cooks","matched":true}`)
+		assert.Contains(t, response.Body.String(), `{"kind":"permission","name":"recipes.delete","matched":false}`)
+	})
+
+	t.Run("requires the diagnose permission", func(t *testing.T) {
+		server := setupTraceServer(new(bytes.Buffer))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read&trace=true", "recipes", "")
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("invalid trace", func(t *testing.T) {
+		server := setupTraceServer(new(bytes.Buffer))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read&trace=maybe", "recipes", "")
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("untraced decisions carry no trace", func(t *testing.T) {
+		server := setupTraceServer(new(bytes.Buffer))
+
+		response := serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.NotContains(t, response.Body.String(), `"trace"`)
+	})
+}
+
+func TestTraceUsers(t *testing.T) {
+	logs := new(bytes.Buffer)
+	server := setupTraceServer(logs)
+
+	response := serve(server, http.MethodPut, "/api/debug/trace/users/alice", "admin", "")
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	response = serve(server, http.MethodGet, "/api/debug/trace/users", "admin", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"users":["alice"]}`, response.Body.String())
+
+	// the decisions of a traced user are logged without the caller asking for a trace
+	response = serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NotContains(t, response.Body.String(), `"trace"`)
+	assert.Contains(t, logs.String(), `msg="decision trace" user=alice permission=recipes.read allowed=true`)
+
+	response = serve(server, http.MethodDelete, "/api/debug/trace/users/alice", "admin", "")
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	logs.Reset()
+
+	serve(server, http.MethodGet, "/api/decisions?user=alice&permission=recipes.read", "recipes", "")
+	assert.NotContains(t, logs.String(), "decision trace")
+
+	response = serve(server, http.MethodPut, "/api/debug/trace/users/alice", "viewer", "")
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestTraceUsers_Wildcard(t *testing.T) {
+	logs := new(bytes.Buffer)
+	server := setupTraceServer(logs)
+
+	response := serve(server, http.MethodPut, "/api/debug/trace/users/recipes", "admin", "")
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	// a permission granted through a wildcard is logged as allowed
+	response = serve(server, http.MethodGet, "/api/decisions?user=recipes&permission=recipes.read", "recipes", "")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"allowed":true`)
+	assert.Contains(t, logs.String(), `msg="decision trace" user=recipes permission=recipes.read allowed=true`)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// API versions. A breaking change, such as resource-scoped permissions, ships as a new
+// version while the previous versions keep working unchanged:
+//   - a version serves the routes of the versions before it, unless it replaces them,
+//   - a version is only removed after being announced deprecated for a full release cycle,
+//   - unversioned paths are served as v1, so clients written before versioning never break.
+const (
+	Version1 = "v1"
+)
+
+// Versions lists the supported API versions, oldest first.
+var Versions = []string{Version1}
+
+// VersionHeader selects the version serving an unversioned path, such as /api/groups.
+// It is also set on every response to the version that served it.
+const VersionHeader = "API-Version"
+
+// versionedPath matches paths naming their version, such as /api/v1/groups.
+var versionedPath = regexp.MustCompile(`^/api/(v[0-9]+)(/.*)$`)
+
+type versionContextKey struct{}
+
+// VersionFromContext returns the API version serving the request.
+func VersionFromContext(ctx context.Context) string {
+	version, ok := ctx.Value(versionContextKey{}).(string)
+	if !ok {
+		return Version1
+	}
+	return version
+}
+
+// apiVersion holds the routes a version adds or replaces.
+type apiVersion struct {
+	name string
+	mux  *http.ServeMux
+}
+
+// addVersion registers a version after the existing ones and returns the mux of its routes.
+func (server *Server) addVersion(name string) *http.ServeMux {
+	mux := http.NewServeMux()
+	server.versions = append(server.versions, apiVersion{name: name, mux: mux})
+	return mux
+}
+
+// serveVersion negotiates the version of the request from its path or VersionHeader and
+// serves it with the most recent route of that version or of the versions before it.
+func (server *Server) serveVersion(w http.ResponseWriter, r *http.Request) {
+	// an unknown version in the path is a route that does not exist, while an unknown
+	// version in the header is a malformed request
+	version, status := r.Header.Get(VersionHeader), http.StatusBadRequest
+	if match := versionedPath.FindStringSubmatch(r.URL.Path); match != nil {
+		version, status = match[1], http.StatusNotFound
+		r = withPath(r, "/api"+match[2])
+	}
+	if version == "" {
+		version = Version1
+	}
+
+	index := slices.IndexFunc(server.versions, func(candidate apiVersion) bool { return candidate.name == version })
+	if index < 0 {
+		writeError(w, status, "unsupported API version "+version+", supported versions are "+strings.Join(Versions, ", "))
+		return
+	}
+
+	w.Header().Set(VersionHeader, version)
+	r = r.WithContext(context.WithValue(r.Context(), versionContextKey{}, version))
+	for i := index; i > 0; i-- {
+		if _, pattern := server.versions[i].mux.Handler(r); pattern != "" {
+			server.versions[i].mux.ServeHTTP(w, r)
+			return
+		}
+	}
+	server.versions[0].mux.ServeHTTP(w, r)
+}
+
+// withPath returns a shallow copy of the request for another path, as http.StripPrefix does.
+func withPath(r *http.Request, path string) *http.Request {
+	copied := new(http.Request)
+	*copied = *r
+	copied.URL = new(url.URL)
+	*copied.URL = *r.URL
+	copied.URL.Path = path
+	copied.URL.RawPath = ""
+	return copied
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVersions(t *testing.T) {
+	t.Run("versioned path", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{{ID: 1, Name: "admins"}}, nil)
+
+		response := serve(server, http.MethodGet, "/api/v1/groups", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, Version1, response.Header().Get(VersionHeader))
+		assert.Contains(t, response.Body.String(), `"admins"`)
+	})
+
+	t.Run("unversioned path", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{}, nil)
+
+		response := serve(server, http.MethodGet, "/api/groups", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, Version1, response.Header().Get(VersionHeader))
+	})
+
+	t.Run("unsupported path version", func(t *testing.T) {
+		_, server := setupMockManagerAndServer()
+
+		response := serve(server, http.MethodGet, "/api/v9/groups", "viewer", "")
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Contains(t, response.Body.String(), "supported versions are v1")
+	})
+
+	t.Run("unsupported header version", func(t *testing.T) {
+		_, server := setupMockManagerAndServer()
+
+		response := serveWithHeaders(server, http.MethodGet, "/api/groups", "viewer", "", map[string]string{VersionHeader: "v9"})
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("later version replaces routes", func(t *testing.T) {
+		manager, server := setupMockManagerAndServer()
+		manager.On("ReadPolicy", mock.Anything).Return(metaPolicy(), nil)
+		manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{}, nil)
+		server.addVersion("v2").HandleFunc("GET /api/groups", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"version": VersionFromContext(r.Context())})
+		})
+
+		response := serve(server, http.MethodGet, "/api/v2/groups", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, `{"version":"v2"}`, response.Body.String())
+
+		// routes the version does not replace are served by the previous versions
+		manager.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{}, nil)
+		response = serveWithHeaders(server, http.MethodGet, "/api/permissions", "viewer", "", map[string]string{VersionHeader: "v2"})
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "v2", response.Header().Get(VersionHeader))
+
+		response = serve(server, http.MethodGet, "/api/v1/groups", "viewer", "")
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, "[]", response.Body.String())
+	})
+}
@@ -0,0 +1,333 @@
+// Package approval implements the workflow for changes that need a second person's approval
+// before they are applied to the policy store.
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Kind identifies the change an approval request applies once approved.
+type Kind string
+
+const (
+	// KindPermissionGrant grants a permission to a group.
+	KindPermissionGrant Kind = "permission_grant"
+	// KindGroupMembership adds a user to a group.
+	KindGroupMembership Kind = "group_membership"
+	// KindGuardrailReview asks a reviewer to confirm a change that exceeded a guardrail.
+	// The change is already applied, so approving it has no further effect.
+	KindGuardrailReview Kind = "guardrail_review"
+)
+
+// Status is the lifecycle state of an approval request.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+var (
+	// ErrNotFound is returned when the approval request does not exist.
+	ErrNotFound = errors.New("approval request not found")
+	// ErrNotPending is returned when deciding on a request that was already decided.
+	ErrNotPending = errors.New("approval request is not pending")
+	// ErrSelfApproval is returned when the requester tries to decide on their own request.
+	ErrSelfApproval = errors.New("approval request cannot be decided by its requester")
+	// ErrNotAssigned is returned when deciding on a request routed to other approvers before it was escalated.
+	ErrNotAssigned = errors.New("approval request is assigned to other approvers")
+)
+
+// Request is a change waiting for, or having received, an approval decision.
+type Request struct {
+	ID            int        `json:"id"`
+	Kind          Kind       `json:"kind"`
+	GroupID       int        `json:"group_id,omitempty"`
+	PermissionID  int        `json:"permission_id,omitempty"`
+	UserID        string     `json:"user_id,omitempty"`
+	RequestedBy   string     `json:"requested_by"`
+	Justification string     `json:"justification,omitempty"`
+	Status        Status     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DecidedBy     string     `json:"decided_by,omitempty"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+	// The approvers the request was routed to. Until the request is escalated only they may
+	// decide on it; a request without approvers may be decided by any approver.
+	Approvers   []string   `json:"approvers,omitempty"`
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"`
+}
+
+// Assigned reports whether the approver may decide on the request under its routing.
+func (request *Request) Assigned(approver string) bool {
+	return len(request.Approvers) == 0 || request.EscalatedAt != nil || slices.Contains(request.Approvers, approver)
+}
+
+// Store persists approval requests.
+type Store interface {
+	Create(ctx context.Context, request Request) (int, error)
+	Get(ctx context.Context, id int) (*Request, error)
+	List(ctx context.Context, status Status) ([]Request, error)
+	ListRequestedBy(ctx context.Context, requester string) ([]Request, error)
+	Decide(ctx context.Context, id int, status Status, decidedBy string, decidedAt time.Time) error
+	Escalate(ctx context.Context, id int, escalatedAt time.Time) error
+}
+
+// Router chooses the approvers of new requests, such as the manager of the requester.
+type Router interface {
+	// Approvers returns the users who should decide on the request, or none to let any approver decide.
+	Approvers(ctx context.Context, request Request) ([]string, error)
+}
+
+// Granter applies approved permission grants and group memberships to the policy store.
+type Granter interface {
+	GrantPermission(ctx context.Context, groupId int, permissionId int) error
+	AddGroupUser(ctx context.Context, groupId int, userId string) error
+}
+
+// Workflow coordinates submitting, approving and rejecting approval requests.
+type Workflow struct {
+	store   Store
+	granter Granter
+	router  Router
+	clock   clock.Clock
+}
+
+// WorkflowOption configures optional Workflow settings.
+type WorkflowOption func(*Workflow)
+
+// WithRouter sets the router choosing the approvers of new requests.
+// By default any approver may decide on any request.
+func WithRouter(router Router) WorkflowOption {
+	return func(workflow *Workflow) {
+		workflow.router = router
+	}
+}
+
+// WithClock sets the clock dating the requests and their decisions, and scheduling the escalations.
+// The default is the system clock.
+func WithClock(clock clock.Clock) WorkflowOption {
+	return func(workflow *Workflow) {
+		workflow.clock = clock
+	}
+}
+
+// NewWorkflow creates a new Workflow persisting requests in the given store
+// and applying approved changes through the given granter.
+func NewWorkflow(store Store, granter Granter, options ...WorkflowOption) *Workflow {
+	workflow := &Workflow{store: store, granter: granter, clock: clock.System()}
+	for _, option := range options {
+		option(workflow)
+	}
+	return workflow
+}
+
+// Submit records a new pending approval request.
+//
+// Parameters:
+//
+//	request - the change to approve. ID, Status and decision fields are ignored.
+//
+// Returns:
+//
+//	*Request - the stored request.
+//	error - an error if the request is incomplete or cannot be stored.
+func (workflow *Workflow) Submit(ctx context.Context, request Request) (*Request, error) {
+	if request.RequestedBy == "" {
+		return nil, errors.New("requester is empty")
+	}
+	switch request.Kind {
+	case KindPermissionGrant, KindGuardrailReview:
+	case KindGroupMembership:
+		if request.GroupID == 0 || request.UserID == "" {
+			return nil, errors.New("group membership requests need a group and a user")
+		}
+	default:
+		return nil, fmt.Errorf("unknown approval request kind %q", request.Kind)
+	}
+
+	request.Status = StatusPending
+	request.CreatedAt = workflow.clock.Now()
+	request.DecidedBy = ""
+	request.DecidedAt = nil
+	request.Approvers = nil
+	request.EscalatedAt = nil
+
+	if workflow.router != nil {
+		approvers, err := workflow.router.Approvers(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("route approval request: %w", err)
+		}
+		// the requester may never decide, so routing to them alone would leave the request stuck
+		request.Approvers = slices.DeleteFunc(approvers, func(approver string) bool { return approver == request.RequestedBy })
+	}
+
+	id, err := workflow.store.Create(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	request.ID = id
+	return &request, nil
+}
+
+// Approve applies the change described by the request and marks it approved.
+// The change is applied before the decision is stored; applying a grant twice is harmless,
+// so a failure to store the decision can be retried safely.
+func (workflow *Workflow) Approve(ctx context.Context, id int, approver string) (*Request, error) {
+	request, err := workflow.pending(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Kind {
+	case KindPermissionGrant:
+		err = workflow.granter.GrantPermission(ctx, request.GroupID, request.PermissionID)
+		if store.IsNoChanges(err) {
+			err = nil
+		}
+	case KindGroupMembership:
+		err = workflow.granter.AddGroupUser(ctx, request.GroupID, request.UserID)
+		if store.IsNoChanges(err) {
+			err = nil
+		}
+	case KindGuardrailReview:
+		// the reviewed change was applied when it was made
+	default:
+		err = fmt.Errorf("unknown approval request kind %q", request.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return workflow.decide(ctx, request, StatusApproved, approver)
+}
+
+// Reject marks the request rejected without applying it.
+func (workflow *Workflow) Reject(ctx context.Context, id int, approver string) (*Request, error) {
+	request, err := workflow.pending(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	return workflow.decide(ctx, request, StatusRejected, approver)
+}
+
+// Get returns the approval request with the given id.
+func (workflow *Workflow) Get(ctx context.Context, id int) (*Request, error) {
+	return workflow.store.Get(ctx, id)
+}
+
+// List returns the approval requests in the given status.
+func (workflow *Workflow) List(ctx context.Context, status Status) ([]Request, error) {
+	return workflow.store.List(ctx, status)
+}
+
+// ListRequestedBy returns the approval requests submitted by the given requester, most recent first.
+func (workflow *Workflow) ListRequestedBy(ctx context.Context, requester string) ([]Request, error) {
+	return workflow.store.ListRequestedBy(ctx, requester)
+}
+
+// Escalate opens the pending requests routed to approvers who did not decide within the timeout
+// to every approver. It returns the escalated requests.
+func (workflow *Workflow) Escalate(ctx context.Context, timeout time.Duration) ([]Request, error) {
+	requests, err := workflow.store.List(ctx, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	now := workflow.clock.Now()
+	escalated := []Request{}
+	for _, request := range requests {
+		if len(request.Approvers) == 0 || request.EscalatedAt != nil || now.Sub(request.CreatedAt) < timeout {
+			continue
+		}
+		if err := workflow.store.Escalate(ctx, request.ID, now); err != nil {
+			// the request may have been decided meanwhile
+			if errors.Is(err, ErrNotPending) {
+				continue
+			}
+			return escalated, err
+		}
+		request.EscalatedAt = &now
+		escalated = append(escalated, request)
+	}
+	return escalated, nil
+}
+
+func (workflow *Workflow) pending(ctx context.Context, id int, approver string) (*Request, error) {
+	if approver == "" {
+		return nil, errors.New("approver is empty")
+	}
+
+	request, err := workflow.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if request.RequestedBy == approver {
+		return nil, ErrSelfApproval
+	}
+	if !request.Assigned(approver) {
+		return nil, ErrNotAssigned
+	}
+
+	return request, nil
+}
+
+func (workflow *Workflow) decide(ctx context.Context, request *Request, status Status, approver string) (*Request, error) {
+	decidedAt := workflow.clock.Now()
+	err := workflow.store.Decide(ctx, request.ID, status, approver, decidedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = status
+	request.DecidedBy = approver
+	request.DecidedAt = &decidedAt
+	return request, nil
+}
+
+// Escalator periodically escalates the requests their assigned approvers did not decide in time.
+type Escalator struct {
+	workflow *Workflow
+	timeout  time.Duration
+	logger   *slog.Logger
+}
+
+// NewEscalator creates a new Escalator opening requests to every approver after the timeout.
+func NewEscalator(workflow *Workflow, timeout time.Duration, logger *slog.Logger) *Escalator {
+	return &Escalator{workflow: workflow, timeout: timeout, logger: logger}
+}
+
+// Run escalates the overdue requests immediately and then every interval until the context is done.
+func (escalator *Escalator) Run(ctx context.Context, interval time.Duration) {
+	ticker := escalator.workflow.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		escalated, err := escalator.workflow.Escalate(ctx, escalator.timeout)
+		if err != nil {
+			escalator.logger.Error("failed to escalate approval requests", "error", err)
+		}
+		for _, request := range escalated {
+			escalator.logger.Warn("approval request escalated to every approver", "id", request.ID, "approvers", request.Approvers)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authzstore "github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockStore is a mock implementation of the Store interface
+type mockStore struct {
+	mock.Mock
+}
+
+func (m *mockStore) Create(ctx context.Context, request Request) (int, error) {
+	args := m.Called(ctx, request)
+	return args.Int(0), args.Error(1)
+}
+func (m *mockStore) Get(ctx context.Context, id int) (*Request, error) {
+	args := m.Called(ctx, id)
+	request, _ := args.Get(0).(*Request)
+	return request, args.Error(1)
+}
+func (m *mockStore) List(ctx context.Context, status Status) ([]Request, error) {
+	args := m.Called(ctx, status)
+	requests, _ := args.Get(0).([]Request)
+	return requests, args.Error(1)
+}
+func (m *mockStore) ListRequestedBy(ctx context.Context, requester string) ([]Request, error) {
+	args := m.Called(ctx, requester)
+	requests, _ := args.Get(0).([]Request)
+	return requests, args.Error(1)
+}
+func (m *mockStore) Escalate(ctx context.Context, id int, escalatedAt time.Time) error {
+	return m.Called(ctx, id, escalatedAt).Error(0)
+}
+func (m *mockStore) Decide(ctx context.Context, id int, status Status, decidedBy string, decidedAt time.Time) error {
+	return m.Called(ctx, id, status, decidedBy, decidedAt).Error(0)
+}
+
+// mockGranter is a mock implementation of the Granter interface
+type mockGranter struct {
+	mock.Mock
+}
+
+func (m *mockGranter) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	return m.Called(ctx, groupId, permissionId).Error(0)
+}
+func (m *mockGranter) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	return m.Called(ctx, groupId, userId).Error(0)
+}
+
+// mockRouter is a mock implementation of the Router interface
+type mockRouter struct {
+	mock.Mock
+}
+
+func (m *mockRouter) Approvers(ctx context.Context, request Request) ([]string, error) {
+	args := m.Called(ctx, request)
+	approvers, _ := args.Get(0).([]string)
+	return approvers, args.Error(1)
+}
+
+var now = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func setupWorkflow() (*mockStore, *mockGranter, *Workflow) {
+	store := new(mockStore)
+	granter := new(mockGranter)
+	workflow := NewWorkflow(store, granter, WithClock(NewFakeClock(now)))
+	return store, granter, workflow
+}
+
+func pendingRequest() *Request {
+	return &Request{
+		ID:           1,
+		Kind:         KindPermissionGrant,
+		GroupID:      2,
+		PermissionID: 3,
+		RequestedBy:  "alice",
+		Status:       StatusPending,
+		CreatedAt:    now,
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		store, _, workflow := setupWorkflow()
+		store.On("Create", ctx, Request{
+			Kind:         KindPermissionGrant,
+			GroupID:      2,
+			PermissionID: 3,
+			RequestedBy:  "alice",
+			Status:       StatusPending,
+			CreatedAt:    now,
+		}).Return(1, nil)
+
+		request, err := workflow.Submit(ctx, Request{Kind: KindPermissionGrant, GroupID: 2, PermissionID: 3, RequestedBy: "alice"})
+		assert.NoError(t, err)
+		assert.Equal(t, pendingRequest(), request)
+
+		store.AssertExpectations(t)
+	})
+
+	t.Run("empty requester", func(t *testing.T) {
+		_, _, workflow := setupWorkflow()
+
+		request, err := workflow.Submit(ctx, Request{Kind: KindPermissionGrant})
+		assert.Error(t, err)
+		assert.Nil(t, request)
+	})
+
+	t.Run("incomplete group membership", func(t *testing.T) {
+		_, _, workflow := setupWorkflow()
+
+		request, err := workflow.Submit(ctx, Request{Kind: KindGroupMembership, GroupID: 2, RequestedBy: "alice"})
+		assert.Error(t, err)
+		assert.Nil(t, request)
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		_, _, workflow := setupWorkflow()
+
+		request, err := workflow.Submit(ctx, Request{Kind: "unknown", RequestedBy: "alice"})
+		assert.Error(t, err)
+		assert.Nil(t, request)
+	})
+}
+
+func TestApprove(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(pendingRequest(), nil)
+		granter.On("GrantPermission", ctx, 2, 3).Return(nil)
+		store.On("Decide", ctx, 1, StatusApproved, "bob", now).Return(nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusApproved, request.Status)
+		assert.Equal(t, "bob", request.DecidedBy)
+		assert.Equal(t, now, *request.DecidedAt)
+
+		store.AssertExpectations(t)
+		granter.AssertExpectations(t)
+	})
+
+	t.Run("guardrail review applies nothing", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		review := &Request{ID: 1, Kind: KindGuardrailReview, UserID: "carol", RequestedBy: "alice", Status: StatusPending}
+		store.On("Get", ctx, 1).Return(review, nil)
+		store.On("Decide", ctx, 1, StatusApproved, "bob", now).Return(nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusApproved, request.Status)
+
+		granter.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("group membership", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		membership := &Request{ID: 1, Kind: KindGroupMembership, GroupID: 2, UserID: "alice", RequestedBy: "alice", Status: StatusPending}
+		store.On("Get", ctx, 1).Return(membership, nil)
+		granter.On("AddGroupUser", ctx, 2, "alice").Return(nil)
+		store.On("Decide", ctx, 1, StatusApproved, "bob", now).Return(nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusApproved, request.Status)
+
+		granter.AssertExpectations(t)
+	})
+
+	t.Run("already granted", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(pendingRequest(), nil)
+		granter.On("GrantPermission", ctx, 2, 3).Return(authzstore.NewNoChangesError())
+		store.On("Decide", ctx, 1, StatusApproved, "bob", now).Return(nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusApproved, request.Status)
+	})
+
+	t.Run("self approval", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(pendingRequest(), nil)
+
+		request, err := workflow.Approve(ctx, 1, "alice")
+		assert.ErrorIs(t, err, ErrSelfApproval)
+		assert.Nil(t, request)
+
+		granter.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not pending", func(t *testing.T) {
+		store, _, workflow := setupWorkflow()
+		decided := pendingRequest()
+		decided.Status = StatusRejected
+		store.On("Get", ctx, 1).Return(decided, nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.ErrorIs(t, err, ErrNotPending)
+		assert.Nil(t, request)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		store, _, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(nil, ErrNotFound)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, request)
+	})
+
+	t.Run("grant failure", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(pendingRequest(), nil)
+		granter.On("GrantPermission", ctx, 2, 3).Return(errors.New("store error"))
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.Error(t, err)
+		assert.Nil(t, request)
+
+		store.AssertNotCalled(t, "Decide", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestReject(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(pendingRequest(), nil)
+		store.On("Decide", ctx, 1, StatusRejected, "bob", now).Return(nil)
+
+		request, err := workflow.Reject(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusRejected, request.Status)
+
+		store.AssertExpectations(t)
+		granter.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty approver", func(t *testing.T) {
+		_, _, workflow := setupWorkflow()
+
+		request, err := workflow.Reject(ctx, 1, "")
+		assert.Error(t, err)
+		assert.Nil(t, request)
+	})
+}
+
+func TestSubmit_Routed(t *testing.T) {
+	ctx := context.Background()
+	store := new(mockStore)
+	router := new(mockRouter)
+	workflow := NewWorkflow(store, new(mockGranter), WithRouter(router), WithClock(NewFakeClock(now)))
+
+	submitted := Request{Kind: KindGroupMembership, GroupID: 2, UserID: "alice", RequestedBy: "alice", Status: StatusPending, CreatedAt: now}
+	router.On("Approvers", ctx, submitted).Return([]string{"alice", "bob"}, nil)
+	routed := submitted
+	routed.Approvers = []string{"bob"}
+	store.On("Create", ctx, routed).Return(1, nil)
+
+	request, err := workflow.Submit(ctx, Request{Kind: KindGroupMembership, GroupID: 2, UserID: "alice", RequestedBy: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, request.Approvers)
+
+	store.AssertExpectations(t)
+}
+
+func TestApprove_Routed(t *testing.T) {
+	ctx := context.Background()
+	routed := func() *Request {
+		request := pendingRequest()
+		request.Approvers = []string{"carol"}
+		return request
+	}
+
+	t.Run("not assigned", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		store.On("Get", ctx, 1).Return(routed(), nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.ErrorIs(t, err, ErrNotAssigned)
+		assert.Nil(t, request)
+
+		granter.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("escalated", func(t *testing.T) {
+		store, granter, workflow := setupWorkflow()
+		escalated := routed()
+		escalated.EscalatedAt = &now
+		store.On("Get", ctx, 1).Return(escalated, nil)
+		granter.On("GrantPermission", ctx, 2, 3).Return(nil)
+		store.On("Decide", ctx, 1, StatusApproved, "bob", now).Return(nil)
+
+		request, err := workflow.Approve(ctx, 1, "bob")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusApproved, request.Status)
+	})
+}
+
+func TestEscalate(t *testing.T) {
+	ctx := context.Background()
+	store, _, workflow := setupWorkflow()
+	overdue := Request{ID: 1, Approvers: []string{"carol"}, Status: StatusPending, CreatedAt: now.Add(-3 * time.Hour)}
+	recent := Request{ID: 2, Approvers: []string{"carol"}, Status: StatusPending, CreatedAt: now.Add(-time.Hour)}
+	unrouted := Request{ID: 3, Status: StatusPending, CreatedAt: now.Add(-3 * time.Hour)}
+	decided := Request{ID: 4, Approvers: []string{"carol"}, Status: StatusPending, CreatedAt: now.Add(-3 * time.Hour)}
+	store.On("List", ctx, StatusPending).Return([]Request{overdue, recent, unrouted, decided}, nil)
+	store.On("Escalate", ctx, 1, now).Return(nil)
+	store.On("Escalate", ctx, 4, now).Return(ErrNotPending)
+
+	escalated, err := workflow.Escalate(ctx, 2*time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, escalated, 1)
+	assert.Equal(t, 1, escalated[0].ID)
+	assert.Equal(t, now, *escalated[0].EscalatedAt)
+
+	store.AssertExpectations(t)
+}
@@ -0,0 +1,169 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const selectRequest = `
+	SELECT id, kind, group_id, permission_id, user_id, requested_by, justification, status, created_at, decided_by, decided_at,
+		approvers, escalated_at
+	FROM approval_requests`
+
+// Create stores a new approval request and returns its id.
+func (store *PostgresStore) Create(ctx context.Context, request Request) (int, error) {
+	var id int
+	err := store.db.QueryRow(ctx, `
+	INSERT INTO approval_requests (kind, group_id, permission_id, user_id, requested_by, justification, status, created_at, approvers)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id
+	`, string(request.Kind), nullableId(request.GroupID), nullableId(request.PermissionID), nullableText(request.UserID), request.RequestedBy, request.Justification, string(request.Status), request.CreatedAt, approvers(request.Approvers)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Get returns the approval request with the given id.
+func (store *PostgresStore) Get(ctx context.Context, id int) (*Request, error) {
+	request, err := scanRequest(store.db.QueryRow(ctx, selectRequest+" WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// List returns the approval requests in the given status, oldest first.
+func (store *PostgresStore) List(ctx context.Context, status Status) ([]Request, error) {
+	return store.list(ctx, selectRequest+" WHERE status = $1 ORDER BY created_at, id", string(status))
+}
+
+// ListRequestedBy returns the approval requests submitted by the given requester, most recent first.
+func (store *PostgresStore) ListRequestedBy(ctx context.Context, requester string) ([]Request, error) {
+	return store.list(ctx, selectRequest+" WHERE requested_by = $1 ORDER BY created_at DESC, id DESC", requester)
+}
+
+func (store *PostgresStore) list(ctx context.Context, query string, args ...any) ([]Request, error) {
+	rows, err := store.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []Request{}
+	for rows.Next() {
+		request, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return requests, nil
+}
+
+// Decide records the decision on a pending approval request.
+func (store *PostgresStore) Decide(ctx context.Context, id int, status Status, decidedBy string, decidedAt time.Time) error {
+	tag, err := store.db.Exec(ctx, `
+	UPDATE approval_requests SET status = $1, decided_by = $2, decided_at = $3
+	WHERE id = $4 AND status = $5
+	`, string(status), decidedBy, decidedAt, id, string(StatusPending))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotPending
+	}
+
+	return nil
+}
+
+// Escalate records the escalation of a pending approval request.
+func (store *PostgresStore) Escalate(ctx context.Context, id int, escalatedAt time.Time) error {
+	tag, err := store.db.Exec(ctx, `
+	UPDATE approval_requests SET escalated_at = $1
+	WHERE id = $2 AND status = $3 AND escalated_at IS NULL
+	`, escalatedAt, id, string(StatusPending))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotPending
+	}
+
+	return nil
+}
+
+func scanRequest(row pgx.Row) (*Request, error) {
+	var request Request
+	var kind, status string
+	var groupId, permissionId pgtype.Int4
+	var userId, decidedBy pgtype.Text
+	var decidedAt, escalatedAt pgtype.Timestamptz
+
+	err := row.Scan(&request.ID, &kind, &groupId, &permissionId, &userId, &request.RequestedBy,
+		&request.Justification, &status, &request.CreatedAt, &decidedBy, &decidedAt, &request.Approvers, &escalatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Kind = Kind(kind)
+	request.Status = Status(status)
+	request.GroupID = int(groupId.Int32)
+	request.PermissionID = int(permissionId.Int32)
+	request.UserID = userId.String
+	request.DecidedBy = decidedBy.String
+	if decidedAt.Valid {
+		request.DecidedAt = &decidedAt.Time
+	}
+	if escalatedAt.Valid {
+		request.EscalatedAt = &escalatedAt.Time
+	}
+	if len(request.Approvers) == 0 {
+		request.Approvers = nil
+	}
+
+	return &request, nil
+}
+
+func nullableId(id int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(id), Valid: id != 0}
+}
+
+// approvers returns the approvers of a request in the form of the NOT NULL approvers column.
+func approvers(users []string) []string {
+	if users == nil {
+		return []string{}
+	}
+	return users
+}
+
+func nullableText(text string) pgtype.Text {
+	return pgtype.Text{String: text, Valid: text != ""}
+}
@@ -0,0 +1,177 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func scanPendingRequest(args mock.Arguments) {
+	dest := args[0].([]any)
+	*(dest[0].(*int)) = 1
+	*(dest[1].(*string)) = string(KindPermissionGrant)
+	*(dest[2].(*pgtype.Int4)) = pgtype.Int4{Int32: 2, Valid: true}
+	*(dest[3].(*pgtype.Int4)) = pgtype.Int4{Int32: 3, Valid: true}
+	*(dest[5].(*string)) = "alice"
+	*(dest[6].(*string)) = ""
+	*(dest[7].(*string)) = string(StatusPending)
+	*(dest[8].(*time.Time)) = now
+}
+
+func TestPostgresStore_Create(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRow := new(MockRow)
+	store := NewPostgresStore(mockDb)
+
+	mockDb.On("QueryRow", ctx, mock.Anything, []any{"permission_grant", pgtype.Int4{Int32: 2, Valid: true}, pgtype.Int4{Int32: 3, Valid: true}, pgtype.Text{}, "alice", "", "pending", now, []string{}}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = 1
+	}).Return(nil)
+
+	id, err := store.Create(ctx, *pendingRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	mockDb.AssertExpectations(t)
+	mockRow.AssertExpectations(t)
+}
+
+func TestPostgresStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(scanPendingRequest).Return(nil)
+
+		request, err := store.Get(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, pendingRequest(), request)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		request, err := store.Get(ctx, 1)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, request)
+	})
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+	store := NewPostgresStore(mockDb)
+
+	mockDb.On("Query", ctx, mock.Anything, []any{"pending"}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(scanPendingRequest).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	requests, err := store.List(ctx, StatusPending)
+	assert.NoError(t, err)
+	assert.Equal(t, []Request{*pendingRequest()}, requests)
+
+	mockDb.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
+
+func TestPostgresStore_ListRequestedBy(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+	store := NewPostgresStore(mockDb)
+
+	mockDb.On("Query", ctx, mock.Anything, []any{"alice"}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(scanPendingRequest).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	requests, err := store.ListRequestedBy(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []Request{*pendingRequest()}, requests)
+
+	mockDb.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
+
+func TestPostgresStore_Decide(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Exec", ctx, mock.Anything, []any{"approved", "bob", now, 1, "pending"}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+		err := store.Decide(ctx, 1, StatusApproved, "bob", now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not pending", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+		err := store.Decide(ctx, 1, StatusApproved, "bob", now)
+		assert.ErrorIs(t, err, ErrNotPending)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		err := store.Decide(ctx, 1, StatusApproved, "bob", now)
+		assert.Error(t, err)
+	})
+}
+
+func TestPostgresStore_Escalate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Exec", ctx, mock.Anything, []any{now, 1, "pending"}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+		err := store.Escalate(ctx, 1, now)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not pending", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+		err := store.Escalate(ctx, 1, now)
+		assert.ErrorIs(t, err, ErrNotPending)
+	})
+}
@@ -0,0 +1,100 @@
+// Package audit records security relevant actions performed against the authorization service.
+// Events are recorded to a Sink: a logger, a file, Postgres, Kafka and a SIEM over syslog are
+// provided, and embedders can plug their own storage by implementing the interface.
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// Event describes a single audited action.
+type Event struct {
+	// The identifier of the event, see id.New, so events sort by the time they were recorded.
+	ID string `json:"id"`
+
+	// The time the action was performed.
+	Time time.Time `json:"time"`
+
+	// The authenticated user that performed the action.
+	Actor string `json:"actor"`
+
+	// The name of the action, such as "impersonate.evaluate".
+	Action string `json:"action"`
+
+	// The user or entity the action was performed on.
+	Subject string `json:"subject,omitempty"`
+
+	// Additional action specific details.
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Sink stores or forwards audit events.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Filter selects the events returned by Reader.List.
+type Filter struct {
+	// Only list the events of this actor, action or subject, when set.
+	Actor   string
+	Action  string
+	Subject string
+	// The maximum number of events to return, most recent first.
+	Limit int
+	// Only list the events following the one with this key, see ListKey, to resume a listing.
+	After paging.Key
+}
+
+// ListKey returns the key events are listed by, most recent first: their time and id.
+func ListKey(event Event) paging.Key {
+	return paging.Key{event.Time, event.ID}
+}
+
+// Reader lists the recorded audit events, for the audit history of the administration API.
+type Reader interface {
+	List(ctx context.Context, filter Filter) ([]Event, error)
+}
+
+// LogSink is a Sink writing audit events to a structured logger.
+type LogSink struct {
+	logger *slog.Logger
+}
+
+// NewLogSink creates a new LogSink writing to the given logger.
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+// Record writes the event to the logger at info level.
+func (sink *LogSink) Record(ctx context.Context, event Event) error {
+	sink.logger.LogAttrs(ctx, slog.LevelInfo, "audit event",
+		slog.String("id", event.ID),
+		slog.Time("time", event.Time),
+		slog.String("actor", event.Actor),
+		slog.String("action", event.Action),
+		slog.String("subject", event.Subject),
+		slog.Any("details", event.Details),
+	)
+	return nil
+}
+
+// MultiSink is a Sink recording every event to each of its sinks, such as a LogSink and a SyslogSink.
+type MultiSink []Sink
+
+var _ Sink = MultiSink(nil)
+
+// Record records the event to every sink, even when some fail, and returns their errors joined.
+func (sinks MultiSink) Record(ctx context.Context, event Event) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Record(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLogSink_Record calls LogSink.Record with an event, checking that it is written to the logger.
+func TestLogSink_Record(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err := sink.Record(context.Background(), Event{
+		ID:      "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e",
+		Time:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Actor:   "support",
+		Action:  "impersonate.evaluate",
+		Subject: "alice",
+		Details: map[string]any{"permissions": []string{"read"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"id":"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e"`)
+	assert.Contains(t, buf.String(), `"actor":"support"`)
+	assert.Contains(t, buf.String(), `"action":"impersonate.evaluate"`)
+	assert.Contains(t, buf.String(), `"subject":"alice"`)
+	assert.Contains(t, buf.String(), `"permissions":["read"]`)
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileSink is a Sink writing audit events as JSON lines.
+type FileSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink creates a new FileSink writing to the given writer.
+func NewFileSink(writer io.Writer) *FileSink {
+	return &FileSink{writer: writer}
+}
+
+// OpenFile creates a new FileSink appending to the file at the given path, created if missing.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{writer: file, closer: file}, nil
+}
+
+// Record appends the event as a single line, so the events of concurrent changes never interleave.
+func (sink *FileSink) Record(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err = sink.writer.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file opened with OpenFile; it does nothing for the writers given to NewFileSink.
+func (sink *FileSink) Close() error {
+	if sink.closer == nil {
+		return nil
+	}
+	return sink.closer.Close()
+}
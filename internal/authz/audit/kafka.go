@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Message is a record to produce to a Kafka topic.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer produces messages to Kafka. It is implemented by embedders on top of the Kafka client
+// they already use, so the service does not depend on a particular client.
+type Producer interface {
+	Produce(ctx context.Context, messages []Message) error
+}
+
+// KafkaSink is a Sink producing every audit event as a JSON message to a Kafka topic.
+// Messages are keyed by subject, so the events on an entity are kept in order within a partition.
+type KafkaSink struct {
+	producer Producer
+	topic    string
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+// NewKafkaSink creates a new KafkaSink producing to the given topic.
+func NewKafkaSink(producer Producer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+// Record produces the event as a single message.
+func (sink *KafkaSink) Record(ctx context.Context, event Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return sink.producer.Produce(ctx, []Message{{Topic: sink.topic, Key: []byte(event.Subject), Value: value}})
+}
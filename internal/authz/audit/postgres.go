@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresSink is a Sink inserting audit events in the audit_events table, and the Reader listing them.
+type PostgresSink struct {
+	db pgdb.DB
+}
+
+var (
+	_ Sink   = (*PostgresSink)(nil)
+	_ Reader = (*PostgresSink)(nil)
+)
+
+// NewPostgresSink creates a new PostgresSink instance.
+func NewPostgresSink(db pgdb.DB) *PostgresSink {
+	return &PostgresSink{db: db}
+}
+
+// Record inserts the event, its details stored as JSON.
+func (sink *PostgresSink) Record(ctx context.Context, event Event) error {
+	details := event.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	_, err := sink.db.Exec(ctx, `
+	INSERT INTO audit_events (id, recorded_at, actor, action, subject, details) VALUES ($1, $2, $3, $4, $5, $6)
+	`, event.ID, event.Time, event.Actor, event.Action, event.Subject, details)
+	return err
+}
+
+// List returns the events matching the filter, most recent first.
+func (sink *PostgresSink) List(ctx context.Context, filter Filter) ([]Event, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	// the events are listed by descending key, so the next ones have a lower key
+	var recordedAt *time.Time
+	var id *string
+	if filter.After != nil {
+		if len(filter.After) != 2 {
+			return nil, paging.ErrInvalidCursor
+		}
+		after, ok := filter.After[0].(time.Time)
+		afterId, idOk := filter.After[1].(string)
+		if !ok || !idOk {
+			return nil, paging.ErrInvalidCursor
+		}
+		recordedAt, id = &after, &afterId
+	}
+
+	rows, err := sink.db.Query(ctx, `
+	SELECT id::text, recorded_at, actor, action, subject, details FROM audit_events
+	WHERE ($1 = '' OR actor = $1) AND ($2 = '' OR action = $2) AND ($3 = '' OR subject = $3)
+	AND ($5::timestamptz IS NULL OR (recorded_at, id) < ($5, $6::uuid))
+	ORDER BY recorded_at DESC, id DESC
+	LIMIT $4
+	`, filter.Actor, filter.Action, filter.Subject, limit, recordedAt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(&event.ID, &event.Time, &event.Actor, &event.Action, &event.Subject, &event.Details); err != nil {
+			return nil, err
+		}
+		if len(event.Details) == 0 {
+			event.Details = nil
+		}
+		events = append(events, event)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return events, nil
+}
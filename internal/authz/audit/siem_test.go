@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func siemEvent() Event {
+	return Event{
+		ID:      "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e",
+		Time:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Actor:   "admin",
+		Action:  "policy.update_group_users",
+		Subject: "group=3",
+		Details: map[string]any{"users": []string{"alice"}},
+	}
+}
+
+// TestCEF formats an event, checking the header and the escaped extension.
+func TestCEF(t *testing.T) {
+	assert.Equal(t, `CEF:0|salmarsumi|recipes-authz|1.0|policy.update_group_users|policy.update_group_users|3|`+
+		`rt=1735787045000 externalId=0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e act=policy.update_group_users suser=admin `+
+		`duser=group\=3 cs1Label=details cs1={"users":["alice"]}`, CEF(siemEvent()))
+
+	denied := Event{Time: siemEvent().Time, Actor: "bob", Action: "access.denied"}
+	assert.Contains(t, CEF(denied), "|access.denied|access.denied|7|")
+}
+
+// TestLEEF formats an event, checking the header and the tab separated attributes.
+func TestLEEF(t *testing.T) {
+	assert.Equal(t, "LEEF:2.0|salmarsumi|recipes-authz|1.0|policy.update_group_users|x09|"+
+		"devTime=Jan 02 2025 03:04:05.000\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS\tsev=3\tcat=policy.update_group_users\t"+
+		"usrName=admin\teventId=0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e\tsubject=group=3\tdetails={\"users\":[\"alice\"]}", LEEF(siemEvent()))
+}
+
+// TestOpenSyslog opens sinks from URLs, checking the network, the default ports and the format.
+func TestOpenSyslog(t *testing.T) {
+	sink, err := OpenSyslog("syslog+tls://siem.example.org?format=leef")
+	assert.NoError(t, err)
+	assert.Equal(t, "tls", sink.network)
+	assert.Equal(t, "siem.example.org:6514", sink.address)
+	assert.Contains(t, sink.format(siemEvent()), "LEEF:2.0|")
+
+	sink, err = OpenSyslog("syslog://siem.example.org:1514")
+	assert.NoError(t, err)
+	assert.Equal(t, "udp", sink.network)
+	assert.Equal(t, "siem.example.org:1514", sink.address)
+	assert.Contains(t, sink.format(siemEvent()), "CEF:0|")
+
+	_, err = OpenSyslog("syslog+tcp://siem.example.org?format=json")
+	assert.ErrorContains(t, err, `unsupported siem format "json"`)
+	_, err = OpenSyslog("http://siem.example.org")
+	assert.ErrorContains(t, err, `unsupported syslog scheme "http"`)
+}
+
+// readFramed reads a message framed with its length.
+func readFramed(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, size)
+	_, err = io.ReadFull(reader, message)
+	return string(message), err
+}
+
+// TestSyslogSink_Record sends events to a TCP collector closing the first connection, checking
+// the messages are framed and the sink reconnects.
+func TestSyslogSink_Record(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	messages := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			message, err := readFramed(bufio.NewReader(conn))
+			if err == nil {
+				messages <- message
+			}
+			// every connection is closed after a message, as a restarting collector would
+			conn.Close()
+		}
+	}()
+
+	sink, err := NewSyslogSink("tcp", listener.Addr().String(), CEF, WithAppName("authz-test"))
+	assert.NoError(t, err)
+	defer sink.Close()
+	sink.hostname = "authz-1"
+
+	assert.NoError(t, sink.Record(context.Background(), siemEvent()))
+	message := <-messages
+	assert.True(t, strings.HasPrefix(message, "<85>1 2025-01-02T03:04:05Z authz-1 authz-test - policy.update_group_users - CEF:0|"), message)
+
+	// the first write may still succeed on the closed connection, so the event is sent until it arrives
+	assert.Eventually(t, func() bool {
+		if err := sink.Record(context.Background(), Event{Time: siemEvent().Time, Actor: "bob", Action: "access.denied"}); err != nil {
+			return false
+		}
+		select {
+		case message = <-messages:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	assert.True(t, strings.HasPrefix(message, "<84>1 "), message)
+}
+
+// failingSink is a Sink failing every event.
+type failingSink struct{}
+
+func (failingSink) Record(ctx context.Context, event Event) error {
+	return errors.New("sink down")
+}
+
+// TestMultiSink_Record records to a failing and a working sink, checking the event reaches the working one.
+func TestMultiSink_Record(t *testing.T) {
+	var buf strings.Builder
+	sinks := MultiSink{failingSink{}, NewLogSink(slog.New(slog.NewTextHandler(&buf, nil)))}
+
+	assert.ErrorContains(t, sinks.Record(context.Background(), siemEvent()), "sink down")
+	assert.Contains(t, buf.String(), "action=policy.update_group_users")
+}
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var recorded = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func changeEvent() Event {
+	return Event{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", Time: recorded, Actor: "admin",
+		Action: "policy.update_group_users", Subject: "group 3", Details: map[string]any{"users": []string{"alice"}}}
+}
+
+// TestFileSink_Record records two events, checking each is written as a JSON line.
+func TestFileSink_Record(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewFileSink(&buf)
+
+	assert.NoError(t, sink.Record(context.Background(), changeEvent()))
+	assert.NoError(t, sink.Record(context.Background(), Event{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f", Time: recorded, Actor: "admin", Action: "policy.create_group"}))
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e","time":"2025-01-02T03:04:05Z","actor":"admin",
+		"action":"policy.update_group_users","subject":"group 3","details":{"users":["alice"]}}`, string(lines[0]))
+}
+
+// TestOpenFile records an event twice to a file, checking the second is appended.
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for range 2 {
+		sink, err := OpenFile(path)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Record(context.Background(), changeEvent()))
+		assert.NoError(t, sink.Close())
+	}
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(content, []byte("\n")))
+}
+
+// TestPostgresSink_Record records an event, checking it is inserted with its details.
+func TestPostgresSink_Record(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	sink := NewPostgresSink(mockDb)
+
+	mockDb.On("Exec", ctx, mock.Anything, []any{"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", recorded, "admin",
+		"policy.update_group_users", "group 3", map[string]any{"users": []string{"alice"}}}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+	mockDb.On("Exec", ctx, mock.Anything, []any{"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f", recorded, "admin",
+		"policy.create_group", "", map[string]any{}}).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+	assert.NoError(t, sink.Record(ctx, changeEvent()))
+	assert.EqualError(t, sink.Record(ctx, Event{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f", Time: recorded, Actor: "admin", Action: "policy.create_group"}), "db error")
+	mockDb.AssertExpectations(t)
+}
+
+// TestPostgresSink_List lists the events, checking the filter and the key of the previous page are passed on.
+func TestPostgresSink_List(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("default limit", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRows := new(MockRows)
+		sink := NewPostgresSink(mockDb)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"admin", "", "", 100, (*time.Time)(nil), (*string)(nil)}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false)
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*string)) = "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e"
+			*(dest[1].(*time.Time)) = recorded
+			*(dest[2].(*string)) = "admin"
+			*(dest[3].(*string)) = "policy.create_group"
+			*(dest[5].(*map[string]any)) = map[string]any{}
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		events, err := sink.List(ctx, Filter{Actor: "admin"})
+		assert.NoError(t, err)
+		assert.Equal(t, []Event{{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", Time: recorded, Actor: "admin", Action: "policy.create_group"}}, events)
+	})
+
+	t.Run("after", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRows := new(MockRows)
+		sink := NewPostgresSink(mockDb)
+		recordedAt, id := recorded, "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e"
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"", "", "group 3", 10, &recordedAt, &id}).Return(mockRows, nil)
+		mockRows.On("Next").Return(false)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		events, err := sink.List(ctx, Filter{Subject: "group 3", Limit: 10, After: ListKey(Event{ID: id, Time: recorded})})
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		sink := NewPostgresSink(new(MockPgDb))
+
+		_, err := sink.List(ctx, Filter{After: paging.Key{"recipes"}})
+		assert.ErrorIs(t, err, paging.ErrInvalidCursor)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		sink := NewPostgresSink(mockDb)
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		events, err := sink.List(ctx, Filter{})
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, events)
+	})
+}
+
+// producerFunc adapts a function to the Producer interface.
+type producerFunc func(ctx context.Context, messages []Message) error
+
+func (produce producerFunc) Produce(ctx context.Context, messages []Message) error {
+	return produce(ctx, messages)
+}
+
+// TestKafkaSink_Record records an event, checking a message keyed by subject is produced.
+func TestKafkaSink_Record(t *testing.T) {
+	var produced []Message
+	sink := NewKafkaSink(producerFunc(func(ctx context.Context, messages []Message) error {
+		produced = messages
+		return nil
+	}), "authz.audit")
+
+	assert.NoError(t, sink.Record(context.Background(), changeEvent()))
+	assert.Len(t, produced, 1)
+	assert.Equal(t, "authz.audit", produced[0].Topic)
+	assert.Equal(t, []byte("group 3"), produced[0].Key)
+	assert.Contains(t, string(produced[0].Value), `"action":"policy.update_group_users"`)
+
+	failing := NewKafkaSink(producerFunc(func(ctx context.Context, messages []Message) error {
+		return errors.New("broker unavailable")
+	}), "authz.audit")
+	assert.Error(t, failing.Record(context.Background(), changeEvent()))
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacility is the authpriv facility, meant for security messages.
+	syslogFacility = 10
+	// syslogTimeout bounds the connection and the write of an event when the context has no deadline.
+	syslogTimeout = 5 * time.Second
+)
+
+// SyslogSink is a Sink sending audit events to a SIEM over syslog, formatted as CEF or LEEF lines
+// so they are ingested without custom parsers. Messages follow RFC 5424, framed with their length
+// over TCP and TLS as RFC 6587 describes. The connection is opened on the first event and opened
+// again once when sending fails, so a restarted collector does not lose the following events.
+type SyslogSink struct {
+	network   string
+	address   string
+	format    Format
+	tlsConfig *tls.Config
+	appName   string
+	hostname  string
+	mu        sync.Mutex
+	conn      net.Conn
+}
+
+var _ Sink = (*SyslogSink)(nil)
+
+// SyslogOption configures a SyslogSink.
+type SyslogOption func(*SyslogSink)
+
+// WithTLSConfig sets the TLS configuration of the connection over TLS, such as the authorities trusted
+// to sign the certificate of the collector. By default the system authorities are trusted.
+func WithTLSConfig(config *tls.Config) SyslogOption {
+	return func(sink *SyslogSink) {
+		sink.tlsConfig = config
+	}
+}
+
+// WithAppName sets the application name of the syslog messages, authz by default.
+func WithAppName(name string) SyslogOption {
+	return func(sink *SyslogSink) {
+		sink.appName = name
+	}
+}
+
+// NewSyslogSink creates a new SyslogSink sending events in the given format to the collector at
+// the address, over udp, tcp or tls.
+func NewSyslogSink(network string, address string, format Format, options ...SyslogOption) (*SyslogSink, error) {
+	switch network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	sink := &SyslogSink{network: network, address: address, format: format, appName: "authz", hostname: hostname}
+	for _, option := range options {
+		option(sink)
+	}
+	return sink, nil
+}
+
+// OpenSyslog creates a new SyslogSink from a URL such as syslog+tls://siem.example.org:6514?format=leef.
+// The syslog scheme sends over UDP, syslog+tcp over TCP and syslog+tls over TLS; the format is cef by default.
+func OpenSyslog(rawURL string, options ...SyslogOption) (*SyslogSink, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse syslog url: %w", err)
+	}
+
+	var network, port string
+	switch location.Scheme {
+	case "syslog", "syslog+udp":
+		network, port = "udp", "514"
+	case "syslog+tcp":
+		network, port = "tcp", "514"
+	case "syslog+tls":
+		network, port = "tls", "6514"
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q", location.Scheme)
+	}
+	if location.Port() != "" {
+		port = location.Port()
+	}
+
+	name := location.Query().Get("format")
+	if name == "" {
+		name = "cef"
+	}
+	format, err := FormatNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewSyslogSink(network, net.JoinHostPort(location.Hostname(), port), format, options...)
+}
+
+// Record sends the event, reconnecting once when the connection was lost.
+func (sink *SyslogSink) Record(ctx context.Context, event Event) error {
+	message := sink.message(event)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	var err error
+	for range 2 {
+		if err = sink.send(ctx, message); err == nil {
+			return nil
+		}
+		sink.disconnect()
+	}
+	return fmt.Errorf("send audit event to syslog: %w", err)
+}
+
+// Close closes the connection to the collector, if any.
+func (sink *SyslogSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
+
+// message returns the event as an RFC 5424 message, framed for the network.
+func (sink *SyslogSink) message(event Event) []byte {
+	severity := 5 // notice
+	if Severity(event) >= 7 {
+		severity = 4 // warning
+	}
+	message := fmt.Sprintf("<%d>1 %s %s %s - %s - %s", syslogFacility*8+severity, event.Time.UTC().Format(time.RFC3339Nano),
+		sink.hostname, sink.appName, syslogMessageID(event.Action), sink.format(event))
+	if sink.network == "udp" {
+		return []byte(message)
+	}
+	return []byte(strconv.Itoa(len(message)) + " " + message)
+}
+
+// send writes the message, connecting first when needed. The caller holds the lock.
+func (sink *SyslogSink) send(ctx context.Context, message []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(syslogTimeout)
+	}
+
+	if sink.conn == nil {
+		conn, err := sink.dial(ctx, deadline)
+		if err != nil {
+			return err
+		}
+		sink.conn = conn
+	}
+	if err := sink.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	_, err := sink.conn.Write(message)
+	return err
+}
+
+// dial connects to the collector.
+func (sink *SyslogSink) dial(ctx context.Context, deadline time.Time) (net.Conn, error) {
+	dialer := &net.Dialer{Deadline: deadline}
+	if sink.network != "tls" {
+		return dialer.DialContext(ctx, sink.network, sink.address)
+	}
+
+	config := sink.tlsConfig
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(sink.address)
+		if err != nil {
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+	return (&tls.Dialer{NetDialer: dialer, Config: config}).DialContext(ctx, "tcp", sink.address)
+}
+
+// disconnect drops the connection so the next send connects again. The caller holds the lock.
+func (sink *SyslogSink) disconnect() {
+	if sink.conn != nil {
+		_ = sink.conn.Close()
+		sink.conn = nil
+	}
+}
+
+// syslogMessageID returns the action as a message identifier, at most 32 printable characters.
+func syslogMessageID(action string) string {
+	if action == "" {
+		return "-"
+	}
+	id := []byte(action)
+	for i, c := range id {
+		if c <= ' ' || c > '~' {
+			id[i] = '_'
+		}
+	}
+	return string(id[:min(len(id), 32)])
+}
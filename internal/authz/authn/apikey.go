@@ -0,0 +1,79 @@
+package authn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader carries the API key of automated callers.
+const APIKeyHeader = "X-API-Key"
+
+// APIKey assigns a static API key to a user. Only the SHA-256 digest of the key is stored.
+type APIKey struct {
+	User string `json:"user"`
+	// The hex encoded SHA-256 digest of the key.
+	SHA256 string `json:"sha256"`
+}
+
+// APIKeys authenticates automated callers, such as deployment pipelines, with static API keys.
+type APIKeys struct {
+	users map[string]string
+}
+
+var _ Provider = (*APIKeys)(nil)
+
+// NewAPIKeys creates a new APIKeys accepting the given keys.
+func NewAPIKeys(keys []APIKey) (*APIKeys, error) {
+	users := make(map[string]string, len(keys))
+	for i, key := range keys {
+		digest := strings.ToLower(key.SHA256)
+		if key.User == "" {
+			return nil, fmt.Errorf("api key %d: user is empty", i+1)
+		}
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %d: invalid sha256 digest", i+1)
+		}
+		if _, exists := users[digest]; exists {
+			return nil, fmt.Errorf("api key %d: digest is listed twice", i+1)
+		}
+		users[digest] = key.User
+	}
+	return &APIKeys{users: users}, nil
+}
+
+// LoadAPIKeys reads the API keys from a JSON file of the form {"keys": [{"user": "...", "sha256": "..."}]}.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []APIKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("decode api keys: %w", err)
+	}
+	return NewAPIKeys(document.Keys)
+}
+
+// Authenticate returns the user owning the API key of the request, or nil when the request carries no key.
+// Keys are looked up by digest, so the comparison does not depend on the key content.
+func (provider *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	user, ok := provider.users[hex.EncodeToString(digest[:])]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+	return &Principal{User: user, Method: "apikey"}, nil
+}
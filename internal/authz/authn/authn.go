@@ -0,0 +1,48 @@
+// Package authn authenticates the callers of the administration API. Several providers,
+// such as OIDC bearer tokens, static API keys and mTLS client certificates, can be combined
+// in a Chain so different organizations can adopt the service without code changes.
+package authn
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidCredentials is returned when a request carries credentials that cannot be verified.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is an authenticated caller.
+type Principal struct {
+	User string
+	// Whether the caller completed multi-factor authentication.
+	MFA bool
+	// The provider that authenticated the caller, such as "oidc".
+	Method string
+}
+
+// Provider authenticates requests carrying a specific kind of credentials.
+type Provider interface {
+	// Authenticate returns the caller of the request, or nil when the request carries
+	// no credentials this provider handles. Credentials that cannot be verified are reported
+	// with an error wrapping ErrInvalidCredentials.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Chain tries every provider in order and returns the first caller authenticated.
+// A request with invalid credentials for any provider is rejected, even if a later provider
+// would accept it, so a bad token is never silently ignored.
+type Chain []Provider
+
+// Authenticate returns the caller of the request, or nil when no provider found credentials.
+func (chain Chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, provider := range chain {
+		principal, err := provider.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if principal != nil {
+			return principal, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,129 @@
+package authn
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// digest returns the hex encoded SHA-256 digest of the key.
+func digest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestChain_FirstMatch authenticates with a chain, checking the first provider finding credentials wins.
+func TestChain_FirstMatch(t *testing.T) {
+	keys, err := NewAPIKeys([]APIKey{{User: "deployer", SHA256: digest("secret")}})
+	assert.NoError(t, err)
+	chain := Chain{keys, NewProxyHeaders("X-Forwarded-User", "X-Forwarded-MFA")}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	principal, err := chain.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "alice", Method: "proxy"}, principal)
+
+	r.Header.Set(APIKeyHeader, "secret")
+	principal, err = chain.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "deployer", Method: "apikey"}, principal)
+}
+
+// TestChain_InvalidCredentials authenticates with an unknown key, checking later providers are not tried.
+func TestChain_InvalidCredentials(t *testing.T) {
+	keys, err := NewAPIKeys([]APIKey{{User: "deployer", SHA256: digest("secret")}})
+	assert.NoError(t, err)
+	chain := Chain{keys, NewProxyHeaders("X-Forwarded-User", "X-Forwarded-MFA")}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "wrong")
+	r.Header.Set("X-Forwarded-User", "alice")
+	principal, err := chain.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Nil(t, principal)
+}
+
+// TestChain_NoCredentials authenticates a request without credentials, checking no caller is returned.
+func TestChain_NoCredentials(t *testing.T) {
+	chain := Chain{NewMTLS(), NewProxyHeaders("X-Forwarded-User", "X-Forwarded-MFA")}
+
+	principal, err := chain.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, principal)
+}
+
+// TestProxyHeaders_MFA authenticates through the proxy headers, checking the MFA flag is read.
+func TestProxyHeaders_MFA(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-User", "alice")
+	r.Header.Set("X-Forwarded-MFA", "true")
+
+	principal, err := NewProxyHeaders("X-Forwarded-User", "X-Forwarded-MFA").Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "alice", MFA: true, Method: "proxy"}, principal)
+}
+
+// TestNewAPIKeys_Invalid creates API keys from invalid entries, checking each is rejected.
+func TestNewAPIKeys_Invalid(t *testing.T) {
+	_, err := NewAPIKeys([]APIKey{{User: "", SHA256: digest("secret")}})
+	assert.ErrorContains(t, err, "user is empty")
+
+	_, err = NewAPIKeys([]APIKey{{User: "deployer", SHA256: "abc"}})
+	assert.ErrorContains(t, err, "invalid sha256 digest")
+
+	_, err = NewAPIKeys([]APIKey{{User: "a", SHA256: digest("secret")}, {User: "b", SHA256: digest("secret")}})
+	assert.ErrorContains(t, err, "listed twice")
+}
+
+// TestLoadAPIKeys reads API keys from a file, checking the listed key authenticates.
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	content := `{"keys": [{"user": "deployer", "sha256": "` + digest("secret") + `"}]}`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := LoadAPIKeys(path)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "secret")
+	principal, err := keys.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "deployer", principal.User)
+}
+
+// TestMTLS_Identity authenticates verified client certificates, checking the URI SAN is preferred over the common name.
+func TestMTLS_Identity(t *testing.T) {
+	certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "deployer"}}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}}
+
+	principal, err := NewMTLS().Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "deployer", Method: "mtls"}, principal)
+
+	spiffe, _ := url.Parse("spiffe://example.org/deployer")
+	certificate.URIs = []*url.URL{spiffe}
+	principal, err = NewMTLS().Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "spiffe://example.org/deployer", principal.User)
+}
+
+// TestMTLS_Unverified authenticates a TLS request without a verified certificate, checking no caller is returned.
+func TestMTLS_Unverified(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.TLS = &tls.ConnectionState{}
+
+	principal, err := NewMTLS().Authenticate(r)
+	assert.NoError(t, err)
+	assert.Nil(t, principal)
+}
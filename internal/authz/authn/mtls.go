@@ -0,0 +1,33 @@
+package authn
+
+import "net/http"
+
+// MTLS authenticates callers with the client certificate verified by the TLS server.
+// The user is the first URI subject alternative name of the certificate, such as a
+// SPIFFE id, or its subject common name when it has none.
+type MTLS struct{}
+
+var _ Provider = (*MTLS)(nil)
+
+// NewMTLS creates a new MTLS provider. The TLS server must verify client certificates
+// against the trusted authorities, see tls.VerifyClientCertIfGiven.
+func NewMTLS() *MTLS {
+	return &MTLS{}
+}
+
+// Authenticate returns the identity of the verified client certificate, or nil when the request has none.
+func (provider *MTLS) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	certificate := r.TLS.VerifiedChains[0][0]
+	user := certificate.Subject.CommonName
+	if len(certificate.URIs) > 0 {
+		user = certificate.URIs[0].String()
+	}
+	if user == "" {
+		return nil, nil
+	}
+	return &Principal{User: user, Method: "mtls"}, nil
+}
@@ -0,0 +1,327 @@
+package authn
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// OIDC authenticates callers with bearer ID or access tokens issued by an OpenID Connect provider.
+// The signing keys are discovered from the issuer and refreshed when a token is signed with an unknown key.
+// RS256 and ES256 signatures are supported.
+type OIDC struct {
+	issuer    string
+	audience  string
+	client    *http.Client
+	userClaim string
+	leeway    time.Duration
+	clock     clock.Clock
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	refreshed time.Time
+}
+
+var _ Provider = (*OIDC)(nil)
+
+// OIDCOption configures optional OIDC settings.
+type OIDCOption func(*OIDC)
+
+// WithClock sets the clock token expirations are checked against. The default is the system clock.
+func WithClock(clock clock.Clock) OIDCOption {
+	return func(provider *OIDC) {
+		provider.clock = clock
+	}
+}
+
+// WithUserClaim sets the token claim naming the user. The default is "sub".
+func WithUserClaim(claim string) OIDCOption {
+	return func(provider *OIDC) {
+		provider.userClaim = claim
+	}
+}
+
+// NewOIDC creates a new OIDC provider accepting the tokens of the given issuer for the given audience.
+// The keys are discovered on first use, so the issuer does not need to be reachable at startup.
+func NewOIDC(issuer string, audience string, client *http.Client, options ...OIDCOption) *OIDC {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	provider := &OIDC{
+		issuer:    strings.TrimSuffix(issuer, "/"),
+		audience:  audience,
+		client:    client,
+		userClaim: "sub",
+		leeway:    time.Minute,
+		clock:     clock.System(),
+	}
+	for _, option := range options {
+		option(provider)
+	}
+	return provider
+}
+
+// Authenticate verifies the bearer token of the request, or returns nil when the request has none.
+// Multi-factor authentication is read from the "amr" claim.
+func (provider *OIDC) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, nil
+	}
+
+	claims, err := provider.verify(r.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	user, _ := claims[provider.userClaim].(string)
+	if user == "" {
+		return nil, fmt.Errorf("%w: token has no %s claim", ErrInvalidCredentials, provider.userClaim)
+	}
+	methods, _ := claims["amr"].([]any)
+	return &Principal{User: user, MFA: slices.Contains(methods, any("mfa")), Method: "oidc"}, nil
+}
+
+// jwtHeader is the header of a signed token.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// verify checks the signature and the registered claims of the token and returns its claims.
+func (provider *OIDC) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	key, err := provider.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Algorithm, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := provider.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (provider *OIDC) checkClaims(claims map[string]any) error {
+	if issuer, _ := claims["iss"].(string); issuer != provider.issuer {
+		return fmt.Errorf("unexpected issuer %q", issuer)
+	}
+
+	switch audience := claims["aud"].(type) {
+	case string:
+		if audience != provider.audience {
+			return fmt.Errorf("unexpected audience %q", audience)
+		}
+	case []any:
+		if !slices.Contains(audience, any(provider.audience)) {
+			return errors.New("token is not issued for this audience")
+		}
+	default:
+		return errors.New("token has no audience")
+	}
+
+	now := provider.clock.Now()
+	expires, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(expires), 0).Add(provider.leeway)) {
+		return errors.New("token is expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(provider.leeway).Before(time.Unix(int64(notBefore), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// key returns the signing key with the given id, refreshing the keys at most once a minute when it is unknown.
+func (provider *OIDC) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if key, ok := provider.keys[id]; ok {
+		return key, nil
+	}
+	if provider.keys != nil && provider.clock.Now().Sub(provider.refreshed) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", id)
+	}
+
+	keys, err := provider.fetchKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	provider.keys = keys
+	provider.refreshed = provider.clock.Now()
+
+	if key, ok := keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", id)
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// fetchKeys discovers the key set of the issuer and returns its signing keys by id.
+// Keys of unsupported types are skipped.
+func (provider *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := provider.getJSON(ctx, provider.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != provider.issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q", discovery.Issuer)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := provider.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+func (provider *OIDC) getJSON(ctx context.Context, url string, target any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, digest []byte, signature []byte) error {
+	switch algorithm {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature) != nil {
+			return errors.New("invalid signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, target any) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(content, target); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(content), nil
+}
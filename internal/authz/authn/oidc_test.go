@@ -0,0 +1,187 @@
+package authn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// issuer is a test OpenID Connect provider signing tokens with an RSA and an ECDSA key.
+type issuer struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	jwksHits int
+}
+
+func newIssuer(t *testing.T) *issuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	issuer := &issuer{rsaKey: rsaKey, ecKey: ecKey}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL, "jwks_uri": issuer.server.URL + "/keys"})
+		case "/keys":
+			issuer.jwksHits++
+			encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+				{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// sign returns a token with the given claims signed with the key of the given id.
+func (issuer *issuer) sign(t *testing.T, kid string, claims map[string]any) string {
+	algorithm := "RS256"
+	if kid == "ec" {
+		algorithm = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	if kid == "ec" {
+		r, s, err := ecdsa.Sign(rand.Reader, issuer.ecKey, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	} else {
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, issuer.rsaKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (issuer *issuer) claims() map[string]any {
+	return map[string]any{
+		"iss": issuer.server.URL,
+		"aud": "authz",
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// TestOIDC_Authenticate authenticates RS256 and ES256 tokens, checking the user and MFA are read from the claims.
+func TestOIDC_Authenticate(t *testing.T) {
+	issuer := newIssuer(t)
+	provider := NewOIDC(issuer.server.URL, "authz", issuer.server.Client())
+
+	principal, err := provider.Authenticate(bearer(issuer.sign(t, "rsa", issuer.claims())))
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "alice", Method: "oidc"}, principal)
+
+	claims := issuer.claims()
+	claims["aud"] = []string{"other", "authz"}
+	claims["amr"] = []string{"pwd", "mfa"}
+	principal, err = provider.Authenticate(bearer(issuer.sign(t, "ec", claims)))
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{User: "alice", MFA: true, Method: "oidc"}, principal)
+
+	assert.Equal(t, 1, issuer.jwksHits)
+}
+
+// TestOIDC_UserClaim authenticates with a custom user claim, checking it names the user.
+func TestOIDC_UserClaim(t *testing.T) {
+	issuer := newIssuer(t)
+	provider := NewOIDC(issuer.server.URL, "authz", issuer.server.Client(), WithUserClaim("email"))
+
+	claims := issuer.claims()
+	claims["email"] = "alice@example.org"
+	principal, err := provider.Authenticate(bearer(issuer.sign(t, "rsa", claims)))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.org", principal.User)
+
+	_, err = provider.Authenticate(bearer(issuer.sign(t, "rsa", issuer.claims())))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestOIDC_Invalid authenticates tokens failing verification, checking each is rejected.
+func TestOIDC_Invalid(t *testing.T) {
+	issuer := newIssuer(t)
+	provider := NewOIDC(issuer.server.URL, "authz", issuer.server.Client())
+
+	tests := map[string]func(claims map[string]any){
+		"issuer":     func(claims map[string]any) { claims["iss"] = "https://evil.example.org" },
+		"audience":   func(claims map[string]any) { claims["aud"] = "other" },
+		"expired":    func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"not before": func(claims map[string]any) { claims["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"no subject": func(claims map[string]any) { delete(claims, "sub") },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := issuer.claims()
+			modify(claims)
+			_, err := provider.Authenticate(bearer(issuer.sign(t, "rsa", claims)))
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+
+	token := issuer.sign(t, "rsa", issuer.claims())
+	_, err := provider.Authenticate(bearer(token[:len(token)-4] + "AAAA"))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = provider.Authenticate(bearer("not-a-token"))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+// TestOIDC_UnknownKey authenticates tokens signed with unknown keys, checking the keys are refreshed at most once a minute.
+func TestOIDC_UnknownKey(t *testing.T) {
+	issuer := newIssuer(t)
+	clock := NewFakeClock(time.Now())
+	provider := NewOIDC(issuer.server.URL, "authz", issuer.server.Client(), WithClock(clock))
+
+	token := issuer.sign(t, "missing", issuer.claims())
+	_, err := provider.Authenticate(bearer(token))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = provider.Authenticate(bearer(token))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Equal(t, 1, issuer.jwksHits)
+
+	clock.Advance(2 * time.Minute)
+	_, err = provider.Authenticate(bearer(token))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Equal(t, 2, issuer.jwksHits)
+}
+
+// TestOIDC_NoToken authenticates a request without a bearer token, checking no caller is returned.
+func TestOIDC_NoToken(t *testing.T) {
+	provider := NewOIDC("https://issuer.example.org", "authz", nil)
+
+	principal, err := provider.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	assert.Nil(t, principal)
+}
@@ -0,0 +1,27 @@
+package authn
+
+import "net/http"
+
+// ProxyHeaders trusts the user and multi-factor authentication headers set by an
+// authenticating reverse proxy. It must only be enabled behind such a proxy.
+type ProxyHeaders struct {
+	userHeader string
+	mfaHeader  string
+}
+
+var _ Provider = (*ProxyHeaders)(nil)
+
+// NewProxyHeaders creates a new ProxyHeaders reading the user from userHeader and the
+// multi-factor authentication flag, set to "true", from mfaHeader.
+func NewProxyHeaders(userHeader string, mfaHeader string) *ProxyHeaders {
+	return &ProxyHeaders{userHeader: userHeader, mfaHeader: mfaHeader}
+}
+
+// Authenticate returns the user named by the proxy, or nil when the header is missing.
+func (provider *ProxyHeaders) Authenticate(r *http.Request) (*Principal, error) {
+	user := r.Header.Get(provider.userHeader)
+	if user == "" {
+		return nil, nil
+	}
+	return &Principal{User: user, MFA: r.Header.Get(provider.mfaHeader) == "true", Method: "proxy"}, nil
+}
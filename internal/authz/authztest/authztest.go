@@ -0,0 +1,185 @@
+// Package authztest runs the authz server in-process, backed by an in-memory store seeded with a
+// policy, so the services calling authz can write end-to-end authorization tests without Docker
+// or a database, for example:
+//
+//	func TestDeleteRecipe(t *testing.T) {
+//		policy, err := policyfile.Load("testdata/policy.yaml")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		server := authztest.NewServer(t, policy)
+//		recipes := newRecipeService(server.Client())
+//		...
+//	}
+//
+// The in-memory store keeps the groups, memberships, permissions, grants, risks and implications
+// of the policy; its folders, denials and conditions are not served.
+package authztest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/store/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// Service is the identity the clients of the server authenticate as. The served policy grants
+// it the meta-policy permissions reading, changing and evaluating the policy.
+const Service = "authztest"
+
+// ServiceGroup is the group of the served policy granting Service the meta-policy permissions.
+const ServiceGroup = "authztest-service"
+
+// servicePermissions are the meta-policy permissions granted to Service.
+var servicePermissions = []string{api.PermissionRead, api.PermissionWrite, api.PermissionEvaluate, api.PermissionImpersonate}
+
+// userMetadata is the gRPC metadata carrying the caller, see grpcapi.
+const userMetadata = "x-forwarded-user"
+
+// Server is an authz server serving the administration API over HTTP, and optionally the gRPC
+// services, on the loopback interface until the end of the test.
+type Server struct {
+	// URL is the base URL of the HTTP API, such as http://127.0.0.1:41235.
+	URL string
+	// GRPCAddr is the address of the gRPC services, empty unless started WithGRPC.
+	GRPCAddr string
+	// Manager is the in-memory store the server answers from, which tests may change directly.
+	Manager *memory.MemoryPolicyManager
+
+	t    testing.TB
+	http *httptest.Server
+}
+
+type config struct {
+	grpc       bool
+	logger     *slog.Logger
+	apiOptions []api.Option
+}
+
+// Option configures optional Server settings.
+type Option func(*config)
+
+// WithGRPC also serves the gRPC health checking, evaluation and management services, see GRPCConn.
+func WithGRPC() Option {
+	return func(config *config) {
+		config.grpc = true
+	}
+}
+
+// WithLogger sets the logger of the server. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(config *config) {
+		config.logger = logger
+	}
+}
+
+// WithAPIOptions sets options of the administration API, such as api.WithEvaluationMode.
+func WithAPIOptions(options ...api.Option) Option {
+	return func(config *config) {
+		config.apiOptions = append(config.apiOptions, options...)
+	}
+}
+
+// NewServer starts a Server serving the given policy, with the meta-policy permissions granted to Service
+// added. The server is stopped when the test and its subtests complete. The test fails at once when the
+// policy cannot be served.
+func NewServer(t testing.TB, policy *authz.Policy, options ...Option) *Server {
+	t.Helper()
+	config := &config{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, option := range options {
+		option(config)
+	}
+
+	server := &Server{Manager: memory.NewMemoryPolicyManager(config.logger), t: t}
+	server.SetPolicy(policy)
+
+	server.http = httptest.NewServer(api.NewServer(server.Manager, config.logger, config.apiOptions...))
+	t.Cleanup(server.http.Close)
+	server.URL = server.http.URL
+
+	if config.grpc {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen for gRPC: %v", err)
+		}
+		grpcServer := grpcapi.NewServer(server.Manager, config.logger, grpcapi.Authentication(authn.NewProxyHeaders(api.UserHeader, api.MFAHeader))...)
+		grpcServer.RegisterPolicy(server.Manager, nil)
+		grpcServer.CheckHealth(context.Background())
+		go func() { _ = grpcServer.Serve(listener) }()
+		t.Cleanup(grpcServer.Stop)
+		server.GRPCAddr = listener.Addr().String()
+	}
+	return server
+}
+
+// SetPolicy replaces the served policy, with the meta-policy permissions granted to Service added.
+// The test fails at once when the policy cannot be served.
+func (server *Server) SetPolicy(policy *authz.Policy) {
+	server.t.Helper()
+	if err := server.Manager.Import(context.Background(), store.DocumentFromPolicy(withService(policy))); err != nil {
+		server.t.Fatalf("import the policy: %v", err)
+	}
+}
+
+// Client returns a client of the HTTP API authenticating as Service, with no decision cache so
+// the changes made with SetPolicy apply to the next checks. The options override these defaults.
+func (server *Server) Client(options ...client.Option) *client.Client {
+	defaults := []client.Option{client.WithService(Service), client.WithCacheSize(0)}
+	return client.NewClient(server.URL, server.http.Client(), append(defaults, options...)...)
+}
+
+// GRPCConn returns a connection to the gRPC services authenticating as Service, closed at the end of the test.
+// The server must be started WithGRPC.
+func (server *Server) GRPCConn() *grpc.ClientConn {
+	server.t.Helper()
+	if server.GRPCAddr == "" {
+		server.t.Fatal("the gRPC services are not served, start the server WithGRPC")
+	}
+
+	conn, err := grpc.NewClient(server.GRPCAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, request, reply any, conn *grpc.ClientConn, invoker grpc.UnaryInvoker, options ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, userMetadata, Service), method, request, reply, conn, options...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, conn *grpc.ClientConn, method string, streamer grpc.Streamer, options ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, userMetadata, Service), desc, conn, method, options...)
+		}),
+	)
+	if err != nil {
+		server.t.Fatalf("connect to the gRPC services: %v", err)
+	}
+	server.t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// withService returns a copy of the policy granting Service the meta-policy permissions.
+func withService(policy *authz.Policy) *authz.Policy {
+	served := &authz.Policy{
+		Groups:      append(slices.Clone(policy.Groups), authz.Group{Name: ServiceGroup, Users: []string{Service}}),
+		Permissions: slices.Clone(policy.Permissions),
+	}
+	for _, name := range servicePermissions {
+		index := slices.IndexFunc(served.Permissions, func(permission authz.Permission) bool { return permission.Name == name })
+		if index < 0 {
+			served.Permissions = append(served.Permissions, authz.Permission{Name: name})
+			index = len(served.Permissions) - 1
+		}
+		permission := &served.Permissions[index]
+		permission.Groups = append(slices.Clone(permission.Groups), ServiceGroup)
+	}
+	return served
+}
@@ -0,0 +1,60 @@
+package authztest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{{Name: "recipes.read", Groups: []string{"cooks"}}, {Name: "recipes.delete", Groups: []string{"chefs"}}},
+		[]authz.Group{{Name: "cooks", Users: []string{"alice", "bob"}}, {Name: "chefs", Users: []string{"alice"}}},
+	)
+}
+
+func TestServer_Client(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(t, testPolicy())
+	client := server.Client()
+
+	allowed, err := client.HasPermission(ctx, "bob", "recipes.read")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	decision, err := client.Check(ctx, "bob", "recipes.delete")
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.NotEmpty(t, decision.Reason)
+
+	// the changes apply to the next checks
+	policy := testPolicy()
+	policy.Groups[1].Users = append(policy.Groups[1].Users, "bob")
+	server.SetPolicy(policy)
+	allowed, err = client.HasPermission(ctx, "bob", "recipes.delete")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestServer_GRPC(t *testing.T) {
+	server := NewServer(t, testPolicy(), WithGRPC())
+	evaluation := authzpb.NewEvaluationClient(server.GRPCConn())
+
+	response, err := evaluation.HasPermission(context.Background(), &authzpb.HasPermissionRequest{User: "alice", Permission: "recipes.delete"})
+	assert.NoError(t, err)
+	assert.True(t, response.Allowed)
+}
+
+func TestWithService(t *testing.T) {
+	policy := testPolicy()
+	policy.Permissions = append(policy.Permissions, authz.Permission{Name: "authz.read", Groups: []string{"chefs"}})
+
+	served := withService(policy)
+	assert.Len(t, policy.Groups, 2, "the policy is not changed")
+	assert.Contains(t, served.Groups, authz.Group{Name: ServiceGroup, Users: []string{Service}})
+	assert.Contains(t, served.Permissions, authz.Permission{Name: "authz.read", Groups: []string{"chefs", ServiceGroup}})
+	assert.Contains(t, served.Permissions, authz.Permission{Name: "authz.evaluate", Groups: []string{ServiceGroup}})
+	assert.Equal(t, []string{"chefs"}, policy.Permissions[2].Groups)
+}
@@ -0,0 +1,140 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Builder assembles a policy in code, for tests and embedded use. Group and Permission add
+// an entry, or select it again when it already exists, and the following calls configure
+// the selected entry:
+//
+//	policy, err := authz.NewBuilder().
+//		Group("admins").Users("alice").
+//		Permission("recipes.write").GrantTo("admins").
+//		Build()
+//
+// Mistakes are collected and reported together by Build.
+type Builder struct {
+	groups      []Group
+	permissions []Permission
+	// The index of the selected group or permission, -1 when none is selected.
+	group      int
+	permission int
+	errs       []error
+}
+
+// NewBuilder creates a new Builder of an empty policy.
+func NewBuilder() *Builder {
+	return &Builder{group: -1, permission: -1}
+}
+
+// Group adds the named group and selects it.
+func (builder *Builder) Group(name string) *Builder {
+	builder.permission = -1
+	if name == "" {
+		builder.group = -1
+		builder.errs = append(builder.errs, errors.New("group name is empty"))
+		return builder
+	}
+
+	builder.group = slices.IndexFunc(builder.groups, func(group Group) bool { return group.Name == name })
+	if builder.group < 0 {
+		builder.groups = append(builder.groups, Group{Name: name, Users: []string{}})
+		builder.group = len(builder.groups) - 1
+	}
+	return builder
+}
+
+// Users adds the users to the selected group.
+func (builder *Builder) Users(users ...string) *Builder {
+	if builder.group < 0 {
+		builder.errs = append(builder.errs, errors.New("no group is selected to add users to"))
+		return builder
+	}
+
+	group := &builder.groups[builder.group]
+	for _, user := range users {
+		if user == "" {
+			builder.errs = append(builder.errs, fmt.Errorf("group %q has an empty user", group.Name))
+			continue
+		}
+		if !slices.Contains(group.Users, user) {
+			group.Users = append(group.Users, user)
+		}
+	}
+	return builder
+}
+
+// Permission adds the named permission and selects it.
+func (builder *Builder) Permission(name string) *Builder {
+	builder.group = -1
+	if name == "" {
+		builder.permission = -1
+		builder.errs = append(builder.errs, errors.New("permission name is empty"))
+		return builder
+	}
+
+	builder.permission = slices.IndexFunc(builder.permissions, func(permission Permission) bool { return permission.Name == name })
+	if builder.permission < 0 {
+		builder.permissions = append(builder.permissions, Permission{Name: name, Groups: []string{}})
+		builder.permission = len(builder.permissions) - 1
+	}
+	return builder
+}
+
+// GrantTo grants the selected permission to the groups.
+func (builder *Builder) GrantTo(groups ...string) *Builder {
+	if permission := builder.selectedPermission("GrantTo"); permission != nil {
+		for _, group := range groups {
+			if !slices.Contains(permission.Groups, group) {
+				permission.Groups = append(permission.Groups, group)
+			}
+		}
+	}
+	return builder
+}
+
+// Implies makes the selected permission imply the given permissions.
+func (builder *Builder) Implies(permissions ...string) *Builder {
+	if permission := builder.selectedPermission("Implies"); permission != nil {
+		for _, implied := range permissions {
+			if !slices.Contains(permission.Implies, implied) {
+				permission.Implies = append(permission.Implies, implied)
+			}
+		}
+	}
+	return builder
+}
+
+// Risk sets the risk level of the selected permission.
+func (builder *Builder) Risk(risk RiskLevel) *Builder {
+	if permission := builder.selectedPermission("Risk"); permission != nil {
+		permission.Risk = risk
+	}
+	return builder
+}
+
+func (builder *Builder) selectedPermission(method string) *Permission {
+	if builder.permission < 0 {
+		builder.errs = append(builder.errs, fmt.Errorf("no permission is selected for %s", method))
+		return nil
+	}
+	return &builder.permissions[builder.permission]
+}
+
+// Policy returns a copy of the policy built so far, without validating it.
+func (builder *Builder) Policy() *Policy {
+	return clonePolicy(NewPolicy(builder.permissions, builder.groups))
+}
+
+// Build validates the policy and compiles it.
+// It fails when a call was misplaced, a permission is granted to an unknown group,
+// an implication is unknown or cyclic, or a risk level is unknown.
+func (builder *Builder) Build() (*CompiledPolicy, error) {
+	if len(builder.errs) > 0 {
+		return nil, errors.Join(builder.errs...)
+	}
+	return Compile(builder.Policy())
+}
@@ -0,0 +1,83 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuilder_Build builds a policy with groups, grants and implications, checking the compiled evaluations.
+func TestBuilder_Build(t *testing.T) {
+	compiled, err := NewBuilder().
+		Group("admins").Users("alice").
+		Group("editors").Users("bob", "alice").
+		Permission("recipes.read").
+		Permission("recipes.write").GrantTo("editors").Implies("recipes.read").
+		Permission("recipes.delete").GrantTo("admins").Risk(RiskHigh).
+		Group("admins").Users("carol").
+		Build()
+	assert.NoError(t, err)
+
+	assert.True(t, compiled.HasPermission("bob", "recipes.read"))
+	assert.False(t, compiled.HasPermission("bob", "recipes.delete"))
+	assert.True(t, compiled.HasPermission("carol", "recipes.delete"))
+	assert.True(t, compiled.IsInGroup("alice", "editors"))
+	assert.False(t, compiled.IsInGroup("dave", "editors"))
+
+	policy := compiled.Policy()
+	assert.Equal(t, []Group{{Name: "admins", Users: []string{"alice", "carol"}}, {Name: "editors", Users: []string{"bob", "alice"}}}, policy.Groups)
+	assert.Equal(t, RiskHigh, policy.Permissions[2].Risk)
+}
+
+// TestBuilder_Build_Errors builds a policy with mistakes, checking they are all reported.
+func TestBuilder_Build_Errors(t *testing.T) {
+	_, err := NewBuilder().
+		Users("alice").
+		Group("admins").Users("").
+		Permission("recipes.write").GrantTo("editors").
+		Group("editors").Risk(RiskHigh).
+		Build()
+
+	assert.ErrorContains(t, err, "no group is selected to add users to")
+	assert.ErrorContains(t, err, `group "admins" has an empty user`)
+	assert.ErrorContains(t, err, "no permission is selected for Risk")
+}
+
+// TestBuilder_Build_UnknownReferences builds a policy granting to unknown groups and implying unknown permissions, checking for errors.
+func TestBuilder_Build_UnknownReferences(t *testing.T) {
+	_, err := NewBuilder().
+		Permission("recipes.write").GrantTo("editors").Implies("recipes.read").
+		Build()
+
+	assert.ErrorContains(t, err, `permission "recipes.write" is granted to unknown group "editors"`)
+	assert.ErrorContains(t, err, `permission "recipes.write" implies unknown permission "recipes.read"`)
+}
+
+// TestCompile_Immutable changes a policy after compiling it, checking the compiled policy is unchanged.
+func TestCompile_Immutable(t *testing.T) {
+	policy := NewPolicy([]Permission{*NewPermission("read", []string{"readers"})}, []Group{*NewGroup("readers", []string{"alice"})})
+	compiled, err := Compile(policy)
+	assert.NoError(t, err)
+
+	policy.Groups[0].Users[0] = "mallory"
+	result, err := compiled.Evaluate("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, result.Permissions)
+
+	result.Permissions[0] = "write"
+	assert.True(t, compiled.HasPermission("alice", "read"))
+	assert.Equal(t, "alice", compiled.Policy().Groups[0].Users[0])
+}
+
+// TestCompiledPolicy_Evaluate evaluates unknown and empty users, checking for empty results and errors.
+func TestCompiledPolicy_Evaluate(t *testing.T) {
+	compiled, err := NewBuilder().Group("readers").Users("alice").Build()
+	assert.NoError(t, err)
+
+	result, err := compiled.Evaluate("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, NewPolicyEvaluationResult([]string{}, []string{}), result)
+
+	_, err = compiled.Evaluate("")
+	assert.EqualError(t, err, "user is empty")
+}
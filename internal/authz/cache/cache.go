@@ -0,0 +1,227 @@
+// Package cache keeps the policy in memory, so reading it does not query the policy store
+// on every call. The cached policy is checked against the revision of the store, a counter
+// bumped on every change, and is only read again when the revision changed.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Source is the policy store the policy is cached from.
+type Source interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+	// PolicyRevision returns a number changing whenever the policy changes.
+	PolicyRevision(ctx context.Context) (int64, error)
+}
+
+// CachedPolicyProvider holds the last policy read from the source. A cached policy is served
+// without checking the source for the TTL after it was last checked; past the TTL the revision
+// of the source is read and the policy is only read again when the revision changed.
+//
+// The cached policy is shared by every caller, which must not modify it.
+type CachedPolicyProvider struct {
+	source Source
+	logger *slog.Logger
+	clock  clock.Clock
+	ttl    time.Duration
+
+	// refreshing serializes the refreshes, so concurrent reads past the TTL query the source once.
+	refreshing sync.Mutex
+
+	mu       sync.RWMutex
+	policy   *authz.Policy
+	revision int64
+	// version is the version of the cached policy, see policyfile.Version, computed once per revision.
+	version string
+	checked time.Time
+	// generation counts the invalidations, so a refresh started before one does not mark the policy fresh.
+	generation uint64
+}
+
+var _ consistency.Reader = (*CachedPolicyProvider)(nil)
+
+// Option configures a CachedPolicyProvider.
+type Option func(*CachedPolicyProvider)
+
+// WithTTL sets how long the policy is served without checking the revision of the source, 5s by default.
+// A zero TTL checks the revision on every read.
+func WithTTL(ttl time.Duration) Option {
+	return func(provider *CachedPolicyProvider) {
+		provider.ttl = ttl
+	}
+}
+
+// NewCachedPolicyProvider creates a new CachedPolicyProvider caching the policy of the source.
+// The policy is read on the first call to ReadPolicy or Refresh.
+func NewCachedPolicyProvider(source Source, logger *slog.Logger, options ...Option) *CachedPolicyProvider {
+	provider := &CachedPolicyProvider{source: source, logger: logger, clock: clock.System(), ttl: 5 * time.Second}
+	for _, option := range options {
+		option(provider)
+	}
+	return provider
+}
+
+// ReadPolicy returns the cached policy, refreshing it first when it was last checked more than the TTL ago.
+func (provider *CachedPolicyProvider) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	if policy, ok := provider.fresh(); ok {
+		return policy, nil
+	}
+
+	provider.refreshing.Lock()
+	defer provider.refreshing.Unlock()
+
+	// another read may have refreshed the policy while this one was waiting
+	if policy, ok := provider.fresh(); ok {
+		return policy, nil
+	}
+	return provider.refresh(ctx)
+}
+
+// ReadPolicyAt returns the cached policy if it is at the revision or a later one, whatever the TTL,
+// and refreshes it otherwise, so a check reflects a change whose consistency token it was given.
+// It fails with consistency.ErrNotYetAvailable when the source has not reached the revision.
+func (provider *CachedPolicyProvider) ReadPolicyAt(ctx context.Context, revision int64) (*authz.Policy, error) {
+	if policy, ok := provider.atLeast(revision); ok {
+		return policy, nil
+	}
+
+	provider.refreshing.Lock()
+	defer provider.refreshing.Unlock()
+
+	// another read may have refreshed the policy while this one was waiting
+	if policy, ok := provider.atLeast(revision); ok {
+		return policy, nil
+	}
+	if _, err := provider.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if policy, ok := provider.atLeast(revision); ok {
+		return policy, nil
+	}
+	return nil, consistency.ErrNotYetAvailable
+}
+
+// PolicyRevision returns the current revision of the source, not the revision of the cached policy.
+func (provider *CachedPolicyProvider) PolicyRevision(ctx context.Context) (int64, error) {
+	return provider.source.PolicyRevision(ctx)
+}
+
+// Version returns the version of the policy, see policyfile.Version, when it is the cached one, so
+// callers answering every request with the version of the policy do not compute it each time.
+func (provider *CachedPolicyProvider) Version(policy *authz.Policy) (string, bool) {
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if policy == nil || policy != provider.policy || provider.version == "" {
+		return "", false
+	}
+	return provider.version, true
+}
+
+// Refresh checks the revision of the source and reads the policy again if it changed, whatever the TTL.
+func (provider *CachedPolicyProvider) Refresh(ctx context.Context) error {
+	provider.refreshing.Lock()
+	defer provider.refreshing.Unlock()
+
+	_, err := provider.refresh(ctx)
+	return err
+}
+
+// Invalidate makes the next read check the revision of the source, for callers that just changed the policy.
+func (provider *CachedPolicyProvider) Invalidate() {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	provider.checked = time.Time{}
+	provider.generation++
+}
+
+// Run refreshes the policy immediately and then every interval until the context is done, so reads
+// seldom wait on the source. Failed refreshes are logged and retried at the next interval.
+func (provider *CachedPolicyProvider) Run(ctx context.Context, interval time.Duration) {
+	ticker := provider.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := provider.Refresh(ctx); err != nil {
+			provider.logger.Warn("failed to refresh the cached policy", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// fresh returns the cached policy if it was checked less than the TTL ago.
+func (provider *CachedPolicyProvider) fresh() (*authz.Policy, bool) {
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if provider.policy == nil || provider.checked.IsZero() || provider.clock.Now().Sub(provider.checked) >= provider.ttl {
+		return nil, false
+	}
+	return provider.policy, true
+}
+
+// atLeast returns the cached policy if it is at the revision or a later one.
+func (provider *CachedPolicyProvider) atLeast(revision int64) (*authz.Policy, bool) {
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if provider.policy == nil || provider.revision < revision {
+		return nil, false
+	}
+	return provider.policy, true
+}
+
+// refresh reads the revision and, when it changed, the policy. The revision is read first, so a
+// change made in between is seen as a newer revision at the next check rather than missed.
+// It must be called with the refreshing lock held.
+func (provider *CachedPolicyProvider) refresh(ctx context.Context) (*authz.Policy, error) {
+	provider.mu.RLock()
+	generation, cached, cachedRevision := provider.generation, provider.policy, provider.revision
+	provider.mu.RUnlock()
+
+	now := provider.clock.Now()
+	revision, err := provider.source.PolicyRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := cached
+	reread := cached == nil || revision != cachedRevision
+	var version string
+	if reread {
+		if policy, err = provider.source.ReadPolicy(ctx); err != nil {
+			return nil, err
+		}
+		// without a version the callers compute it themselves
+		if version, err = policyfile.Version(policy); err != nil {
+			provider.logger.Warn("failed to compute the version of the cached policy", "revision", revision, "error", err)
+		}
+		provider.logger.Debug("cached policy refreshed", "revision", revision, "version", version)
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.policy = policy
+	provider.revision = revision
+	if reread {
+		provider.version = version
+	}
+	if provider.generation == generation {
+		provider.checked = now
+	}
+	return policy, nil
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeSource is a Source counting the policy reads.
+type fakeSource struct {
+	mu       sync.Mutex
+	revision int64
+	reads    int
+	err      error
+}
+
+func (source *fakeSource) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.reads++
+	return authz.NewPolicy(nil, []authz.Group{*authz.NewGroup("cooks", []string{"alice"})}), nil
+}
+
+func (source *fakeSource) PolicyRevision(ctx context.Context) (int64, error) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	return source.revision, source.err
+}
+
+func (source *fakeSource) change() {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.revision++
+}
+
+func newTestProvider(source Source) (*CachedPolicyProvider, *FakeClock) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	provider := NewCachedPolicyProvider(source, slog.New(slog.NewTextHandler(io.Discard, nil)), WithTTL(time.Minute))
+	provider.clock = fake
+	return provider, fake
+}
+
+// TestCachedPolicyProvider_ReadPolicy reads the policy around changes and TTL expiries,
+// checking it is read from the source only when its revision changed.
+func TestCachedPolicyProvider_ReadPolicy(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	provider, fake := newTestProvider(source)
+
+	first, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, source.reads)
+
+	// within the TTL a change is not seen
+	source.change()
+	cached, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, first, cached)
+
+	// past the TTL the changed revision is read again
+	fake.Advance(time.Minute)
+	changed, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.NotSame(t, first, changed)
+	assert.Equal(t, 2, source.reads)
+
+	// past the TTL an unchanged revision keeps the cached policy
+	fake.Advance(time.Minute)
+	unchanged, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, changed, unchanged)
+	assert.Equal(t, 2, source.reads)
+}
+
+// TestCachedPolicyProvider_Version reads the policy around a change, checking its version is the one
+// of policyfile.Version and is only known for the cached policy.
+func TestCachedPolicyProvider_Version(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	provider, _ := newTestProvider(source)
+
+	_, ok := provider.Version(nil)
+	assert.False(t, ok)
+
+	first, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	expected, err := policyfile.Version(first)
+	assert.NoError(t, err)
+	version, ok := provider.Version(first)
+	assert.True(t, ok)
+	assert.Equal(t, expected, version)
+
+	source.change()
+	assert.NoError(t, provider.Refresh(ctx))
+	_, ok = provider.Version(first)
+	assert.False(t, ok, "the version of a replaced policy is not known")
+	changed, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	version, ok = provider.Version(changed)
+	assert.True(t, ok)
+	assert.Equal(t, expected, version, "the same policy has the same version")
+}
+
+// TestCachedPolicyProvider_Invalidate invalidates the cache after a change, checking the change is read within the TTL.
+func TestCachedPolicyProvider_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	provider, _ := newTestProvider(source)
+
+	_, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	source.change()
+	provider.Invalidate()
+
+	_, err = provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.reads)
+}
+
+// TestCachedPolicyProvider_ReadPolicyAt reads the policy at the revision of consistency tokens, checking the
+// cached policy is served while recent enough and refreshed within the TTL otherwise.
+func TestCachedPolicyProvider_ReadPolicyAt(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	provider, _ := newTestProvider(source)
+
+	_, err := provider.ReadPolicyAt(ctx, 0)
+	assert.NoError(t, err)
+	_, err = provider.ReadPolicyAt(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, source.reads)
+
+	source.change()
+	revision, err := provider.PolicyRevision(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), revision)
+	_, err = provider.ReadPolicyAt(ctx, revision)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.reads)
+
+	_, err = provider.ReadPolicyAt(ctx, 5)
+	assert.ErrorIs(t, err, consistency.ErrNotYetAvailable)
+}
+
+// TestCachedPolicyProvider_Error fails to read the revision, checking the error is returned and the next read retries.
+func TestCachedPolicyProvider_Error(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{err: errors.New("database down")}
+	provider, _ := newTestProvider(source)
+
+	_, err := provider.ReadPolicy(ctx)
+	assert.Error(t, err)
+
+	source.err = nil
+	policy, err := provider.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, policy)
+}
+
+// TestCachedPolicyProvider_Run refreshes the policy in the background, checking it is read at once and again after a change.
+func TestCachedPolicyProvider_Run(t *testing.T) {
+	source := &fakeSource{}
+	provider, fake := newTestProvider(source)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		provider.Run(ctx, time.Second)
+		close(done)
+	}()
+	reads := func(count int) func() bool {
+		return func() bool {
+			source.mu.Lock()
+			defer source.mu.Unlock()
+			return source.reads == count
+		}
+	}
+	assert.Eventually(t, reads(1), time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	source.change()
+	fake.Advance(time.Second)
+	assert.Eventually(t, reads(2), time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+// TestManager reads the policy through the manager around a change, checking the change invalidates the cache.
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeSource{}
+	provider, _ := newTestProvider(source)
+	next := new(MockPolicyManager)
+	next.On("CreateGroup", mock.Anything, "bakers").Run(func(mock.Arguments) { source.change() }).Return(7, nil)
+	manager := NewManager(next, provider)
+
+	_, err := manager.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	_, err = manager.CreateGroup(ctx, "bakers")
+	assert.NoError(t, err)
+	_, err = manager.ReadPolicy(ctx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, source.reads)
+	next.AssertNotCalled(t, "ReadPolicy", mock.Anything)
+}
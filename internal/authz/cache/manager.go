@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/hooks"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// PolicyManager is the policy store whose reads are cached.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// Manager is a PolicyManager reading the policy from a CachedPolicyProvider. Every change made
+// through it invalidates the cache, so callers read their own changes; changes made by other
+// instances are seen once the TTL of the provider expired.
+type Manager struct {
+	PolicyManager
+	provider *CachedPolicyProvider
+}
+
+var _ PolicyManager = (*Manager)(nil)
+
+// NewManager creates a new Manager caching the reads of the next PolicyManager with the provider.
+func NewManager(next PolicyManager, provider *CachedPolicyProvider) *Manager {
+	invalidate := hooks.Funcs{AfterFunc: func(ctx context.Context, operation hooks.Operation, result hooks.Result) {
+		// failed changes are invalidated too, a concurrency error means another change was made
+		if operation.Write {
+			provider.Invalidate()
+		}
+	}}
+	return &Manager{PolicyManager: hooks.NewManager(next, invalidate), provider: provider}
+}
+
+// ReadPolicy returns the cached policy.
+func (manager *Manager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return manager.provider.ReadPolicy(ctx)
+}
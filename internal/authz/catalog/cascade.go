@@ -0,0 +1,344 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// Cascade is what happens to the grants of a permission when it is removed from the catalog of its
+// application, or renamed with Entry.RenamedFrom. Permissions granted to no group are left alone.
+type Cascade string
+
+const (
+	// CascadeBlock refuses the catalog while a removed permission is still granted.
+	CascadeBlock Cascade = "block"
+	// CascadeRevoke revokes a removed permission from every group. The groups holding a renamed
+	// permission are granted the new name instead, created if missing.
+	CascadeRevoke Cascade = "revoke"
+	// CascadeOrphan keeps the grants and flags the permission with the OrphanedLabel label,
+	// and the RenamedToLabel label when it is renamed, for the admins to clean up.
+	CascadeOrphan Cascade = "orphan"
+)
+
+// The labels CascadeOrphan sets on the permissions it flags.
+const (
+	// OrphanedLabel is set to the application whose catalog no longer declares the permission.
+	OrphanedLabel = "catalog.orphaned"
+	// RenamedToLabel is set to the new name of a renamed permission.
+	RenamedToLabel = "catalog.renamed-to"
+)
+
+// ParseCascade converts a string to a Cascade.
+func ParseCascade(value string) (Cascade, error) {
+	switch cascade := Cascade(strings.TrimSpace(value)); cascade {
+	case CascadeBlock, CascadeRevoke, CascadeOrphan:
+		return cascade, nil
+	default:
+		return "", fmt.Errorf("unknown cascade %q, expected block, revoke or orphan", value)
+	}
+}
+
+// Cascades is the Cascade of every application.
+type Cascades struct {
+	// The cascade of the applications not listed, CascadeOrphan when empty.
+	Default Cascade
+	// The cascade of single applications by name.
+	Applications map[string]Cascade
+}
+
+// ParseCascades parses a comma separated list of cascades, such as "block,recipes=revoke": a cascade
+// without an application is the default, the others apply to the application before the equal sign.
+func ParseCascades(value string) (Cascades, error) {
+	cascades := Cascades{Applications: map[string]Cascade{}}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		application, name, found := strings.Cut(item, "=")
+		if !found {
+			name = application
+		}
+		cascade, err := ParseCascade(name)
+		if err != nil {
+			return Cascades{}, err
+		}
+		if !found {
+			cascades.Default = cascade
+			continue
+		}
+		if application = strings.TrimSpace(application); application == "" {
+			return Cascades{}, fmt.Errorf("cascade %q: application is empty", item)
+		}
+		cascades.Applications[application] = cascade
+	}
+	return cascades, nil
+}
+
+// For returns the cascade of the application.
+func (cascades Cascades) For(application string) Cascade {
+	if cascade, ok := cascades.Applications[application]; ok {
+		return cascade
+	}
+	if cascades.Default == "" {
+		return CascadeOrphan
+	}
+	return cascades.Default
+}
+
+// Removal is a permission of the previous catalog of an application missing from the next one.
+type Removal struct {
+	Permission string
+	// The new name of the permission when it is renamed, empty when it is removed.
+	RenamedTo string
+}
+
+// Removals returns the permissions of the previous catalog the next one no longer declares,
+// ordered by name. There are none without a previous catalog.
+func Removals(previous *Catalog, next *Catalog) []Removal {
+	if previous == nil {
+		return nil
+	}
+
+	declared := make(map[string]bool, len(next.Permissions))
+	renamed := make(map[string]string)
+	for _, entry := range next.Permissions {
+		declared[entry.Name] = true
+		if entry.RenamedFrom != "" {
+			renamed[entry.RenamedFrom] = entry.Name
+		}
+	}
+
+	removals := []Removal{}
+	for _, entry := range previous.Permissions {
+		if !declared[entry.Name] {
+			removals = append(removals, Removal{Permission: entry.Name, RenamedTo: renamed[entry.Name]})
+		}
+	}
+	slices.SortFunc(removals, func(a, b Removal) int { return strings.Compare(a.Permission, b.Permission) })
+	return removals
+}
+
+// InUseError is returned by CascadingStore for a catalog removing permissions that are still granted
+// while the cascade of the application is CascadeBlock.
+type InUseError struct {
+	// The groups holding each removed permission, by permission name.
+	Grants map[string][]string
+}
+
+func (err *InUseError) Error() string {
+	permissions := make([]string, 0, len(err.Grants))
+	for permission := range err.Grants {
+		permissions = append(permissions, permission)
+	}
+	slices.Sort(permissions)
+
+	details := make([]string, len(permissions))
+	for i, permission := range permissions {
+		details[i] = fmt.Sprintf("%s (granted to %s)", permission, strings.Join(err.Grants[permission], ", "))
+	}
+	return "removed permissions are still granted: " + strings.Join(details, "; ")
+}
+
+// PolicyManager is the policy store the cascades change.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// CascadingStore is a Store applying the Cascade of the application to the grants of the permissions
+// a catalog removes or renames before registering it, so the catalogs and the policy stay in line
+// whoever registers them. The changes go through the policy manager, so guardrails and hooks apply,
+// and every cascade is recorded as an audit event attributed to the registrant of the catalog.
+type CascadingStore struct {
+	Store
+	manager  PolicyManager
+	cascades Cascades
+	audit    audit.Sink
+	logger   *slog.Logger
+	clock    clock.Clock
+}
+
+var _ Store = (*CascadingStore)(nil)
+
+// NewCascadingStore creates a new CascadingStore registering the catalogs to the next store.
+func NewCascadingStore(next Store, manager PolicyManager, cascades Cascades, sink audit.Sink, logger *slog.Logger) *CascadingStore {
+	return &CascadingStore{Store: next, manager: manager, cascades: cascades, audit: sink, logger: logger, clock: clock.System()}
+}
+
+// Register applies the cascade to the permissions removed from the previous catalog of the application,
+// then registers the catalog. The cascade is applied first, so a catalog whose registration failed is
+// registered again with the same outcome.
+func (cascading *CascadingStore) Register(ctx context.Context, catalog Catalog) error {
+	previous, err := cascading.Get(ctx, catalog.Application)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	removals := Removals(previous, &catalog)
+	if len(removals) > 0 {
+		if err := cascading.cascade(ctx, catalog, removals); err != nil {
+			return err
+		}
+	}
+	return cascading.Store.Register(ctx, catalog)
+}
+
+// cascade applies the cascade of the application to the removals still granted.
+func (cascading *CascadingStore) cascade(ctx context.Context, catalog Catalog, removals []Removal) error {
+	policy, err := cascading.manager.ReadPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	grants := map[string][]string{}
+	for _, permission := range policy.Permissions {
+		if len(permission.Groups) > 0 {
+			grants[permission.Name] = permission.Groups
+		}
+	}
+
+	inUse := []Removal{}
+	for _, removal := range removals {
+		if len(grants[removal.Permission]) > 0 {
+			inUse = append(inUse, removal)
+		}
+	}
+	if len(inUse) == 0 {
+		return nil
+	}
+
+	cascade := cascading.cascades.For(catalog.Application)
+	switch cascade {
+	case CascadeBlock:
+		blocked := &InUseError{Grants: map[string][]string{}}
+		for _, removal := range inUse {
+			blocked.Grants[removal.Permission] = grants[removal.Permission]
+			cascading.record(ctx, catalog, cascade, removal, grants[removal.Permission])
+		}
+		return blocked
+	case CascadeRevoke:
+		for _, removal := range inUse {
+			if err := cascading.revoke(ctx, removal, grants[removal.Permission]); err != nil {
+				return err
+			}
+			cascading.record(ctx, catalog, cascade, removal, grants[removal.Permission])
+		}
+	default:
+		for _, removal := range inUse {
+			if err := cascading.orphan(ctx, catalog.Application, removal); err != nil {
+				return err
+			}
+			cascading.record(ctx, catalog, cascade, removal, grants[removal.Permission])
+		}
+	}
+	return nil
+}
+
+// revoke revokes the removed permission from the groups, granting them its new name instead when it is renamed.
+func (cascading *CascadingStore) revoke(ctx context.Context, removal Removal, groups []string) error {
+	permissionId, err := cascading.permissionId(ctx, removal.Permission)
+	if err != nil {
+		return err
+	}
+	renamedId := 0
+	if removal.RenamedTo != "" {
+		renamedId, err = cascading.manager.CreatePermission(ctx, removal.RenamedTo)
+		var storeErr *store.PolicyStoreError
+		if errors.As(err, &storeErr) && storeErr.Code == store.NameAlreadyExist {
+			renamedId, err = cascading.permissionId(ctx, removal.RenamedTo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	groupIds, err := cascading.groupIds(ctx)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		details, err := cascading.manager.GetGroup(ctx, groupIds[group])
+		if err != nil {
+			return err
+		}
+
+		permissions := []int{}
+		for _, permission := range details.Permissions {
+			if permission.ID != permissionId && permission.ID != renamedId {
+				permissions = append(permissions, permission.ID)
+			}
+		}
+		if renamedId != 0 {
+			permissions = append(permissions, renamedId)
+		}
+		if err := cascading.manager.UpdateGroupPermissions(ctx, groupIds[group], permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orphan flags the removed permission with the labels of CascadeOrphan.
+func (cascading *CascadingStore) orphan(ctx context.Context, application string, removal Removal) error {
+	permissionId, err := cascading.permissionId(ctx, removal.Permission)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]*string{OrphanedLabel: &application}
+	if removal.RenamedTo != "" {
+		labels[RenamedToLabel] = &removal.RenamedTo
+	}
+	_, err = cascading.manager.UpdatePermissionMetadata(ctx, permissionId, store.MetadataPatch{Labels: labels})
+	return err
+}
+
+func (cascading *CascadingStore) permissionId(ctx context.Context, name string) (int, error) {
+	permissions, err := cascading.manager.ListPermissions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, permission := range permissions {
+		if permission.Name == name {
+			return permission.ID, nil
+		}
+	}
+	return 0, store.NewPermissionNotFoundError()
+}
+
+func (cascading *CascadingStore) groupIds(ctx context.Context) (map[string]int, error) {
+	groups, err := cascading.manager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int, len(groups))
+	for _, group := range groups {
+		ids[group.Name] = group.ID
+	}
+	return ids, nil
+}
+
+// record records the cascade of a removal. A failure to record it is logged, since the cascade already happened.
+func (cascading *CascadingStore) record(ctx context.Context, catalog Catalog, cascade Cascade, removal Removal, groups []string) {
+	details := map[string]any{"application": catalog.Application, "cascade": string(cascade), "groups": groups}
+	if removal.RenamedTo != "" {
+		details["renamed_to"] = removal.RenamedTo
+	}
+
+	err := cascading.audit.Record(ctx, audit.Event{
+		ID:      id.New(),
+		Time:    cascading.clock.Now(),
+		Actor:   catalog.RegisteredBy,
+		Action:  "catalog.cascade",
+		Subject: removal.Permission,
+		Details: details,
+	})
+	if err != nil {
+		cascading.logger.Error("failed to record catalog cascade audit event", "permission", removal.Permission, "error", err)
+	}
+}
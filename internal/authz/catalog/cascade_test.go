@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// memoryStore is a Store keeping the catalogs in a map.
+type memoryStore map[string]Catalog
+
+func (memory memoryStore) Register(ctx context.Context, catalog Catalog) error {
+	memory[catalog.Application] = catalog
+	return nil
+}
+
+func (memory memoryStore) Get(ctx context.Context, application string) (*Catalog, error) {
+	catalog, ok := memory[application]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &catalog, nil
+}
+
+func (memory memoryStore) List(ctx context.Context) ([]Catalog, error) {
+	return nil, nil
+}
+
+// recordingSink is an audit.Sink keeping the events it records.
+type recordingSink struct {
+	events []audit.Event
+}
+
+func (sink *recordingSink) Record(ctx context.Context, event audit.Event) error {
+	sink.events = append(sink.events, event)
+	return nil
+}
+
+// setupCascadingStore returns a store whose recipes catalog declares recipes.read and recipes.write,
+// over a policy granting recipes.write to the cooks.
+func setupCascadingStore(cascades Cascades) (*MockPolicyManager, memoryStore, *recordingSink, *CascadingStore) {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(authz.NewPolicy(
+		[]authz.Permission{*authz.NewPermission("recipes.read", nil), *authz.NewPermission("recipes.write", []string{"cooks"})},
+		[]authz.Group{*authz.NewGroup("cooks", []string{"alice"})},
+	), nil)
+	manager.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{
+		{ID: 1, Name: "recipes.read"}, {ID: 2, Name: "recipes.write"}, {ID: 3, Name: "recipes.delete"},
+	}, nil)
+
+	catalogs := memoryStore{"recipes": Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.read"}, {Name: "recipes.write"}}}}
+	sink := &recordingSink{}
+	return manager, catalogs, sink, NewCascadingStore(catalogs, manager, cascades, sink, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// TestCascadingStore_Register_Block removes a granted permission under CascadeBlock, checking the catalog is refused.
+func TestCascadingStore_Register_Block(t *testing.T) {
+	_, catalogs, sink, cascading := setupCascadingStore(Cascades{Default: CascadeOrphan, Applications: map[string]Cascade{"recipes": CascadeBlock}})
+
+	err := cascading.Register(context.Background(), Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.read"}}, RegisteredBy: "admin"})
+	assert.EqualError(t, err, "removed permissions are still granted: recipes.write (granted to cooks)")
+	assert.Len(t, catalogs["recipes"].Permissions, 2)
+	assert.Len(t, sink.events, 1)
+	assert.Equal(t, "block", sink.events[0].Details["cascade"])
+}
+
+// TestCascadingStore_Register_Revoke renames a granted permission under CascadeRevoke, checking the groups get the new name.
+func TestCascadingStore_Register_Revoke(t *testing.T) {
+	manager, catalogs, sink, cascading := setupCascadingStore(Cascades{Default: CascadeRevoke})
+	manager.On("CreatePermission", mock.Anything, "recipes.edit").Return(4, nil)
+	manager.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{{ID: 10, Name: "cooks"}}, nil)
+	manager.On("GetGroup", mock.Anything, 10).Return(&store.GroupDetails[int, int, string]{
+		Permissions: []store.PermissionInfo[int]{{ID: 2, Name: "recipes.write"}, {ID: 3, Name: "recipes.delete"}},
+	}, nil)
+	manager.On("UpdateGroupPermissions", mock.Anything, 10, []int{3, 4}).Return(nil)
+
+	registered := Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.edit", RenamedFrom: "recipes.write"}}, RegisteredBy: "admin"}
+	assert.NoError(t, cascading.Register(context.Background(), registered))
+
+	manager.AssertExpectations(t)
+	assert.Equal(t, registered, catalogs["recipes"])
+	assert.Len(t, sink.events, 1)
+	assert.Equal(t, "admin", sink.events[0].Actor)
+	assert.Equal(t, "recipes.write", sink.events[0].Subject)
+	assert.Equal(t, map[string]any{"application": "recipes", "cascade": "revoke", "groups": []string{"cooks"}, "renamed_to": "recipes.edit"}, sink.events[0].Details)
+}
+
+// TestCascadingStore_Register_Orphan removes a granted permission under the default cascade, checking it is flagged.
+func TestCascadingStore_Register_Orphan(t *testing.T) {
+	manager, catalogs, sink, cascading := setupCascadingStore(Cascades{})
+	application := "recipes"
+	manager.On("UpdatePermissionMetadata", mock.Anything, 2, store.MetadataPatch{Labels: map[string]*string{OrphanedLabel: &application}}).
+		Return(&store.PermissionInfo[int]{ID: 2}, nil)
+
+	assert.NoError(t, cascading.Register(context.Background(), Catalog{Application: "recipes", Permissions: []Entry{}}))
+
+	manager.AssertExpectations(t)
+	manager.AssertNotCalled(t, "UpdateGroupPermissions", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, catalogs["recipes"].Permissions)
+	assert.Len(t, sink.events, 1, "recipes.read is granted to no group")
+}
+
+// TestCascadingStore_Register_New registers a first catalog, checking nothing cascades.
+func TestCascadingStore_Register_New(t *testing.T) {
+	manager, catalogs, sink, cascading := setupCascadingStore(Cascades{Default: CascadeBlock})
+
+	assert.NoError(t, cascading.Register(context.Background(), Catalog{Application: "billing", Permissions: []Entry{}}))
+	assert.Contains(t, catalogs, "billing")
+	assert.Empty(t, sink.events)
+	manager.AssertNotCalled(t, "ReadPolicy", mock.Anything)
+}
+
+func TestParseCascades(t *testing.T) {
+	cascades, err := ParseCascades("block, recipes=revoke,billing=orphan")
+	assert.NoError(t, err)
+	assert.Equal(t, CascadeBlock, cascades.For("catalog"))
+	assert.Equal(t, CascadeRevoke, cascades.For("recipes"))
+	assert.Equal(t, CascadeOrphan, cascades.For("billing"))
+
+	cascades, err = ParseCascades("")
+	assert.NoError(t, err)
+	assert.Equal(t, CascadeOrphan, cascades.For("recipes"))
+
+	_, err = ParseCascades("recipes=delete")
+	assert.EqualError(t, err, `unknown cascade "delete", expected block, revoke or orphan`)
+	_, err = ParseCascades("=block")
+	assert.EqualError(t, err, `cascade "=block": application is empty`)
+}
+
+func TestRemovals(t *testing.T) {
+	previous := &Catalog{Permissions: []Entry{{Name: "recipes.delete"}, {Name: "recipes.read"}, {Name: "recipes.write"}}}
+	next := &Catalog{Permissions: []Entry{{Name: "recipes.edit", RenamedFrom: "recipes.write"}, {Name: "recipes.read"}}}
+
+	assert.Equal(t, []Removal{{Permission: "recipes.delete"}, {Permission: "recipes.write", RenamedTo: "recipes.edit"}}, Removals(previous, next))
+	assert.Empty(t, Removals(nil, next))
+}
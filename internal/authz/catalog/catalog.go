@@ -0,0 +1,174 @@
+// Package catalog records the permissions each application declares, with their descriptions
+// and risk levels, so grants can be checked against what applications actually use. A CascadingStore
+// keeps the grants in line with the catalogs as permissions are removed from them or renamed.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// ErrNotFound is returned when no catalog is registered for the application.
+var ErrNotFound = errors.New("permission catalog not found")
+
+// Entry describes a single permission declared by an application.
+type Entry struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Risk        authz.RiskLevel `json:"risk,omitempty"`
+	// The name the permission had in the previous catalog of the application when it is renamed,
+	// see Cascade for what happens to the grants of the previous name.
+	RenamedFrom string `json:"renamed_from,omitempty"`
+}
+
+// Catalog is the set of permissions an application declares.
+type Catalog struct {
+	// The application owning the catalog, such as "recipes".
+	Application  string    `json:"application"`
+	Permissions  []Entry   `json:"permissions"`
+	RegisteredBy string    `json:"registered_by"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Store persists permission catalogs, one per application.
+type Store interface {
+	// Register replaces the catalog of the application.
+	Register(ctx context.Context, catalog Catalog) error
+	Get(ctx context.Context, application string) (*Catalog, error)
+	// List returns every catalog ordered by application.
+	List(ctx context.Context) ([]Catalog, error)
+}
+
+// Validate checks that the catalog is complete, normalizes the risk levels
+// and sorts the permissions by name.
+func (catalog *Catalog) Validate() error {
+	if strings.TrimSpace(catalog.Application) == "" {
+		return errors.New("application is empty")
+	}
+
+	names := make(shared.Set[string], len(catalog.Permissions))
+	for i := range catalog.Permissions {
+		entry := &catalog.Permissions[i]
+		if strings.TrimSpace(entry.Name) == "" {
+			return fmt.Errorf("permission %d: name is empty", i+1)
+		}
+		if !names.Insert(entry.Name) {
+			return fmt.Errorf("permission %q is listed twice", entry.Name)
+		}
+
+		risk, err := authz.ParseRiskLevel(string(entry.Risk))
+		if err != nil {
+			return fmt.Errorf("permission %q: %w", entry.Name, err)
+		}
+		entry.Risk = risk
+	}
+
+	renamed := make(shared.Set[string])
+	for _, entry := range catalog.Permissions {
+		if entry.RenamedFrom == "" {
+			continue
+		}
+		if names.Contains(entry.RenamedFrom) {
+			return fmt.Errorf("permission %q: renamed from %q, which is still listed", entry.Name, entry.RenamedFrom)
+		}
+		if !renamed.Insert(entry.RenamedFrom) {
+			return fmt.Errorf("permission %q is renamed twice", entry.RenamedFrom)
+		}
+	}
+
+	slices.SortFunc(catalog.Permissions, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+	return nil
+}
+
+// Read decodes a JSON catalog document, such as the one an application keeps next to its code,
+// and validates it.
+func Read(r io.Reader) (*Catalog, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	catalog := &Catalog{}
+	if err := decoder.Decode(catalog); err != nil {
+		return nil, fmt.Errorf("decode catalog document: %w", err)
+	}
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Load reads the catalog document stored at the given path.
+func Load(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Warning is a finding of Lint.
+type Warning struct {
+	Permission string `json:"permission"`
+	Message    string `json:"message"`
+}
+
+// Lint checks the policy against the registered catalogs. It warns about permissions granted
+// to groups that no catalog declares, and about permissions whose risk level differs from
+// the level declared in their catalog. It also warns about permissions inherited from a parent
+// folder unless every folder they pass through sets authz.InheritanceInherit. Warnings are sorted by permission.
+func Lint(policy *authz.Policy, catalogs []Catalog) []Warning {
+	declared := make(map[string]Entry)
+	for _, catalog := range catalogs {
+		for _, entry := range catalog.Permissions {
+			declared[entry.Name] = entry
+		}
+	}
+
+	warnings := []Warning{}
+	for _, permission := range policy.Permissions {
+		entry, ok := declared[permission.Name]
+		if !ok {
+			if len(permission.Groups) > 0 {
+				warnings = append(warnings, Warning{
+					Permission: permission.Name,
+					Message:    fmt.Sprintf("granted to %s but not declared in any catalog", strings.Join(permission.Groups, ", ")),
+				})
+			}
+			continue
+		}
+
+		risk, err := authz.ParseRiskLevel(string(permission.Risk))
+		if err == nil && risk != entry.Risk {
+			warnings = append(warnings, Warning{
+				Permission: permission.Name,
+				Message:    fmt.Sprintf("risk level is %s but the catalog declares %s", risk, entry.Risk),
+			})
+		}
+	}
+
+	for _, grant := range policy.InheritedGrants() {
+		if grant.Expected {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Permission: grant.Permission,
+			Message: fmt.Sprintf("granted to %s in folder %s through inheritance from folder %s, which the folders in between did not opt into",
+				grant.Group, grant.Folder, grant.From),
+		})
+	}
+
+	slices.SortStableFunc(warnings, func(a, b Warning) int { return strings.Compare(a.Permission, b.Permission) })
+	return warnings
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidate calls Catalog.Validate with a valid catalog, checking the risk levels are normalized and the permissions sorted.
+func TestValidate(t *testing.T) {
+	catalog := Catalog{Application: "recipes", Permissions: []Entry{
+		{Name: "recipes.write", Risk: authz.RiskMedium},
+		{Name: "recipes.read", Description: "Read recipes"},
+	}}
+
+	assert.NoError(t, catalog.Validate())
+	assert.Equal(t, []Entry{
+		{Name: "recipes.read", Description: "Read recipes", Risk: authz.RiskLow},
+		{Name: "recipes.write", Risk: authz.RiskMedium},
+	}, catalog.Permissions)
+}
+
+// TestValidate_Error calls Catalog.Validate with invalid catalogs, checking for the expected errors.
+func TestValidate_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		catalog  Catalog
+		expected string
+	}{
+		{name: "empty application", catalog: Catalog{}, expected: "application is empty"},
+		{name: "empty name", catalog: Catalog{Application: "recipes", Permissions: []Entry{{}}}, expected: "permission 1: name is empty"},
+		{
+			name:     "duplicate name",
+			catalog:  Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.read"}, {Name: "recipes.read"}}},
+			expected: `permission "recipes.read" is listed twice`,
+		},
+		{
+			name:     "renamed from a listed permission",
+			catalog:  Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.read"}, {Name: "recipes.edit", RenamedFrom: "recipes.read"}}},
+			expected: `permission "recipes.edit": renamed from "recipes.read", which is still listed`,
+		},
+		{
+			name:     "renamed twice",
+			catalog:  Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.edit", RenamedFrom: "recipes.write"}, {Name: "recipes.update", RenamedFrom: "recipes.write"}}},
+			expected: `permission "recipes.write" is renamed twice`,
+		},
+		{
+			name:     "unknown risk",
+			catalog:  Catalog{Application: "recipes", Permissions: []Entry{{Name: "recipes.read", Risk: "critical"}}},
+			expected: `permission "recipes.read": unknown risk level "critical"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.catalog.Validate(), tt.expected)
+		})
+	}
+}
+
+// TestRead calls catalog.Read with a valid document, checking for the validated catalog.
+func TestRead(t *testing.T) {
+	catalog, err := Read(strings.NewReader(`{"application": "recipes", "permissions": [
+		{"name": "recipes.write", "description": "Edit recipes", "risk": "medium"},
+		{"name": "recipes.read"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "recipes", catalog.Application)
+	assert.Equal(t, []Entry{
+		{Name: "recipes.read", Risk: authz.RiskLow},
+		{Name: "recipes.write", Description: "Edit recipes", Risk: authz.RiskMedium},
+	}, catalog.Permissions)
+}
+
+// TestRead_Error calls catalog.Read with an unknown field and an invalid catalog, checking for errors.
+func TestRead_Error(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"application": "recipes", "roles": []}`))
+	assert.Error(t, err)
+
+	_, err = Read(strings.NewReader(`{"permissions": []}`))
+	assert.EqualError(t, err, "application is empty")
+}
+
+// TestLint calls Lint with undeclared and mismatched permissions, checking for the expected warnings.
+func TestLint(t *testing.T) {
+	policy := authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "recipes.read", Groups: []string{"cooks"}},
+			{Name: "recipes.delete", Groups: []string{"admins"}},
+			{Name: "recipes.legacy", Groups: []string{"cooks", "admins"}},
+			{Name: "recipes.unused", Groups: []string{}},
+		},
+		nil,
+	)
+	catalogs := []Catalog{{Application: "recipes", Permissions: []Entry{
+		{Name: "recipes.read", Risk: authz.RiskLow},
+		{Name: "recipes.delete", Risk: authz.RiskHigh},
+	}}}
+
+	assert.Equal(t, []Warning{
+		{Permission: "recipes.delete", Message: "risk level is low but the catalog declares high"},
+		{Permission: "recipes.legacy", Message: "granted to cooks, admins but not declared in any catalog"},
+	}, Lint(policy, catalogs))
+}
+
+func TestLint_FolderInheritance(t *testing.T) {
+	policy := authz.NewPolicy(
+		[]authz.Permission{{Name: "recipes.read", Groups: []string{}}},
+		[]authz.Group{{Name: "cooks"}, {Name: "bakers"}, {Name: "billing"}},
+	)
+	policy.Folders = []authz.Folder{
+		{Path: "kitchen", Grants: []string{"recipes.read"}},
+		{Path: "kitchen/pastry", Groups: []string{"bakers"}, Inheritance: authz.InheritanceInherit},
+		{Path: "kitchen/line", Groups: []string{"cooks"}},
+		{Path: "kitchen/billing", Groups: []string{"billing"}, Inheritance: authz.InheritanceBlock},
+	}
+	catalogs := []Catalog{{Application: "recipes", Permissions: []Entry{{Name: "recipes.read", Risk: authz.RiskLow}}}}
+
+	assert.Equal(t, []Warning{
+		{Permission: "recipes.read", Message: "granted to cooks in folder kitchen/line through inheritance from folder kitchen, which the folders in between did not opt into"},
+	}, Lint(policy, catalogs))
+}
@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Register inserts the catalog or replaces the one already registered for the application.
+func (store *PostgresStore) Register(ctx context.Context, catalog Catalog) error {
+	entries, err := json.Marshal(catalog.Permissions)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.db.Exec(ctx, `
+	INSERT INTO permission_catalogs (application, entries, registered_by, registered_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (application) DO UPDATE
+	SET entries = EXCLUDED.entries, registered_by = EXCLUDED.registered_by, registered_at = EXCLUDED.registered_at
+	`, catalog.Application, entries, catalog.RegisteredBy, catalog.RegisteredAt)
+	return err
+}
+
+// Get returns the catalog registered for the application.
+func (store *PostgresStore) Get(ctx context.Context, application string) (*Catalog, error) {
+	catalog := Catalog{Application: application}
+	var entries []byte
+	err := store.db.QueryRow(ctx, `
+	SELECT entries, registered_by, registered_at FROM permission_catalogs WHERE application = $1
+	`, application).Scan(&entries, &catalog.RegisteredBy, &catalog.RegisteredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(entries, &catalog.Permissions); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// List returns every catalog ordered by application.
+func (store *PostgresStore) List(ctx context.Context) ([]Catalog, error) {
+	rows, err := store.db.Query(ctx, `
+	SELECT application, entries, registered_by, registered_at FROM permission_catalogs ORDER BY application
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalogs := []Catalog{}
+	for rows.Next() {
+		var catalog Catalog
+		var entries []byte
+		if err := rows.Scan(&catalog.Application, &entries, &catalog.RegisteredBy, &catalog.RegisteredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(entries, &catalog.Permissions); err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, catalog)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return catalogs, nil
+}
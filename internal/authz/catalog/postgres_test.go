@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var registered = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPostgresStore_Register(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	store := NewPostgresStore(mockDb)
+
+	mockDb.On("Exec", ctx, mock.Anything, []any{"recipes",
+		[]byte(`[{"name":"recipes.read","description":"Read recipes","risk":"low"}]`), "deployer", registered}).
+		Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+	err := store.Register(ctx, Catalog{
+		Application:  "recipes",
+		Permissions:  []Entry{{Name: "recipes.read", Description: "Read recipes", Risk: authz.RiskLow}},
+		RegisteredBy: "deployer",
+		RegisteredAt: registered,
+	})
+	assert.NoError(t, err)
+
+	mockDb.AssertExpectations(t)
+}
+
+func TestPostgresStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"recipes"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*[]byte)) = []byte(`[{"name":"recipes.read","risk":"low"}]`)
+			*(dest[1].(*string)) = "deployer"
+			*(dest[2].(*time.Time)) = registered
+		}).Return(nil)
+
+		catalog, err := store.Get(ctx, "recipes")
+		assert.NoError(t, err)
+		assert.Equal(t, &Catalog{
+			Application:  "recipes",
+			Permissions:  []Entry{{Name: "recipes.read", Risk: authz.RiskLow}},
+			RegisteredBy: "deployer",
+			RegisteredAt: registered,
+		}, catalog)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"recipes"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		catalog, err := store.Get(ctx, "recipes")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, catalog)
+	})
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+	store := NewPostgresStore(mockDb)
+
+	mockDb.On("Query", ctx, mock.Anything, []any(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*string)) = "recipes"
+		*(dest[1].(*[]byte)) = []byte(`[{"name":"recipes.read","risk":"low"}]`)
+		*(dest[2].(*string)) = "deployer"
+		*(dest[3].(*time.Time)) = registered
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	catalogs, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Catalog{{
+		Application:  "recipes",
+		Permissions:  []Entry{{Name: "recipes.read", Risk: authz.RiskLow}},
+		RegisteredBy: "deployer",
+		RegisteredAt: registered,
+	}}, catalogs)
+}
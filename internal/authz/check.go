@@ -0,0 +1,117 @@
+package authz
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+)
+
+// DenialReason tells why a permission check was denied, so callers and logs can tell the cases apart.
+type DenialReason string
+
+const (
+	// DenialUserUnknown means the user is not a member of any group of the policy.
+	DenialUserUnknown DenialReason = "user_unknown"
+	// DenialPermissionUnknown means the permission is not defined in the policy, nor any wildcard above it.
+	DenialPermissionUnknown DenialReason = "permission_unknown"
+	// DenialNoMatchingGroup means none of the groups of the user is granted the permission,
+	// directly or through an implication.
+	DenialNoMatchingGroup DenialReason = "no_matching_group"
+	// DenialExplicit means the permission is explicitly denied to the user or one of their groups,
+	// overriding any grant, see Denial.
+	DenialExplicit DenialReason = "explicitly_denied"
+	// DenialConditionNotMet means a group of the user is granted the permission, but the condition
+	// of the grant does not hold for the attributes of the request, see Permission.Condition.
+	DenialConditionNotMet DenialReason = "condition_not_met"
+)
+
+// CheckResult is the outcome of checking a single permission of a user.
+type CheckResult struct {
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+	// Why the permission was denied, empty when the policy grants it. A permission allowed
+	// with a reason was allowed by the evaluation mode rather than by the policy.
+	Reason DenialReason `json:"reason,omitempty"`
+	// The evaluation mode the check was made in.
+	Mode EvaluationMode `json:"mode"`
+	// The wildcard permission the user holds granting the permission, see GrantingPermission,
+	// empty when the permission is held by name.
+	GrantedBy string `json:"granted_by,omitempty"`
+}
+
+// Check tells whether the user is granted the permission and, when they are not, why.
+// Permissions the user is not granted are treated according to the evaluation mode of the policy.
+//
+// Parameters:
+//
+//	user - the username to be evaluated against the policy.
+//	permission - the name of the permission to check.
+//
+// Returns:
+//
+//	*CheckResult - the outcome of the check.
+//	error - an error if the evaluation process encounters an issue.
+func (policy *Policy) Check(user string, permission string) (*CheckResult, error) {
+	return policy.CheckWith(user, permission, nil)
+}
+
+// CheckWith tells whether the user is granted the permission for a request with the given attributes
+// and, when they are not, why. The permissions with a condition are granted only when it holds for the
+// attributes, see EvaluateWith.
+func (policy *Policy) CheckWith(user string, permission string, attributes condition.Attributes) (*CheckResult, error) {
+	if permission == "" {
+		return nil, errors.New("permission is empty")
+	}
+
+	result, err := policy.EvaluateWith(user, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.CheckEvaluated(user, permission, result), nil
+}
+
+// CheckEvaluated derives the outcome of a check from an evaluation of the user already made,
+// for callers needing both the evaluation and the reason of a denial.
+func (policy *Policy) CheckEvaluated(user string, permission string, result *PolicyEvaluationResult) *CheckResult {
+	check := &CheckResult{User: user, Permission: permission}
+	granting, granted := GrantingPermission(result.Permissions, permission)
+	_, isDenied := DenyingPermission(result.Denied, permission)
+	switch {
+	case isDenied:
+		check.Reason = DenialExplicit
+	case granted:
+		check.Allowed = true
+		if granting != permission {
+			check.GrantedBy = granting
+		}
+	case !policy.defines(permission):
+		check.Reason = DenialPermissionUnknown
+	case len(result.Groups) == 0:
+		check.Reason = DenialUserUnknown
+	case policy.grantedConditionally(permission, result.Groups):
+		check.Reason = DenialConditionNotMet
+	default:
+		check.Reason = DenialNoMatchingGroup
+	}
+
+	mode := policy.Mode
+	if mode == "" {
+		mode = ModeDefaultDeny
+	}
+	mode.apply(check)
+	return check
+}
+
+// grantedConditionally reports whether one of the groups is granted the permission, or a permission
+// granting it, with a condition.
+func (policy *Policy) grantedConditionally(permission string, groups []string) bool {
+	granting := policy.granting(permission)
+	return slices.ContainsFunc(policy.Permissions, func(candidate Permission) bool {
+		return candidate.Condition != "" && granting.Contains(candidate.Name) && slices.ContainsFunc(candidate.Groups, func(group string) bool {
+			return slices.Contains(groups, group)
+		})
+	})
+}
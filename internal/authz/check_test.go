@@ -0,0 +1,44 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPolicy_Check checks permissions of users, checking the reason of every denial.
+func TestPolicy_Check(t *testing.T) {
+	policy := sessionPolicy()
+
+	tests := []struct {
+		name       string
+		user       string
+		permission string
+		expected   CheckResult
+	}{
+		{"allowed", "bob", "write", CheckResult{User: "bob", Permission: "write", Allowed: true, Mode: ModeDefaultDeny}},
+		{"allowed through an implication", "bob", "read", CheckResult{User: "bob", Permission: "read", Allowed: true, Mode: ModeDefaultDeny}},
+		{"no matching group", "alice", "write", CheckResult{User: "alice", Permission: "write", Reason: DenialNoMatchingGroup, Mode: ModeDefaultDeny}},
+		{"user unknown", "mallory", "read", CheckResult{User: "mallory", Permission: "read", Reason: DenialUserUnknown, Mode: ModeDefaultDeny}},
+		{"permission unknown", "alice", "purge", CheckResult{User: "alice", Permission: "purge", Reason: DenialPermissionUnknown, Mode: ModeDefaultDeny}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := policy.Check(test.user, test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, &test.expected, result)
+		})
+	}
+}
+
+// TestPolicy_Check_Invalid checks with an empty user or permission, checking for an error.
+func TestPolicy_Check_Invalid(t *testing.T) {
+	policy := sessionPolicy()
+
+	_, err := policy.Check("", "read")
+	assert.EqualError(t, err, "user is empty")
+
+	_, err = policy.Check("alice", "")
+	assert.EqualError(t, err, "permission is empty")
+}
@@ -0,0 +1,110 @@
+// Package cleanup finds and removes the records left behind by users who no longer belong
+// to any group, such as the directory entries and group ownerships of users who left.
+// Memberships always reference an existing group, because deleting a group deletes its
+// memberships and the store enforces the reference, so they never need cleaning up.
+package cleanup
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Ownership is a user owning a group.
+type Ownership struct {
+	GroupID int    `json:"group_id"`
+	User    string `json:"user"`
+}
+
+// Report lists the orphaned records found or removed by a cleanup.
+//
+// A user is orphaned when they have no group membership, manage no directory user and
+// either have no directory entry or one that was not synchronized during the grace period.
+type Report struct {
+	// Whether the records were only found, and left in place.
+	DryRun bool `json:"dry_run"`
+	// The directory entries of orphaned users, sorted by user.
+	DirectoryUsers []string `json:"directory_users"`
+	// The group ownerships of orphaned users, sorted by group and user.
+	GroupOwners []Ownership `json:"group_owners"`
+}
+
+// Empty reports whether no orphaned record was found.
+func (report *Report) Empty() bool {
+	return len(report.DirectoryUsers) == 0 && len(report.GroupOwners) == 0
+}
+
+// Store finds and removes orphaned records.
+type Store interface {
+	// FindOrphans returns the orphaned records, treating directory entries synchronized before the given time as stale.
+	FindOrphans(ctx context.Context, staleBefore time.Time) (*Report, error)
+	// RemoveOrphans deletes the orphaned records, like FindOrphans finds them, and returns those deleted.
+	RemoveOrphans(ctx context.Context, staleBefore time.Time) (*Report, error)
+}
+
+// Job periodically removes, or only reports, orphaned records.
+type Job struct {
+	store       Store
+	gracePeriod time.Duration
+	dryRun      bool
+	logger      *slog.Logger
+	clock       clock.Clock
+}
+
+// JobOption configures optional Job settings.
+type JobOption func(*Job)
+
+// WithDryRun makes the Job only report the orphaned records, leaving them in place.
+func WithDryRun() JobOption {
+	return func(job *Job) {
+		job.dryRun = true
+	}
+}
+
+// NewJob creates a new Job cleaning up the store. Directory entries synchronized during the
+// grace period are kept, so users the directory reports before their memberships are not removed.
+func NewJob(store Store, gracePeriod time.Duration, logger *slog.Logger, options ...JobOption) *Job {
+	job := &Job{store: store, gracePeriod: gracePeriod, logger: logger, clock: clock.System()}
+	for _, option := range options {
+		option(job)
+	}
+	return job
+}
+
+// Clean removes the orphaned records, or only finds them in dry run, and reports them.
+func (job *Job) Clean(ctx context.Context) (*Report, error) {
+	staleBefore := job.clock.Now().Add(-job.gracePeriod)
+	if job.dryRun {
+		report, err := job.store.FindOrphans(ctx, staleBefore)
+		if err != nil {
+			return nil, err
+		}
+		report.DryRun = true
+		return report, nil
+	}
+	return job.store.RemoveOrphans(ctx, staleBefore)
+}
+
+// Run cleans up immediately and then every interval until the context is done.
+func (job *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := job.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := job.Clean(ctx)
+		if err != nil {
+			job.logger.Error("failed to clean up orphaned records", "error", err)
+		} else if !report.Empty() {
+			job.logger.Info("cleaned up orphaned records", "dry_run", report.DryRun,
+				"directory_users", report.DirectoryUsers, "group_owners", len(report.GroupOwners))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
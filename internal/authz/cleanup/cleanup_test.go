@@ -0,0 +1,59 @@
+package cleanup
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func newTestJob(mockDb *MockPgDb, options ...JobOption) *Job {
+	job := NewJob(NewPostgresStore(mockDb), 30*24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+	job.clock = NewFakeClock(staleBefore.Add(30 * 24 * time.Hour))
+	return job
+}
+
+func TestJob_Clean(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("removes orphans", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		job := newTestJob(mockDb)
+
+		mockDb.On("Query", ctx, statement("DELETE FROM group_owners"), []any{staleBefore}).Return(ownerRows(), nil)
+		mockDb.On("Query", ctx, statement("DELETE FROM directory_users"), []any{staleBefore}).Return(userRows("carol"), nil)
+
+		report, err := job.Clean(ctx)
+		assert.NoError(t, err)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, []string{"carol"}, report.DirectoryUsers)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("dry run only finds orphans", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		job := newTestJob(mockDb, WithDryRun())
+
+		mockDb.On("Query", ctx, statement("SELECT o.group_id"), []any{staleBefore}).Return(ownerRows(), nil)
+		mockDb.On("Query", ctx, statement("SELECT d.id"), []any{staleBefore}).Return(userRows("carol"), nil)
+
+		report, err := job.Clean(ctx)
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, []string{"carol"}, report.DirectoryUsers)
+
+		mockDb.AssertNotCalled(t, "Query", ctx, statement("DELETE"), mock.Anything)
+	})
+}
+
+func TestReport_Empty(t *testing.T) {
+	assert.True(t, (&Report{}).Empty())
+	assert.False(t, (&Report{GroupOwners: []Ownership{{GroupID: 1, User: "bob"}}}).Empty())
+}
@@ -0,0 +1,129 @@
+package cleanup
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// withOrphans selects the orphaned users, see Report.
+// Every statement checks the conditions again, so users joining a group meanwhile are kept.
+const withOrphans = `
+	WITH orphans AS (
+		SELECT d.id FROM directory_users d WHERE d.updated_at < $1
+		UNION
+		SELECT o.user_id FROM group_owners o WHERE NOT EXISTS (SELECT 1 FROM directory_users d WHERE d.id = o.user_id)
+	), orphaned AS (
+		SELECT u.id FROM orphans u
+		WHERE NOT EXISTS (SELECT 1 FROM subjects s WHERE s.id = u.id)
+		AND NOT EXISTS (SELECT 1 FROM directory_users r WHERE r.manager = u.id)
+	)`
+
+// FindOrphans returns the orphaned records without changing them.
+func (store *PostgresStore) FindOrphans(ctx context.Context, staleBefore time.Time) (*Report, error) {
+	owners, err := store.ownerships(ctx, withOrphans+`
+	SELECT o.group_id, o.user_id FROM group_owners o JOIN orphaned ON orphaned.id = o.user_id
+	ORDER BY o.group_id, o.user_id
+	`, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := store.users(ctx, withOrphans+`
+	SELECT d.id FROM directory_users d JOIN orphaned ON orphaned.id = d.id
+	ORDER BY d.id
+	`, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{DirectoryUsers: users, GroupOwners: owners}, nil
+}
+
+// RemoveOrphans deletes the group ownerships and then the directory entries of the orphaned users.
+func (store *PostgresStore) RemoveOrphans(ctx context.Context, staleBefore time.Time) (*Report, error) {
+	owners, err := store.ownerships(ctx, withOrphans+`
+	DELETE FROM group_owners o USING orphaned WHERE o.user_id = orphaned.id
+	RETURNING o.group_id, o.user_id
+	`, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := store.users(ctx, withOrphans+`
+	DELETE FROM directory_users d USING orphaned WHERE d.id = orphaned.id
+	RETURNING d.id
+	`, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	// DELETE does not order the rows it returns
+	slices.SortFunc(owners, func(a, b Ownership) int {
+		return cmp.Or(cmp.Compare(a.GroupID, b.GroupID), strings.Compare(a.User, b.User))
+	})
+	slices.Sort(users)
+	return &Report{DirectoryUsers: users, GroupOwners: owners}, nil
+}
+
+func (store *PostgresStore) ownerships(ctx context.Context, sql string, args ...any) ([]Ownership, error) {
+	rows, err := store.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []Ownership{}
+	for rows.Next() {
+		var owner Ownership
+		if err := rows.Scan(&owner.GroupID, &owner.User); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return owners, nil
+}
+
+func (store *PostgresStore) users(ctx context.Context, sql string, args ...any) ([]string, error) {
+	rows, err := store.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []string{}
+	for rows.Next() {
+		var user string
+		if err := rows.Scan(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return users, nil
+}
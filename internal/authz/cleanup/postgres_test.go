@@ -0,0 +1,114 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var staleBefore = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func statement(fragment string) any {
+	return mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, fragment) })
+}
+
+func ownerRows(owners ...Ownership) *MockRows {
+	rows := new(MockRows)
+	for _, owner := range owners {
+		rows.On("Next").Return(true).Once()
+		rows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*int)) = owner.GroupID
+			*(dest[1].(*string)) = owner.User
+		}).Return(nil).Once()
+	}
+	rows.On("Next").Return(false)
+	rows.On("Err").Return(nil)
+	rows.On("Close").Return()
+	return rows
+}
+
+func userRows(users ...string) *MockRows {
+	rows := new(MockRows)
+	for _, user := range users {
+		rows.On("Next").Return(true).Once()
+		rows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*string)) = user
+		}).Return(nil).Once()
+	}
+	rows.On("Next").Return(false)
+	rows.On("Err").Return(nil)
+	rows.On("Close").Return()
+	return rows
+}
+
+func TestPostgresStore_FindOrphans(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, statement("SELECT o.group_id, o.user_id FROM group_owners o JOIN orphaned"), []any{staleBefore}).
+			Return(ownerRows(Ownership{GroupID: 3, User: "bob"}), nil)
+		mockDb.On("Query", ctx, statement("SELECT d.id FROM directory_users d JOIN orphaned"), []any{staleBefore}).
+			Return(userRows("bob", "carol"), nil)
+
+		report, err := store.FindOrphans(ctx, staleBefore)
+		assert.NoError(t, err)
+		assert.Equal(t, &Report{DirectoryUsers: []string{"bob", "carol"}, GroupOwners: []Ownership{{GroupID: 3, User: "bob"}}}, report)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		report, err := store.FindOrphans(ctx, staleBefore)
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+}
+
+func TestPostgresStore_RemoveOrphans(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, statement("DELETE FROM group_owners o USING orphaned"), []any{staleBefore}).
+			Return(ownerRows(Ownership{GroupID: 7, User: "bob"}, Ownership{GroupID: 3, User: "dave"}, Ownership{GroupID: 3, User: "bob"}), nil)
+		mockDb.On("Query", ctx, statement("DELETE FROM directory_users d USING orphaned"), []any{staleBefore}).
+			Return(userRows("carol", "bob"), nil)
+
+		report, err := store.RemoveOrphans(ctx, staleBefore)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bob", "carol"}, report.DirectoryUsers)
+		assert.Equal(t, []Ownership{{GroupID: 3, User: "bob"}, {GroupID: 3, User: "dave"}, {GroupID: 7, User: "bob"}}, report.GroupOwners)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("owners kept on directory error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, statement("DELETE FROM group_owners"), []any{staleBefore}).Return(ownerRows(), nil)
+		mockDb.On("Query", ctx, statement("DELETE FROM directory_users"), []any{staleBefore}).Return(new(MockRows), errors.New("db error"))
+
+		report, err := store.RemoveOrphans(ctx, staleBefore)
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+)
+
+// cacheKey identifies a cached decision.
+type cacheKey struct {
+	user       string
+	permission string
+}
+
+// cacheEntry is a cached decision with its expiry.
+type cacheEntry struct {
+	key      cacheKey
+	decision Decision
+	expires  time.Time
+}
+
+// maxSuperseded is the number of policy versions remembered as replaced by an invalidation.
+const maxSuperseded = 8
+
+// decisionCache is a bounded least recently used cache of decisions.
+// Every entry is tagged with the policy version it was decided with; observing a
+// new version drops every entry so grants revoked by the new policy do not linger.
+type decisionCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+	version  string
+	// The revision of the last invalidation, to skip duplicate and out of order ones.
+	revision uint64
+	// The versions replaced by invalidations, whose decisions still in flight are not cached.
+	superseded []string
+}
+
+func newDecisionCache(capacity int) *decisionCache {
+	return &decisionCache{capacity: capacity, entries: make(map[cacheKey]*list.Element), order: list.New()}
+}
+
+// get returns the decision cached for the key, unless it expired.
+func (cache *decisionCache) get(key cacheKey, now time.Time) (Decision, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return Decision{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		cache.remove(element)
+		return Decision{}, false
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.decision, true
+}
+
+// put caches the decision until its TTL elapses, evicting the least recently used
+// entry when the cache is full. Decisions without a TTL are not cached.
+func (cache *decisionCache) put(key cacheKey, decision Decision, now time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if slices.Contains(cache.superseded, decision.PolicyVersion) {
+		return
+	}
+	if decision.PolicyVersion != cache.version {
+		cache.clear()
+		cache.version = decision.PolicyVersion
+	}
+	if decision.TTL <= 0 || cache.capacity <= 0 {
+		return
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+	for cache.order.Len() >= cache.capacity {
+		cache.remove(cache.order.Back())
+	}
+
+	entry := &cacheEntry{key: key, decision: decision, expires: now.Add(decision.TTL)}
+	cache.entries[key] = cache.order.PushFront(entry)
+}
+
+// invalidate drops every entry when told the policy changed to the given version, unless the
+// notification is a duplicate: it names the version already cached, or a revision not newer
+// than the last one. A zero revision is never considered out of order.
+// It reports whether the entries were dropped.
+func (cache *decisionCache) invalidate(version string, revision uint64) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if revision != 0 {
+		if revision <= cache.revision {
+			return false
+		}
+		cache.revision = revision
+	}
+	if version != "" && version == cache.version {
+		return false
+	}
+
+	// without the new version, decisions of the cached one may still be current
+	if version != "" && cache.version != "" && !slices.Contains(cache.superseded, cache.version) {
+		cache.superseded = append(cache.superseded, cache.version)
+		if len(cache.superseded) > maxSuperseded {
+			cache.superseded = cache.superseded[1:]
+		}
+	}
+	cache.superseded = slices.DeleteFunc(cache.superseded, func(superseded string) bool { return superseded == version })
+	cache.clear()
+	cache.version = version
+	return true
+}
+
+func (cache *decisionCache) clear() {
+	cache.entries = make(map[cacheKey]*list.Element)
+	cache.order.Init()
+}
+
+// len returns the number of cached decisions, including expired ones not yet evicted.
+func (cache *decisionCache) len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *decisionCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,198 @@
+// Package client is the Go SDK services use to check authorization decisions with the authz API.
+// Decisions are cached in a bounded LRU for the lifetime suggested by the server, and the cache
+// is dropped as soon as a decision made with a newer policy version is received, or a policy
+// change is notified through the event stream, see Invalidation. A service observing the consistency
+// token of a change it made has its following checks made with a policy reflecting the change.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// userHeader carries the identity of the calling service, see api.UserHeader.
+const userHeader = "X-Forwarded-User"
+
+// consistencyHeader carries consistency tokens, see api.ConsistencyHeader.
+const consistencyHeader = "Consistency-Token"
+
+// Decision tells whether a user is granted a permission.
+type Decision struct {
+	User       string
+	Permission string
+	Allowed    bool
+	// Why the permission was denied, such as "user_unknown" or "no_matching_group".
+	// Empty when it is allowed.
+	Reason string
+	// The version of the policy the decision was made with.
+	PolicyVersion string
+	// How long the decision may be reused.
+	TTL time.Duration
+}
+
+// decisionResponse is the body returned by GET /api/v1/decisions.
+type decisionResponse struct {
+	User          string `json:"user"`
+	Permission    string `json:"permission"`
+	Allowed       bool   `json:"allowed"`
+	Reason        string `json:"reason"`
+	PolicyVersion string `json:"policy_version"`
+	TTLSeconds    int    `json:"ttl_seconds"`
+}
+
+// Client checks decisions with version 1 of the authz API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	service    string
+	cache      *decisionCache
+	clock      clock.Clock
+
+	mu    sync.Mutex
+	token string
+}
+
+// Option configures optional Client settings.
+type Option func(*Client)
+
+// WithCacheSize sets the maximum number of cached decisions. The default is 10000;
+// zero disables caching.
+func WithCacheSize(size int) Option {
+	return func(client *Client) {
+		client.cache = newDecisionCache(size)
+	}
+}
+
+// WithService sets the identity the client authenticates as. The service must be
+// granted the authz.evaluate permission.
+func WithService(name string) Option {
+	return func(client *Client) {
+		client.service = name
+	}
+}
+
+// WithClock sets the clock the cached decisions expire by. The default is the system clock.
+func WithClock(clock clock.Clock) Option {
+	return func(client *Client) {
+		client.clock = clock
+	}
+}
+
+// NewClient creates a new Client for the authz API at baseURL, such as "https://authz.internal".
+// When httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client, options ...Option) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		cache:      newDecisionCache(10000),
+		clock:      clock.System(),
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// Check returns the decision for the user and permission, from the cache when a fresh one is available.
+func (client *Client) Check(ctx context.Context, user string, permission string) (Decision, error) {
+	key := cacheKey{user: user, permission: permission}
+	if decision, ok := client.cache.get(key, client.clock.Now()); ok {
+		return decision, nil
+	}
+
+	token := client.consistencyToken()
+	decision, err := client.fetch(ctx, user, permission, token)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// a decision in flight while a token was observed may predate the change
+	if client.consistencyToken() == token {
+		client.cache.put(key, decision, client.clock.Now())
+	}
+	return decision, nil
+}
+
+// HasPermission tells whether the policy grants the user the permission. The evaluation mode of the
+// policy is ignored: a decision allowed with a reason, as made in shadow mode, is not a grant.
+func (client *Client) HasPermission(ctx context.Context, user string, permission string) (bool, error) {
+	decision, err := client.Check(ctx, user, permission)
+	if err != nil {
+		return false, err
+	}
+	return decision.Allowed && decision.Reason == "", nil
+}
+
+// ObserveToken records the consistency token returned by a change, in the Consistency-Token header of
+// the administration API or the consistency-token metadata of the gRPC Management service. The cached
+// decisions are dropped and the token is sent with the following checks, so they reflect the change.
+// An empty token is ignored.
+func (client *Client) ObserveToken(token string) {
+	if token == "" {
+		return
+	}
+	client.mu.Lock()
+	client.token = token
+	client.mu.Unlock()
+	client.cache.invalidate("", 0)
+}
+
+// consistencyToken returns the last observed consistency token, empty when none was.
+func (client *Client) consistencyToken() string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.token
+}
+
+func (client *Client) fetch(ctx context.Context, user string, permission string, token string) (Decision, error) {
+	query := url.Values{"user": {user}, "permission": {permission}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, client.baseURL+"/api/v1/decisions?"+query.Encode(), nil)
+	if err != nil {
+		return Decision{}, err
+	}
+	if client.service != "" {
+		request.Header.Set(userHeader, client.service)
+	}
+	if token != "" {
+		request.Header.Set(consistencyHeader, token)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&body)
+		return Decision{}, fmt.Errorf("check decision: %s: %s", response.Status, body.Error)
+	}
+
+	var body decisionResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return Decision{}, fmt.Errorf("decode decision: %w", err)
+	}
+
+	return Decision{
+		User:          body.User,
+		Permission:    body.Permission,
+		Allowed:       body.Allowed,
+		Reason:        body.Reason,
+		PolicyVersion: body.PolicyVersion,
+		TTL:           time.Duration(body.TTLSeconds) * time.Second,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// decisionServer answers every decision with the given policy version and TTL, counting the requests.
+func decisionServer(t *testing.T, version *atomic.Value, ttl int, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, "/api/v1/decisions", r.URL.Path)
+		assert.Equal(t, "recipes", r.Header.Get(userHeader))
+		fmt.Fprintf(w, `{"user":%q,"permission":%q,"allowed":true,"policy_version":%q,"ttl_seconds":%d}`,
+			r.URL.Query().Get("user"), r.URL.Query().Get("permission"), version.Load(), ttl)
+	}))
+}
+
+// TestClient_Check_Cached checks a decision twice, checking the second check is served from the cache until it expires.
+func TestClient_Check_Cached(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	var requests atomic.Int32
+	server := decisionServer(t, &version, 60, &requests)
+	defer server.Close()
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := NewClient(server.URL, server.Client(), WithService("recipes"), WithClock(clock))
+
+	decision, err := client.Check(context.Background(), "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.Equal(t, Decision{User: "alice", Permission: "recipes.read", Allowed: true, PolicyVersion: "v1", TTL: time.Minute}, decision)
+
+	allowed, err := client.HasPermission(context.Background(), "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int32(1), requests.Load())
+
+	clock.Advance(time.Minute)
+	_, err = client.Check(context.Background(), "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+// TestClient_Check_NoStore checks a decision without a TTL twice, checking it is never cached.
+func TestClient_Check_NoStore(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	var requests atomic.Int32
+	server := decisionServer(t, &version, 0, &requests)
+	defer server.Close()
+	client := NewClient(server.URL, server.Client(), WithService("recipes"))
+
+	for range 2 {
+		_, err := client.Check(context.Background(), "alice", "recipes.delete")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+// TestClient_Check_PolicyChanged receives a decision made with a new policy version, checking older decisions are dropped.
+func TestClient_Check_PolicyChanged(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	var requests atomic.Int32
+	server := decisionServer(t, &version, 60, &requests)
+	defer server.Close()
+	client := NewClient(server.URL, server.Client(), WithService("recipes"))
+	ctx := context.Background()
+
+	_, _ = client.Check(ctx, "alice", "recipes.read")
+	version.Store("v2")
+	_, _ = client.Check(ctx, "bob", "recipes.read")
+	assert.Equal(t, 1, client.cache.len())
+
+	decision, err := client.Check(ctx, "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", decision.PolicyVersion)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+// TestClient_Check_Denied checks a denied decision, checking the reason of the denial is returned.
+func TestClient_Check_Denied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"user":"mallory","permission":"recipes.read","allowed":false,"reason":"user_unknown","policy_version":"v1","ttl_seconds":10}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, server.Client())
+
+	decision, err := client.Check(context.Background(), "mallory", "recipes.read")
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "user_unknown", decision.Reason)
+}
+
+// TestClient_HasPermission_Mode checks a decision allowed by the evaluation mode of the policy,
+// checking HasPermission only tells whether the policy grants the permission.
+func TestClient_HasPermission_Mode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"user":"alice","permission":"recipes.purge","allowed":true,"reason":"permission_unknown","policy_version":"v1","ttl_seconds":10}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, server.Client())
+
+	decision, err := client.Check(context.Background(), "alice", "recipes.purge")
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	allowed, err := client.HasPermission(context.Background(), "alice", "recipes.purge")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+// TestClient_Check_Error checks a decision the server refuses, checking for an error.
+func TestClient_Check_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"permission denied"}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, server.Client())
+
+	_, err := client.Check(context.Background(), "alice", "recipes.read")
+	assert.EqualError(t, err, "check decision: 403 Forbidden: permission denied")
+}
+
+// TestDecisionCache_Evict fills the cache beyond its capacity, checking the least recently used decision is evicted.
+func TestDecisionCache_Evict(t *testing.T) {
+	now := time.Now()
+	cache := newDecisionCache(2)
+	decision := Decision{PolicyVersion: "v1", TTL: time.Minute}
+
+	cache.put(cacheKey{user: "alice"}, decision, now)
+	cache.put(cacheKey{user: "bob"}, decision, now)
+	_, ok := cache.get(cacheKey{user: "alice"}, now)
+	assert.True(t, ok)
+	cache.put(cacheKey{user: "carol"}, decision, now)
+
+	_, ok = cache.get(cacheKey{user: "bob"}, now)
+	assert.False(t, ok)
+	_, ok = cache.get(cacheKey{user: "alice"}, now)
+	assert.True(t, ok)
+	assert.Equal(t, 2, cache.len())
+}
+
+// TestClient_ObserveToken observes the consistency token of a change, checking the cached decision
+// is dropped and the token is sent with the following checks.
+func TestClient_ObserveToken(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get(consistencyHeader))
+		fmt.Fprintf(w, `{"user":"alice","permission":"recipes.read","allowed":true,"policy_version":"v1","ttl_seconds":60}`)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL, server.Client())
+
+	for range 2 {
+		_, err := client.Check(context.Background(), "alice", "recipes.read")
+		assert.NoError(t, err)
+	}
+	client.ObserveToken("")
+	_, err := client.Check(context.Background(), "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{""}, tokens)
+
+	client.ObserveToken("cjE6MTI")
+	for range 2 {
+		_, err := client.Check(context.Background(), "alice", "recipes.read")
+		assert.NoError(t, err)
+	}
+	client.ObserveToken("cjE6MTM")
+	_, err = client.Check(context.Background(), "alice", "bakery.read")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "cjE6MTI", "cjE6MTM"}, tokens)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Invalidation tells the client the policy changed, so cached decisions are dropped as soon as
+// the change is published rather than when their TTL elapses.
+type Invalidation struct {
+	// The version of the new policy, see Decision.PolicyVersion. Decisions of the replaced
+	// version still in flight are not cached. Empty when unknown.
+	PolicyVersion string `json:"policy_version"`
+	// The position of the change in the stream it was received from, such as the revision of a
+	// distribution backend. Notifications not newer than the last one are duplicates or arrived
+	// out of order and are ignored. Zero when the stream is unordered.
+	Revision uint64 `json:"revision"`
+}
+
+// Invalidate drops the cached decisions when the notification names a change not seen yet.
+// It reports whether the cache was dropped. It fits distribution.WithInvalidation, so a
+// distribution.Watcher drops the cache within moments of a change being published.
+func (client *Client) Invalidate(invalidation Invalidation) bool {
+	return client.cache.invalidate(invalidation.PolicyVersion, invalidation.Revision)
+}
+
+// InvalidationHandler serves the webhook notifying the client of policy changes: a POST with an
+// Invalidation as JSON body. Anyone reaching it can drop the cache, so serve it on an internal listener.
+func (client *Client) InvalidationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var invalidation Invalidation
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&invalidation); err != nil {
+			http.Error(w, "invalid invalidation", http.StatusBadRequest)
+			return
+		}
+
+		client.Invalidate(invalidation)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClient_Invalidate notifies the client of policy changes, checking the cache is dropped once per change.
+func TestClient_Invalidate(t *testing.T) {
+	var version atomic.Value
+	version.Store("v1")
+	var requests atomic.Int32
+	server := decisionServer(t, &version, 60, &requests)
+	defer server.Close()
+	client := NewClient(server.URL, server.Client(), WithService("recipes"))
+
+	_, err := client.Check(context.Background(), "alice", "recipes.read")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.cache.len())
+
+	// the cached version is current
+	assert.False(t, client.Invalidate(Invalidation{PolicyVersion: "v1", Revision: 4}))
+	assert.Equal(t, 1, client.cache.len())
+
+	assert.True(t, client.Invalidate(Invalidation{PolicyVersion: "v2", Revision: 5}))
+	assert.Equal(t, 0, client.cache.len())
+
+	// duplicate and out of order notifications are ignored
+	assert.False(t, client.Invalidate(Invalidation{PolicyVersion: "v3", Revision: 5}))
+	assert.False(t, client.Invalidate(Invalidation{PolicyVersion: "v1", Revision: 3}))
+}
+
+// TestDecisionCache_Invalidate_InFlight caches a decision of a replaced version, checking it is not cached.
+func TestDecisionCache_Invalidate_InFlight(t *testing.T) {
+	now := time.Now()
+	cache := newDecisionCache(10)
+	cache.put(cacheKey{user: "alice"}, Decision{PolicyVersion: "v1", TTL: time.Minute}, now)
+
+	assert.True(t, cache.invalidate("v2", 0))
+	cache.put(cacheKey{user: "bob"}, Decision{PolicyVersion: "v1", TTL: time.Minute}, now)
+	assert.Equal(t, 0, cache.len())
+
+	cache.put(cacheKey{user: "bob"}, Decision{PolicyVersion: "v2", TTL: time.Minute}, now)
+	assert.Equal(t, 1, cache.len())
+
+	// a change of unknown version drops the cache without superseding the cached version
+	assert.True(t, cache.invalidate("", 0))
+	cache.put(cacheKey{user: "bob"}, Decision{PolicyVersion: "v2", TTL: time.Minute}, now)
+	assert.Equal(t, 1, cache.len())
+}
+
+// TestClient_InvalidationHandler posts notifications to the webhook, checking the cache is dropped.
+func TestClient_InvalidationHandler(t *testing.T) {
+	client := NewClient("http://authz.internal", nil)
+	client.cache.put(cacheKey{user: "alice"}, Decision{PolicyVersion: "v1", TTL: time.Minute}, time.Now())
+	handler := client.InvalidationHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/authz/invalidate", strings.NewReader(`{"policy_version":"v2","revision":7}`)))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, 0, client.cache.len())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/authz/invalidate", strings.NewReader(`not json`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/authz/invalidate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -0,0 +1,124 @@
+// Package codegen generates Go source code from the authorization policy.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Constant is a permission to generate a constant for.
+type Constant struct {
+	// The permission name.
+	Name string
+	// The documentation of the permission, rendered as the constant doc comment when set.
+	Description string
+}
+
+// PermissionConstants renders a Go source file declaring a typed constant for every
+// permission name, so consuming services can reference permissions without magic strings.
+//
+// Parameters:
+//   - packageName: The name of the generated Go package.
+//   - permissions: The permission names to generate constants for. Duplicates are ignored.
+//
+// Returns:
+//   - []byte: The formatted Go source file.
+//   - error: An error if the package name is invalid, a permission name cannot be
+//     converted to an identifier, or two permissions map to the same identifier.
+func PermissionConstants(packageName string, permissions []string) ([]byte, error) {
+	constants := make([]Constant, 0, len(permissions))
+	for _, name := range permissions {
+		constants = append(constants, Constant{Name: name})
+	}
+	return DocumentedConstants(packageName, constants)
+}
+
+// DocumentedConstants renders the same Go source file as PermissionConstants, documenting
+// every constant with the permission description, such as the ones declared in a permission catalog.
+// When a permission is listed more than once, the first description is used.
+func DocumentedConstants(packageName string, permissions []Constant) ([]byte, error) {
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("invalid package name %q", packageName)
+	}
+
+	constants := slices.Clone(permissions)
+	slices.SortStableFunc(constants, func(a, b Constant) int { return strings.Compare(a.Name, b.Name) })
+	constants = slices.CompactFunc(constants, func(a, b Constant) bool { return a.Name == b.Name })
+
+	identifiers := make(map[string]string, len(constants))
+	for _, constant := range constants {
+		identifier, err := Identifier(constant.Name)
+		if err != nil {
+			return nil, err
+		}
+		if existing, ok := identifiers[identifier]; ok {
+			return nil, fmt.Errorf("permissions %q and %q both map to identifier %s", existing, constant.Name, identifier)
+		}
+		identifiers[identifier] = constant.Name
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by authz generate. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "// Package %s declares the permissions defined in the authorization policy.\n", packageName)
+	fmt.Fprintf(&buf, "package %s\n\n", packageName)
+	fmt.Fprintf(&buf, "// Permission is the name of a permission defined in the authorization policy.\n")
+	fmt.Fprintf(&buf, "type Permission string\n\n")
+	fmt.Fprintf(&buf, "// String returns the permission name.\n")
+	fmt.Fprintf(&buf, "func (p Permission) String() string {\n\treturn string(p)\n}\n\n")
+
+	if len(constants) > 0 {
+		fmt.Fprintf(&buf, "const (\n")
+		for _, constant := range constants {
+			identifier, _ := Identifier(constant.Name)
+			for _, line := range strings.Split(strings.TrimSpace(constant.Description), "\n") {
+				if line != "" {
+					fmt.Fprintf(&buf, "\t// %s\n", strings.TrimSpace(line))
+				}
+			}
+			fmt.Fprintf(&buf, "\t%s Permission = %q\n", identifier, constant.Name)
+		}
+		fmt.Fprintf(&buf, ")\n\n")
+	}
+
+	fmt.Fprintf(&buf, "// All lists every permission defined in the authorization policy.\n")
+	fmt.Fprintf(&buf, "var All = []Permission{\n")
+	for _, constant := range constants {
+		identifier, _ := Identifier(constant.Name)
+		fmt.Fprintf(&buf, "\t%s,\n", identifier)
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// Identifier converts a permission name into an exported Go identifier.
+// Separators such as dots, dashes and spaces start a new word,
+// so "recipes.read" becomes "RecipesRead".
+func Identifier(name string) (string, error) {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "", fmt.Errorf("permission %q has no usable characters", name)
+	}
+
+	var builder strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		builder.WriteRune(unicode.ToUpper(runes[0]))
+		builder.WriteString(string(runes[1:]))
+	}
+
+	// prefix identifiers that would not compile or would clash with the generated declarations
+	identifier := builder.String()
+	if !unicode.IsLetter([]rune(identifier)[0]) || identifier == "All" || identifier == "Permission" {
+		identifier = "Permission" + identifier
+	}
+
+	return identifier, nil
+}
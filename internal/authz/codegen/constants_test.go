@@ -0,0 +1,86 @@
+package codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission string
+		expected   string
+	}{
+		{name: "dotted name", permission: "recipes.read", expected: "RecipesRead"},
+		{name: "dashed name", permission: "publish-recipe", expected: "PublishRecipe"},
+		{name: "leading digit", permission: "2fa.reset", expected: "Permission2faReset"},
+		{name: "reserved name", permission: "all", expected: "PermissionAll"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identifier, err := Identifier(tt.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, identifier)
+		})
+	}
+}
+
+// TestIdentifier_Error_NoUsableCharacters calls Identifier with a name made of separators only, checking for an error.
+func TestIdentifier_Error_NoUsableCharacters(t *testing.T) {
+	identifier, err := Identifier("..")
+	assert.Error(t, err)
+	assert.Empty(t, identifier)
+}
+
+// TestPermissionConstants calls PermissionConstants with a list of permissions, checking for valid sorted Go source.
+func TestPermissionConstants(t *testing.T) {
+	source, err := PermissionConstants("permissions", []string{"recipes.write", "recipes.read", "recipes.read"})
+	assert.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "permissions.go", source, parser.AllErrors)
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), "package permissions")
+	assert.Contains(t, string(source), `RecipesRead  Permission = "recipes.read"`)
+	assert.Contains(t, string(source), `RecipesWrite Permission = "recipes.write"`)
+	assert.Less(t, strings.Index(string(source), "RecipesRead"), strings.Index(string(source), "RecipesWrite"))
+}
+
+// TestDocumentedConstants calls DocumentedConstants with described permissions, checking the descriptions become doc comments.
+func TestDocumentedConstants(t *testing.T) {
+	source, err := DocumentedConstants("permissions", []Constant{
+		{Name: "recipes.write", Description: "Create and edit recipes.\nIncludes drafts."},
+		{Name: "recipes.read"},
+	})
+	assert.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "permissions.go", source, parser.AllErrors)
+	assert.NoError(t, err)
+	assert.Contains(t, string(source), "\t// Create and edit recipes.\n\t// Includes drafts.\n\tRecipesWrite Permission = \"recipes.write\"")
+	assert.NotContains(t, string(source), "//\n\tRecipesRead")
+}
+
+// TestPermissionConstants_Empty calls PermissionConstants without permissions, checking for valid Go source.
+func TestPermissionConstants_Empty(t *testing.T) {
+	source, err := PermissionConstants("permissions", nil)
+	assert.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "permissions.go", source, parser.AllErrors)
+	assert.NoError(t, err)
+}
+
+// TestPermissionConstants_Error_InvalidPackage calls PermissionConstants with an invalid package name, checking for an error.
+func TestPermissionConstants_Error_InvalidPackage(t *testing.T) {
+	_, err := PermissionConstants("my-package", []string{"read"})
+	assert.Error(t, err)
+}
+
+// TestPermissionConstants_Error_Collision calls PermissionConstants with names mapping to the same identifier, checking for an error.
+func TestPermissionConstants_Error_Collision(t *testing.T) {
+	_, err := PermissionConstants("permissions", []string{"recipes.read", "recipes-read"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package authz
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+)
+
+// CompiledPolicy is a validated policy whose evaluations are computed once, up front.
+// It cannot be changed, so it is safe for concurrent use.
+type CompiledPolicy struct {
+	policy  *Policy
+	results map[string]*PolicyEvaluationResult
+	// Whether a permission has a condition, so evaluations with attributes cannot use the results.
+	conditional bool
+}
+
+// Compile validates the policy, grants the permissions of its folders like ReadPolicy does and
+// evaluates every user it names. The policy is copied, so changing it afterwards does not change
+// the compiled policy.
+//
+// Parameters:
+//
+//	policy - the policy to compile.
+//
+// Returns:
+//
+//	*CompiledPolicy - the compiled policy.
+//	error - an error if the policy is invalid, see Validate.
+func Compile(policy *Policy) (*CompiledPolicy, error) {
+	policy = clonePolicy(policy)
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	policy.ResolveFolders()
+
+	compiled := &CompiledPolicy{policy: policy, results: map[string]*PolicyEvaluationResult{}}
+	compiled.conditional = slices.ContainsFunc(policy.Permissions, func(permission Permission) bool { return permission.Condition != "" })
+	for _, group := range policy.Groups {
+		for _, user := range group.Users {
+			if _, ok := compiled.results[user]; ok {
+				continue
+			}
+			result, err := policy.Evaluate(user)
+			if err != nil {
+				return nil, err
+			}
+			compiled.results[user] = result
+		}
+	}
+	return compiled, nil
+}
+
+// Policy returns a copy of the compiled policy.
+func (compiled *CompiledPolicy) Policy() *Policy {
+	return clonePolicy(compiled.policy)
+}
+
+// Evaluate returns the groups and permissions of the user, which are empty for unknown users.
+// Conditions are evaluated without request attributes, like Policy.Evaluate.
+func (compiled *CompiledPolicy) Evaluate(user string) (*PolicyEvaluationResult, error) {
+	if user == "" {
+		return nil, errors.New("user is empty")
+	}
+
+	result, ok := compiled.results[user]
+	if !ok {
+		return NewPolicyEvaluationResult([]string{}, []string{}), nil
+	}
+	clone := NewPolicyEvaluationResult(slices.Clone(result.Groups), slices.Clone(result.Permissions))
+	clone.Denied = slices.Clone(result.Denied)
+	return clone, nil
+}
+
+// EvaluateWith returns the groups and permissions of the user for a request with the given attributes,
+// see Policy.EvaluateWith. Policies without conditions answer from the evaluations computed up front.
+func (compiled *CompiledPolicy) EvaluateWith(user string, attributes condition.Attributes) (*PolicyEvaluationResult, error) {
+	if !compiled.conditional {
+		return compiled.Evaluate(user)
+	}
+	return compiled.policy.EvaluateWith(user, attributes)
+}
+
+// HasPermission reports whether the user holds the permission, directly, through an implication or a wildcard,
+// and is not explicitly denied it. The evaluation mode is ignored, like Policy.HasPermission.
+func (compiled *CompiledPolicy) HasPermission(user string, permission string) bool {
+	result, ok := compiled.results[user]
+	if !ok {
+		return false
+	}
+	return compiled.policy.CheckEvaluated(user, permission, result).Reason == ""
+}
+
+// IsInGroup reports whether the user is a member of the group.
+func (compiled *CompiledPolicy) IsInGroup(user string, group string) bool {
+	result, ok := compiled.results[user]
+	return ok && slices.Contains(result.Groups, group)
+}
+
+func clonePolicy(policy *Policy) *Policy {
+	clone := &Policy{Permissions: make([]Permission, len(policy.Permissions)), Groups: make([]Group, len(policy.Groups)), Mode: policy.Mode}
+	for i, permission := range policy.Permissions {
+		permission.Groups = slices.Clone(permission.Groups)
+		permission.Implies = slices.Clone(permission.Implies)
+		clone.Permissions[i] = permission
+	}
+	for i, group := range policy.Groups {
+		group.Users = slices.Clone(group.Users)
+		clone.Groups[i] = group
+	}
+	for _, folder := range policy.Folders {
+		folder.Groups = slices.Clone(folder.Groups)
+		folder.Grants = slices.Clone(folder.Grants)
+		clone.Folders = append(clone.Folders, folder)
+	}
+	for _, denial := range policy.Denials {
+		denial.Groups = slices.Clone(denial.Groups)
+		denial.Users = slices.Clone(denial.Users)
+		clone.Denials = append(clone.Denials, denial)
+	}
+	return clone
+}
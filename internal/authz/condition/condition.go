@@ -0,0 +1,202 @@
+// Package condition implements the small expression language of the permission conditions, which
+// make a grant depend on the attributes of the request, such as the owner of the resource, the time
+// of day or the tenant:
+//
+//	resource.owner == user && time.hour >= 8 && time.hour < 18
+//	tenant in ["acme", "globex"] || !resource.private
+//
+// Expressions combine comparisons (==, !=, <, <=, >, >=), membership in a list (in) and the boolean
+// operators !, && and ||, with parentheses. Operands are attributes, named by identifiers that may
+// contain dots, and string, number, boolean and list literals. Strings compare with strings and
+// numbers with numbers; == and != compare values of any type.
+//
+// Expressions are compiled once and evaluated many times: Compile parses the source, and a Cache
+// keeps the compiled expressions by source so policies do not parse their conditions on every check.
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMissingAttribute is returned when an expression names an attribute the request does not have.
+var ErrMissingAttribute = errors.New("missing attribute")
+
+// Attributes are the attributes of a request by name, such as "resource.owner". Values are strings,
+// booleans, numbers of any Go numeric type, or slices of those. Nested maps are also looked up,
+// so "resource.owner" is found in Attributes{"resource": map[string]any{"owner": "alice"}}.
+type Attributes map[string]any
+
+// lookup returns the attribute with the given name, looking into nested maps when it is not set as is.
+func (attributes Attributes) lookup(name string) (any, bool) {
+	if value, ok := attributes[name]; ok {
+		return value, true
+	}
+
+	var current any = map[string]any(attributes)
+	for _, segment := range strings.Split(name, ".") {
+		nested, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = nested[segment]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// TimeAttributes returns the attributes of the given time conditions usually compare: time.hour from 0
+// to 23, time.weekday from "monday" to "sunday", and time.date formatted as "2006-01-02".
+func TimeAttributes(t time.Time) Attributes {
+	return Attributes{
+		"time.hour":    t.Hour(),
+		"time.weekday": strings.ToLower(t.Weekday().String()),
+		"time.date":    t.Format(time.DateOnly),
+	}
+}
+
+// Expression is a compiled condition. It is safe for concurrent use.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the source of a condition.
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", source, err)
+	}
+
+	parser := &parser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err == nil && parser.peek().kind != tokenEnd {
+		err = fmt.Errorf("unexpected %s at offset %d", parser.peek(), parser.peek().offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (expression *Expression) String() string {
+	return expression.source
+}
+
+// Evaluate reports whether the condition holds for the attributes. An error is returned when the
+// condition names a missing attribute, see ErrMissingAttribute, or compares values of different types.
+func (expression *Expression) Evaluate(attributes Attributes) (bool, error) {
+	value, err := expression.root.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q is not a boolean", expression.source)
+	}
+	return result, nil
+}
+
+// Cache keeps the compiled expressions by source, including the ones that failed to compile.
+// It is safe for concurrent use.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	expression *Expression
+	err        error
+}
+
+// NewCache creates a new empty Cache.
+func NewCache() *Cache {
+	return &Cache{entries: map[string]cacheEntry{}}
+}
+
+// Compile returns the compiled expression of the source, compiling it on first use.
+func (cache *Cache) Compile(source string) (*Expression, error) {
+	cache.mu.RLock()
+	entry, ok := cache.entries[source]
+	cache.mu.RUnlock()
+	if ok {
+		return entry.expression, entry.err
+	}
+
+	expression, err := Compile(source)
+	cache.mu.Lock()
+	cache.entries[source] = cacheEntry{expression: expression, err: err}
+	cache.mu.Unlock()
+	return expression, err
+}
+
+// Len returns the number of sources cached.
+func (cache *Cache) Len() int {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return len(cache.entries)
+}
+
+// normalize converts the numbers to float64 and the slices to []any, so values compare regardless of their Go type.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int8:
+		return float64(value)
+	case int16:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint:
+		return float64(value)
+	case uint8:
+		return float64(value)
+	case uint16:
+		return float64(value)
+	case uint32:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	case []string:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = item
+		}
+		return items
+	case []int:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = float64(item)
+		}
+		return items
+	case []any:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = normalize(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// equal reports whether two normalized values are equal, comparing lists item by item.
+func equal(a any, b any) bool {
+	aItems, aList := a.([]any)
+	bItems, bList := b.([]any)
+	if aList || bList {
+		return aList && bList && slices.EqualFunc(aItems, bItems, equal)
+	}
+	return a == b
+}
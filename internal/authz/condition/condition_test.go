@@ -0,0 +1,133 @@
+package condition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression_Evaluate(t *testing.T) {
+	attributes := Attributes{
+		"user":      "alice",
+		"tenant":    "acme",
+		"time.hour": 9,
+		"resource":  map[string]any{"owner": "alice", "private": true, "tags": []string{"dessert", "vegan"}},
+		"size":      int64(12),
+		"ratio":     0.5,
+	}
+
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{"true", true},
+		{"resource.owner == user", true},
+		{"resource.owner != user", false},
+		{`tenant == "acme" && time.hour >= 8 && time.hour < 18`, true},
+		{"time.hour > 9 || tenant == 'globex'", false},
+		{`tenant in ["acme", "globex"]`, true},
+		{`"vegan" in resource.tags`, true},
+		{"!resource.private", false},
+		{"!(resource.private && size <= 10)", true},
+		{"size == 12 && ratio < 1", true},
+		{"user < 'bob'", true},
+		{"resource.tags == ['dessert', 'vegan']", true},
+		{"false && missing == 1", false},
+		{"true || missing == 1", true},
+		{"-1 < ratio", true},
+	}
+
+	for _, test := range tests {
+		expression, err := Compile(test.source)
+		if !assert.NoError(t, err, test.source) {
+			continue
+		}
+		result, err := expression.Evaluate(attributes)
+		assert.NoError(t, err, test.source)
+		assert.Equal(t, test.expected, result, test.source)
+	}
+}
+
+func TestExpression_Evaluate_Errors(t *testing.T) {
+	attributes := Attributes{"user": "alice", "time.hour": 9}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"resource.owner == user", `missing attribute "resource.owner"`},
+		{"user < 3", "cannot compare a string with a number"},
+		{"user && true", "operand of && is a string, not a boolean"},
+		{"user in 'alice'", "right operand of in is a string, not a list"},
+		{"true < false", "cannot order a boolean"},
+		{"user", `condition "user" is not a boolean`},
+	}
+
+	for _, test := range tests {
+		expression, err := Compile(test.source)
+		if !assert.NoError(t, err, test.source) {
+			continue
+		}
+		_, err = expression.Evaluate(attributes)
+		assert.EqualError(t, err, test.expected, test.source)
+	}
+
+	_, err := mustCompile(t, "missing").Evaluate(attributes)
+	assert.ErrorIs(t, err, ErrMissingAttribute)
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"", `condition "": unexpected end of condition at offset 0`},
+		{"user ==", `condition "user ==": unexpected end of condition at offset 7`},
+		{"user == 'alice", `condition "user == 'alice": unterminated string at offset 8`},
+		{"(user == 'alice'", `condition "(user == 'alice'": expected ")", found end of condition at offset 16`},
+		{"user = 'alice'", `condition "user = 'alice'": unexpected character '=' at offset 5`},
+		{"user == 'alice' tenant", `condition "user == 'alice' tenant": unexpected "tenant" at offset 16`},
+		{"tenant in [1 2]", `condition "tenant in [1 2]": expected ",", found "2" at offset 13`},
+		{"size > 1.2.3", `condition "size > 1.2.3": invalid number "1.2.3" at offset 7`},
+	}
+
+	for _, test := range tests {
+		_, err := Compile(test.source)
+		assert.EqualError(t, err, test.expected, test.source)
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+
+	first, err := cache.Compile("user == 'alice'")
+	assert.NoError(t, err)
+	second, err := cache.Compile("user == 'alice'")
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+
+	_, err = cache.Compile("user ==")
+	assert.Error(t, err)
+	_, err = cache.Compile("user ==")
+	assert.Error(t, err, "failures are cached too")
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestTimeAttributes(t *testing.T) {
+	attributes := TimeAttributes(time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC))
+
+	result, err := mustCompile(t, "time.hour >= 8 && time.hour < 18 && time.weekday == 'friday' && time.date == '2026-10-16'").Evaluate(attributes)
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+// mustCompile compiles the source, failing the test when it does not compile.
+func mustCompile(t *testing.T, source string) *Expression {
+	t.Helper()
+	expression, err := Compile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expression
+}
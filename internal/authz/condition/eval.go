@@ -0,0 +1,183 @@
+package condition
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// node is a node of the syntax tree of an expression, evaluated to a normalized value.
+type node interface {
+	eval(attributes Attributes) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (literal literalNode) eval(Attributes) (any, error) {
+	return literal.value, nil
+}
+
+type attributeNode struct {
+	name string
+}
+
+func (attribute attributeNode) eval(attributes Attributes) (any, error) {
+	value, ok := attributes.lookup(attribute.name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrMissingAttribute, attribute.name)
+	}
+	return normalize(value), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (list listNode) eval(attributes Attributes) (any, error) {
+	values := make([]any, len(list.items))
+	for i, item := range list.items {
+		value, err := item.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (not notNode) eval(attributes Attributes) (any, error) {
+	value, err := evalBool(not.operand, attributes, "!")
+	return !value, err
+}
+
+// andNode and orNode short-circuit, so the right operand may name attributes only set when the left one allows.
+type andNode struct {
+	left, right node
+}
+
+func (and andNode) eval(attributes Attributes) (any, error) {
+	left, err := evalBool(and.left, attributes, "&&")
+	if err != nil || !left {
+		return false, err
+	}
+	return evalBool(and.right, attributes, "&&")
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (or orNode) eval(attributes Attributes) (any, error) {
+	left, err := evalBool(or.left, attributes, "||")
+	if err != nil || left {
+		return left, err
+	}
+	return evalBool(or.right, attributes, "||")
+}
+
+func evalBool(operand node, attributes Attributes, operator string) (bool, error) {
+	value, err := operand.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("operand of %s is %s, not a boolean", operator, describe(value))
+	}
+	return result, nil
+}
+
+type inNode struct {
+	item, list node
+}
+
+func (in inNode) eval(attributes Attributes) (any, error) {
+	item, err := in.item.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	list, err := in.list.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := list.([]any)
+	if !ok {
+		return nil, fmt.Errorf("right operand of in is %s, not a list", describe(list))
+	}
+	return slices.ContainsFunc(items, func(candidate any) bool { return equal(candidate, item) }), nil
+}
+
+type compareNode struct {
+	operator    string
+	left, right node
+}
+
+func (compare compareNode) eval(attributes Attributes) (any, error) {
+	left, err := compare.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	right, err := compare.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch compare.operator {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	var order int
+	switch leftValue := left.(type) {
+	case float64:
+		rightValue, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s with %s", describe(left), describe(right))
+		}
+		order = cmp.Compare(leftValue, rightValue)
+	case string:
+		rightValue, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s with %s", describe(left), describe(right))
+		}
+		order = strings.Compare(leftValue, rightValue)
+	default:
+		return nil, fmt.Errorf("cannot order %s", describe(left))
+	}
+
+	switch compare.operator {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+// describe returns the type of a normalized value for error messages.
+func describe(value any) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "a list"
+	default:
+		return fmt.Sprintf("a %T", value)
+	}
+}
@@ -0,0 +1,235 @@
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (token token) String() string {
+	if token.kind == tokenEnd {
+		return "end of condition"
+	}
+	return strconv.Quote(token.text)
+}
+
+// operators are the operator tokens, the two character ones first so they are matched before their prefix.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+// comparisons are the comparison operators.
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// tokenize splits the source into tokens, ending with a tokenEnd token.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for offset := 0; offset < len(source); {
+		char := rune(source[offset])
+		switch {
+		case unicode.IsSpace(char):
+			offset++
+		case char == '"' || char == '\'':
+			end := offset + 1
+			for end < len(source) && source[end] != source[offset] {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at offset %d", offset)
+			}
+			text, err := unquote(source[offset : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", offset, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, offset: offset})
+			offset = end + 1
+		case unicode.IsDigit(char) || (char == '-' && offset+1 < len(source) && unicode.IsDigit(rune(source[offset+1]))):
+			end := offset + 1
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[offset:end], offset: offset})
+			offset = end
+		case unicode.IsLetter(char) || char == '_':
+			end := offset + 1
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_' || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: source[offset:end], offset: offset})
+			offset = end
+		default:
+			matched := false
+			for _, operator := range operators {
+				if strings.HasPrefix(source[offset:], operator) {
+					tokens = append(tokens, token{kind: tokenOperator, text: operator, offset: offset})
+					offset += len(operator)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", char, offset)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, offset: len(source)}), nil
+}
+
+// unquote returns the text of a string literal quoted with double or single quotes.
+func unquote(literal string) (string, error) {
+	if literal[0] == '\'' {
+		literal = `"` + strings.ReplaceAll(strings.ReplaceAll(literal[1:len(literal)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(literal)
+}
+
+// parser is a recursive descent parser of the tokens of an expression, by increasing precedence:
+// ||, &&, !, then the comparisons and the operands.
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (parser *parser) peek() token {
+	return parser.tokens[parser.position]
+}
+
+func (parser *parser) next() token {
+	token := parser.tokens[parser.position]
+	if token.kind != tokenEnd {
+		parser.position++
+	}
+	return token
+}
+
+// accept consumes the next token when it is the given operator.
+func (parser *parser) accept(operator string) bool {
+	if token := parser.peek(); token.kind == tokenOperator && token.text == operator {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *parser) expect(operator string) error {
+	if !parser.accept(operator) {
+		return fmt.Errorf("expected %q, found %s at offset %d", operator, parser.peek(), parser.peek().offset)
+	}
+	return nil
+}
+
+func (parser *parser) parseOr() (node, error) {
+	left, err := parser.parseAnd()
+	for err == nil && parser.accept("||") {
+		var right node
+		if right, err = parser.parseAnd(); err == nil {
+			left = orNode{left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (parser *parser) parseAnd() (node, error) {
+	left, err := parser.parseNot()
+	for err == nil && parser.accept("&&") {
+		var right node
+		if right, err = parser.parseNot(); err == nil {
+			left = andNode{left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (parser *parser) parseNot() (node, error) {
+	if parser.accept("!") {
+		operand, err := parser.parseNot()
+		return notNode{operand: operand}, err
+	}
+	return parser.parseComparison()
+}
+
+func (parser *parser) parseComparison() (node, error) {
+	left, err := parser.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	token := parser.peek()
+	switch {
+	case token.kind == tokenIdentifier && token.text == "in":
+		parser.next()
+		right, err := parser.parseOperand()
+		return inNode{item: left, list: right}, err
+	case token.kind == tokenOperator && comparisons[token.text]:
+		parser.next()
+		right, err := parser.parseOperand()
+		return compareNode{operator: token.text, left: left, right: right}, err
+	default:
+		return left, nil
+	}
+}
+
+func (parser *parser) parseOperand() (node, error) {
+	token := parser.next()
+	switch token.kind {
+	case tokenString:
+		return literalNode{value: token.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at offset %d", token, token.offset)
+		}
+		return literalNode{value: number}, nil
+	case tokenIdentifier:
+		switch token.text {
+		case "true", "false":
+			return literalNode{value: token.text == "true"}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %s at offset %d", token, token.offset)
+		}
+		return attributeNode{name: token.text}, nil
+	case tokenOperator:
+		switch token.text {
+		case "(":
+			inner, err := parser.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, parser.expect(")")
+		case "[":
+			list := listNode{}
+			for !parser.accept("]") {
+				if len(list.items) > 0 {
+					if err := parser.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := parser.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", token, token.offset)
+}
@@ -0,0 +1,84 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/stretchr/testify/assert"
+)
+
+// conditionPolicy grants recipes.edit to the cooks on the recipes they own, recipes.read to the cooks
+// of the acme tenant, implying recipes.list, and recipes.publish unconditionally.
+func conditionPolicy() *Policy {
+	return NewPolicy(
+		[]Permission{
+			{Name: "recipes.edit", Groups: []string{"cooks"}, Condition: "resource.owner == user"},
+			{Name: "recipes.read", Groups: []string{"cooks"}, Condition: "tenant == 'acme'", Implies: []string{"recipes.list"}},
+			{Name: "recipes.list", Groups: []string{}},
+			{Name: "recipes.publish", Groups: []string{"cooks"}},
+		},
+		[]Group{*NewGroup("cooks", []string{"alice"})},
+	)
+}
+
+// TestPolicy_EvaluateWith evaluates a user with request attributes, checking conditional permissions are granted when they hold.
+func TestPolicy_EvaluateWith(t *testing.T) {
+	policy := conditionPolicy()
+
+	result, err := policy.EvaluateWith("alice", condition.Attributes{"resource.owner": "alice", "tenant": "acme"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.edit", "recipes.read", "recipes.list", "recipes.publish"}, result.Permissions)
+
+	result, err = policy.EvaluateWith("alice", condition.Attributes{"resource.owner": "bob", "tenant": "acme"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.read", "recipes.list", "recipes.publish"}, result.Permissions)
+
+	// without attributes the conditions naming them do not hold
+	result, err = policy.Evaluate("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recipes.publish"}, result.Permissions)
+}
+
+// TestPolicy_CheckWith checks conditional permissions, checking a condition not met is reported.
+func TestPolicy_CheckWith(t *testing.T) {
+	policy := conditionPolicy()
+	attributes := condition.Attributes{"resource": map[string]any{"owner": "bob"}, "tenant": "acme"}
+
+	result, err := policy.CheckWith("alice", "recipes.edit", attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, &CheckResult{User: "alice", Permission: "recipes.edit", Reason: DenialConditionNotMet, Mode: ModeDefaultDeny}, result)
+
+	result, err = policy.CheckWith("alice", "recipes.list", attributes)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = policy.Check("alice", "recipes.list")
+	assert.NoError(t, err)
+	assert.Equal(t, DenialConditionNotMet, result.Reason, "the implying permission is conditional")
+
+	allowed, err := policy.HasPermission("alice", "recipes.edit")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = policy.HasPermission("alice", "recipes.publish")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+// TestCompile_Conditions compiles policies with conditions, checking evaluations with attributes and invalid conditions.
+func TestCompile_Conditions(t *testing.T) {
+	compiled, err := Compile(conditionPolicy())
+	assert.NoError(t, err)
+	assert.False(t, compiled.HasPermission("alice", "recipes.edit"))
+
+	result, err := compiled.EvaluateWith("alice", condition.Attributes{"resource.owner": "alice"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.edit", "recipes.publish"}, result.Permissions)
+
+	invalid := conditionPolicy()
+	invalid.Permissions[0].Condition = "resource.owner =="
+	_, err = Compile(invalid)
+	assert.ErrorContains(t, err, `permission "recipes.edit": condition "resource.owner =="`)
+
+	_, err = invalid.Evaluate("alice")
+	assert.ErrorContains(t, err, "evaluate permissions")
+}
@@ -0,0 +1,95 @@
+// Package consistency issues consistency tokens, also known as zookies, for read-after-write checks.
+// A change returns the token of the policy revision it was made at, and a check passing that token
+// is made with the policy at that revision or a later one. A caller that just granted access is thus
+// never denied by a cached policy that has not seen the grant yet.
+package consistency
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+var (
+	// ErrInvalidToken is returned for a token that was not issued by Encode.
+	ErrInvalidToken = errors.New("invalid consistency token")
+	// ErrNotYetAvailable is returned when the store has not reached the revision of a token yet,
+	// such as a token issued by another deployment. The read may be retried.
+	ErrNotYetAvailable = errors.New("policy revision of the consistency token is not available yet")
+)
+
+// tokenPrefix versions the format of the tokens, which callers must treat as opaque.
+const tokenPrefix = "r1:"
+
+// Encode returns the token of the policy revision.
+func Encode(revision int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + strconv.FormatInt(revision, 10)))
+}
+
+// Decode returns the policy revision of the token.
+func Decode(token string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	text, ok := strings.CutPrefix(string(decoded), tokenPrefix)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	revision, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, ErrInvalidToken
+	}
+	return revision, nil
+}
+
+// Reader reads the policy at least as recent as a token.
+type Reader interface {
+	// PolicyRevision returns the current revision of the policy, whose token a change just made returns.
+	PolicyRevision(ctx context.Context) (int64, error)
+	// ReadPolicyAt returns the policy at the revision or a later one, or ErrNotYetAvailable when the
+	// store has not reached the revision.
+	ReadPolicyAt(ctx context.Context, revision int64) (*authz.Policy, error)
+}
+
+// Source is the policy store read by Direct.
+type Source interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+	// PolicyRevision returns a number increasing whenever the policy changes.
+	PolicyRevision(ctx context.Context) (int64, error)
+}
+
+// Direct is a Reader for a store whose reads are not cached, which always reflect the last change.
+// See cache.CachedPolicyProvider for the Reader of a cached policy.
+type Direct struct {
+	source Source
+}
+
+var _ Reader = (*Direct)(nil)
+
+// NewDirect creates a new Direct reading the policy from the source.
+func NewDirect(source Source) *Direct {
+	return &Direct{source: source}
+}
+
+// PolicyRevision returns the current revision of the source.
+func (direct *Direct) PolicyRevision(ctx context.Context) (int64, error) {
+	return direct.source.PolicyRevision(ctx)
+}
+
+// ReadPolicyAt reads the policy of the source. The revision is read first, so the policy read
+// after it is at that revision or a later one.
+func (direct *Direct) ReadPolicyAt(ctx context.Context, revision int64) (*authz.Policy, error) {
+	current, err := direct.source.PolicyRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current < revision {
+		return nil, ErrNotYetAvailable
+	}
+	return direct.source.ReadPolicy(ctx)
+}
@@ -0,0 +1,63 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	for _, revision := range []int64{0, 1, 42, 1 << 40} {
+		decoded, err := Decode(Encode(revision))
+		assert.NoError(t, err)
+		assert.Equal(t, revision, decoded)
+	}
+
+	for _, token := range []string{"", "42", "not base64!", Encode(42)[1:], "cjE6LTE", "cjI6NDI"} {
+		_, err := Decode(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+// fakeSource is a Source at a fixed revision.
+type fakeSource struct {
+	revision int64
+	err      error
+}
+
+func (source *fakeSource) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return authz.NewPolicy(nil, []authz.Group{*authz.NewGroup("cooks", []string{"alice"})}), nil
+}
+
+func (source *fakeSource) PolicyRevision(ctx context.Context) (int64, error) {
+	return source.revision, source.err
+}
+
+func TestDirect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reached revision", func(t *testing.T) {
+		direct := NewDirect(&fakeSource{revision: 7})
+
+		revision, err := direct.PolicyRevision(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), revision)
+
+		policy, err := direct.ReadPolicyAt(ctx, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, "cooks", policy.Groups[0].Name)
+	})
+
+	t.Run("revision not reached", func(t *testing.T) {
+		_, err := NewDirect(&fakeSource{revision: 7}).ReadPolicyAt(ctx, 8)
+		assert.ErrorIs(t, err, ErrNotYetAvailable)
+	})
+
+	t.Run("source error", func(t *testing.T) {
+		_, err := NewDirect(&fakeSource{err: errors.New("db error")}).ReadPolicyAt(ctx, 1)
+		assert.EqualError(t, err, "db error")
+	})
+}
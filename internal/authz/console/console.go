@@ -0,0 +1,23 @@
+// Package console serves the embedded administration web console.
+package console
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler returns an http.Handler serving the console assets.
+// The console talks to the administration API mounted under /api.
+func Handler() http.Handler {
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		// the embedded directory is part of the binary, so this can only fail on a programming error
+		panic(err)
+	}
+
+	return http.FileServerFS(assets)
+}
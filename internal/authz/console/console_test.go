@@ -0,0 +1,28 @@
+package console
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHandler_ServesIndex requests the console root, checking that the index page is served with the audit history.
+func TestHandler_ServesIndex(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "<title>authz console</title>")
+	assert.Contains(t, recorder.Body.String(), `<table id="audit">`)
+}
+
+// TestHandler_ServesScript requests the console script, checking that it is served.
+func TestHandler_ServesScript(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/console.js", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "javascript")
+}
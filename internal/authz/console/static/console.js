@@ -0,0 +1,146 @@
+"use strict";
+
+const statusElement = document.getElementById("status");
+
+function showStatus(message, isError) {
+    statusElement.textContent = message;
+    statusElement.className = isError ? "error" : "";
+}
+
+async function failure(response) {
+    const body = await response.json().catch(() => ({ error: response.statusText }));
+    return new Error(body.error);
+}
+
+async function request(method, path, body) {
+    const response = await fetch(path, {
+        method: method,
+        headers: body === undefined ? {} : { "Content-Type": "application/json" },
+        body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (!response.ok) {
+        throw await failure(response);
+    }
+    return response.status === 204 ? null : response.json();
+}
+
+// requestPage fetches a page of a list, with the path of the next page taken from the Link header, if any.
+async function requestPage(path) {
+    const response = await fetch(path);
+    if (!response.ok) {
+        throw await failure(response);
+    }
+    const next = (response.headers.get("Link") || "").match(/<([^>]+)>;\s*rel="next"/);
+    return { items: await response.json(), next: next ? next[1] : null };
+}
+
+function cell(row, content) {
+    const td = row.insertCell();
+    if (content instanceof Node) {
+        td.appendChild(content);
+    } else {
+        td.textContent = content;
+    }
+    return td;
+}
+
+function render(policy, groups, permissions) {
+    const usersByGroup = new Map(policy.groups.map((group) => [group.name, group.users]));
+    const groupsByPermission = new Map(policy.permissions.map((permission) => [permission.name, permission.groups]));
+
+    const groupRows = document.querySelector("#groups tbody");
+    groupRows.replaceChildren();
+    for (const group of groups) {
+        const row = groupRows.insertRow();
+        cell(row, group.name);
+
+        const users = document.createElement("textarea");
+        users.value = (usersByGroup.get(group.name) || []).join("\n");
+        cell(row, users);
+
+        const grants = document.createElement("div");
+        for (const permission of permissions) {
+            const label = document.createElement("label");
+            const checkbox = document.createElement("input");
+            checkbox.type = "checkbox";
+            checkbox.value = permission.id;
+            checkbox.checked = (groupsByPermission.get(permission.name) || []).includes(group.name);
+            label.append(checkbox, " " + permission.name);
+            grants.append(label, document.createElement("br"));
+        }
+        cell(row, grants);
+
+        const save = document.createElement("button");
+        save.textContent = "Save";
+        save.addEventListener("click", () => saveGroup(group, users, grants));
+        cell(row, save);
+    }
+
+    const permissionRows = document.querySelector("#permissions tbody");
+    permissionRows.replaceChildren();
+    for (const permission of permissions) {
+        const row = permissionRows.insertRow();
+        cell(row, permission.name);
+        cell(row, (groupsByPermission.get(permission.name) || []).join(", "));
+    }
+}
+
+async function saveGroup(group, users, grants) {
+    const members = users.value.split("\n").map((user) => user.trim()).filter((user) => user !== "");
+    const granted = Array.from(grants.querySelectorAll("input:checked")).map((input) => Number(input.value));
+    try {
+        await request("PUT", `/api/groups/${group.id}/users`, { users: members });
+        await request("PUT", `/api/groups/${group.id}/permissions`, { permissions: granted });
+        showStatus(`Saved group ${group.name}`, false);
+        await load();
+    } catch (error) {
+        showStatus(`Failed to save group ${group.name}: ${error.message}`, true);
+    }
+}
+
+async function load() {
+    try {
+        const [policy, groups, permissions] = await Promise.all([
+            request("GET", "/api/policy"),
+            request("GET", "/api/groups"),
+            request("GET", "/api/permissions"),
+        ]);
+        render(policy, groups, permissions);
+    } catch (error) {
+        showStatus(`Failed to load the policy: ${error.message}`, true);
+    }
+}
+
+const auditStatus = document.getElementById("audit-status");
+const olderEvents = document.getElementById("older-events");
+let nextEvents = null;
+
+async function loadAudit(path) {
+    const rows = document.querySelector("#audit tbody");
+    if (path === undefined) {
+        path = "/api/audit/events?limit=50";
+        rows.replaceChildren();
+    }
+    try {
+        const page = await requestPage(path);
+        for (const event of page.items) {
+            const row = rows.insertRow();
+            cell(row, new Date(event.time).toLocaleString());
+            cell(row, event.actor);
+            cell(row, event.action);
+            cell(row, event.subject || "");
+            cell(row, event.details ? JSON.stringify(event.details) : "");
+        }
+        nextEvents = page.next;
+        auditStatus.textContent = rows.rows.length === 0 ? "No audit events recorded." : "";
+    } catch (error) {
+        nextEvents = null;
+        auditStatus.textContent = `Audit history unavailable: ${error.message}`;
+    }
+    olderEvents.hidden = nextEvents === null;
+}
+
+olderEvents.addEventListener("click", () => loadAudit(nextEvents));
+
+load();
+loadAudit();
@@ -0,0 +1,145 @@
+package decisionlog
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// aggregateFlushInterval is how often the Aggregator looks for the minutes that ended.
+const aggregateFlushInterval = 10 * time.Second
+
+// aggregateKey identifies the decisions counted together: the same decision made in the same minute.
+type aggregateKey struct {
+	caller        string
+	user          string
+	permission    string
+	allowed       bool
+	reason        authz.DenialReason
+	mode          authz.EvaluationMode
+	policyVersion string
+	minute        time.Time
+}
+
+// Aggregator counts the identical decisions made in the same minute and records a single decision
+// with their count once the minute ended, so a service checking the same permission of the same user
+// over and over stores one record per minute. It is meant to sit in front of a Batcher, and its
+// goroutine started with Run records the counted decisions.
+type Aggregator struct {
+	next    Recorder
+	logger  *slog.Logger
+	clock   clock.Clock
+	maxKeys int
+	mu      sync.Mutex
+	counts  map[aggregateKey]*Decision
+}
+
+var _ Recorder = (*Aggregator)(nil)
+
+// AggregatorOption configures an Aggregator.
+type AggregatorOption func(*Aggregator)
+
+// WithMaxKeys sets how many distinct decisions are counted at once, 10000 by default. Once reached,
+// new distinct decisions are recorded as they are until the counted ones are recorded, bounding memory.
+func WithMaxKeys(keys int) AggregatorOption {
+	return func(aggregator *Aggregator) {
+		aggregator.maxKeys = keys
+	}
+}
+
+// NewAggregator creates a new Aggregator recording the counted decisions to next.
+func NewAggregator(next Recorder, logger *slog.Logger, options ...AggregatorOption) *Aggregator {
+	aggregator := &Aggregator{
+		next:    next,
+		logger:  logger,
+		clock:   clock.System(),
+		maxKeys: 10000,
+		counts:  map[aggregateKey]*Decision{},
+	}
+	for _, option := range options {
+		option(aggregator)
+	}
+	return aggregator
+}
+
+// Record counts the decision with the identical ones made in the same minute. The counted decision
+// keeps the identifier of the first one.
+func (aggregator *Aggregator) Record(ctx context.Context, decision Decision) error {
+	minute := decision.Time.Truncate(time.Minute)
+	key := aggregateKey{
+		caller:        decision.Caller,
+		user:          decision.User,
+		permission:    decision.Permission,
+		allowed:       decision.Allowed,
+		reason:        decision.Reason,
+		mode:          decision.Mode,
+		policyVersion: decision.PolicyVersion,
+		minute:        minute,
+	}
+
+	aggregator.mu.Lock()
+	if counted, ok := aggregator.counts[key]; ok {
+		counted.Count += decision.count()
+		aggregator.mu.Unlock()
+		return nil
+	}
+	if len(aggregator.counts) < aggregator.maxKeys {
+		decision.Time = minute
+		decision.Count = decision.count()
+		aggregator.counts[key] = &decision
+		aggregator.mu.Unlock()
+		return nil
+	}
+	aggregator.mu.Unlock()
+	return aggregator.next.Record(ctx, decision)
+}
+
+// Run records the counted decisions of every minute once it ended, until the context is done.
+// The decisions still counted when the context is done are recorded before returning, so the
+// Batcher they are recorded to should be stopped after Run returns.
+func (aggregator *Aggregator) Run(ctx context.Context) {
+	ticker := aggregator.clock.NewTicker(aggregateFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			aggregator.flush(ctx, aggregator.clock.Now().Truncate(time.Minute))
+		case <-ctx.Done():
+			aggregator.flush(context.WithoutCancel(ctx), time.Time{})
+			return
+		}
+	}
+}
+
+// flush records the decisions counted in the minutes before the given one, or all of them when it is zero.
+func (aggregator *Aggregator) flush(ctx context.Context, before time.Time) {
+	var ended []Decision
+	aggregator.mu.Lock()
+	for key, counted := range aggregator.counts {
+		if before.IsZero() || key.minute.Before(before) {
+			ended = append(ended, *counted)
+			delete(aggregator.counts, key)
+		}
+	}
+	aggregator.mu.Unlock()
+
+	slices.SortFunc(ended, func(a, b Decision) int {
+		return cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.ID, b.ID))
+	})
+	failed := 0
+	for _, decision := range ended {
+		if err := aggregator.next.Record(ctx, decision); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		aggregator.logger.Warn("counted decisions not recorded", "count", failed, "total", len(ended))
+	}
+}
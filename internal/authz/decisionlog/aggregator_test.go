@@ -0,0 +1,89 @@
+package decisionlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func newTestAggregator(next Recorder, fake *FakeClock, options ...AggregatorOption) *Aggregator {
+	aggregator := NewAggregator(next, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+	aggregator.clock = fake
+	return aggregator
+}
+
+// startAggregator runs the aggregator until the returned function is called, which waits for Run to return.
+func startAggregator(t *testing.T, aggregator *Aggregator, fake *FakeClock) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		aggregator.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// timedDecision returns an allowed decision of the user made at the given time.
+func timedDecision(id string, user string, at time.Time) Decision {
+	made := decision(user)
+	made.ID = id
+	made.Time = at
+	return made
+}
+
+// TestAggregator_Run records decisions over two minutes, checking the identical ones of a minute are
+// recorded once with their count after the minute ended.
+func TestAggregator_Run(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)
+	fake := NewFakeClock(start)
+	recorder := &recordingRecorder{}
+	aggregator := newTestAggregator(recorder, fake)
+	stop := startAggregator(t, aggregator, fake)
+	defer stop()
+
+	ctx := context.Background()
+	assert.NoError(t, aggregator.Record(ctx, timedDecision("1", "alice", start.Add(time.Second))))
+	assert.NoError(t, aggregator.Record(ctx, timedDecision("2", "alice", start.Add(30*time.Second))))
+	assert.NoError(t, aggregator.Record(ctx, timedDecision("3", "bob", start.Add(40*time.Second))))
+	assert.NoError(t, aggregator.Record(ctx, timedDecision("4", "alice", start.Add(70*time.Second))))
+
+	// the minute has not ended yet
+	fake.Advance(aggregateFlushInterval)
+	assert.Never(t, func() bool { return len(recorder.Decisions()) > 0 }, 20*time.Millisecond, time.Millisecond)
+
+	fake.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return len(recorder.Decisions()) == 2 }, time.Second, time.Millisecond)
+	aliceCounted := timedDecision("1", "alice", start)
+	aliceCounted.Count = 2
+	bobCounted := timedDecision("3", "bob", start)
+	bobCounted.Count = 1
+	assert.Equal(t, []Decision{aliceCounted, bobCounted}, recorder.Decisions())
+
+	// the decisions of the current minute are recorded when stopping
+	stop()
+	assert.Len(t, recorder.Decisions(), 3)
+	assert.Equal(t, start.Add(time.Minute), recorder.Decisions()[2].Time)
+}
+
+// TestAggregator_MaxKeys records more distinct decisions than counted at once, checking the extra one is recorded as it is.
+func TestAggregator_MaxKeys(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)
+	recorder := &recordingRecorder{err: errors.New("queue full")}
+	aggregator := newTestAggregator(recorder, NewFakeClock(start), WithMaxKeys(1))
+
+	assert.NoError(t, aggregator.Record(context.Background(), timedDecision("1", "alice", start)))
+	assert.NoError(t, aggregator.Record(context.Background(), timedDecision("2", "alice", start)))
+	assert.Error(t, aggregator.Record(context.Background(), timedDecision("3", "bob", start)))
+	assert.Equal(t, []Decision{timedDecision("3", "bob", start)}, recorder.Decisions())
+}
@@ -0,0 +1,167 @@
+package decisionlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// ErrQueueFull is returned by Batcher.Record when the decision was dropped because the queue is full.
+var ErrQueueFull = errors.New("decision log queue is full")
+
+// Batcher queues the recorded decisions and writes them to a Sink in batches, from a single goroutine
+// started with Run. When the sink falls behind and the queue fills up, new decisions are dropped
+// and counted rather than slowing down the decisions themselves, unless WithBlocking is set.
+type Batcher struct {
+	sink      Sink
+	logger    *slog.Logger
+	clock     clock.Clock
+	queue     chan Decision
+	queueSize int
+	batchSize int
+	interval  time.Duration
+	blocking  bool
+	dropped   atomic.Int64
+}
+
+var _ Recorder = (*Batcher)(nil)
+
+// BatcherOption configures a Batcher.
+type BatcherOption func(*Batcher)
+
+// WithBatchSize sets the most decisions written at once, 100 by default.
+func WithBatchSize(size int) BatcherOption {
+	return func(batcher *Batcher) {
+		batcher.batchSize = size
+	}
+}
+
+// WithFlushInterval sets how long decisions wait for a batch to fill up before being written, 1s by default.
+func WithFlushInterval(interval time.Duration) BatcherOption {
+	return func(batcher *Batcher) {
+		batcher.interval = interval
+	}
+}
+
+// WithQueueSize sets how many decisions wait to be written before new ones are dropped, 10000 by default.
+func WithQueueSize(size int) BatcherOption {
+	return func(batcher *Batcher) {
+		batcher.queueSize = size
+	}
+}
+
+// WithBlocking makes Record wait for room in the queue instead of dropping the decision,
+// for deployments where every decision must be logged even at the cost of latency.
+func WithBlocking() BatcherOption {
+	return func(batcher *Batcher) {
+		batcher.blocking = true
+	}
+}
+
+// NewBatcher creates a new Batcher writing to the given sink.
+func NewBatcher(sink Sink, logger *slog.Logger, options ...BatcherOption) *Batcher {
+	batcher := &Batcher{
+		sink:      sink,
+		logger:    logger,
+		clock:     clock.System(),
+		queueSize: 10000,
+		batchSize: 100,
+		interval:  time.Second,
+	}
+	for _, option := range options {
+		option(batcher)
+	}
+	batcher.queue = make(chan Decision, batcher.queueSize)
+	return batcher
+}
+
+// Record queues the decision. It returns ErrQueueFull when the decision was dropped, or the
+// context error when blocking and the context is done first.
+func (batcher *Batcher) Record(ctx context.Context, decision Decision) error {
+	if batcher.blocking {
+		select {
+		case batcher.queue <- decision:
+			return nil
+		case <-ctx.Done():
+			batcher.dropped.Add(decision.count())
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case batcher.queue <- decision:
+		return nil
+	default:
+		batcher.dropped.Add(decision.count())
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns how many decisions were dropped so far, because the queue was full or the sink failed.
+// A counted decision, see Aggregator, adds its count.
+func (batcher *Batcher) Dropped() int64 {
+	return batcher.dropped.Load()
+}
+
+// Run writes the queued decisions until the context is done, whenever a batch is full and every
+// flush interval. The decisions still queued when the context is done are written before returning.
+func (batcher *Batcher) Run(ctx context.Context) {
+	ticker := batcher.clock.NewTicker(batcher.interval)
+	defer ticker.Stop()
+
+	batch := make([]Decision, 0, batcher.batchSize)
+	for {
+		select {
+		case decision := <-batcher.queue:
+			batch = append(batch, decision)
+			if len(batch) >= batcher.batchSize {
+				batch = batcher.flush(ctx, batch)
+			}
+		case <-ticker.C():
+			batch = batcher.flush(ctx, batch)
+		case <-ctx.Done():
+			batcher.drain(context.WithoutCancel(ctx), batch)
+			return
+		}
+	}
+}
+
+// drain writes the batch and the decisions left in the queue, giving the sink a few seconds to complete.
+func (batcher *Batcher) drain(ctx context.Context, batch []Decision) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for {
+		select {
+		case decision := <-batcher.queue:
+			batch = append(batch, decision)
+			if len(batch) >= batcher.batchSize {
+				batch = batcher.flush(ctx, batch)
+			}
+		default:
+			batcher.flush(ctx, batch)
+			return
+		}
+	}
+}
+
+// flush writes the batch and returns an empty batch to fill next. A failed batch is logged and dropped.
+func (batcher *Batcher) flush(ctx context.Context, batch []Decision) []Decision {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := batcher.sink.Write(ctx, batch); err != nil {
+		for _, decision := range batch {
+			batcher.dropped.Add(decision.count())
+		}
+		batcher.logger.Error("failed to write decisions", "count", len(batch), "error", err)
+	} else {
+		batcher.logger.Debug("decisions written", "count", len(batch))
+	}
+	return make([]Decision, 0, batcher.batchSize)
+}
@@ -0,0 +1,145 @@
+package decisionlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// recordingSink is a Sink keeping the batches written to it.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]Decision
+	err     error
+	written chan struct{}
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{written: make(chan struct{}, 10)}
+}
+
+func (sink *recordingSink) Write(ctx context.Context, decisions []Decision) error {
+	sink.mu.Lock()
+	sink.batches = append(sink.batches, decisions)
+	sink.mu.Unlock()
+	sink.written <- struct{}{}
+	return sink.err
+}
+
+func (sink *recordingSink) Batches() [][]Decision {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.batches
+}
+
+func newTestBatcher(sink Sink, fake *FakeClock, options ...BatcherOption) *Batcher {
+	batcher := NewBatcher(sink, slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+	batcher.clock = fake
+	return batcher
+}
+
+// startBatcher runs the batcher until the returned function is called, which waits for Run to return.
+func startBatcher(t *testing.T, batcher *Batcher, fake *FakeClock) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		batcher.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func decision(user string) Decision {
+	return Decision{User: user, Permission: "recipes.read", Allowed: true}
+}
+
+// TestBatcher_BatchSize records a full batch, checking it is written without waiting for the flush interval.
+func TestBatcher_BatchSize(t *testing.T) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink := newRecordingSink()
+	batcher := newTestBatcher(sink, fake, WithBatchSize(2))
+	stop := startBatcher(t, batcher, fake)
+	defer stop()
+
+	assert.NoError(t, batcher.Record(context.Background(), decision("alice")))
+	assert.NoError(t, batcher.Record(context.Background(), decision("bob")))
+	<-sink.written
+
+	assert.Equal(t, [][]Decision{{decision("alice"), decision("bob")}}, sink.Batches())
+}
+
+// TestBatcher_FlushInterval records a decision and advances the clock, checking the partial batch is written.
+func TestBatcher_FlushInterval(t *testing.T) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink := newRecordingSink()
+	batcher := newTestBatcher(sink, fake, WithFlushInterval(time.Second))
+	stop := startBatcher(t, batcher, fake)
+	defer stop()
+
+	assert.NoError(t, batcher.Record(context.Background(), decision("alice")))
+	assert.Eventually(t, func() bool { return len(batcher.queue) == 0 }, time.Second, time.Millisecond)
+	fake.Advance(time.Second)
+	<-sink.written
+
+	assert.Equal(t, [][]Decision{{decision("alice")}}, sink.Batches())
+}
+
+// TestBatcher_Stop stops the batcher with queued decisions, checking they are written before Run returns.
+func TestBatcher_Stop(t *testing.T) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink := newRecordingSink()
+	batcher := newTestBatcher(sink, fake)
+
+	assert.NoError(t, batcher.Record(context.Background(), decision("alice")))
+	startBatcher(t, batcher, fake)()
+
+	assert.Equal(t, [][]Decision{{decision("alice")}}, sink.Batches())
+}
+
+// TestBatcher_QueueFull records more decisions than the queue holds, checking the extra ones are dropped or wait.
+func TestBatcher_QueueFull(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		batcher := NewBatcher(newRecordingSink(), slog.New(slog.NewTextHandler(io.Discard, nil)), WithQueueSize(1))
+
+		assert.NoError(t, batcher.Record(context.Background(), decision("alice")))
+		assert.ErrorIs(t, batcher.Record(context.Background(), decision("bob")), ErrQueueFull)
+		assert.Equal(t, int64(1), batcher.Dropped())
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		batcher := NewBatcher(newRecordingSink(), slog.New(slog.NewTextHandler(io.Discard, nil)), WithQueueSize(1), WithBlocking())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.NoError(t, batcher.Record(ctx, decision("alice")))
+		assert.ErrorIs(t, batcher.Record(ctx, decision("bob")), context.DeadlineExceeded)
+		assert.Equal(t, int64(1), batcher.Dropped())
+	})
+}
+
+// TestBatcher_SinkError fails to write a batch, checking its decisions are counted as dropped.
+func TestBatcher_SinkError(t *testing.T) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink := newRecordingSink()
+	sink.err = errors.New("storage down")
+	batcher := newTestBatcher(sink, fake, WithBatchSize(1))
+	stop := startBatcher(t, batcher, fake)
+
+	assert.NoError(t, batcher.Record(context.Background(), decision("alice")))
+	<-sink.written
+	stop()
+
+	assert.Equal(t, int64(1), batcher.Dropped())
+}
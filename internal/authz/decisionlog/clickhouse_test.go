@@ -0,0 +1,154 @@
+package decisionlog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeClickHouse is a minimal ClickHouse HTTP interface recording the queries it receives.
+type fakeClickHouse struct {
+	mu         sync.Mutex
+	queries    []string
+	settings   []string
+	data       []string
+	partitions string
+	user       string
+}
+
+func (clickHouse *fakeClickHouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	clickHouse.mu.Lock()
+	defer clickHouse.mu.Unlock()
+
+	clickHouse.user, _, _ = r.BasicAuth()
+	body, _ := io.ReadAll(r.Body)
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		query = string(body)
+	} else {
+		clickHouse.data = append(clickHouse.data, string(body))
+	}
+	query = strings.Join(strings.Fields(query), " ")
+	clickHouse.queries = append(clickHouse.queries, query)
+	clickHouse.settings = append(clickHouse.settings, r.URL.Query().Get("async_insert"))
+
+	switch {
+	case strings.Contains(query, "missing"):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Code: 60. DB::Exception: Table authz.missing does not exist"))
+	case strings.HasPrefix(query, "SELECT DISTINCT partition_id"):
+		_, _ = w.Write([]byte(clickHouse.partitions))
+	}
+}
+
+func newTestClickHouse(t *testing.T, table string) (*fakeClickHouse, *ClickHouseSink) {
+	clickHouse := &fakeClickHouse{}
+	server := httptest.NewServer(clickHouse)
+	t.Cleanup(server.Close)
+
+	sink, err := OpenClickHouse("clickhouse://writer:secret@"+strings.TrimPrefix(server.URL, "http://")+"/"+table, server.Client())
+	assert.NoError(t, err)
+	return clickHouse, sink
+}
+
+// TestClickHouseSink_Write writes a batch, checking it is inserted as JSON rows with an asynchronous insert.
+func TestClickHouseSink_Write(t *testing.T) {
+	clickHouse, sink := newTestClickHouse(t, "authz.decision_logs")
+
+	assert.NoError(t, sink.Write(context.Background(), testDecisions()))
+	assert.Equal(t, []string{"INSERT INTO authz.decision_logs FORMAT JSONEachRow"}, clickHouse.queries)
+	assert.Equal(t, []string{"1"}, clickHouse.settings)
+	assert.Equal(t, "writer", clickHouse.user)
+
+	rows := strings.Split(strings.TrimSpace(clickHouse.data[0]), "\n")
+	assert.Len(t, rows, 2)
+	assert.JSONEq(t, `{"id":"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f","decided_at":"2025-01-02 03:04:05.000","caller":"recipes-service",
+		"user_id":"bob","permission":"recipes.read","allowed":false,"reason":"no_matching_group","mode":"default-deny","policy_version":"v1","count":1}`, rows[1])
+}
+
+// TestClickHouseSink_Error writes to a missing table, checking the error of the server is returned.
+func TestClickHouseSink_Error(t *testing.T) {
+	_, sink := newTestClickHouse(t, "authz.missing")
+
+	err := sink.Write(context.Background(), testDecisions())
+	assert.ErrorContains(t, err, "Table authz.missing does not exist")
+}
+
+// TestClickHouseSink_Migrate creates the table, checking it is partitioned by day and older tables get the count column.
+func TestClickHouseSink_Migrate(t *testing.T) {
+	clickHouse, sink := newTestClickHouse(t, "decision_logs")
+
+	assert.NoError(t, sink.Migrate(context.Background()))
+	assert.Len(t, clickHouse.queries, 2)
+	assert.Contains(t, clickHouse.queries[0], "CREATE TABLE IF NOT EXISTS decision_logs (")
+	assert.Contains(t, clickHouse.queries[0], "PARTITION BY toYYYYMMDD(decided_at)")
+	assert.Equal(t, "ALTER TABLE decision_logs ADD COLUMN IF NOT EXISTS count UInt64 DEFAULT 1", clickHouse.queries[1])
+}
+
+// TestClickHouseSink_Prune prunes the decisions, checking only the partitions of the days before the cutoff are dropped.
+func TestClickHouseSink_Prune(t *testing.T) {
+	clickHouse, sink := newTestClickHouse(t, "authz.decision_logs")
+	clickHouse.partitions = "20241230\n20241231\n20250101\n20250102\n"
+
+	dropped, err := sink.Prune(context.Background(), time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, dropped)
+	assert.Equal(t, []string{
+		"ALTER TABLE authz.decision_logs DROP PARTITION ID '20241230'",
+		"ALTER TABLE authz.decision_logs DROP PARTITION ID '20241231'",
+	}, clickHouse.queries[1:])
+}
+
+// TestOpenClickHouse opens invalid URLs, checking they are refused.
+func TestOpenClickHouse(t *testing.T) {
+	for _, rawURL := range []string{
+		"postgres://localhost/authz.decision_logs",
+		"clickhouse://localhost:8123/",
+		"clickhouse://localhost:8123/authz.decision_logs;DROP TABLE users",
+	} {
+		_, err := OpenClickHouse(rawURL, http.DefaultClient)
+		assert.Error(t, err, rawURL)
+	}
+}
+
+// TestRetention_Run runs the retention on a fake clock, checking it prunes once per interval against the advanced time.
+func TestRetention_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := NewFakeClock(decided)
+	pruned := make(chan time.Time)
+	retention := NewRetention(prunerFunc(func(ctx context.Context, before time.Time) (int, error) {
+		pruned <- before
+		return 0, nil
+	}), 24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	retention.clock = fake
+
+	done := make(chan struct{})
+	go func() {
+		retention.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	assert.Equal(t, decided.Add(-24*time.Hour), <-pruned)
+	fake.Advance(time.Hour)
+	assert.Equal(t, decided.Add(-23*time.Hour), <-pruned)
+
+	cancel()
+	<-done
+}
+
+// prunerFunc adapts a function to the Pruner interface.
+type prunerFunc func(ctx context.Context, before time.Time) (int, error)
+
+func (prune prunerFunc) Prune(ctx context.Context, before time.Time) (int, error) {
+	return prune(ctx, before)
+}
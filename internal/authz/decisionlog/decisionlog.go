@@ -0,0 +1,60 @@
+// Package decisionlog records the permission decisions made by the authorization service, so they
+// can be reviewed and analyzed later. Decisions are written to a Sink: a file, Postgres, Kafka and
+// ClickHouse are provided, and embedders can plug their own storage by implementing the interface.
+// A Batcher sits between the service and the sink, so recording a decision never waits on storage.
+// At high rates a Sampler and an Aggregator in front of the Batcher cut down what is stored.
+package decisionlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// Decision is a single recorded permission decision.
+type Decision struct {
+	// The identifier of the decision, see id.New, so decisions sort by the time they were made.
+	ID string `json:"id"`
+
+	// The time the decision was made.
+	Time time.Time `json:"time"`
+
+	// The authenticated service or user that asked for the decision.
+	Caller string `json:"caller"`
+
+	// The user and permission the decision was made on.
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+
+	Allowed bool `json:"allowed"`
+
+	// Why the permission was denied, or allowed by the evaluation mode only, see authz.DenialReason.
+	Reason authz.DenialReason `json:"reason,omitempty"`
+
+	// The evaluation mode the decision was made in.
+	Mode authz.EvaluationMode `json:"mode"`
+
+	// The version of the policy the decision was made with, see policyfile.Version.
+	PolicyVersion string `json:"policy_version"`
+
+	// How many identical decisions the record stands for when aggregated by an Aggregator,
+	// in which case Time is the start of the minute they were made in. Zero means one.
+	Count int64 `json:"count,omitempty"`
+}
+
+// count returns how many decisions the record stands for.
+func (decision *Decision) count() int64 {
+	return max(decision.Count, 1)
+}
+
+// Recorder records decisions one at a time: a Batcher, or a Sampler or Aggregator in front of one.
+type Recorder interface {
+	Record(ctx context.Context, decision Decision) error
+}
+
+// Sink stores or forwards batches of decisions. Write is called by a single goroutine of the
+// Batcher at a time, and a failed batch is not retried, so sinks retry transient failures themselves.
+type Sink interface {
+	Write(ctx context.Context, decisions []Decision) error
+}
@@ -0,0 +1,57 @@
+package decisionlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileSink is a Sink writing decisions as JSON lines.
+type FileSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink creates a new FileSink writing to the given writer.
+func NewFileSink(writer io.Writer) *FileSink {
+	return &FileSink{writer: writer}
+}
+
+// OpenFile creates a new FileSink appending to the file at the given path, created if missing.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{writer: file, closer: file}, nil
+}
+
+// Write appends one line per decision. The batch is written at once, so the lines of
+// concurrent batches never interleave.
+func (sink *FileSink) Write(ctx context.Context, decisions []Decision) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	buffered := bufio.NewWriter(sink.writer)
+	encoder := json.NewEncoder(buffered)
+	for _, decision := range decisions {
+		if err := encoder.Encode(decision); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// Close closes the file opened with OpenFile; it does nothing for the writers given to NewFileSink.
+func (sink *FileSink) Close() error {
+	if sink.closer == nil {
+		return nil
+	}
+	return sink.closer.Close()
+}
@@ -0,0 +1,46 @@
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Message is a record to produce to a Kafka topic.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer produces messages to Kafka. It is implemented by embedders on top of the Kafka client
+// they already use, so the service does not depend on a particular client.
+type Producer interface {
+	Produce(ctx context.Context, messages []Message) error
+}
+
+// KafkaSink is a Sink producing every decision as a JSON message to a Kafka topic.
+// Messages are keyed by user, so the decisions on a user are kept in order within a partition.
+type KafkaSink struct {
+	producer Producer
+	topic    string
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+// NewKafkaSink creates a new KafkaSink producing to the given topic.
+func NewKafkaSink(producer Producer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+// Write produces one message per decision.
+func (sink *KafkaSink) Write(ctx context.Context, decisions []Decision) error {
+	messages := make([]Message, len(decisions))
+	for i, decision := range decisions {
+		value, err := json.Marshal(decision)
+		if err != nil {
+			return err
+		}
+		messages[i] = Message{Topic: sink.topic, Key: []byte(decision.User), Value: value}
+	}
+	return sink.producer.Produce(ctx, messages)
+}
@@ -0,0 +1,52 @@
+package decisionlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresSink is a Sink inserting decisions in the decision_logs table.
+type PostgresSink struct {
+	db pgdb.DB
+}
+
+var _ Sink = (*PostgresSink)(nil)
+
+// NewPostgresSink creates a new PostgresSink instance.
+func NewPostgresSink(db pgdb.DB) *PostgresSink {
+	return &PostgresSink{db: db}
+}
+
+// Write inserts the batch with a single statement, so it is stored entirely or not at all.
+func (sink *PostgresSink) Write(ctx context.Context, decisions []Decision) error {
+	ids := make([]string, len(decisions))
+	times := make([]time.Time, len(decisions))
+	callers := make([]string, len(decisions))
+	users := make([]string, len(decisions))
+	permissions := make([]string, len(decisions))
+	allowed := make([]bool, len(decisions))
+	reasons := make([]string, len(decisions))
+	modes := make([]string, len(decisions))
+	versions := make([]string, len(decisions))
+	counts := make([]int64, len(decisions))
+	for i, decision := range decisions {
+		ids[i] = decision.ID
+		times[i] = decision.Time
+		callers[i] = decision.Caller
+		users[i] = decision.User
+		permissions[i] = decision.Permission
+		allowed[i] = decision.Allowed
+		reasons[i] = string(decision.Reason)
+		modes[i] = string(decision.Mode)
+		versions[i] = decision.PolicyVersion
+		counts[i] = decision.count()
+	}
+
+	_, err := sink.db.Exec(ctx, `
+	INSERT INTO decision_logs (id, decided_at, caller, user_id, permission, allowed, reason, mode, policy_version, count)
+	SELECT * FROM unnest($1::uuid[], $2::timestamptz[], $3::text[], $4::text[], $5::text[], $6::boolean[], $7::text[], $8::text[], $9::text[], $10::bigint[])
+	`, ids, times, callers, users, permissions, allowed, reasons, modes, versions, counts)
+	return err
+}
@@ -0,0 +1,55 @@
+package decisionlog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Pruner deletes the decisions made before a time, such as ClickHouseSink.
+type Pruner interface {
+	// Prune deletes the decisions made before the time and returns how many units of storage,
+	// such as rows or partitions, were deleted.
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// Retention periodically deletes the decisions older than a maximum age.
+type Retention struct {
+	pruner Pruner
+	maxAge time.Duration
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// NewRetention creates a new Retention deleting the decisions older than maxAge with the pruner.
+func NewRetention(pruner Pruner, maxAge time.Duration, logger *slog.Logger) *Retention {
+	return &Retention{pruner: pruner, maxAge: maxAge, logger: logger, clock: clock.System()}
+}
+
+// Prune deletes the decisions made before the retention period and returns how many units were deleted.
+func (retention *Retention) Prune(ctx context.Context) (int, error) {
+	return retention.pruner.Prune(ctx, retention.clock.Now().Add(-retention.maxAge))
+}
+
+// Run prunes the decisions immediately and then every interval until the context is done.
+func (retention *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := retention.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := retention.Prune(ctx)
+		if err != nil {
+			retention.logger.Error("failed to prune decision logs", "error", err)
+		} else if deleted > 0 {
+			retention.logger.Info("pruned decision logs", "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
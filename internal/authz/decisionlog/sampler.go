@@ -0,0 +1,70 @@
+package decisionlog
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// Sampler records every denial but only a share of the allowed decisions, which are the bulk of
+// the decisions and the least interesting to review. Decisions allowed by the evaluation mode
+// only carry a reason and are always recorded too, so their effect can be reviewed before
+// enforcing the policy.
+type Sampler struct {
+	next    Recorder
+	rate    float64
+	mu      sync.Mutex
+	random  *rand.Rand
+	skipped atomic.Int64
+}
+
+var _ Recorder = (*Sampler)(nil)
+
+// SamplerOption configures a Sampler.
+type SamplerOption func(*Sampler)
+
+// WithSampleSeed seeds the choice of the recorded decisions, so tests are repeatable.
+func WithSampleSeed(seed uint64) SamplerOption {
+	return func(sampler *Sampler) {
+		sampler.random = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// NewSampler creates a new Sampler recording the given percentage of the allowed decisions to next,
+// from 0 to record none of them to 100 to record them all.
+func NewSampler(next Recorder, percent float64, options ...SamplerOption) *Sampler {
+	sampler := &Sampler{
+		next:   next,
+		rate:   min(max(percent, 0), 100) / 100,
+		random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	for _, option := range options {
+		option(sampler)
+	}
+	return sampler
+}
+
+// Record records the decision if it is a denial, carries a reason or is picked by the sampling.
+func (sampler *Sampler) Record(ctx context.Context, decision Decision) error {
+	if !decision.Allowed || decision.Reason != "" || sampler.sample() {
+		return sampler.next.Record(ctx, decision)
+	}
+	sampler.skipped.Add(decision.count())
+	return nil
+}
+
+// Skipped returns how many allowed decisions were left out by the sampling so far.
+func (sampler *Sampler) Skipped() int64 {
+	return sampler.skipped.Load()
+}
+
+// sample tells whether an allowed decision is recorded.
+func (sampler *Sampler) sample() bool {
+	if sampler.rate >= 1 {
+		return true
+	}
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+	return sampler.random.Float64() < sampler.rate
+}
@@ -0,0 +1,67 @@
+package decisionlog
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingRecorder is a Recorder keeping the decisions recorded to it.
+type recordingRecorder struct {
+	mu        sync.Mutex
+	decisions []Decision
+	err       error
+}
+
+func (recorder *recordingRecorder) Record(ctx context.Context, decision Decision) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.decisions = append(recorder.decisions, decision)
+	return recorder.err
+}
+
+func (recorder *recordingRecorder) Decisions() []Decision {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.decisions
+}
+
+// TestSampler_Record records many decisions, checking denials and decisions allowed by the mode are
+// always recorded while only a share of the allowed ones are.
+func TestSampler_Record(t *testing.T) {
+	recorder := &recordingRecorder{}
+	sampler := NewSampler(recorder, 10, WithSampleSeed(1))
+	denied := Decision{User: "bob", Permission: "recipes.read", Reason: authz.DenialNoMatchingGroup}
+	shadowed := Decision{User: "carol", Permission: "recipes.read", Allowed: true, Reason: authz.DenialNoMatchingGroup, Mode: authz.ModeShadow}
+
+	for range 1000 {
+		assert.NoError(t, sampler.Record(context.Background(), decision("alice")))
+		assert.NoError(t, sampler.Record(context.Background(), denied))
+		assert.NoError(t, sampler.Record(context.Background(), shadowed))
+	}
+
+	counts := map[string]int{}
+	for _, recorded := range recorder.Decisions() {
+		counts[recorded.User]++
+	}
+	assert.Equal(t, 1000, counts["bob"])
+	assert.Equal(t, 1000, counts["carol"])
+	assert.InDelta(t, 100, counts["alice"], 30)
+	assert.Equal(t, int64(1000-counts["alice"]), sampler.Skipped())
+}
+
+// TestSampler_Bounds samples at 0% and 100%, checking no allowed decision or every one is recorded.
+func TestSampler_Bounds(t *testing.T) {
+	none := &recordingRecorder{}
+	all := &recordingRecorder{}
+	for range 100 {
+		assert.NoError(t, NewSampler(none, 0).Record(context.Background(), decision("alice")))
+		assert.NoError(t, NewSampler(all, 100).Record(context.Background(), decision("alice")))
+	}
+
+	assert.Empty(t, none.Decisions())
+	assert.Len(t, all.Decisions(), 100)
+}
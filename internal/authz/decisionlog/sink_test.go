@@ -0,0 +1,107 @@
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var decided = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func testDecisions() []Decision {
+	return []Decision{
+		{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", Time: decided, Caller: "recipes-service", User: "alice",
+			Permission: "recipes.read", Allowed: true, Mode: authz.ModeDefaultDeny, PolicyVersion: "v1"},
+		{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f", Time: decided, Caller: "recipes-service", User: "bob",
+			Permission: "recipes.read", Reason: authz.DenialNoMatchingGroup, Mode: authz.ModeDefaultDeny, PolicyVersion: "v1"},
+	}
+}
+
+// TestFileSink_Write writes a batch, checking every decision is written as a JSON line.
+func TestFileSink_Write(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewFileSink(&buf)
+
+	assert.NoError(t, sink.Write(context.Background(), testDecisions()))
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f","time":"2025-01-02T03:04:05Z","caller":"recipes-service",
+		"user":"bob","permission":"recipes.read","allowed":false,"reason":"no_matching_group","mode":"default-deny","policy_version":"v1"}`,
+		string(lines[1]))
+}
+
+// TestOpenFile writes two batches to a file, checking the second is appended.
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	for range 2 {
+		sink, err := OpenFile(path)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Write(context.Background(), testDecisions()[:1]))
+		assert.NoError(t, sink.Close())
+	}
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(content, []byte("\n")))
+}
+
+// TestPostgresSink_Write writes a batch, checking it is inserted with a single statement.
+func TestPostgresSink_Write(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	sink := NewPostgresSink(mockDb)
+
+	mockDb.On("Exec", ctx, mock.Anything, []any{
+		[]string{"0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5f"},
+		[]time.Time{decided, decided},
+		[]string{"recipes-service", "recipes-service"},
+		[]string{"alice", "bob"},
+		[]string{"recipes.read", "recipes.read"},
+		[]bool{true, false},
+		[]string{"", "no_matching_group"},
+		[]string{"default-deny", "default-deny"},
+		[]string{"v1", "v1"},
+		[]int64{1, 1},
+	}).Return(pgconn.NewCommandTag("INSERT 0 2"), nil)
+
+	assert.NoError(t, sink.Write(ctx, testDecisions()))
+	mockDb.AssertExpectations(t)
+}
+
+// producerFunc adapts a function to the Producer interface.
+type producerFunc func(ctx context.Context, messages []Message) error
+
+func (produce producerFunc) Produce(ctx context.Context, messages []Message) error {
+	return produce(ctx, messages)
+}
+
+// TestKafkaSink_Write writes a batch, checking one message keyed by user is produced per decision.
+func TestKafkaSink_Write(t *testing.T) {
+	var produced []Message
+	sink := NewKafkaSink(producerFunc(func(ctx context.Context, messages []Message) error {
+		produced = messages
+		return nil
+	}), "authz.decisions")
+
+	assert.NoError(t, sink.Write(context.Background(), testDecisions()))
+	assert.Len(t, produced, 2)
+	assert.Equal(t, "authz.decisions", produced[1].Topic)
+	assert.Equal(t, []byte("bob"), produced[1].Key)
+	assert.Contains(t, string(produced[1].Value), `"reason":"no_matching_group"`)
+
+	failing := NewKafkaSink(producerFunc(func(ctx context.Context, messages []Message) error {
+		return errors.New("broker unavailable")
+	}), "authz.decisions")
+	assert.Error(t, failing.Write(context.Background(), testDecisions()))
+}
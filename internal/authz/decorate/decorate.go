@@ -0,0 +1,80 @@
+// Package decorate composes the PolicyManager decorators, such as guardrails and hooks,
+// in a defined order, so the fully decorated manager is built with a single Chain call.
+package decorate
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/cache"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/hooks"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// PolicyManager is the policy store being decorated.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// Layer orders the decorators: lower layers wrap the store more closely.
+type Layer int
+
+const (
+	// LayerValidation checks changes before they reach the store, such as guardrails and business rules.
+	LayerValidation Layer = iota + 1
+	// LayerHooks observes the operations, such as audit and metrics, including the changes validation rejects.
+	LayerHooks
+	// LayerCache answers reads from memory. It wraps the hooks, so they only observe the reads reaching the store.
+	LayerCache
+)
+
+// Option adds a decorator to the chain.
+type Option struct {
+	layer    Layer
+	decorate func(next PolicyManager) PolicyManager
+}
+
+// With adds a custom decorator at the given layer.
+func With(layer Layer, decorate func(next PolicyManager) PolicyManager) Option {
+	return Option{layer: layer, decorate: decorate}
+}
+
+// WithGuardrails enforces the guardrail rules on changes, see guardrail.NewManager.
+func WithGuardrails(rules []guardrail.Rule, reviewer guardrail.Reviewer, logger *slog.Logger, options ...guardrail.ManagerOption) Option {
+	return With(LayerValidation, func(next PolicyManager) PolicyManager {
+		return guardrail.NewManager(next, rules, reviewer, logger, options...)
+	})
+}
+
+// WithHooks runs the hooks around every operation, see hooks.NewManager.
+func WithHooks(registered ...hooks.Hook) Option {
+	return With(LayerHooks, func(next PolicyManager) PolicyManager {
+		return hooks.NewManager(next, registered...)
+	})
+}
+
+// WithAudit records every change as an audit event to the sink, whatever the store, see hooks.NewAudit.
+func WithAudit(sink audit.Sink, actor func(ctx context.Context) string, logger *slog.Logger) Option {
+	return WithHooks(hooks.NewAudit(sink, actor, logger))
+}
+
+// WithCache reads the policy from the provider and invalidates it on every change, see cache.NewManager.
+func WithCache(provider *cache.CachedPolicyProvider) Option {
+	return With(LayerCache, func(next PolicyManager) PolicyManager {
+		return cache.NewManager(next, provider)
+	})
+}
+
+// Chain wraps the manager with the decorators of the options, ordered by layer whatever the
+// order of the options. Decorators of the same layer wrap in the order they are given, so the
+// first one is the closest to the store.
+func Chain(manager PolicyManager, options ...Option) PolicyManager {
+	ordered := slices.Clone(options)
+	slices.SortStableFunc(ordered, func(a, b Option) int { return int(a.layer) - int(b.layer) })
+
+	for _, option := range ordered {
+		manager = option.decorate(manager)
+	}
+	return manager
+}
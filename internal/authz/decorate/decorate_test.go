@@ -0,0 +1,86 @@
+package decorate
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/authz/cache"
+	"github.com/salmarsumi/recipes/internal/authz/hooks"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// tracing is a decorator recording when its ListGroups is called.
+type tracing struct {
+	PolicyManager
+	name  string
+	calls *[]string
+}
+
+func (manager *tracing) ListGroups(ctx context.Context) ([]store.GroupInfo[int], error) {
+	*manager.calls = append(*manager.calls, manager.name)
+	return manager.PolicyManager.ListGroups(ctx)
+}
+
+func trace(layer Layer, name string, calls *[]string) Option {
+	return With(layer, func(next PolicyManager) PolicyManager {
+		return &tracing{PolicyManager: next, name: name, calls: calls}
+	})
+}
+
+// TestChain chains decorators given out of order, checking they are called from the outermost layer.
+func TestChain(t *testing.T) {
+	next := new(MockPolicyManager)
+	next.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{}, nil)
+	calls := []string{}
+
+	manager := Chain(next,
+		trace(LayerHooks, "metrics", &calls),
+		trace(LayerValidation, "rules", &calls),
+		trace(LayerHooks, "audit", &calls),
+		trace(LayerValidation, "guardrails", &calls),
+	)
+
+	_, err := manager.ListGroups(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit", "metrics", "guardrails", "rules"}, calls)
+}
+
+// TestChain_Builtin chains the builtin decorators, checking the hooks wrap the guardrails and the cache wraps the hooks.
+func TestChain_Builtin(t *testing.T) {
+	next := new(MockPolicyManager)
+
+	manager := Chain(next, WithHooks(hooks.NewMetrics()), WithGuardrails(nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	assert.IsType(t, &hooks.Manager{}, manager)
+
+	provider := cache.NewCachedPolicyProvider(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	manager = Chain(next, WithCache(provider), WithHooks(hooks.NewMetrics()))
+	assert.IsType(t, &cache.Manager{}, manager)
+}
+
+// TestChain_Audit chains the auditing decorator, checking a change reaching the store is recorded to the sink.
+func TestChain_Audit(t *testing.T) {
+	next := new(MockPolicyManager)
+	next.On("AddGroupUser", mock.Anything, 3, "alice").Return(nil)
+	var buf bytes.Buffer
+	actor := func(ctx context.Context) string { return "admin" }
+
+	manager := Chain(next, WithAudit(audit.NewFileSink(&buf), actor, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	assert.NoError(t, manager.AddGroupUser(context.Background(), 3, "alice"))
+
+	next.AssertExpectations(t)
+	assert.Contains(t, buf.String(), `"actor":"admin","action":"policy.add_group_user","subject":"group 3","details":{"user":"alice"}`)
+}
+
+// TestChain_Empty chains no decorator, checking the manager is returned unchanged.
+func TestChain_Empty(t *testing.T) {
+	next := new(MockPolicyManager)
+	assert.Same(t, next, Chain(next))
+}
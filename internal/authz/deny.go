@@ -0,0 +1,78 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// Denial explicitly denies a permission to groups and users. A denial overrides every grant: the
+// permission is denied to the members of the groups and to the users even when another of their
+// groups is granted it, directly, through an implication or a wildcard. A wildcard denial such as
+// "recipes.*" denies every permission below it.
+type Denial struct {
+	Permission string `json:"permission"`
+	// The groups whose members are denied the permission.
+	Groups []string `json:"groups,omitempty"`
+	// The users denied the permission, whatever their groups.
+	Users []string `json:"users,omitempty"`
+}
+
+// NewDenial creates a new Denial of the permission to the specified groups and users.
+func NewDenial(permission string, groups []string, users []string) *Denial {
+	return &Denial{Permission: permission, Groups: groups, Users: users}
+}
+
+// appliesTo reports whether the denial applies to the user, member of the groups.
+func (denial *Denial) appliesTo(user string, groups shared.Set[string]) bool {
+	return slices.Contains(denial.Users, user) || groups.ContainsAny(denial.Groups...)
+}
+
+// denied returns the permissions explicitly denied to the user, member of the groups, in the order of the denials.
+func (policy *Policy) denied(user string, groups shared.Set[string]) []string {
+	var denied []string
+	for _, denial := range policy.Denials {
+		if denial.appliesTo(user, groups) && !slices.Contains(denied, denial.Permission) {
+			denied = append(denied, denial.Permission)
+		}
+	}
+	return denied
+}
+
+// DenyingPermission returns the denied permission denying the checked one, directly or as a wildcard
+// above it, or false when none does. The most specific takes precedence, like GrantingPermission.
+func DenyingPermission(denied []string, checked string) (string, bool) {
+	return GrantingPermission(denied, checked)
+}
+
+// ValidateDenials checks that every denial names a permission of the policy, with a valid wildcard, and known groups,
+// like the store does.
+func (policy *Policy) ValidateDenials() error {
+	groups := make(shared.Set[string], len(policy.Groups))
+	for _, group := range policy.Groups {
+		groups.Add(group.Name)
+	}
+	permissions := make(shared.Set[string], len(policy.Permissions))
+	for _, permission := range policy.Permissions {
+		permissions.Add(permission.Name)
+	}
+	for _, denial := range policy.Denials {
+		if denial.Permission == "" {
+			return errors.New("a denial has no permission")
+		}
+		if err := ValidateWildcard(denial.Permission); err != nil {
+			return err
+		}
+		if !permissions.Contains(denial.Permission) {
+			return fmt.Errorf("unknown permission %q is denied", denial.Permission)
+		}
+		for _, group := range denial.Groups {
+			if !groups.Contains(group) {
+				return fmt.Errorf("permission %q is denied to unknown group %q", denial.Permission, group)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// denialPolicy grants recipes.* to the cooks and recipes.read to the readers, denying recipes.delete
+// to the interns, recipes.* to mallory and recipes.read to carol.
+func denialPolicy() *Policy {
+	policy := NewPolicy(
+		[]Permission{
+			*NewPermission("recipes.*", []string{"cooks"}),
+			{Name: "recipes.read", Groups: []string{"readers"}},
+			{Name: "recipes.write", Groups: []string{"cooks"}, Implies: []string{"recipes.read"}},
+			*NewPermission("recipes.delete", nil),
+		},
+		[]Group{
+			*NewGroup("cooks", []string{"alice", "bob", "mallory"}),
+			*NewGroup("interns", []string{"bob"}),
+			*NewGroup("readers", []string{"carol"}),
+		},
+	)
+	policy.Denials = []Denial{
+		*NewDenial("recipes.delete", []string{"interns"}, nil),
+		*NewDenial("recipes.*", nil, []string{"mallory"}),
+		*NewDenial("recipes.read", nil, []string{"carol"}),
+	}
+	return policy
+}
+
+// TestPolicy_Evaluate_Denials evaluates users denied permissions, checking the denials override the grants.
+func TestPolicy_Evaluate_Denials(t *testing.T) {
+	policy := denialPolicy()
+
+	result, err := policy.Evaluate("bob")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.*", "recipes.read", "recipes.write"}, result.Permissions)
+	assert.Equal(t, []string{"recipes.delete"}, result.Denied)
+
+	result, err = policy.Evaluate("mallory")
+	assert.NoError(t, err)
+	assert.Empty(t, result.Permissions)
+	assert.Equal(t, []string{"recipes.*"}, result.Denied)
+
+	result, err = policy.Evaluate("alice")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.*", "recipes.read", "recipes.write"}, result.Permissions)
+	assert.Nil(t, result.Denied)
+}
+
+// TestPolicy_Check_Denials checks denied permissions, checking the denial overrides grants, wildcards and the evaluation modes.
+func TestPolicy_Check_Denials(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       EvaluationMode
+		user       string
+		permission string
+		expected   CheckResult
+	}{
+		{"denied to a group over a wildcard", ModeDefaultDeny, "bob", "recipes.delete",
+			CheckResult{User: "bob", Permission: "recipes.delete", Reason: DenialExplicit, Mode: ModeDefaultDeny}},
+		{"denied by a wildcard", ModeDefaultDeny, "mallory", "recipes.write",
+			CheckResult{User: "mallory", Permission: "recipes.write", Reason: DenialExplicit, Mode: ModeDefaultDeny}},
+		{"denied to a user", ModeDefaultDeny, "carol", "recipes.read",
+			CheckResult{User: "carol", Permission: "recipes.read", Reason: DenialExplicit, Mode: ModeDefaultDeny}},
+		{"not denied", ModeDefaultDeny, "alice", "recipes.delete",
+			CheckResult{User: "alice", Permission: "recipes.delete", Allowed: true, Mode: ModeDefaultDeny, GrantedBy: "recipes.*"}},
+		{"denied in default-allow", ModeDefaultAllow, "bob", "recipes.delete",
+			CheckResult{User: "bob", Permission: "recipes.delete", Reason: DenialExplicit, Mode: ModeDefaultAllow}},
+		{"allowed in shadow", ModeShadow, "bob", "recipes.delete",
+			CheckResult{User: "bob", Permission: "recipes.delete", Allowed: true, Reason: DenialExplicit, Mode: ModeShadow}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := denialPolicy()
+			policy.Mode = test.mode
+
+			result, err := policy.Check(test.user, test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, &test.expected, result)
+
+			// HasPermission ignores the mode, so it allows only what the policy grants
+			allowed, err := policy.HasPermission(test.user, test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected.Reason == "", allowed, "HasPermission")
+		})
+	}
+}
+
+// TestSession_Denials checks sessions and compiled policies, checking denials override wildcards held.
+func TestSession_Denials(t *testing.T) {
+	session, err := denialPolicy().Session("bob")
+	assert.NoError(t, err)
+	assert.True(t, session.HasPermission("recipes.publish"))
+	assert.False(t, session.HasPermission("recipes.delete"))
+	assert.Equal(t, []string{"recipes.delete"}, session.Result().Denied)
+
+	compiled, err := Compile(denialPolicy())
+	assert.NoError(t, err)
+	assert.True(t, compiled.HasPermission("bob", "recipes.publish"))
+	assert.False(t, compiled.HasPermission("bob", "recipes.delete"))
+	assert.False(t, compiled.HasPermission("mallory", "recipes.read"))
+	assert.True(t, compiled.HasPermission("alice", "recipes.delete"))
+}
+
+// TestPolicy_EvaluateTraced_Denials traces a denied user, checking the denials applying to them are reported.
+func TestPolicy_EvaluateTraced_Denials(t *testing.T) {
+	var denials []TraceStep
+	_, err := denialPolicy().EvaluateTraced("bob", func(step TraceStep) {
+		if step.Kind == TraceDenial {
+			denials = append(denials, step)
+		}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []TraceStep{
+		{Kind: TraceDenial, Name: "recipes.delete", Matched: true, Via: []string{"interns"}},
+		{Kind: TraceDenial, Name: "recipes.*"},
+		{Kind: TraceDenial, Name: "recipes.read"},
+	}, denials)
+}
+
+func TestValidateDenials(t *testing.T) {
+	assert.NoError(t, denialPolicy().ValidateDenials())
+
+	policy := denialPolicy()
+	policy.Denials = append(policy.Denials, *NewDenial("recipes.read", []string{"guests"}, nil))
+	assert.EqualError(t, policy.ValidateDenials(), `permission "recipes.read" is denied to unknown group "guests"`)
+
+	policy.Denials = []Denial{*NewDenial("recipes.purge", nil, []string{"bob"})}
+	assert.EqualError(t, policy.ValidateDenials(), `unknown permission "recipes.purge" is denied`)
+
+	policy.Denials = []Denial{*NewDenial("recipes.*.read", nil, []string{"bob"})}
+	assert.ErrorContains(t, policy.ValidateDenials(), "a wildcard must be the whole last segment")
+
+	_, err := Compile(policy)
+	assert.ErrorContains(t, err, "a wildcard must be the whole last segment")
+}
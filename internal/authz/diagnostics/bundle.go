@@ -0,0 +1,143 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// BundleEntry is a file of the support bundle and the API path it is downloaded from.
+type BundleEntry struct {
+	Name string
+	Path string
+}
+
+// BundleEntries lists the files of the support bundle.
+var BundleEntries = []BundleEntry{
+	{Name: "health.json", Path: "/api/health"},
+	{Name: "diagnostics.json", Path: "/api/debug/diagnostics"},
+	{Name: "goroutines.txt", Path: "/api/debug/pprof/goroutine?debug=2"},
+	{Name: "heap.pprof", Path: "/api/debug/pprof/heap"},
+	{Name: "allocs.pprof", Path: "/api/debug/pprof/allocs"},
+}
+
+// Bundler downloads the diagnostics of a running server into a support bundle.
+type Bundler struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+	cpuProfile time.Duration
+	clock      clock.Clock
+}
+
+// BundlerOption configures optional Bundler settings.
+type BundlerOption func(*Bundler)
+
+// WithHeader sets a header sent with every request, such as the credentials of the caller.
+func WithHeader(name string, value string) BundlerOption {
+	return func(bundler *Bundler) {
+		bundler.header.Set(name, value)
+	}
+}
+
+// WithCPUProfile adds a CPU profile of the given duration to the bundle.
+func WithCPUProfile(duration time.Duration) BundlerOption {
+	return func(bundler *Bundler) {
+		bundler.cpuProfile = duration
+	}
+}
+
+// NewBundler creates a new Bundler for the server at baseURL, such as "https://authz.internal".
+// When httpClient is nil, http.DefaultClient is used.
+func NewBundler(baseURL string, httpClient *http.Client, options ...BundlerOption) *Bundler {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	bundler := &Bundler{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient, header: http.Header{}, clock: clock.System()}
+	for _, option := range options {
+		option(bundler)
+	}
+	return bundler
+}
+
+// Write downloads every bundle entry and writes them to w as a gzipped tar archive.
+// Entries that cannot be downloaded are listed in errors.txt instead, so a partial bundle
+// is still written; an error is returned only when no entry could be downloaded.
+func (bundler *Bundler) Write(ctx context.Context, w io.Writer) error {
+	entries := BundleEntries
+	if bundler.cpuProfile > 0 {
+		entries = append(entries[:len(entries):len(entries)],
+			BundleEntry{Name: "cpu.pprof", Path: fmt.Sprintf("/api/debug/pprof/profile?seconds=%d", int(bundler.cpuProfile.Seconds()))})
+	}
+
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+	modified := bundler.clock.Now()
+
+	var failures []string
+	for _, entry := range entries {
+		content, err := bundler.fetch(ctx, entry.Path)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", entry.Name, err))
+			continue
+		}
+		if err := writeFile(archive, entry.Name, content, modified); err != nil {
+			return err
+		}
+	}
+	if len(failures) == len(entries) {
+		return fmt.Errorf("no diagnostics could be downloaded: %s", failures[0])
+	}
+	if len(failures) > 0 {
+		if err := writeFile(archive, "errors.txt", []byte(strings.Join(failures, "\n")+"\n"), modified); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func (bundler *Bundler) fetch(ctx context.Context, path string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, bundler.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range bundler.header {
+		request.Header[name] = values
+	}
+
+	response, err := bundler.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(response.Status)
+	}
+	return content, nil
+}
+
+func writeFile(archive *tar.Writer, name string, content []byte, modified time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: modified}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(content)
+	return err
+}
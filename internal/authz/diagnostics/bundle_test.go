@@ -0,0 +1,85 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readBundle returns the files of a bundle by name.
+func readBundle(t *testing.T, bundle []byte) map[string]string {
+	compressed, err := gzip.NewReader(bytes.NewReader(bundle))
+	assert.NoError(t, err)
+	archive := tar.NewReader(compressed)
+
+	files := map[string]string{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return files
+		}
+		assert.NoError(t, err)
+		content, err := io.ReadAll(archive)
+		assert.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestBundler_Write(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		switch r.URL.Path {
+		case "/api/debug/pprof/allocs":
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			_, _ = w.Write([]byte(r.URL.RequestURI()))
+		}
+	}))
+	defer server.Close()
+	bundler := NewBundler(server.URL, server.Client(), WithHeader("X-API-Key", "secret"))
+
+	var bundle bytes.Buffer
+	assert.NoError(t, bundler.Write(context.Background(), &bundle))
+
+	files := readBundle(t, bundle.Bytes())
+	assert.Equal(t, "/api/debug/diagnostics", files["diagnostics.json"])
+	assert.Equal(t, "/api/debug/pprof/goroutine?debug=2", files["goroutines.txt"])
+	assert.Equal(t, "/api/debug/pprof/heap", files["heap.pprof"])
+	assert.NotContains(t, files, "allocs.pprof")
+	assert.NotContains(t, files, "cpu.pprof")
+	assert.Equal(t, "allocs.pprof: 403 Forbidden\n", files["errors.txt"])
+}
+
+func TestBundler_Write_CPUProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer server.Close()
+	bundler := NewBundler(server.URL, server.Client(), WithCPUProfile(5*time.Second))
+
+	var bundle bytes.Buffer
+	assert.NoError(t, bundler.Write(context.Background(), &bundle))
+
+	files := readBundle(t, bundle.Bytes())
+	assert.Equal(t, "/api/debug/pprof/profile?seconds=5", files["cpu.pprof"])
+	assert.NotContains(t, files, "errors.txt")
+}
+
+func TestBundler_Write_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	bundler := NewBundler(server.URL, server.Client())
+
+	var bundle bytes.Buffer
+	assert.ErrorContains(t, bundler.Write(context.Background(), &bundle), "401 Unauthorized")
+}
@@ -0,0 +1,153 @@
+// Package diagnostics collects the runtime state of a running server, such as its goroutines,
+// memory, connection pool and recent errors, and bundles it with profiles for support.
+package diagnostics
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Collector returns a snapshot of the statistics of a component, such as a connection pool.
+type Collector func(ctx context.Context) (any, error)
+
+// Memory summarizes the memory statistics of the Go runtime.
+type Memory struct {
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	Sys          uint64 `json:"sys"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+// Snapshot is the runtime state of the server at a point in time.
+type Snapshot struct {
+	CollectedAt time.Time `json:"collected_at"`
+	Uptime      string    `json:"uptime"`
+	GoVersion   string    `json:"go_version"`
+	Goroutines  int       `json:"goroutines"`
+	Memory      Memory    `json:"memory"`
+	// The statistics of every registered collector, by name.
+	Stats map[string]any `json:"stats"`
+	// The collectors that failed, by name.
+	CollectorErrors map[string]string `json:"collector_errors,omitempty"`
+	RecentErrors    []Error           `json:"recent_errors"`
+}
+
+// Diagnostics collects snapshots of the runtime state.
+type Diagnostics struct {
+	errors     *ErrorLog
+	started    time.Time
+	clock      clock.Clock
+	mutex      sync.Mutex
+	collectors map[string]Collector
+}
+
+// New creates a new Diagnostics reporting the errors recorded by the given log, which may be nil.
+func New(errors *ErrorLog) *Diagnostics {
+	diagnostics := &Diagnostics{errors: errors, clock: clock.System(), collectors: map[string]Collector{}}
+	diagnostics.started = diagnostics.clock.Now()
+	return diagnostics
+}
+
+// Register adds a collector whose statistics are reported under the given name.
+func (diagnostics *Diagnostics) Register(name string, collector Collector) {
+	diagnostics.mutex.Lock()
+	defer diagnostics.mutex.Unlock()
+	diagnostics.collectors[name] = collector
+}
+
+// Snapshot collects the current runtime state. A failing collector is reported in the
+// snapshot instead of failing it, so the rest of the state can still be inspected.
+func (diagnostics *Diagnostics) Snapshot(ctx context.Context) Snapshot {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	now := diagnostics.clock.Now()
+	snapshot := Snapshot{
+		CollectedAt: now.UTC(),
+		Uptime:      now.Sub(diagnostics.started).Round(time.Second).String(),
+		GoVersion:   runtime.Version(),
+		Goroutines:  runtime.NumGoroutine(),
+		Memory: Memory{
+			HeapAlloc:    memory.HeapAlloc,
+			HeapInuse:    memory.HeapInuse,
+			HeapObjects:  memory.HeapObjects,
+			Sys:          memory.Sys,
+			TotalAlloc:   memory.TotalAlloc,
+			NumGC:        memory.NumGC,
+			PauseTotalNs: memory.PauseTotalNs,
+		},
+		Stats:        map[string]any{},
+		RecentErrors: []Error{},
+	}
+
+	diagnostics.mutex.Lock()
+	names := make([]string, 0, len(diagnostics.collectors))
+	for name := range diagnostics.collectors {
+		names = append(names, name)
+	}
+	collectors := diagnostics.collectors
+	diagnostics.mutex.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats, err := collectors[name](ctx)
+		if err != nil {
+			if snapshot.CollectorErrors == nil {
+				snapshot.CollectorErrors = map[string]string{}
+			}
+			snapshot.CollectorErrors[name] = err.Error()
+			continue
+		}
+		snapshot.Stats[name] = stats
+	}
+
+	if diagnostics.errors != nil {
+		snapshot.RecentErrors = diagnostics.errors.Recent()
+	}
+	return snapshot
+}
+
+// PoolStats summarizes the statistics of a Postgres connection pool.
+type PoolStats struct {
+	TotalConns         int32  `json:"total_conns"`
+	IdleConns          int32  `json:"idle_conns"`
+	AcquiredConns      int32  `json:"acquired_conns"`
+	MaxConns           int32  `json:"max_conns"`
+	AcquireCount       int64  `json:"acquire_count"`
+	EmptyAcquireCount  int64  `json:"empty_acquire_count"`
+	CanceledAcquires   int64  `json:"canceled_acquire_count"`
+	AcquireDuration    string `json:"acquire_duration"`
+	NewConnsCount      int64  `json:"new_conns_count"`
+	MaxLifetimeDestroy int64  `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroy     int64  `json:"max_idle_destroy_count"`
+}
+
+// Pool returns a collector reporting the statistics of the connection pool.
+func Pool(pool *pgxpool.Pool) Collector {
+	return func(ctx context.Context) (any, error) {
+		stat := pool.Stat()
+		return PoolStats{
+			TotalConns:         stat.TotalConns(),
+			IdleConns:          stat.IdleConns(),
+			AcquiredConns:      stat.AcquiredConns(),
+			MaxConns:           stat.MaxConns(),
+			AcquireCount:       stat.AcquireCount(),
+			EmptyAcquireCount:  stat.EmptyAcquireCount(),
+			CanceledAcquires:   stat.CanceledAcquireCount(),
+			AcquireDuration:    stat.AcquireDuration().String(),
+			NewConnsCount:      stat.NewConnsCount(),
+			MaxLifetimeDestroy: stat.MaxLifetimeDestroyCount(),
+			MaxIdleDestroy:     stat.MaxIdleDestroyCount(),
+		}, nil
+	}
+}
@@ -0,0 +1,76 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	errorLog := NewErrorLog(slog.DiscardHandler, 10)
+	slog.New(errorLog).Error("store failed", "error", "connection refused")
+
+	diagnostics := New(errorLog)
+	diagnostics.started = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	diagnostics.clock = NewFakeClock(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC))
+	diagnostics.Register("pool", func(ctx context.Context) (any, error) { return PoolStats{TotalConns: 4}, nil })
+	diagnostics.Register("cache", func(ctx context.Context) (any, error) { return nil, errors.New("unavailable") })
+
+	snapshot := diagnostics.Snapshot(context.Background())
+	assert.Equal(t, "1h0m0s", snapshot.Uptime)
+	assert.Positive(t, snapshot.Goroutines)
+	assert.Positive(t, snapshot.Memory.Sys)
+	assert.Equal(t, map[string]any{"pool": PoolStats{TotalConns: 4}}, snapshot.Stats)
+	assert.Equal(t, map[string]string{"cache": "unavailable"}, snapshot.CollectorErrors)
+	assert.Len(t, snapshot.RecentErrors, 1)
+	assert.Equal(t, "store failed", snapshot.RecentErrors[0].Message)
+}
+
+func TestErrorLog(t *testing.T) {
+	errorLog := NewErrorLog(slog.DiscardHandler, 2)
+	logger := slog.New(errorLog).With("component", "api")
+
+	logger.Info("listening")
+	logger.Error("first")
+	logger.Error("second", "user", "alice")
+	logger.Error("third")
+
+	recent := errorLog.Recent()
+	assert.Len(t, recent, 2)
+	assert.Equal(t, "second", recent[0].Message)
+	assert.Equal(t, map[string]string{"component": "api", "user": "alice"}, recent[0].Attrs)
+	assert.Equal(t, "third", recent[1].Message)
+}
+
+func TestErrorLog_PassesRecordsOn(t *testing.T) {
+	next := &countingHandler{level: slog.LevelInfo}
+	logger := slog.New(NewErrorLog(next, 2))
+
+	logger.Debug("ignored")
+	logger.Info("listening")
+	logger.Error("failed")
+
+	assert.Equal(t, 2, next.count)
+}
+
+// countingHandler counts the records of the level and above.
+type countingHandler struct {
+	level slog.Level
+	count int
+}
+
+func (handler *countingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= handler.level
+}
+func (handler *countingHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler.count++
+	return nil
+}
+func (handler *countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return handler }
+func (handler *countingHandler) WithGroup(name string) slog.Handler       { return handler }
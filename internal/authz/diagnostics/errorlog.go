@@ -0,0 +1,100 @@
+package diagnostics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Error is an error logged by the server.
+type Error struct {
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// ErrorLog is a slog.Handler keeping the most recent error records in memory,
+// and passing every record on to the next handler.
+type ErrorLog struct {
+	next   slog.Handler
+	attrs  []slog.Attr
+	buffer *errorBuffer
+}
+
+var _ slog.Handler = (*ErrorLog)(nil)
+
+// errorBuffer is a ring of the most recent errors, shared by the handlers derived from one ErrorLog.
+type errorBuffer struct {
+	mutex  sync.Mutex
+	errors []Error
+	next   int
+	full   bool
+}
+
+// NewErrorLog creates a new ErrorLog keeping up to capacity errors and passing records on to next.
+func NewErrorLog(next slog.Handler, capacity int) *ErrorLog {
+	return &ErrorLog{next: next, buffer: &errorBuffer{errors: make([]Error, max(capacity, 1))}}
+}
+
+// Enabled reports whether the next handler handles records of the level. Errors are always recorded.
+func (log *ErrorLog) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || log.next.Enabled(ctx, level)
+}
+
+// Handle records error records and passes every record the next handler is enabled for on to it.
+func (log *ErrorLog) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelError {
+		attrs := map[string]string{}
+		for _, attr := range log.attrs {
+			attrs[attr.Key] = attr.Value.String()
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+		log.buffer.add(Error{Time: record.Time.UTC(), Message: record.Message, Attrs: attrs})
+	}
+
+	if !log.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return log.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler adding the attributes to every record, sharing the recorded errors.
+func (log *ErrorLog) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ErrorLog{next: log.next.WithAttrs(attrs), attrs: append(log.attrs[:len(log.attrs):len(log.attrs)], attrs...), buffer: log.buffer}
+}
+
+// WithGroup returns a handler qualifying the attributes with the group name, sharing the recorded errors.
+// The recorded errors keep the attribute names unqualified.
+func (log *ErrorLog) WithGroup(name string) slog.Handler {
+	return &ErrorLog{next: log.next.WithGroup(name), attrs: log.attrs, buffer: log.buffer}
+}
+
+// Recent returns the recorded errors, oldest first.
+func (log *ErrorLog) Recent() []Error {
+	return log.buffer.recent()
+}
+
+func (buffer *errorBuffer) add(err Error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	buffer.errors[buffer.next] = err
+	buffer.next = (buffer.next + 1) % len(buffer.errors)
+	if buffer.next == 0 {
+		buffer.full = true
+	}
+}
+
+func (buffer *errorBuffer) recent() []Error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if !buffer.full {
+		return append([]Error{}, buffer.errors[:buffer.next]...)
+	}
+	return append(append([]Error{}, buffer.errors[buffer.next:]...), buffer.errors[:buffer.next]...)
+}
@@ -0,0 +1,112 @@
+// Package directory keeps the user attributes and group owners synchronized from the
+// user registry or identity provider, and routes access requests to the approvers they name.
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+)
+
+// ErrNotFound is returned when the user is not in the directory.
+var ErrNotFound = errors.New("user not found in the directory")
+
+// User holds the attributes of a user synchronized from the user registry or identity provider.
+type User struct {
+	ID string `json:"id"`
+	// The user id of the manager, empty when the user has none.
+	Manager   string    `json:"manager,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store persists the directory.
+type Store interface {
+	// PutUsers inserts the users or replaces the attributes of those already known.
+	PutUsers(ctx context.Context, users []User) error
+	GetUser(ctx context.Context, id string) (*User, error)
+	// SetGroupOwners replaces the owners of the group.
+	SetGroupOwners(ctx context.Context, groupId int, owners []string) error
+	GroupOwners(ctx context.Context, groupId int) ([]string, error)
+}
+
+// Route names who an access request is routed to.
+type Route string
+
+const (
+	// RouteManager routes the request to the manager of the requester.
+	RouteManager Route = "manager"
+	// RouteGroupOwners routes the request to the owners of the requested group.
+	RouteGroupOwners Route = "owners"
+)
+
+// ParseRoutes parses a comma separated list of routes, such as "owners,manager".
+func ParseRoutes(value string) ([]Route, error) {
+	routes := []Route{}
+	for _, part := range strings.Split(value, ",") {
+		route := Route(strings.TrimSpace(part))
+		switch route {
+		case "":
+			continue
+		case RouteManager, RouteGroupOwners:
+		default:
+			return nil, fmt.Errorf("unknown approval route %q", route)
+		}
+		if slices.Contains(routes, route) {
+			return nil, fmt.Errorf("approval route %q is listed twice", route)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// Router routes access requests to the approvers named by the directory.
+// The routes are tried in order and the first one naming approvers wins, so with
+// "owners,manager" requests for groups without owners go to the manager of the requester.
+// Requests no route names approvers for may be decided by any approver.
+type Router struct {
+	store  Store
+	routes []Route
+}
+
+var _ approval.Router = (*Router)(nil)
+
+// NewRouter creates a new Router trying the given routes in order.
+func NewRouter(store Store, routes []Route) *Router {
+	return &Router{store: store, routes: routes}
+}
+
+// Approvers returns the approvers of group membership requests. Other requests are not routed.
+func (router *Router) Approvers(ctx context.Context, request approval.Request) ([]string, error) {
+	if request.Kind != approval.KindGroupMembership {
+		return nil, nil
+	}
+
+	for _, route := range router.routes {
+		var approvers []string
+		switch route {
+		case RouteManager:
+			user, err := router.store.GetUser(ctx, request.RequestedBy)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			if user != nil && user.Manager != "" {
+				approvers = []string{user.Manager}
+			}
+		case RouteGroupOwners:
+			owners, err := router.store.GroupOwners(ctx, request.GroupID)
+			if err != nil {
+				return nil, err
+			}
+			approvers = owners
+		}
+		if len(approvers) > 0 {
+			return approvers, nil
+		}
+	}
+	return nil, nil
+}
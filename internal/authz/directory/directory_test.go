@@ -0,0 +1,92 @@
+package directory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockStore is a mock implementation of the Store interface
+type mockStore struct {
+	mock.Mock
+}
+
+func (m *mockStore) PutUsers(ctx context.Context, users []User) error {
+	return m.Called(ctx, users).Error(0)
+}
+func (m *mockStore) GetUser(ctx context.Context, id string) (*User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*User)
+	return user, args.Error(1)
+}
+func (m *mockStore) SetGroupOwners(ctx context.Context, groupId int, owners []string) error {
+	return m.Called(ctx, groupId, owners).Error(0)
+}
+func (m *mockStore) GroupOwners(ctx context.Context, groupId int) ([]string, error) {
+	args := m.Called(ctx, groupId)
+	owners, _ := args.Get(0).([]string)
+	return owners, args.Error(1)
+}
+
+var membership = approval.Request{Kind: approval.KindGroupMembership, GroupID: 2, UserID: "alice", RequestedBy: "alice"}
+
+// TestParseRoutes parses route lists, checking unknown and repeated routes are rejected.
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(" owners, manager ")
+	assert.NoError(t, err)
+	assert.Equal(t, []Route{RouteGroupOwners, RouteManager}, routes)
+
+	routes, err = ParseRoutes("")
+	assert.NoError(t, err)
+	assert.Empty(t, routes)
+
+	_, err = ParseRoutes("owners,skip-level")
+	assert.ErrorContains(t, err, "unknown approval route")
+
+	_, err = ParseRoutes("manager,manager")
+	assert.ErrorContains(t, err, "listed twice")
+}
+
+// TestRouter_Owners routes a request for a group with owners, checking the owners are the approvers.
+func TestRouter_Owners(t *testing.T) {
+	ctx := context.Background()
+	store := new(mockStore)
+	store.On("GroupOwners", ctx, 2).Return([]string{"carol", "dave"}, nil)
+
+	approvers, err := NewRouter(store, []Route{RouteGroupOwners, RouteManager}).Approvers(ctx, membership)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol", "dave"}, approvers)
+
+	store.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+}
+
+// TestRouter_Fallback routes a request for a group without owners, checking it falls back to the manager.
+func TestRouter_Fallback(t *testing.T) {
+	ctx := context.Background()
+	store := new(mockStore)
+	store.On("GroupOwners", ctx, 2).Return([]string{}, nil)
+	store.On("GetUser", ctx, "alice").Return(&User{ID: "alice", Manager: "bob"}, nil)
+
+	approvers, err := NewRouter(store, []Route{RouteGroupOwners, RouteManager}).Approvers(ctx, membership)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, approvers)
+}
+
+// TestRouter_Unrouted routes requests no route names approvers for, checking any approver may decide.
+func TestRouter_Unrouted(t *testing.T) {
+	ctx := context.Background()
+	store := new(mockStore)
+	store.On("GetUser", ctx, "alice").Return(nil, ErrNotFound)
+	router := NewRouter(store, []Route{RouteManager})
+
+	approvers, err := router.Approvers(ctx, membership)
+	assert.NoError(t, err)
+	assert.Empty(t, approvers)
+
+	approvers, err = router.Approvers(ctx, approval.Request{Kind: approval.KindPermissionGrant, GroupID: 2, PermissionID: 3, RequestedBy: "alice"})
+	assert.NoError(t, err)
+	assert.Empty(t, approvers)
+}
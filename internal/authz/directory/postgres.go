@@ -0,0 +1,106 @@
+package directory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// PutUsers upserts the users in a single statement.
+func (directory *PostgresStore) PutUsers(ctx context.Context, users []User) error {
+	ids := make([]string, 0, len(users))
+	managers := make([]pgtype.Text, 0, len(users))
+	updatedAt := make([]pgtype.Timestamptz, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+		managers = append(managers, pgtype.Text{String: user.Manager, Valid: user.Manager != ""})
+		updatedAt = append(updatedAt, pgtype.Timestamptz{Time: user.UpdatedAt, Valid: true})
+	}
+
+	_, err := directory.db.Exec(ctx, `
+	INSERT INTO directory_users (id, manager, updated_at)
+	SELECT * FROM unnest($1::text[], $2::text[], $3::timestamptz[])
+	ON CONFLICT (id) DO UPDATE
+	SET manager = EXCLUDED.manager, updated_at = EXCLUDED.updated_at
+	`, ids, managers, updatedAt)
+	return err
+}
+
+// GetUser returns the attributes of the user.
+func (directory *PostgresStore) GetUser(ctx context.Context, id string) (*User, error) {
+	user := User{ID: id}
+	var manager pgtype.Text
+	err := directory.db.QueryRow(ctx, "SELECT manager, updated_at FROM directory_users WHERE id = $1", id).Scan(&manager, &user.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.Manager = manager.String
+	return &user, nil
+}
+
+// SetGroupOwners replaces the owners of the group.
+// A GroupNotFound error is returned when the group does not exist.
+func (directory *PostgresStore) SetGroupOwners(ctx context.Context, groupId int, owners []string) error {
+	_, err := directory.db.Exec(ctx, `
+	WITH new_owners AS (SELECT unnest($1::text[]) AS user_id)
+	MERGE INTO group_owners owner
+	USING new_owners nw
+	ON owner.group_id = $2 AND owner.user_id = nw.user_id
+	WHEN NOT MATCHED BY TARGET THEN
+		INSERT (group_id, user_id) VALUES ($2, nw.user_id)
+	WHEN NOT MATCHED BY SOURCE AND owner.group_id = $2 THEN
+		DELETE;
+	`, owners, groupId)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return store.NewGroupNotFoundError()
+	}
+	return err
+}
+
+// GroupOwners returns the owners of the group ordered by user id.
+func (directory *PostgresStore) GroupOwners(ctx context.Context, groupId int) ([]string, error) {
+	rows, err := directory.db.Query(ctx, "SELECT user_id FROM group_owners WHERE group_id = $1 ORDER BY user_id", groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	owners := []string{}
+	for rows.Next() {
+		var owner string
+		if err := rows.Scan(&owner); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return owners, nil
+}
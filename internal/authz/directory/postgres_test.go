@@ -0,0 +1,104 @@
+package directory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var updated = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPostgresStore_PutUsers(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockDb.On("Exec", ctx, mock.Anything, []any{
+		[]string{"alice", "bob"},
+		[]pgtype.Text{{String: "bob", Valid: true}, {}},
+		[]pgtype.Timestamptz{{Time: updated, Valid: true}, {Time: updated, Valid: true}},
+	}).Return(pgconn.NewCommandTag("INSERT 0 2"), nil)
+
+	err := NewPostgresStore(mockDb).PutUsers(ctx, []User{{ID: "alice", Manager: "bob", UpdatedAt: updated}, {ID: "bob", UpdatedAt: updated}})
+	assert.NoError(t, err)
+
+	mockDb.AssertExpectations(t)
+}
+
+func TestPostgresStore_GetUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"alice"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*pgtype.Text)) = pgtype.Text{String: "bob", Valid: true}
+			*(dest[1].(*time.Time)) = updated
+		}).Return(nil)
+
+		user, err := NewPostgresStore(mockDb).GetUser(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, &User{ID: "alice", Manager: "bob", UpdatedAt: updated}, user)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"alice"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		user, err := NewPostgresStore(mockDb).GetUser(ctx, "alice")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, user)
+	})
+}
+
+func TestPostgresStore_SetGroupOwners(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]string{"carol"}, 2}).Return(pgconn.NewCommandTag("MERGE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).SetGroupOwners(ctx, 2, []string{"carol"}))
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		err := NewPostgresStore(mockDb).SetGroupOwners(ctx, 2, []string{"carol"})
+		assert.Equal(t, store.NewGroupNotFoundError(), err)
+	})
+}
+
+func TestPostgresStore_GroupOwners(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any{2}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*string)) = "carol"
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	owners, err := NewPostgresStore(mockDb).GroupOwners(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, owners)
+
+	mockRows.AssertExpectations(t)
+}
@@ -0,0 +1,105 @@
+package distribution
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// consulWait is the longest time a Consul blocking query waits for a change.
+const consulWait = "5m"
+
+// ConsulBackend is a Backend storing the policy document in the Consul KV store.
+// Changes are detected with blocking queries on the key.
+type ConsulBackend struct {
+	client   *http.Client
+	endpoint string
+	key      string
+}
+
+var _ Backend = (*ConsulBackend)(nil)
+
+// NewConsulBackend creates a new ConsulBackend using the Consul agent at the given endpoint,
+// such as http://localhost:8500, and storing the document under the given key.
+func NewConsulBackend(client *http.Client, endpoint string, key string) *ConsulBackend {
+	return &ConsulBackend{client: client, endpoint: endpoint, key: key}
+}
+
+// Publish writes the document to the key.
+func (backend *ConsulBackend) Publish(ctx context.Context, document []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, backend.url(nil), bytes.NewReader(document))
+	if err != nil {
+		return err
+	}
+
+	response, err := backend.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("publish policy to consul: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("publish policy to consul: unexpected status %s", response.Status)
+	}
+	return nil
+}
+
+// Wait blocks until the key changes from the given Consul index, then returns its value and new index.
+// A missing key is waited for until it is created.
+func (backend *ConsulBackend) Wait(ctx context.Context, revision uint64) ([]byte, uint64, error) {
+	for {
+		query := url.Values{"raw": {""}}
+		if revision > 0 {
+			query.Set("index", strconv.FormatUint(revision, 10))
+			query.Set("wait", consulWait)
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.url(query), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		response, err := backend.client.Do(request)
+		if err != nil {
+			return nil, 0, fmt.Errorf("watch policy in consul: %w", err)
+		}
+		document, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("watch policy in consul: %w", err)
+		}
+
+		index, err := strconv.ParseUint(response.Header.Get("X-Consul-Index"), 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("watch policy in consul: invalid index: %w", err)
+		}
+		// Consul may reset the index, in which case the next query must start over
+		if index < revision {
+			index = 0
+		}
+
+		switch {
+		case response.StatusCode == http.StatusNotFound:
+			if index == 0 {
+				index = 1
+			}
+		case response.StatusCode != http.StatusOK:
+			return nil, 0, fmt.Errorf("watch policy in consul: unexpected status %s", response.Status)
+		case index != revision:
+			return document, index, nil
+		}
+		revision = index
+	}
+}
+
+func (backend *ConsulBackend) url(query url.Values) string {
+	location := backend.endpoint + "/v1/kv/" + backend.key
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+	return location
+}
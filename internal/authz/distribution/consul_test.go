@@ -0,0 +1,87 @@
+package distribution
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsul is a minimal Consul KV endpoint for a single key.
+// Blocking queries return immediately with the current value.
+type fakeConsul struct {
+	mu      sync.Mutex
+	value   []byte
+	index   uint64
+	queries []string
+}
+
+func (consul *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	consul.mu.Lock()
+	defer consul.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		consul.value, _ = io.ReadAll(r.Body)
+		consul.index++
+		_, _ = w.Write([]byte("true"))
+	case http.MethodGet:
+		consul.queries = append(consul.queries, r.URL.Query().Get("index"))
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(max(consul.index, 1), 10))
+		if consul.value == nil {
+			w.WriteHeader(http.StatusNotFound)
+			// the next blocking query sees the key created
+			consul.value = []byte("created")
+			consul.index = 2
+			return
+		}
+		_, _ = w.Write(consul.value)
+	}
+}
+
+// TestConsulBackend publishes a document and waits for it, checking the value and index.
+func TestConsulBackend(t *testing.T) {
+	consul := &fakeConsul{}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	backend := NewConsulBackend(server.Client(), server.URL, "authz/policy")
+	ctx := context.Background()
+
+	assert.NoError(t, backend.Publish(ctx, []byte("document")))
+
+	document, index, err := backend.Wait(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "document", string(document))
+	assert.Equal(t, uint64(1), index)
+	assert.Equal(t, []string{""}, consul.queries)
+}
+
+// TestConsulBackend_Wait_MissingKey waits for a key that does not exist yet, checking it blocks until created.
+func TestConsulBackend_Wait_MissingKey(t *testing.T) {
+	consul := &fakeConsul{}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	backend := NewConsulBackend(server.Client(), server.URL, "authz/policy")
+
+	document, index, err := backend.Wait(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "created", string(document))
+	assert.Equal(t, uint64(2), index)
+	assert.Equal(t, []string{"", "1"}, consul.queries)
+}
+
+// TestConsulBackend_Publish_Error publishes to a failing agent, checking for an error.
+func TestConsulBackend_Publish_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	backend := NewConsulBackend(server.Client(), server.URL, "authz/policy")
+
+	assert.ErrorContains(t, backend.Publish(context.Background(), []byte("document")), "403")
+}
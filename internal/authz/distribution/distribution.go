@@ -0,0 +1,60 @@
+// Package distribution publishes the policy to a key-value store such as Consul or etcd
+// and keeps local evaluators in sync with it, for push-based policy distribution.
+package distribution
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Backend stores the policy document under a single key.
+type Backend interface {
+	// Publish replaces the stored policy document.
+	Publish(ctx context.Context, document []byte) error
+	// Wait returns the stored policy document once its revision differs from the given one.
+	// A zero revision returns the current document immediately when there is one.
+	Wait(ctx context.Context, revision uint64) ([]byte, uint64, error)
+}
+
+// Open creates the Backend described by the given URL, such as consul://localhost:8500/authz/policy
+// or etcd://localhost:2379/authz/policy. The URL path is the key holding the policy document.
+// Use consul+https or etcd+https to connect over TLS.
+func Open(rawURL string, client *http.Client) (Backend, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse distribution url: %w", err)
+	}
+
+	key := strings.TrimPrefix(location.Path, "/")
+	if key == "" {
+		return nil, fmt.Errorf("distribution url %s has no key", rawURL)
+	}
+
+	scheme, transport, _ := strings.Cut(location.Scheme, "+")
+	switch transport {
+	case "":
+		transport = "http"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported distribution transport %q", transport)
+	}
+	endpoint := transport + "://" + location.Host
+
+	switch scheme {
+	case "consul":
+		return NewConsulBackend(client, endpoint, key), nil
+	case "etcd":
+		return NewEtcdBackend(client, endpoint, key), nil
+	default:
+		return nil, fmt.Errorf("unsupported distribution backend %q", scheme)
+	}
+}
+
+// changed reports whether the document differs from the previous one.
+func changed(previous []byte, document []byte) bool {
+	return previous == nil || !bytes.Equal(previous, document)
+}
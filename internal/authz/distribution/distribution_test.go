@@ -0,0 +1,191 @@
+package distribution
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/salmarsumi/recipes/internal/authz/embedded"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBackend is a Backend keeping the published documents in memory.
+type memoryBackend struct {
+	documents [][]byte
+	err       error
+}
+
+func (backend *memoryBackend) Publish(ctx context.Context, document []byte) error {
+	if backend.err != nil {
+		return backend.err
+	}
+	backend.documents = append(backend.documents, document)
+	return nil
+}
+
+// Wait returns the document published after the given revision, the revision being its position.
+func (backend *memoryBackend) Wait(ctx context.Context, revision uint64) ([]byte, uint64, error) {
+	if backend.err != nil {
+		return nil, 0, backend.err
+	}
+	if int(revision) >= len(backend.documents) {
+		return nil, 0, errors.New("no change")
+	}
+	return backend.documents[revision], revision + 1, nil
+}
+
+type policyReaderFunc func(ctx context.Context) (*authz.Policy, error)
+
+func (f policyReaderFunc) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return f(ctx)
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testPolicy(users ...string) *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{{Name: "write", Groups: []string{"admin"}}},
+		[]authz.Group{{Name: "admin", Users: users}},
+	)
+}
+
+// TestOpen calls distribution.Open with supported and unsupported urls.
+func TestOpen(t *testing.T) {
+	backend, err := Open("consul://localhost:8500/authz/policy", http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, &ConsulBackend{client: http.DefaultClient, endpoint: "http://localhost:8500", key: "authz/policy"}, backend)
+
+	backend, err = Open("etcd+https://etcd:2379/authz/policy", http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, &EtcdBackend{client: http.DefaultClient, endpoint: "https://etcd:2379", key: "authz/policy"}, backend)
+
+	for _, rawURL := range []string{"zookeeper://localhost/policy", "consul://localhost:8500", "consul+ftp://localhost/policy"} {
+		backend, err = Open(rawURL, http.DefaultClient)
+		assert.Error(t, err, rawURL)
+		assert.Nil(t, backend)
+	}
+}
+
+// TestPublisher_Publish publishes the policy twice, checking an unchanged policy is not published again.
+func TestPublisher_Publish(t *testing.T) {
+	backend := &memoryBackend{}
+	users := []string{"adminuser"}
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return testPolicy(users...), nil })
+	publisher := NewPublisher(source, backend, time.Minute, discardLogger())
+
+	published, err := publisher.Publish(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, published)
+
+	published, err = publisher.Publish(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, published)
+
+	users = append(users, "otheruser")
+	published, err = publisher.Publish(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, published)
+	assert.Len(t, backend.documents, 2)
+}
+
+// TestPublisher_Publish_Error publishes while the backend fails, checking the document is published on retry.
+func TestPublisher_Publish_Error(t *testing.T) {
+	backend := &memoryBackend{err: errors.New("backend is down")}
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return testPolicy("adminuser"), nil })
+	publisher := NewPublisher(source, backend, time.Minute, discardLogger())
+
+	_, err := publisher.Publish(context.Background())
+	assert.Error(t, err)
+
+	backend.err = nil
+	published, err := publisher.Publish(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, published)
+}
+
+// TestPublisher_Trigger triggers a running publisher, checking it reads the policy without waiting for the interval.
+func TestPublisher_Trigger(t *testing.T) {
+	reads := make(chan struct{}, 10)
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) {
+		reads <- struct{}{}
+		return testPolicy("adminuser"), nil
+	})
+	publisher := NewPublisher(source, &memoryBackend{}, time.Hour, discardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		publisher.Run(ctx)
+		close(done)
+	}()
+
+	<-reads
+	publisher.Trigger()
+	<-reads
+
+	cancel()
+	<-done
+}
+
+// TestWatcher_Update follows published documents, checking the embedded evaluator sees every change.
+func TestWatcher_Update(t *testing.T) {
+	ctx := context.Background()
+	backend := &memoryBackend{}
+	var updates int
+	watcher := NewWatcher(backend, discardLogger(), WithUpdateHook(func(*authz.Policy) { updates++ }))
+	evaluator := embedded.NewEvaluator(watcher)
+
+	_, err := evaluator.HasPermission("adminuser", "write")
+	assert.Error(t, err)
+
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return testPolicy("adminuser"), nil })
+	_, err = NewPublisher(source, backend, time.Minute, discardLogger()).Publish(ctx)
+	assert.NoError(t, err)
+
+	assert.NoError(t, watcher.Update(ctx))
+	allowed, err := evaluator.HasPermission("adminuser", "write")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	// a malformed document is skipped
+	assert.NoError(t, backend.Publish(ctx, []byte(`{"roles": []}`)))
+	assert.Error(t, watcher.Update(ctx))
+	allowed, err = evaluator.HasPermission("adminuser", "write")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	// an unchanged document does not trigger an update
+	assert.NoError(t, backend.Publish(ctx, []byte(`{"roles": []}`)))
+	assert.NoError(t, watcher.Update(ctx))
+	assert.Equal(t, 1, updates)
+}
+
+// TestWatcher_Update_Invalidation applies published changes, checking every change notifies its version and revision.
+func TestWatcher_Update_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	backend := &memoryBackend{}
+	var invalidations []client.Invalidation
+	watcher := NewWatcher(backend, discardLogger(), WithInvalidation(func(invalidation client.Invalidation) bool {
+		invalidations = append(invalidations, invalidation)
+		return true
+	}))
+
+	document, err := policyfile.Marshal(testPolicy("adminuser"))
+	assert.NoError(t, err)
+	assert.NoError(t, backend.Publish(ctx, document))
+	assert.NoError(t, backend.Publish(ctx, document))
+	assert.NoError(t, watcher.Update(ctx))
+	assert.NoError(t, watcher.Update(ctx))
+
+	version, err := policyfile.Version(testPolicy("adminuser"))
+	assert.NoError(t, err)
+	assert.Equal(t, []client.Invalidation{{PolicyVersion: version, Revision: 1}}, invalidations)
+}
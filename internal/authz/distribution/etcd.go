@@ -0,0 +1,171 @@
+package distribution
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// EtcdBackend is a Backend storing the policy document in etcd through its v3 JSON gateway.
+// Changes are detected with a watch on the key.
+type EtcdBackend struct {
+	client   *http.Client
+	endpoint string
+	key      string
+}
+
+var _ Backend = (*EtcdBackend)(nil)
+
+// etcdKeyValue is a key-value pair in an etcd gateway response.
+// The gateway encodes 64-bit integers as strings and bytes as base64.
+type etcdKeyValue struct {
+	Value       []byte `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+type etcdRangeResponse struct {
+	Header struct {
+		Revision string `json:"revision"`
+	} `json:"header"`
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+type etcdWatchResponse struct {
+	Result struct {
+		Events []struct {
+			Kv etcdKeyValue `json:"kv"`
+		} `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewEtcdBackend creates a new EtcdBackend using the etcd server at the given endpoint,
+// such as http://localhost:2379, and storing the document under the given key.
+func NewEtcdBackend(client *http.Client, endpoint string, key string) *EtcdBackend {
+	return &EtcdBackend{client: client, endpoint: endpoint, key: key}
+}
+
+// Publish writes the document to the key.
+func (backend *EtcdBackend) Publish(ctx context.Context, document []byte) error {
+	response, err := backend.post(ctx, "/v3/kv/put", map[string]any{
+		"key":   []byte(backend.key),
+		"value": document,
+	})
+	if err != nil {
+		return fmt.Errorf("publish policy to etcd: %w", err)
+	}
+	response.Body.Close()
+	return nil
+}
+
+// Wait blocks until the key is modified after the given revision, then returns its value and
+// modification revision. Deleting the key is not a change, as the last policy stays in effect.
+func (backend *EtcdBackend) Wait(ctx context.Context, revision uint64) ([]byte, uint64, error) {
+	if revision == 0 {
+		document, modRevision, storeRevision, err := backend.get(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		if document != nil {
+			return document, modRevision, nil
+		}
+		revision = storeRevision
+	}
+
+	response, err := backend.post(ctx, "/v3/watch", map[string]any{
+		"create_request": map[string]any{
+			"key":            []byte(backend.key),
+			"start_revision": strconv.FormatUint(revision+1, 10),
+			"filters":        []string{"NODELETE"},
+		},
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("watch policy in etcd: %w", err)
+	}
+	defer response.Body.Close()
+
+	// the gateway streams one JSON object per watch response
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var watch etcdWatchResponse
+		if err := decoder.Decode(&watch); err != nil {
+			return nil, 0, fmt.Errorf("watch policy in etcd: %w", err)
+		}
+		if watch.Error != nil {
+			return nil, 0, fmt.Errorf("watch policy in etcd: %s", watch.Error.Message)
+		}
+
+		events := watch.Result.Events
+		if len(events) == 0 {
+			continue
+		}
+		latest := events[len(events)-1].Kv
+		modRevision, err := strconv.ParseUint(latest.ModRevision, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("watch policy in etcd: invalid revision: %w", err)
+		}
+		return latest.Value, modRevision, nil
+	}
+}
+
+// get returns the current value of the key and its modification revision, or a nil value
+// when the key does not exist, together with the current store revision.
+func (backend *EtcdBackend) get(ctx context.Context) ([]byte, uint64, uint64, error) {
+	response, err := backend.post(ctx, "/v3/kv/range", map[string]any{"key": []byte(backend.key)})
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("read policy from etcd: %w", err)
+	}
+	defer response.Body.Close()
+
+	var result etcdRangeResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, 0, 0, fmt.Errorf("read policy from etcd: %w", err)
+	}
+
+	storeRevision, err := strconv.ParseUint(result.Header.Revision, 10, 64)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("read policy from etcd: invalid revision: %w", err)
+	}
+	if len(result.Kvs) == 0 {
+		return nil, 0, storeRevision, nil
+	}
+
+	modRevision, err := strconv.ParseUint(result.Kvs[0].ModRevision, 10, 64)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("read policy from etcd: invalid revision: %w", err)
+	}
+	// an empty value decodes as nil, which would read as a missing key
+	if result.Kvs[0].Value == nil {
+		return []byte{}, modRevision, storeRevision, nil
+	}
+	return result.Kvs[0].Value, modRevision, storeRevision, nil
+}
+
+// post sends a JSON request to the gateway and returns the successful response.
+func (backend *EtcdBackend) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, backend.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := backend.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return response, nil
+}
@@ -0,0 +1,85 @@
+package distribution
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEtcdBackend_Publish publishes a document, checking the put request sent to the gateway.
+func TestEtcdBackend_Publish(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/put", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	backend := NewEtcdBackend(server.Client(), server.URL, "authz/policy")
+
+	assert.NoError(t, backend.Publish(context.Background(), []byte("document")))
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("authz/policy")), body["key"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("document")), body["value"])
+}
+
+// TestEtcdBackend_Wait_Existing waits with no revision, checking the current value is returned.
+func TestEtcdBackend_Wait_Existing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		fmt.Fprintf(w, `{"header":{"revision":"9"},"kvs":[{"value":%q,"mod_revision":"7"}]}`,
+			base64.StdEncoding.EncodeToString([]byte("document")))
+	}))
+	defer server.Close()
+	backend := NewEtcdBackend(server.Client(), server.URL, "authz/policy")
+
+	document, revision, err := backend.Wait(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "document", string(document))
+	assert.Equal(t, uint64(7), revision)
+}
+
+// TestEtcdBackend_Wait_Watch waits for a missing key, checking the watch starts after the store revision.
+func TestEtcdBackend_Wait_Watch(t *testing.T) {
+	var watch struct {
+		CreateRequest struct {
+			StartRevision string `json:"start_revision"`
+		} `json:"create_request"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			_, _ = w.Write([]byte(`{"header":{"revision":"9"}}`))
+		case "/v3/watch":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&watch))
+			_, _ = w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+			fmt.Fprintf(w, `{"result":{"events":[{"kv":{"value":%q,"mod_revision":"12"}}]}}`+"\n",
+				base64.StdEncoding.EncodeToString([]byte("document")))
+		}
+	}))
+	defer server.Close()
+	backend := NewEtcdBackend(server.Client(), server.URL, "authz/policy")
+
+	document, revision, err := backend.Wait(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "document", string(document))
+	assert.Equal(t, uint64(12), revision)
+	assert.Equal(t, "10", watch.CreateRequest.StartRevision)
+}
+
+// TestEtcdBackend_Wait_Error waits while the watch is cancelled by the server, checking for an error.
+func TestEtcdBackend_Wait_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"error":{"message":"mvcc: required revision has been compacted"}}`))
+	}))
+	defer server.Close()
+	backend := NewEtcdBackend(server.Client(), server.URL, "authz/policy")
+
+	_, _, err := backend.Wait(context.Background(), 3)
+	assert.ErrorContains(t, err, "compacted")
+}
@@ -0,0 +1,201 @@
+package distribution
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/salmarsumi/recipes/internal/authz/embedded"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// PolicyReader reads the current policy from the policy store.
+type PolicyReader interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+}
+
+// Publisher periodically publishes the policy read from the store to a Backend.
+type Publisher struct {
+	source    PolicyReader
+	backend   Backend
+	interval  time.Duration
+	logger    *slog.Logger
+	published []byte
+	trigger   chan struct{}
+	clock     clock.Clock
+}
+
+// NewPublisher creates a new Publisher publishing the policy read from source every interval.
+func NewPublisher(source PolicyReader, backend Backend, interval time.Duration, logger *slog.Logger) *Publisher {
+	return &Publisher{source: source, backend: backend, interval: interval, logger: logger,
+		trigger: make(chan struct{}, 1), clock: clock.System()}
+}
+
+// Trigger makes Run publish the policy now rather than at the next interval, such as when the store
+// notified a change. Triggers made while a publish is pending are merged into it.
+func (publisher *Publisher) Trigger() {
+	select {
+	case publisher.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Publish reads the policy and publishes its canonical document when it changed
+// since the last successful publish. It reports whether the document was published.
+func (publisher *Publisher) Publish(ctx context.Context) (bool, error) {
+	policy, err := publisher.source.ReadPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	document, err := policyfile.Marshal(policy)
+	if err != nil {
+		return false, err
+	}
+	if !changed(publisher.published, document) {
+		return false, nil
+	}
+
+	if err := publisher.backend.Publish(ctx, document); err != nil {
+		return false, err
+	}
+	publisher.published = document
+	return true, nil
+}
+
+// Run publishes the policy immediately and then every interval, or when triggered, until the context is done.
+func (publisher *Publisher) Run(ctx context.Context) {
+	ticker := publisher.clock.NewTicker(publisher.interval)
+	defer ticker.Stop()
+
+	for {
+		if published, err := publisher.Publish(ctx); err != nil {
+			publisher.logger.Error("failed to publish policy", "error", err)
+		} else if published {
+			publisher.logger.Debug("policy published")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		case <-publisher.trigger:
+		}
+	}
+}
+
+// Watcher keeps a local copy of the policy published to a Backend.
+// It is a PolicyProvider for the embedded Evaluator.
+type Watcher struct {
+	backend    Backend
+	logger     *slog.Logger
+	policy     atomic.Pointer[authz.Policy]
+	document   []byte
+	revision   uint64
+	retryDelay time.Duration
+	onUpdate   func(policy *authz.Policy)
+	invalidate func(invalidation client.Invalidation) bool
+	clock      clock.Clock
+}
+
+var _ embedded.PolicyProvider = (*Watcher)(nil)
+
+// WatcherOption configures optional Watcher settings.
+type WatcherOption func(*Watcher)
+
+// WithRetryDelay sets how long the Watcher waits after a failure before watching again.
+// The default is five seconds.
+func WithRetryDelay(delay time.Duration) WatcherOption {
+	return func(watcher *Watcher) {
+		watcher.retryDelay = delay
+	}
+}
+
+// WithUpdateHook sets a function called with the new policy after every update.
+func WithUpdateHook(hook func(policy *authz.Policy)) WatcherOption {
+	return func(watcher *Watcher) {
+		watcher.onUpdate = hook
+	}
+}
+
+// WithInvalidation sets a function notified of every update with the version of the new policy
+// and the revision it was published with, such as client.Client.Invalidate, so the decision
+// caches of a service are dropped as soon as a change is published.
+func WithInvalidation(invalidate func(invalidation client.Invalidation) bool) WatcherOption {
+	return func(watcher *Watcher) {
+		watcher.invalidate = invalidate
+	}
+}
+
+// NewWatcher creates a new Watcher following the policy published to the given backend.
+// No policy is available until the first update is received.
+func NewWatcher(backend Backend, logger *slog.Logger, options ...WatcherOption) *Watcher {
+	watcher := &Watcher{backend: backend, logger: logger, retryDelay: 5 * time.Second, clock: clock.System()}
+	for _, option := range options {
+		option(watcher)
+	}
+	return watcher
+}
+
+// Policy returns the last received policy, or nil before the first update.
+func (watcher *Watcher) Policy() *authz.Policy {
+	return watcher.policy.Load()
+}
+
+// Update waits for the next change of the published document and applies it.
+// The first call returns as soon as a document is published.
+// A malformed document is skipped and the previous policy stays in effect.
+// It must not be called concurrently.
+func (watcher *Watcher) Update(ctx context.Context) error {
+	document, revision, err := watcher.backend.Wait(ctx, watcher.revision)
+	if err != nil {
+		// the revision may no longer be available, so start over from the current document
+		watcher.revision = 0
+		return err
+	}
+	watcher.revision = revision
+	if !changed(watcher.document, document) {
+		return nil
+	}
+
+	policy, err := policyfile.Read(bytes.NewReader(document))
+	if err != nil {
+		watcher.document = document
+		return err
+	}
+
+	watcher.document = document
+	watcher.policy.Store(policy)
+	if watcher.onUpdate != nil {
+		watcher.onUpdate(policy)
+	}
+	if watcher.invalidate != nil {
+		version, err := policyfile.Version(policy)
+		if err != nil {
+			return err
+		}
+		watcher.invalidate(client.Invalidation{PolicyVersion: version, Revision: revision})
+	}
+	return nil
+}
+
+// Run applies published changes until the context is done, retrying after failures.
+func (watcher *Watcher) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := watcher.Update(ctx)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		watcher.logger.Error("failed to update policy, keeping the previous policy", "error", err)
+		select {
+		case <-ctx.Done():
+		case <-watcher.clock.After(watcher.retryDelay):
+		}
+	}
+}
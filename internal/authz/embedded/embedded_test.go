@@ -0,0 +1,308 @@
+package embedded
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `{
+	"groups": [{"name": "admin", "users": ["adminuser"]}],
+	"permissions": [{"name": "write", "groups": ["admin"]}]
+}`
+
+const updatedDocument = `
+groups:
+  - name: admin
+    users: [adminuser, otheruser]
+permissions:
+  - name: write
+    groups: [admin]
+`
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// writeFile writes the document and moves its modification time forward,
+// so a change is detected even on file systems with a coarse timestamp resolution.
+func writeFile(t *testing.T, path string, document string, modTime time.Time) {
+	assert.NoError(t, os.WriteFile(path, []byte(document), 0o600))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// TestNewFileProvider creates a FileProvider for a JSON document, checking the policy is loaded.
+func TestNewFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeFile(t, path, testDocument, time.Now())
+
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	assert.Len(t, provider.Policy().Groups, 1)
+}
+
+// TestNewFileProvider_Error_MissingFile creates a FileProvider for a missing file, checking for an error.
+func TestNewFileProvider_Error_MissingFile(t *testing.T) {
+	provider, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json"), discardLogger())
+	assert.Error(t, err)
+	assert.Nil(t, provider)
+}
+
+// TestFileProvider_Reload reloads a changed file, checking the new policy is served.
+func TestFileProvider_Reload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, path, "groups:\n  - name: admin\n    users: [adminuser]\n", start)
+
+	var reloads []*authz.Policy
+	provider, err := NewFileProvider(path, discardLogger(), WithReloadHook(func(policy *authz.Policy) {
+		reloads = append(reloads, policy)
+	}))
+	assert.NoError(t, err)
+
+	reloaded, err := provider.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	writeFile(t, path, updatedDocument, start.Add(time.Minute))
+	reloaded, err = provider.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, []string{"adminuser", "otheruser"}, provider.Policy().Groups[0].Users)
+	assert.Len(t, reloads, 2)
+}
+
+// TestFileProvider_Reload_Error_Malformed reloads a malformed file, checking the previous policy is kept.
+func TestFileProvider_Reload_Error_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, path, testDocument, start)
+
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+
+	writeFile(t, path, `{"roles": []}`, start.Add(time.Minute))
+	reloaded, err := provider.Reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Len(t, provider.Policy().Groups, 1)
+
+	// the broken document is not loaded again until the file changes
+	reloaded, err = provider.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+}
+
+// TestFileProvider_Reload_Error_Invalid reloads a file whose condition does not compile, checking it is
+// refused on load and the last good policy keeps being served.
+func TestFileProvider_Reload_Error_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, path, updatedDocument, start)
+
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+
+	writeFile(t, path, updatedDocument+"    condition: resource.owner ==\n", start.Add(time.Minute))
+	reloaded, err := provider.Reload()
+	assert.ErrorContains(t, err, "validate policy document")
+	assert.False(t, reloaded)
+
+	allowed, err := NewEvaluator(provider).HasPermission("otheruser", "write")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+// TestFileProvider_Watch changes the file while watching it, checking the change is picked up.
+func TestFileProvider_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, path, testDocument, start)
+
+	reloaded := make(chan struct{}, 2)
+	provider, err := NewFileProvider(path, discardLogger(), WithPollInterval(10*time.Millisecond), WithReloadHook(func(*authz.Policy) {
+		reloaded <- struct{}{}
+	}))
+	assert.NoError(t, err)
+	<-reloaded
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go provider.Watch(ctx)
+
+	writeFile(t, path, `{"groups": [], "permissions": []}`, start.Add(time.Minute))
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("policy file was not reloaded")
+	}
+	assert.Empty(t, provider.Policy().Groups)
+}
+
+// TestEvaluator evaluates users against a file backed policy.
+func TestEvaluator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeFile(t, path, testDocument, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	result, err := evaluator.Evaluate("adminuser")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, result.Groups)
+	assert.Equal(t, []string{"write"}, result.Permissions)
+
+	allowed, err := evaluator.HasPermission("adminuser", "write")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = evaluator.HasPermission("otheruser", "write")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	member, err := evaluator.IsInGroup("adminuser", "admin")
+	assert.NoError(t, err)
+	assert.True(t, member)
+
+	_, err = evaluator.HasPermission("", "write")
+	assert.Error(t, err)
+}
+
+// TestEvaluator_Folders evaluates users against a file backed policy granting a permission through a folder,
+// checking the groups of the folder hold it like they do once the policy is in the store.
+func TestEvaluator_Folders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writeFile(t, path, `
+groups:
+  - name: admin
+    users: [adminuser]
+  - name: editors
+    users: [otheruser]
+permissions:
+  - name: write
+    groups: [admin]
+folders:
+  - path: recipes
+    groups: [editors]
+    grants: [write]
+`, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	allowed, err := evaluator.HasPermission("otheruser", "write")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	result, err := evaluator.Evaluate("otheruser")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"write"}, result.Permissions)
+}
+
+// TestEvaluator_HasPermission_IgnoresModes checks permissions against a file backed policy in every evaluation mode,
+// checking only the permissions the policy grants are held while Check follows the mode.
+func TestEvaluator_HasPermission_IgnoresModes(t *testing.T) {
+	for _, mode := range []authz.EvaluationMode{authz.ModeDefaultDeny, authz.ModeDefaultAllow, authz.ModeShadow} {
+		t.Run(string(mode), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			writeFile(t, path, updatedDocument+"mode: "+string(mode)+"\n", time.Now())
+			provider, err := NewFileProvider(path, discardLogger())
+			assert.NoError(t, err)
+			evaluator := NewEvaluator(provider)
+
+			allowed, err := evaluator.HasPermission("adminuser", "write")
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+
+			allowed, err = evaluator.HasPermission("adminuser", "purge")
+			assert.NoError(t, err)
+			assert.False(t, allowed)
+
+			check, err := evaluator.Check("adminuser", "purge")
+			assert.NoError(t, err)
+			assert.Equal(t, mode != authz.ModeDefaultDeny, check.Allowed)
+		})
+	}
+}
+
+// TestEvaluator_Session evaluates a user once and answers their checks from the session.
+func TestEvaluator_Session(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeFile(t, path, testDocument, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	session, err := evaluator.Session("adminuser")
+	assert.NoError(t, err)
+	assert.True(t, session.HasPermission("write"))
+	assert.True(t, session.IsInGroup("admin"))
+
+	_, err = evaluator.Session("")
+	assert.Error(t, err)
+}
+
+// TestEvaluator_EvaluateTraced evaluates a user with a tracer against a file backed policy.
+func TestEvaluator_EvaluateTraced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeFile(t, path, testDocument, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	var steps []authz.TraceStep
+	_, err = evaluator.EvaluateTraced("adminuser", func(step authz.TraceStep) { steps = append(steps, step) })
+	assert.NoError(t, err)
+	assert.Contains(t, steps, authz.TraceStep{Kind: authz.TracePermission, Name: "write", Matched: true, Via: []string{"admin"}})
+}
+
+// TestEvaluator_Check checks permissions against a file backed policy, checking the reason of a denial.
+func TestEvaluator_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeFile(t, path, testDocument, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	check, err := evaluator.Check("adminuser", "write")
+	assert.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	check, err = evaluator.Check("otheruser", "write")
+	assert.NoError(t, err)
+	assert.Equal(t, authz.DenialUserUnknown, check.Reason)
+}
+
+// TestEvaluator_CheckWith checks a conditional permission against a file backed policy, checking the request attributes decide.
+func TestEvaluator_CheckWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writeFile(t, path, `
+groups:
+  - name: admin
+    users: [adminuser]
+permissions:
+  - name: write
+    groups: [admin]
+    condition: resource.owner == user
+`, time.Now())
+	provider, err := NewFileProvider(path, discardLogger())
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(provider)
+
+	check, err := evaluator.CheckWith("adminuser", "write", condition.Attributes{"resource.owner": "adminuser"})
+	assert.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	check, err = evaluator.CheckWith("adminuser", "write", condition.Attributes{"resource.owner": "otheruser"})
+	assert.NoError(t, err)
+	assert.Equal(t, authz.DenialConditionNotMet, check.Reason)
+}
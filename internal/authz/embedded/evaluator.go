@@ -0,0 +1,95 @@
+package embedded
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+)
+
+// Evaluator evaluates users against the current policy of a PolicyProvider.
+// It is safe for concurrent use as long as the provider is.
+type Evaluator struct {
+	provider PolicyProvider
+}
+
+var _ authz.PolicyOperations = (*Evaluator)(nil)
+
+// NewEvaluator creates a new Evaluator reading the policy from the given provider.
+func NewEvaluator(provider PolicyProvider) *Evaluator {
+	return &Evaluator{provider: provider}
+}
+
+// Evaluate returns the groups and permissions of the user in the current policy.
+func (evaluator *Evaluator) Evaluate(user string) (*authz.PolicyEvaluationResult, error) {
+	policy := evaluator.provider.Policy()
+	if policy == nil {
+		return nil, errors.New("no policy loaded")
+	}
+
+	return policy.Evaluate(user)
+}
+
+// EvaluateTraced evaluates the user like Evaluate, reporting every group and permission considered to the tracer.
+func (evaluator *Evaluator) EvaluateTraced(user string, tracer authz.Tracer) (*authz.PolicyEvaluationResult, error) {
+	policy := evaluator.provider.Policy()
+	if policy == nil {
+		return nil, errors.New("no policy loaded")
+	}
+
+	return policy.EvaluateTraced(user, tracer)
+}
+
+// Check tells whether the user is granted the permission in the current policy and, when they are not, why.
+func (evaluator *Evaluator) Check(user string, permission string) (*authz.CheckResult, error) {
+	policy := evaluator.provider.Policy()
+	if policy == nil {
+		return nil, errors.New("no policy loaded")
+	}
+
+	return policy.Check(user, permission)
+}
+
+// CheckWith tells whether the user is granted the permission in the current policy for a request with
+// the given attributes, which the conditions of the permissions are evaluated against, and, when they are not, why.
+func (evaluator *Evaluator) CheckWith(user string, permission string, attributes condition.Attributes) (*authz.CheckResult, error) {
+	policy := evaluator.provider.Policy()
+	if policy == nil {
+		return nil, errors.New("no policy loaded")
+	}
+
+	return policy.CheckWith(user, permission, attributes)
+}
+
+// Session evaluates the user once against the current policy, for requests checking many permissions.
+func (evaluator *Evaluator) Session(user string) (*authz.Session, error) {
+	result, err := evaluator.Evaluate(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return authz.NewSession(user, result), nil
+}
+
+// HasPermission reports whether the user is granted the permission in the current policy, directly,
+// through an implication or a wildcard, and is not explicitly denied it. The evaluation mode of the policy
+// is ignored, like authz.Policy.HasPermission; use Check for the decision the mode makes.
+func (evaluator *Evaluator) HasPermission(user string, permission string) (bool, error) {
+	policy := evaluator.provider.Policy()
+	if policy == nil {
+		return false, errors.New("no policy loaded")
+	}
+
+	return policy.HasPermission(user, permission)
+}
+
+// IsInGroup reports whether the user is a member of the group in the current policy.
+func (evaluator *Evaluator) IsInGroup(user string, group string) (bool, error) {
+	result, err := evaluator.Evaluate(user)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(result.Groups, group), nil
+}
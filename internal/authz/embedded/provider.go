@@ -0,0 +1,126 @@
+// Package embedded evaluates the authorization policy in process from a local file,
+// for services that cannot depend on the policy store or the network.
+package embedded
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// PolicyProvider supplies the current policy to an Evaluator.
+type PolicyProvider interface {
+	Policy() *authz.Policy
+}
+
+// FileProvider is a PolicyProvider serving a JSON or YAML policy document from disk.
+// Watch reloads the document whenever the file changes; a document that fails to load
+// is logged and the last good policy keeps being served.
+type FileProvider struct {
+	path     string
+	interval time.Duration
+	logger   *slog.Logger
+	policy   atomic.Pointer[authz.Policy]
+	modTime  time.Time
+	size     int64
+	onReload func(policy *authz.Policy)
+	clock    clock.Clock
+}
+
+// FileOption configures optional FileProvider settings.
+type FileOption func(*FileProvider)
+
+// WithPollInterval sets how often Watch checks the file for changes. The default is five seconds.
+func WithPollInterval(interval time.Duration) FileOption {
+	return func(provider *FileProvider) {
+		provider.interval = interval
+	}
+}
+
+// WithReloadHook sets a function called with the new policy after every successful reload.
+func WithReloadHook(hook func(policy *authz.Policy)) FileOption {
+	return func(provider *FileProvider) {
+		provider.onReload = hook
+	}
+}
+
+// NewFileProvider creates a new FileProvider and loads the policy document at the given path.
+// Files with a .yaml or .yml extension are decoded as YAML, any other file as JSON.
+//
+// Returns:
+//
+//	*FileProvider - the provider serving the loaded policy.
+//	error - an error if the document cannot be loaded.
+func NewFileProvider(path string, logger *slog.Logger, options ...FileOption) (*FileProvider, error) {
+	provider := &FileProvider{path: path, interval: 5 * time.Second, logger: logger, clock: clock.System()}
+	for _, option := range options {
+		option(provider)
+	}
+
+	if _, err := provider.Reload(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// Policy returns the last successfully loaded policy.
+func (provider *FileProvider) Policy() *authz.Policy {
+	return provider.policy.Load()
+}
+
+// Reload loads the policy document again when the file changed since the last attempt.
+// It reports whether a new policy was loaded.
+func (provider *FileProvider) Reload() (bool, error) {
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return false, err
+	}
+	if provider.policy.Load() != nil && info.ModTime().Equal(provider.modTime) && info.Size() == provider.size {
+		return false, nil
+	}
+
+	// remember the attempt so a broken document is reported once rather than on every poll
+	provider.modTime = info.ModTime()
+	provider.size = info.Size()
+
+	policy, err := policyfile.Load(provider.path)
+	if err != nil {
+		return false, err
+	}
+
+	provider.policy.Store(policy)
+	if provider.onReload != nil {
+		provider.onReload(policy)
+	}
+	return true, nil
+}
+
+// Watch checks the file for changes every poll interval until the context is done.
+// It must not be called concurrently with Reload.
+func (provider *FileProvider) Watch(ctx context.Context) {
+	ticker := provider.clock.NewTicker(provider.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		reloaded, err := provider.Reload()
+		if err != nil {
+			provider.logger.Error("failed to reload policy file, keeping the previous policy", "path", provider.path, "error", err)
+			continue
+		}
+		if reloaded {
+			provider.logger.Info("policy file reloaded", "path", provider.path)
+		}
+	}
+}
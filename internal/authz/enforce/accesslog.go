@@ -0,0 +1,146 @@
+package enforce
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"google.golang.org/grpc/codes"
+)
+
+// The decisions recorded in the access log.
+const (
+	DecisionAllow           = "allow"
+	DecisionDeny            = "deny"
+	DecisionUnauthenticated = "unauthenticated"
+	DecisionUnavailable     = "unavailable"
+)
+
+// AccessEntry is a line of the access log.
+type AccessEntry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+	// The time taken to serve the request, authorization included, in milliseconds.
+	LatencyMs float64 `json:"latency_ms"`
+	User      string  `json:"user,omitempty"`
+	// The authorization decision, one of the Decision constants, empty when the request was not enforced.
+	Decision   string `json:"decision,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	// The version of the policy the decision was made with, when the Checker reports it.
+	PolicyVersion string `json:"policy_version,omitempty"`
+	// Set when a denied request was let through in shadow mode.
+	Shadow bool `json:"shadow,omitempty"`
+}
+
+// AccessLog writes a JSON line for every request served, annotated with the authorization
+// decision the Enforcer made for it. It is safe for concurrent use.
+type AccessLog struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	clock   clock.Clock
+}
+
+// NewAccessLog creates a new AccessLog writing to w.
+func NewAccessLog(w io.Writer) *AccessLog {
+	return &AccessLog{encoder: json.NewEncoder(w), clock: clock.System()}
+}
+
+// Handler wraps the handler so every request it serves is logged. It must wrap the handlers
+// of the Enforcer, not be wrapped by them, to see the decisions they make.
+func (log *AccessLog) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := log.clock.Now()
+		annotation := &accessAnnotation{}
+		writer := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r.WithContext(context.WithValue(r.Context(), accessAnnotationKey{}, annotation)))
+
+		status := writer.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.write(AccessEntry{
+			Time:          start.UTC(),
+			Method:        r.Method,
+			Path:          r.URL.Path,
+			Status:        status,
+			LatencyMs:     float64(log.clock.Now().Sub(start).Microseconds()) / 1000,
+			User:          annotation.user,
+			Decision:      annotation.decision,
+			Permission:    annotation.permission,
+			PolicyVersion: annotation.policyVersion,
+			Shadow:        annotation.shadow,
+		})
+	})
+}
+
+func (log *AccessLog) write(entry AccessEntry) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	// the access log must not fail the request it describes
+	_ = log.encoder.Encode(entry)
+}
+
+type accessAnnotationKey struct{}
+
+// accessAnnotation is the decision the Enforcer made for a request, filled in for the AccessLog.
+type accessAnnotation struct {
+	user          string
+	decision      string
+	permission    string
+	policyVersion string
+	shadow        bool
+}
+
+// annotate records the decision made for the request in its access log entry, if it is logged.
+func annotate(ctx context.Context, user string, permission string, code codes.Code, policyVersion string, shadow bool) {
+	annotation, ok := ctx.Value(accessAnnotationKey{}).(*accessAnnotation)
+	if !ok {
+		return
+	}
+
+	annotation.user = user
+	annotation.permission = permission
+	annotation.policyVersion = policyVersion
+	annotation.shadow = shadow
+	switch code {
+	case codes.OK:
+		annotation.decision = DecisionAllow
+	case codes.PermissionDenied:
+		annotation.decision = DecisionDeny
+	case codes.Unauthenticated:
+		annotation.decision = DecisionUnauthenticated
+	default:
+		annotation.decision = DecisionUnavailable
+	}
+}
+
+// statusWriter remembers the status of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (writer *statusWriter) WriteHeader(status int) {
+	if writer.status == 0 {
+		writer.status = status
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *statusWriter) Write(content []byte) (int, error) {
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (writer *statusWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
@@ -0,0 +1,95 @@
+package enforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// decisionChecker is a DecisionChecker granting recipes.read to alice with policy version v1.
+type decisionChecker struct {
+	checkerFunc
+}
+
+func (checker decisionChecker) Check(ctx context.Context, user string, permission string) (client.Decision, error) {
+	allowed, err := checker.checkerFunc(ctx, user, permission)
+	return client.Decision{User: user, Permission: permission, Allowed: allowed, PolicyVersion: "v1"}, err
+}
+
+func readAccessLog(t *testing.T, buf *bytes.Buffer) []AccessEntry {
+	var entries []AccessEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry AccessEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestAccessLog_Handler serves granted, denied, anonymous and unenforced requests, checking each is logged with its decision.
+func TestAccessLog_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake := NewFakeClock(now)
+	accessLog := NewAccessLog(&buf)
+	accessLog.clock = fake
+
+	enforcer := NewEnforcer(decisionChecker{grants}, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+	mux := http.NewServeMux()
+	mux.Handle("GET /recipes/{id}", enforcer.Require("recipes.read", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.Advance(1500 * time.Microsecond)
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	handler := accessLog.Handler(mux)
+
+	for _, user := range []string{"alice", "bob", ""} {
+		request := httptest.NewRequest(http.MethodGet, "/recipes/1", nil)
+		if user != "" {
+			request.Header.Set(UserHeader, user)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, []AccessEntry{
+		{Time: now, Method: http.MethodGet, Path: "/recipes/1", Status: http.StatusNoContent, LatencyMs: 1.5,
+			User: "alice", Decision: DecisionAllow, Permission: "recipes.read", PolicyVersion: "v1"},
+		{Time: now.Add(1500 * time.Microsecond), Method: http.MethodGet, Path: "/recipes/1", Status: http.StatusForbidden,
+			User: "bob", Decision: DecisionDeny, Permission: "recipes.read", PolicyVersion: "v1"},
+		{Time: now.Add(1500 * time.Microsecond), Method: http.MethodGet, Path: "/recipes/1", Status: http.StatusUnauthorized,
+			Decision: DecisionUnauthenticated, Permission: "recipes.read"},
+		{Time: now.Add(1500 * time.Microsecond), Method: http.MethodGet, Path: "/healthz", Status: http.StatusOK},
+	}, readAccessLog(t, &buf))
+}
+
+// TestAccessLog_Handler_Shadow serves a denied request in shadow mode with a plain Checker,
+// checking it is logged as a shadow denial without a policy version.
+func TestAccessLog_Handler_Shadow(t *testing.T) {
+	var buf bytes.Buffer
+	enforcer := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)), WithShadow())
+	handler := NewAccessLog(&buf).Handler(enforcer.Require("recipes.write", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	request := httptest.NewRequest(http.MethodDelete, "/recipes/1", nil)
+	request.Header.Set(UserHeader, "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	entries := readAccessLog(t, &buf)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.Equal(t, DecisionDeny, entries[0].Decision)
+	assert.True(t, entries[0].Shadow)
+	assert.Empty(t, entries[0].PolicyVersion)
+	assert.Contains(t, buf.String(), `"latency_ms":`)
+}
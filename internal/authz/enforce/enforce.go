@@ -0,0 +1,252 @@
+// Package enforce is the middleware services put in front of their HTTP handlers and gRPC methods
+// to require authz permissions, checked with the decision API through the client package.
+// In shadow mode denials are only logged, so a service can adopt enforcement route by route
+// and review what would be denied before blocking anyone.
+// An AccessLog around the enforced handlers records the decision made for every request.
+package enforce
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UserHeader carries the authenticated end user, see api.UserHeader.
+const UserHeader = "X-Forwarded-User"
+
+// Checker decides whether a user is granted a permission, such as a client.Client.
+type Checker interface {
+	HasPermission(ctx context.Context, user string, permission string) (bool, error)
+}
+
+// DecisionChecker is a Checker also returning the decision it made, such as a client.Client.
+// The policy version of its decisions is recorded in the AccessLog.
+type DecisionChecker interface {
+	Checker
+	Check(ctx context.Context, user string, permission string) (client.Decision, error)
+}
+
+// Enforcer requires permissions from the callers of HTTP handlers and gRPC methods.
+type Enforcer struct {
+	checker     Checker
+	logger      *slog.Logger
+	shadow      bool
+	httpUser    func(r *http.Request) string
+	contextUser func(ctx context.Context) string
+}
+
+// Option configures optional Enforcer settings.
+type Option func(*Enforcer)
+
+// WithShadow lets every request through, logging the requests that would have been denied.
+func WithShadow() Option {
+	return func(enforcer *Enforcer) {
+		enforcer.shadow = true
+	}
+}
+
+// WithHTTPUser sets how the user is read from HTTP requests.
+// By default it is read from the UserHeader set by the reverse proxy.
+func WithHTTPUser(user func(r *http.Request) string) Option {
+	return func(enforcer *Enforcer) {
+		enforcer.httpUser = user
+	}
+}
+
+// WithContextUser sets how the user is read from the context of gRPC calls.
+// By default it is read from the x-forwarded-user metadata.
+func WithContextUser(user func(ctx context.Context) string) Option {
+	return func(enforcer *Enforcer) {
+		enforcer.contextUser = user
+	}
+}
+
+// NewEnforcer creates a new Enforcer checking permissions with the given checker.
+func NewEnforcer(checker Checker, logger *slog.Logger, options ...Option) *Enforcer {
+	enforcer := &Enforcer{
+		checker:     checker,
+		logger:      logger,
+		httpUser:    func(r *http.Request) string { return r.Header.Get(UserHeader) },
+		contextUser: metadataUser,
+	}
+	for _, option := range options {
+		option(enforcer)
+	}
+	return enforcer
+}
+
+// Require wraps the handler so it only runs when the user of the request is granted the permission.
+// Anonymous requests are refused with 401 and denied ones with 403.
+func (enforcer *Enforcer) Require(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enforcer.serveHTTP(w, r, permission, next)
+	})
+}
+
+// RequireRoutes wraps the handler so every request requires the permission of the route it matches,
+// read from the source on every request so reloaded routes apply at once. See Routes.
+func (enforcer *Enforcer) RequireRoutes(source RouteSource, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes := source.Routes()
+		permission, ok, err := routes.HTTPPermission(r)
+		if !ok && !routes.DenyUnmatched {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			if !enforcer.unresolved(err, "path", r.URL.Path) {
+				writeError(w, http.StatusBadRequest, "request attributes are missing")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		enforcer.serveHTTP(w, r, permission, next)
+	})
+}
+
+// UnaryInterceptor requires the permissions mapped to full gRPC method names, such as
+// "/recipes.v1.Recipes/DeleteRecipe". Methods without a permission are not enforced.
+func (enforcer *Enforcer) UnaryInterceptor(permissions map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		permission, ok := permissions[info.FullMethod]
+		if !ok {
+			return handler(ctx, request)
+		}
+		return enforcer.intercept(ctx, request, info, handler, permission)
+	}
+}
+
+// UnaryRoutesInterceptor requires the permissions the gRPC routes of the source map to the called methods,
+// read on every call so reloaded routes apply at once. See Routes.
+func (enforcer *Enforcer) UnaryRoutesInterceptor(source RouteSource) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		routes := source.Routes()
+		permission, ok, err := routes.GRPCPermission(info.FullMethod, request)
+		if !ok && !routes.DenyUnmatched {
+			return handler(ctx, request)
+		}
+		if err != nil {
+			if !enforcer.unresolved(err, "method", info.FullMethod) {
+				return nil, status.Error(codes.InvalidArgument, "request attributes are missing")
+			}
+			return handler(ctx, request)
+		}
+		return enforcer.intercept(ctx, request, info, handler, permission)
+	}
+}
+
+// serveHTTP serves the request when the user is granted the permission, an empty permission is never granted.
+func (enforcer *Enforcer) serveHTTP(w http.ResponseWriter, r *http.Request, permission string, next http.Handler) {
+	switch enforcer.check(r.Context(), enforcer.httpUser(r), permission, "path", r.URL.Path) {
+	case codes.OK:
+		next.ServeHTTP(w, r)
+	case codes.Unauthenticated:
+		writeError(w, http.StatusUnauthorized, "authentication required")
+	case codes.PermissionDenied:
+		writeError(w, http.StatusForbidden, "permission denied")
+	default:
+		writeError(w, http.StatusServiceUnavailable, "authorization is unavailable")
+	}
+}
+
+// intercept calls the method when the user is granted the permission, an empty permission is never granted.
+func (enforcer *Enforcer) intercept(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler, permission string) (any, error) {
+	switch code := enforcer.check(ctx, enforcer.contextUser(ctx), permission, "method", info.FullMethod); code {
+	case codes.OK:
+		return handler(ctx, request)
+	case codes.Unauthenticated:
+		return nil, status.Error(code, "authentication required")
+	case codes.PermissionDenied:
+		return nil, status.Error(code, "permission denied")
+	default:
+		return nil, status.Error(code, "authorization is unavailable")
+	}
+}
+
+// check decides whether the request may proceed, returning codes.OK when it may.
+// In shadow mode every request may proceed and the would-be denials are logged instead.
+func (enforcer *Enforcer) check(ctx context.Context, user string, permission string, target string, name string) codes.Code {
+	code, policyVersion, err := enforcer.decide(ctx, user, permission)
+	annotate(ctx, user, permission, code, policyVersion, enforcer.shadow && code != codes.OK)
+	if code == codes.OK {
+		return code
+	}
+
+	attributes := []any{"user", user, "permission", permission, target, name}
+	if err != nil {
+		attributes = append(attributes, "error", err)
+	}
+	if enforcer.shadow {
+		enforcer.logger.Warn("request would have been denied", append(attributes, "code", code.String())...)
+		return codes.OK
+	}
+	if err != nil {
+		enforcer.logger.Error("failed to check permission", attributes...)
+	} else {
+		enforcer.logger.Warn("request denied", attributes...)
+	}
+	return code
+}
+
+// unresolved logs a request whose permission could not be resolved from its attributes,
+// reporting whether it may proceed anyway, which it only may in shadow mode.
+func (enforcer *Enforcer) unresolved(err error, target string, name string) bool {
+	if enforcer.shadow {
+		enforcer.logger.Warn("request would have been refused", "error", err, target, name)
+		return true
+	}
+	enforcer.logger.Warn("request refused", "error", err, target, name)
+	return false
+}
+
+// decide checks the permission of the user, returning the policy version of the decision
+// when the checker is a DecisionChecker.
+func (enforcer *Enforcer) decide(ctx context.Context, user string, permission string) (codes.Code, string, error) {
+	if user == "" {
+		return codes.Unauthenticated, "", nil
+	}
+	// the request matches no route while unmatched requests are denied
+	if permission == "" {
+		return codes.PermissionDenied, "", nil
+	}
+
+	var allowed bool
+	var policyVersion string
+	var err error
+	if checker, ok := enforcer.checker.(DecisionChecker); ok {
+		var decision client.Decision
+		decision, err = checker.Check(ctx, user, permission)
+		allowed, policyVersion = decision.Allowed, decision.PolicyVersion
+	} else {
+		allowed, err = enforcer.checker.HasPermission(ctx, user, permission)
+	}
+	if err != nil {
+		return codes.Unavailable, "", err
+	}
+	if !allowed {
+		return codes.PermissionDenied, policyVersion, nil
+	}
+	return codes.OK, policyVersion, nil
+}
+
+// metadataUser reads the user from the x-forwarded-user metadata of the incoming call.
+func metadataUser(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, "x-forwarded-user")
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(`{"error":"` + message + `"}` + "\n"))
+}
@@ -0,0 +1,120 @@
+package enforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the decision API client is the checker of services
+var _ DecisionChecker = (*client.Client)(nil)
+
+// checkerFunc adapts a function to the Checker interface.
+type checkerFunc func(ctx context.Context, user string, permission string) (bool, error)
+
+func (check checkerFunc) HasPermission(ctx context.Context, user string, permission string) (bool, error) {
+	return check(ctx, user, permission)
+}
+
+// grants is a Checker granting recipes.read to alice.
+var grants = checkerFunc(func(ctx context.Context, user string, permission string) (bool, error) {
+	return user == "alice" && permission == "recipes.read", nil
+})
+
+func serveRequire(enforcer *Enforcer, user string) (*httptest.ResponseRecorder, bool) {
+	served := false
+	handler := enforcer.Require("recipes.read", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/recipes/1", nil)
+	if user != "" {
+		request.Header.Set(UserHeader, user)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder, served
+}
+
+// TestEnforcer_Require sends requests of various users, checking only granted ones are served.
+func TestEnforcer_Require(t *testing.T) {
+	enforcer := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+
+	response, served := serveRequire(enforcer, "alice")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, served)
+
+	response, served = serveRequire(enforcer, "bob")
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.False(t, served)
+
+	response, served = serveRequire(enforcer, "")
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.False(t, served)
+}
+
+// TestEnforcer_Require_CheckerError checks a permission while the decision API fails, checking the request is refused.
+func TestEnforcer_Require_CheckerError(t *testing.T) {
+	failing := checkerFunc(func(context.Context, string, string) (bool, error) { return false, errors.New("connection refused") })
+	enforcer := NewEnforcer(failing, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+
+	response, served := serveRequire(enforcer, "alice")
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.False(t, served)
+}
+
+// TestEnforcer_Require_Shadow sends a denied request in shadow mode, checking it is served and the denial logged.
+func TestEnforcer_Require_Shadow(t *testing.T) {
+	logs := new(bytes.Buffer)
+	enforcer := NewEnforcer(grants, slog.New(slog.NewTextHandler(logs, nil)), WithShadow())
+
+	response, served := serveRequire(enforcer, "bob")
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, served)
+	assert.Contains(t, logs.String(), `msg="request would have been denied" user=bob permission=recipes.read path=/recipes/1 code=PermissionDenied`)
+
+	logs.Reset()
+	serveRequire(enforcer, "alice")
+	assert.Empty(t, logs.String())
+}
+
+// TestEnforcer_UnaryInterceptor calls gRPC methods as various users, checking only mapped methods are enforced.
+func TestEnforcer_UnaryInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, request any) (any, error) { return "served", nil }
+	call := func(enforcer *Enforcer, method string, user string) (any, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-user", user))
+		interceptor := enforcer.UnaryInterceptor(map[string]string{"/recipes.v1.Recipes/GetRecipe": "recipes.read"})
+		return interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+	enforcer := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+
+	response, err := call(enforcer, "/recipes.v1.Recipes/GetRecipe", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "served", response)
+
+	_, err = call(enforcer, "/recipes.v1.Recipes/GetRecipe", "bob")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = call(enforcer, "/recipes.v1.Recipes/GetRecipe", "")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	response, err = call(enforcer, "/recipes.v1.Recipes/ListRecipes", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "served", response)
+
+	shadow := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)), WithShadow())
+	response, err = call(shadow, "/recipes.v1.Recipes/GetRecipe", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "served", response)
+}
@@ -0,0 +1,105 @@
+package enforce
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// RouteSource supplies the current routes to the middleware.
+type RouteSource interface {
+	Routes() *Routes
+}
+
+// RouteFile is a RouteSource serving a YAML routes document from disk.
+// Watch reloads the document whenever the file changes; a document that fails to load
+// is logged and the last good routes keep being enforced.
+type RouteFile struct {
+	path     string
+	interval time.Duration
+	logger   *slog.Logger
+	routes   atomic.Pointer[Routes]
+	modTime  time.Time
+	size     int64
+	clock    clock.Clock
+}
+
+// RouteFileOption configures optional RouteFile settings.
+type RouteFileOption func(*RouteFile)
+
+// WithPollInterval sets how often Watch checks the file for changes. The default is five seconds.
+func WithPollInterval(interval time.Duration) RouteFileOption {
+	return func(file *RouteFile) {
+		file.interval = interval
+	}
+}
+
+// NewRouteFile creates a new RouteFile and loads the routes document at the given path.
+func NewRouteFile(path string, logger *slog.Logger, options ...RouteFileOption) (*RouteFile, error) {
+	file := &RouteFile{path: path, interval: 5 * time.Second, logger: logger, clock: clock.System()}
+	for _, option := range options {
+		option(file)
+	}
+
+	if _, err := file.Reload(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Routes returns the last successfully loaded routes.
+func (file *RouteFile) Routes() *Routes {
+	return file.routes.Load()
+}
+
+// Reload loads the routes document again when the file changed since the last attempt.
+// It reports whether new routes were loaded.
+func (file *RouteFile) Reload() (bool, error) {
+	info, err := os.Stat(file.path)
+	if err != nil {
+		return false, err
+	}
+	if file.routes.Load() != nil && info.ModTime().Equal(file.modTime) && info.Size() == file.size {
+		return false, nil
+	}
+
+	// remember the attempt so a broken document is reported once rather than on every poll
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	routes, err := LoadRoutes(file.path)
+	if err != nil {
+		return false, err
+	}
+
+	file.routes.Store(routes)
+	return true, nil
+}
+
+// Watch checks the file for changes every poll interval until the context is done.
+// It must not be called concurrently with Reload.
+func (file *RouteFile) Watch(ctx context.Context) {
+	ticker := file.clock.NewTicker(file.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		reloaded, err := file.Reload()
+		if err != nil {
+			file.logger.Error("failed to reload routes file, keeping the previous routes", "path", file.path, "error", err)
+			continue
+		}
+		if reloaded {
+			file.logger.Info("routes file reloaded", "path", file.path)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package enforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Routes maps the routes of a service to the permissions they require, so new routes are
+// enforced by editing a document rather than the code of the service. For example:
+//
+//	http:
+//	  - route: GET /recipes/{id}
+//	    permission: recipes.read
+//	  - route: DELETE /recipes/{id}
+//	    permission: recipes.delete
+//	grpc:
+//	  - method: /recipes.v1.Recipes/GetRecipe
+//	    permission: recipes.read
+//	deny_unmatched: true
+//
+// HTTP routes use the patterns of http.ServeMux, the most specific pattern matching a request wins.
+//
+// Permissions may read attributes of the request, so routes enforce object level permissions
+// such as "recipes.42.edit": {path.name} reads a wildcard of the pattern, {query.name} a query
+// parameter and {body.name} a field of the JSON body, nested fields and array elements being
+// selected with dots such as {body.recipe.id}. gRPC permissions only read the request message
+// with {body.name}, using the proto field names. Requests lacking an attribute are refused.
+type Routes struct {
+	HTTP []HTTPRoute `yaml:"http"`
+	GRPC []GRPCRoute `yaml:"grpc"`
+	// DenyUnmatched refuses the requests matching no route. By default they are not enforced.
+	DenyUnmatched bool `yaml:"deny_unmatched"`
+
+	mux     *http.ServeMux
+	methods map[string]*template
+}
+
+// HTTPRoute requires a permission for the HTTP requests matching a pattern, such as "GET /recipes/{id}".
+type HTTPRoute struct {
+	Route      string `yaml:"route"`
+	Permission string `yaml:"permission"`
+}
+
+// GRPCRoute requires a permission for the calls of a full gRPC method name, such as "/recipes.v1.Recipes/GetRecipe".
+type GRPCRoute struct {
+	Method     string `yaml:"method"`
+	Permission string `yaml:"permission"`
+}
+
+// maxBodySize is the size of the request bodies permission templates read attributes from.
+const maxBodySize = 1 << 20
+
+// routePermission marks the permission of an HTTP route in the mux matching requests.
+type routePermission struct {
+	permission *template
+}
+
+func (*routePermission) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+// ParseRoutes decodes and validates a YAML routes document from the given reader.
+func ParseRoutes(r io.Reader) (*Routes, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	routes := &Routes{}
+	if err := decoder.Decode(routes); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode routes document: %w", err)
+	}
+	if err := routes.compile(); err != nil {
+		return nil, fmt.Errorf("validate routes document: %w", err)
+	}
+	return routes, nil
+}
+
+// LoadRoutes reads the YAML routes document stored at the given path.
+func LoadRoutes(path string) (*Routes, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRoutes(file)
+}
+
+// compile validates the routes and builds the structures matching requests against them.
+func (routes *Routes) compile() error {
+	routes.mux = http.NewServeMux()
+	for _, route := range routes.HTTP {
+		if route.Permission == "" {
+			return fmt.Errorf("route %q has no permission", route.Route)
+		}
+		permission, err := parseTemplate(route.Permission, sourcePath, sourceQuery, sourceBody)
+		if err != nil {
+			return fmt.Errorf("route %q: %w", route.Route, err)
+		}
+		if err := handle(routes.mux, route.Route, permission); err != nil {
+			return err
+		}
+	}
+
+	routes.methods = make(map[string]*template, len(routes.GRPC))
+	for _, route := range routes.GRPC {
+		if !strings.HasPrefix(route.Method, "/") || strings.Count(route.Method, "/") != 2 {
+			return fmt.Errorf("method %q is not a full gRPC method name such as /package.Service/Method", route.Method)
+		}
+		if route.Permission == "" {
+			return fmt.Errorf("method %q has no permission", route.Method)
+		}
+		if _, exists := routes.methods[route.Method]; exists {
+			return fmt.Errorf("method %q is mapped more than once", route.Method)
+		}
+		permission, err := parseTemplate(route.Permission, sourceBody)
+		if err != nil {
+			return fmt.Errorf("method %q: %w", route.Method, err)
+		}
+		routes.methods[route.Method] = permission
+	}
+	return nil
+}
+
+// handle registers the route, reporting the invalid and conflicting patterns http.ServeMux panics on.
+func handle(mux *http.ServeMux, pattern string, permission *template) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("route %q: %v", pattern, recovered)
+		}
+	}()
+
+	mux.Handle(pattern, &routePermission{permission: permission})
+	return nil
+}
+
+// Routes returns the routes themselves, so fixed routes can be used as a RouteSource.
+func (routes *Routes) Routes() *Routes {
+	return routes
+}
+
+// HTTPPermission returns the permission required by the route matching the request, if any,
+// with the attributes of the request in place of its placeholders. Reading the body leaves it
+// unchanged for the handler.
+func (routes *Routes) HTTPPermission(r *http.Request) (string, bool, error) {
+	if routes.mux == nil {
+		return "", false, nil
+	}
+	handler, pattern := routes.mux.Handler(r)
+	route, ok := handler.(*routePermission)
+	if !ok {
+		return "", false, nil
+	}
+	if !route.permission.dynamic() {
+		return route.permission.raw, true, nil
+	}
+
+	var body []byte
+	if route.permission.reads(sourceBody) && r.Body != nil {
+		read, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			return "", true, fmt.Errorf("read request body: %w", err)
+		}
+		body = read
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	}
+
+	var values map[string]string
+	permission, err := route.permission.expand(func(source string, path string) (string, bool) {
+		switch source {
+		case sourcePath:
+			if values == nil {
+				values = pathValues(pattern, r.URL.EscapedPath())
+			}
+			value, ok := values[path]
+			return value, ok
+		case sourceQuery:
+			return r.URL.Query().Get(path), true
+		default:
+			return jsonValue(body, path)
+		}
+	})
+	return permission, true, err
+}
+
+// GRPCPermission returns the permission required by the full gRPC method name, if any,
+// with the fields of the request message in place of its placeholders.
+func (routes *Routes) GRPCPermission(method string, request any) (string, bool, error) {
+	route, ok := routes.methods[method]
+	if !ok {
+		return "", false, nil
+	}
+	if !route.dynamic() {
+		return route.raw, true, nil
+	}
+
+	var body []byte
+	var err error
+	if message, ok := request.(proto.Message); ok {
+		body, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	} else {
+		body, err = json.Marshal(request)
+	}
+	if err != nil {
+		return "", true, fmt.Errorf("encode request message: %w", err)
+	}
+
+	permission, err := route.expand(func(source string, path string) (string, bool) {
+		return jsonValue(body, path)
+	})
+	return permission, true, err
+}
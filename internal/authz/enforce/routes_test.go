@@ -0,0 +1,260 @@
+package enforce
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testRoutes = `
+http:
+  - route: GET /recipes/{id}
+    permission: recipes.read
+  - route: DELETE /recipes/{id}
+    permission: recipes.delete
+  - route: /admin/
+    permission: recipes.admin
+grpc:
+  - method: /recipes.v1.Recipes/GetRecipe
+    permission: recipes.read
+`
+
+func parseTestRoutes(t *testing.T, document string) *Routes {
+	routes, err := ParseRoutes(strings.NewReader(document))
+	assert.NoError(t, err)
+	return routes
+}
+
+// TestRoutes_HTTPPermission matches requests against the routes, checking the permission of the most specific route is returned.
+func TestRoutes_HTTPPermission(t *testing.T) {
+	routes := parseTestRoutes(t, testRoutes)
+
+	tests := []struct {
+		method     string
+		target     string
+		permission string
+		matched    bool
+	}{
+		{http.MethodGet, "/recipes/1", "recipes.read", true},
+		{http.MethodHead, "/recipes/1", "recipes.read", true},
+		{http.MethodDelete, "/recipes/1", "recipes.delete", true},
+		{http.MethodPost, "/recipes/1", "", false},
+		{http.MethodPost, "/admin/users/5", "recipes.admin", true},
+		{http.MethodGet, "/health", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			permission, matched, err := routes.HTTPPermission(httptest.NewRequest(test.method, test.target, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, test.permission, permission)
+			assert.Equal(t, test.matched, matched)
+		})
+	}
+
+	permission, matched, err := routes.GRPCPermission("/recipes.v1.Recipes/GetRecipe", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "recipes.read", permission)
+	assert.True(t, matched)
+}
+
+// TestParseRoutes_Error parses invalid routes documents, checking for an error.
+func TestParseRoutes_Error(t *testing.T) {
+	tests := map[string]struct {
+		document string
+		message  string
+	}{
+		"unknown field":       {"routes: []", "decode routes document"},
+		"missing permission":  {"http:\n  - route: GET /recipes\n", `route "GET /recipes" has no permission`},
+		"invalid pattern":     {"http:\n  - route: GET recipes\n    permission: recipes.read\n", `route "GET recipes"`},
+		"conflicting pattern": {"http:\n  - route: GET /recipes\n    permission: a\n  - route: GET /recipes\n    permission: b\n", "conflicts"},
+		"invalid method":      {"grpc:\n  - method: GetRecipe\n    permission: recipes.read\n", "not a full gRPC method name"},
+		"invalid placeholder": {"http:\n  - route: GET /recipes/{id}\n    permission: recipes.{id}.read\n", "invalid placeholder {id}"},
+		"grpc path attribute": {"grpc:\n  - method: /a.B/C\n    permission: a.{path.id}\n", "invalid placeholder {path.id}"},
+		"unterminated":        {"http:\n  - route: GET /recipes/{id}\n    permission: recipes.{path.id\n", "unterminated placeholder"},
+		"duplicate method":    {"grpc:\n  - method: /a.B/C\n    permission: a\n  - method: /a.B/C\n    permission: b\n", "mapped more than once"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			routes, err := ParseRoutes(strings.NewReader(test.document))
+			assert.ErrorContains(t, err, test.message)
+			assert.Nil(t, routes)
+		})
+	}
+}
+
+// TestEnforcer_RequireRoutes sends requests through the routes middleware, checking each requires the permission of its route.
+func TestEnforcer_RequireRoutes(t *testing.T) {
+	serve := func(routes *Routes, method string, target string, user string) int {
+		handler := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))).
+			RequireRoutes(routes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		request := httptest.NewRequest(method, target, nil)
+		request.Header.Set(UserHeader, user)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	routes := parseTestRoutes(t, testRoutes)
+
+	assert.Equal(t, http.StatusOK, serve(routes, http.MethodGet, "/recipes/1", "alice"))
+	assert.Equal(t, http.StatusForbidden, serve(routes, http.MethodDelete, "/recipes/1", "alice"))
+	assert.Equal(t, http.StatusOK, serve(routes, http.MethodGet, "/health", "bob"))
+
+	routes = parseTestRoutes(t, testRoutes+"deny_unmatched: true\n")
+	assert.Equal(t, http.StatusForbidden, serve(routes, http.MethodGet, "/health", "alice"))
+}
+
+// TestEnforcer_UnaryRoutesInterceptor calls gRPC methods through the routes interceptor, checking mapped methods are enforced.
+func TestEnforcer_UnaryRoutesInterceptor(t *testing.T) {
+	routes := parseTestRoutes(t, testRoutes)
+	interceptor := NewEnforcer(grants, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil))).UnaryRoutesInterceptor(routes)
+	handler := func(ctx context.Context, request any) (any, error) { return "served", nil }
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-user", "bob"))
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/recipes.v1.Recipes/GetRecipe"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	response, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/recipes.v1.Recipes/ListRecipes"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "served", response)
+}
+
+// TestRoutes_HTTPPermission_Attributes matches requests against routes reading their attributes,
+// checking the attributes are expanded in the permission and the body is left for the handler.
+func TestRoutes_HTTPPermission_Attributes(t *testing.T) {
+	routes := parseTestRoutes(t, `
+http:
+  - route: PUT /books/{book}/recipes/{id}
+    permission: recipes.{path.book}.{path.id}.edit
+  - route: GET /files/{name...}
+    permission: files.{path.name}.read
+  - route: GET /search
+    permission: books.{query.book}.search
+  - route: POST /recipes
+    permission: books.{body.recipe.book}.create
+`)
+
+	tests := []struct {
+		method     string
+		target     string
+		body       string
+		permission string
+		err        string
+	}{
+		{http.MethodPut, "/books/7/recipes/42", "", "recipes.7.42.edit", ""},
+		{http.MethodGet, "/files/menu", "", "files.menu.read", ""},
+		{http.MethodGet, "/files/a/b", "", "files.a/b.read", ""},
+		{http.MethodGet, "/files/a.b", "", "", `path attribute "name" has an invalid value "a.b"`},
+		{http.MethodGet, "/search?book=7", "", "books.7.search", ""},
+		{http.MethodGet, "/search", "", "", `request has no query attribute "book"`},
+		{http.MethodGet, "/search?book=7.x", "", "", `query attribute "book" has an invalid value "7.x"`},
+		{http.MethodPost, "/recipes", `{"recipe":{"book":12345678901234567890}}`, "books.12345678901234567890.create", ""},
+		{http.MethodPost, "/recipes", `{"recipe":{}}`, "", `request has no body attribute "recipe.book"`},
+		{http.MethodPost, "/recipes", `not json`, "", `request has no body attribute "recipe.book"`},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target+" "+test.body, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			permission, matched, err := routes.HTTPPermission(request)
+			assert.True(t, matched)
+			assert.Equal(t, test.permission, permission)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			body, _ := io.ReadAll(request.Body)
+			assert.Equal(t, test.body, string(body))
+		})
+	}
+}
+
+// TestRoutes_GRPCPermission_Attributes calls gRPC methods with routes reading the request message, checking the fields are expanded.
+func TestRoutes_GRPCPermission_Attributes(t *testing.T) {
+	routes := parseTestRoutes(t, "grpc:\n  - method: /recipes.v1.Recipes/GetRecipe\n    permission: recipes.{body.ids.0}.read\n")
+
+	permission, matched, err := routes.GRPCPermission("/recipes.v1.Recipes/GetRecipe", map[string]any{"ids": []int{42}})
+	assert.True(t, matched)
+	assert.NoError(t, err)
+	assert.Equal(t, "recipes.42.read", permission)
+
+	_, _, err = routes.GRPCPermission("/recipes.v1.Recipes/GetRecipe", map[string]any{"ids": []int{}})
+	assert.ErrorContains(t, err, `request has no body attribute "ids.0"`)
+}
+
+// TestEnforcer_RequireRoutes_Attributes sends requests through routes reading their attributes,
+// checking the object level permission is required and requests lacking attributes are refused.
+func TestEnforcer_RequireRoutes_Attributes(t *testing.T) {
+	checker := checkerFunc(func(ctx context.Context, user string, permission string) (bool, error) {
+		return permission == "recipes.42.edit", nil
+	})
+	routes := parseTestRoutes(t, "http:\n  - route: PUT /recipes/{id}\n    permission: recipes.{path.id}.edit\n  - route: POST /recipes\n    permission: books.{body.book}.create\n")
+	serve := func(enforcer *Enforcer, method string, target string) int {
+		handler := enforcer.RequireRoutes(routes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		request := httptest.NewRequest(method, target, strings.NewReader(`{}`))
+		request.Header.Set(UserHeader, "alice")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	enforcer := NewEnforcer(checker, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+
+	assert.Equal(t, http.StatusOK, serve(enforcer, http.MethodPut, "/recipes/42"))
+	assert.Equal(t, http.StatusForbidden, serve(enforcer, http.MethodPut, "/recipes/43"))
+	assert.Equal(t, http.StatusBadRequest, serve(enforcer, http.MethodPost, "/recipes"))
+
+	shadow := NewEnforcer(checker, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)), WithShadow())
+	assert.Equal(t, http.StatusOK, serve(shadow, http.MethodPost, "/recipes"))
+}
+
+// TestRouteFile_Reload changes the routes file, checking the new routes are loaded and a broken document keeps the previous ones.
+func TestRouteFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	modified := time.Now().Add(-time.Hour)
+	write := func(document string) {
+		assert.NoError(t, os.WriteFile(path, []byte(document), 0o600))
+		modified = modified.Add(time.Minute)
+		assert.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	write(testRoutes)
+
+	file, err := NewRouteFile(path, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+	assert.NoError(t, err)
+	assert.Len(t, file.Routes().HTTP, 3)
+
+	reloaded, err := file.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	write("http:\n  - route: GET /recipes\n    permission: recipes.read\n")
+	reloaded, err = file.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Len(t, file.Routes().HTTP, 1)
+
+	write("http: [")
+	_, err = file.Reload()
+	assert.Error(t, err)
+	assert.Len(t, file.Routes().HTTP, 1)
+}
+
+// TestNewRouteFile_Error_MissingFile creates a RouteFile for a missing file, checking for an error.
+func TestNewRouteFile_Error_MissingFile(t *testing.T) {
+	file, err := NewRouteFile(filepath.Join(t.TempDir(), "missing.yaml"), slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+	assert.Error(t, err)
+	assert.Nil(t, file)
+}
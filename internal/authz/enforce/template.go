@@ -0,0 +1,168 @@
+package enforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sources of the attributes a permission template reads.
+const (
+	// sourcePath reads a wildcard of the route pattern, such as {path.id} for "GET /recipes/{id}".
+	sourcePath = "path"
+	// sourceQuery reads a query parameter, such as {query.book}.
+	sourceQuery = "query"
+	// sourceBody reads a field of the JSON request body, or of the gRPC request message,
+	// such as {body.recipe.id} or {body.items.0}.
+	sourceBody = "body"
+)
+
+// template is a permission name with placeholders expanded from the attributes of each request,
+// such as "recipes.{path.id}.edit", so route level enforcement can check object level permissions.
+type template struct {
+	raw      string
+	segments []segment
+}
+
+// segment is either literal text or a placeholder reading the attribute at path in source.
+type segment struct {
+	literal string
+	source  string
+	path    string
+}
+
+// parseTemplate parses the placeholders of the permission, which may only read the given sources.
+func parseTemplate(permission string, sources ...string) (*template, error) {
+	parsed := &template{raw: permission}
+	rest := permission
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			parsed.segments = append(parsed.segments, segment{literal: rest})
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("permission %q has an unterminated placeholder", permission)
+		}
+		if start > 0 {
+			parsed.segments = append(parsed.segments, segment{literal: rest[:start]})
+		}
+
+		placeholder := rest[start+1 : start+end]
+		source, path, _ := strings.Cut(placeholder, ".")
+		if path == "" || !slices.Contains(sources, source) {
+			return nil, fmt.Errorf("permission %q has an invalid placeholder {%s}, expected {%s.name}", permission, placeholder, strings.Join(sources, ".name} or {"))
+		}
+		parsed.segments = append(parsed.segments, segment{source: source, path: path})
+		rest = rest[start+end+1:]
+	}
+	return parsed, nil
+}
+
+// dynamic reports whether the template has placeholders.
+func (parsed *template) dynamic() bool {
+	for _, segment := range parsed.segments {
+		if segment.source != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// reads reports whether a placeholder of the template reads the source.
+func (parsed *template) reads(source string) bool {
+	for _, segment := range parsed.segments {
+		if segment.source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// expand replaces the placeholders with the attributes returned by lookup.
+// Attributes containing a dot are refused so a request cannot select another permission.
+func (parsed *template) expand(lookup func(source string, path string) (string, bool)) (string, error) {
+	var builder strings.Builder
+	for _, segment := range parsed.segments {
+		if segment.source == "" {
+			builder.WriteString(segment.literal)
+			continue
+		}
+		value, ok := lookup(segment.source, segment.path)
+		if !ok || value == "" {
+			return "", fmt.Errorf("request has no %s attribute %q", segment.source, segment.path)
+		}
+		if strings.ContainsAny(value, ".{}") {
+			return "", fmt.Errorf("%s attribute %q has an invalid value %q", segment.source, segment.path, value)
+		}
+		builder.WriteString(value)
+	}
+	return builder.String(), nil
+}
+
+// jsonValue returns the scalar at the dotted path of the JSON document, array elements being selected by index.
+func jsonValue(document []byte, path string) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", false
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]any:
+			value = current[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return "", false
+			}
+			value = current[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch scalar := value.(type) {
+	case string:
+		return scalar, true
+	case json.Number:
+		return scalar.String(), true
+	case bool:
+		return strconv.FormatBool(scalar), true
+	default:
+		return "", false
+	}
+}
+
+// pathValues returns the values of the wildcards of the http.ServeMux pattern in the escaped path it matched.
+func pathValues(pattern string, escapedPath string) map[string]string {
+	if _, rest, found := strings.Cut(pattern, " "); found {
+		pattern = rest
+	}
+	pattern = pattern[strings.IndexByte(pattern, '/'):]
+
+	values := map[string]string{}
+	segments := strings.Split(escapedPath, "/")
+	for i, wildcard := range strings.Split(pattern, "/") {
+		if !strings.HasPrefix(wildcard, "{") || wildcard == "{$}" || i >= len(segments) {
+			continue
+		}
+		name := strings.Trim(wildcard, "{}")
+		value := segments[i]
+		if remainder, found := strings.CutSuffix(name, "..."); found {
+			name = remainder
+			value = strings.Join(segments[i:], "/")
+		}
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			values[name] = unescaped
+		}
+	}
+	return values
+}
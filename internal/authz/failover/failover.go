@@ -0,0 +1,100 @@
+// Package failover automates the disaster recovery procedure of the policy store: promoting the
+// standby database, checking it holds the expected policy, re-pointing the service at it and
+// waiting for the service to resume. Every step is plain code, so the procedure is tested rather
+// than written down and the outcome of a failover is reported step by step.
+package failover
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// Step is one step of a Runbook.
+type Step struct {
+	Name string
+	// Run performs the step and returns a short description of what it did.
+	Run func(ctx context.Context) (string, error)
+}
+
+// StepStatus tells how a step of a run ended.
+type StepStatus string
+
+const (
+	// StepDone means the step succeeded.
+	StepDone StepStatus = "done"
+	// StepFailed means the step failed and the run stopped.
+	StepFailed StepStatus = "failed"
+	// StepSkipped means the step did not run because an earlier step failed.
+	StepSkipped StepStatus = "skipped"
+)
+
+// StepResult is the outcome of a step of a run.
+type StepResult struct {
+	Name    string        `json:"name"`
+	Status  StepStatus    `json:"status"`
+	Detail  string        `json:"detail,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Report is the outcome of every step of a run, in order.
+type Report struct {
+	// The identifier of the run, see id.New, logged with every step.
+	ID    string       `json:"id"`
+	Steps []StepResult `json:"steps"`
+}
+
+// OK reports whether every step succeeded.
+func (report *Report) OK() bool {
+	for _, step := range report.Steps {
+		if step.Status != StepDone {
+			return false
+		}
+	}
+	return true
+}
+
+// Runbook runs the steps of a failover in order, stopping at the first failure
+// so no step runs on the outcome of a failed one.
+type Runbook struct {
+	steps  []Step
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// NewRunbook creates a new Runbook running the given steps.
+func NewRunbook(logger *slog.Logger, steps ...Step) *Runbook {
+	return &Runbook{steps: steps, logger: logger, clock: clock.System()}
+}
+
+// Run runs the steps and reports their outcome. The error is the one of the failed step, if any.
+func (runbook *Runbook) Run(ctx context.Context) (*Report, error) {
+	report := &Report{ID: id.New()}
+	logger := runbook.logger.With("run_id", report.ID)
+	var failure error
+	for _, step := range runbook.steps {
+		if failure != nil {
+			report.Steps = append(report.Steps, StepResult{Name: step.Name, Status: StepSkipped})
+			continue
+		}
+
+		start := runbook.clock.Now()
+		detail, err := step.Run(ctx)
+		result := StepResult{Name: step.Name, Status: StepDone, Detail: detail, Elapsed: runbook.clock.Now().Sub(start)}
+		if err != nil {
+			result.Status = StepFailed
+			result.Error = err.Error()
+			failure = fmt.Errorf("%s: %w", step.Name, err)
+			logger.Error("failover step failed", "step", step.Name, "error", err)
+		} else {
+			logger.Info("failover step done", "step", step.Name, "detail", detail)
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	return report, failure
+}
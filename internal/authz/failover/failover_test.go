@@ -0,0 +1,203 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func step(name string, err error, ran *[]string) Step {
+	return Step{Name: name, Run: func(ctx context.Context) (string, error) {
+		*ran = append(*ran, name)
+		return name + " ran", err
+	}}
+}
+
+// TestRunbook_Run runs a runbook whose second step fails, checking the later steps are skipped.
+func TestRunbook_Run(t *testing.T) {
+	var ran []string
+	runbook := NewRunbook(discardLogger(), step("promote", nil, &ran), step("verify-policy", errors.New("mismatch"), &ran), step("repoint", nil, &ran))
+
+	report, err := runbook.Run(context.Background())
+	assert.EqualError(t, err, "verify-policy: mismatch")
+	assert.Equal(t, []string{"promote", "verify-policy"}, ran)
+	assert.False(t, report.OK())
+	assert.Equal(t, []StepStatus{StepDone, StepFailed, StepSkipped},
+		[]StepStatus{report.Steps[0].Status, report.Steps[1].Status, report.Steps[2].Status})
+	assert.Equal(t, "promote ran", report.Steps[0].Detail)
+	assert.Equal(t, "mismatch", report.Steps[1].Error)
+	_, err = id.Time(report.ID)
+	assert.NoError(t, err)
+
+	ran = nil
+	report, err = NewRunbook(discardLogger(), step("promote", nil, &ran)).Run(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+}
+
+// TestPromote promotes databases in and out of recovery, checking only standbys are promoted.
+func TestPromote(t *testing.T) {
+	ctx := context.Background()
+	recovery := func(mockDb *MockPgDb, recovering bool) {
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, "SELECT pg_is_in_recovery()", []any(nil)).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*bool)) = recovering
+		}).Return(nil)
+	}
+
+	t.Run("standby", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		recovery(mockDb, true)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, "SELECT pg_promote(true, $1)", []any{60}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*bool)) = true
+		}).Return(nil)
+
+		detail, err := Promote(mockDb, time.Minute).Run(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "the standby database was promoted", detail)
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("already primary", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		recovery(mockDb, false)
+
+		detail, err := Promote(mockDb, time.Minute).Run(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "the database is already a primary", detail)
+		mockDb.AssertNotCalled(t, "QueryRow", ctx, "SELECT pg_promote(true, $1)", mock.Anything)
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		recovery(mockDb, true)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, "SELECT pg_promote(true, $1)", []any{60}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil)
+
+		_, err := Promote(mockDb, time.Minute).Run(ctx)
+		assert.EqualError(t, err, "the promotion did not complete within 1m0s")
+	})
+}
+
+// TestVerifyPolicy compares the policies of the promoted store and the reference, checking a difference fails.
+func TestVerifyPolicy(t *testing.T) {
+	ctx := context.Background()
+	reader := func(users ...string) PolicyReader {
+		return PolicyReaderFunc(func(ctx context.Context) (*authz.Policy, error) {
+			return authz.NewPolicy(nil, []authz.Group{*authz.NewGroup("admins", users)}), nil
+		})
+	}
+
+	detail, err := VerifyPolicy(reader("alice", "bob"), reader("bob", "alice")).Run(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, detail, "policy version ")
+
+	_, err = VerifyPolicy(reader("alice"), reader("alice", "bob")).Run(ctx)
+	assert.ErrorContains(t, err, "the promoted store holds policy version")
+
+	failing := PolicyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return nil, errors.New("checksum mismatch") })
+	_, err = VerifyPolicy(reader("alice"), failing).Run(ctx)
+	assert.EqualError(t, err, "read the reference policy: checksum mismatch")
+}
+
+// TestRepoint re-points environment files, checking the variable is set and the other lines kept.
+func TestRepoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authz.env")
+
+	_, err := Repoint(path, "AUTHZ_DATABASE_URL", "postgres://standby/authz").Run(context.Background())
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "AUTHZ_DATABASE_URL=postgres://standby/authz\n", string(data))
+
+	assert.NoError(t, os.WriteFile(path, []byte("LOG_LEVEL=debug\nAUTHZ_DATABASE_URL=postgres://primary/authz\nPORT=8080\n"), 0o600))
+	detail, err := Repoint(path, "AUTHZ_DATABASE_URL", "postgres://standby/authz").Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "AUTHZ_DATABASE_URL set in "+path, detail)
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "LOG_LEVEL=debug\nAUTHZ_DATABASE_URL=postgres://standby/authz\nPORT=8080\n", string(data))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+// TestRestart runs restart commands, checking a failing command reports its output.
+func TestRestart(t *testing.T) {
+	_, err := Restart("true").Run(context.Background())
+	assert.NoError(t, err)
+
+	_, err = Restart("echo unit not found >&2; exit 5").Run(context.Background())
+	assert.EqualError(t, err, "exit status 5: unit not found")
+}
+
+// TestAwaitService polls a service becoming healthy on a fake clock, checking the step waits the
+// interval between polls until the service reports ok.
+func TestAwaitService(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) < 3 {
+			_, _ = w.Write([]byte(`{"status":"degraded"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+	fake := NewFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := AwaitService(server.Client(), fake, server.URL, time.Minute, time.Minute).Run(context.Background())
+		done <- outcome{detail, err}
+	}()
+
+	for poll := int32(1); poll < 3; poll++ {
+		assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, poll, polls.Load())
+		fake.Advance(time.Minute)
+	}
+	result := <-done
+	assert.NoError(t, result.err)
+	assert.Equal(t, "the service reports ok", result.detail)
+	assert.Equal(t, int32(3), polls.Load())
+}
+
+// TestAwaitService_Timeout polls a service staying degraded, checking the step fails with its last status.
+func TestAwaitService_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"degraded"}`))
+	}))
+	defer server.Close()
+
+	_, err := AwaitService(server.Client(), clock.System(), server.URL, time.Millisecond, 20*time.Millisecond).Run(context.Background())
+	assert.ErrorContains(t, err, "the service did not report ok within 20ms, last status degraded")
+}
@@ -0,0 +1,206 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// queryRower is the part of a pool of Postgres connections Promote queries the database with.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PolicyReader reads the policy of a store or a snapshot.
+type PolicyReader interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+}
+
+// PolicyReaderFunc adapts a function to the PolicyReader interface.
+type PolicyReaderFunc func(ctx context.Context) (*authz.Policy, error)
+
+// ReadPolicy calls the function.
+func (read PolicyReaderFunc) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return read(ctx)
+}
+
+// Promote promotes the standby database to a primary accepting writes, waiting up to the
+// timeout for the promotion to complete. A database already out of recovery is left as is,
+// so the runbook can be run again after a later step failed.
+func Promote(db queryRower, timeout time.Duration) Step {
+	return Step{Name: "promote", Run: func(ctx context.Context) (string, error) {
+		var recovering bool
+		if err := db.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&recovering); err != nil {
+			return "", fmt.Errorf("check recovery: %w", err)
+		}
+		if !recovering {
+			return "the database is already a primary", nil
+		}
+
+		var promoted bool
+		if err := db.QueryRow(ctx, "SELECT pg_promote(true, $1)", int(timeout/time.Second)).Scan(&promoted); err != nil {
+			return "", fmt.Errorf("promote: %w", err)
+		}
+		if !promoted {
+			return "", fmt.Errorf("the promotion did not complete within %s", timeout)
+		}
+		return "the standby database was promoted", nil
+	}}
+}
+
+// VerifyPolicy checks the promoted store holds the same policy as the reference, such as the
+// standby file or the former primary, by comparing their versions, see policyfile.Version.
+func VerifyPolicy(promoted PolicyReader, reference PolicyReader) Step {
+	return Step{Name: "verify-policy", Run: func(ctx context.Context) (string, error) {
+		expected, err := policyVersion(ctx, reference)
+		if err != nil {
+			return "", fmt.Errorf("read the reference policy: %w", err)
+		}
+		actual, err := policyVersion(ctx, promoted)
+		if err != nil {
+			return "", fmt.Errorf("read the promoted policy: %w", err)
+		}
+		if actual != expected {
+			return "", fmt.Errorf("the promoted store holds policy version %s, expected %s", actual, expected)
+		}
+		return "policy version " + actual, nil
+	}}
+}
+
+func policyVersion(ctx context.Context, reader PolicyReader) (string, error) {
+	policy, err := reader.ReadPolicy(ctx)
+	if err != nil {
+		return "", err
+	}
+	return policyfile.Version(policy)
+}
+
+// Repoint sets the variable of the environment file the service is started with, such as
+// AUTHZ_DATABASE_URL, to the connection string of the promoted store. The other lines are kept
+// and the file is replaced atomically, so the service never reads a partial file.
+func Repoint(path string, variable string, value string) Step {
+	return Step{Name: "repoint", Run: func(ctx context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		line := variable + "=" + value
+		var lines []string
+		replaced := false
+		for _, existing := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			if strings.HasPrefix(existing, variable+"=") {
+				existing, replaced = line, true
+			}
+			if existing != "" || len(lines) > 0 {
+				lines = append(lines, existing)
+			}
+		}
+		if !replaced {
+			lines = append(lines, line)
+		}
+
+		if err := writeAtomically(path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s set in %s", variable, path), nil
+	}}
+}
+
+func writeAtomically(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	// removing the temporary file fails harmlessly once it has been renamed
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	// the file holds a connection string, keep it private to its owner
+	if err := os.Chmod(temp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Restart runs the shell command restarting the service, such as "systemctl restart authz",
+// so it connects to the promoted store.
+func Restart(command string) Step {
+	return Step{Name: "restart", Run: func(ctx context.Context) (string, error) {
+		output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return "ran " + command, nil
+	}}
+}
+
+// AwaitService polls the health endpoint of the service, such as https://authz.internal/api/health,
+// until it reports the store as ok, giving up after the timeout. A service serving the standby file
+// reports itself as degraded; once it is ok it uses the promoted store again and runs its background
+// jobs, such as the standby exporter and the policy publisher. The interval between polls is waited
+// on the clock, while the timeout bounds the requests too and is kept by the context.
+func AwaitService(client *http.Client, clock clock.Clock, healthURL string, interval time.Duration, timeout time.Duration) Step {
+	return Step{Name: "await-service", Run: func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		last := "no response"
+		for {
+			status, err := serviceStatus(ctx, client, healthURL)
+			if err == nil && status == "ok" {
+				return "the service reports ok", nil
+			}
+			// a request cut short by the timeout tells nothing about the service
+			if err != nil && ctx.Err() == nil {
+				last = err.Error()
+			} else if err == nil {
+				last = "status " + status
+			}
+
+			select {
+			case <-ctx.Done():
+				return "", fmt.Errorf("the service did not report ok within %s, last %s", timeout, last)
+			case <-clock.After(interval):
+			}
+		}
+	}}
+}
+
+func serviceStatus(ctx context.Context, client *http.Client, healthURL string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode health: %w", err)
+	}
+	return body.Status, nil
+}
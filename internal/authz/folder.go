@@ -0,0 +1,215 @@
+package authz
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// Inheritance tells whether a folder receives the permissions granted by its parent folders.
+type Inheritance string
+
+const (
+	// InheritanceImplicit inherits the grants of the parent folders without the folder opting in,
+	// so the inherited grants are flagged by lint.
+	InheritanceImplicit Inheritance = ""
+	// InheritanceInherit inherits the grants of the parent folders on purpose.
+	InheritanceInherit Inheritance = "inherit"
+	// InheritanceBlock stops the grants of the parent folders, for the folder and its subfolders.
+	InheritanceBlock Inheritance = "block"
+)
+
+// ParseInheritance parses the name of an inheritance. An empty name is the implicit inheritance.
+func ParseInheritance(name string) (Inheritance, error) {
+	switch inheritance := Inheritance(name); inheritance {
+	case InheritanceImplicit, InheritanceInherit, InheritanceBlock:
+		return inheritance, nil
+	default:
+		return "", fmt.Errorf("invalid inheritance %q, expected %s or %s", name, InheritanceInherit, InheritanceBlock)
+	}
+}
+
+// Folder grants permissions to the groups it holds and, following their inheritance, to the groups of its subfolders.
+type Folder struct {
+	// The names of the folders from the top-level one down to this one, separated by slashes.
+	Path   string   `json:"path"`
+	Groups []string `json:"groups,omitempty"`
+	// The permissions granted to the groups of the folder.
+	Grants      []string    `json:"grants,omitempty"`
+	Inheritance Inheritance `json:"inheritance,omitempty"`
+}
+
+// parent returns the path of the parent folder, empty for top-level folders.
+func (folder *Folder) parent() string {
+	if i := strings.LastIndex(folder.Path, "/"); i >= 0 {
+		return folder.Path[:i]
+	}
+	return ""
+}
+
+// InheritedGrant is a permission a group receives from a parent folder of the folder holding it.
+type InheritedGrant struct {
+	Permission string
+	Group      string
+	// The folder holding the group.
+	Folder string
+	// The folder granting the permission.
+	From string
+	// Whether every folder the grant passes through, down to the one holding the group, opted in with InheritanceInherit.
+	Expected bool
+}
+
+// folderGrant is a permission reaching a folder, with where it comes from.
+type folderGrant struct {
+	from     string
+	expected bool
+}
+
+// ValidateFolders checks that every folder has a unique path under an existing parent folder and a
+// known inheritance, and only names the groups and permissions of the policy. A group is held by one folder at most.
+func (policy *Policy) ValidateFolders() error {
+	groups := make(shared.Set[string], len(policy.Groups))
+	for _, group := range policy.Groups {
+		groups.Add(group.Name)
+	}
+	permissions := make(shared.Set[string], len(policy.Permissions))
+	for _, permission := range policy.Permissions {
+		permissions.Add(permission.Name)
+	}
+
+	paths := make(shared.Set[string], len(policy.Folders))
+	for _, folder := range policy.Folders {
+		if folder.Path == "" || slices.Contains(strings.Split(folder.Path, "/"), "") {
+			return fmt.Errorf("invalid folder path %q", folder.Path)
+		}
+		if !paths.Insert(folder.Path) {
+			return fmt.Errorf("folder %q is listed twice", folder.Path)
+		}
+	}
+
+	held := map[string]string{}
+	for _, folder := range policy.Folders {
+		if parent := folder.parent(); parent != "" && !paths.Contains(parent) {
+			return fmt.Errorf("folder %q is in unknown folder %q", folder.Path, parent)
+		}
+		if _, err := ParseInheritance(string(folder.Inheritance)); err != nil {
+			return fmt.Errorf("folder %q: %w", folder.Path, err)
+		}
+		for _, group := range folder.Groups {
+			if !groups.Contains(group) {
+				return fmt.Errorf("folder %q holds unknown group %q", folder.Path, group)
+			}
+			if other, ok := held[group]; ok {
+				return fmt.Errorf("group %q is held by folders %q and %q", group, other, folder.Path)
+			}
+			held[group] = folder.Path
+		}
+		for _, grant := range folder.Grants {
+			if !permissions.Contains(grant) {
+				return fmt.Errorf("folder %q grants unknown permission %q", folder.Path, grant)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveFolders grants the permissions of every folder to the groups of the folder and of the subfolders
+// inheriting them, by adding the groups to the permissions. Resolving a resolved policy changes nothing,
+// so ReadPolicy and Compile resolve the folders the same way. Unknown groups and permissions are skipped.
+func (policy *Policy) ResolveFolders() {
+	grants := policy.folderGrants()
+	index := make(map[string]int, len(policy.Permissions))
+	for i, permission := range policy.Permissions {
+		index[permission.Name] = i
+	}
+
+	for _, folder := range policy.Folders {
+		for permission := range grants[folder.Path] {
+			i, ok := index[permission]
+			if !ok {
+				continue
+			}
+			granted := &policy.Permissions[i]
+			for _, group := range folder.Groups {
+				if !slices.Contains(granted.Groups, group) {
+					granted.Groups = append(granted.Groups, group)
+				}
+			}
+		}
+	}
+}
+
+// InheritedGrants returns the permissions the groups receive from the parent folders of their folder,
+// sorted by permission, group and folder. The unexpected ones are flagged by lint.
+func (policy *Policy) InheritedGrants() []InheritedGrant {
+	grants := policy.folderGrants()
+
+	inherited := []InheritedGrant{}
+	for _, folder := range policy.Folders {
+		for permission, grant := range grants[folder.Path] {
+			if grant.from == folder.Path {
+				continue
+			}
+			for _, group := range folder.Groups {
+				inherited = append(inherited, InheritedGrant{
+					Permission: permission,
+					Group:      group,
+					Folder:     folder.Path,
+					From:       grant.from,
+					Expected:   grant.expected,
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(inherited, func(a, b InheritedGrant) int {
+		return cmp.Or(cmp.Compare(a.Permission, b.Permission), cmp.Compare(a.Group, b.Group), cmp.Compare(a.Folder, b.Folder))
+	})
+	return inherited
+}
+
+// folderGrants returns the permissions reaching every folder, granted by the folder itself or inherited
+// from the nearest parent folder granting them.
+func (policy *Policy) folderGrants() map[string]map[string]folderGrant {
+	folders := make(map[string]*Folder, len(policy.Folders))
+	for i := range policy.Folders {
+		folders[policy.Folders[i].Path] = &policy.Folders[i]
+	}
+
+	grants := make(map[string]map[string]folderGrant, len(folders))
+	// parents are prefixes of the paths of their subfolders, so the recursion ends
+	var resolve func(path string) map[string]folderGrant
+	resolve = func(path string) map[string]folderGrant {
+		if resolved, ok := grants[path]; ok {
+			return resolved
+		}
+		folder, ok := folders[path]
+		if !ok {
+			return nil
+		}
+
+		resolved := map[string]folderGrant{}
+		if folder.Inheritance != InheritanceBlock && folder.parent() != "" {
+			parent := folder.parent()
+			for permission, grant := range resolve(parent) {
+				// a grant is expected when every folder below the granting one opted in
+				expected := folder.Inheritance == InheritanceInherit && (grant.from == parent || grant.expected)
+				resolved[permission] = folderGrant{from: grant.from, expected: expected}
+			}
+		}
+		for _, permission := range folder.Grants {
+			resolved[permission] = folderGrant{from: path}
+		}
+
+		grants[path] = resolved
+		return resolved
+	}
+
+	for path := range folders {
+		resolve(path)
+	}
+	return grants
+}
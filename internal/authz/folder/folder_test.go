@@ -0,0 +1,61 @@
+package folder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTree() *Tree {
+	return NewTree([]Folder{
+		{ID: 1, Name: "kitchen", Labels: map[string]string{"team": "kitchen", "tier": "1"}, Admins: []string{"chef"}},
+		{ID: 2, Name: "pastry", ParentID: 1, Labels: map[string]string{"tier": "2"}, Admins: []string{"baker", "chef"}},
+		{ID: 3, Name: "bread", ParentID: 2, Admins: []string{"sous"}},
+		{ID: 4, Name: "billing"},
+		// a folder whose parent is missing is top-level
+		{ID: 5, Name: "orphan", ParentID: 42},
+	})
+}
+
+func TestTree_Path(t *testing.T) {
+	tree := testTree()
+
+	path := tree.Path(3)
+	assert.Equal(t, []string{"kitchen", "pastry", "bread"}, []string{path[0].Name, path[1].Name, path[2].Name})
+	assert.Empty(t, tree.Path(42))
+	assert.Len(t, tree.Path(5), 1)
+}
+
+func TestTree_Path_Cycle(t *testing.T) {
+	tree := NewTree([]Folder{{ID: 1, Name: "a", ParentID: 2}, {ID: 2, Name: "b", ParentID: 1}})
+
+	assert.Len(t, tree.Path(1), 2)
+}
+
+func TestTree_Labels(t *testing.T) {
+	tree := testTree()
+
+	assert.Equal(t, map[string]string{"team": "kitchen", "tier": "2"}, tree.Labels(3))
+	assert.Equal(t, map[string]string{"team": "kitchen", "tier": "1"}, tree.Labels(1))
+	assert.Equal(t, map[string]string{}, tree.Labels(4))
+}
+
+func TestTree_Admins(t *testing.T) {
+	tree := testTree()
+
+	assert.Equal(t, []string{"baker", "chef", "sous"}, tree.Admins(3))
+	assert.Equal(t, []string{}, tree.Admins(4))
+
+	assert.True(t, tree.IsAdmin(3, "chef"))
+	assert.True(t, tree.IsAdmin(2, "baker"))
+	assert.False(t, tree.IsAdmin(1, "baker"))
+	assert.False(t, tree.IsAdmin(42, "chef"))
+}
+
+func TestTree_Subtree(t *testing.T) {
+	tree := testTree()
+
+	assert.Equal(t, []int{1, 2, 3}, tree.Subtree(1))
+	assert.Equal(t, []int{4}, tree.Subtree(4))
+	assert.Equal(t, []int{1, 4, 5}, tree.Children(0))
+}
@@ -0,0 +1,257 @@
+package folder
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Create inserts the folder. An ErrNotFound error is returned when the parent folder does not exist.
+func (folders *PostgresStore) Create(ctx context.Context, folder Folder) (int, error) {
+	var id int
+	err := folders.db.QueryRow(ctx, `
+	INSERT INTO folders (name, parent_id, labels, admins, inheritance) VALUES ($1, $2, $3, $4, $5) RETURNING id
+	`, folder.Name, parentId(folder.ParentID), labels(folder.Labels), admins(folder.Admins), folder.Inheritance).Scan(&id)
+	if err != nil {
+		return 0, folderError(err)
+	}
+	return id, nil
+}
+
+// List returns every folder ordered by id.
+func (folders *PostgresStore) List(ctx context.Context) ([]Folder, error) {
+	rows, err := folders.db.Query(ctx, `
+	SELECT f.id, f.name, f.parent_id, f.labels, f.admins, f.inheritance, array(
+		SELECT fp.permission_id FROM folder_permissions fp WHERE fp.folder_id = f.id ORDER BY fp.permission_id
+	) AS permissions
+	FROM folders f ORDER BY f.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Folder{}
+	for rows.Next() {
+		var folder Folder
+		var parent pgtype.Int4
+		if err := rows.Scan(&folder.ID, &folder.Name, &parent, &folder.Labels, &folder.Admins, &folder.Inheritance, &folder.Permissions); err != nil {
+			return nil, err
+		}
+		folder.ParentID = int(parent.Int32)
+		if len(folder.Labels) == 0 {
+			folder.Labels = nil
+		}
+		if len(folder.Permissions) == 0 {
+			folder.Permissions = nil
+		}
+		list = append(list, folder)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return list, nil
+}
+
+// Update replaces the folder in a single statement, refusing to move it into its own subtree.
+func (folders *PostgresStore) Update(ctx context.Context, folder Folder) error {
+	if folder.ParentID == folder.ID {
+		return ErrCycle
+	}
+
+	tag, err := folders.db.Exec(ctx, `
+	UPDATE folders SET name = $2, parent_id = $3, labels = $4, admins = $5, inheritance = $6
+	WHERE id = $1 AND NOT EXISTS (
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = $3
+			UNION ALL
+			SELECT f.id, f.parent_id FROM folders f JOIN ancestors a ON f.id = a.parent_id
+		)
+		SELECT 1 FROM ancestors WHERE id = $1
+	)
+	`, folder.ID, folder.Name, parentId(folder.ParentID), labels(folder.Labels), admins(folder.Admins), folder.Inheritance)
+	if err != nil {
+		return folderError(err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// nothing was updated because the folder is missing or the new parent is below it
+	var exists bool
+	if err := folders.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM folders WHERE id = $1)", folder.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrCycle
+	}
+	return ErrNotFound
+}
+
+// Delete deletes the folder. The foreign keys of its subfolders, groups and catalogs refuse to delete
+// a folder that is not empty.
+func (folders *PostgresStore) Delete(ctx context.Context, id int) error {
+	tag, err := folders.db.Exec(ctx, "DELETE FROM folders WHERE id = $1", id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return ErrNotEmpty
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AssignGroup moves the group into the folder. A GroupNotFound error is returned when the group
+// does not exist and ErrNotFound when the folder does not.
+func (folders *PostgresStore) AssignGroup(ctx context.Context, groupId int, folderId int) error {
+	if folderId == 0 {
+		_, err := folders.db.Exec(ctx, "DELETE FROM folder_groups WHERE group_id = $1", groupId)
+		return err
+	}
+
+	_, err := folders.db.Exec(ctx, `
+	INSERT INTO folder_groups (group_id, folder_id) VALUES ($1, $2)
+	ON CONFLICT (group_id) DO UPDATE SET folder_id = EXCLUDED.folder_id
+	`, groupId, folderId)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "folder_groups_group_id_fkey" {
+		return store.NewGroupNotFoundError()
+	}
+	return folderError(err)
+}
+
+// AssignCatalog moves the catalog into the folder. A catalog.ErrNotFound error is returned when the
+// application registered no catalog and ErrNotFound when the folder does not exist.
+func (folders *PostgresStore) AssignCatalog(ctx context.Context, application string, folderId int) error {
+	if folderId == 0 {
+		_, err := folders.db.Exec(ctx, "DELETE FROM folder_catalogs WHERE application = $1", application)
+		return err
+	}
+
+	_, err := folders.db.Exec(ctx, `
+	INSERT INTO folder_catalogs (application, folder_id) VALUES ($1, $2)
+	ON CONFLICT (application) DO UPDATE SET folder_id = EXCLUDED.folder_id
+	`, application, folderId)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "folder_catalogs_application_fkey" {
+		return catalog.ErrNotFound
+	}
+	return folderError(err)
+}
+
+// SetPermissions replaces the permissions granted by the folder. A PermissionNotFound error is
+// returned when a permission does not exist and ErrNotFound when the folder does not.
+func (folders *PostgresStore) SetPermissions(ctx context.Context, folderId int, permissionIds []int) error {
+	if permissionIds == nil {
+		permissionIds = []int{}
+	}
+
+	_, err := folders.db.Exec(ctx, `
+	WITH new_permissions AS (SELECT unnest($1::int[]) AS permission_id)
+	MERGE INTO folder_permissions granted
+	USING new_permissions np
+	ON granted.folder_id = $2 AND granted.permission_id = np.permission_id
+	WHEN NOT MATCHED BY TARGET THEN
+		INSERT (folder_id, permission_id) VALUES ($2, np.permission_id)
+	WHEN NOT MATCHED BY SOURCE AND granted.folder_id = $2 THEN
+		DELETE;
+	`, permissionIds, folderId)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		if pgErr.ConstraintName == "folder_permissions_folder_id_fkey" {
+			return ErrNotFound
+		}
+		return store.NewPermissionNotFoundError()
+	}
+	return err
+}
+
+// Assignments returns the folder holding every group and catalog in a single query.
+func (folders *PostgresStore) Assignments(ctx context.Context) (*Assignments, error) {
+	rows, err := folders.db.Query(ctx, `
+	SELECT group_id, NULL, folder_id FROM folder_groups
+	UNION ALL
+	SELECT NULL, application, folder_id FROM folder_catalogs
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := &Assignments{Groups: map[int]int{}, Catalogs: map[string]int{}}
+	for rows.Next() {
+		var groupId pgtype.Int4
+		var application pgtype.Text
+		var folderId int
+		if err := rows.Scan(&groupId, &application, &folderId); err != nil {
+			return nil, err
+		}
+		if groupId.Valid {
+			assignments.Groups[int(groupId.Int32)] = folderId
+		} else {
+			assignments.Catalogs[application.String] = folderId
+		}
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return assignments, nil
+}
+
+// folderError maps the errors of the statements writing a folder or naming one.
+func folderError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.ForeignKeyViolation:
+			return ErrNotFound
+		case pgerrcode.UniqueViolation:
+			return ErrNameExists
+		}
+	}
+	return err
+}
+
+// parentId returns the parent_id column of a folder, NULL for top-level folders.
+func parentId(id int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(id), Valid: id != 0}
+}
+
+func labels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+func admins(admins []string) []string {
+	if admins == nil {
+		return []string{}
+	}
+	return admins
+}
@@ -0,0 +1,271 @@
+package folder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestPostgresStore_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"kitchen", pgtype.Int4{}, map[string]string{}, []string{}, authz.InheritanceImplicit}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 7
+		}).Return(nil)
+
+		id, err := NewPostgresStore(mockDb).Create(ctx, Folder{Name: "kitchen"})
+		assert.NoError(t, err)
+		assert.Equal(t, 7, id)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("parent not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"pastry", pgtype.Int4{Int32: 42, Valid: true}, map[string]string{}, []string{}, authz.InheritanceImplicit}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		_, err := NewPostgresStore(mockDb).Create(ctx, Folder{Name: "pastry", ParentID: 42})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("name exists", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+
+		_, err := NewPostgresStore(mockDb).Create(ctx, Folder{Name: "kitchen"})
+		assert.ErrorIs(t, err, ErrNameExists)
+	})
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Twice()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*int)) = 1
+		*(dest[1].(*string)) = "kitchen"
+		*(dest[3].(*map[string]string)) = map[string]string{"team": "kitchen"}
+		*(dest[4].(*[]string)) = []string{"chef"}
+	}).Return(nil).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*int)) = 2
+		*(dest[1].(*string)) = "pastry"
+		*(dest[2].(*pgtype.Int4)) = pgtype.Int4{Int32: 1, Valid: true}
+		*(dest[3].(*map[string]string)) = map[string]string{}
+		*(dest[4].(*[]string)) = []string{}
+		*(dest[5].(*authz.Inheritance)) = authz.InheritanceInherit
+		*(dest[6].(*[]int)) = []int{3, 5}
+	}).Return(nil).Once()
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	folders, err := NewPostgresStore(mockDb).List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Folder{
+		{ID: 1, Name: "kitchen", Labels: map[string]string{"team": "kitchen"}, Admins: []string{"chef"}},
+		{ID: 2, Name: "pastry", ParentID: 1, Admins: []string{}, Inheritance: authz.InheritanceInherit, Permissions: []int{3, 5}},
+	}, folders)
+}
+
+func TestPostgresStore_Update(t *testing.T) {
+	ctx := context.Background()
+	folder := Folder{ID: 2, Name: "pastry", ParentID: 3, Inheritance: authz.InheritanceBlock}
+	args := []any{2, "pastry", pgtype.Int4{Int32: 3, Valid: true}, map[string]string{}, []string{}, authz.InheritanceBlock}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, args).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).Update(ctx, folder))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("own parent", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).Update(ctx, Folder{ID: 2, Name: "pastry", ParentID: 2}), ErrCycle)
+		mockDb.AssertNotCalled(t, "Exec")
+	})
+
+	for name, test := range map[string]struct {
+		exists   bool
+		expected error
+	}{
+		"cycle":     {exists: true, expected: ErrCycle},
+		"not found": {exists: false, expected: ErrNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockDb := new(MockPgDb)
+			mockRow := new(MockRow)
+			mockDb.On("Exec", ctx, mock.Anything, args).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+			mockDb.On("QueryRow", ctx, mock.Anything, []any{2}).Return(mockRow)
+			mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*bool)) = test.exists
+			}).Return(nil)
+
+			assert.ErrorIs(t, NewPostgresStore(mockDb).Update(ctx, folder), test.expected)
+		})
+	}
+}
+
+func TestPostgresStore_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, "DELETE FROM folders WHERE id = $1", []any{2}).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).Delete(ctx, 2))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{2}).Return(pgconn.NewCommandTag("DELETE 0"), nil)
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).Delete(ctx, 2), ErrNotFound)
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{2}).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).Delete(ctx, 2), ErrNotEmpty)
+	})
+}
+
+func TestPostgresStore_AssignGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{10, 2}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).AssignGroup(ctx, 10, 2))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, "DELETE FROM folder_groups WHERE group_id = $1", []any{10}).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).AssignGroup(ctx, 10, 0))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "folder_groups_group_id_fkey"})
+
+		assert.Equal(t, store.NewGroupNotFoundError(), NewPostgresStore(mockDb).AssignGroup(ctx, 10, 2))
+	})
+
+	t.Run("folder not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "folder_groups_folder_id_fkey"})
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).AssignGroup(ctx, 10, 2), ErrNotFound)
+	})
+}
+
+func TestPostgresStore_AssignCatalog(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{"recipes", 2}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).AssignCatalog(ctx, "recipes", 2))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("catalog not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "folder_catalogs_application_fkey"})
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).AssignCatalog(ctx, "recipes", 2), catalog.ErrNotFound)
+	})
+}
+
+func TestPostgresStore_SetPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{3, 5}, 2}).Return(pgconn.NewCommandTag("MERGE 2"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).SetPermissions(ctx, 2, []int{3, 5}))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{}, 2}).Return(pgconn.NewCommandTag("MERGE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).SetPermissions(ctx, 2, nil))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "folder_permissions_permission_id_fkey"})
+
+		assert.Equal(t, store.NewPermissionNotFoundError(), NewPostgresStore(mockDb).SetPermissions(ctx, 2, []int{9}))
+	})
+
+	t.Run("folder not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "folder_permissions_folder_id_fkey"})
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).SetPermissions(ctx, 42, []int{3}), ErrNotFound)
+	})
+}
+
+func TestPostgresStore_Assignments(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Twice()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*pgtype.Int4)) = pgtype.Int4{Int32: 10, Valid: true}
+		*(dest[2].(*int)) = 1
+	}).Return(nil).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[1].(*pgtype.Text)) = pgtype.Text{String: "recipes", Valid: true}
+		*(dest[2].(*int)) = 2
+	}).Return(nil).Once()
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	assignments, err := NewPostgresStore(mockDb).Assignments(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &Assignments{Groups: map[int]int{10: 1}, Catalogs: map[string]int{"recipes": 2}}, assignments)
+}
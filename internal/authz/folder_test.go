@@ -0,0 +1,118 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// folderPolicy grants recipes.read in the kitchen folder, which bakers inherit on purpose in
+// kitchen/pastry, cooks inherit implicitly in kitchen/line and billing does not in kitchen/billing.
+func folderPolicy() *Policy {
+	policy := NewPolicy(
+		[]Permission{
+			{Name: "recipes.read", Groups: []string{}},
+			{Name: "recipes.write", Groups: []string{"cooks"}},
+		},
+		[]Group{
+			{Name: "chefs", Users: []string{"carol"}},
+			{Name: "bakers", Users: []string{"bob"}},
+			{Name: "cooks", Users: []string{"alice"}},
+			{Name: "billing", Users: []string{"dave"}},
+		},
+	)
+	policy.Folders = []Folder{
+		{Path: "kitchen", Groups: []string{"chefs"}, Grants: []string{"recipes.read"}},
+		{Path: "kitchen/pastry", Groups: []string{"bakers"}, Inheritance: InheritanceInherit},
+		{Path: "kitchen/line", Groups: []string{"cooks"}},
+		{Path: "kitchen/billing", Groups: []string{"billing"}, Inheritance: InheritanceBlock},
+	}
+	return policy
+}
+
+func TestParseInheritance(t *testing.T) {
+	for _, name := range []string{"", "inherit", "block"} {
+		inheritance, err := ParseInheritance(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Inheritance(name), inheritance)
+	}
+
+	_, err := ParseInheritance("always")
+	assert.Error(t, err)
+}
+
+func TestPolicy_ValidateFolders(t *testing.T) {
+	assert.NoError(t, folderPolicy().ValidateFolders())
+
+	for name, test := range map[string]struct {
+		folder   Folder
+		expected string
+	}{
+		"empty path":         {folder: Folder{Path: "kitchen//line"}, expected: `invalid folder path "kitchen//line"`},
+		"listed twice":       {folder: Folder{Path: "kitchen"}, expected: `folder "kitchen" is listed twice`},
+		"unknown parent":     {folder: Folder{Path: "bar/wine"}, expected: `folder "bar/wine" is in unknown folder "bar"`},
+		"unknown group":      {folder: Folder{Path: "bar", Groups: []string{"waiters"}}, expected: `folder "bar" holds unknown group "waiters"`},
+		"group held twice":   {folder: Folder{Path: "bar", Groups: []string{"cooks"}}, expected: `group "cooks" is held by folders "kitchen/line" and "bar"`},
+		"unknown permission": {folder: Folder{Path: "bar", Grants: []string{"wine.read"}}, expected: `folder "bar" grants unknown permission "wine.read"`},
+		"unknown inheritance": {
+			folder:   Folder{Path: "bar", Inheritance: "always"},
+			expected: `folder "bar": invalid inheritance "always", expected inherit or block`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			policy := folderPolicy()
+			policy.Folders = append(policy.Folders, test.folder)
+			assert.EqualError(t, policy.ValidateFolders(), test.expected)
+		})
+	}
+}
+
+func TestPolicy_ResolveFolders(t *testing.T) {
+	policy := folderPolicy()
+	policy.ResolveFolders()
+
+	assert.ElementsMatch(t, []string{"chefs", "bakers", "cooks"}, policy.Permissions[0].Groups)
+	assert.Equal(t, []string{"cooks"}, policy.Permissions[1].Groups)
+
+	// resolving twice changes nothing
+	policy.ResolveFolders()
+	assert.Len(t, policy.Permissions[0].Groups, 3)
+
+	result, err := policy.Evaluate("dave")
+	assert.NoError(t, err)
+	assert.Empty(t, result.Permissions)
+}
+
+func TestPolicy_InheritedGrants(t *testing.T) {
+	policy := folderPolicy()
+	policy.Folders = append(policy.Folders,
+		Folder{Path: "kitchen/line/grill", Inheritance: InheritanceInherit, Groups: []string{}},
+	)
+
+	assert.Equal(t, []InheritedGrant{
+		{Permission: "recipes.read", Group: "bakers", Folder: "kitchen/pastry", From: "kitchen", Expected: true},
+		{Permission: "recipes.read", Group: "cooks", Folder: "kitchen/line", From: "kitchen", Expected: false},
+	}, policy.InheritedGrants())
+}
+
+func TestCompile_Folders(t *testing.T) {
+	compiled, err := Compile(folderPolicy())
+	assert.NoError(t, err)
+	assert.True(t, compiled.HasPermission("bob", "recipes.read"))
+	assert.True(t, compiled.HasPermission("alice", "recipes.read"))
+	assert.False(t, compiled.HasPermission("dave", "recipes.read"))
+
+	// the compiled policy resolves the folders the same way as ReadPolicy
+	policy := folderPolicy()
+	policy.ResolveFolders()
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		expected, _ := policy.Evaluate(user)
+		actual, _ := compiled.Evaluate(user)
+		assert.Equal(t, expected, actual, user)
+	}
+
+	invalid := folderPolicy()
+	invalid.Folders = append(invalid.Folders, Folder{Path: "bar/wine"})
+	_, err = Compile(invalid)
+	assert.ErrorContains(t, err, `folder "bar/wine" is in unknown folder "bar"`)
+}
@@ -10,8 +10,8 @@ import (
 // Given a user the group instance can evaluate whether this user
 // is a member of the specified group.
 type Group struct {
-	Name  string
-	Users []string
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
 }
 
 // NewGroup creates a new Group with the specified name and list of users.
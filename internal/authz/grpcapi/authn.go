@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type principalKey struct{}
+
+// Authentication returns the server options authenticating the callers of every method with the
+// providers, tried in order like an authn.Chain, so the gRPC services accept the same credentials as
+// the administration API. The providers read the call metadata as the HTTP headers of the same name,
+// such as authorization or x-api-key, and the client certificate verified by the TLS credentials of
+// the server. Calls without credentials go through unauthenticated, so the health checking and
+// reflection services stay open while the policy services refuse them; calls with invalid
+// credentials are refused. Without these options every call of the policy services is refused.
+func Authentication(providers ...authn.Provider) []grpc.ServerOption {
+	chain := authn.Chain(providers)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := authenticate(ctx, chain)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, request)
+		}),
+		grpc.ChainStreamInterceptor(func(server any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(stream.Context(), chain)
+			if err != nil {
+				return err
+			}
+			return handler(server, &authenticatedStream{ServerStream: stream, ctx: ctx})
+		}),
+	}
+}
+
+// authenticate returns the context carrying the caller authenticated by the chain, if any.
+func authenticate(ctx context.Context, chain authn.Chain) (context.Context, error) {
+	principal, err := chain.Authenticate(authenticationRequest(ctx))
+	if errors.Is(err, authn.ErrInvalidCredentials) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "authentication failed")
+	}
+	if principal == nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// authenticationRequest returns the HTTP request the providers authenticate for a call: its headers
+// are the call metadata and its TLS state the one of the connection, if any.
+func authenticationRequest(ctx context.Context) *http.Request {
+	request := (&http.Request{Header: http.Header{}}).WithContext(ctx)
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range incoming {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	if caller, ok := peer.FromContext(ctx); ok {
+		if info, ok := caller.AuthInfo.(credentials.TLSInfo); ok {
+			request.TLS = &info.State
+		}
+	}
+	return request
+}
+
+// principalFromContext returns the caller authenticated by the Authentication interceptors, if any.
+func principalFromContext(ctx context.Context) (*authn.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*authn.Principal)
+	return principal, ok
+}
+
+// authenticatedStream is a server stream whose context carries the authenticated caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,80 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// TestAuthentication_APIKeys serves the policy services authenticating callers with API keys only,
+// checking the forwarded user metadata is not trusted and the keys are verified.
+func TestAuthentication_APIKeys(t *testing.T) {
+	digest := sha256.Sum256([]byte("secret"))
+	keys, err := authn.NewAPIKeys([]authn.APIKey{{User: "recipes-service", SHA256: hex.EncodeToString(digest[:])}})
+	assert.NoError(t, err)
+
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(policyServerPolicy(), nil)
+	manager.On("Health", mock.Anything).Return(nil, nil)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), Authentication(keys)...)
+	server.RegisterPolicy(manager, nil)
+	conn := startServer(t, server)
+	evaluation := authzpb.NewEvaluationClient(conn)
+	request := &authzpb.HasPermissionRequest{User: "alice", Permission: "recipes.read"}
+
+	_, err = evaluation.HasPermission(as("recipes-service"), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "the forwarded user is not trusted")
+
+	_, err = evaluation.HasPermission(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "guess"), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	check, err := evaluation.HasPermission(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret"), request)
+	assert.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.NoError(t, err, "health checks need no credentials")
+}
+
+// TestAuthentication_MTLS authenticates a call over a connection with a verified client certificate,
+// checking its identity is read by the mtls provider.
+func TestAuthentication_MTLS(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://recipes/service")
+	certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "recipes"}, URIs: []*url.URL{spiffe}}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{certificate}}},
+	}})
+
+	ctx, err := authenticate(ctx, authn.Chain{authn.NewMTLS()})
+	assert.NoError(t, err)
+	principal, ok := principalFromContext(ctx)
+	if assert.True(t, ok) {
+		assert.Equal(t, "spiffe://recipes/service", principal.User)
+		assert.Equal(t, "mtls", principal.Method)
+	}
+
+	ctx, err = authenticate(context.Background(), authn.Chain{authn.NewMTLS()})
+	assert.NoError(t, err)
+	_, ok = principalFromContext(ctx)
+	assert.False(t, ok, "a plain connection carries no identity")
+}
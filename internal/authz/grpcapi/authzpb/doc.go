@@ -0,0 +1,6 @@
+// Package authzpb holds the protobuf messages and the gRPC Evaluation and Management services
+// defined in authz.proto, and the Watch service defined in watch.proto, generated with
+// protoc-gen-go and protoc-gen-go-grpc.
+package authzpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative authz.proto watch.proto
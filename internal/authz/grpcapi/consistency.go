@@ -0,0 +1,56 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ConsistencyMetadata carries consistency tokens like api.ConsistencyHeader, whose name it shares.
+// It is set in the response header of the successful changes of the Management service, and an
+// Evaluate or HasPermission call passing it back is answered with a policy reflecting the change.
+const ConsistencyMetadata = "consistency-token"
+
+// issueToken sets the token of the current policy revision in the response header of a change.
+// A revision that cannot be read only leaves the token out, the change itself succeeded.
+func (service *policyService) issueToken(ctx context.Context) {
+	if service.consistency == nil {
+		return
+	}
+	revision, err := service.consistency.PolicyRevision(ctx)
+	if err != nil {
+		service.logger.Warn("failed to read the policy revision of a consistency token", "error", err)
+		return
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(ConsistencyMetadata, consistency.Encode(revision))); err != nil {
+		service.logger.Warn("failed to set the consistency token", "error", err)
+	}
+}
+
+// evaluationPolicy returns the policy an evaluation is made with: the policy the caller was authorized
+// with, or one at least as recent as the consistency token of the call if any.
+func (service *policyService) evaluationPolicy(ctx context.Context, policy *authz.Policy) (*authz.Policy, error) {
+	tokens := metadata.ValueFromIncomingContext(ctx, ConsistencyMetadata)
+	if len(tokens) == 0 || tokens[0] == "" || service.consistency == nil {
+		return policy, nil
+	}
+
+	revision, err := consistency.Decode(tokens[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	policy, err = service.consistency.ReadPolicyAt(ctx, revision)
+	if errors.Is(err, consistency.ErrNotYetAvailable) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, service.storeError(err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeReader is a consistency.Reader at a fixed revision, whose policy adds bob to the cooks.
+type fakeReader struct {
+	revision int64
+}
+
+func (reader *fakeReader) PolicyRevision(ctx context.Context) (int64, error) {
+	return reader.revision, nil
+}
+
+func (reader *fakeReader) ReadPolicyAt(ctx context.Context, revision int64) (*authz.Policy, error) {
+	if revision > reader.revision {
+		return nil, consistency.ErrNotYetAvailable
+	}
+	policy := policyServerPolicy()
+	policy.Groups[3].Users = append(policy.Groups[3].Users, "bob")
+	return policy, nil
+}
+
+// startConsistentServer serves the policy services issuing and honoring the tokens of the reader.
+func startConsistentServer(t *testing.T) (*MockPolicyManager, authzpb.EvaluationClient, authzpb.ManagementClient) {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(policyServerPolicy(), nil)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), proxyAuthentication()...)
+	server.RegisterPolicy(manager, &fakeReader{revision: 12})
+	conn := startServer(t, server)
+	return manager, authzpb.NewEvaluationClient(conn), authzpb.NewManagementClient(conn)
+}
+
+func TestConsistencyToken(t *testing.T) {
+	t.Run("issued by changes", func(t *testing.T) {
+		manager, _, management := startConsistentServer(t)
+		manager.On("CreateGroup", mock.Anything, "bakers").Return(7, nil)
+		manager.On("CreateGroup", mock.Anything, "cooks").Return(0, store.NewNameExistsError())
+
+		var header metadata.MD
+		_, err := management.CreateGroup(as("admin"), &authzpb.CreateGroupRequest{Name: "bakers"}, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{consistency.Encode(12)}, header.Get(ConsistencyMetadata))
+
+		header = nil
+		_, err = management.CreateGroup(as("admin"), &authzpb.CreateGroupRequest{Name: "cooks"}, grpc.Header(&header))
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Empty(t, header.Get(ConsistencyMetadata))
+	})
+
+	t.Run("not issued without a reader", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("CreateGroup", mock.Anything, "bakers").Return(7, nil)
+
+		var header metadata.MD
+		_, err := management.CreateGroup(as("admin"), &authzpb.CreateGroupRequest{Name: "bakers"}, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Empty(t, header.Get(ConsistencyMetadata))
+	})
+
+	t.Run("honored by evaluations", func(t *testing.T) {
+		// the policy of the manager is stale and does not grant bob yet
+		_, evaluation, _ := startConsistentServer(t)
+		request := &authzpb.HasPermissionRequest{User: "bob", Permission: "recipes.read"}
+
+		check, err := evaluation.HasPermission(as("recipes-service"), request)
+		assert.NoError(t, err)
+		assert.False(t, check.Allowed)
+
+		check, err = evaluation.HasPermission(as("recipes-service", ConsistencyMetadata, consistency.Encode(12)), request)
+		assert.NoError(t, err)
+		assert.True(t, check.Allowed)
+
+		result, err := evaluation.Evaluate(as("recipes-service", ConsistencyMetadata, consistency.Encode(12)), &authzpb.EvaluateRequest{User: "bob"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"recipes.read"}, result.Permissions)
+	})
+
+	t.Run("revision not available yet", func(t *testing.T) {
+		_, evaluation, _ := startConsistentServer(t)
+
+		_, err := evaluation.HasPermission(as("recipes-service", ConsistencyMetadata, consistency.Encode(13)),
+			&authzpb.HasPermissionRequest{User: "bob", Permission: "recipes.read"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, evaluation, _ := startConsistentServer(t)
+
+		_, err := evaluation.Evaluate(as("recipes-service", ConsistencyMetadata, "12"), &authzpb.EvaluateRequest{User: "bob"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
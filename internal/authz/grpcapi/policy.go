@@ -0,0 +1,587 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/salmarsumi/recipes/internal/authz/consistency"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/salmarsumi/recipes/internal/authz/guardrail"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxBatchIds is the most ids a single ListGroups or ListPermissions call may ask for.
+const maxBatchIds = 500
+
+// PolicyManager is the policy store the Evaluation and Management services answer from.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// RegisterPolicy registers the Evaluation and Management services of authz.proto, answering from
+// the policy manager. Callers are authenticated by the interceptors of Authentication and authorized
+// with the meta-policy of the administration API. Changes return consistency tokens read from the
+// reader and evaluations honor them, see ConsistencyMetadata; a nil reader issues no token and
+// ignores them.
+func (server *Server) RegisterPolicy(manager PolicyManager, reader consistency.Reader) {
+	service := &policyService{manager: manager, consistency: reader, logger: server.logger}
+	authzpb.RegisterEvaluationServer(server.grpc, &evaluationServer{policyService: service})
+	authzpb.RegisterManagementServer(server.grpc, &managementServer{policyService: service})
+}
+
+// policyService holds what the Evaluation and Management services share.
+type policyService struct {
+	manager     PolicyManager
+	consistency consistency.Reader
+	logger      *slog.Logger
+}
+
+// identity is the caller of a method.
+type identity struct {
+	user string
+	mfa  bool
+}
+
+// authorize checks the caller holds the permission in the meta-policy, returning the policy it
+// was checked against so the method can answer from the same version.
+func (service *policyService) authorize(ctx context.Context, permission string, method string) (*authz.Policy, identity, error) {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return nil, identity{}, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	caller := identity{user: principal.User, mfa: principal.MFA}
+
+	policy, err := service.manager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, caller, service.storeError(err)
+	}
+	session, err := policy.Session(caller.user)
+	if err != nil {
+		return nil, caller, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !session.HasPermission(permission) {
+		service.logger.Warn("access to the gRPC API denied", "user", caller.user, "permission", permission, "method", method)
+		return nil, caller, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return policy, caller, nil
+}
+
+// storeError maps a policy store error to the matching gRPC status, like api.Server maps it to an HTTP status.
+func (service *policyService) storeError(err error) error {
+	var violationErr *guardrail.ViolationError
+	if errors.As(err, &violationErr) {
+		return status.Error(codes.FailedPrecondition, violationErr.Error())
+	}
+
+	var storeErr *store.PolicyStoreError
+	if !errors.As(err, &storeErr) {
+		service.logger.Error("unexpected store error", "error", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+	return status.Error(codeFromStoreCode(storeErr.Code), storeErr.Error())
+}
+
+// codeFromStoreCode returns the gRPC code matching a policy store error code.
+func codeFromStoreCode(code store.ErrorCode) codes.Code {
+	switch code {
+	case store.GroupNotFound, store.NoUserRecordsDeleted, store.PermissionNotFound:
+		return codes.NotFound
+	case store.Concurrency:
+		return codes.Aborted
+	case store.NameAlreadyExist:
+		return codes.AlreadyExists
+	case store.NoChanges:
+		return codes.FailedPrecondition
+	case store.InvalidArgument:
+		return codes.InvalidArgument
+	case store.ReadOnly:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// evaluationServer implements the Evaluation service.
+type evaluationServer struct {
+	authzpb.UnimplementedEvaluationServer
+	*policyService
+}
+
+func (server *evaluationServer) Evaluate(ctx context.Context, request *authzpb.EvaluateRequest) (*authzpb.EvaluateResponse, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionEvaluate, "Evaluate")
+	if err != nil {
+		return nil, err
+	}
+	if policy, err = server.evaluationPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	result, err := policy.EvaluateWith(request.GetUser(), requestAttributes(request.GetAttributes()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &authzpb.EvaluateResponse{Groups: result.Groups, Permissions: result.Permissions}, nil
+}
+
+func (server *evaluationServer) HasPermission(ctx context.Context, request *authzpb.HasPermissionRequest) (*authzpb.HasPermissionResponse, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionEvaluate, "HasPermission")
+	if err != nil {
+		return nil, err
+	}
+	if policy, err = server.evaluationPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	check, err := policy.CheckWith(request.GetUser(), request.GetPermission(), requestAttributes(request.GetAttributes()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &authzpb.HasPermissionResponse{Allowed: check.Allowed, Reason: string(check.Reason)}, nil
+}
+
+func (server *evaluationServer) IsInGroup(ctx context.Context, request *authzpb.IsInGroupRequest) (*authzpb.IsInGroupResponse, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionEvaluate, "IsInGroup")
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := policy.Session(request.GetUser())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &authzpb.IsInGroupResponse{Member: session.IsInGroup(request.GetGroup())}, nil
+}
+
+// requestAttributes returns the attributes of a request the conditions of permissions are
+// evaluated with, or nil when the request has none.
+func requestAttributes(attributes *structpb.Struct) condition.Attributes {
+	if attributes == nil {
+		return nil
+	}
+	return attributes.AsMap()
+}
+
+// managementServer implements the Management service. It applies the same multi-factor
+// authentication rules as the administration API, while high risk permissions can only be
+// granted through the approval workflow of the administration API.
+type managementServer struct {
+	authzpb.UnimplementedManagementServer
+	*policyService
+}
+
+func (server *managementServer) ReadPolicy(ctx context.Context, request *authzpb.ReadPolicyRequest) (*authzpb.Policy, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionRead, "ReadPolicy")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &authzpb.Policy{Mode: string(policy.Mode)}
+	for _, group := range policy.Groups {
+		response.Groups = append(response.Groups, &authzpb.Group{Name: group.Name, Users: group.Users})
+	}
+	for _, permission := range policy.Permissions {
+		response.Permissions = append(response.Permissions, &authzpb.Permission{
+			Name: permission.Name, Groups: permission.Groups, Risk: string(permission.Risk), Implies: permission.Implies,
+			Condition: permission.Condition})
+	}
+	for _, folder := range policy.Folders {
+		response.Folders = append(response.Folders, &authzpb.Folder{
+			Path: folder.Path, Groups: folder.Groups, Grants: folder.Grants, Inheritance: string(folder.Inheritance)})
+	}
+	for _, denial := range policy.Denials {
+		response.Denials = append(response.Denials, &authzpb.Denial{Permission: denial.Permission, Groups: denial.Groups, Users: denial.Users})
+	}
+	return response, nil
+}
+
+func (server *managementServer) ListGroups(ctx context.Context, request *authzpb.ListGroupsRequest) (*authzpb.ListGroupsResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionRead, "ListGroups"); err != nil {
+		return nil, err
+	}
+	if len(request.GetIds()) > maxBatchIds {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids, the maximum is %d", maxBatchIds)
+	}
+
+	var groups []store.GroupInfo[int]
+	var err error
+	if len(request.GetIds()) > 0 {
+		groups, err = server.manager.GetGroups(ctx, shared.Distinct(toInts(request.GetIds())))
+	} else {
+		groups, err = server.manager.ListGroups(ctx)
+	}
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+
+	response := &authzpb.ListGroupsResponse{}
+	for _, group := range groups {
+		response.Groups = append(response.Groups, groupInfo(group))
+	}
+	return response, nil
+}
+
+func (server *managementServer) ListPermissions(ctx context.Context, request *authzpb.ListPermissionsRequest) (*authzpb.ListPermissionsResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionRead, "ListPermissions"); err != nil {
+		return nil, err
+	}
+	if len(request.GetIds()) > maxBatchIds {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ids, the maximum is %d", maxBatchIds)
+	}
+
+	var permissions []store.PermissionInfo[int]
+	var err error
+	if len(request.GetIds()) > 0 {
+		permissions, err = server.manager.GetPermissions(ctx, shared.Distinct(toInts(request.GetIds())))
+	} else {
+		permissions, err = server.manager.ListPermissions(ctx)
+	}
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+
+	response := &authzpb.ListPermissionsResponse{}
+	for _, permission := range permissions {
+		response.Permissions = append(response.Permissions, permissionInfo(permission))
+	}
+	return response, nil
+}
+
+func (server *managementServer) CreateGroup(ctx context.Context, request *authzpb.CreateGroupRequest) (*authzpb.CreateGroupResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "CreateGroup"); err != nil {
+		return nil, err
+	}
+
+	id, err := server.manager.CreateGroup(ctx, request.GetName())
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.CreateGroupResponse{Id: int64(id)}, nil
+}
+
+func (server *managementServer) CreatePermission(ctx context.Context, request *authzpb.CreatePermissionRequest) (*authzpb.CreatePermissionResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "CreatePermission"); err != nil {
+		return nil, err
+	}
+
+	id, err := server.manager.CreatePermission(ctx, request.GetName())
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.CreatePermissionResponse{Id: int64(id)}, nil
+}
+
+func (server *managementServer) ChangeGroupName(ctx context.Context, request *authzpb.ChangeGroupNameRequest) (*authzpb.ChangeGroupNameResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "ChangeGroupName"); err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.ChangeGroupName(ctx, int(request.GetGroupId()), request.GetName()); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.ChangeGroupNameResponse{}, nil
+}
+
+func (server *managementServer) DeleteGroup(ctx context.Context, request *authzpb.DeleteGroupRequest) (*authzpb.DeleteGroupResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "DeleteGroup"); err != nil {
+		return nil, err
+	}
+
+	deletion, err := server.manager.DeleteGroup(ctx, int(request.GetGroupId()))
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.DeleteGroupResponse{Members: int64(deletion.Members), Grants: int64(deletion.Grants)}, nil
+}
+
+func (server *managementServer) DeleteUser(ctx context.Context, request *authzpb.DeleteUserRequest) (*authzpb.DeleteUserResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "DeleteUser"); err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.DeleteUser(ctx, request.GetUser()); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.DeleteUserResponse{}, nil
+}
+
+func (server *managementServer) UpdateGroupUsers(ctx context.Context, request *authzpb.UpdateGroupUsersRequest) (*authzpb.UpdateGroupUsersResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "UpdateGroupUsers"); err != nil {
+		return nil, err
+	}
+	ctx, err := withMembershipSource(ctx, request.GetSource())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.UpdateGroupUsers(ctx, int(request.GetGroupId()), request.GetUsers()); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.UpdateGroupUsersResponse{}, nil
+}
+
+func (server *managementServer) UpdateUserGroups(ctx context.Context, request *authzpb.UpdateUserGroupsRequest) (*authzpb.UpdateUserGroupsResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "UpdateUserGroups"); err != nil {
+		return nil, err
+	}
+	ctx, err := withMembershipSource(ctx, request.GetSource())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.UpdateUserGroups(ctx, request.GetUser(), toInts(request.GetGroupIds())); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.UpdateUserGroupsResponse{}, nil
+}
+
+func (server *managementServer) AddGroupUser(ctx context.Context, request *authzpb.AddGroupUserRequest) (*authzpb.AddGroupUserResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "AddGroupUser"); err != nil {
+		return nil, err
+	}
+	ctx, err := withMembershipSource(ctx, request.GetSource())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.AddGroupUser(ctx, int(request.GetGroupId()), request.GetUser()); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.AddGroupUserResponse{}, nil
+}
+
+func (server *managementServer) UpdateGroupPermissions(ctx context.Context, request *authzpb.UpdateGroupPermissionsRequest) (*authzpb.UpdateGroupPermissionsResponse, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionWrite, "UpdateGroupPermissions")
+	if err != nil {
+		return nil, err
+	}
+	groupId := int(request.GetGroupId())
+	permissionIds := toInts(request.GetPermissionIds())
+	if err := server.refuseHighRiskGrants(ctx, policy, groupId, permissionIds); err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.UpdateGroupPermissions(ctx, groupId, permissionIds); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.UpdateGroupPermissionsResponse{}, nil
+}
+
+func (server *managementServer) GrantPermission(ctx context.Context, request *authzpb.GrantPermissionRequest) (*authzpb.GrantPermissionResponse, error) {
+	policy, _, err := server.authorize(ctx, api.PermissionWrite, "GrantPermission")
+	if err != nil {
+		return nil, err
+	}
+	groupId, permissionId := int(request.GetGroupId()), int(request.GetPermissionId())
+	if err := server.refuseHighRiskGrants(ctx, policy, groupId, []int{permissionId}); err != nil {
+		return nil, err
+	}
+
+	if err := server.manager.GrantPermission(ctx, groupId, permissionId); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.GrantPermissionResponse{}, nil
+}
+
+// refuseHighRiskGrants fails when any of the permissions is high risk and not granted to the group yet,
+// as such grants must go through the approval workflow of the administration API.
+func (server *managementServer) refuseHighRiskGrants(ctx context.Context, policy *authz.Policy, groupId int, permissionIds []int) error {
+	groups, err := server.manager.GetGroups(ctx, []int{groupId})
+	if err != nil {
+		return server.storeError(err)
+	}
+	if len(groups) == 0 {
+		return server.storeError(store.NewGroupNotFoundError())
+	}
+	permissions, err := server.manager.GetPermissions(ctx, permissionIds)
+	if err != nil {
+		return server.storeError(err)
+	}
+
+	granted := shared.NewSet[string]()
+	for _, permission := range policy.Permissions {
+		if slices.Contains(permission.Groups, groups[0].Name) {
+			granted.Add(permission.Name)
+		}
+	}
+	for _, permission := range permissions {
+		if permission.Risk == authz.RiskHigh && !granted.Contains(permission.Name) {
+			return status.Errorf(codes.FailedPrecondition,
+				"high risk permission %q can only be granted through the approval workflow of the administration API", permission.Name)
+		}
+	}
+	return nil
+}
+
+func (server *managementServer) SetPermissionRisk(ctx context.Context, request *authzpb.SetPermissionRiskRequest) (*authzpb.SetPermissionRiskResponse, error) {
+	_, caller, err := server.authorize(ctx, api.PermissionWrite, "SetPermissionRisk")
+	if err != nil {
+		return nil, err
+	}
+	risk, err := authz.ParseRiskLevel(request.GetRisk())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	permissionId := int(request.GetPermissionId())
+	permissions, err := server.manager.GetPermissions(ctx, []int{permissionId})
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	if len(permissions) == 0 {
+		return nil, server.storeError(store.NewPermissionNotFoundError())
+	}
+	if (risk == authz.RiskHigh || permissions[0].Risk == authz.RiskHigh) && !caller.mfa {
+		return nil, status.Error(codes.PermissionDenied, "multi-factor authentication is required to change high risk permissions")
+	}
+
+	if err := server.manager.SetPermissionRisk(ctx, permissionId, risk); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.SetPermissionRiskResponse{}, nil
+}
+
+func (server *managementServer) SetPermissionImplications(ctx context.Context, request *authzpb.SetPermissionImplicationsRequest) (*authzpb.SetPermissionImplicationsResponse, error) {
+	_, caller, err := server.authorize(ctx, api.PermissionWrite, "SetPermissionImplications")
+	if err != nil {
+		return nil, err
+	}
+
+	implied := toInts(request.GetImpliedIds())
+	if !caller.mfa && len(implied) > 0 {
+		permissions, err := server.manager.GetPermissions(ctx, implied)
+		if err != nil {
+			return nil, server.storeError(err)
+		}
+		for _, permission := range permissions {
+			if permission.Risk == authz.RiskHigh {
+				return nil, status.Error(codes.PermissionDenied, "multi-factor authentication is required to imply high risk permissions")
+			}
+		}
+	}
+
+	if err := server.manager.SetPermissionImplications(ctx, int(request.GetPermissionId()), implied); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.SetPermissionImplicationsResponse{}, nil
+}
+
+func (server *managementServer) SetPermissionDenials(ctx context.Context, request *authzpb.SetPermissionDenialsRequest) (*authzpb.SetPermissionDenialsResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "SetPermissionDenials"); err != nil {
+		return nil, err
+	}
+	err := server.manager.SetPermissionDenials(ctx, int(request.GetPermissionId()), toInts(request.GetGroupIds()), request.GetUsers())
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.SetPermissionDenialsResponse{}, nil
+}
+
+func (server *managementServer) SetPermissionCondition(ctx context.Context, request *authzpb.SetPermissionConditionRequest) (*authzpb.SetPermissionConditionResponse, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "SetPermissionCondition"); err != nil {
+		return nil, err
+	}
+	if err := server.manager.SetPermissionCondition(ctx, int(request.GetPermissionId()), request.GetCondition()); err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return &authzpb.SetPermissionConditionResponse{}, nil
+}
+
+func (server *managementServer) UpdateGroupMetadata(ctx context.Context, request *authzpb.UpdateGroupMetadataRequest) (*authzpb.GroupInfo, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "UpdateGroupMetadata"); err != nil {
+		return nil, err
+	}
+	patch, err := metadataPatch(request.GetPatch())
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := server.manager.UpdateGroupMetadata(ctx, int(request.GetGroupId()), patch)
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return groupInfo(*group), nil
+}
+
+func (server *managementServer) UpdatePermissionMetadata(ctx context.Context, request *authzpb.UpdatePermissionMetadataRequest) (*authzpb.PermissionInfo, error) {
+	if _, _, err := server.authorize(ctx, api.PermissionWrite, "UpdatePermissionMetadata"); err != nil {
+		return nil, err
+	}
+	patch, err := metadataPatch(request.GetPatch())
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := server.manager.UpdatePermissionMetadata(ctx, int(request.GetPermissionId()), patch)
+	if err != nil {
+		return nil, server.storeError(err)
+	}
+	server.issueToken(ctx)
+	return permissionInfo(*permission), nil
+}
+
+// withMembershipSource attributes the memberships changed with the context to the named source, manual when empty.
+func withMembershipSource(ctx context.Context, name string) (context.Context, error) {
+	source, err := store.ParseMembershipSource(name)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return store.WithMembershipSource(ctx, source), nil
+}
+
+// metadataPatch converts the patch of a request, a missing patch changes nothing.
+func metadataPatch(patch *authzpb.MetadataPatch) (store.MetadataPatch, error) {
+	converted := store.MetadataPatch{Description: patch.Description, ClearLabels: patch.GetClearLabels()}
+	if len(patch.GetSetLabels()) > 0 || len(patch.GetRemoveLabels()) > 0 {
+		converted.Labels = map[string]*string{}
+	}
+	for _, key := range patch.GetRemoveLabels() {
+		converted.Labels[key] = nil
+	}
+	for key, value := range patch.GetSetLabels() {
+		converted.Labels[key] = &value
+	}
+	if err := converted.Validate(); err != nil {
+		return store.MetadataPatch{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return converted, nil
+}
+
+func groupInfo(group store.GroupInfo[int]) *authzpb.GroupInfo {
+	return &authzpb.GroupInfo{Id: int64(group.ID), Name: group.Name, Version: int64(group.Version),
+		Description: group.Description, Labels: group.Labels}
+}
+
+func permissionInfo(permission store.PermissionInfo[int]) *authzpb.PermissionInfo {
+	return &authzpb.PermissionInfo{Id: int64(permission.ID), Name: permission.Name, Version: int64(permission.Version),
+		Risk: string(permission.Risk), Description: permission.Description, Labels: permission.Labels}
+}
+
+func toInts(ids []int64) []int {
+	converted := make([]int, len(ids))
+	for i, id := range ids {
+		converted[i] = int(id)
+	}
+	return converted
+}
@@ -0,0 +1,241 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// The metadata read by the proxy authentication of the test servers.
+const (
+	userMetadata = "x-forwarded-user"
+	mfaMetadata  = "x-forwarded-mfa"
+)
+
+// proxyAuthentication trusts the user and MFA metadata, like a server behind an authenticating proxy.
+func proxyAuthentication() []grpc.ServerOption {
+	return Authentication(authn.NewProxyHeaders(api.UserHeader, api.MFAHeader))
+}
+
+func policyServerPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			*authz.NewPermission(api.PermissionRead, []string{"admins", "viewers"}),
+			*authz.NewPermission(api.PermissionWrite, []string{"admins"}),
+			*authz.NewPermission(api.PermissionEvaluate, []string{"services"}),
+			*authz.NewPermission("recipes.read", []string{"cooks"}),
+			{Name: "recipes.edit", Groups: []string{"cooks"}, Condition: "resource.owner == user"},
+		},
+		[]authz.Group{
+			*authz.NewGroup("admins", []string{"admin"}),
+			*authz.NewGroup("viewers", []string{"viewer"}),
+			*authz.NewGroup("services", []string{"recipes-service"}),
+			*authz.NewGroup("cooks", []string{"alice"}),
+		},
+	)
+}
+
+// startPolicyServer serves the policy services answering from a mock manager and returns their clients.
+func startPolicyServer(t *testing.T) (*MockPolicyManager, authzpb.EvaluationClient, authzpb.ManagementClient) {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(policyServerPolicy(), nil)
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), proxyAuthentication()...)
+	server.RegisterPolicy(manager, nil)
+	conn := startServer(t, server)
+	return manager, authzpb.NewEvaluationClient(conn), authzpb.NewManagementClient(conn)
+}
+
+func as(user string, pairs ...string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), append([]string{userMetadata, user}, pairs...)...)
+}
+
+// TestEvaluation evaluates the policy over gRPC, checking the results and that callers need authz.evaluate.
+func TestEvaluation(t *testing.T) {
+	_, evaluation, _ := startPolicyServer(t)
+	ctx := as("recipes-service")
+
+	result, err := evaluation.Evaluate(ctx, &authzpb.EvaluateRequest{User: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cooks"}, result.Groups)
+	assert.Equal(t, []string{"recipes.read"}, result.Permissions)
+
+	check, err := evaluation.HasPermission(ctx, &authzpb.HasPermissionRequest{User: "alice", Permission: "recipes.write"})
+	assert.NoError(t, err)
+	assert.False(t, check.Allowed)
+	assert.Equal(t, string(authz.DenialPermissionUnknown), check.Reason)
+
+	check, err = evaluation.HasPermission(ctx, &authzpb.HasPermissionRequest{User: "alice", Permission: "recipes.edit"})
+	assert.NoError(t, err)
+	assert.False(t, check.Allowed)
+	assert.Equal(t, string(authz.DenialConditionNotMet), check.Reason)
+
+	attributes, err := structpb.NewStruct(map[string]any{"resource": map[string]any{"owner": "alice"}})
+	assert.NoError(t, err)
+	check, err = evaluation.HasPermission(ctx, &authzpb.HasPermissionRequest{User: "alice", Permission: "recipes.edit", Attributes: attributes})
+	assert.NoError(t, err)
+	assert.True(t, check.Allowed)
+
+	result, err = evaluation.Evaluate(ctx, &authzpb.EvaluateRequest{User: "alice", Attributes: attributes})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.read", "recipes.edit"}, result.Permissions)
+
+	member, err := evaluation.IsInGroup(ctx, &authzpb.IsInGroupRequest{User: "alice", Group: "cooks"})
+	assert.NoError(t, err)
+	assert.True(t, member.Member)
+
+	_, err = evaluation.Evaluate(as("admin"), &authzpb.EvaluateRequest{User: "alice"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = evaluation.Evaluate(context.Background(), &authzpb.EvaluateRequest{User: "alice"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// TestManagement changes the policy over gRPC, checking the calls reach the store and store errors map to gRPC codes.
+func TestManagement(t *testing.T) {
+	t.Run("create group", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("CreateGroup", mock.Anything, "bakers").Return(7, nil)
+
+		response, err := management.CreateGroup(as("admin"), &authzpb.CreateGroupRequest{Name: "bakers"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), response.Id)
+	})
+
+	t.Run("read only callers cannot write", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+
+		_, err := management.CreateGroup(as("viewer"), &authzpb.CreateGroupRequest{Name: "bakers"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		manager.AssertNotCalled(t, "CreateGroup", mock.Anything, mock.Anything)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("ChangeGroupName", mock.Anything, 3, "cooks").Return(store.NewNameExistsError())
+		manager.On("DeleteUser", mock.Anything, "bob").Return(store.NewNoUserRecordsDeletedError())
+
+		_, err := management.ChangeGroupName(as("admin"), &authzpb.ChangeGroupNameRequest{GroupId: 3, Name: "cooks"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		_, err = management.DeleteUser(as("admin"), &authzpb.DeleteUserRequest{User: "bob"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("list groups by id", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("GetGroups", mock.Anything, []int{4}).Return([]store.GroupInfo[int]{{ID: 4, Name: "cooks", Version: 2}}, nil)
+
+		response, err := management.ListGroups(as("viewer"), &authzpb.ListGroupsRequest{Ids: []int64{4, 4}})
+		assert.NoError(t, err)
+		assert.Len(t, response.Groups, 1)
+		assert.Equal(t, "cooks", response.Groups[0].Name)
+	})
+
+	t.Run("high risk grants need the approval workflow", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("GetGroups", mock.Anything, []int{4}).Return([]store.GroupInfo[int]{{ID: 4, Name: "cooks"}}, nil)
+		manager.On("GetPermissions", mock.Anything, []int{9}).Return(
+			[]store.PermissionInfo[int]{{ID: 9, Name: "recipes.delete", Risk: authz.RiskHigh}}, nil)
+
+		_, err := management.GrantPermission(as("admin", mfaMetadata, "true"), &authzpb.GrantPermissionRequest{GroupId: 4, PermissionId: 9})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		manager.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("high risk changes need multi-factor authentication", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("GetPermissions", mock.Anything, []int{9}).Return([]store.PermissionInfo[int]{{ID: 9, Name: "recipes.delete"}}, nil)
+		manager.On("SetPermissionRisk", mock.Anything, 9, authz.RiskHigh).Return(nil)
+
+		request := &authzpb.SetPermissionRiskRequest{PermissionId: 9, Risk: "high"}
+		_, err := management.SetPermissionRisk(as("admin"), request)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = management.SetPermissionRisk(as("admin", mfaMetadata, "true"), request)
+		assert.NoError(t, err)
+		manager.AssertNumberOfCalls(t, "SetPermissionRisk", 1)
+	})
+
+	t.Run("read policy", func(t *testing.T) {
+		policy := policyServerPolicy()
+		policy.Folders = []authz.Folder{{Path: "kitchen", Groups: []string{"cooks"}, Grants: []string{"recipes.read"}, Inheritance: authz.InheritanceBlock}}
+		policy.Denials = []authz.Denial{{Permission: "recipes.read", Groups: []string{"viewers"}, Users: []string{"mallory"}}}
+		manager := new(MockPolicyManager)
+		manager.On("ReadPolicy", mock.Anything).Return(policy, nil)
+		server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), proxyAuthentication()...)
+		server.RegisterPolicy(manager, nil)
+		management := authzpb.NewManagementClient(startServer(t, server))
+
+		response, err := management.ReadPolicy(as("viewer"), &authzpb.ReadPolicyRequest{})
+		assert.NoError(t, err)
+		if assert.Len(t, response.Permissions, 5) {
+			assert.Equal(t, "resource.owner == user", response.Permissions[4].Condition)
+		}
+		if assert.Len(t, response.Folders, 1) {
+			assert.Equal(t, "kitchen", response.Folders[0].Path)
+			assert.Equal(t, []string{"recipes.read"}, response.Folders[0].Grants)
+			assert.Equal(t, "block", response.Folders[0].Inheritance)
+		}
+		if assert.Len(t, response.Denials, 1) {
+			assert.Equal(t, "recipes.read", response.Denials[0].Permission)
+			assert.Equal(t, []string{"viewers"}, response.Denials[0].Groups)
+			assert.Equal(t, []string{"mallory"}, response.Denials[0].Users)
+		}
+	})
+
+	t.Run("set permission denials", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("SetPermissionDenials", mock.Anything, 9, []int{4}, []string{"mallory"}).Return(nil)
+		manager.On("SetPermissionDenials", mock.Anything, 9, []int{7}, []string(nil)).Return(store.NewGroupNotFoundError())
+
+		_, err := management.SetPermissionDenials(as("admin"), &authzpb.SetPermissionDenialsRequest{PermissionId: 9, GroupIds: []int64{4}, Users: []string{"mallory"}})
+		assert.NoError(t, err)
+		_, err = management.SetPermissionDenials(as("admin"), &authzpb.SetPermissionDenialsRequest{PermissionId: 9, GroupIds: []int64{7}})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = management.SetPermissionDenials(as("viewer"), &authzpb.SetPermissionDenialsRequest{PermissionId: 9})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("set permission condition", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		manager.On("SetPermissionCondition", mock.Anything, 9, "resource.owner == user").Return(nil)
+		manager.On("SetPermissionCondition", mock.Anything, 9, "resource.owner ==").Return(store.NewInvalidArgumentError())
+
+		_, err := management.SetPermissionCondition(as("admin"), &authzpb.SetPermissionConditionRequest{PermissionId: 9, Condition: "resource.owner == user"})
+		assert.NoError(t, err)
+		_, err = management.SetPermissionCondition(as("admin"), &authzpb.SetPermissionConditionRequest{PermissionId: 9, Condition: "resource.owner =="})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = management.SetPermissionCondition(as("viewer"), &authzpb.SetPermissionConditionRequest{PermissionId: 9})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("update metadata", func(t *testing.T) {
+		manager, _, management := startPolicyServer(t)
+		description := "Bakery staff"
+		manager.On("UpdateGroupMetadata", mock.Anything, 4, mock.MatchedBy(func(patch store.MetadataPatch) bool {
+			return *patch.Description == description && *patch.Labels["team"] == "bakery" && patch.Labels["old"] == nil
+		})).Return(&store.GroupInfo[int]{ID: 4, Name: "cooks", Version: 3,
+			Metadata: store.Metadata{Description: description, Labels: map[string]string{"team": "bakery"}}}, nil)
+
+		response, err := management.UpdateGroupMetadata(as("admin"), &authzpb.UpdateGroupMetadataRequest{GroupId: 4,
+			Patch: &authzpb.MetadataPatch{Description: &description, SetLabels: map[string]string{"team": "bakery"}, RemoveLabels: []string{"old"}}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), response.Version)
+		assert.Equal(t, map[string]string{"team": "bakery"}, response.Labels)
+	})
+}
@@ -0,0 +1,86 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// startServer serves the server on a local port and returns a client connection to it.
+func startServer(t *testing.T, server *Server) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newTestServer(healthErr error) *Server {
+	manager := new(MockPolicyManager)
+	manager.On("Health", mock.Anything).Return(&store.Health{SchemaVersion: 5}, healthErr)
+	return NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// TestServer_Health checks the health of a server before and after a store health check, checking the reported status.
+func TestServer_Health(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(nil)
+	client := grpc_health_v1.NewHealthClient(startServer(t, server))
+
+	response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: ServiceName})
+	assert.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, response.Status)
+
+	server.CheckHealth(ctx)
+	response, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+}
+
+// TestServer_Health_StoreUnavailable checks the health of a server whose store is unavailable, checking it is not serving.
+func TestServer_Health_StoreUnavailable(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(errors.New("connection refused"))
+	client := grpc_health_v1.NewHealthClient(startServer(t, server))
+
+	server.CheckHealth(ctx)
+	response, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: ServiceName})
+	assert.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, response.Status)
+}
+
+// TestServer_Reflection lists the services through server reflection, checking the health service is listed.
+func TestServer_Reflection(t *testing.T) {
+	client := grpc_reflection_v1.NewServerReflectionClient(startServer(t, newTestServer(nil)))
+
+	stream, err := client.ServerReflectionInfo(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+	}))
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+
+	services := []string{}
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	assert.Contains(t, services, "grpc.health.v1.Health")
+}
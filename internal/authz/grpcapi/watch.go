@@ -0,0 +1,128 @@
+package grpcapi
+
+import (
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/api"
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/salmarsumi/recipes/internal/authz/watch"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBatchSize is the most changes sent in a single Watch response.
+const watchBatchSize = 100
+
+// RegisterWatch registers the Watch service of watch.proto, streaming the changes of the change log.
+// The notifier wakes the streams up as changes are made, and idle streams get a heartbeat every interval.
+// Callers are authorized against the policy of the manager, like the other services.
+func (server *Server) RegisterWatch(manager PolicyManager, changes watch.Store, notifier *watch.Notifier, heartbeat time.Duration) {
+	authzpb.RegisterWatchServer(server.grpc, &watchServer{
+		policyService: &policyService{manager: manager, logger: server.logger},
+		changes:       changes,
+		notifier:      notifier,
+		heartbeat:     heartbeat,
+		clock:         server.clock,
+	})
+}
+
+// watchServer implements the Watch service.
+type watchServer struct {
+	authzpb.UnimplementedWatchServer
+	*policyService
+	changes   watch.Store
+	notifier  *watch.Notifier
+	heartbeat time.Duration
+	clock     clock.Clock
+}
+
+func (server *watchServer) Watch(request *authzpb.WatchRequest, stream grpc.ServerStreamingServer[authzpb.WatchResponse]) error {
+	ctx := stream.Context()
+	if _, _, err := server.authorize(ctx, api.PermissionRead, "Watch"); err != nil {
+		return err
+	}
+
+	after, err := server.start(stream, request)
+	if err != nil {
+		return err
+	}
+
+	ticker := server.clock.NewTicker(server.heartbeat)
+	defer ticker.Stop()
+
+	sent := false
+	for {
+		// taken before reading, so a change logged while reading wakes the stream up again
+		changed := server.notifier.Changed()
+		for {
+			changes, err := server.changes.Changes(ctx, after, watchBatchSize)
+			if err != nil {
+				return server.storeError(err)
+			}
+			if len(changes) == 0 {
+				break
+			}
+			after = changes[len(changes)-1].ID
+			if err := stream.Send(watchResponse(changes, after)); err != nil {
+				return err
+			}
+			sent = true
+			if len(changes) < watchBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-changed:
+		case <-ticker.C():
+			if !sent {
+				if err := stream.Send(watchResponse(nil, after)); err != nil {
+					return err
+				}
+			}
+			sent = false
+		}
+	}
+}
+
+// start returns the id of the change the stream starts after: the one of the resume token,
+// the last one at the requested revision, or the last one logged.
+func (server *watchServer) start(stream grpc.ServerStreamingServer[authzpb.WatchResponse], request *authzpb.WatchRequest) (int64, error) {
+	if request.GetResumeToken() != "" {
+		after, err := watch.DecodeToken(request.GetResumeToken())
+		if err != nil {
+			return 0, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return after, nil
+	}
+	if request.GetSinceRevision() < 0 {
+		return 0, status.Error(codes.InvalidArgument, "since_revision must not be negative")
+	}
+
+	after, err := server.changes.LastChange(stream.Context(), request.GetSinceRevision())
+	if err != nil {
+		return 0, server.storeError(err)
+	}
+	return after, nil
+}
+
+// watchResponse returns the response sending the changes, resuming after the change with the given id.
+func watchResponse(changes []watch.Change, after int64) *authzpb.WatchResponse {
+	response := &authzpb.WatchResponse{ResumeToken: watch.EncodeToken(after)}
+	for _, change := range changes {
+		response.Changes = append(response.Changes, &authzpb.Change{
+			Kind:         string(change.Kind),
+			Revision:     change.Revision,
+			Group:        change.Group,
+			User:         change.User,
+			Permission:   change.Permission,
+			PreviousName: change.PreviousName,
+			Time:         change.Time.UTC().Format(time.RFC3339),
+		})
+	}
+	return response
+}
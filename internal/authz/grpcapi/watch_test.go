@@ -0,0 +1,142 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/grpcapi/authzpb"
+	"github.com/salmarsumi/recipes/internal/authz/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeChanges is an in-memory change log.
+type fakeChanges struct {
+	mu      sync.Mutex
+	changes []watch.Change
+}
+
+func (fake *fakeChanges) add(change watch.Change) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	change.ID = int64(len(fake.changes) + 1)
+	fake.changes = append(fake.changes, change)
+}
+
+func (fake *fakeChanges) Changes(ctx context.Context, after int64, limit int) ([]watch.Change, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	changes := []watch.Change{}
+	for _, change := range fake.changes {
+		if change.ID > after && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (fake *fakeChanges) LastChange(ctx context.Context, revision int64) (int64, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	last := int64(0)
+	for _, change := range fake.changes {
+		if revision == 0 || change.Revision <= revision {
+			last = change.ID
+		}
+	}
+	return last, nil
+}
+
+// startWatchServer serves the Watch service of a change log holding a change at revision 1 and one at revision 2.
+func startWatchServer(t *testing.T) (*fakeChanges, *watch.Notifier, *FakeClock, authzpb.WatchClient) {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(policyServerPolicy(), nil)
+	changes := &fakeChanges{}
+	changes.add(watch.Change{Kind: watch.MemberAdded, Revision: 1, Group: "cooks", User: "alice"})
+	changes.add(watch.Change{Kind: watch.GrantAdded, Revision: 2, Group: "cooks", Permission: "recipes.read"})
+	notifier := watch.NewNotifier()
+	fake := NewFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	server := NewServer(manager, slog.New(slog.NewTextHandler(io.Discard, nil)), proxyAuthentication()...)
+	server.clock = fake
+	server.RegisterWatch(manager, changes, notifier, time.Minute)
+	return changes, notifier, fake, authzpb.NewWatchClient(startServer(t, server))
+}
+
+// TestWatch follows the change log from a revision, checking the backlog, the changes notified and the resume tokens.
+func TestWatch(t *testing.T) {
+	changes, notifier, _, client := startWatchServer(t)
+	ctx, cancel := context.WithCancel(as("viewer"))
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &authzpb.WatchRequest{SinceRevision: 1})
+	assert.NoError(t, err)
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Len(t, response.Changes, 1)
+	assert.Equal(t, "grant_added", response.Changes[0].Kind)
+	assert.Equal(t, "recipes.read", response.Changes[0].Permission)
+	assert.Equal(t, watch.EncodeToken(2), response.ResumeToken)
+
+	changes.add(watch.Change{Kind: watch.MemberRemoved, Revision: 3, Group: "cooks", User: "alice"})
+	notifier.Notify()
+	response, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Len(t, response.Changes, 1)
+	assert.Equal(t, "member_removed", response.Changes[0].Kind)
+	assert.Equal(t, int64(3), response.Changes[0].Revision)
+	assert.Equal(t, watch.EncodeToken(3), response.ResumeToken)
+
+	resumed, err := client.Watch(ctx, &authzpb.WatchRequest{ResumeToken: watch.EncodeToken(1)})
+	assert.NoError(t, err)
+	response, err = resumed.Recv()
+	assert.NoError(t, err)
+	assert.Len(t, response.Changes, 2)
+	assert.Equal(t, watch.EncodeToken(3), response.ResumeToken)
+}
+
+// TestWatch_Heartbeat checks an idle stream gets a heartbeat resuming after the last change.
+func TestWatch_Heartbeat(t *testing.T) {
+	_, _, fake, client := startWatchServer(t)
+	ctx, cancel := context.WithCancel(as("viewer"))
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &authzpb.WatchRequest{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	fake.Advance(time.Minute)
+	response, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Empty(t, response.Changes)
+	assert.Equal(t, watch.EncodeToken(2), response.ResumeToken)
+}
+
+// TestWatch_Errors checks callers need authz.read and resume tokens must be valid.
+func TestWatch_Errors(t *testing.T) {
+	_, _, _, client := startWatchServer(t)
+
+	for _, test := range []struct {
+		ctx     context.Context
+		request *authzpb.WatchRequest
+		code    codes.Code
+	}{
+		{context.Background(), &authzpb.WatchRequest{}, codes.Unauthenticated},
+		{as("alice"), &authzpb.WatchRequest{}, codes.PermissionDenied},
+		{as("viewer"), &authzpb.WatchRequest{ResumeToken: "invalid"}, codes.InvalidArgument},
+		{as("viewer"), &authzpb.WatchRequest{SinceRevision: -1}, codes.InvalidArgument},
+	} {
+		stream, err := client.Watch(test.ctx, test.request)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, test.code, status.Code(err))
+	}
+}
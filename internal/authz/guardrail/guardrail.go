@@ -0,0 +1,402 @@
+// Package guardrail enforces cardinality limits on the authorization policy,
+// such as the number of high risk permissions a single user may hold.
+package guardrail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/report"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// Kind identifies what a guardrail rule limits.
+type Kind string
+
+const (
+	// KindMaxHighRiskPermissions limits the number of high risk permissions held by any single user.
+	KindMaxHighRiskPermissions Kind = "max_high_risk_permissions"
+	// KindMaxGroupMembers limits the number of members of a group,
+	// or of every group when the rule does not name one.
+	KindMaxGroupMembers Kind = "max_group_members"
+)
+
+// Action is what happens when a change exceeds a guardrail.
+type Action string
+
+const (
+	// ActionBlock refuses the change with a ViolationError.
+	ActionBlock Action = "block"
+	// ActionReview applies the change and opens a review task for it.
+	ActionReview Action = "review"
+)
+
+// Rule is a single configured guardrail.
+type Rule struct {
+	Name   string `json:"name"`
+	Kind   Kind   `json:"kind"`
+	Group  string `json:"group,omitempty"`
+	Limit  int    `json:"limit"`
+	Action Action `json:"action,omitempty"`
+	// The percentage of the limit from which changes raise warnings, such as 80, so the limit
+	// can be dealt with before it is enforced. No warning is raised when it is 0.
+	WarnPercent int `json:"warn_percent,omitempty"`
+}
+
+// SoftLimit returns the count from which changes raise warnings, or 0 when the rule raises none.
+func (rule Rule) SoftLimit() int {
+	if rule.WarnPercent == 0 {
+		return 0
+	}
+	// rounded up, so a subject at the soft limit holds at least the percentage of the limit
+	return max((rule.Limit*rule.WarnPercent+99)/100, 1)
+}
+
+// Config is the guardrails configuration document.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Violation describes a subject exceeding the limit of a rule.
+type Violation struct {
+	Rule   string `json:"rule"`
+	Action Action `json:"action"`
+	// The user or group exceeding the limit, depending on the rule kind.
+	User  string `json:"user,omitempty"`
+	Group string `json:"group,omitempty"`
+	Count int    `json:"count"`
+	Limit int    `json:"limit"`
+}
+
+// String describes the violation in a human readable form.
+func (violation Violation) String() string {
+	if violation.User != "" {
+		return fmt.Sprintf("guardrail %s: user %s would hold %d high risk permissions (limit %d)",
+			violation.Rule, violation.User, violation.Count, violation.Limit)
+	}
+
+	return fmt.Sprintf("guardrail %s: group %s would have %d members (limit %d)",
+		violation.Rule, violation.Group, violation.Count, violation.Limit)
+}
+
+// Warning describes a subject reaching the soft limit of a rule without exceeding its limit.
+type Warning struct {
+	Rule string `json:"rule"`
+	// The user or group reaching the soft limit, depending on the rule kind.
+	User      string `json:"user,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Count     int    `json:"count"`
+	SoftLimit int    `json:"soft_limit"`
+	Limit     int    `json:"limit"`
+}
+
+// String describes the warning in a human readable form.
+func (warning Warning) String() string {
+	if warning.User != "" {
+		return fmt.Sprintf("guardrail %s: user %s holds %d high risk permissions (soft limit %d, limit %d)",
+			warning.Rule, warning.User, warning.Count, warning.SoftLimit, warning.Limit)
+	}
+
+	return fmt.Sprintf("guardrail %s: group %s has %d members (soft limit %d, limit %d)",
+		warning.Rule, warning.Group, warning.Count, warning.SoftLimit, warning.Limit)
+}
+
+// ViolationError is returned when a change is blocked by one or more guardrails.
+type ViolationError struct {
+	Violations []Violation
+}
+
+// Error lists the blocking violations.
+func (e *ViolationError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		descriptions = append(descriptions, violation.String())
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// Validate checks that the rule is complete and fills in the default action.
+func (rule *Rule) Validate() error {
+	if rule.Name == "" {
+		return errors.New("guardrail name is empty")
+	}
+	if rule.Kind != KindMaxHighRiskPermissions && rule.Kind != KindMaxGroupMembers {
+		return fmt.Errorf("guardrail %s: unknown kind %q", rule.Name, rule.Kind)
+	}
+	if rule.Group != "" && rule.Kind != KindMaxGroupMembers {
+		return fmt.Errorf("guardrail %s: group is only supported by %s", rule.Name, KindMaxGroupMembers)
+	}
+	if rule.Limit < 0 {
+		return fmt.Errorf("guardrail %s: limit is negative", rule.Name)
+	}
+	if rule.WarnPercent < 0 || rule.WarnPercent > 99 {
+		return fmt.Errorf("guardrail %s: warn_percent must be between 1 and 99, or 0 for no warning", rule.Name)
+	}
+
+	switch rule.Action {
+	case "":
+		rule.Action = ActionBlock
+	case ActionBlock, ActionReview:
+	default:
+		return fmt.Errorf("guardrail %s: unknown action %q", rule.Name, rule.Action)
+	}
+
+	return nil
+}
+
+// Read decodes and validates a JSON guardrails configuration from the given reader.
+func Read(r io.Reader) ([]Rule, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("decode guardrails: %w", err)
+	}
+
+	names := make(shared.Set[string], len(config.Rules))
+	for i := range config.Rules {
+		if err := config.Rules[i].Validate(); err != nil {
+			return nil, err
+		}
+		if !names.Insert(config.Rules[i].Name) {
+			return nil, fmt.Errorf("guardrail %s is defined twice", config.Rules[i].Name)
+		}
+	}
+
+	return config.Rules, nil
+}
+
+// Load reads the JSON guardrails configuration stored at the given path.
+func Load(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Check returns the violations introduced by changing the policy from before to after.
+// A subject already over the limit only violates the rule when the change increases its count,
+// so existing violations do not block unrelated changes.
+//
+// Parameters:
+//
+//	rules - the guardrails to enforce.
+//	before - the policy before the change.
+//	after - the policy after the change.
+//
+// Returns:
+//
+//	[]Violation - the violations, sorted by rule and subject.
+//	error - an error if a policy cannot be evaluated.
+func Check(rules []Rule, before *authz.Policy, after *authz.Policy) ([]Violation, error) {
+	violations := []Violation{}
+	for _, rule := range rules {
+		beforeCounts, afterCounts, err := changedCounts(rule, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, subject := range sortedSubjects(afterCounts) {
+			count := afterCounts[subject]
+			if count <= rule.Limit || count <= beforeCounts[subject] {
+				continue
+			}
+
+			violation := Violation{Rule: rule.Name, Action: rule.Action, Count: count, Limit: rule.Limit}
+			if rule.Kind == KindMaxHighRiskPermissions {
+				violation.User = subject
+			} else {
+				violation.Group = subject
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations, nil
+}
+
+// Warnings returns the warnings raised by changing the policy from before to after: the subjects
+// whose count the change increases to the soft limit of a rule or above, but not beyond its limit.
+// Subjects beyond the limit are reported by Check instead.
+//
+// Parameters:
+//
+//	rules - the guardrails to enforce.
+//	before - the policy before the change.
+//	after - the policy after the change.
+//
+// Returns:
+//
+//	[]Warning - the warnings, sorted by rule and subject.
+//	error - an error if a policy cannot be evaluated.
+func Warnings(rules []Rule, before *authz.Policy, after *authz.Policy) ([]Warning, error) {
+	warnings := []Warning{}
+	for _, rule := range rules {
+		softLimit := rule.SoftLimit()
+		if softLimit == 0 {
+			continue
+		}
+		beforeCounts, afterCounts, err := changedCounts(rule, before, after)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, subject := range sortedSubjects(afterCounts) {
+			count := afterCounts[subject]
+			if count < softLimit || count > rule.Limit || count <= beforeCounts[subject] {
+				continue
+			}
+			warnings = append(warnings, newWarning(rule, subject, count))
+		}
+	}
+
+	return warnings, nil
+}
+
+// Status is how close a subject is to the limit of a rule.
+type Status string
+
+const (
+	// StatusOK is below the soft limit, or below the limit when the rule has no soft limit.
+	StatusOK Status = "ok"
+	// StatusWarning is at the soft limit or above, up to the limit.
+	StatusWarning Status = "warning"
+	// StatusExceeded is beyond the limit.
+	StatusExceeded Status = "exceeded"
+)
+
+// SubjectUsage is the current count of a subject of a rule.
+type SubjectUsage struct {
+	// The user or group counted, depending on the rule kind.
+	User   string `json:"user,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Count  int    `json:"count"`
+	Status Status `json:"status"`
+}
+
+// RuleUsage is the current usage of a rule by every subject it counts.
+type RuleUsage struct {
+	Rule      string `json:"rule"`
+	Kind      Kind   `json:"kind"`
+	Limit     int    `json:"limit"`
+	SoftLimit int    `json:"soft_limit,omitempty"`
+	// The subjects, sorted by decreasing count then by name.
+	Subjects []SubjectUsage `json:"subjects"`
+}
+
+// Usage returns the current usage of every rule by the subjects of the policy, so the subjects
+// nearing a limit can be found before a change is refused.
+func Usage(rules []Rule, policy *authz.Policy) ([]RuleUsage, error) {
+	usages := make([]RuleUsage, 0, len(rules))
+	for _, rule := range rules {
+		counts, err := ruleCounts(rule, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		usage := RuleUsage{Rule: rule.Name, Kind: rule.Kind, Limit: rule.Limit, SoftLimit: rule.SoftLimit(), Subjects: []SubjectUsage{}}
+		for _, subject := range sortedSubjects(counts) {
+			count := counts[subject]
+			subjectUsage := SubjectUsage{Count: count, Status: StatusOK}
+			switch {
+			case count > rule.Limit:
+				subjectUsage.Status = StatusExceeded
+			case usage.SoftLimit > 0 && count >= usage.SoftLimit:
+				subjectUsage.Status = StatusWarning
+			}
+			if rule.Kind == KindMaxHighRiskPermissions {
+				subjectUsage.User = subject
+			} else {
+				subjectUsage.Group = subject
+			}
+			usage.Subjects = append(usage.Subjects, subjectUsage)
+		}
+		slices.SortStableFunc(usage.Subjects, func(a, b SubjectUsage) int { return b.Count - a.Count })
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+func newWarning(rule Rule, subject string, count int) Warning {
+	warning := Warning{Rule: rule.Name, Count: count, SoftLimit: rule.SoftLimit(), Limit: rule.Limit}
+	if rule.Kind == KindMaxHighRiskPermissions {
+		warning.User = subject
+	} else {
+		warning.Group = subject
+	}
+	return warning
+}
+
+// changedCounts returns the counts of the subjects of the rule before and after a change.
+func changedCounts(rule Rule, before *authz.Policy, after *authz.Policy) (map[string]int, map[string]int, error) {
+	beforeCounts, err := ruleCounts(rule, before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterCounts, err := ruleCounts(rule, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeCounts, afterCounts, nil
+}
+
+// ruleCounts returns the count of every subject of the rule in the policy.
+func ruleCounts(rule Rule, policy *authz.Policy) (map[string]int, error) {
+	switch rule.Kind {
+	case KindMaxHighRiskPermissions:
+		return highRiskCounts(policy)
+	case KindMaxGroupMembers:
+		return memberCounts(policy, rule.Group), nil
+	default:
+		return nil, fmt.Errorf("guardrail %s: unknown kind %q", rule.Name, rule.Kind)
+	}
+}
+
+// sortedSubjects returns the subjects counted, sorted by name.
+func sortedSubjects(counts map[string]int) []string {
+	subjects := make([]string, 0, len(counts))
+	for subject := range counts {
+		subjects = append(subjects, subject)
+	}
+	slices.Sort(subjects)
+	return subjects
+}
+
+// highRiskCounts returns the number of high risk permissions held by each user.
+func highRiskCounts(policy *authz.Policy) (map[string]int, error) {
+	risk, err := report.Risk(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(risk.HighRiskHolders))
+	for _, holder := range risk.HighRiskHolders {
+		counts[holder.User] = len(holder.Permissions)
+	}
+	return counts, nil
+}
+
+// memberCounts returns the number of distinct members of each group, or of the named group only.
+func memberCounts(policy *authz.Policy, group string) map[string]int {
+	counts := make(map[string]int)
+	for _, candidate := range policy.Groups {
+		if group != "" && candidate.Name != group {
+			continue
+		}
+		users := slices.Clone(candidate.Users)
+		slices.Sort(users)
+		counts[candidate.Name] = len(slices.Compact(users))
+	}
+	return counts
+}
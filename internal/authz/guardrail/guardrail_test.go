@@ -0,0 +1,175 @@
+package guardrail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "recipes.read", Groups: []string{"readers", "admins"}},
+			{Name: "recipes.delete", Groups: []string{"admins"}, Risk: authz.RiskHigh},
+			{Name: "recipes.purge", Groups: []string{}, Risk: authz.RiskHigh},
+		},
+		[]authz.Group{
+			*authz.NewGroup("readers", []string{"carol", "bob"}),
+			*authz.NewGroup("admins", []string{"alice"}),
+		},
+	)
+}
+
+// TestRead calls guardrail.Read with a valid document, checking for the rules with default actions.
+func TestRead(t *testing.T) {
+	rules, err := Read(strings.NewReader(`{"rules": [
+		{"name": "high-risk", "kind": "max_high_risk_permissions", "limit": 1},
+		{"name": "admins", "kind": "max_group_members", "group": "admins", "limit": 2, "action": "review"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Name: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 1, Action: ActionBlock},
+		{Name: "admins", Kind: KindMaxGroupMembers, Group: "admins", Limit: 2, Action: ActionReview},
+	}, rules)
+}
+
+// TestRead_Error calls guardrail.Read with invalid documents, checking for an error.
+func TestRead_Error(t *testing.T) {
+	documents := map[string]string{
+		"malformed":      `{"rules": [`,
+		"unknown field":  `{"rules": [], "extra": true}`,
+		"missing name":   `{"rules": [{"kind": "max_group_members", "limit": 1}]}`,
+		"unknown kind":   `{"rules": [{"name": "a", "kind": "max_users", "limit": 1}]}`,
+		"misplaced":      `{"rules": [{"name": "a", "kind": "max_high_risk_permissions", "group": "admins", "limit": 1}]}`,
+		"negative limit": `{"rules": [{"name": "a", "kind": "max_group_members", "limit": -1}]}`,
+		"unknown action": `{"rules": [{"name": "a", "kind": "max_group_members", "limit": 1, "action": "warn"}]}`,
+		"warn percent":   `{"rules": [{"name": "a", "kind": "max_group_members", "limit": 1, "warn_percent": 100}]}`,
+		"duplicate":      `{"rules": [{"name": "a", "kind": "max_group_members", "limit": 1}, {"name": "a", "kind": "max_group_members", "limit": 2}]}`,
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			rules, err := Read(strings.NewReader(document))
+			assert.Error(t, err)
+			assert.Nil(t, rules)
+		})
+	}
+}
+
+// TestCheck_HighRiskPermissions calls guardrail.Check with a grant pushing a user over the limit, checking for a violation.
+func TestCheck_HighRiskPermissions(t *testing.T) {
+	rules := []Rule{{Name: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 1, Action: ActionBlock}}
+	after := clonePolicy(testPolicy())
+	after.Permissions[2].Groups = []string{"admins"}
+
+	violations, err := Check(rules, testPolicy(), after)
+	assert.NoError(t, err)
+	assert.Equal(t, []Violation{{Rule: "high-risk", Action: ActionBlock, User: "alice", Count: 2, Limit: 1}}, violations)
+}
+
+// TestCheck_GroupMembers calls guardrail.Check with rules scoped to one and to every group, checking for the violations.
+func TestCheck_GroupMembers(t *testing.T) {
+	rules := []Rule{
+		{Name: "admins", Kind: KindMaxGroupMembers, Group: "admins", Limit: 1, Action: ActionReview},
+		{Name: "any", Kind: KindMaxGroupMembers, Limit: 2, Action: ActionBlock},
+	}
+	after := clonePolicy(testPolicy())
+	after.Groups[0].Users = append(after.Groups[0].Users, "dave")
+	after.Groups[1].Users = append(after.Groups[1].Users, "dave")
+
+	violations, err := Check(rules, testPolicy(), after)
+	assert.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Rule: "admins", Action: ActionReview, Group: "admins", Count: 2, Limit: 1},
+		{Rule: "any", Action: ActionBlock, Group: "readers", Count: 3, Limit: 2},
+	}, violations)
+}
+
+// TestCheck_ExistingViolation calls guardrail.Check with a change that does not worsen an existing violation, checking for no violations.
+func TestCheck_ExistingViolation(t *testing.T) {
+	rules := []Rule{{Name: "any", Kind: KindMaxGroupMembers, Limit: 1, Action: ActionBlock}}
+	after := clonePolicy(testPolicy())
+	after.Groups[0].Users = []string{"carol", "dave"}
+
+	violations, err := Check(rules, testPolicy(), after)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+// TestViolationError calls ViolationError.Error, checking the violations are described.
+func TestViolationError(t *testing.T) {
+	err := &ViolationError{Violations: []Violation{
+		{Rule: "high-risk", User: "alice", Count: 2, Limit: 1},
+		{Rule: "admins", Group: "admins", Count: 3, Limit: 2},
+	}}
+
+	assert.Equal(t, "guardrail high-risk: user alice would hold 2 high risk permissions (limit 1); "+
+		"guardrail admins: group admins would have 3 members (limit 2)", err.Error())
+}
+
+// TestRule_SoftLimit calls Rule.SoftLimit, checking the percentage of the limit is rounded up.
+func TestRule_SoftLimit(t *testing.T) {
+	assert.Equal(t, 0, Rule{Limit: 10}.SoftLimit())
+	assert.Equal(t, 8, Rule{Limit: 10, WarnPercent: 80}.SoftLimit())
+	assert.Equal(t, 3, Rule{Limit: 3, WarnPercent: 80}.SoftLimit())
+	assert.Equal(t, 1, Rule{Limit: 0, WarnPercent: 50}.SoftLimit())
+}
+
+// TestWarnings calls guardrail.Warnings with changes reaching, staying at and exceeding soft limits, checking for the warnings.
+func TestWarnings(t *testing.T) {
+	rules := []Rule{
+		{Name: "any", Kind: KindMaxGroupMembers, Limit: 4, Action: ActionBlock, WarnPercent: 75},
+		{Name: "admins", Kind: KindMaxGroupMembers, Group: "admins", Limit: 1, Action: ActionBlock, WarnPercent: 50},
+		{Name: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 3, Action: ActionBlock, WarnPercent: 60},
+		{Name: "quiet", Kind: KindMaxGroupMembers, Limit: 3, Action: ActionBlock},
+	}
+	after := clonePolicy(testPolicy())
+	after.Groups[0].Users = append(after.Groups[0].Users, "dave")
+	after.Groups[1].Users = append(after.Groups[1].Users, "erin")
+	after.Permissions[2].Groups = []string{"admins"}
+
+	warnings, err := Warnings(rules, testPolicy(), after)
+	assert.NoError(t, err)
+	// the admins exceed their limit, which Check reports
+	assert.Equal(t, []Warning{
+		{Rule: "any", Group: "readers", Count: 3, SoftLimit: 3, Limit: 4},
+		{Rule: "high-risk", User: "alice", Count: 2, SoftLimit: 2, Limit: 3},
+		{Rule: "high-risk", User: "erin", Count: 2, SoftLimit: 2, Limit: 3},
+	}, warnings)
+
+	warnings, err = Warnings(rules, after, clonePolicy(after))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings, "an unchanged count raises no new warning")
+}
+
+// TestWarning_String calls Warning.String, checking the warning is described.
+func TestWarning_String(t *testing.T) {
+	assert.Equal(t, "guardrail high-risk: user alice holds 2 high risk permissions (soft limit 2, limit 3)",
+		Warning{Rule: "high-risk", User: "alice", Count: 2, SoftLimit: 2, Limit: 3}.String())
+	assert.Equal(t, "guardrail any: group readers has 3 members (soft limit 3, limit 4)",
+		Warning{Rule: "any", Group: "readers", Count: 3, SoftLimit: 3, Limit: 4}.String())
+}
+
+// TestUsage calls guardrail.Usage, checking every subject is reported with its status, by decreasing count.
+func TestUsage(t *testing.T) {
+	rules := []Rule{
+		{Name: "any", Kind: KindMaxGroupMembers, Limit: 2, Action: ActionBlock, WarnPercent: 50},
+		{Name: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 0, Action: ActionBlock},
+	}
+	policy := testPolicy()
+	policy.Groups[0].Users = append(policy.Groups[0].Users, "dave")
+
+	usages, err := Usage(rules, policy)
+	assert.NoError(t, err)
+	assert.Equal(t, []RuleUsage{
+		{Rule: "any", Kind: KindMaxGroupMembers, Limit: 2, SoftLimit: 1, Subjects: []SubjectUsage{
+			{Group: "readers", Count: 3, Status: StatusExceeded},
+			{Group: "admins", Count: 1, Status: StatusWarning},
+		}},
+		{Rule: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 0, Subjects: []SubjectUsage{
+			{User: "alice", Count: 1, Status: StatusExceeded},
+		}},
+	}, usages)
+}
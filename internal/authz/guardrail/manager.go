@@ -0,0 +1,347 @@
+package guardrail
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// PolicyManager is the policy store guarded by the Manager.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// Reviewer opens review tasks for changes exceeding guardrails configured with ActionReview.
+type Reviewer interface {
+	Review(ctx context.Context, violations []Violation) error
+}
+
+// Notifier is told about the warnings raised by the changes applied, see Warnings.
+type Notifier interface {
+	Notify(ctx context.Context, warnings []Warning) error
+}
+
+// Manager is a PolicyManager enforcing guardrails on every change that can add
+// group members or grant permissions. Other operations are passed through unchanged.
+// Changes bringing a subject to the soft limit of a rule are applied, and their warnings logged
+// and sent to the Notifier if any.
+//
+// Guardrails are checked against the policy read before the change is applied,
+// so concurrent changes may together exceed a limit that each of them respects.
+type Manager struct {
+	PolicyManager
+	rules    []Rule
+	reviewer Reviewer
+	notifier Notifier
+	logger   *slog.Logger
+}
+
+var _ PolicyManager = (*Manager)(nil)
+
+// ManagerOption configures optional Manager settings.
+type ManagerOption func(*Manager)
+
+// WithNotifier sets the notifier told about the warnings. By default warnings are only logged.
+func WithNotifier(notifier Notifier) ManagerOption {
+	return func(manager *Manager) {
+		manager.notifier = notifier
+	}
+}
+
+// NewManager creates a new Manager enforcing the given rules on top of the given policy store.
+// The reviewer receives review violations; when it is nil, review violations block the change.
+func NewManager(next PolicyManager, rules []Rule, reviewer Reviewer, logger *slog.Logger, options ...ManagerOption) *Manager {
+	manager := &Manager{PolicyManager: next, rules: rules, reviewer: reviewer, logger: logger}
+	for _, option := range options {
+		option(manager)
+	}
+	return manager
+}
+
+// UpdateGroupUsers checks the guardrails before replacing the members of the group.
+func (manager *Manager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, groups map[int]string, _ map[int]string) {
+		name, ok := groups[groupId]
+		if !ok {
+			return
+		}
+		for i := range policy.Groups {
+			if policy.Groups[i].Name == name {
+				policy.Groups[i].Users = slices.Clone(users)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.UpdateGroupUsers(ctx, groupId, users); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// UpdateUserGroups checks the guardrails before replacing the groups of the user.
+func (manager *Manager) UpdateUserGroups(ctx context.Context, userId string, groupIds []int) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, groups map[int]string, _ map[int]string) {
+		names := make([]string, 0, len(groupIds))
+		for _, groupId := range groupIds {
+			names = append(names, groups[groupId])
+		}
+		for i := range policy.Groups {
+			group := &policy.Groups[i]
+			group.Users = slices.DeleteFunc(group.Users, func(user string) bool { return user == userId })
+			if slices.Contains(names, group.Name) {
+				group.Users = append(group.Users, userId)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.UpdateUserGroups(ctx, userId, groupIds); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// AddGroupUser checks the guardrails before adding the user to the group.
+func (manager *Manager) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, groups map[int]string, _ map[int]string) {
+		name, ok := groups[groupId]
+		if !ok {
+			return
+		}
+		for i := range policy.Groups {
+			group := &policy.Groups[i]
+			if group.Name == name && !slices.Contains(group.Users, userId) {
+				group.Users = append(group.Users, userId)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.AddGroupUser(ctx, groupId, userId); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// UpdateGroupPermissions checks the guardrails before replacing the permissions of the group.
+func (manager *Manager) UpdateGroupPermissions(ctx context.Context, groupId int, permissionIds []int) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, groups map[int]string, permissions map[int]string) {
+		name, ok := groups[groupId]
+		if !ok {
+			return
+		}
+		names := make([]string, 0, len(permissionIds))
+		for _, permissionId := range permissionIds {
+			names = append(names, permissions[permissionId])
+		}
+		for i := range policy.Permissions {
+			permission := &policy.Permissions[i]
+			permission.Groups = slices.DeleteFunc(permission.Groups, func(group string) bool { return group == name })
+			if slices.Contains(names, permission.Name) {
+				permission.Groups = append(permission.Groups, name)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.UpdateGroupPermissions(ctx, groupId, permissionIds); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// GrantPermission checks the guardrails before granting the permission to the group.
+func (manager *Manager) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, groups map[int]string, permissions map[int]string) {
+		name, ok := groups[groupId]
+		if !ok {
+			return
+		}
+		for i := range policy.Permissions {
+			permission := &policy.Permissions[i]
+			if permission.Name == permissions[permissionId] && !slices.Contains(permission.Groups, name) {
+				permission.Groups = append(permission.Groups, name)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.GrantPermission(ctx, groupId, permissionId); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// SetPermissionRisk checks the guardrails before changing the risk level of the permission.
+func (manager *Manager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, _ map[int]string, permissions map[int]string) {
+		for i := range policy.Permissions {
+			if policy.Permissions[i].Name == permissions[permissionId] {
+				policy.Permissions[i].Risk = risk
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.SetPermissionRisk(ctx, permissionId, risk); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// SetPermissionImplications checks the guardrails before replacing the permissions implied by the permission,
+// since implied permissions are granted to every group holding the implying one.
+func (manager *Manager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, _ map[int]string, permissions map[int]string) {
+		names := make([]string, 0, len(implied))
+		for _, impliedId := range implied {
+			names = append(names, permissions[impliedId])
+		}
+		for i := range policy.Permissions {
+			if policy.Permissions[i].Name == permissions[permissionId] {
+				policy.Permissions[i].Implies = names
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.SetPermissionImplications(ctx, permissionId, implied); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// Import checks the guardrails before replacing the whole policy with the document.
+func (manager *Manager) Import(ctx context.Context, document *store.PolicyDocument) error {
+	warnings, err := manager.guard(ctx, func(policy *authz.Policy, _ map[int]string, _ map[int]string) {
+		imported := document.Policy()
+		policy.Groups = imported.Groups
+		policy.Permissions = imported.Permissions
+		policy.Denials = imported.Denials
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.PolicyManager.Import(ctx, document); err != nil {
+		return err
+	}
+	manager.warn(ctx, warnings)
+	return nil
+}
+
+// guard simulates a change on a copy of the current policy and enforces the guardrails on the result,
+// returning the warnings to raise once the change is applied, see warn.
+// The change receives the group and permission names indexed by id.
+func (manager *Manager) guard(ctx context.Context, change func(policy *authz.Policy, groups map[int]string, permissions map[int]string)) ([]Warning, error) {
+	if len(manager.rules) == 0 {
+		return nil, nil
+	}
+
+	before, err := manager.PolicyManager.ReadPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupInfos, err := manager.PolicyManager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissionInfos, err := manager.PolicyManager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[int]string, len(groupInfos))
+	for _, group := range groupInfos {
+		groups[group.ID] = group.Name
+	}
+	permissions := make(map[int]string, len(permissionInfos))
+	for _, permission := range permissionInfos {
+		permissions[permission.ID] = permission.Name
+	}
+
+	after := clonePolicy(before)
+	change(after, groups, permissions)
+
+	violations, err := Check(manager.rules, before, after)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := Warnings(manager.rules, before, after)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) == 0 {
+		return warnings, nil
+	}
+
+	blocking := slices.DeleteFunc(slices.Clone(violations), func(violation Violation) bool {
+		return violation.Action == ActionReview && manager.reviewer != nil
+	})
+	if len(blocking) > 0 {
+		manager.logger.Warn("change blocked by guardrails", "violations", len(blocking))
+		return nil, &ViolationError{Violations: blocking}
+	}
+
+	// record the review before applying the change so no reviewed change goes unnoticed
+	return warnings, manager.reviewer.Review(ctx, violations)
+}
+
+// warn logs the warnings of an applied change and sends them to the notifier. A failure to notify
+// them is logged, since the change already happened.
+func (manager *Manager) warn(ctx context.Context, warnings []Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		manager.logger.Warn("change reached a guardrail soft limit", "warning", warning.String())
+	}
+	if manager.notifier == nil {
+		return
+	}
+	if err := manager.notifier.Notify(ctx, warnings); err != nil {
+		manager.logger.Error("failed to notify guardrail warnings", "warnings", len(warnings), "error", err)
+	}
+}
+
+// clonePolicy returns a deep copy of the policy.
+func clonePolicy(policy *authz.Policy) *authz.Policy {
+	clone := &authz.Policy{
+		Groups:      make([]authz.Group, 0, len(policy.Groups)),
+		Permissions: make([]authz.Permission, 0, len(policy.Permissions)),
+		Mode:        policy.Mode,
+	}
+	for _, group := range policy.Groups {
+		clone.Groups = append(clone.Groups, authz.Group{Name: group.Name, Users: slices.Clone(group.Users)})
+	}
+	for _, permission := range policy.Permissions {
+		clone.Permissions = append(clone.Permissions, authz.Permission{Name: permission.Name, Groups: slices.Clone(permission.Groups), Risk: permission.Risk, Implies: slices.Clone(permission.Implies), Condition: permission.Condition})
+	}
+	for _, denial := range policy.Denials {
+		clone.Denials = append(clone.Denials, authz.Denial{Permission: denial.Permission, Groups: slices.Clone(denial.Groups), Users: slices.Clone(denial.Users)})
+	}
+	return clone
+}
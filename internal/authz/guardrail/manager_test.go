@@ -0,0 +1,217 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// mockReviewer is a mock implementation of the Reviewer interface
+type mockReviewer struct {
+	mock.Mock
+}
+
+func (m *mockReviewer) Review(ctx context.Context, violations []Violation) error {
+	return m.Called(ctx, violations).Error(0)
+}
+
+// mockNotifier is a mock implementation of the Notifier interface
+type mockNotifier struct {
+	mock.Mock
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, warnings []Warning) error {
+	return m.Called(ctx, warnings).Error(0)
+}
+
+func setupManager(rules []Rule, reviewer Reviewer, options ...ManagerOption) (*MockPolicyManager, *Manager) {
+	next := new(MockPolicyManager)
+	next.On("ReadPolicy", mock.Anything).Return(testPolicy(), nil).Maybe()
+	next.On("ListGroups", mock.Anything).Return([]store.GroupInfo[int]{
+		{ID: 1, Name: "readers", Version: 1},
+		{ID: 2, Name: "admins", Version: 1},
+	}, nil).Maybe()
+	next.On("ListPermissions", mock.Anything).Return([]store.PermissionInfo[int]{
+		{ID: 1, Name: "recipes.read", Version: 1},
+		{ID: 2, Name: "recipes.delete", Version: 1},
+		{ID: 3, Name: "recipes.purge", Version: 1},
+	}, nil).Maybe()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return next, NewManager(next, rules, reviewer, logger, options...)
+}
+
+var highRiskRule = Rule{Name: "high-risk", Kind: KindMaxHighRiskPermissions, Limit: 1, Action: ActionBlock}
+
+// TestManager_GrantPermission_Blocked grants a second high risk permission, checking the change is refused.
+func TestManager_GrantPermission_Blocked(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{highRiskRule}, nil)
+
+	err := manager.GrantPermission(ctx, 2, 3)
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+	assert.Equal(t, "alice", violationErr.Violations[0].User)
+	next.AssertNotCalled(t, "GrantPermission", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestManager_UpdateGroupPermissions_Allowed replaces permissions within the limits, checking the change is applied.
+func TestManager_UpdateGroupPermissions_Allowed(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{highRiskRule}, nil)
+	next.On("UpdateGroupPermissions", ctx, 2, []int{1, 3}).Return(nil)
+
+	err := manager.UpdateGroupPermissions(ctx, 2, []int{1, 3})
+	assert.NoError(t, err)
+
+	next.AssertExpectations(t)
+}
+
+// TestManager_SetPermissionRisk_Blocked raises the risk of a held permission, checking the change is refused.
+func TestManager_SetPermissionRisk_Blocked(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{highRiskRule}, nil)
+
+	err := manager.SetPermissionRisk(ctx, 1, "high")
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+	next.AssertNotCalled(t, "SetPermissionRisk", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestManager_SetPermissionImplications_Blocked makes a held high risk permission imply another one, checking the change is refused.
+func TestManager_SetPermissionImplications_Blocked(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{highRiskRule}, nil)
+
+	err := manager.SetPermissionImplications(ctx, 2, []int{3})
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+	assert.Equal(t, []Violation{{Rule: "high-risk", Action: ActionBlock, User: "alice", Count: 2, Limit: 1}}, violationErr.Violations)
+	next.AssertNotCalled(t, "SetPermissionImplications", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestManager_UpdateUserGroups_Review adds a user to a full group with a review rule, checking a review is opened and the change applied.
+func TestManager_UpdateUserGroups_Review(t *testing.T) {
+	ctx := context.Background()
+	reviewer := new(mockReviewer)
+	next, manager := setupManager([]Rule{{Name: "admins", Kind: KindMaxGroupMembers, Group: "admins", Limit: 1, Action: ActionReview}}, reviewer)
+	reviewer.On("Review", ctx, []Violation{{Rule: "admins", Action: ActionReview, Group: "admins", Count: 2, Limit: 1}}).Return(nil)
+	next.On("UpdateUserGroups", ctx, "bob", []int{1, 2}).Return(nil)
+
+	err := manager.UpdateUserGroups(ctx, "bob", []int{1, 2})
+	assert.NoError(t, err)
+
+	reviewer.AssertExpectations(t)
+	next.AssertExpectations(t)
+}
+
+// TestManager_AddGroupUser_Blocked adds a user to a full group, checking the change is refused.
+func TestManager_AddGroupUser_Blocked(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{{Name: "admins", Kind: KindMaxGroupMembers, Group: "admins", Limit: 1, Action: ActionBlock}}, nil)
+
+	err := manager.AddGroupUser(ctx, 2, "bob")
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+	assert.Equal(t, []Violation{{Rule: "admins", Action: ActionBlock, Group: "admins", Count: 2, Limit: 1}}, violationErr.Violations)
+	next.AssertNotCalled(t, "AddGroupUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestManager_UpdateGroupUsers_ReviewFails fails to open a review, checking the change is not applied.
+func TestManager_UpdateGroupUsers_ReviewFails(t *testing.T) {
+	ctx := context.Background()
+	reviewer := new(mockReviewer)
+	next, manager := setupManager([]Rule{{Name: "any", Kind: KindMaxGroupMembers, Limit: 2, Action: ActionReview}}, reviewer)
+	reviewer.On("Review", ctx, mock.Anything).Return(errors.New("unavailable"))
+
+	err := manager.UpdateGroupUsers(ctx, 1, []string{"bob", "carol", "dave"})
+	assert.Error(t, err)
+
+	next.AssertNotCalled(t, "UpdateGroupUsers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestManager_UpdateGroupUsers_ReviewWithoutReviewer exceeds a review rule without a reviewer, checking the change is refused.
+func TestManager_UpdateGroupUsers_ReviewWithoutReviewer(t *testing.T) {
+	ctx := context.Background()
+	_, manager := setupManager([]Rule{{Name: "any", Kind: KindMaxGroupMembers, Limit: 2, Action: ActionReview}}, nil)
+
+	err := manager.UpdateGroupUsers(ctx, 1, []string{"bob", "carol", "dave"})
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+}
+
+// TestManager_NoRules checks changes are passed through without reading the policy when no rules are configured.
+func TestManager_NoRules(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockPolicyManager)
+	manager := NewManager(next, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	next.On("UpdateGroupUsers", ctx, 1, []string{"bob"}).Return(nil)
+
+	err := manager.UpdateGroupUsers(ctx, 1, []string{"bob"})
+	assert.NoError(t, err)
+
+	next.AssertNotCalled(t, "ReadPolicy", mock.Anything)
+}
+
+// TestManager_Import_Blocked imports a document granting alice a second high risk permission, checking the import is refused.
+func TestManager_Import_Blocked(t *testing.T) {
+	ctx := context.Background()
+	next, manager := setupManager([]Rule{highRiskRule}, nil)
+	document := store.DocumentFromPolicy(testPolicy())
+	document.Permissions[2].Groups = []string{"admins"}
+
+	err := manager.Import(ctx, document)
+
+	var violationErr *ViolationError
+	assert.ErrorAs(t, err, &violationErr)
+	assert.Equal(t, []Violation{{Rule: "high-risk", Action: ActionBlock, User: "alice", Count: 2, Limit: 1}}, violationErr.Violations)
+	next.AssertNotCalled(t, "Import", mock.Anything, mock.Anything)
+}
+
+var membersRule = Rule{Name: "members", Kind: KindMaxGroupMembers, Limit: 5, Action: ActionBlock, WarnPercent: 80}
+
+// TestManager_AddGroupUser_Warning adds a member reaching the soft limit, checking the change is applied and the warning notified.
+func TestManager_AddGroupUser_Warning(t *testing.T) {
+	ctx := context.Background()
+	notifier := new(mockNotifier)
+	next, manager := setupManager([]Rule{membersRule}, nil, WithNotifier(notifier))
+	next.On("AddGroupUser", ctx, 1, "dave").Return(nil)
+
+	// the soft limit of 80% of 5 members is 4, which 3 members do not reach
+	err := manager.AddGroupUser(ctx, 1, "dave")
+	assert.NoError(t, err)
+	notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+	manager.rules = []Rule{{Name: "members", Kind: KindMaxGroupMembers, Limit: 4, Action: ActionBlock, WarnPercent: 75}}
+	notifier.On("Notify", ctx, []Warning{{Rule: "members", Group: "readers", Count: 3, SoftLimit: 3, Limit: 4}}).Return(errors.New("sink down"))
+
+	err = manager.AddGroupUser(ctx, 1, "dave")
+	assert.NoError(t, err, "a failure to notify does not fail the applied change")
+	next.AssertNumberOfCalls(t, "AddGroupUser", 2)
+	notifier.AssertNumberOfCalls(t, "Notify", 1)
+}
+
+// TestManager_AddGroupUser_WarningNotApplied fails to apply a change reaching the soft limit, checking no warning is notified.
+func TestManager_AddGroupUser_WarningNotApplied(t *testing.T) {
+	ctx := context.Background()
+	notifier := new(mockNotifier)
+	next, manager := setupManager([]Rule{{Name: "members", Kind: KindMaxGroupMembers, Limit: 3, Action: ActionBlock, WarnPercent: 50}}, nil, WithNotifier(notifier))
+	next.On("AddGroupUser", ctx, 1, "dave").Return(store.NewDataBaseError())
+
+	err := manager.AddGroupUser(ctx, 1, "dave")
+	assert.Equal(t, store.NewDataBaseError(), err)
+	notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+}
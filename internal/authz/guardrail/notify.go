@@ -0,0 +1,58 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// WarningAction is the action of the audit events recorded for warnings by the AuditNotifier.
+const WarningAction = "guardrail.warning"
+
+// AuditNotifier records an audit event for every warning, so they reach the SIEM and the
+// alerting built on the audit log.
+type AuditNotifier struct {
+	sink  audit.Sink
+	actor func(ctx context.Context) string
+	clock clock.Clock
+}
+
+var _ Notifier = (*AuditNotifier)(nil)
+
+// NewAuditNotifier creates a new AuditNotifier recording to the given sink.
+// The actor function returns the user making the change from the request context.
+func NewAuditNotifier(sink audit.Sink, actor func(ctx context.Context) string) *AuditNotifier {
+	return &AuditNotifier{sink: sink, actor: actor, clock: clock.System()}
+}
+
+// Notify records one audit event per warning, with the user or group reaching the soft limit as subject.
+func (notifier *AuditNotifier) Notify(ctx context.Context, warnings []Warning) error {
+	actor := notifier.actor(ctx)
+	var errs []error
+	for _, warning := range warnings {
+		subject := warning.User
+		if subject == "" {
+			subject = "group " + warning.Group
+		}
+		err := notifier.sink.Record(ctx, audit.Event{
+			ID:      id.New(),
+			Time:    notifier.clock.Now(),
+			Actor:   actor,
+			Action:  WarningAction,
+			Subject: subject,
+			Details: map[string]any{
+				"rule":       warning.Rule,
+				"count":      warning.Count,
+				"soft_limit": warning.SoftLimit,
+				"limit":      warning.Limit,
+			},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+type recordingSink struct {
+	events []audit.Event
+	err    error
+}
+
+func (sink *recordingSink) Record(ctx context.Context, event audit.Event) error {
+	sink.events = append(sink.events, event)
+	return sink.err
+}
+
+// TestAuditNotifier_Notify notifies a user and a group warning, checking an audit event is recorded for each.
+func TestAuditNotifier_Notify(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink down")}
+	notifier := NewAuditNotifier(sink, func(ctx context.Context) string { return "admin" })
+	notifier.clock = NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	err := notifier.Notify(context.Background(), []Warning{
+		{Rule: "high-risk", User: "alice", Count: 2, SoftLimit: 2, Limit: 3},
+		{Rule: "any", Group: "readers", Count: 3, SoftLimit: 3, Limit: 4},
+	})
+	assert.ErrorContains(t, err, "sink down")
+
+	if assert.Len(t, sink.events, 2, "every warning is recorded even when some fail") {
+		for i := range sink.events {
+			assert.NotEmpty(t, sink.events[i].ID)
+			sink.events[i].ID = ""
+		}
+		assert.Equal(t, []audit.Event{
+			{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "admin", Action: WarningAction, Subject: "alice",
+				Details: map[string]any{"rule": "high-risk", "count": 2, "soft_limit": 2, "limit": 3}},
+			{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "admin", Action: WarningAction, Subject: "group readers",
+				Details: map[string]any{"rule": "any", "count": 3, "soft_limit": 3, "limit": 4}},
+		}, sink.events)
+	}
+}
@@ -0,0 +1,39 @@
+package guardrail
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/authz/approval"
+)
+
+// ApprovalReviewer opens a guardrail review request in the approval workflow for every violation.
+type ApprovalReviewer struct {
+	workflow  *approval.Workflow
+	requester func(ctx context.Context) string
+}
+
+var _ Reviewer = (*ApprovalReviewer)(nil)
+
+// NewApprovalReviewer creates a new ApprovalReviewer submitting requests to the given workflow.
+// The requester function returns the user making the change from the request context.
+func NewApprovalReviewer(workflow *approval.Workflow, requester func(ctx context.Context) string) *ApprovalReviewer {
+	return &ApprovalReviewer{workflow: workflow, requester: requester}
+}
+
+// Review submits one review request per violation.
+func (reviewer *ApprovalReviewer) Review(ctx context.Context, violations []Violation) error {
+	requestedBy := reviewer.requester(ctx)
+	for _, violation := range violations {
+		_, err := reviewer.workflow.Submit(ctx, approval.Request{
+			Kind:          approval.KindGuardrailReview,
+			UserID:        violation.User,
+			RequestedBy:   requestedBy,
+			Justification: violation.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+)
+
+// Audit is a Hook recording every change to the policy as an audit event, including the
+// changes that failed or were rejected, whose event holds the error.
+type Audit struct {
+	sink   audit.Sink
+	actor  func(ctx context.Context) string
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+var _ Hook = (*Audit)(nil)
+
+// NewAudit creates a new Audit recording the changes to the given sink, attributed to the
+// user the actor function finds in the operation context.
+func NewAudit(sink audit.Sink, actor func(ctx context.Context) string, logger *slog.Logger) *Audit {
+	return &Audit{sink: sink, actor: actor, logger: logger, clock: clock.System()}
+}
+
+// Before lets every operation through.
+func (hook *Audit) Before(ctx context.Context, operation Operation) (context.Context, error) {
+	return ctx, nil
+}
+
+// After records the change. A failure to record it is logged, since the change already happened.
+func (hook *Audit) After(ctx context.Context, operation Operation, result Result) {
+	if !operation.Write {
+		return
+	}
+
+	details := maps.Clone(operation.Args)
+	if result.Err != nil {
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["error"] = result.Err.Error()
+	}
+
+	err := hook.sink.Record(ctx, audit.Event{
+		ID:      id.New(),
+		Time:    hook.clock.Now(),
+		Actor:   hook.actor(ctx),
+		Action:  "policy." + operation.Name,
+		Subject: operation.Subject,
+		Details: details,
+	})
+	if err != nil {
+		hook.logger.Error("failed to record policy change audit event", "operation", operation.Name, "subject", operation.Subject, "error", err)
+	}
+}
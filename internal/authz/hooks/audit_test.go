@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/audit"
+	"github.com/salmarsumi/recipes/internal/shared/id"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+type recordingSink struct {
+	events []audit.Event
+	err    error
+}
+
+func (sink *recordingSink) Record(ctx context.Context, event audit.Event) error {
+	sink.events = append(sink.events, event)
+	return sink.err
+}
+
+func newTestAudit(sink audit.Sink) *Audit {
+	hook := NewAudit(sink, func(ctx context.Context) string { return "admin" }, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hook.clock = NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	return hook
+}
+
+// TestAudit_After completes a write, a failed write and a read, checking only the writes are recorded.
+func TestAudit_After(t *testing.T) {
+	sink := &recordingSink{}
+	hook := newTestAudit(sink)
+	ctx := context.Background()
+	update := Operation{Name: "update_group_users", Write: true, Subject: "group 3", Args: map[string]any{"users": []string{"alice"}}}
+
+	hook.After(ctx, update, Result{})
+	hook.After(ctx, Operation{Name: "delete_group", Write: true, Subject: "group 4"}, Result{Err: errors.New("group not found")})
+	hook.After(ctx, Operation{Name: "read_policy"}, Result{})
+
+	// the events are identified in the order they were recorded
+	assert.Len(t, sink.events, 2)
+	assert.Less(t, sink.events[0].ID, sink.events[1].ID)
+	for i := range sink.events {
+		_, err := id.Time(sink.events[i].ID)
+		assert.NoError(t, err)
+		sink.events[i].ID = ""
+	}
+
+	assert.Equal(t, []audit.Event{
+		{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "admin", Action: "policy.update_group_users", Subject: "group 3",
+			Details: map[string]any{"users": []string{"alice"}}},
+		{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Actor: "admin", Action: "policy.delete_group", Subject: "group 4",
+			Details: map[string]any{"error": "group not found"}},
+	}, sink.events)
+	assert.NotContains(t, update.Args, "error")
+}
+
+// TestAudit_After_SinkError fails to record an event, checking the failure does not panic.
+func TestAudit_After_SinkError(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink down")}
+	hook := newTestAudit(sink)
+
+	hook.After(context.Background(), Operation{Name: "create_group", Write: true}, Result{})
+	assert.Len(t, sink.events, 1)
+}
@@ -0,0 +1,323 @@
+// Package hooks runs callbacks around every policy store operation, so embedders can add
+// metrics, caching or business rules without wrapping every PolicyManager method themselves.
+package hooks
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// PolicyManager is the policy store whose operations are hooked.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// Operation describes a single call to the PolicyManager.
+type Operation struct {
+	// The operation name, such as "update_group_users".
+	Name string
+	// Whether the operation changes the policy.
+	Write bool
+	// The entity the operation acts on, such as "group 3", empty for operations on the whole policy.
+	Subject string
+	// The other arguments of the call by name, such as "users".
+	Args map[string]any
+}
+
+// Result is the outcome of an operation.
+type Result struct {
+	// The error returned to the caller, nil when the operation succeeded.
+	Err error
+	// The time the PolicyManager took, excluding the hooks.
+	Elapsed time.Duration
+}
+
+// Hook is called around every PolicyManager operation.
+type Hook interface {
+	// Before is called before the operation and returns the context the operation runs with.
+	// Returning an error rejects the operation and the error is returned to the caller,
+	// so business rules should return store errors such as InvalidArgument.
+	Before(ctx context.Context, operation Operation) (context.Context, error)
+	// After is called once the operation completed, or was rejected by a later hook.
+	After(ctx context.Context, operation Operation, result Result)
+}
+
+// Funcs is a Hook calling the functions that are set, for callbacks that need no state.
+type Funcs struct {
+	BeforeFunc func(ctx context.Context, operation Operation) error
+	AfterFunc  func(ctx context.Context, operation Operation, result Result)
+}
+
+var _ Hook = Funcs{}
+
+// Before calls BeforeFunc, when set.
+func (funcs Funcs) Before(ctx context.Context, operation Operation) (context.Context, error) {
+	if funcs.BeforeFunc == nil {
+		return ctx, nil
+	}
+	return ctx, funcs.BeforeFunc(ctx, operation)
+}
+
+// After calls AfterFunc, when set.
+func (funcs Funcs) After(ctx context.Context, operation Operation, result Result) {
+	if funcs.AfterFunc != nil {
+		funcs.AfterFunc(ctx, operation, result)
+	}
+}
+
+// Manager is a PolicyManager running hooks around every operation of the next PolicyManager.
+// Before hooks run in registration order and After hooks in reverse order, like middleware.
+//
+// It wraps every method explicitly rather than embedding the next PolicyManager, so operations
+// added to the interface cannot bypass the hooks.
+type Manager struct {
+	next  PolicyManager
+	hooks []Hook
+	clock clock.Clock
+}
+
+var _ PolicyManager = (*Manager)(nil)
+
+// NewManager creates a new Manager running the given hooks around the operations of next.
+func NewManager(next PolicyManager, hooks ...Hook) *Manager {
+	return &Manager{next: next, hooks: hooks, clock: clock.System()}
+}
+
+func invoke[T any](manager *Manager, ctx context.Context, operation Operation, call func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	var err error
+	ran := 0
+	for _, hook := range manager.hooks {
+		var next context.Context
+		if next, err = hook.Before(ctx, operation); err != nil {
+			break
+		}
+		ctx = next
+		ran++
+	}
+
+	var elapsed time.Duration
+	if err == nil {
+		start := manager.clock.Now()
+		value, err = call(ctx)
+		elapsed = manager.clock.Now().Sub(start)
+	}
+
+	for i := ran - 1; i >= 0; i-- {
+		manager.hooks[i].After(ctx, operation, Result{Err: err, Elapsed: elapsed})
+	}
+	return value, err
+}
+
+func invokeWrite(manager *Manager, ctx context.Context, operation Operation, call func(ctx context.Context) error) error {
+	operation.Write = true
+	_, err := invoke(manager, ctx, operation, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, call(ctx)
+	})
+	return err
+}
+
+func group(groupId int) string {
+	return "group " + strconv.Itoa(groupId)
+}
+
+func permission(permissionId int) string {
+	return "permission " + strconv.Itoa(permissionId)
+}
+
+func user(userId string) string {
+	return "user " + userId
+}
+
+func (manager *Manager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error {
+	operation := Operation{Name: "update_group_permissions", Subject: group(groupId), Args: map[string]any{"permissions": permissions}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.UpdateGroupPermissions(ctx, groupId, permissions)
+	})
+}
+
+func (manager *Manager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	operation := Operation{Name: "update_group_users", Subject: group(groupId), Args: map[string]any{"users": users}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.UpdateGroupUsers(ctx, groupId, users)
+	})
+}
+
+func (manager *Manager) UpdateUserGroups(ctx context.Context, userId string, groups []int) error {
+	operation := Operation{Name: "update_user_groups", Subject: user(userId), Args: map[string]any{"groups": groups}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.UpdateUserGroups(ctx, userId, groups)
+	})
+}
+
+func (manager *Manager) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	operation := Operation{Name: "add_group_user", Subject: group(groupId), Args: map[string]any{"user": userId}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.AddGroupUser(ctx, groupId, userId)
+	})
+}
+
+func (manager *Manager) CreateGroup(ctx context.Context, groupName string) (int, error) {
+	operation := Operation{Name: "create_group", Write: true, Args: map[string]any{"name": groupName}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (int, error) {
+		return manager.next.CreateGroup(ctx, groupName)
+	})
+}
+
+func (manager *Manager) CreatePermission(ctx context.Context, permissionName string) (int, error) {
+	operation := Operation{Name: "create_permission", Write: true, Args: map[string]any{"name": permissionName}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (int, error) {
+		return manager.next.CreatePermission(ctx, permissionName)
+	})
+}
+
+func (manager *Manager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) error {
+	operation := Operation{Name: "set_permission_risk", Subject: permission(permissionId), Args: map[string]any{"risk": risk}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.SetPermissionRisk(ctx, permissionId, risk)
+	})
+}
+
+func (manager *Manager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) error {
+	operation := Operation{Name: "set_permission_implications", Subject: permission(permissionId), Args: map[string]any{"implied": implied}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.SetPermissionImplications(ctx, permissionId, implied)
+	})
+}
+
+func (manager *Manager) SetPermissionDenials(ctx context.Context, permissionId int, groups []int, users []string) error {
+	operation := Operation{Name: "set_permission_denials", Subject: permission(permissionId), Args: map[string]any{"groups": groups, "users": users}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.SetPermissionDenials(ctx, permissionId, groups, users)
+	})
+}
+
+func (manager *Manager) SetPermissionCondition(ctx context.Context, permissionId int, condition string) error {
+	operation := Operation{Name: "set_permission_condition", Subject: permission(permissionId), Args: map[string]any{"condition": condition}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.SetPermissionCondition(ctx, permissionId, condition)
+	})
+}
+
+func (manager *Manager) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	operation := Operation{Name: "grant_permission", Subject: group(groupId), Args: map[string]any{"permission": permissionId}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.GrantPermission(ctx, groupId, permissionId)
+	})
+}
+
+func (manager *Manager) DeleteGroup(ctx context.Context, groupId int) (*store.GroupDeletion, error) {
+	operation := Operation{Name: "delete_group", Write: true, Subject: group(groupId)}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (*store.GroupDeletion, error) {
+		return manager.next.DeleteGroup(ctx, groupId)
+	})
+}
+
+func (manager *Manager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) error {
+	operation := Operation{Name: "change_group_name", Subject: group(groupId), Args: map[string]any{"name": newGroupName}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.ChangeGroupName(ctx, groupId, newGroupName)
+	})
+}
+
+func (manager *Manager) UpdateGroupMetadata(ctx context.Context, groupId int, patch store.MetadataPatch) (*store.GroupInfo[int], error) {
+	operation := Operation{Name: "update_group_metadata", Write: true, Subject: group(groupId), Args: map[string]any{"patch": patch}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (*store.GroupInfo[int], error) {
+		return manager.next.UpdateGroupMetadata(ctx, groupId, patch)
+	})
+}
+
+func (manager *Manager) UpdatePermissionMetadata(ctx context.Context, permissionId int, patch store.MetadataPatch) (*store.PermissionInfo[int], error) {
+	operation := Operation{Name: "update_permission_metadata", Write: true, Subject: permission(permissionId), Args: map[string]any{"patch": patch}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (*store.PermissionInfo[int], error) {
+		return manager.next.UpdatePermissionMetadata(ctx, permissionId, patch)
+	})
+}
+
+func (manager *Manager) DeleteUser(ctx context.Context, userId string) error {
+	operation := Operation{Name: "delete_user", Subject: user(userId)}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.DeleteUser(ctx, userId)
+	})
+}
+
+func (manager *Manager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return invoke(manager, ctx, Operation{Name: "read_policy"}, manager.next.ReadPolicy)
+}
+
+func (manager *Manager) Export(ctx context.Context) (*store.PolicyDocument, error) {
+	return invoke(manager, ctx, Operation{Name: "export_policy"}, manager.next.Export)
+}
+
+func (manager *Manager) Import(ctx context.Context, document *store.PolicyDocument) error {
+	operation := Operation{Name: "import_policy", Args: map[string]any{"groups": len(document.Groups), "permissions": len(document.Permissions)}}
+	return invokeWrite(manager, ctx, operation, func(ctx context.Context) error {
+		return manager.next.Import(ctx, document)
+	})
+}
+
+func (manager *Manager) ListGroups(ctx context.Context) ([]store.GroupInfo[int], error) {
+	return invoke(manager, ctx, Operation{Name: "list_groups"}, manager.next.ListGroups)
+}
+
+func (manager *Manager) ListPermissions(ctx context.Context) ([]store.PermissionInfo[int], error) {
+	return invoke(manager, ctx, Operation{Name: "list_permissions"}, manager.next.ListPermissions)
+}
+
+func (manager *Manager) GetGroups(ctx context.Context, ids []int) ([]store.GroupInfo[int], error) {
+	operation := Operation{Name: "get_groups", Args: map[string]any{"ids": ids}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) ([]store.GroupInfo[int], error) {
+		return manager.next.GetGroups(ctx, ids)
+	})
+}
+
+func (manager *Manager) GetPermissions(ctx context.Context, ids []int) ([]store.PermissionInfo[int], error) {
+	operation := Operation{Name: "get_permissions", Args: map[string]any{"ids": ids}}
+	return invoke(manager, ctx, operation, func(ctx context.Context) ([]store.PermissionInfo[int], error) {
+		return manager.next.GetPermissions(ctx, ids)
+	})
+}
+
+func (manager *Manager) ListGroupsPage(ctx context.Context, page paging.Request) ([]store.GroupInfo[int], string, error) {
+	var next string
+	operation := Operation{Name: "list_groups_page", Args: map[string]any{"limit": page.Limit}}
+	groups, err := invoke(manager, ctx, operation, func(ctx context.Context) ([]store.GroupInfo[int], error) {
+		groups, cursor, err := manager.next.ListGroupsPage(ctx, page)
+		next = cursor
+		return groups, err
+	})
+	return groups, next, err
+}
+
+func (manager *Manager) ListPermissionsPage(ctx context.Context, page paging.Request) ([]store.PermissionInfo[int], string, error) {
+	var next string
+	operation := Operation{Name: "list_permissions_page", Args: map[string]any{"limit": page.Limit}}
+	permissions, err := invoke(manager, ctx, operation, func(ctx context.Context) ([]store.PermissionInfo[int], error) {
+		permissions, cursor, err := manager.next.ListPermissionsPage(ctx, page)
+		next = cursor
+		return permissions, err
+	})
+	return permissions, next, err
+}
+
+func (manager *Manager) GetGroup(ctx context.Context, groupId int) (*store.GroupDetails[int, int, string], error) {
+	operation := Operation{Name: "get_group", Subject: group(groupId)}
+	return invoke(manager, ctx, operation, func(ctx context.Context) (*store.GroupDetails[int, int, string], error) {
+		return manager.next.GetGroup(ctx, groupId)
+	})
+}
+
+func (manager *Manager) GetUserGroups(ctx context.Context, userId string) ([]store.GroupInfo[int], error) {
+	operation := Operation{Name: "get_user_groups", Subject: user(userId)}
+	return invoke(manager, ctx, operation, func(ctx context.Context) ([]store.GroupInfo[int], error) {
+		return manager.next.GetUserGroups(ctx, userId)
+	})
+}
+
+func (manager *Manager) Health(ctx context.Context) (*store.Health, error) {
+	return invoke(manager, ctx, Operation{Name: "health"}, manager.next.Health)
+}
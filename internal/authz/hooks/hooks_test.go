@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+type contextKey struct{}
+
+// TestManager_Order runs two hooks around a write, checking the order of the calls and the operation they see.
+func TestManager_Order(t *testing.T) {
+	next := new(MockPolicyManager)
+	calls := []string{}
+	record := func(name string) Funcs {
+		return Funcs{
+			BeforeFunc: func(ctx context.Context, operation Operation) error {
+				calls = append(calls, name+" before "+operation.Name)
+				return nil
+			},
+			AfterFunc: func(ctx context.Context, operation Operation, result Result) {
+				calls = append(calls, name+" after "+operation.Name)
+			},
+		}
+	}
+	manager := NewManager(next, record("first"), record("second"))
+
+	next.On("UpdateGroupUsers", mock.Anything, 3, []string{"alice"}).Run(func(args mock.Arguments) {
+		calls = append(calls, "update")
+	}).Return(nil)
+
+	assert.NoError(t, manager.UpdateGroupUsers(context.Background(), 3, []string{"alice"}))
+	assert.Equal(t, []string{"first before update_group_users", "second before update_group_users", "update",
+		"second after update_group_users", "first after update_group_users"}, calls)
+}
+
+// TestManager_Reject rejects an operation in a hook, checking the store is not called and earlier hooks see the error.
+func TestManager_Reject(t *testing.T) {
+	next := new(MockPolicyManager)
+	var observed Result
+	var operation Operation
+	manager := NewManager(next,
+		Funcs{AfterFunc: func(ctx context.Context, op Operation, result Result) { operation, observed = op, result }},
+		Funcs{BeforeFunc: func(ctx context.Context, operation Operation) error { return store.NewInvalidArgumentError() }},
+	)
+
+	_, err := manager.CreateGroup(context.Background(), "cooks")
+	assert.Equal(t, store.NewInvalidArgumentError(), err)
+	assert.Equal(t, store.NewInvalidArgumentError(), observed.Err)
+	assert.Equal(t, Operation{Name: "create_group", Write: true, Args: map[string]any{"name": "cooks"}}, operation)
+
+	next.AssertNotCalled(t, "CreateGroup", mock.Anything, mock.Anything)
+}
+
+// TestManager_Context passes a value from a Before hook to the store, checking the result and elapsed time reach After.
+func TestManager_Context(t *testing.T) {
+	next := new(MockPolicyManager)
+	var observed Result
+	hook := Funcs{AfterFunc: func(ctx context.Context, operation Operation, result Result) { observed = result }}
+	manager := NewManager(next, contextHook{}, hook)
+	clock := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	manager.clock = clock
+
+	next.On("ListGroups", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Value(contextKey{}) == "hooked" })).
+		Run(func(mock.Arguments) { clock.Advance(time.Second) }).
+		Return([]store.GroupInfo[int]{{ID: 1, Name: "cooks"}}, nil)
+
+	groups, err := manager.ListGroups(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupInfo[int]{{ID: 1, Name: "cooks"}}, groups)
+	assert.Equal(t, Result{Elapsed: time.Second}, observed)
+}
+
+type contextHook struct{}
+
+func (contextHook) Before(ctx context.Context, operation Operation) (context.Context, error) {
+	return context.WithValue(ctx, contextKey{}, "hooked"), nil
+}
+
+func (contextHook) After(ctx context.Context, operation Operation, result Result) {}
@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OperationStats summarizes the calls of a single operation.
+type OperationStats struct {
+	Calls  int64 `json:"calls"`
+	Errors int64 `json:"errors"`
+	// The mean and maximum time the PolicyManager took.
+	Mean string `json:"mean"`
+	Max  string `json:"max"`
+}
+
+type counters struct {
+	calls  int64
+	errors int64
+	total  time.Duration
+	max    time.Duration
+}
+
+// Metrics is a Hook counting the calls, errors and latency of every operation.
+type Metrics struct {
+	mutex      sync.Mutex
+	operations map[string]*counters
+}
+
+var _ Hook = (*Metrics)(nil)
+
+// NewMetrics creates a new Metrics with no recorded calls.
+func NewMetrics() *Metrics {
+	return &Metrics{operations: map[string]*counters{}}
+}
+
+// Before lets every operation through.
+func (metrics *Metrics) Before(ctx context.Context, operation Operation) (context.Context, error) {
+	return ctx, nil
+}
+
+// After records the call.
+func (metrics *Metrics) After(ctx context.Context, operation Operation, result Result) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	stats, ok := metrics.operations[operation.Name]
+	if !ok {
+		stats = &counters{}
+		metrics.operations[operation.Name] = stats
+	}
+	stats.calls++
+	if result.Err != nil {
+		stats.errors++
+	}
+	stats.total += result.Elapsed
+	stats.max = max(stats.max, result.Elapsed)
+}
+
+// Snapshot returns the statistics of every operation called so far, by operation name.
+func (metrics *Metrics) Snapshot() map[string]OperationStats {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	snapshot := make(map[string]OperationStats, len(metrics.operations))
+	for name, stats := range metrics.operations {
+		snapshot[name] = OperationStats{
+			Calls:  stats.calls,
+			Errors: stats.errors,
+			Mean:   (stats.total / time.Duration(stats.calls)).String(),
+			Max:    stats.max.String(),
+		}
+	}
+	return snapshot
+}
+
+// Collect returns the Snapshot. It is a diagnostics Collector.
+func (metrics *Metrics) Collect(ctx context.Context) (any, error) {
+	return metrics.Snapshot(), nil
+}
@@ -0,0 +1,29 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics_Snapshot records calls of two operations, checking their counts and latencies.
+func TestMetrics_Snapshot(t *testing.T) {
+	metrics := NewMetrics()
+	ctx := context.Background()
+
+	metrics.After(ctx, Operation{Name: "read_policy"}, Result{Elapsed: 10 * time.Millisecond})
+	metrics.After(ctx, Operation{Name: "read_policy"}, Result{Elapsed: 30 * time.Millisecond})
+	metrics.After(ctx, Operation{Name: "delete_group"}, Result{Err: errors.New("group not found"), Elapsed: time.Millisecond})
+
+	assert.Equal(t, map[string]OperationStats{
+		"read_policy":  {Calls: 2, Mean: "20ms", Max: "30ms"},
+		"delete_group": {Calls: 1, Errors: 1, Mean: "1ms", Max: "1ms"},
+	}, metrics.Snapshot())
+
+	collected, err := metrics.Collect(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Snapshot(), collected)
+}
@@ -0,0 +1,250 @@
+package iam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// AWSAuthorizationDetails is the part of the output of aws iam get-account-authorization-details
+// the conversion reads: the users with their groups, the groups with their policies and the
+// managed policies. Other fields, such as the roles, are reported as unsupported.
+type AWSAuthorizationDetails struct {
+	UserDetailList  []AWSUser   `json:"UserDetailList"`
+	GroupDetailList []AWSGroup  `json:"GroupDetailList"`
+	RoleDetailList  []AWSRole   `json:"RoleDetailList"`
+	Policies        []AWSPolicy `json:"Policies"`
+}
+
+// AWSUser is an IAM user with the groups it belongs to.
+type AWSUser struct {
+	UserName                string              `json:"UserName"`
+	GroupList               []string            `json:"GroupList"`
+	UserPolicyList          []AWSInlinePolicy   `json:"UserPolicyList"`
+	AttachedManagedPolicies []AWSAttachedPolicy `json:"AttachedManagedPolicies"`
+}
+
+// AWSGroup is an IAM group with its inline and attached policies.
+type AWSGroup struct {
+	GroupName               string              `json:"GroupName"`
+	GroupPolicyList         []AWSInlinePolicy   `json:"GroupPolicyList"`
+	AttachedManagedPolicies []AWSAttachedPolicy `json:"AttachedManagedPolicies"`
+}
+
+// AWSRole is an IAM role. Roles are assumed rather than held, so they are not converted.
+type AWSRole struct {
+	RoleName string `json:"RoleName"`
+}
+
+// AWSInlinePolicy is a policy embedded in a user or group.
+type AWSInlinePolicy struct {
+	PolicyName     string            `json:"PolicyName"`
+	PolicyDocument AWSPolicyDocument `json:"PolicyDocument"`
+}
+
+// AWSAttachedPolicy is a managed policy attached to a user or group.
+type AWSAttachedPolicy struct {
+	PolicyName string `json:"PolicyName"`
+	PolicyArn  string `json:"PolicyArn"`
+}
+
+// AWSPolicy is a managed policy with its versions.
+type AWSPolicy struct {
+	PolicyName        string             `json:"PolicyName"`
+	Arn               string             `json:"Arn"`
+	DefaultVersionId  string             `json:"DefaultVersionId"`
+	PolicyVersionList []AWSPolicyVersion `json:"PolicyVersionList"`
+}
+
+// AWSPolicyVersion is a version of a managed policy.
+type AWSPolicyVersion struct {
+	Document         AWSPolicyDocument `json:"Document"`
+	VersionId        string            `json:"VersionId"`
+	IsDefaultVersion bool              `json:"IsDefaultVersion"`
+}
+
+// AWSPolicyDocument is an IAM policy document. The IAM API returns documents URL-encoded
+// while the AWS CLI decodes them, so both forms are accepted.
+type AWSPolicyDocument struct {
+	Version   string         `json:"Version"`
+	Statement []AWSStatement `json:"Statement"`
+}
+
+// AWSStatement is a statement of an IAM policy document. The fields the conversion cannot
+// express are only checked for presence.
+type AWSStatement struct {
+	Sid          string          `json:"Sid"`
+	Effect       string          `json:"Effect"`
+	Action       awsStrings      `json:"Action"`
+	NotAction    awsStrings      `json:"NotAction"`
+	Resource     awsStrings      `json:"Resource"`
+	NotResource  awsStrings      `json:"NotResource"`
+	Principal    json.RawMessage `json:"Principal"`
+	NotPrincipal json.RawMessage `json:"NotPrincipal"`
+	Condition    json.RawMessage `json:"Condition"`
+}
+
+// awsStrings is a policy element holding a single string or a list of strings.
+type awsStrings []string
+
+// UnmarshalJSON decodes a single string or a list of strings.
+func (values *awsStrings) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*values = awsStrings{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*values = list
+	return nil
+}
+
+// UnmarshalJSON decodes a policy document, URL-encoded or not.
+func (document *AWSPolicyDocument) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(data, &encoded); err != nil {
+			return err
+		}
+		decoded, err := url.QueryUnescape(encoded)
+		if err != nil {
+			return fmt.Errorf("decode policy document: %w", err)
+		}
+		data = []byte(decoded)
+	}
+
+	// a policy document holds a single statement or a list of statements
+	var raw struct {
+		Version   string          `json:"Version"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	document.Version = raw.Version
+	document.Statement = nil
+	statements := bytes.TrimSpace(raw.Statement)
+	switch {
+	case len(statements) == 0:
+		return nil
+	case statements[0] == '[':
+		return json.Unmarshal(statements, &document.Statement)
+	default:
+		var statement AWSStatement
+		if err := json.Unmarshal(statements, &statement); err != nil {
+			return err
+		}
+		document.Statement = []AWSStatement{statement}
+		return nil
+	}
+}
+
+// ReadAWS decodes the JSON output of aws iam get-account-authorization-details.
+func ReadAWS(r io.Reader) (*AWSAuthorizationDetails, error) {
+	details := &AWSAuthorizationDetails{}
+	if err := json.NewDecoder(r).Decode(details); err != nil {
+		return nil, fmt.Errorf("decode AWS authorization details: %w", err)
+	}
+	return details, nil
+}
+
+// ConvertAWS converts the IAM groups into groups holding their users, and the actions the policies
+// of each group allow on every resource into permissions granted to the group, named after the
+// actions such as s3:GetObject. Denies, conditions, principals, resource scopes, wildcard actions,
+// the policies of users and the roles are reported as unsupported.
+func ConvertAWS(details *AWSAuthorizationDetails) *Conversion {
+	b := newBuilder("aws-iam")
+
+	managed := make(map[string]AWSPolicy, len(details.Policies))
+	for _, policy := range details.Policies {
+		managed[policy.Arn] = policy
+	}
+
+	for _, group := range details.GroupDetailList {
+		b.group(group.GroupName)
+		for _, policy := range group.GroupPolicyList {
+			b.awsDocument(group.GroupName, "group "+group.GroupName+", policy "+policy.PolicyName, policy.PolicyDocument)
+		}
+		for _, attached := range group.AttachedManagedPolicies {
+			location := "group " + group.GroupName + ", policy " + attached.PolicyName
+			document, ok := defaultVersion(managed[attached.PolicyArn])
+			if !ok {
+				b.skip(location, "managed policy %s is missing from the input", attached.PolicyArn)
+				continue
+			}
+			b.awsDocument(group.GroupName, location, document)
+		}
+	}
+
+	for _, user := range details.UserDetailList {
+		location := "user " + user.UserName
+		for _, group := range user.GroupList {
+			b.member(location, group, user.UserName)
+		}
+		if len(user.UserPolicyList) > 0 || len(user.AttachedManagedPolicies) > 0 {
+			b.skip(location, "policies of users are not converted, only those of their groups")
+		}
+	}
+
+	for _, role := range details.RoleDetailList {
+		b.skip("role "+role.RoleName, "roles are not converted")
+	}
+
+	return b.conversion()
+}
+
+// defaultVersion returns the document of the default version of the managed policy.
+func defaultVersion(policy AWSPolicy) (AWSPolicyDocument, bool) {
+	for _, version := range policy.PolicyVersionList {
+		if version.IsDefaultVersion || version.VersionId == policy.DefaultVersionId {
+			return version.Document, true
+		}
+	}
+	return AWSPolicyDocument{}, false
+}
+
+// awsDocument grants the actions allowed by the statements of the document to the group.
+func (b *builder) awsDocument(group string, location string, document AWSPolicyDocument) {
+	for i, statement := range document.Statement {
+		statementLocation := fmt.Sprintf("%s, statement %d", location, i+1)
+		if statement.Sid != "" {
+			statementLocation += " (" + statement.Sid + ")"
+		}
+
+		switch {
+		case statement.Effect != "Allow":
+			b.skip(statementLocation, "%s statements are not supported, the authz policy only grants", statement.Effect)
+			continue
+		case len(statement.NotAction) > 0:
+			b.skip(statementLocation, "NotAction is not supported")
+			continue
+		case len(statement.NotResource) > 0:
+			b.skip(statementLocation, "NotResource is not supported")
+			continue
+		case len(statement.Principal) > 0 || len(statement.NotPrincipal) > 0:
+			b.skip(statementLocation, "principals of resource policies are not supported")
+			continue
+		case len(statement.Condition) > 0:
+			b.skip(statementLocation, "conditions are not supported")
+			continue
+		case len(statement.Resource) != 1 || statement.Resource[0] != "*":
+			b.skip(statementLocation, "statements scoped to resources %s are not supported, only those on every resource", strings.Join(statement.Resource, ", "))
+			continue
+		}
+
+		for _, action := range statement.Action {
+			if strings.ContainsAny(action, "*?") {
+				b.skip(statementLocation, "wildcard action %s is not expanded", action)
+				continue
+			}
+			b.grant(action, group)
+		}
+	}
+}
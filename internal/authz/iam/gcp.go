@@ -0,0 +1,115 @@
+package iam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GCPPolicy is an IAM policy of a GCP resource, as printed by gcloud projects get-iam-policy --format=json.
+type GCPPolicy struct {
+	Bindings     []GCPBinding    `json:"bindings"`
+	AuditConfigs json.RawMessage `json:"auditConfigs"`
+}
+
+// GCPBinding binds the members to a role, under a condition when set.
+type GCPBinding struct {
+	Role      string          `json:"role"`
+	Members   []string        `json:"members"`
+	Condition json.RawMessage `json:"condition"`
+}
+
+// GCPRole is the definition of a role, as printed by gcloud iam roles describe --format=json.
+type GCPRole struct {
+	Name                string   `json:"name"`
+	IncludedPermissions []string `json:"includedPermissions"`
+}
+
+// ReadGCP decodes a GCP IAM policy in JSON.
+func ReadGCP(r io.Reader) (*GCPPolicy, error) {
+	policy := &GCPPolicy{}
+	if err := json.NewDecoder(r).Decode(policy); err != nil {
+		return nil, fmt.Errorf("decode GCP policy: %w", err)
+	}
+	return policy, nil
+}
+
+// ReadGCPRoles decodes a single GCP role definition or a list of them in JSON.
+func ReadGCPRoles(r io.Reader) ([]GCPRole, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		roles := []GCPRole{}
+		if err := json.Unmarshal(data, &roles); err != nil {
+			return nil, fmt.Errorf("decode GCP roles: %w", err)
+		}
+		return roles, nil
+	}
+
+	var role GCPRole
+	if err := json.Unmarshal(data, &role); err != nil {
+		return nil, fmt.Errorf("decode GCP role: %w", err)
+	}
+	return []GCPRole{role}, nil
+}
+
+// ConvertGCP converts every bound role into a group, named after the role such as
+// roles/storage.objectViewer, holding the users and service accounts bound to it. The group is
+// granted the permissions of the role when its definition is given, or a single permission named
+// after the role otherwise. Conditional bindings, members that are not single identities, such as
+// Google groups and domains, and audit configurations are reported as unsupported, as are the
+// roles granted as a single permission.
+func ConvertGCP(policy *GCPPolicy, roles []GCPRole) *Conversion {
+	b := newBuilder("gcp-iam")
+
+	definitions := make(map[string]GCPRole, len(roles))
+	for _, role := range roles {
+		definitions[role.Name] = role
+	}
+
+	converted := map[string]bool{}
+	for i, binding := range policy.Bindings {
+		location := fmt.Sprintf("binding %d (%s)", i+1, binding.Role)
+		if len(binding.Condition) > 0 && string(binding.Condition) != "null" {
+			b.skip(location, "conditional bindings are not supported")
+			continue
+		}
+
+		b.group(binding.Role)
+		for _, member := range binding.Members {
+			kind, identity, _ := strings.Cut(member, ":")
+			switch kind {
+			case "user", "serviceAccount":
+				b.member(location, binding.Role, identity)
+			default:
+				b.skip(location, "member %s is not a single identity", member)
+			}
+		}
+
+		if converted[binding.Role] {
+			continue
+		}
+		converted[binding.Role] = true
+		definition, ok := definitions[binding.Role]
+		if !ok {
+			b.skip(location, "role %s has no definition, so it is granted as a single permission named after it", binding.Role)
+			b.grant(binding.Role, binding.Role)
+			continue
+		}
+		for _, permission := range definition.IncludedPermissions {
+			b.grant(permission, binding.Role)
+		}
+	}
+
+	if len(policy.AuditConfigs) > 0 && string(policy.AuditConfigs) != "null" {
+		b.skip("auditConfigs", "audit configurations are not converted")
+	}
+
+	return b.conversion()
+}
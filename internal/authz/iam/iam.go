@@ -0,0 +1,103 @@
+// Package iam converts the authorization policies of cloud IAM services, AWS IAM and GCP IAM,
+// into policy documents, so teams can migrate the authorization of their services to the authz store.
+// The constructs the authz policy cannot express, such as denies, conditions and resource scopes,
+// are left out of the document and listed in the conversion report rather than approximated.
+package iam
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// Unsupported is a construct of the source policy left out of the converted document,
+// or converted in a way the team migrating should review.
+type Unsupported struct {
+	// Where the construct is found, such as "group developers, policy ReadOnly, statement 2".
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+// Conversion is the policy document converted from a cloud IAM policy, with the report of the
+// constructs left out. The document is valid and can be imported, see store.PolicyManager.Import.
+type Conversion struct {
+	Document    *store.PolicyDocument `json:"document"`
+	Unsupported []Unsupported         `json:"unsupported"`
+}
+
+// builder accumulates the groups, memberships and grants of a conversion.
+type builder struct {
+	// labels is set on every converted group and permission, telling where they come from.
+	labels      map[string]string
+	members     map[string]shared.Set[string]
+	grants      map[string]shared.Set[string]
+	unsupported []Unsupported
+}
+
+func newBuilder(source string) *builder {
+	return &builder{
+		labels:  map[string]string{"imported-from": source},
+		members: map[string]shared.Set[string]{},
+		grants:  map[string]shared.Set[string]{},
+	}
+}
+
+// group adds the group, unless it is already there.
+func (b *builder) group(name string) {
+	if _, ok := b.members[name]; !ok {
+		b.members[name] = shared.NewSet[string]()
+	}
+}
+
+// member adds the user to the group. Users with an empty id are reported instead.
+func (b *builder) member(location string, group string, user string) {
+	user, err := store.NormalizeUserId(user)
+	if err != nil {
+		b.skip(location, "user with an empty id")
+		return
+	}
+	b.group(group)
+	b.members[group].Add(user)
+}
+
+// grant grants the permission to the group.
+func (b *builder) grant(permission string, group string) {
+	b.group(group)
+	if _, ok := b.grants[permission]; !ok {
+		b.grants[permission] = shared.NewSet[string]()
+	}
+	b.grants[permission].Add(group)
+}
+
+// skip reports a construct left out of the conversion.
+func (b *builder) skip(location string, format string, args ...any) {
+	b.unsupported = append(b.unsupported, Unsupported{Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+// conversion returns the document built so far, sorted by name like the exports of the store.
+func (b *builder) conversion() *Conversion {
+	document := &store.PolicyDocument{Groups: []store.GroupDocument{}, Permissions: []store.PermissionDocument{}}
+	for _, name := range slices.Sorted(maps.Keys(b.members)) {
+		members := []store.MemberDocument{}
+		for _, user := range shared.Sorted(b.members[name]) {
+			members = append(members, store.MemberDocument{User: user})
+		}
+		document.Groups = append(document.Groups, store.GroupDocument{Name: name, Labels: maps.Clone(b.labels), Members: members})
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.grants)) {
+		document.Permissions = append(document.Permissions, store.PermissionDocument{
+			Name:   name,
+			Labels: maps.Clone(b.labels),
+			Groups: shared.Sorted(b.grants[name]),
+		})
+	}
+
+	unsupported := b.unsupported
+	if unsupported == nil {
+		unsupported = []Unsupported{}
+	}
+	return &Conversion{Document: document, Unsupported: unsupported}
+}
@@ -0,0 +1,133 @@
+package iam
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+)
+
+const awsDetails = `{
+	"UserDetailList": [
+		{"UserName": "alice", "GroupList": ["developers", "admins"]},
+		{"UserName": "bob", "GroupList": ["developers"], "AttachedManagedPolicies": [{"PolicyName": "Extra", "PolicyArn": "arn:aws:iam::1:policy/Extra"}]}
+	],
+	"GroupDetailList": [
+		{
+			"GroupName": "developers",
+			"GroupPolicyList": [{
+				"PolicyName": "ReadRecipes",
+				"PolicyDocument": {"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "recipes:Read", "Resource": "*"}}
+			}],
+			"AttachedManagedPolicies": [{"PolicyName": "Buckets", "PolicyArn": "arn:aws:iam::1:policy/Buckets"}]
+		},
+		{
+			"GroupName": "admins",
+			"GroupPolicyList": [{
+				"PolicyName": "Admin",
+				"PolicyDocument": "%7B%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Action%22%3A%5B%22recipes%3ARead%22%2C%22recipes%3AWrite%22%2C%22recipes%3A*%22%5D%2C%22Resource%22%3A%22*%22%7D%2C%7B%22Effect%22%3A%22Deny%22%2C%22Action%22%3A%22recipes%3ADelete%22%2C%22Resource%22%3A%22*%22%7D%5D%7D"
+			}],
+			"AttachedManagedPolicies": [{"PolicyName": "Missing", "PolicyArn": "arn:aws:iam::1:policy/Missing"}]
+		}
+	],
+	"RoleDetailList": [{"RoleName": "deployer"}],
+	"Policies": [{
+		"PolicyName": "Buckets",
+		"Arn": "arn:aws:iam::1:policy/Buckets",
+		"DefaultVersionId": "v2",
+		"PolicyVersionList": [
+			{"VersionId": "v1", "Document": {"Statement": [{"Effect": "Allow", "Action": "s3:DeleteObject", "Resource": "*"}]}},
+			{"VersionId": "v2", "Document": {"Statement": [
+				{"Sid": "List", "Effect": "Allow", "Action": ["s3:ListBucket"], "Resource": ["*"]},
+				{"Sid": "Objects", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::recipes/*"},
+				{"Effect": "Allow", "Action": "s3:PutObject", "Resource": "*", "Condition": {"Bool": {"aws:SecureTransport": "true"}}}
+			]}}
+		]
+	}]
+}`
+
+func TestConvertAWS(t *testing.T) {
+	details, err := ReadAWS(strings.NewReader(awsDetails))
+	assert.NoError(t, err)
+
+	conversion := ConvertAWS(details)
+
+	labels := map[string]string{"imported-from": "aws-iam"}
+	assert.Equal(t, &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: "admins", Labels: labels, Members: []store.MemberDocument{{User: "alice"}}},
+			{Name: "developers", Labels: labels, Members: []store.MemberDocument{{User: "alice"}, {User: "bob"}}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes:Read", Labels: labels, Groups: []string{"admins", "developers"}},
+			{Name: "recipes:Write", Labels: labels, Groups: []string{"admins"}},
+			{Name: "s3:ListBucket", Labels: labels, Groups: []string{"developers"}},
+		},
+	}, conversion.Document)
+	assert.NoError(t, conversion.Document.Validate())
+
+	assert.Equal(t, []Unsupported{
+		{Location: "group developers, policy Buckets, statement 2 (Objects)", Message: "statements scoped to resources arn:aws:s3:::recipes/* are not supported, only those on every resource"},
+		{Location: "group developers, policy Buckets, statement 3", Message: "conditions are not supported"},
+		{Location: "group admins, policy Admin, statement 1", Message: "wildcard action recipes:* is not expanded"},
+		{Location: "group admins, policy Admin, statement 2", Message: "Deny statements are not supported, the authz policy only grants"},
+		{Location: "group admins, policy Missing", Message: "managed policy arn:aws:iam::1:policy/Missing is missing from the input"},
+		{Location: "user bob", Message: "policies of users are not converted, only those of their groups"},
+		{Location: "role deployer", Message: "roles are not converted"},
+	}, conversion.Unsupported)
+}
+
+func TestReadAWS_Invalid(t *testing.T) {
+	_, err := ReadAWS(strings.NewReader(`{"GroupDetailList": [{"GroupName": "a", "GroupPolicyList": [{"PolicyDocument": "%zz"}]}]}`))
+
+	assert.ErrorContains(t, err, "decode AWS authorization details")
+}
+
+func TestConvertGCP(t *testing.T) {
+	policy, err := ReadGCP(strings.NewReader(`{
+		"bindings": [
+			{"role": "roles/viewer", "members": ["user:alice@example.org", "group:cooks@example.org", "allUsers"]},
+			{"role": "projects/recipes/roles/editor", "members": ["serviceAccount:ci@recipes.iam.gserviceaccount.com", "user:bob@example.org"]},
+			{"role": "roles/owner", "members": ["user:carol@example.org"], "condition": {"title": "expires", "expression": "request.time < timestamp('2027-01-01T00:00:00Z')"}},
+			{"role": "roles/viewer", "members": ["user:bob@example.org"]}
+		],
+		"auditConfigs": [{"service": "allServices"}],
+		"etag": "BwX=",
+		"version": 3
+	}`))
+	assert.NoError(t, err)
+	roles, err := ReadGCPRoles(strings.NewReader(`[{"name": "projects/recipes/roles/editor", "includedPermissions": ["recipes.read", "recipes.write"]}]`))
+	assert.NoError(t, err)
+
+	conversion := ConvertGCP(policy, roles)
+
+	labels := map[string]string{"imported-from": "gcp-iam"}
+	assert.Equal(t, &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: "projects/recipes/roles/editor", Labels: labels, Members: []store.MemberDocument{{User: "bob@example.org"}, {User: "ci@recipes.iam.gserviceaccount.com"}}},
+			{Name: "roles/viewer", Labels: labels, Members: []store.MemberDocument{{User: "alice@example.org"}, {User: "bob@example.org"}}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes.read", Labels: labels, Groups: []string{"projects/recipes/roles/editor"}},
+			{Name: "recipes.write", Labels: labels, Groups: []string{"projects/recipes/roles/editor"}},
+			{Name: "roles/viewer", Labels: labels, Groups: []string{"roles/viewer"}},
+		},
+	}, conversion.Document)
+	assert.NoError(t, conversion.Document.Validate())
+
+	assert.Equal(t, []Unsupported{
+		{Location: "binding 1 (roles/viewer)", Message: "member group:cooks@example.org is not a single identity"},
+		{Location: "binding 1 (roles/viewer)", Message: "member allUsers is not a single identity"},
+		{Location: "binding 1 (roles/viewer)", Message: "role roles/viewer has no definition, so it is granted as a single permission named after it"},
+		{Location: "binding 3 (roles/owner)", Message: "conditional bindings are not supported"},
+		{Location: "auditConfigs", Message: "audit configurations are not converted"},
+	}, conversion.Unsupported)
+}
+
+func TestReadGCPRoles_Single(t *testing.T) {
+	roles, err := ReadGCPRoles(strings.NewReader(`{"name": "roles/viewer", "includedPermissions": ["recipes.read"]}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []GCPRole{{Name: "roles/viewer", IncludedPermissions: []string{"recipes.read"}}}, roles)
+}
@@ -0,0 +1,153 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// ImplicationCycleError is returned when permissions imply each other in a cycle.
+type ImplicationCycleError struct {
+	// The permissions forming the cycle, starting and ending with the same permission.
+	Cycle []string
+}
+
+func (err *ImplicationCycleError) Error() string {
+	return fmt.Sprintf("permission implications form a cycle: %s", strings.Join(err.Cycle, " -> "))
+}
+
+// Implied returns the names of every permission implied by the named permission,
+// directly or transitively, not including the permission itself.
+// Cycles are tolerated, every permission is visited once.
+func (policy *Policy) Implied(name string) []string {
+	implied := policy.expandImplied([]string{name})
+	for i, permission := range implied {
+		if permission == name {
+			return append(implied[:i], implied[i+1:]...)
+		}
+	}
+	return implied
+}
+
+// ValidateImplications checks that every implied permission exists in the policy
+// and that no permission implies itself, directly or transitively.
+func (policy *Policy) ValidateImplications() error {
+	implies := policy.implications()
+	for _, permission := range policy.Permissions {
+		for _, implied := range permission.Implies {
+			if !implies.Has(implied) {
+				return fmt.Errorf("permission %q implies unknown permission %q", permission.Name, implied)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(implies))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, permission := range path {
+				if permission == name {
+					start = i
+					break
+				}
+			}
+			cycle := append([]string{}, path[start:]...)
+			return &ImplicationCycleError{Cycle: append(cycle, name)}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, implied := range implies.Get(name) {
+			if err := visit(implied); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, permission := range policy.Permissions {
+		if err := visit(permission.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// implications maps every permission name to the names of the permissions it directly implies.
+func (policy *Policy) implications() shared.MultiMap[string, string] {
+	implies := shared.NewMultiMap[string, string](len(policy.Permissions))
+	for _, permission := range policy.Permissions {
+		implies.Add(permission.Name, permission.Implies...)
+	}
+	return implies
+}
+
+// expandImplied adds the permissions transitively implied by the given ones.
+// The result keeps the order of the policy permissions.
+func (policy *Policy) expandImplied(names []string) []string {
+	implies := policy.implications()
+	granted := make(shared.Set[string], len(names))
+	pending := append([]string{}, names...)
+	expanded := false
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !granted.Insert(name) {
+			continue
+		}
+		for _, implied := range implies.Get(name) {
+			if !granted.Contains(implied) {
+				pending = append(pending, implied)
+				expanded = true
+			}
+		}
+	}
+	if !expanded {
+		return names
+	}
+
+	result := make([]string, 0, len(granted))
+	for _, permission := range policy.Permissions {
+		if granted.Contains(permission.Name) {
+			result = append(result, permission.Name)
+		}
+	}
+	return result
+}
+
+// granting returns the name of the permission, of the wildcards above it and of every permission
+// implying them, directly or transitively, so holding any of them grants the permission.
+func (policy *Policy) granting(name string) shared.Set[string] {
+	impliedBy := shared.NewMultiMap[string, string](len(policy.Permissions))
+	pending := []string{name}
+	for _, permission := range policy.Permissions {
+		for _, implied := range permission.Implies {
+			impliedBy.Add(implied, permission.Name)
+		}
+		if permission.Name != name && MatchPermission(permission.Name, name) {
+			pending = append(pending, permission.Name)
+		}
+	}
+
+	granting := shared.NewSet[string]()
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if granting.Insert(current) {
+			pending = append(pending, impliedBy.Get(current)...)
+		}
+	}
+	return granting
+}
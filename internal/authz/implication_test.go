@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEvaluate_ImpliedPermissions calls policy.Evaluate with transitive implications, checking the implied permissions are granted.
+func TestEvaluate_ImpliedPermissions(t *testing.T) {
+	policy := NewPolicy(
+		[]Permission{
+			{Name: "admin", Groups: []string{"admins"}, Implies: []string{"write"}},
+			{Name: "list", Groups: []string{}},
+			{Name: "read", Groups: []string{"readers"}, Implies: []string{"list"}},
+			{Name: "write", Groups: []string{}, Implies: []string{"read"}},
+		},
+		[]Group{
+			*NewGroup("admins", []string{"adminuser"}),
+			*NewGroup("readers", []string{"readeruser"}),
+		},
+	)
+
+	adminResult, err := policy.Evaluate("adminuser")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "list", "read", "write"}, adminResult.Permissions)
+
+	readerResult, err := policy.Evaluate("readeruser")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"list", "read"}, readerResult.Permissions)
+}
+
+// TestEvaluate_ImplicationCycle calls policy.Evaluate with permissions implying each other, checking evaluation terminates.
+func TestEvaluate_ImplicationCycle(t *testing.T) {
+	policy := NewPolicy(
+		[]Permission{
+			{Name: "read", Groups: []string{}, Implies: []string{"write"}},
+			{Name: "write", Groups: []string{"editors"}, Implies: []string{"read"}},
+		},
+		[]Group{*NewGroup("editors", []string{"editor"})},
+	)
+
+	result, err := policy.Evaluate("editor")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read", "write"}, result.Permissions)
+}
+
+// TestImplied calls policy.Implied, checking for the transitively implied permissions without the permission itself.
+func TestImplied(t *testing.T) {
+	policy := NewPolicy(
+		[]Permission{
+			{Name: "admin", Implies: []string{"write"}},
+			{Name: "read", Implies: []string{"admin"}},
+			{Name: "write", Implies: []string{"read"}},
+		},
+		nil,
+	)
+
+	assert.Equal(t, []string{"read", "write"}, policy.Implied("admin"))
+	assert.Empty(t, policy.Implied("missing"))
+}
+
+// TestValidateImplications calls policy.ValidateImplications with valid and invalid implications, checking for the expected errors.
+func TestValidateImplications(t *testing.T) {
+	valid := NewPolicy([]Permission{
+		{Name: "admin", Implies: []string{"read", "write"}},
+		{Name: "read"},
+		{Name: "write", Implies: []string{"read"}},
+	}, nil)
+	assert.NoError(t, valid.ValidateImplications())
+
+	unknown := NewPolicy([]Permission{{Name: "admin", Implies: []string{"read"}}}, nil)
+	assert.EqualError(t, unknown.ValidateImplications(), `permission "admin" implies unknown permission "read"`)
+
+	cycle := NewPolicy([]Permission{
+		{Name: "admin", Implies: []string{"write"}},
+		{Name: "read", Implies: []string{"admin"}},
+		{Name: "write", Implies: []string{"read"}},
+	}, nil)
+	err := cycle.ValidateImplications()
+	var cycleErr *ImplicationCycleError
+	assert.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []string{"admin", "write", "read", "admin"}, cycleErr.Cycle)
+
+	self := NewPolicy([]Permission{{Name: "admin", Implies: []string{"admin"}}}, nil)
+	assert.EqualError(t, self.ValidateImplications(), "permission implications form a cycle: admin -> admin")
+}
@@ -0,0 +1,114 @@
+// Package loadtest drives synthetic evaluation and mutation traffic against the policy store
+// or the administration API and reports latency percentiles, to validate caching and store
+// changes under realistic policy sizes. The policy is generated with the seed package.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/client"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// Target is the system the traffic is sent to.
+type Target interface {
+	// Evaluate checks whether the user is granted the permission.
+	Evaluate(ctx context.Context, user string, permission string) (bool, error)
+	// UpdateGroupUsers replaces the users of the group.
+	UpdateGroupUsers(ctx context.Context, groupId int, users []string) error
+}
+
+// StoreTarget sends the traffic straight to the policy store, evaluating decisions the way
+// the API does, by reading the whole policy for every decision.
+type StoreTarget struct {
+	manager store.PolicyManager[int, int, string]
+}
+
+var _ Target = (*StoreTarget)(nil)
+
+// NewStoreTarget creates a new StoreTarget for the given store.
+func NewStoreTarget(manager store.PolicyManager[int, int, string]) *StoreTarget {
+	return &StoreTarget{manager: manager}
+}
+
+// Evaluate reads the policy and checks whether the user is granted the permission.
+func (target *StoreTarget) Evaluate(ctx context.Context, user string, permission string) (bool, error) {
+	policy, err := target.manager.ReadPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	session, err := policy.Session(user)
+	if err != nil {
+		return false, err
+	}
+	return session.HasPermission(permission), nil
+}
+
+// UpdateGroupUsers replaces the users of the group in the store.
+func (target *StoreTarget) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	return target.manager.UpdateGroupUsers(ctx, groupId, users)
+}
+
+// APITarget sends the traffic to a running authz server. Decisions are checked with the Go
+// client, so its cache can be measured too, and memberships are changed through the API.
+// The service must be granted the authz.evaluate and authz.write permissions.
+type APITarget struct {
+	baseURL    string
+	httpClient *http.Client
+	service    string
+	client     *client.Client
+}
+
+var _ Target = (*APITarget)(nil)
+
+// NewAPITarget creates a new APITarget for the server at baseURL, authenticating as service.
+// Decisions are cached in up to cacheSize entries; zero sends every decision to the server.
+func NewAPITarget(baseURL string, httpClient *http.Client, service string, cacheSize int) *APITarget {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &APITarget{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		service:    service,
+		client:     client.NewClient(baseURL, httpClient, client.WithService(service), client.WithCacheSize(cacheSize)),
+	}
+}
+
+// Evaluate checks the decision with the server.
+func (target *APITarget) Evaluate(ctx context.Context, user string, permission string) (bool, error) {
+	return target.client.HasPermission(ctx, user, permission)
+}
+
+// UpdateGroupUsers replaces the users of the group through the API.
+func (target *APITarget) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	body, err := json.Marshal(map[string][]string{"users": users})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut,
+		fmt.Sprintf("%s/api/v1/groups/%d/users", target.baseURL, groupId), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Forwarded-User", target.service)
+
+	response, err := target.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("update group users: %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/seed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeTarget counts the requests it receives, failing every mutation when failMutations is set.
+type fakeTarget struct {
+	evaluations   atomic.Int32
+	mutations     atomic.Int32
+	failMutations bool
+	mutex         sync.Mutex
+	lastUsers     []string
+}
+
+func (target *fakeTarget) Evaluate(ctx context.Context, user string, permission string) (bool, error) {
+	target.evaluations.Add(1)
+	return true, nil
+}
+
+func (target *fakeTarget) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	target.mutations.Add(1)
+	target.mutex.Lock()
+	target.lastUsers = users
+	target.mutex.Unlock()
+	if target.failMutations {
+		return errors.New("guardrail violated")
+	}
+	return nil
+}
+
+func testDataset() *seed.Dataset {
+	return &seed.Dataset{
+		Users:       []string{"alice", "bob"},
+		Permissions: []string{"read"},
+		GroupIds:    []int{10},
+		Members:     map[int][]string{10: {"alice"}},
+	}
+}
+
+func TestRun(t *testing.T) {
+	target := &fakeTarget{}
+
+	report := Run(context.Background(), target, testDataset(), Options{Workers: 4, Duration: 50 * time.Millisecond, MutationRatio: 0.5, Seed: 1})
+	assert.Len(t, report.Stats, 2)
+	assert.Equal(t, OperationEvaluate, report.Stats[0].Operation)
+	assert.Equal(t, OperationMutate, report.Stats[1].Operation)
+	assert.Positive(t, report.Stats[0].Requests)
+	assert.Positive(t, report.Stats[1].Requests)
+	assert.Zero(t, report.Stats[0].Errors)
+	assert.Empty(t, report.FirstError)
+
+	// every mutation changes a single user
+	assert.Contains(t, [][]string{{}, {"alice", "bob"}}, target.lastUsers)
+}
+
+func TestRun_Errors(t *testing.T) {
+	target := &fakeTarget{failMutations: true}
+
+	report := Run(context.Background(), target, testDataset(), Options{Workers: 1, Duration: 20 * time.Millisecond, MutationRatio: 1, Seed: 1})
+	assert.Len(t, report.Stats, 1)
+	assert.Equal(t, report.Stats[0].Requests, report.Stats[0].Errors)
+	assert.Zero(t, report.Stats[0].Throughput)
+	assert.Equal(t, "guardrail violated", report.FirstError)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	assert.Equal(t, time.Duration(5), Percentile(latencies, 50))
+	assert.Equal(t, time.Duration(9), Percentile(latencies, 90))
+	assert.Equal(t, time.Duration(10), Percentile(latencies, 99))
+	assert.Equal(t, time.Duration(1), Percentile(latencies, 0))
+	assert.Zero(t, Percentile(nil, 50))
+}
+
+func TestStoreTarget_Evaluate(t *testing.T) {
+	manager := new(MockPolicyManager)
+	manager.On("ReadPolicy", mock.Anything).Return(authz.NewPolicy(
+		[]authz.Permission{*authz.NewPermission("read", []string{"readers"}), *authz.NewPermission("recipes.*", []string{"readers"})},
+		[]authz.Group{*authz.NewGroup("readers", []string{"alice"})},
+	), nil)
+	target := NewStoreTarget(manager)
+
+	allowed, err := target.Evaluate(context.Background(), "alice", "read")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = target.Evaluate(context.Background(), "alice", "recipes.publish")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = target.Evaluate(context.Background(), "bob", "read")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestAPITarget(t *testing.T) {
+	var body map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "loadtest", r.Header.Get("X-Forwarded-User"))
+		switch r.URL.Path {
+		case "/api/v1/decisions":
+			_, _ = w.Write([]byte(`{"user":"alice","permission":"read","allowed":true,"policy_version":"v1","ttl_seconds":60}`))
+		case "/api/v1/groups/10/users":
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	target := NewAPITarget(server.URL, server.Client(), "loadtest", 0)
+
+	allowed, err := target.Evaluate(context.Background(), "alice", "read")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	assert.NoError(t, target.UpdateGroupUsers(context.Background(), 10, []string{"alice"}))
+	assert.Equal(t, []string{"alice"}, body["users"])
+
+	assert.Error(t, target.UpdateGroupUsers(context.Background(), 11, []string{"alice"}))
+}
@@ -0,0 +1,175 @@
+package loadtest
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/seed"
+)
+
+// Operation names the kind of traffic measured.
+type Operation string
+
+const (
+	OperationEvaluate Operation = "evaluate"
+	OperationMutate   Operation = "mutate"
+)
+
+// Options configures the traffic of a run.
+type Options struct {
+	// How many workers send requests concurrently.
+	Workers int
+	// How long the run lasts.
+	Duration time.Duration
+	// The share of requests changing memberships, between 0 and 1. The rest are evaluations.
+	MutationRatio float64
+	// The seed of the random choices, so runs can be repeated.
+	Seed uint64
+}
+
+// Stats summarizes the latencies of one operation.
+type Stats struct {
+	Operation Operation     `json:"operation"`
+	Requests  int           `json:"requests"`
+	Errors    int           `json:"errors"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+	// Successful requests per second.
+	Throughput float64 `json:"throughput"`
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Duration time.Duration `json:"duration"`
+	Stats    []Stats       `json:"stats"`
+	// The first error of the run, if any, to explain the error counts.
+	FirstError string `json:"first_error,omitempty"`
+}
+
+// recorder collects the latencies of a single worker, so workers never contend on a lock.
+type recorder struct {
+	latencies map[Operation][]time.Duration
+	errors    map[Operation]int
+	firstErr  error
+}
+
+// Run sends traffic to the target until the duration elapses or the context is done.
+// Every mutation swaps one random user in or out of a random group, so the policy keeps its size.
+func Run(ctx context.Context, target Target, dataset *seed.Dataset, options Options) Report {
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+
+	workers := max(options.Workers, 1)
+	recorders := make([]*recorder, workers)
+	started := time.Now()
+
+	var wait sync.WaitGroup
+	for i := range workers {
+		recorders[i] = &recorder{latencies: map[Operation][]time.Duration{}, errors: map[Operation]int{}}
+		random := rand.New(rand.NewPCG(options.Seed, uint64(i)))
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			work(ctx, target, dataset, options.MutationRatio, random, recorders[i])
+		}()
+	}
+	wait.Wait()
+
+	return summarize(recorders, time.Since(started))
+}
+
+func work(ctx context.Context, target Target, dataset *seed.Dataset, mutationRatio float64, random *rand.Rand, recorder *recorder) {
+	for ctx.Err() == nil {
+		operation := OperationEvaluate
+		if random.Float64() < mutationRatio {
+			operation = OperationMutate
+		}
+
+		started := time.Now()
+		var err error
+		switch operation {
+		case OperationEvaluate:
+			user := dataset.Users[random.IntN(len(dataset.Users))]
+			permission := dataset.Permissions[random.IntN(len(dataset.Permissions))]
+			_, err = target.Evaluate(ctx, user, permission)
+		case OperationMutate:
+			groupId := dataset.GroupIds[random.IntN(len(dataset.GroupIds))]
+			err = target.UpdateGroupUsers(ctx, groupId, swapUser(dataset, groupId, random))
+		}
+		elapsed := time.Since(started)
+
+		// requests cut short by the end of the run are not failures of the target
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			recorder.errors[operation]++
+			if recorder.firstErr == nil {
+				recorder.firstErr = err
+			}
+			continue
+		}
+		recorder.latencies[operation] = append(recorder.latencies[operation], elapsed)
+	}
+}
+
+// swapUser returns the users of the group with one random user added, or removed when it is a member.
+func swapUser(dataset *seed.Dataset, groupId int, random *rand.Rand) []string {
+	members := dataset.Members[groupId]
+	user := dataset.Users[random.IntN(len(dataset.Users))]
+	if index := slices.Index(members, user); index >= 0 {
+		return slices.Delete(slices.Clone(members), index, index+1)
+	}
+	return append(slices.Clone(members), user)
+}
+
+func summarize(recorders []*recorder, duration time.Duration) Report {
+	report := Report{Duration: duration}
+	for _, operation := range []Operation{OperationEvaluate, OperationMutate} {
+		var latencies []time.Duration
+		errors := 0
+		for _, recorder := range recorders {
+			latencies = append(latencies, recorder.latencies[operation]...)
+			errors += recorder.errors[operation]
+		}
+		if len(latencies) == 0 && errors == 0 {
+			continue
+		}
+
+		slices.Sort(latencies)
+		report.Stats = append(report.Stats, Stats{
+			Operation:  operation,
+			Requests:   len(latencies) + errors,
+			Errors:     errors,
+			P50:        Percentile(latencies, 50),
+			P90:        Percentile(latencies, 90),
+			P99:        Percentile(latencies, 99),
+			Max:        Percentile(latencies, 100),
+			Throughput: float64(len(latencies)) / duration.Seconds(),
+		})
+	}
+
+	for _, recorder := range recorders {
+		if recorder.firstErr != nil {
+			report.FirstError = recorder.firstErr.Error()
+			break
+		}
+	}
+	return report
+}
+
+// Percentile returns the nearest-rank percentile of the sorted latencies, or zero when there are none.
+func Percentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Load reads a Config from a JSON file such as
+//
+//	{"default": "info", "components": {"store": "debug"}}
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("decode log levels: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LevelFile applies the log levels of a JSON file, see Load, to Levels.
+// Watch reloads the file whenever it changes; a file that fails to load
+// is logged and the last good levels are kept.
+type LevelFile struct {
+	path    string
+	levels  *Levels
+	logger  *slog.Logger
+	modTime time.Time
+	size    int64
+	clock   clock.Clock
+}
+
+// NewLevelFile creates a new LevelFile and applies the levels of the file at the given path.
+func NewLevelFile(path string, levels *Levels, logger *slog.Logger) (*LevelFile, error) {
+	file := &LevelFile{path: path, levels: levels, logger: logger, clock: clock.System()}
+	if _, err := file.Reload(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Reload applies the levels of the file again when it changed since the last attempt.
+// It reports whether new levels were applied.
+func (file *LevelFile) Reload() (bool, error) {
+	info, err := os.Stat(file.path)
+	if err != nil {
+		return false, err
+	}
+	if !file.modTime.IsZero() && info.ModTime().Equal(file.modTime) && info.Size() == file.size {
+		return false, nil
+	}
+
+	// remember the attempt so a broken file is reported once rather than on every poll
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	config, err := Load(file.path)
+	if err != nil {
+		return false, err
+	}
+	return true, file.levels.Set(*config)
+}
+
+// Watch checks the file for changes every interval until the context is done.
+// It must not be called concurrently with Reload.
+func (file *LevelFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := file.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		reloaded, err := file.Reload()
+		if err != nil {
+			file.logger.Error("failed to reload log levels, keeping the previous levels", "path", file.path, "error", err)
+			continue
+		}
+		if reloaded {
+			file.logger.Info("log levels reloaded", "path", file.path, "default", file.levels.Config().Default)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package logging sets the log level of each component of the service separately, so operators
+// can turn on debug logging for one subsystem without flooding the logs with the others.
+// The component of a logger is the value of its ComponentKey attribute, see For, and the levels
+// can be changed at runtime, see LevelFile.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
+// ComponentKey is the attribute naming the component of a logger.
+const ComponentKey = "component"
+
+// Component is a subsystem of the service whose log level is set separately.
+type Component string
+
+const (
+	// ComponentStore is the policy store and the decorators around it.
+	ComponentStore Component = "store"
+	// ComponentAPI is the administration API and the web console.
+	ComponentAPI Component = "api"
+	// ComponentCache is the local copies of the policy, such as the standby file.
+	ComponentCache Component = "cache"
+	// ComponentSync is the background jobs keeping other systems in sync with the policy.
+	ComponentSync Component = "sync"
+)
+
+// Components lists every known component.
+var Components = []Component{ComponentStore, ComponentAPI, ComponentCache, ComponentSync}
+
+// Config is the minimum level of every component.
+type Config struct {
+	// The level of the records of components not listed, and of records of no component.
+	Default slog.Level `json:"default"`
+	// The level of the records of each listed component.
+	Components map[Component]slog.Level `json:"components,omitempty"`
+}
+
+// Validate checks every listed component is known, so a misspelled one is not silently ignored.
+func (config *Config) Validate() error {
+	for component := range config.Components {
+		if !slices.Contains(Components, component) {
+			return fmt.Errorf("unknown log component %q", component)
+		}
+	}
+	return nil
+}
+
+// Level returns the minimum level of the component.
+func (config *Config) Level(component Component) slog.Level {
+	if level, ok := config.Components[component]; ok {
+		return level
+	}
+	return config.Default
+}
+
+// Levels holds the current Config. It is safe for concurrent use, so the levels can be changed
+// while the handlers created by Handler are logging.
+type Levels struct {
+	config atomic.Pointer[Config]
+}
+
+// NewLevels creates new Levels logging every component at the Info level.
+func NewLevels() *Levels {
+	levels := &Levels{}
+	levels.config.Store(&Config{Default: slog.LevelInfo})
+	return levels
+}
+
+// Config returns a copy of the current configuration.
+func (levels *Levels) Config() Config {
+	config := *levels.config.Load()
+	config.Components = maps.Clone(config.Components)
+	return config
+}
+
+// Set replaces the current configuration, unless it is invalid.
+func (levels *Levels) Set(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	config.Components = maps.Clone(config.Components)
+	levels.config.Store(&config)
+	return nil
+}
+
+// Level returns the current minimum level of the component.
+func (levels *Levels) Level(component Component) slog.Level {
+	return levels.config.Load().Level(component)
+}
+
+// Handler returns a handler passing on to next the records at or above the current level of
+// their component. next must be enabled for every level the components may be set to,
+// usually by creating it with the Debug level.
+func (levels *Levels) Handler(next slog.Handler) slog.Handler {
+	return &componentHandler{levels: levels, next: next}
+}
+
+// For returns a logger tagging its records with the component, so they are filtered at its level.
+func For(logger *slog.Logger, component Component) *slog.Logger {
+	return logger.With(ComponentKey, string(component))
+}
+
+// componentHandler filters the records of a single component, learned from the ComponentKey
+// attribute added to the logger.
+type componentHandler struct {
+	levels    *Levels
+	component Component
+	// grouped is set once a group is opened, as a ComponentKey attribute in a group
+	// does not name the component of the logger.
+	grouped bool
+	next    slog.Handler
+}
+
+func (handler *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= handler.levels.Level(handler.component) && handler.next.Enabled(ctx, level)
+}
+
+func (handler *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	// handlers wrapping this one may not have asked Enabled first
+	if record.Level < handler.levels.Level(handler.component) {
+		return nil
+	}
+	return handler.next.Handle(ctx, record)
+}
+
+func (handler *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := handler.component
+	if !handler.grouped {
+		for _, attr := range attrs {
+			if attr.Key == ComponentKey {
+				component = Component(attr.Value.String())
+			}
+		}
+	}
+	return &componentHandler{levels: handler.levels, component: component, grouped: handler.grouped, next: handler.next.WithAttrs(attrs)}
+}
+
+func (handler *componentHandler) WithGroup(name string) slog.Handler {
+	return &componentHandler{levels: handler.levels, component: handler.component, grouped: true, next: handler.next.WithGroup(name)}
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/diagnostics"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(levels *Levels) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	next := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slog.New(levels.Handler(next)), &buf
+}
+
+// TestLevels_Handler logs debug records of two components, checking only the one set to debug is written.
+func TestLevels_Handler(t *testing.T) {
+	levels := NewLevels()
+	logger, buf := newTestLogger(levels)
+	assert.NoError(t, levels.Set(Config{Default: slog.LevelInfo, Components: map[Component]slog.Level{ComponentStore: slog.LevelDebug}}))
+
+	For(logger, ComponentStore).Debug("store query")
+	For(logger, ComponentAPI).Debug("api request")
+	For(logger, ComponentAPI).Info("api started")
+	logger.Debug("no component")
+
+	assert.Contains(t, buf.String(), "store query")
+	assert.Contains(t, buf.String(), "component=store")
+	assert.NotContains(t, buf.String(), "api request")
+	assert.Contains(t, buf.String(), "api started")
+	assert.NotContains(t, buf.String(), "no component")
+
+	// the new levels apply to the loggers already created
+	store := For(logger, ComponentStore).With("table", "groups")
+	assert.NoError(t, levels.Set(Config{Default: slog.LevelWarn}))
+	store.Info("store connected")
+	assert.NotContains(t, buf.String(), "store connected")
+}
+
+// TestLevels_Handler_Group adds a component attribute inside a group, checking it does not change the component.
+func TestLevels_Handler_Group(t *testing.T) {
+	levels := NewLevels()
+	logger, buf := newTestLogger(levels)
+	assert.NoError(t, levels.Set(Config{Default: slog.LevelInfo, Components: map[Component]slog.Level{ComponentStore: slog.LevelDebug}}))
+
+	logger.WithGroup("request").With(ComponentKey, string(ComponentStore)).Debug("grouped")
+	assert.Empty(t, buf.String())
+}
+
+// TestLevels_Handler_ErrorLog wraps the handler in the diagnostics error log, checking filtered records are still dropped.
+func TestLevels_Handler_ErrorLog(t *testing.T) {
+	levels := NewLevels()
+	var buf bytes.Buffer
+	errorLog := diagnostics.NewErrorLog(levels.Handler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), 10)
+	logger := slog.New(errorLog)
+
+	For(logger, ComponentSync).Debug("sync tick")
+	For(logger, ComponentSync).Error("sync failed")
+	assert.NotContains(t, buf.String(), "sync tick")
+	assert.Contains(t, buf.String(), "sync failed")
+	assert.Len(t, errorLog.Recent(), 1)
+}
+
+// TestLevels_Set_UnknownComponent sets the level of an unknown component, checking for an error and the previous levels are kept.
+func TestLevels_Set_UnknownComponent(t *testing.T) {
+	levels := NewLevels()
+	err := levels.Set(Config{Default: slog.LevelDebug, Components: map[Component]slog.Level{"stroe": slog.LevelDebug}})
+	assert.EqualError(t, err, `unknown log component "stroe"`)
+	assert.Equal(t, slog.LevelInfo, levels.Level(ComponentStore))
+}
+
+// TestLevelFile_Reload changes the levels file, checking the new levels are applied and a broken file keeps the previous ones.
+func TestLevelFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels.json")
+	modified := time.Now().Add(-time.Hour)
+	write := func(document string) {
+		assert.NoError(t, os.WriteFile(path, []byte(document), 0o600))
+		modified = modified.Add(time.Minute)
+		assert.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	write(`{"default": "warn", "components": {"store": "debug"}}`)
+
+	levels := NewLevels()
+	file, err := NewLevelFile(path, levels, slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, levels.Level(ComponentStore))
+	assert.Equal(t, slog.LevelWarn, levels.Level(ComponentAPI))
+
+	reloaded, err := file.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	write(`{"default": "info", "components": {"api": "DEBUG"}}`)
+	reloaded, err = file.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, slog.LevelInfo, levels.Level(ComponentStore))
+	assert.Equal(t, slog.LevelDebug, levels.Level(ComponentAPI))
+
+	write(`{"default": "loud"}`)
+	_, err = file.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, slog.LevelDebug, levels.Level(ComponentAPI))
+
+	write(`{"default": "info", "components": {"cahce": "debug"}}`)
+	_, err = file.Reload()
+	assert.EqualError(t, err, `unknown log component "cahce"`)
+	assert.Equal(t, slog.LevelDebug, levels.Level(ComponentAPI))
+}
+
+// TestNewLevelFile_Error_MissingFile creates a LevelFile for a missing file, checking for an error.
+func TestNewLevelFile_Error_MissingFile(t *testing.T) {
+	file, err := NewLevelFile(filepath.Join(t.TempDir(), "missing.json"), NewLevels(), slog.New(slog.NewTextHandler(new(bytes.Buffer), nil)))
+	assert.Error(t, err)
+	assert.Nil(t, file)
+}
@@ -0,0 +1,121 @@
+// Package middleware evaluates the policy in process for the callers of a service's HTTP handlers.
+// The Middleware verifies the bearer token of each request, evaluates the policy for its subject
+// and stores the result in the request context, where downstream handlers read it with FromContext
+// or require permissions with RequirePermission, without calling the decision API again.
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+)
+
+// PolicyReader supplies the current policy, such as a cache.CachedPolicyProvider.
+type PolicyReader interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+}
+
+// evaluationContextKey is the context key of the evaluation of the request subject.
+type evaluationContextKey struct{}
+
+// evaluation is the evaluation of the request subject stored in the context.
+type evaluation struct {
+	user   string
+	policy *authz.Policy
+	result *authz.PolicyEvaluationResult
+}
+
+// Middleware authenticates requests with bearer tokens and evaluates the policy for their subject.
+type Middleware struct {
+	tokens authn.Provider
+	policy PolicyReader
+	logger *slog.Logger
+}
+
+// NewMiddleware creates a new Middleware verifying bearer tokens with the given provider, such as
+// an authn.OIDC, and evaluating the policy read from the given reader.
+func NewMiddleware(tokens authn.Provider, policy PolicyReader, logger *slog.Logger) *Middleware {
+	return &Middleware{tokens: tokens, policy: policy, logger: logger}
+}
+
+// Wrap wraps the handler so it only runs for requests carrying a valid bearer token, with the
+// evaluation of the token subject in the context. Requests without a valid token are refused with
+// 401, and with 503 when the policy cannot be read.
+func (middleware *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := middleware.tokens.Authenticate(r)
+		if err != nil {
+			middleware.logger.Warn("invalid bearer token", "path", r.URL.Path, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if principal == nil || principal.User == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		policy, err := middleware.policy.ReadPolicy(r.Context())
+		if err != nil {
+			middleware.logger.Error("failed to read the policy", "error", err)
+			writeError(w, http.StatusServiceUnavailable, "authorization is unavailable")
+			return
+		}
+		result, err := policy.Evaluate(principal.User)
+		if err != nil {
+			middleware.logger.Error("failed to evaluate the policy", "user", principal.User, "error", err)
+			writeError(w, http.StatusServiceUnavailable, "authorization is unavailable")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), evaluationContextKey{}, &evaluation{user: principal.User, policy: policy, result: result})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the evaluation of the request subject stored by Middleware.Wrap.
+func FromContext(ctx context.Context) (*authz.PolicyEvaluationResult, bool) {
+	evaluated, ok := ctx.Value(evaluationContextKey{}).(*evaluation)
+	if !ok {
+		return nil, false
+	}
+	return evaluated.result, true
+}
+
+// UserFromContext returns the request subject authenticated by Middleware.Wrap.
+func UserFromContext(ctx context.Context) (string, bool) {
+	evaluated, ok := ctx.Value(evaluationContextKey{}).(*evaluation)
+	if !ok {
+		return "", false
+	}
+	return evaluated.user, true
+}
+
+// RequirePermission wraps the handler so it only runs when the request subject is granted the
+// permission, in the evaluation mode of the policy. It must run inside Middleware.Wrap: requests
+// without an evaluation in their context are refused with 401, and denied ones with 403.
+func RequirePermission(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		evaluated, ok := r.Context().Value(evaluationContextKey{}).(*evaluation)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !evaluated.policy.CheckEvaluated(evaluated.user, permission, evaluated.result).Allowed {
+			writeError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeError writes a JSON error response with the given status.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(`{"error":"` + message + `"}` + "\n"))
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/authn"
+	"github.com/stretchr/testify/assert"
+)
+
+// bearerTokens is an authn.Provider accepting the bearer tokens "token-<user>".
+type bearerTokens struct{}
+
+func (bearerTokens) Authenticate(r *http.Request) (*authn.Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, nil
+	}
+	user, ok := strings.CutPrefix(token, "token-")
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown token", authn.ErrInvalidCredentials)
+	}
+	return &authn.Principal{User: user, Method: "test"}, nil
+}
+
+// policyReaderFunc adapts a function to the PolicyReader interface.
+type policyReaderFunc func(ctx context.Context) (*authz.Policy, error)
+
+func (read policyReaderFunc) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return read(ctx)
+}
+
+func testPolicy(ctx context.Context) (*authz.Policy, error) {
+	return authz.NewBuilder().
+		Group("cooks").Users("alice").
+		Permission("recipes.read").GrantTo("cooks").
+		Permission("recipes.delete").
+		Policy(), nil
+}
+
+func request(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/recipes/1", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+// TestMiddleware_Wrap sends requests with various tokens, checking the evaluation of valid ones is in the context.
+func TestMiddleware_Wrap(t *testing.T) {
+	middleware := NewMiddleware(bearerTokens{}, policyReaderFunc(testPolicy), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var result *authz.PolicyEvaluationResult
+	var user string
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, _ = FromContext(r.Context())
+		user, _ = UserFromContext(r.Context())
+	}))
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request("token-alice"))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, &authz.PolicyEvaluationResult{Groups: []string{"cooks"}, Permissions: []string{"recipes.read"}}, result)
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request(""))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, "Bearer", response.Header().Get("WWW-Authenticate"))
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, request("forged"))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, response.Header().Get("WWW-Authenticate"))
+}
+
+// TestMiddleware_PolicyUnavailable fails to read the policy, checking the request is refused.
+func TestMiddleware_PolicyUnavailable(t *testing.T) {
+	unavailable := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) {
+		return nil, errors.New("store down")
+	})
+	middleware := NewMiddleware(bearerTokens{}, unavailable, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("the handler must not run")
+	}))
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request("token-alice"))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+}
+
+// TestRequirePermission sends requests of various users, checking only granted ones are served.
+func TestRequirePermission(t *testing.T) {
+	middleware := NewMiddleware(bearerTokens{}, policyReaderFunc(testPolicy), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		token      string
+		wantStatus int
+	}{
+		{"granted", middleware.Wrap(RequirePermission("recipes.read", ok)), "token-alice", http.StatusOK},
+		{"denied", middleware.Wrap(RequirePermission("recipes.delete", ok)), "token-alice", http.StatusForbidden},
+		{"unknown user", middleware.Wrap(RequirePermission("recipes.read", ok)), "token-bob", http.StatusForbidden},
+		{"not wrapped", RequirePermission("recipes.read", ok), "token-alice", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			test.handler.ServeHTTP(response, request(test.token))
+			assert.Equal(t, test.wantStatus, response.Code)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"fmt"
+)
+
+// EvaluationMode tells how permission checks treat the permissions a user is not granted.
+// Modes only affect the outcome of Check, CheckWith and CheckEvaluated: the groups and permissions
+// returned by Evaluate are always the granted ones, and HasPermission of a Policy, a CompiledPolicy,
+// a Session or an embedded Evaluator always tells whether the policy grants the permission.
+type EvaluationMode string
+
+const (
+	// ModeDefaultDeny denies every permission the user is not granted. It is the mode of policies without one.
+	ModeDefaultDeny EvaluationMode = "default-deny"
+	// ModeDefaultAllow allows the permissions the policy does not define, so applications can start
+	// checking permissions before they are added to the policy, such as during a migration.
+	ModeDefaultAllow EvaluationMode = "default-allow"
+	// ModeShadow allows every check while still reporting why it would have been denied,
+	// to observe the effect of a policy before enforcing it.
+	ModeShadow EvaluationMode = "shadow"
+)
+
+// ParseEvaluationMode parses the name of an evaluation mode. An empty name is the default-deny mode.
+func ParseEvaluationMode(name string) (EvaluationMode, error) {
+	switch mode := EvaluationMode(name); mode {
+	case "":
+		return ModeDefaultDeny, nil
+	case ModeDefaultDeny, ModeDefaultAllow, ModeShadow:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid evaluation mode %q, expected %s, %s or %s", name, ModeDefaultDeny, ModeDefaultAllow, ModeShadow)
+	}
+}
+
+// apply overrides the outcome of a denied check according to the mode. The denial reason is kept,
+// so an allowed check with a reason tells it was allowed by the mode rather than by the policy.
+func (mode EvaluationMode) apply(check *CheckResult) {
+	check.Mode = mode
+	if check.Allowed {
+		return
+	}
+
+	switch mode {
+	case ModeShadow:
+		check.Allowed = true
+	case ModeDefaultAllow:
+		check.Allowed = check.Reason == DenialPermissionUnknown
+	}
+}
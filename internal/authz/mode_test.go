@@ -0,0 +1,79 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseEvaluationMode parses the names of evaluation modes, checking an empty name is the default-deny mode.
+func TestParseEvaluationMode(t *testing.T) {
+	mode, err := ParseEvaluationMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, ModeDefaultDeny, mode)
+
+	mode, err = ParseEvaluationMode("shadow")
+	assert.NoError(t, err)
+	assert.Equal(t, ModeShadow, mode)
+
+	_, err = ParseEvaluationMode("permissive")
+	assert.EqualError(t, err, `invalid evaluation mode "permissive", expected default-deny, default-allow or shadow`)
+}
+
+// TestPolicy_Check_Modes checks permissions in every evaluation mode, checking which denials each mode overrides.
+func TestPolicy_Check_Modes(t *testing.T) {
+	tests := []struct {
+		mode       EvaluationMode
+		permission string
+		allowed    bool
+		reason     DenialReason
+	}{
+		{"", "purge", false, DenialPermissionUnknown},
+		{ModeDefaultDeny, "purge", false, DenialPermissionUnknown},
+		{ModeDefaultAllow, "purge", true, DenialPermissionUnknown},
+		{ModeDefaultAllow, "write", false, DenialNoMatchingGroup},
+		{ModeDefaultAllow, "read", true, ""},
+		{ModeShadow, "write", true, DenialNoMatchingGroup},
+		{ModeShadow, "read", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.mode)+" "+test.permission, func(t *testing.T) {
+			policy := sessionPolicy()
+			policy.Mode = test.mode
+
+			check, err := policy.Check("alice", test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, test.allowed, check.Allowed)
+			assert.Equal(t, test.reason, check.Reason)
+			if test.mode == "" {
+				assert.Equal(t, ModeDefaultDeny, check.Mode)
+			} else {
+				assert.Equal(t, test.mode, check.Mode)
+			}
+		})
+	}
+}
+
+// TestHasPermission_IgnoresModes checks permissions in every evaluation mode, checking the policy, the compiled
+// policy and the session only allow the permissions the policy grants.
+func TestHasPermission_IgnoresModes(t *testing.T) {
+	for _, mode := range []EvaluationMode{ModeDefaultDeny, ModeDefaultAllow, ModeShadow} {
+		t.Run(string(mode), func(t *testing.T) {
+			policy := sessionPolicy()
+			policy.Mode = mode
+			compiled, err := Compile(policy)
+			assert.NoError(t, err)
+			session, err := policy.Session("alice")
+			assert.NoError(t, err)
+
+			for permission, granted := range map[string]bool{"read": true, "write": false, "purge": false} {
+				allowed, err := policy.HasPermission("alice", permission)
+				assert.NoError(t, err)
+				assert.Equal(t, granted, allowed, "Policy %s", permission)
+				assert.Equal(t, granted, compiled.HasPermission("alice", permission), "CompiledPolicy %s", permission)
+				assert.Equal(t, granted, session.HasPermission(permission), "Session %s", permission)
+			}
+		})
+	}
+}
@@ -2,16 +2,30 @@ package authz
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/salmarsumi/recipes/internal/shared"
 )
 
+// conditions caches the compiled conditions of the permissions of every policy by source.
+var conditions = condition.NewCache()
+
 // Represents a single system permission with all the
 // groups assigned that specific permission.Given a
 // collection of groups the permission instance can
 // evaluate whether these groups have been granted
 // the specified permission.
 type Permission struct {
-	Name   string
-	Groups []string
+	Name   string    `json:"name"`
+	Groups []string  `json:"groups"`
+	Risk   RiskLevel `json:"risk,omitempty"`
+	// The names of the permissions granted along with this one, such as "read" for "admin".
+	Implies []string `json:"implies,omitempty"`
+	// The condition the attributes of the request must meet for the grants to apply, such as
+	// "resource.owner == user", see the condition package. Empty grants the permission unconditionally.
+	Condition string `json:"condition,omitempty"`
 }
 
 // NewPermission creates a new Permission instance with the specified name and groups.
@@ -49,18 +63,27 @@ func (permission *Permission) Evaluate(groups []string) (bool, error) {
 		return false, nil
 	}
 
-	// use a map for faster lookup
-	groupsMap := make(map[string]struct{}, len(permission.Groups))
-	for _, group := range permission.Groups {
-		groupsMap[group] = struct{}{}
+	// use a set for faster lookup and check if the groups intersect
+	return shared.NewSet(permission.Groups...).ContainsAny(groups...), nil
+}
+
+// ConditionHolds reports whether the condition of the permission holds for the attributes of a request
+// made by the user, who is set as the "user" attribute unless the attributes already have one.
+// Permissions without a condition always hold. A condition naming a missing attribute or comparing
+// values of different types does not hold, so the grant is withheld rather than failing the evaluation.
+// An error is returned when the condition does not compile.
+func (permission *Permission) ConditionHolds(user string, attributes condition.Attributes) (bool, error) {
+	if permission.Condition == "" {
+		return true, nil
 	}
 
-	// check if the groups intersect
-	for _, group := range groups {
-		if _, exists := groupsMap[group]; exists {
-			return true, nil
-		}
+	expression, err := conditions.Compile(permission.Condition)
+	if err != nil {
+		return false, fmt.Errorf("permission %q: %w", permission.Name, err)
 	}
 
-	return false, nil
+	request := condition.Attributes{"user": user}
+	maps.Copy(request, attributes)
+	holds, err := expression.Evaluate(request)
+	return err == nil && holds, nil
 }
@@ -2,7 +2,10 @@ package authz
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 
+	"github.com/salmarsumi/recipes/internal/authz/condition"
 	"github.com/salmarsumi/recipes/internal/shared"
 )
 
@@ -10,18 +13,69 @@ import (
 // This class will be the single source of truth regarding which user can have what permission.Given a user
 // the policy instance can evaluate and return what permissions and membership the user has.
 type Policy struct {
-	Permissions []Permission
-	Groups      []Group
+	Permissions []Permission `json:"permissions"`
+	Groups      []Group      `json:"groups"`
+	// How permission checks treat the permissions a user is not granted, see EvaluationMode.
+	// Empty means ModeDefaultDeny.
+	Mode EvaluationMode `json:"mode,omitempty"`
+	// The folders granting permissions to the groups they hold, see ResolveFolders.
+	Folders []Folder `json:"folders,omitempty"`
+	// The permissions explicitly denied to groups and users, overriding the grants, see Denial.
+	Denials []Denial `json:"denials,omitempty"`
 }
 
+var _ PolicyOperations = (*Policy)(nil)
+
 // NewPolicy creates a new Policy instance with the specified permissions and groups.
 func NewPolicy(permissions []Permission, groups []Group) *Policy {
 	return &Policy{Permissions: permissions, Groups: groups}
 }
 
+// Validate checks the policy can be evaluated and stored: it reports, joined, every permission granted
+// to an unknown group, unknown or cyclic implication, invalid evaluation mode, risk level, wildcard
+// or condition, and invalid folder or denial.
+func (policy *Policy) Validate() error {
+	groups := make(shared.Set[string], len(policy.Groups))
+	for _, group := range policy.Groups {
+		groups.Add(group.Name)
+	}
+	var errs []error
+	if _, err := ParseEvaluationMode(string(policy.Mode)); err != nil {
+		errs = append(errs, err)
+	}
+	for _, permission := range policy.Permissions {
+		for _, group := range permission.Groups {
+			if !groups.Contains(group) {
+				errs = append(errs, fmt.Errorf("permission %q is granted to unknown group %q", permission.Name, group))
+			}
+		}
+		if _, err := ParseRiskLevel(string(permission.Risk)); err != nil {
+			errs = append(errs, fmt.Errorf("permission %q: %w", permission.Name, err))
+		}
+		if err := ValidateWildcard(permission.Name); err != nil {
+			errs = append(errs, err)
+		}
+		if _, err := permission.ConditionHolds("", nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := policy.ValidateImplications(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := policy.ValidateFolders(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := policy.ValidateDenials(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
 // Evaluate assesses the given user's permissions based on the policy.
 // It returns a PolicyEvaluationResult which indicates whether the user
 // meets the policy requirements, and an error if the evaluation fails.
+// The permissions with a condition are granted only when it holds without
+// request attributes, see EvaluateWith.
 //
 // Parameters:
 //
@@ -32,31 +86,140 @@ func NewPolicy(permissions []Permission, groups []Group) *Policy {
 //	*PolicyEvaluationResult - the result of the policy evaluation.
 //	error - an error if the evaluation process encounters an issue.
 func (policy *Policy) Evaluate(user string) (*PolicyEvaluationResult, error) {
+	return policy.EvaluateWith(user, nil)
+}
+
+// EvaluateWith assesses the given user's permissions for a request with the given attributes,
+// granting the permissions with a condition only when it holds for the attributes, see Permission.Condition.
+//
+// Parameters:
+//
+//	user - the username to be evaluated against the policy.
+//	attributes - the attributes of the request, such as the owner of the resource.
+//
+// Returns:
+//
+//	*PolicyEvaluationResult - the result of the policy evaluation.
+//	error - an error if the evaluation process encounters an issue, such as a condition that does not compile.
+func (policy *Policy) EvaluateWith(user string, attributes condition.Attributes) (*PolicyEvaluationResult, error) {
 	if user == "" {
 		return nil, errors.New("user is empty")
 	}
 
 	// get the user groups
-	groups := shared.Filter(policy.Groups, func(group Group) bool {
-		result, error := group.Evaluate(user)
-		if error != nil {
-			return false
-		}
-		return result
+	groups, err := shared.FilterErr(policy.Groups, func(group Group) (bool, error) {
+		return group.Evaluate(user)
 	}, func(group Group) string {
 		return group.Name
 	})
+	if err != nil {
+		return nil, fmt.Errorf("evaluate groups: %w", err)
+	}
 
-	// get the groups permissions
-	permissions := shared.Filter(policy.Permissions, func(permission Permission) bool {
-		result, error := permission.Evaluate(groups)
-		if error != nil {
-			return false
+	// get the groups permissions whose condition holds, a user outside every group holds none
+	var permissions []string
+	if len(groups) > 0 {
+		permissions, err = shared.FilterErr(policy.Permissions, func(permission Permission) (bool, error) {
+			granted, err := permission.Evaluate(groups)
+			if err != nil || !granted {
+				return false, err
+			}
+			return permission.ConditionHolds(user, attributes)
+		}, func(permission Permission) string {
+			return permission.Name
+		})
+		if err != nil {
+			return nil, fmt.Errorf("evaluate permissions: %w", err)
 		}
-		return result
-	}, func(permission Permission) string {
-		return permission.Name
-	})
+	}
+
+	// add the permissions implied by the granted ones
+	permissions = policy.expandImplied(permissions)
+
+	// remove the permissions explicitly denied, which override the grants
+	denied := policy.denied(user, shared.NewSet(groups...))
+	if len(denied) > 0 {
+		permissions = slices.DeleteFunc(permissions, func(permission string) bool {
+			_, isDenied := DenyingPermission(denied, permission)
+			return isDenied
+		})
+	}
+
+	return &PolicyEvaluationResult{Groups: groups, Permissions: permissions, Denied: denied}, nil
+}
 
-	return &PolicyEvaluationResult{Groups: groups, Permissions: permissions}, nil
+// HasPermission tells whether the user is granted the permission, directly, through an implication or a wildcard,
+// and is not explicitly denied it. Conditions are evaluated without request attributes, like Evaluate.
+// The evaluation mode of the policy is ignored, see EvaluationMode. Unlike Check it only looks at the
+// permissions granting the one checked, rather than evaluating every permission of the user.
+//
+// Parameters:
+//
+//	user - the username to be evaluated against the policy.
+//	permission - the name of the permission to check.
+//
+// Returns:
+//
+//	bool - true if the user is granted the permission, otherwise false.
+//	error - an error if the user or the permission is empty, or a condition does not compile.
+func (policy *Policy) HasPermission(user string, permission string) (bool, error) {
+	if user == "" {
+		return false, errors.New("user is empty")
+	}
+	if permission == "" {
+		return false, errors.New("permission is empty")
+	}
+
+	groups := shared.NewSet[string]()
+	result := NewPolicyEvaluationResult([]string{}, []string{})
+	for _, group := range policy.Groups {
+		if slices.Contains(group.Users, user) && groups.Insert(group.Name) {
+			result.Groups = append(result.Groups, group.Name)
+		}
+	}
+
+	// the permission stands for whichever held permission grants it, which is all the check needs
+	granting := policy.granting(permission)
+	for _, candidate := range policy.Permissions {
+		if !granting.Contains(candidate.Name) || !groups.ContainsAny(candidate.Groups...) {
+			continue
+		}
+		holds, err := candidate.ConditionHolds(user, nil)
+		if err != nil {
+			return false, err
+		}
+		if holds {
+			result.Permissions = []string{permission}
+			break
+		}
+	}
+	result.Denied = policy.denied(user, groups)
+
+	// a reason tells the policy does not grant the permission, even when the mode allows it
+	return policy.CheckEvaluated(user, permission, result).Reason == "", nil
+}
+
+// IsInGroup tells whether the user is a member of the named group, without evaluating the other groups.
+// It returns false for groups the policy does not define.
+//
+// Parameters:
+//
+//	user - the username to be checked within the group.
+//	group - the name of the group.
+//
+// Returns:
+//
+//	bool - true if the user is a member of the group, otherwise false.
+//	error - an error if the user is empty.
+func (policy *Policy) IsInGroup(user string, group string) (bool, error) {
+	if user == "" {
+		return false, errors.New("user is empty")
+	}
+
+	for _, candidate := range policy.Groups {
+		if candidate.Name == group {
+			return candidate.Evaluate(user)
+		}
+	}
+	return false, nil
 }
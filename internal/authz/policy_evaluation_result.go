@@ -4,10 +4,14 @@ package authz
 type PolicyEvaluationResult struct {
 
 	// The groups that the user is a member of.
-	Groups []string
+	Groups []string `json:"groups"`
 
 	// The permissions that the user has.
-	Permissions []string
+	Permissions []string `json:"permissions"`
+
+	// The permissions explicitly denied to the user, removed from the permissions they have.
+	// A wildcard denies every permission below it, see Denial.
+	Denied []string `json:"denied,omitempty"`
 }
 
 // Creates a new instance of PolicyEvaluationResult.
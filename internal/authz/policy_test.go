@@ -72,3 +72,88 @@ func TestEvaluate_EmptyPolicy(t *testing.T) {
 	assert.Empty(t, result.Groups)
 	assert.Empty(t, result.Permissions)
 }
+
+func operationsPolicy() *Policy {
+	return &Policy{
+		Groups: []Group{
+			*NewGroup("admins", []string{"alice"}),
+			*NewGroup("editors", []string{"bob"}),
+			*NewGroup("readers", []string{"carol", "bob"}),
+			*NewGroup("empty", []string{}),
+		},
+		Permissions: []Permission{
+			{Name: "admin", Groups: []string{"admins"}, Implies: []string{"write"}},
+			{Name: "write", Groups: []string{"editors"}, Implies: []string{"read"}},
+			{Name: "read", Groups: []string{"readers"}},
+			{Name: "delete", Groups: []string{}},
+		},
+	}
+}
+
+// TestHasPermission checks permissions granted directly, through implications and not at all, checking the outcome.
+func TestHasPermission(t *testing.T) {
+	policy := operationsPolicy()
+	tests := []struct {
+		user       string
+		permission string
+		expected   bool
+	}{
+		{"alice", "admin", true},
+		{"alice", "read", true},
+		{"bob", "write", true},
+		{"bob", "admin", false},
+		{"carol", "read", true},
+		{"carol", "write", false},
+		{"dave", "read", false},
+		{"alice", "delete", false},
+		{"alice", "purge", false},
+	}
+	for _, test := range tests {
+		granted, err := policy.HasPermission(test.user, test.permission)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, granted, "%s %s", test.user, test.permission)
+	}
+
+	_, err := policy.HasPermission("", "read")
+	assert.EqualError(t, err, "user is empty")
+	_, err = policy.HasPermission("alice", "")
+	assert.EqualError(t, err, "permission is empty")
+}
+
+// TestHasPermission_MatchesCheck checks every user and permission in every mode, checking HasPermission agrees with
+// Check in default-deny mode and ignores the other modes, which allow checks with a reason.
+func TestHasPermission_MatchesCheck(t *testing.T) {
+	for _, mode := range []EvaluationMode{"", ModeDefaultDeny, ModeDefaultAllow, ModeShadow} {
+		policy := operationsPolicy()
+		policy.Mode = mode
+		for _, user := range []string{"alice", "bob", "carol", "dave"} {
+			for _, permission := range []string{"admin", "write", "read", "delete", "purge"} {
+				check, err := policy.Check(user, permission)
+				assert.NoError(t, err)
+				granted, err := policy.HasPermission(user, permission)
+				assert.NoError(t, err)
+				assert.Equal(t, check.Allowed && check.Reason == "", granted, "%s %s %s", mode, user, permission)
+			}
+		}
+	}
+}
+
+// TestIsInGroup checks memberships of defined and undefined groups, checking the outcome.
+func TestIsInGroup(t *testing.T) {
+	policy := operationsPolicy()
+
+	member, err := policy.IsInGroup("bob", "readers")
+	assert.NoError(t, err)
+	assert.True(t, member)
+
+	member, err = policy.IsInGroup("bob", "admins")
+	assert.NoError(t, err)
+	assert.False(t, member)
+
+	member, err = policy.IsInGroup("bob", "unknown")
+	assert.NoError(t, err)
+	assert.False(t, member)
+
+	_, err = policy.IsInGroup("", "readers")
+	assert.EqualError(t, err, "user is empty")
+}
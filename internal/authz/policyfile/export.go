@@ -0,0 +1,89 @@
+package policyfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadExport decodes a JSON policy export, see store.PolicyDocument, and validates it.
+func ReadExport(r io.Reader) (*store.PolicyDocument, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	document := &store.PolicyDocument{}
+	if err := decoder.Decode(document); err != nil {
+		return nil, fmt.Errorf("decode policy export: %w", err)
+	}
+	if err := document.Validate(); err != nil {
+		return nil, fmt.Errorf("validate policy export: %w", err)
+	}
+	return document, nil
+}
+
+// ReadExportYAML decodes a YAML policy export and validates it.
+// The export uses the same field names as the JSON form.
+func ReadExportYAML(r io.Reader) (*store.PolicyDocument, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	document := &store.PolicyDocument{}
+	if err := decoder.Decode(document); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode policy export: %w", err)
+	}
+	if err := document.Validate(); err != nil {
+		return nil, fmt.Errorf("validate policy export: %w", err)
+	}
+	return document, nil
+}
+
+// LoadExport reads the policy export stored at the given path.
+// Files with a .yaml or .yml extension are decoded as YAML, any other file as JSON.
+func LoadExport(path string) (*store.PolicyDocument, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if IsYAML(path) {
+		return ReadExportYAML(file)
+	}
+	return ReadExport(file)
+}
+
+// WriteExport encodes the policy export to the given writer, as YAML when asked to and as indented JSON otherwise.
+func WriteExport(w io.Writer, document *store.PolicyDocument, asYAML bool) error {
+	if asYAML {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			return fmt.Errorf("encode policy export: %w", err)
+		}
+		return encoder.Close()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("encode policy export: %w", err)
+	}
+	return nil
+}
+
+// IsYAML tells whether the file at the given path holds YAML, judging by its extension.
+func IsYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
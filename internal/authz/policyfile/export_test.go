@@ -0,0 +1,62 @@
+package policyfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func testExport() *store.PolicyDocument {
+	return &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: "cooks", Labels: map[string]string{"team": "kitchen"}, Members: []store.MemberDocument{{User: "alice", Source: store.SourceSCIM}}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes.read", Description: "Read recipes", Groups: []string{"cooks"}},
+		},
+	}
+}
+
+// TestWriteExport writes an export as JSON and as YAML, checking both read back to the same document.
+func TestWriteExport(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteExport(&buf, testExport(), false))
+	assert.Contains(t, buf.String(), `"source": "scim"`)
+	document, err := ReadExport(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, testExport(), document)
+
+	buf.Reset()
+	assert.NoError(t, WriteExport(&buf, testExport(), true))
+	assert.Contains(t, buf.String(), "source: scim")
+	document, err = ReadExportYAML(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, testExport(), document)
+}
+
+// TestReadExport_Error calls ReadExport with unknown fields and an invalid document, checking for errors.
+func TestReadExport_Error(t *testing.T) {
+	_, err := ReadExport(strings.NewReader(`{"groups": [{"name": "cooks", "users": ["alice"]}], "permissions": []}`))
+	assert.ErrorContains(t, err, "decode policy export")
+
+	_, err = ReadExportYAML(strings.NewReader("groups: []\npermissions:\n  - name: read\n    groups: [cooks]\n"))
+	assert.ErrorContains(t, err, `validate policy export: permission "read" is granted to unknown group "cooks"`)
+}
+
+// TestLoadExport loads an export on disk by extension, checking for the decoded document.
+func TestLoadExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("groups:\n  - name: cooks\n    members: [{user: alice}]\npermissions: []\n"), 0o600))
+
+	document, err := LoadExport(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []store.MemberDocument{{User: "alice"}}, document.Groups[0].Members)
+
+	_, err = LoadExport(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
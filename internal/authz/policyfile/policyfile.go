@@ -0,0 +1,187 @@
+// Package policyfile reads and writes policy documents stored on disk.
+package policyfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"gopkg.in/yaml.v3"
+)
+
+// Read decodes a JSON policy document from the given reader. The permissions of its folders are granted
+// to their groups like ReadPolicy does, see authz.Policy.ResolveFolders, so the policy evaluates the same
+// as once imported in the store.
+//
+// Parameters:
+//   - r: The reader containing the JSON encoded policy.
+//
+// Returns:
+//   - *authz.Policy: The decoded policy, with its folders resolved.
+//   - error: An error if the document is malformed or the policy is invalid, see authz.Policy.Validate.
+func Read(r io.Reader) (*authz.Policy, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	policy := &authz.Policy{}
+	if err := decoder.Decode(policy); err != nil {
+		return nil, fmt.Errorf("decode policy document: %w", err)
+	}
+	return resolve(policy)
+}
+
+// ReadYAML decodes a YAML policy document from the given reader, resolving its folders like Read.
+// The document uses the same field names as the JSON form.
+func ReadYAML(r io.Reader) (*authz.Policy, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	policy := &authz.Policy{}
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode policy document: %w", err)
+	}
+	return resolve(policy)
+}
+
+// resolve validates a decoded policy like authz.Compile does, so an invalid document is refused
+// on load rather than failing the checks made with it, and resolves its folders.
+func resolve(policy *authz.Policy) (*authz.Policy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("validate policy document: %w", err)
+	}
+	policy.ResolveFolders()
+	return policy, nil
+}
+
+// Load reads the policy document stored at the given path.
+// Files with a .yaml or .yml extension are decoded as YAML, any other file as JSON.
+func Load(path string) (*authz.Policy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if IsYAML(path) {
+		return ReadYAML(file)
+	}
+	return Read(file)
+}
+
+// Canonical returns a copy of the policy in canonical form: groups and permissions sorted by name,
+// group members and permission grants sorted without duplicates, and empty lists instead of nil.
+// Risk levels are omitted when low, as an unset risk level means low, and so is the default-deny mode.
+// Folders are sorted by path, with their groups and grants sorted, and so are the denials by permission,
+// the denials of a same permission being merged into one.
+// Two equivalent policies always have the same canonical form.
+func Canonical(policy *authz.Policy) *authz.Policy {
+	canonical := &authz.Policy{
+		Groups:      make([]authz.Group, 0, len(policy.Groups)),
+		Permissions: make([]authz.Permission, 0, len(policy.Permissions)),
+	}
+	if policy.Mode != authz.ModeDefaultDeny {
+		canonical.Mode = policy.Mode
+	}
+
+	for _, group := range policy.Groups {
+		canonical.Groups = append(canonical.Groups, authz.Group{Name: group.Name, Users: sortedSet(group.Users)})
+	}
+	slices.SortFunc(canonical.Groups, func(a, b authz.Group) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, permission := range policy.Permissions {
+		risk := permission.Risk
+		if risk == authz.RiskLow {
+			risk = ""
+		}
+		var implies []string
+		if len(permission.Implies) > 0 {
+			implies = sortedSet(permission.Implies)
+		}
+		canonical.Permissions = append(canonical.Permissions, authz.Permission{Name: permission.Name, Groups: sortedSet(permission.Groups), Risk: risk, Implies: implies, Condition: permission.Condition})
+	}
+	slices.SortFunc(canonical.Permissions, func(a, b authz.Permission) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, folder := range policy.Folders {
+		canonical.Folders = append(canonical.Folders, authz.Folder{Path: folder.Path, Inheritance: folder.Inheritance})
+		if len(folder.Groups) > 0 {
+			canonical.Folders[len(canonical.Folders)-1].Groups = sortedSet(folder.Groups)
+		}
+		if len(folder.Grants) > 0 {
+			canonical.Folders[len(canonical.Folders)-1].Grants = sortedSet(folder.Grants)
+		}
+	}
+	slices.SortFunc(canonical.Folders, func(a, b authz.Folder) int { return strings.Compare(a.Path, b.Path) })
+
+	// denials of a same permission are merged, so the order they are listed in does not matter
+	denials := map[string]*authz.Denial{}
+	for _, denial := range policy.Denials {
+		merged, ok := denials[denial.Permission]
+		if !ok {
+			merged = &authz.Denial{Permission: denial.Permission}
+			denials[denial.Permission] = merged
+		}
+		merged.Groups = append(merged.Groups, denial.Groups...)
+		merged.Users = append(merged.Users, denial.Users...)
+	}
+	for _, denial := range denials {
+		canonical.Denials = append(canonical.Denials, authz.Denial{Permission: denial.Permission})
+		if len(denial.Groups) > 0 {
+			canonical.Denials[len(canonical.Denials)-1].Groups = sortedSet(denial.Groups)
+		}
+		if len(denial.Users) > 0 {
+			canonical.Denials[len(canonical.Denials)-1].Users = sortedSet(denial.Users)
+		}
+	}
+	slices.SortFunc(canonical.Denials, func(a, b authz.Denial) int { return strings.Compare(a.Permission, b.Permission) })
+
+	return canonical
+}
+
+// Marshal encodes the canonical form of the policy as indented JSON terminated by a newline,
+// so the output is stable and suitable for version control and golden files.
+func Marshal(policy *authz.Policy) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Write(&buf, policy); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Version returns a short fingerprint of the canonical form of the policy.
+// Equivalent policies have the same version and any change to the policy changes it,
+// so callers can tell whether results computed from two policies may differ.
+func Version(policy *authz.Policy) (string, error) {
+	document, err := Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(document)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Write encodes the canonical form of the policy to the given writer. See Marshal.
+func Write(w io.Writer, policy *authz.Policy) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(Canonical(policy)); err != nil {
+		return fmt.Errorf("encode policy document: %w", err)
+	}
+	return nil
+}
+
+func sortedSet(values []string) []string {
+	sorted := slices.Clone(values)
+	if sorted == nil {
+		sorted = []string{}
+	}
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
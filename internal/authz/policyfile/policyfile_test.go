@@ -0,0 +1,282 @@
+package policyfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `{
+	"groups": [{"name": "admin", "users": ["adminuser"]}],
+	"permissions": [{"name": "write", "groups": ["admin"]}]
+}`
+
+// TestRead calls policyfile.Read with a valid document, checking for the decoded policy.
+func TestRead(t *testing.T) {
+	policy, err := Read(strings.NewReader(testDocument))
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+	assert.Equal(t, "admin", policy.Groups[0].Name)
+	assert.Equal(t, []string{"adminuser"}, policy.Groups[0].Users)
+	assert.Len(t, policy.Permissions, 1)
+	assert.Equal(t, "write", policy.Permissions[0].Name)
+	assert.Equal(t, []string{"admin"}, policy.Permissions[0].Groups)
+}
+
+// TestRead_Error_UnknownField calls policyfile.Read with an unknown field, checking for an error.
+func TestRead_Error_UnknownField(t *testing.T) {
+	policy, err := Read(strings.NewReader(`{"roles": []}`))
+	assert.Error(t, err)
+	assert.Nil(t, policy)
+}
+
+// TestRead_Error_ImplicationCycle calls policyfile.Read with permissions implying each other, checking for an error.
+func TestRead_Error_ImplicationCycle(t *testing.T) {
+	policy, err := Read(strings.NewReader(`{"permissions": [
+		{"name": "read", "groups": [], "implies": ["write"]},
+		{"name": "write", "groups": [], "implies": ["read"]}
+	], "groups": []}`))
+	assert.ErrorContains(t, err, "read -> write -> read")
+	assert.Nil(t, policy)
+}
+
+// TestRead_Error_Denials calls policyfile.Read with denials of unknown permissions and groups, checking for an error.
+func TestRead_Error_Denials(t *testing.T) {
+	documents := map[string]string{
+		"unknown permission": `{"permissions": [], "groups": [], "denials": [{"permission": "read", "users": ["bob"]}]}`,
+		"unknown group":      `{"permissions": [{"name": "read", "groups": []}], "groups": [], "denials": [{"permission": "read", "groups": ["guests"]}]}`,
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			policy, err := Read(strings.NewReader(document))
+			assert.ErrorContains(t, err, "validate policy document")
+			assert.Nil(t, policy)
+		})
+	}
+}
+
+// TestRead_Error_Invalid calls policyfile.Read with policies authz.Compile refuses, checking they are refused on load too.
+func TestRead_Error_Invalid(t *testing.T) {
+	documents := map[string]string{
+		"invalid condition": `{"permissions": [{"name": "write", "groups": [], "condition": "resource.owner =="}], "groups": []}`,
+		"invalid wildcard":  `{"permissions": [{"name": "recipes.*.read", "groups": []}], "groups": []}`,
+		"invalid risk":      `{"permissions": [{"name": "write", "groups": [], "risk": "extreme"}], "groups": []}`,
+		"unknown group":     `{"permissions": [{"name": "write", "groups": ["admins"]}], "groups": []}`,
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			policy, err := Read(strings.NewReader(document))
+			assert.ErrorContains(t, err, "validate policy document")
+			assert.Nil(t, policy)
+		})
+	}
+}
+
+// TestRead_Mode calls policyfile.Read with an evaluation mode, checking it is decoded and validated.
+func TestRead_Mode(t *testing.T) {
+	policy, err := Read(strings.NewReader(`{"permissions": [], "groups": [], "mode": "shadow"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, authz.ModeShadow, policy.Mode)
+
+	policy, err = Read(strings.NewReader(`{"permissions": [], "groups": [], "mode": "permissive"}`))
+	assert.ErrorContains(t, err, `invalid evaluation mode "permissive"`)
+	assert.Nil(t, policy)
+}
+
+// TestLoad calls policyfile.Load with a document on disk, checking for the decoded policy.
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	assert.NoError(t, os.WriteFile(path, []byte(testDocument), 0o600))
+
+	policy, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+}
+
+// TestReadYAML calls policyfile.ReadYAML with a valid document, checking for the decoded policy.
+func TestReadYAML(t *testing.T) {
+	policy, err := ReadYAML(strings.NewReader(`
+groups:
+  - name: admin
+    users: [adminuser]
+permissions:
+  - name: write
+    groups: [admin]
+    risk: high
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []authz.Group{{Name: "admin", Users: []string{"adminuser"}}}, policy.Groups)
+	assert.Equal(t, []authz.Permission{{Name: "write", Groups: []string{"admin"}, Risk: authz.RiskHigh}}, policy.Permissions)
+}
+
+// TestReadYAML_Error_UnknownField calls policyfile.ReadYAML with an unknown field, checking for an error.
+func TestReadYAML_Error_UnknownField(t *testing.T) {
+	policy, err := ReadYAML(strings.NewReader("roles: []\n"))
+	assert.Error(t, err)
+	assert.Nil(t, policy)
+}
+
+// TestReadYAML_Folders calls policyfile.ReadYAML with folders, checking they are decoded, validated and resolved.
+func TestReadYAML_Folders(t *testing.T) {
+	document := `
+groups:
+  - name: bakers
+permissions:
+  - name: recipes.read
+    groups: []
+folders:
+  - path: kitchen
+    grants: [recipes.read]
+  - path: kitchen/pastry
+    groups: [bakers]
+    inheritance: inherit
+`
+	policy, err := ReadYAML(strings.NewReader(document))
+	assert.NoError(t, err)
+	assert.Equal(t, []authz.Folder{
+		{Path: "kitchen", Grants: []string{"recipes.read"}},
+		{Path: "kitchen/pastry", Groups: []string{"bakers"}, Inheritance: authz.InheritanceInherit},
+	}, policy.Folders)
+	// the folders are resolved, so the groups of the subfolder inheriting the grants hold them
+	assert.Equal(t, []string{"bakers"}, policy.Permissions[0].Groups)
+
+	_, err = ReadYAML(strings.NewReader(strings.Replace(document, "path: kitchen\n", "path: bar\n", 1)))
+	assert.ErrorContains(t, err, `folder "kitchen/pastry" is in unknown folder "kitchen"`)
+}
+
+// TestLoad_YAML calls policyfile.Load with a YAML document on disk, checking for the decoded policy.
+func TestLoad_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("groups:\n  - name: admin\n    users: [adminuser]\n"), 0o600))
+
+	policy, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+}
+
+// TestLoad_Error_MissingFile calls policyfile.Load with a missing file, checking for an error.
+func TestLoad_Error_MissingFile(t *testing.T) {
+	policy, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+	assert.Nil(t, policy)
+}
+
+// TestCanonical calls policyfile.Canonical with an unordered policy, checking for the sorted copy.
+func TestCanonical(t *testing.T) {
+	policy := authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "write", Groups: []string{"editors", "admin", "editors"}, Risk: authz.RiskHigh, Implies: []string{"read", "list", "read"}},
+			{Name: "read", Risk: authz.RiskLow, Condition: "tenant == 'acme'"},
+		},
+		[]authz.Group{
+			{Name: "editors", Users: []string{"bob", "alice"}},
+			{Name: "admin"},
+		},
+	)
+
+	canonical := Canonical(policy)
+	assert.Equal(t, authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "read", Groups: []string{}, Condition: "tenant == 'acme'"},
+			{Name: "write", Groups: []string{"admin", "editors"}, Risk: authz.RiskHigh, Implies: []string{"list", "read"}},
+		},
+		[]authz.Group{
+			{Name: "admin", Users: []string{}},
+			{Name: "editors", Users: []string{"alice", "bob"}},
+		},
+	), canonical)
+
+	// the original policy is left untouched
+	assert.Equal(t, []string{"bob", "alice"}, policy.Groups[0].Users)
+}
+
+// TestCanonical_Folders calls policyfile.Canonical with folders, checking they are sorted by path.
+func TestCanonical_Folders(t *testing.T) {
+	policy := authz.NewPolicy(nil, nil)
+	policy.Folders = []authz.Folder{
+		{Path: "kitchen/pastry", Groups: []string{"cooks", "bakers", "cooks"}, Inheritance: authz.InheritanceBlock},
+		{Path: "kitchen", Grants: []string{"write", "read"}},
+	}
+
+	assert.Equal(t, []authz.Folder{
+		{Path: "kitchen", Grants: []string{"read", "write"}},
+		{Path: "kitchen/pastry", Groups: []string{"bakers", "cooks"}, Inheritance: authz.InheritanceBlock},
+	}, Canonical(policy).Folders)
+}
+
+// TestCanonical_Denials calls policyfile.Canonical with denials, checking they are sorted by permission.
+func TestCanonical_Denials(t *testing.T) {
+	policy := authz.NewPolicy(nil, nil)
+	policy.Denials = []authz.Denial{
+		{Permission: "write", Users: []string{"mallory", "eve", "mallory"}},
+		{Permission: "delete", Groups: []string{"interns", "guests"}},
+	}
+
+	assert.Equal(t, []authz.Denial{
+		{Permission: "delete", Groups: []string{"guests", "interns"}},
+		{Permission: "write", Users: []string{"eve", "mallory"}},
+	}, Canonical(policy).Denials)
+}
+
+// TestCanonical_MergedDenials calls policyfile.Canonical with two denials of a same permission, checking they
+// are merged into one whatever their order.
+func TestCanonical_MergedDenials(t *testing.T) {
+	first := authz.Denial{Permission: "write", Groups: []string{"interns"}, Users: []string{"mallory"}}
+	second := authz.Denial{Permission: "write", Groups: []string{"guests", "interns"}, Users: []string{"eve"}}
+	merged := []authz.Denial{{Permission: "write", Groups: []string{"guests", "interns"}, Users: []string{"eve", "mallory"}}}
+
+	policy := authz.NewPolicy(nil, nil)
+	policy.Denials = []authz.Denial{first, second}
+	assert.Equal(t, merged, Canonical(policy).Denials)
+	document, err := Marshal(policy)
+	assert.NoError(t, err)
+
+	policy.Denials = []authz.Denial{second, first}
+	assert.Equal(t, merged, Canonical(policy).Denials)
+	reordered, err := Marshal(policy)
+	assert.NoError(t, err)
+	assert.Equal(t, string(document), string(reordered))
+}
+
+// TestMarshal calls policyfile.Marshal with equivalent policies, checking for identical output.
+func TestMarshal(t *testing.T) {
+	first, err := Marshal(authz.NewPolicy(
+		[]authz.Permission{{Name: "write", Groups: []string{"admin"}}, {Name: "read", Groups: []string{"readers", "admin"}}},
+		[]authz.Group{{Name: "readers", Users: []string{"bob"}}, {Name: "admin", Users: []string{"alice"}}},
+	))
+	assert.NoError(t, err)
+
+	second, err := Marshal(authz.NewPolicy(
+		[]authz.Permission{{Name: "read", Groups: []string{"admin", "readers"}}, {Name: "write", Groups: []string{"admin"}}},
+		[]authz.Group{{Name: "admin", Users: []string{"alice"}}, {Name: "readers", Users: []string{"bob"}}},
+	))
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(first), string(second))
+	assert.True(t, strings.HasSuffix(string(first), "}\n"))
+
+	// the canonical output reads back to the same policy
+	policy, err := Read(strings.NewReader(string(first)))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "readers"}, policy.Permissions[0].Groups)
+}
+
+// TestVersion calls policyfile.Version with equivalent and different policies, checking only changes alter the version.
+func TestVersion(t *testing.T) {
+	first, err := Version(authz.NewPolicy([]authz.Permission{{Name: "read", Groups: []string{"b", "a"}}}, nil))
+	assert.NoError(t, err)
+	same, err := Version(authz.NewPolicy([]authz.Permission{{Name: "read", Groups: []string{"a", "b"}}}, []authz.Group{}))
+	assert.NoError(t, err)
+	changed, err := Version(authz.NewPolicy([]authz.Permission{{Name: "read", Groups: []string{"a"}}}, nil))
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 16)
+	assert.Equal(t, first, same)
+	assert.NotEqual(t, first, changed)
+}
@@ -0,0 +1,56 @@
+package policytest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+)
+
+// UpdateGoldenEnv is the environment variable that makes Golden rewrite golden files
+// instead of comparing against them.
+const UpdateGoldenEnv = "AUTHZ_UPDATE_GOLDEN"
+
+// Golden compares the canonical export of the policy with the golden file at the given path
+// and fails the test when they differ. Running the tests with AUTHZ_UPDATE_GOLDEN=1 writes
+// the current export to the golden file instead.
+//
+// Policy-as-code repositories can use it to catch unintended policy changes, for example:
+//
+//	func TestPolicySnapshot(t *testing.T) {
+//		policy, err := policyfile.Load("policy.json")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		policytest.Golden(t, "testdata/policy.golden.json", policy)
+//	}
+func Golden(t testing.TB, path string, policy *authz.Policy) {
+	t.Helper()
+
+	actual, err := policyfile.Marshal(policy)
+	if err != nil {
+		t.Fatalf("export policy: %v", err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("policy export does not match golden file %s (run with %s=1 to update it)\n--- golden\n%s\n+++ actual\n%s",
+			path, UpdateGoldenEnv, expected, actual)
+	}
+}
@@ -0,0 +1,62 @@
+package policytest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT records failures instead of failing the running test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.failed = true
+}
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.failed = true
+	panic(fmt.Sprintf(format, args...))
+}
+
+// TestGolden compares the test policy with the checked in golden file.
+func TestGolden(t *testing.T) {
+	Golden(t, filepath.Join("testdata", "policy.golden.json"), testPolicy())
+}
+
+// TestGolden_Mismatch compares a changed policy with the golden file, checking for a failure.
+func TestGolden_Mismatch(t *testing.T) {
+	policy := testPolicy()
+	policy.Groups[0].Users = append(policy.Groups[0].Users, "mallory")
+
+	fake := &fakeT{TB: t}
+	Golden(fake, filepath.Join("testdata", "policy.golden.json"), policy)
+	assert.True(t, fake.failed)
+}
+
+// TestGolden_Update runs Golden in update mode, checking the golden file is written and then matches.
+func TestGolden_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "policy.golden.json")
+	t.Setenv(UpdateGoldenEnv, "1")
+	Golden(t, path, testPolicy())
+
+	_, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	t.Setenv(UpdateGoldenEnv, "")
+	fake := &fakeT{TB: t}
+	Golden(fake, path, testPolicy())
+	assert.False(t, fake.failed)
+}
+
+// TestGolden_MissingFile compares with a missing golden file, checking for a failure.
+func TestGolden_MissingFile(t *testing.T) {
+	fake := &fakeT{TB: t}
+	assert.Panics(t, func() { Golden(fake, filepath.Join(t.TempDir(), "missing.json"), testPolicy()) })
+	assert.True(t, fake.failed)
+}
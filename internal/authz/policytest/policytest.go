@@ -0,0 +1,127 @@
+// Package policytest checks a policy against a file of expected user access,
+// so policy changes that break expectations are caught before they are deployed.
+package policytest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a YAML assertions file.
+type Suite struct {
+	Tests []Case `yaml:"tests"`
+}
+
+// Case lists the access expected for a single user.
+type Case struct {
+	// An optional description shown in failure messages.
+	Name string `yaml:"name"`
+	User string `yaml:"user"`
+	// The groups the user must be a member of.
+	Groups []string `yaml:"groups"`
+	// The permissions the user must be granted.
+	Permissions []string `yaml:"permissions"`
+	// The permissions the user must not be granted.
+	Denied []string `yaml:"denied"`
+}
+
+// Failure is a single broken expectation.
+type Failure struct {
+	Case    string
+	User    string
+	Message string
+}
+
+// String describes the failure in a human readable form.
+func (failure Failure) String() string {
+	return fmt.Sprintf("%s: %s", failure.Case, failure.Message)
+}
+
+// Read decodes and validates a YAML assertions file from the given reader.
+func Read(r io.Reader) (*Suite, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	suite := &Suite{}
+	if err := decoder.Decode(suite); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode assertions: %w", err)
+	}
+
+	for i, test := range suite.Tests {
+		if test.User == "" {
+			return nil, fmt.Errorf("test %d: user is empty", i+1)
+		}
+		for _, permission := range test.Permissions {
+			if slices.Contains(test.Denied, permission) {
+				return nil, fmt.Errorf("test %d: permission %s is both expected and denied", i+1, permission)
+			}
+		}
+	}
+
+	return suite, nil
+}
+
+// Load reads the YAML assertions file stored at the given path.
+func Load(path string) (*Suite, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Run evaluates every test case of the suite against the policy.
+//
+// Parameters:
+//
+//	policy - the policy under test.
+//	suite - the expectations to check.
+//
+// Returns:
+//
+//	[]Failure - the broken expectations in suite order, empty when every test passes.
+//	error - an error if a user cannot be evaluated.
+func Run(policy *authz.Policy, suite *Suite) ([]Failure, error) {
+	failures := []Failure{}
+	for i, test := range suite.Tests {
+		result, err := policy.Evaluate(test.User)
+		if err != nil {
+			return nil, err
+		}
+
+		name := test.Name
+		if name == "" {
+			name = fmt.Sprintf("test %d (%s)", i+1, test.User)
+		}
+		fail := func(format string, args ...any) {
+			failures = append(failures, Failure{Case: name, User: test.User, Message: fmt.Sprintf(format, args...)})
+		}
+
+		for _, group := range test.Groups {
+			if !slices.Contains(result.Groups, group) {
+				fail("user %s is not a member of group %s", test.User, group)
+			}
+		}
+		session := authz.NewSession(test.User, result)
+		for _, permission := range test.Permissions {
+			if !session.HasPermission(permission) {
+				fail("user %s is not granted permission %s", test.User, permission)
+			}
+		}
+		for _, permission := range test.Denied {
+			if session.HasPermission(permission) {
+				fail("user %s is granted denied permission %s", test.User, permission)
+			}
+		}
+	}
+
+	return failures, nil
+}
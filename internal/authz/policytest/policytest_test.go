@@ -0,0 +1,147 @@
+package policytest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSuite = `
+tests:
+  - name: alice administers recipes
+    user: alice
+    groups: [admins]
+    permissions: [recipes.read, recipes.delete]
+  - user: bob
+    permissions: [recipes.read]
+    denied: [recipes.delete]
+`
+
+func testPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "recipes.read", Groups: []string{"readers", "admins"}},
+			{Name: "recipes.delete", Groups: []string{"admins"}},
+		},
+		[]authz.Group{
+			*authz.NewGroup("readers", []string{"bob"}),
+			*authz.NewGroup("admins", []string{"alice"}),
+		},
+	)
+}
+
+// TestRead calls policytest.Read with a valid document, checking for the decoded suite.
+func TestRead(t *testing.T) {
+	suite, err := Read(strings.NewReader(testSuite))
+	assert.NoError(t, err)
+	assert.Equal(t, []Case{
+		{Name: "alice administers recipes", User: "alice", Groups: []string{"admins"}, Permissions: []string{"recipes.read", "recipes.delete"}},
+		{User: "bob", Permissions: []string{"recipes.read"}, Denied: []string{"recipes.delete"}},
+	}, suite.Tests)
+}
+
+// TestRead_Empty calls policytest.Read with an empty document, checking for an empty suite.
+func TestRead_Empty(t *testing.T) {
+	suite, err := Read(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, suite.Tests)
+}
+
+// TestRead_Error calls policytest.Read with invalid documents, checking for an error.
+func TestRead_Error(t *testing.T) {
+	documents := map[string]string{
+		"unknown field": "tests:\n  - user: bob\n    roles: [admin]\n",
+		"missing user":  "tests:\n  - permissions: [read]\n",
+		"contradiction": "tests:\n  - user: bob\n    permissions: [read]\n    denied: [read]\n",
+		"malformed":     "tests: [",
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			suite, err := Read(strings.NewReader(document))
+			assert.Error(t, err)
+			assert.Nil(t, suite)
+		})
+	}
+}
+
+// TestRun calls policytest.Run with passing expectations, checking for no failures.
+func TestRun(t *testing.T) {
+	suite, err := Read(strings.NewReader(testSuite))
+	assert.NoError(t, err)
+
+	failures, err := Run(testPolicy(), suite)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+// TestRun_Wildcard calls policytest.Run with permissions granted and denied through wildcards,
+// checking they are matched against the expected names.
+func TestRun_Wildcard(t *testing.T) {
+	policy := testPolicy()
+	policy.Permissions = append(policy.Permissions, authz.Permission{Name: "recipes.*", Groups: []string{"admins"}})
+	policy.Denials = []authz.Denial{*authz.NewDenial("recipes.*", nil, []string{"bob"})}
+	suite := &Suite{Tests: []Case{
+		{User: "alice", Permissions: []string{"recipes.publish", "recipes.drafts.delete"}},
+		{User: "bob", Denied: []string{"recipes.read", "recipes.publish"}},
+	}}
+
+	failures, err := Run(policy, suite)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+// TestRun_FileFolders calls policytest.Run with a policy file granting permissions through folders,
+// checking the groups of the folders and of the subfolders inheriting them hold the grants.
+func TestRun_FileFolders(t *testing.T) {
+	policy, err := policyfile.ReadYAML(strings.NewReader(`
+groups:
+  - name: cooks
+    users: [alice]
+  - name: bakers
+    users: [bob]
+  - name: guests
+    users: [carol]
+permissions:
+  - name: recipes.read
+folders:
+  - path: kitchen
+    groups: [cooks]
+    grants: [recipes.read]
+  - path: kitchen/pastry
+    groups: [bakers]
+    inheritance: inherit
+  - path: kitchen/cellar
+    groups: [guests]
+    inheritance: block
+`))
+	assert.NoError(t, err)
+	suite := &Suite{Tests: []Case{
+		{User: "alice", Permissions: []string{"recipes.read"}},
+		{User: "bob", Permissions: []string{"recipes.read"}},
+		{User: "carol", Denied: []string{"recipes.read"}},
+	}}
+
+	failures, err := Run(policy, suite)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+// TestRun_Failures calls policytest.Run with broken expectations, checking every failure is reported.
+func TestRun_Failures(t *testing.T) {
+	suite := &Suite{Tests: []Case{
+		{User: "bob", Groups: []string{"admins"}, Permissions: []string{"recipes.delete"}},
+		{Name: "alice cannot read", User: "alice", Denied: []string{"recipes.read"}},
+	}}
+
+	failures, err := Run(testPolicy(), suite)
+	assert.NoError(t, err)
+	assert.Equal(t, []Failure{
+		{Case: "test 1 (bob)", User: "bob", Message: "user bob is not a member of group admins"},
+		{Case: "test 1 (bob)", User: "bob", Message: "user bob is not granted permission recipes.delete"},
+		{Case: "alice cannot read", User: "alice", Message: "user alice is granted denied permission recipes.read"},
+	}, failures)
+}
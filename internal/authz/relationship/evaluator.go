@@ -0,0 +1,175 @@
+package relationship
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// DefaultMaxDepth is the default maximum number of nested usersets an evaluation follows.
+const DefaultMaxDepth = 25
+
+// Evaluator answers questions about relationships by walking the graph of the tuples of a store:
+// a subject holds a relation on an object when a tuple relates them directly, or relates the object
+// to a userset, such as group:cooks#member, the subject belongs to.
+type Evaluator struct {
+	store    Store
+	maxDepth int
+}
+
+// EvaluatorOption configures optional Evaluator behavior.
+type EvaluatorOption func(*Evaluator)
+
+// WithMaxDepth sets the maximum number of nested usersets an evaluation follows before failing
+// with ErrDepthExceeded. The default is DefaultMaxDepth.
+func WithMaxDepth(depth int) EvaluatorOption {
+	return func(evaluator *Evaluator) {
+		evaluator.maxDepth = depth
+	}
+}
+
+// NewEvaluator creates a new Evaluator reading the tuples of the given store.
+func NewEvaluator(store Store, options ...EvaluatorOption) *Evaluator {
+	evaluator := &Evaluator{store: store, maxDepth: DefaultMaxDepth}
+	for _, option := range options {
+		option(evaluator)
+	}
+	return evaluator
+}
+
+// Check reports whether the subject holds the relation on the object. The usersets are walked
+// breadth first, each once, so the shortest path is found first and cycles end the walk.
+// The subject may itself be a userset, asking whether all its members hold the relation through it.
+func (evaluator *Evaluator) Check(ctx context.Context, object Object, relation string, subject Subject) (bool, error) {
+	start := Subject{Type: object.Type, ID: object.ID, Relation: relation}
+	if start == subject {
+		return true, nil
+	}
+
+	visited := map[Subject]bool{start: true}
+	level := []Subject{start}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth >= evaluator.maxDepth {
+			return false, ErrDepthExceeded
+		}
+
+		var next []Subject
+		for _, userset := range level {
+			tuples, err := evaluator.store.Read(ctx, usersetFilter(userset))
+			if err != nil {
+				return false, err
+			}
+			for _, tuple := range tuples {
+				if tuple.Subject == subject {
+					return true, nil
+				}
+				if tuple.Subject.IsUserset() && !visited[tuple.Subject] {
+					visited[tuple.Subject] = true
+					next = append(next, tuple.Subject)
+				}
+			}
+		}
+		level = next
+	}
+	return false, nil
+}
+
+// Tree is the expansion of a userset: the subjects related to it directly and the expansions
+// of the usersets related to it.
+type Tree struct {
+	// The expanded userset, the relation on an object.
+	Userset Subject `json:"userset"`
+	// The subjects holding the relation directly, ordered by Compare.
+	Subjects []Subject `json:"subjects"`
+	// The expansions of the usersets holding the relation.
+	Children []*Tree `json:"children,omitempty"`
+	// Whether the userset was expanded elsewhere in the tree, which leaves this node without
+	// subjects and children so cycles end the expansion.
+	Repeated bool `json:"repeated,omitempty"`
+}
+
+// Expand returns the tree of the subjects holding the relation on the object, following the usersets
+// to the maximum depth of the evaluator. Deeper usersets fail the expansion with ErrDepthExceeded.
+func (evaluator *Evaluator) Expand(ctx context.Context, object Object, relation string) (*Tree, error) {
+	visited := map[Subject]bool{}
+	return evaluator.expand(ctx, Subject{Type: object.Type, ID: object.ID, Relation: relation}, visited, 0)
+}
+
+func (evaluator *Evaluator) expand(ctx context.Context, userset Subject, visited map[Subject]bool, depth int) (*Tree, error) {
+	tree := &Tree{Userset: userset, Subjects: []Subject{}}
+	if visited[userset] {
+		tree.Repeated = true
+		return tree, nil
+	}
+	if depth >= evaluator.maxDepth {
+		return nil, ErrDepthExceeded
+	}
+	visited[userset] = true
+
+	tuples, err := evaluator.store.Read(ctx, usersetFilter(userset))
+	if err != nil {
+		return nil, err
+	}
+	for _, tuple := range tuples {
+		if !tuple.Subject.IsUserset() {
+			tree.Subjects = append(tree.Subjects, tuple.Subject)
+			continue
+		}
+		child, err := evaluator.expand(ctx, tuple.Subject, visited, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		tree.Children = append(tree.Children, child)
+	}
+	return tree, nil
+}
+
+// LookupSubjects returns the subjects holding the relation on the object, directly or through
+// any of the usersets, ordered by type and id. A subject type, such as user, keeps only the
+// subjects of that type; an empty one keeps them all. The usersets themselves are not returned.
+// Like Check, the usersets are walked breadth first, each once, to the maximum depth of the evaluator.
+func (evaluator *Evaluator) LookupSubjects(ctx context.Context, object Object, relation string, subjectType string) ([]Subject, error) {
+	start := Subject{Type: object.Type, ID: object.ID, Relation: relation}
+	found := map[Subject]bool{}
+	visited := map[Subject]bool{start: true}
+	level := []Subject{start}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth >= evaluator.maxDepth {
+			return nil, ErrDepthExceeded
+		}
+
+		var next []Subject
+		for _, userset := range level {
+			tuples, err := evaluator.store.Read(ctx, usersetFilter(userset))
+			if err != nil {
+				return nil, err
+			}
+			for _, tuple := range tuples {
+				switch {
+				case !tuple.Subject.IsUserset():
+					if subjectType == "" || tuple.Subject.Type == subjectType {
+						found[tuple.Subject] = true
+					}
+				case !visited[tuple.Subject]:
+					visited[tuple.Subject] = true
+					next = append(next, tuple.Subject)
+				}
+			}
+		}
+		level = next
+	}
+
+	subjects := make([]Subject, 0, len(found))
+	for subject := range found {
+		subjects = append(subjects, subject)
+	}
+	slices.SortFunc(subjects, func(a, b Subject) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.ID, b.ID))
+	})
+	return subjects, nil
+}
+
+// usersetFilter selects the tuples relating subjects to the userset.
+func usersetFilter(userset Subject) Filter {
+	return Filter{ObjectType: userset.Type, ObjectID: userset.ID, Relation: userset.Relation}
+}
@@ -0,0 +1,174 @@
+package relationship
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedStore returns a store where the readme is viewed by the members of the cooks group,
+// whose members include the members of the chefs group, and edited by bob.
+func sharedStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	store := NewMemoryStore()
+	written, err := store.Write(context.Background(), mustParse(t,
+		"document:readme#viewer@group:cooks#member",
+		"document:readme#editor@user:bob",
+		"group:cooks#member@user:alice",
+		"group:cooks#member@group:chefs#member",
+		"group:chefs#member@user:carol",
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, written)
+	return store
+}
+
+func mustParse(t *testing.T, texts ...string) []Tuple {
+	t.Helper()
+	tuples := []Tuple{}
+	for _, text := range texts {
+		tuple, err := Parse(text)
+		assert.NoError(t, err)
+		tuples = append(tuples, tuple)
+	}
+	return tuples
+}
+
+func TestEvaluator_Check(t *testing.T) {
+	ctx := context.Background()
+	evaluator := NewEvaluator(sharedStore(t))
+	readme := Object{Type: "document", ID: "readme"}
+
+	for _, test := range []struct {
+		relation string
+		subject  Subject
+		expected bool
+	}{
+		{"viewer", Subject{Type: "user", ID: "alice"}, true},
+		{"viewer", Subject{Type: "user", ID: "carol"}, true},
+		{"viewer", Subject{Type: "group", ID: "chefs", Relation: "member"}, true},
+		// relations are not derived from each other
+		{"viewer", Subject{Type: "user", ID: "bob"}, false},
+		{"editor", Subject{Type: "user", ID: "bob"}, true},
+		{"editor", Subject{Type: "user", ID: "alice"}, false},
+	} {
+		allowed, err := evaluator.Check(ctx, readme, test.relation, test.subject)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, allowed, "%s %s", test.relation, test.subject)
+	}
+}
+
+func TestEvaluator_Check_Cycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.Write(ctx, mustParse(t, "group:a#member@group:b#member", "group:b#member@group:a#member"))
+	assert.NoError(t, err)
+
+	allowed, err := NewEvaluator(store).Check(ctx, Object{Type: "group", ID: "a"}, "member", Subject{Type: "user", ID: "alice"})
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestEvaluator_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	evaluator := NewEvaluator(sharedStore(t), WithMaxDepth(2))
+	readme := Object{Type: "document", ID: "readme"}
+
+	// carol is two usersets away from the readme
+	_, err := evaluator.Check(ctx, readme, "viewer", Subject{Type: "user", ID: "carol"})
+	assert.ErrorIs(t, err, ErrDepthExceeded)
+	_, err = evaluator.Expand(ctx, readme, "viewer")
+	assert.ErrorIs(t, err, ErrDepthExceeded)
+
+	allowed, err := evaluator.Check(ctx, readme, "viewer", Subject{Type: "user", ID: "alice"})
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestEvaluator_Expand(t *testing.T) {
+	ctx := context.Background()
+	store := sharedStore(t)
+	// the chefs are members of the cooks twice, the second path is not expanded again
+	_, err := store.Write(ctx, mustParse(t, "document:readme#viewer@group:chefs#member"))
+	assert.NoError(t, err)
+
+	tree, err := NewEvaluator(store).Expand(ctx, Object{Type: "document", ID: "readme"}, "viewer")
+	assert.NoError(t, err)
+
+	chefs := Subject{Type: "group", ID: "chefs", Relation: "member"}
+	assert.Equal(t, &Tree{
+		Userset:  Subject{Type: "document", ID: "readme", Relation: "viewer"},
+		Subjects: []Subject{},
+		Children: []*Tree{
+			{Userset: chefs, Subjects: []Subject{{Type: "user", ID: "carol"}}},
+			{
+				Userset:  Subject{Type: "group", ID: "cooks", Relation: "member"},
+				Subjects: []Subject{{Type: "user", ID: "alice"}},
+				Children: []*Tree{{Userset: chefs, Subjects: []Subject{}, Repeated: true}},
+			},
+		},
+	}, tree)
+}
+
+func TestEvaluator_LookupSubjects(t *testing.T) {
+	ctx := context.Background()
+	store := sharedStore(t)
+	// alice is reached twice and a robot views the readme directly
+	_, err := store.Write(ctx, mustParse(t, "group:chefs#member@user:alice", "document:readme#viewer@service:indexer"))
+	assert.NoError(t, err)
+	evaluator := NewEvaluator(store)
+	readme := Object{Type: "document", ID: "readme"}
+
+	subjects, err := evaluator.LookupSubjects(ctx, readme, "viewer", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []Subject{{Type: "service", ID: "indexer"}, {Type: "user", ID: "alice"}, {Type: "user", ID: "carol"}}, subjects)
+
+	subjects, err = evaluator.LookupSubjects(ctx, readme, "viewer", "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []Subject{{Type: "user", ID: "alice"}, {Type: "user", ID: "carol"}}, subjects)
+
+	subjects, err = evaluator.LookupSubjects(ctx, Object{Type: "document", ID: "changelog"}, "viewer", "")
+	assert.NoError(t, err)
+	assert.Empty(t, subjects)
+
+	_, err = NewEvaluator(store, WithMaxDepth(2)).LookupSubjects(ctx, readme, "viewer", "")
+	assert.ErrorIs(t, err, ErrDepthExceeded)
+}
+
+// failingStore fails every read.
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) Read(ctx context.Context, filter Filter) ([]Tuple, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestEvaluator_StoreError(t *testing.T) {
+	ctx := context.Background()
+	evaluator := NewEvaluator(failingStore{NewMemoryStore()})
+
+	_, err := evaluator.Check(ctx, Object{Type: "document", ID: "readme"}, "viewer", Subject{Type: "user", ID: "alice"})
+	assert.EqualError(t, err, "store unavailable")
+	_, err = evaluator.Expand(ctx, Object{Type: "document", ID: "readme"}, "viewer")
+	assert.EqualError(t, err, "store unavailable")
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := sharedStore(t)
+
+	written, err := store.Write(ctx, mustParse(t, "group:cooks#member@user:alice", "group:cooks#member@user:dave"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, written)
+	_, err = store.Write(ctx, []Tuple{{Object: Object{Type: "group", ID: "cooks"}, Relation: "Member", Subject: Subject{Type: "user", ID: "erin"}}})
+	assert.ErrorIs(t, err, ErrInvalidTuple)
+
+	deleted, err := store.Delete(ctx, mustParse(t, "group:cooks#member@user:dave", "group:cooks#member@user:zoe"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	tuples, err := store.Read(ctx, Filter{ObjectType: "group", ObjectID: "cooks"})
+	assert.NoError(t, err)
+	assert.Equal(t, mustParse(t, "group:cooks#member@group:chefs#member", "group:cooks#member@user:alice"), tuples)
+}
@@ -0,0 +1,72 @@
+package relationship
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore is a thread-safe in-memory implementation of the Store interface, for tests and demos.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tuples map[Tuple]struct{}
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tuples: map[Tuple]struct{}{}}
+}
+
+// Write records the tuples, ignoring those already recorded, and returns how many were added.
+// Nothing is recorded when a tuple is invalid.
+func (store *MemoryStore) Write(ctx context.Context, tuples []Tuple) (int, error) {
+	for _, tuple := range tuples {
+		if err := tuple.Validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	written := 0
+	for _, tuple := range tuples {
+		if _, ok := store.tuples[tuple]; !ok {
+			store.tuples[tuple] = struct{}{}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// Delete removes the tuples, ignoring those not recorded, and returns how many were removed.
+func (store *MemoryStore) Delete(ctx context.Context, tuples []Tuple) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := 0
+	for _, tuple := range tuples {
+		if _, ok := store.tuples[tuple]; ok {
+			delete(store.tuples, tuple)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Read returns the tuples matching the filter, ordered by Compare.
+func (store *MemoryStore) Read(ctx context.Context, filter Filter) ([]Tuple, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	tuples := []Tuple{}
+	for tuple := range store.tuples {
+		if filter.Matches(tuple) {
+			tuples = append(tuples, tuple)
+		}
+	}
+	slices.SortFunc(tuples, Compare)
+	return tuples, nil
+}
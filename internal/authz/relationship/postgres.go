@@ -0,0 +1,108 @@
+package relationship
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface, recording the tuples
+// in the relationship_tuples table.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Write records the tuples in a single statement, ignoring those already recorded, and returns
+// how many were added. Nothing is recorded when a tuple is invalid.
+func (store *PostgresStore) Write(ctx context.Context, tuples []Tuple) (int, error) {
+	for _, tuple := range tuples {
+		if err := tuple.Validate(); err != nil {
+			return 0, err
+		}
+	}
+	if len(tuples) == 0 {
+		return 0, nil
+	}
+
+	tag, err := store.db.Exec(ctx, `
+	INSERT INTO relationship_tuples (object_type, object_id, relation, subject_type, subject_id, subject_relation)
+	SELECT * FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[])
+	ON CONFLICT DO NOTHING
+	`, columns(tuples)...)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Delete removes the tuples in a single statement, ignoring those not recorded, and returns how many were removed.
+func (store *PostgresStore) Delete(ctx context.Context, tuples []Tuple) (int, error) {
+	if len(tuples) == 0 {
+		return 0, nil
+	}
+
+	tag, err := store.db.Exec(ctx, `
+	DELETE FROM relationship_tuples t
+	USING unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[])
+		AS d(object_type, object_id, relation, subject_type, subject_id, subject_relation)
+	WHERE t.object_type = d.object_type AND t.object_id = d.object_id AND t.relation = d.relation
+	AND t.subject_type = d.subject_type AND t.subject_id = d.subject_id AND t.subject_relation = d.subject_relation
+	`, columns(tuples)...)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Read returns the tuples matching the filter, ordered by Compare. The C collation orders
+// the text byte by byte, as Compare does.
+func (store *PostgresStore) Read(ctx context.Context, filter Filter) ([]Tuple, error) {
+	rows, err := store.db.Query(ctx, `
+	SELECT object_type, object_id, relation, subject_type, subject_id, subject_relation FROM relationship_tuples
+	WHERE ($1 = '' OR object_type = $1) AND ($2 = '' OR object_id = $2) AND ($3 = '' OR relation = $3)
+	AND ($4 = '' OR subject_type = $4) AND ($5 = '' OR subject_id = $5) AND (NOT $7 OR subject_relation = $6)
+	ORDER BY object_type COLLATE "C", object_id COLLATE "C", relation COLLATE "C",
+		subject_type COLLATE "C", subject_id COLLATE "C", subject_relation COLLATE "C"
+	`, filter.ObjectType, filter.ObjectID, filter.Relation, filter.SubjectType, filter.SubjectID, filter.SubjectRelation, filter.subjectSet())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tuples := []Tuple{}
+	for rows.Next() {
+		var tuple Tuple
+		err := rows.Scan(&tuple.Object.Type, &tuple.Object.ID, &tuple.Relation, &tuple.Subject.Type, &tuple.Subject.ID, &tuple.Subject.Relation)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, tuple)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return tuples, nil
+}
+
+// columns returns the arrays of the values of each column of the tuples, for unnest.
+func columns(tuples []Tuple) []any {
+	objectTypes := make([]string, len(tuples))
+	objectIds := make([]string, len(tuples))
+	relations := make([]string, len(tuples))
+	subjectTypes := make([]string, len(tuples))
+	subjectIds := make([]string, len(tuples))
+	subjectRelations := make([]string, len(tuples))
+	for i, tuple := range tuples {
+		objectTypes[i], objectIds[i], relations[i] = tuple.Object.Type, tuple.Object.ID, tuple.Relation
+		subjectTypes[i], subjectIds[i], subjectRelations[i] = tuple.Subject.Type, tuple.Subject.ID, tuple.Subject.Relation
+	}
+	return []any{objectTypes, objectIds, relations, subjectTypes, subjectIds, subjectRelations}
+}
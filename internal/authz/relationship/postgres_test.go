@@ -0,0 +1,101 @@
+package relationship
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestPostgresStore_Write(t *testing.T) {
+	ctx := context.Background()
+	tuples := mustParse(t, "document:readme#viewer@group:cooks#member", "group:cooks#member@user:alice")
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{
+			[]string{"document", "group"}, []string{"readme", "cooks"}, []string{"viewer", "member"},
+			[]string{"group", "user"}, []string{"cooks", "alice"}, []string{"member", ""},
+		}).Return(pgconn.NewCommandTag("INSERT 0 2"), nil)
+
+		written, err := NewPostgresStore(mockDb).Write(ctx, tuples)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, written)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("invalid tuple", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+
+		_, err := NewPostgresStore(mockDb).Write(ctx, append(tuples, Tuple{Object: Object{Type: "document"}, Relation: "viewer"}))
+		assert.ErrorIs(t, err, ErrInvalidTuple)
+
+		mockDb.AssertNotCalled(t, "Exec")
+	})
+
+	t.Run("no tuples", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+
+		written, err := NewPostgresStore(mockDb).Write(ctx, nil)
+		assert.NoError(t, err)
+		assert.Zero(t, written)
+
+		mockDb.AssertNotCalled(t, "Exec")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		_, err := NewPostgresStore(mockDb).Write(ctx, tuples)
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestPostgresStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockDb.On("Exec", ctx, mock.Anything, []any{
+		[]string{"group"}, []string{"cooks"}, []string{"member"}, []string{"user"}, []string{"alice"}, []string{""},
+	}).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+
+	deleted, err := NewPostgresStore(mockDb).Delete(ctx, mustParse(t, "group:cooks#member@user:alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	mockDb.AssertExpectations(t)
+}
+
+func TestPostgresStore_Read(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any{"group", "cooks", "member", "", "", "", false}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*string)) = "group"
+		*(dest[1].(*string)) = "cooks"
+		*(dest[2].(*string)) = "member"
+		*(dest[3].(*string)) = "group"
+		*(dest[4].(*string)) = "chefs"
+		*(dest[5].(*string)) = "member"
+	}).Return(nil).Once()
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	tuples, err := NewPostgresStore(mockDb).Read(ctx, Filter{ObjectType: "group", ObjectID: "cooks", Relation: "member"})
+	assert.NoError(t, err)
+	assert.Equal(t, mustParse(t, "group:cooks#member@group:chefs#member"), tuples)
+
+	mockDb.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
@@ -0,0 +1,265 @@
+// Package relationship stores relationship tuples, such as document:readme#viewer@user:alice, and
+// evaluates them by walking the graph they form, so single objects can be shared with users and groups.
+// It follows the Zanzibar model: a tuple relates an object to a subject, which is either a user or,
+// with a relation, the set of subjects holding that relation on another object, such as group:cooks#member.
+// Relationships coexist with the groups and permissions of the policy, which grant permissions globally
+// rather than on objects.
+package relationship
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidTuple is returned for a tuple, an object or a subject that does not parse or validate.
+	ErrInvalidTuple = errors.New("invalid relationship tuple")
+	// ErrDepthExceeded is returned when evaluating a relation nests usersets deeper than the maximum depth.
+	ErrDepthExceeded = errors.New("relationship graph exceeds the maximum depth")
+)
+
+// namePattern matches the object types and the relations, such as document or viewer.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// maxIDLength is the longest object id stored.
+const maxIDLength = 255
+
+// ValidateRelation checks the relation is a lower case name, such as viewer.
+func ValidateRelation(relation string) error {
+	if !namePattern.MatchString(relation) {
+		return fmt.Errorf("%w: invalid relation %q", ErrInvalidTuple, relation)
+	}
+	return nil
+}
+
+// ValidateType checks the object type is a lower case name, such as document.
+func ValidateType(objectType string) error {
+	if !namePattern.MatchString(objectType) {
+		return fmt.Errorf("%w: invalid object type %q", ErrInvalidTuple, objectType)
+	}
+	return nil
+}
+
+// Object is an object relationships are recorded on, such as document:readme.
+type Object struct {
+	Type string
+	ID   string
+}
+
+// ParseObject parses an object in the type:id form.
+func ParseObject(text string) (Object, error) {
+	objectType, id, ok := strings.Cut(text, ":")
+	if !ok {
+		return Object{}, fmt.Errorf("%w: %q is not an object", ErrInvalidTuple, text)
+	}
+	object := Object{Type: objectType, ID: id}
+	return object, object.Validate()
+}
+
+// Validate checks the type is a lower case name and the id is not empty and holds no # or @.
+func (object Object) Validate() error {
+	if err := ValidateType(object.Type); err != nil {
+		return err
+	}
+	if object.ID == "" || len(object.ID) > maxIDLength || strings.ContainsAny(object.ID, "#@ \t\r\n") {
+		return fmt.Errorf("%w: invalid object id %q", ErrInvalidTuple, object.ID)
+	}
+	return nil
+}
+
+// String returns the object in the type:id form.
+func (object Object) String() string {
+	return object.Type + ":" + object.ID
+}
+
+// MarshalText encodes the object in the type:id form.
+func (object Object) MarshalText() ([]byte, error) {
+	return []byte(object.String()), nil
+}
+
+// UnmarshalText decodes an object in the type:id form.
+func (object *Object) UnmarshalText(text []byte) error {
+	parsed, err := ParseObject(string(text))
+	if err != nil {
+		return err
+	}
+	*object = parsed
+	return nil
+}
+
+// Subject is the subject of a relationship: an object, usually a user such as user:alice, or with
+// a relation the set of subjects holding that relation on the object, such as group:cooks#member.
+type Subject struct {
+	Type     string
+	ID       string
+	Relation string
+}
+
+// ParseSubject parses a subject in the type:id or type:id#relation form.
+func ParseSubject(text string) (Subject, error) {
+	objectText, relation, hasRelation := strings.Cut(text, "#")
+	object, err := ParseObject(objectText)
+	if err != nil {
+		return Subject{}, err
+	}
+	subject := Subject{Type: object.Type, ID: object.ID, Relation: relation}
+	if hasRelation && !namePattern.MatchString(relation) {
+		return Subject{}, fmt.Errorf("%w: invalid subject relation %q", ErrInvalidTuple, relation)
+	}
+	return subject, nil
+}
+
+// Validate checks the object of the subject and, for a userset, its relation.
+func (subject Subject) Validate() error {
+	if err := subject.Object().Validate(); err != nil {
+		return err
+	}
+	if subject.Relation != "" && !namePattern.MatchString(subject.Relation) {
+		return fmt.Errorf("%w: invalid subject relation %q", ErrInvalidTuple, subject.Relation)
+	}
+	return nil
+}
+
+// Object returns the object of the subject, without its relation.
+func (subject Subject) Object() Object {
+	return Object{Type: subject.Type, ID: subject.ID}
+}
+
+// IsUserset reports whether the subject is the set of subjects holding a relation on an object.
+func (subject Subject) IsUserset() bool {
+	return subject.Relation != ""
+}
+
+// String returns the subject in the type:id or type:id#relation form.
+func (subject Subject) String() string {
+	if subject.Relation == "" {
+		return subject.Object().String()
+	}
+	return subject.Object().String() + "#" + subject.Relation
+}
+
+// MarshalText encodes the subject in the type:id or type:id#relation form.
+func (subject Subject) MarshalText() ([]byte, error) {
+	return []byte(subject.String()), nil
+}
+
+// UnmarshalText decodes a subject in the type:id or type:id#relation form.
+func (subject *Subject) UnmarshalText(text []byte) error {
+	parsed, err := ParseSubject(string(text))
+	if err != nil {
+		return err
+	}
+	*subject = parsed
+	return nil
+}
+
+// Tuple relates an object to a subject, such as document:readme#viewer@group:cooks#member.
+type Tuple struct {
+	Object   Object
+	Relation string
+	Subject  Subject
+}
+
+// Parse parses a tuple in the object#relation@subject form.
+func Parse(text string) (Tuple, error) {
+	resource, subjectText, ok := strings.Cut(text, "@")
+	if !ok {
+		return Tuple{}, fmt.Errorf("%w: %q has no subject", ErrInvalidTuple, text)
+	}
+	objectText, relation, ok := strings.Cut(resource, "#")
+	if !ok {
+		return Tuple{}, fmt.Errorf("%w: %q has no relation", ErrInvalidTuple, text)
+	}
+
+	object, err := ParseObject(objectText)
+	if err != nil {
+		return Tuple{}, err
+	}
+	subject, err := ParseSubject(subjectText)
+	if err != nil {
+		return Tuple{}, err
+	}
+	tuple := Tuple{Object: object, Relation: relation, Subject: subject}
+	return tuple, tuple.Validate()
+}
+
+// Validate checks the object, the relation and the subject of the tuple.
+func (tuple Tuple) Validate() error {
+	if err := tuple.Object.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateRelation(tuple.Relation); err != nil {
+		return err
+	}
+	return tuple.Subject.Validate()
+}
+
+// String returns the tuple in the object#relation@subject form.
+func (tuple Tuple) String() string {
+	return tuple.Object.String() + "#" + tuple.Relation + "@" + tuple.Subject.String()
+}
+
+// MarshalText encodes the tuple in the object#relation@subject form, so tuples are JSON strings.
+func (tuple Tuple) MarshalText() ([]byte, error) {
+	return []byte(tuple.String()), nil
+}
+
+// UnmarshalText decodes a tuple in the object#relation@subject form.
+func (tuple *Tuple) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*tuple = parsed
+	return nil
+}
+
+// Compare orders tuples by object, relation and subject, the order stores list them in.
+func Compare(a Tuple, b Tuple) int {
+	return cmp.Or(
+		cmp.Compare(a.Object.Type, b.Object.Type), cmp.Compare(a.Object.ID, b.Object.ID), cmp.Compare(a.Relation, b.Relation),
+		cmp.Compare(a.Subject.Type, b.Subject.Type), cmp.Compare(a.Subject.ID, b.Subject.ID), cmp.Compare(a.Subject.Relation, b.Subject.Relation),
+	)
+}
+
+// Filter selects the tuples returned by Store.Read. Empty fields match any value, except
+// SubjectRelation which only matches when the subject type and id are set as well.
+type Filter struct {
+	ObjectType      string
+	ObjectID        string
+	Relation        string
+	SubjectType     string
+	SubjectID       string
+	SubjectRelation string
+}
+
+// Matches reports whether the tuple is selected by the filter.
+func (filter Filter) Matches(tuple Tuple) bool {
+	matches := func(want string, value string) bool { return want == "" || want == value }
+	if !matches(filter.ObjectType, tuple.Object.Type) || !matches(filter.ObjectID, tuple.Object.ID) || !matches(filter.Relation, tuple.Relation) {
+		return false
+	}
+	if !matches(filter.SubjectType, tuple.Subject.Type) || !matches(filter.SubjectID, tuple.Subject.ID) {
+		return false
+	}
+	return !filter.subjectSet() || filter.SubjectRelation == tuple.Subject.Relation
+}
+
+// subjectSet reports whether the filter selects one subject, matching its relation exactly.
+func (filter Filter) subjectSet() bool {
+	return filter.SubjectType != "" && filter.SubjectID != ""
+}
+
+// Store persists relationship tuples.
+type Store interface {
+	// Write records the tuples, ignoring those already recorded, and returns how many were added.
+	Write(ctx context.Context, tuples []Tuple) (int, error)
+	// Delete removes the tuples, ignoring those not recorded, and returns how many were removed.
+	Delete(ctx context.Context, tuples []Tuple) (int, error)
+	// Read returns the tuples matching the filter, ordered by Compare.
+	Read(ctx context.Context, filter Filter) ([]Tuple, error)
+}
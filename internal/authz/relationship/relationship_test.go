@@ -0,0 +1,63 @@
+package relationship
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("user subject", func(t *testing.T) {
+		tuple, err := Parse("document:docs/readme.md#viewer@user:alice")
+		assert.NoError(t, err)
+		assert.Equal(t, Tuple{Object: Object{Type: "document", ID: "docs/readme.md"}, Relation: "viewer", Subject: Subject{Type: "user", ID: "alice"}}, tuple)
+		assert.Equal(t, "document:docs/readme.md#viewer@user:alice", tuple.String())
+		assert.False(t, tuple.Subject.IsUserset())
+	})
+
+	t.Run("userset subject", func(t *testing.T) {
+		tuple, err := Parse("document:readme#viewer@group:cooks#member")
+		assert.NoError(t, err)
+		assert.Equal(t, Subject{Type: "group", ID: "cooks", Relation: "member"}, tuple.Subject)
+		assert.Equal(t, "document:readme#viewer@group:cooks#member", tuple.String())
+		assert.True(t, tuple.Subject.IsUserset())
+	})
+
+	for _, text := range []string{
+		"", "document:readme#viewer", "document:readme@user:alice", "readme#viewer@user:alice",
+		"Document:readme#viewer@user:alice", "document:#viewer@user:alice", "document:readme#Viewer@user:alice",
+		"document:readme#viewer@alice", "document:readme#viewer@group:cooks#", "document:read me#viewer@user:alice",
+		"document:readme#viewer@user:alice@example.org",
+	} {
+		_, err := Parse(text)
+		assert.ErrorIs(t, err, ErrInvalidTuple, text)
+	}
+}
+
+func TestTuple_JSON(t *testing.T) {
+	tuple := Tuple{Object: Object{Type: "document", ID: "readme"}, Relation: "viewer", Subject: Subject{Type: "group", ID: "cooks", Relation: "member"}}
+
+	encoded, err := json.Marshal([]Tuple{tuple})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["document:readme#viewer@group:cooks#member"]`, string(encoded))
+
+	var decoded []Tuple
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, []Tuple{tuple}, decoded)
+	assert.Error(t, json.Unmarshal([]byte(`["document:readme"]`), &decoded))
+}
+
+func TestFilter_Matches(t *testing.T) {
+	direct := Tuple{Object: Object{Type: "document", ID: "readme"}, Relation: "viewer", Subject: Subject{Type: "group", ID: "cooks"}}
+	userset := Tuple{Object: Object{Type: "document", ID: "readme"}, Relation: "viewer", Subject: Subject{Type: "group", ID: "cooks", Relation: "member"}}
+
+	assert.True(t, Filter{}.Matches(userset))
+	assert.True(t, Filter{ObjectType: "document", Relation: "viewer"}.Matches(userset))
+	assert.False(t, Filter{ObjectID: "changelog"}.Matches(userset))
+	// the subject relation is matched exactly once the subject is set
+	assert.True(t, Filter{SubjectType: "group", SubjectID: "cooks"}.Matches(direct))
+	assert.False(t, Filter{SubjectType: "group", SubjectID: "cooks"}.Matches(userset))
+	assert.True(t, Filter{SubjectType: "group", SubjectID: "cooks", SubjectRelation: "member"}.Matches(userset))
+	assert.True(t, Filter{SubjectType: "group"}.Matches(userset))
+}
@@ -0,0 +1,192 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// GraphFormat is a text format the policy graph can be rendered in.
+type GraphFormat string
+
+const (
+	// GraphDOT renders the graph for Graphviz.
+	GraphDOT GraphFormat = "dot"
+	// GraphMermaid renders the graph as a Mermaid flowchart, which Markdown renderers draw inline.
+	GraphMermaid GraphFormat = "mermaid"
+)
+
+// ParseGraphFormat validates the given value and returns the matching GraphFormat.
+func ParseGraphFormat(value string) (GraphFormat, error) {
+	format := GraphFormat(value)
+	if format != GraphDOT && format != GraphMermaid {
+		return "", fmt.Errorf("unknown graph format %q, expected dot or mermaid", value)
+	}
+	return format, nil
+}
+
+// GraphOptions configures how the policy graph is rendered.
+type GraphOptions struct {
+	Format GraphFormat
+	// Leave the users out, which keeps the graph of large policies readable.
+	OmitUsers bool
+}
+
+// graph is the policy graph, with its nodes sorted by name so renders are reproducible.
+type graph struct {
+	users       []string
+	groups      []string
+	permissions []authz.Permission
+	// The edges from users to groups, from groups to permissions and between implied permissions.
+	memberships [][2]string
+	grants      [][2]string
+	implies     [][2]string
+}
+
+// WriteGraph renders the policy as a graph of users, the groups they belong to and the permissions
+// granted to those groups. Implied permissions are linked with dashed edges and high risk
+// permissions are highlighted.
+//
+// Parameters:
+//
+//	w - the writer the graph is written to.
+//	policy - the policy to render.
+//	options - the format and content of the graph.
+//
+// Returns:
+//
+//	error - an error if the format is unknown or writing fails.
+func WriteGraph(w io.Writer, policy *authz.Policy, options GraphOptions) error {
+	g := newGraph(policy, options)
+
+	out := bufio.NewWriter(w)
+	switch options.Format {
+	case GraphDOT:
+		g.writeDOT(out)
+	case GraphMermaid:
+		g.writeMermaid(out)
+	default:
+		return fmt.Errorf("unknown graph format %q, expected dot or mermaid", options.Format)
+	}
+	return out.Flush()
+}
+
+func newGraph(policy *authz.Policy, options GraphOptions) *graph {
+	g := &graph{permissions: slices.Clone(policy.Permissions)}
+	slices.SortFunc(g.permissions, func(a, b authz.Permission) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, group := range policy.Groups {
+		g.groups = append(g.groups, group.Name)
+		if options.OmitUsers {
+			continue
+		}
+		for _, user := range group.Users {
+			g.users = append(g.users, user)
+			g.memberships = append(g.memberships, [2]string{user, group.Name})
+		}
+	}
+	for _, permission := range g.permissions {
+		for _, group := range permission.Groups {
+			// grants may name groups without members, which are only known through the grant
+			g.groups = append(g.groups, group)
+			g.grants = append(g.grants, [2]string{group, permission.Name})
+		}
+		for _, implied := range permission.Implies {
+			g.implies = append(g.implies, [2]string{permission.Name, implied})
+		}
+	}
+
+	for _, names := range []*[]string{&g.users, &g.groups} {
+		slices.Sort(*names)
+		*names = slices.Compact(*names)
+	}
+	for _, edges := range []*[][2]string{&g.memberships, &g.grants, &g.implies} {
+		slices.SortFunc(*edges, func(a, b [2]string) int {
+			return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+		})
+		*edges = slices.Compact(*edges)
+	}
+	return g
+}
+
+func (g *graph) writeDOT(out *bufio.Writer) {
+	id := func(kind string, name string) string { return dotQuote(kind + ":" + name) }
+
+	fmt.Fprintln(out, "digraph policy {")
+	fmt.Fprintln(out, "\trankdir=LR;")
+	for _, user := range g.users {
+		fmt.Fprintf(out, "\t%s [label=%s, shape=ellipse];\n", id("user", user), dotQuote(user))
+	}
+	for _, group := range g.groups {
+		fmt.Fprintf(out, "\t%s [label=%s, shape=box];\n", id("group", group), dotQuote(group))
+	}
+	for _, permission := range g.permissions {
+		style := ""
+		if permission.Risk == authz.RiskHigh {
+			style = ", color=red"
+		}
+		fmt.Fprintf(out, "\t%s [label=%s, shape=note%s];\n", id("permission", permission.Name), dotQuote(permission.Name), style)
+	}
+	for _, edge := range g.memberships {
+		fmt.Fprintf(out, "\t%s -> %s;\n", id("user", edge[0]), id("group", edge[1]))
+	}
+	for _, edge := range g.grants {
+		fmt.Fprintf(out, "\t%s -> %s;\n", id("group", edge[0]), id("permission", edge[1]))
+	}
+	for _, edge := range g.implies {
+		fmt.Fprintf(out, "\t%s -> %s [style=dashed, label=\"implies\"];\n", id("permission", edge[0]), id("permission", edge[1]))
+	}
+	fmt.Fprintln(out, "}")
+}
+
+func (g *graph) writeMermaid(out *bufio.Writer) {
+	// Mermaid node ids must be plain identifiers, so nodes are numbered and named through labels
+	ids := make(map[string]string)
+	id := func(kind string, name string) string {
+		key := kind + ":" + name
+		if _, ok := ids[key]; !ok {
+			ids[key] = fmt.Sprintf("%s%d", kind[:1], len(ids))
+		}
+		return ids[key]
+	}
+
+	fmt.Fprintln(out, "flowchart LR")
+	for _, user := range g.users {
+		fmt.Fprintf(out, "\t%s([%s])\n", id("user", user), mermaidQuote(user))
+	}
+	for _, group := range g.groups {
+		fmt.Fprintf(out, "\t%s[%s]\n", id("group", group), mermaidQuote(group))
+	}
+	highRisk := []string{}
+	for _, permission := range g.permissions {
+		fmt.Fprintf(out, "\t%s{{%s}}\n", id("permission", permission.Name), mermaidQuote(permission.Name))
+		if permission.Risk == authz.RiskHigh {
+			highRisk = append(highRisk, id("permission", permission.Name))
+		}
+	}
+	for _, edge := range g.memberships {
+		fmt.Fprintf(out, "\t%s --> %s\n", id("user", edge[0]), id("group", edge[1]))
+	}
+	for _, edge := range g.grants {
+		fmt.Fprintf(out, "\t%s --> %s\n", id("group", edge[0]), id("permission", edge[1]))
+	}
+	for _, edge := range g.implies {
+		fmt.Fprintf(out, "\t%s -.->|implies| %s\n", id("permission", edge[0]), id("permission", edge[1]))
+	}
+	if len(highRisk) > 0 {
+		fmt.Fprintln(out, "\tclassDef highRisk stroke:#d00,stroke-width:2px")
+		fmt.Fprintf(out, "\tclass %s highRisk\n", strings.Join(highRisk, ","))
+	}
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func mermaidQuote(value string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(value) + `"`
+}
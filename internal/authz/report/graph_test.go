@@ -0,0 +1,95 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+func graphPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "recipes.read", Groups: []string{"readers"}},
+			{Name: "recipes.delete", Groups: []string{"admins", "owners"}, Implies: []string{"recipes.read"}, Risk: authz.RiskHigh},
+		},
+		[]authz.Group{
+			*authz.NewGroup("readers", []string{"bob"}),
+			*authz.NewGroup("admins", []string{"alice"}),
+		},
+	)
+}
+
+// TestWriteGraph_DOT renders a policy as DOT, checking the nodes and edges.
+func TestWriteGraph_DOT(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteGraph(&out, graphPolicy(), GraphOptions{Format: GraphDOT}))
+
+	assert.Equal(t, `digraph policy {
+	rankdir=LR;
+	"user:alice" [label="alice", shape=ellipse];
+	"user:bob" [label="bob", shape=ellipse];
+	"group:admins" [label="admins", shape=box];
+	"group:owners" [label="owners", shape=box];
+	"group:readers" [label="readers", shape=box];
+	"permission:recipes.delete" [label="recipes.delete", shape=note, color=red];
+	"permission:recipes.read" [label="recipes.read", shape=note];
+	"user:alice" -> "group:admins";
+	"user:bob" -> "group:readers";
+	"group:admins" -> "permission:recipes.delete";
+	"group:owners" -> "permission:recipes.delete";
+	"group:readers" -> "permission:recipes.read";
+	"permission:recipes.delete" -> "permission:recipes.read" [style=dashed, label="implies"];
+}
+`, out.String())
+}
+
+// TestWriteGraph_Mermaid renders a policy as Mermaid without users, checking the nodes and edges.
+func TestWriteGraph_Mermaid(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, WriteGraph(&out, graphPolicy(), GraphOptions{Format: GraphMermaid, OmitUsers: true}))
+
+	assert.Equal(t, `flowchart LR
+	g0["admins"]
+	g1["owners"]
+	g2["readers"]
+	p3{{"recipes.delete"}}
+	p4{{"recipes.read"}}
+	g0 --> p3
+	g1 --> p3
+	g2 --> p4
+	p3 -.->|implies| p4
+	classDef highRisk stroke:#d00,stroke-width:2px
+	class p3 highRisk
+`, out.String())
+}
+
+// TestWriteGraph_Quoting renders names with quotes, checking they are escaped.
+func TestWriteGraph_Quoting(t *testing.T) {
+	policy := authz.NewPolicy(nil, []authz.Group{*authz.NewGroup(`say "hi"`, nil)})
+
+	var dot bytes.Buffer
+	assert.NoError(t, WriteGraph(&dot, policy, GraphOptions{Format: GraphDOT}))
+	assert.Contains(t, dot.String(), `"group:say \"hi\"" [label="say \"hi\""`)
+
+	var mermaid bytes.Buffer
+	assert.NoError(t, WriteGraph(&mermaid, policy, GraphOptions{Format: GraphMermaid}))
+	assert.Contains(t, mermaid.String(), `g0["say #quot;hi#quot;"]`)
+}
+
+// TestWriteGraph_Error_Format renders a policy in an unknown format, checking for an error.
+func TestWriteGraph_Error_Format(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, WriteGraph(&out, graphPolicy(), GraphOptions{Format: "svg"}))
+}
+
+// TestParseGraphFormat calls report.ParseGraphFormat, checking known and unknown formats.
+func TestParseGraphFormat(t *testing.T) {
+	format, err := ParseGraphFormat("mermaid")
+	assert.NoError(t, err)
+	assert.Equal(t, GraphMermaid, format)
+
+	_, err = ParseGraphFormat("svg")
+	assert.Error(t, err)
+}
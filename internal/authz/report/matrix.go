@@ -0,0 +1,129 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// MatrixFilter narrows a Matrix down to some groups or permissions.
+type MatrixFilter struct {
+	// Only include the members of these groups, when set.
+	Groups []string
+	// Only include these permissions, when set.
+	Permissions []string
+}
+
+// Matrix tells which users hold which effective permissions, including the implied ones.
+type Matrix struct {
+	// The permission columns, sorted by name.
+	Permissions []string `json:"permissions"`
+
+	// A row per user, sorted by user.
+	Rows []MatrixRow `json:"rows"`
+}
+
+// MatrixRow holds, for a single user, whether each permission of the matrix is held.
+type MatrixRow struct {
+	User    string `json:"user"`
+	Granted []bool `json:"granted"`
+}
+
+// BuildMatrix builds the users by permissions Matrix of the given policy.
+//
+// Parameters:
+//
+//	policy - the policy to summarize.
+//	filter - the groups and permissions to restrict the matrix to.
+//
+// Returns:
+//
+//	*Matrix - the effective permissions of every selected user.
+//	error - an error if the filter names an unknown group or permission, or evaluating a user fails.
+func BuildMatrix(policy *authz.Policy, filter MatrixFilter) (*Matrix, error) {
+	groups := shared.NewMultiMap[string, string](len(policy.Groups))
+	for _, group := range policy.Groups {
+		groups.Add(group.Name, group.Users...)
+	}
+
+	users := Users(policy)
+	if len(filter.Groups) > 0 {
+		users = []string{}
+		for _, name := range filter.Groups {
+			if !groups.Has(name) {
+				return nil, fmt.Errorf("unknown group %q", name)
+			}
+			users = append(users, groups.Get(name)...)
+		}
+		slices.Sort(users)
+		users = slices.Compact(users)
+	}
+
+	permissions := []string{}
+	for _, permission := range policy.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+	if len(filter.Permissions) > 0 {
+		for _, name := range filter.Permissions {
+			if !slices.Contains(permissions, name) {
+				return nil, fmt.Errorf("unknown permission %q", name)
+			}
+		}
+		permissions = slices.Clone(filter.Permissions)
+	}
+	slices.Sort(permissions)
+	permissions = slices.Compact(permissions)
+
+	matrix := &Matrix{Permissions: permissions, Rows: []MatrixRow{}}
+	for _, user := range users {
+		result, err := policy.Evaluate(user)
+		if err != nil {
+			return nil, err
+		}
+
+		session := authz.NewSession(user, result)
+		row := MatrixRow{User: user, Granted: make([]bool, len(permissions))}
+		for i, permission := range permissions {
+			row.Granted[i] = session.HasPermission(permission)
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	return matrix, nil
+}
+
+// Records returns the matrix as a header record followed by a record per user,
+// marking held permissions with an "x".
+func (matrix *Matrix) Records() [][]string {
+	records := [][]string{append([]string{"user"}, matrix.Permissions...)}
+	for _, row := range matrix.Rows {
+		record := []string{row.User}
+		for _, granted := range row.Granted {
+			if granted {
+				record = append(record, "x")
+			} else {
+				record = append(record, "")
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// WriteCSV writes the matrix records as CSV.
+func (matrix *Matrix) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(matrix.Records()); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// WriteXLSX writes the matrix records as a single sheet Excel workbook.
+func (matrix *Matrix) WriteXLSX(w io.Writer) error {
+	return writeXLSX(w, "Matrix", matrix.Records())
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuildMatrix calls report.BuildMatrix without a filter, checking every user and permission is included.
+func TestBuildMatrix(t *testing.T) {
+	matrix, err := BuildMatrix(testPolicy(), MatrixFilter{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"recipes.delete", "recipes.publish", "recipes.read"}, matrix.Permissions)
+	assert.Equal(t, []MatrixRow{
+		{User: "alice", Granted: []bool{true, false, true}},
+		{User: "bob", Granted: []bool{false, true, true}},
+		{User: "carol", Granted: []bool{false, false, true}},
+	}, matrix.Rows)
+}
+
+// TestBuildMatrix_Filter calls report.BuildMatrix with a group and permission filter, checking the matrix is narrowed down.
+func TestBuildMatrix_Filter(t *testing.T) {
+	matrix, err := BuildMatrix(testPolicy(), MatrixFilter{Groups: []string{"editors"}, Permissions: []string{"recipes.read", "recipes.delete"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"recipes.delete", "recipes.read"}, matrix.Permissions)
+	assert.Equal(t, []MatrixRow{{User: "bob", Granted: []bool{false, true}}}, matrix.Rows)
+}
+
+// TestBuildMatrix_Implied calls report.BuildMatrix with implied permissions, checking they are marked as held.
+func TestBuildMatrix_Implied(t *testing.T) {
+	policy := testPolicy()
+	policy.Permissions[1].Implies = []string{"recipes.read"}
+
+	matrix, err := BuildMatrix(policy, MatrixFilter{Groups: []string{"editors"}, Permissions: []string{"recipes.read"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []MatrixRow{{User: "bob", Granted: []bool{true}}}, matrix.Rows)
+}
+
+// TestBuildMatrix_Wildcard calls report.BuildMatrix with a wildcard permission, checking the permissions below it are marked as held.
+func TestBuildMatrix_Wildcard(t *testing.T) {
+	policy := testPolicy()
+	policy.Permissions = append(policy.Permissions, authz.Permission{Name: "recipes.*", Groups: []string{"editors"}})
+
+	matrix, err := BuildMatrix(policy, MatrixFilter{Groups: []string{"editors"}, Permissions: []string{"recipes.delete", "recipes.read"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []MatrixRow{{User: "bob", Granted: []bool{true, true}}}, matrix.Rows)
+}
+
+// TestBuildMatrix_Error_Unknown calls report.BuildMatrix with unknown names, checking for an error.
+func TestBuildMatrix_Error_Unknown(t *testing.T) {
+	_, err := BuildMatrix(testPolicy(), MatrixFilter{Groups: []string{"cooks"}})
+	assert.ErrorContains(t, err, `unknown group "cooks"`)
+
+	_, err = BuildMatrix(testPolicy(), MatrixFilter{Permissions: []string{"recipes.cook"}})
+	assert.ErrorContains(t, err, `unknown permission "recipes.cook"`)
+}
+
+// TestMatrix_WriteCSV writes a matrix as CSV, checking the header and marks.
+func TestMatrix_WriteCSV(t *testing.T) {
+	matrix := &Matrix{Permissions: []string{"read", "write"}, Rows: []MatrixRow{{User: "alice", Granted: []bool{true, false}}}}
+
+	var out bytes.Buffer
+	assert.NoError(t, matrix.WriteCSV(&out))
+	assert.Equal(t, "user,read,write\nalice,x,\n", out.String())
+}
+
+// TestMatrix_WriteXLSX writes a matrix as a workbook, checking the parts and cells of the archive.
+func TestMatrix_WriteXLSX(t *testing.T) {
+	matrix := &Matrix{Permissions: []string{"read", "write"}, Rows: []MatrixRow{{User: "a&b", Granted: []bool{false, true}}}}
+
+	var out bytes.Buffer
+	assert.NoError(t, matrix.WriteXLSX(&out))
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	assert.NoError(t, err)
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		parts[file.Name] = string(content)
+	}
+
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, parts["xl/workbook.xml"], `name="Matrix"`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<c r="C1" t="inlineStr"><is><t>write</t></is></c>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<c r="A2" t="inlineStr"><is><t>a&amp;b</t></is></c>`)
+	assert.Contains(t, parts["xl/worksheets/sheet1.xml"], `<c r="C2" t="inlineStr"><is><t>x</t></is></c>`)
+}
+
+// TestXLSXColumn calls xlsxColumn with zero based indexes, checking the column letters.
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "AZ", xlsxColumn(51))
+	assert.Equal(t, "BA", xlsxColumn(52))
+}
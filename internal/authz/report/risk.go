@@ -0,0 +1,100 @@
+// Package report builds summaries of the authorization policy for reviews and audits.
+package report
+
+import (
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// RiskReport rolls the policy up by permission risk level.
+type RiskReport struct {
+	// The number of permissions defined at each risk level.
+	Permissions map[authz.RiskLevel]int `json:"permissions"`
+
+	// The number of group grants at each risk level.
+	Grants map[authz.RiskLevel]int `json:"grants"`
+
+	// The number of users holding at least one permission at each risk level.
+	Users map[authz.RiskLevel]int `json:"users"`
+
+	// The users holding high risk permissions, sorted by user.
+	HighRiskHolders []UserPermissions `json:"high_risk_holders"`
+}
+
+// UserPermissions lists permissions held by a single user.
+type UserPermissions struct {
+	User        string   `json:"user"`
+	Permissions []string `json:"permissions"`
+}
+
+// Risk builds a RiskReport for the given policy.
+//
+// Parameters:
+//
+//	policy - the policy to summarize.
+//
+// Returns:
+//
+//	*RiskReport - the risk roll-up of the policy.
+//	error - an error if evaluating a user fails.
+func Risk(policy *authz.Policy) (*RiskReport, error) {
+	report := &RiskReport{
+		Permissions:     make(map[authz.RiskLevel]int, len(authz.RiskLevels)),
+		Grants:          make(map[authz.RiskLevel]int, len(authz.RiskLevels)),
+		Users:           make(map[authz.RiskLevel]int, len(authz.RiskLevels)),
+		HighRiskHolders: []UserPermissions{},
+	}
+	for _, level := range authz.RiskLevels {
+		report.Permissions[level] = 0
+		report.Grants[level] = 0
+		report.Users[level] = 0
+	}
+
+	risks := make(map[string]authz.RiskLevel, len(policy.Permissions))
+	for _, permission := range policy.Permissions {
+		risk, err := authz.ParseRiskLevel(string(permission.Risk))
+		if err != nil {
+			return nil, err
+		}
+		risks[permission.Name] = risk
+		report.Permissions[risk]++
+		report.Grants[risk] += len(permission.Groups)
+	}
+
+	for _, user := range Users(policy) {
+		result, err := policy.Evaluate(user)
+		if err != nil {
+			return nil, err
+		}
+
+		levels := make(map[authz.RiskLevel]struct{}, len(authz.RiskLevels))
+		highRisk := []string{}
+		for _, permission := range result.Permissions {
+			levels[risks[permission]] = struct{}{}
+			if risks[permission] == authz.RiskHigh {
+				highRisk = append(highRisk, permission)
+			}
+		}
+
+		for level := range levels {
+			report.Users[level]++
+		}
+		if len(highRisk) > 0 {
+			slices.Sort(highRisk)
+			report.HighRiskHolders = append(report.HighRiskHolders, UserPermissions{User: user, Permissions: highRisk})
+		}
+	}
+
+	return report, nil
+}
+
+// Users returns every user that is a member of at least one group, sorted and without duplicates.
+func Users(policy *authz.Policy) []string {
+	users := []string{}
+	for _, group := range policy.Groups {
+		users = append(users, group.Users...)
+	}
+	slices.Sort(users)
+	return slices.Compact(users)
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{
+			{Name: "recipes.read", Groups: []string{"readers", "admins"}},
+			{Name: "recipes.publish", Groups: []string{"editors"}, Risk: authz.RiskMedium},
+			{Name: "recipes.delete", Groups: []string{"admins"}, Risk: authz.RiskHigh},
+		},
+		[]authz.Group{
+			*authz.NewGroup("readers", []string{"carol", "bob"}),
+			*authz.NewGroup("editors", []string{"bob"}),
+			*authz.NewGroup("admins", []string{"alice"}),
+		},
+	)
+}
+
+// TestRisk calls report.Risk with a policy, checking the roll-up per risk level.
+func TestRisk(t *testing.T) {
+	report, err := Risk(testPolicy())
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[authz.RiskLevel]int{authz.RiskLow: 1, authz.RiskMedium: 1, authz.RiskHigh: 1}, report.Permissions)
+	assert.Equal(t, map[authz.RiskLevel]int{authz.RiskLow: 2, authz.RiskMedium: 1, authz.RiskHigh: 1}, report.Grants)
+	assert.Equal(t, map[authz.RiskLevel]int{authz.RiskLow: 3, authz.RiskMedium: 1, authz.RiskHigh: 1}, report.Users)
+	assert.Equal(t, []UserPermissions{{User: "alice", Permissions: []string{"recipes.delete"}}}, report.HighRiskHolders)
+}
+
+// TestRisk_EmptyPolicy calls report.Risk with an empty policy, checking for zero counts.
+func TestRisk_EmptyPolicy(t *testing.T) {
+	report, err := Risk(authz.NewPolicy(nil, nil))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, report.Permissions[authz.RiskHigh])
+	assert.Empty(t, report.HighRiskHolders)
+}
+
+// TestRisk_Error_UnknownRisk calls report.Risk with an invalid risk level, checking for an error.
+func TestRisk_Error_UnknownRisk(t *testing.T) {
+	policy := authz.NewPolicy([]authz.Permission{{Name: "read", Risk: "critical"}}, nil)
+
+	report, err := Risk(policy)
+	assert.Error(t, err)
+	assert.Nil(t, report)
+}
+
+// TestUsers calls report.Users with a policy, checking for the sorted distinct users.
+func TestUsers(t *testing.T) {
+	assert.Equal(t, []string{"alice", "bob", "carol"}, Users(testPolicy()))
+}
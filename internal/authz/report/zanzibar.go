@@ -0,0 +1,132 @@
+package report
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// SpiceDBSchema is the SpiceDB schema of the relation tuples of a policy. Permissions are the
+// objects checked, since the policy grants them globally rather than on resources: a user holds
+// a permission when checking the allowed permission of the permission object succeeds.
+const SpiceDBSchema = `definition user {}
+
+definition group {
+	relation member: user
+}
+
+definition permission {
+	relation granted: group#member | permission#granted
+	permission allowed = granted
+}
+`
+
+// Tuple is a Zanzibar relation tuple, object#relation@subject, where the subject is an object
+// or, with a subject relation, the set of subjects holding that relation on the object.
+type Tuple struct {
+	ObjectType      string
+	ObjectID        string
+	Relation        string
+	SubjectType     string
+	SubjectID       string
+	SubjectRelation string
+}
+
+// String returns the tuple in the text form of Zanzibar and SpiceDB, such as group:cooks#member@user:alice.
+func (tuple Tuple) String() string {
+	subject := tuple.SubjectType + ":" + escapeObjectID(tuple.SubjectID)
+	if tuple.SubjectRelation != "" {
+		subject += "#" + tuple.SubjectRelation
+	}
+	return fmt.Sprintf("%s:%s#%s@%s", tuple.ObjectType, escapeObjectID(tuple.ObjectID), tuple.Relation, subject)
+}
+
+// RelationTuples returns the relation tuples of the policy, following SpiceDBSchema: the members
+// of every group, the groups granted every permission and, for every implied permission, the
+// permissions implying it. Tuples are sorted, so exports are reproducible. The evaluation mode
+// of the policy has no equivalent and is left out.
+func RelationTuples(policy *authz.Policy) []Tuple {
+	tuples := []Tuple{}
+	for _, group := range policy.Groups {
+		for _, user := range group.Users {
+			tuples = append(tuples, Tuple{ObjectType: "group", ObjectID: group.Name, Relation: "member", SubjectType: "user", SubjectID: user})
+		}
+	}
+	for _, permission := range policy.Permissions {
+		for _, group := range permission.Groups {
+			tuples = append(tuples, Tuple{
+				ObjectType: "permission", ObjectID: permission.Name, Relation: "granted",
+				SubjectType: "group", SubjectID: group, SubjectRelation: "member",
+			})
+		}
+		// whoever is granted the permission is granted the permissions it implies
+		for _, implied := range permission.Implies {
+			tuples = append(tuples, Tuple{
+				ObjectType: "permission", ObjectID: implied, Relation: "granted",
+				SubjectType: "permission", SubjectID: permission.Name, SubjectRelation: "granted",
+			})
+		}
+	}
+
+	slices.SortFunc(tuples, func(a, b Tuple) int {
+		return cmp.Or(
+			cmp.Compare(a.ObjectType, b.ObjectType), cmp.Compare(a.ObjectID, b.ObjectID), cmp.Compare(a.Relation, b.Relation),
+			cmp.Compare(a.SubjectType, b.SubjectType), cmp.Compare(a.SubjectID, b.SubjectID), cmp.Compare(a.SubjectRelation, b.SubjectRelation),
+		)
+	})
+	return slices.Compact(tuples)
+}
+
+// WriteTuples writes the relation tuples of the policy, one per line. With schema it writes
+// a SpiceDB validation file instead, holding SpiceDBSchema and the tuples, which zed import
+// and the SpiceDB playground load as they are.
+func WriteTuples(w io.Writer, policy *authz.Policy, schema bool) error {
+	out := bufio.NewWriter(w)
+	tuples := RelationTuples(policy)
+	if !schema {
+		for _, tuple := range tuples {
+			fmt.Fprintln(out, tuple)
+		}
+		return out.Flush()
+	}
+
+	fmt.Fprintln(out, "schema: |-")
+	for _, line := range strings.Split(strings.TrimSuffix(SpiceDBSchema, "\n"), "\n") {
+		writeIndented(out, line)
+	}
+	fmt.Fprintln(out, "relationships: |-")
+	for _, tuple := range tuples {
+		writeIndented(out, tuple.String())
+	}
+	return out.Flush()
+}
+
+// writeIndented writes a line of a YAML block scalar, leaving empty lines unindented.
+func writeIndented(out *bufio.Writer, line string) {
+	if line == "" {
+		fmt.Fprintln(out)
+		return
+	}
+	fmt.Fprintln(out, "  "+line)
+}
+
+// escapeObjectID escapes the characters SpiceDB refuses in object ids, such as the dots of
+// permission names and the at signs of user ids, as = followed by their hex code, so
+// recipes.read becomes recipes=2Eread. = is escaped as well, keeping the escaping reversible.
+func escapeObjectID(id string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(id) {
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', strings.IndexByte("/_|-+", b) >= 0:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "=%02X", b)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,40 @@
+package authz
+
+import (
+	"fmt"
+)
+
+// RiskLevel classifies how sensitive a permission is.
+// Stricter workflows apply when granting high risk permissions.
+type RiskLevel string
+
+const (
+	RiskLow    RiskLevel = "low"
+	RiskMedium RiskLevel = "medium"
+	RiskHigh   RiskLevel = "high"
+)
+
+// RiskLevels lists every risk level from the least to the most sensitive.
+var RiskLevels = []RiskLevel{RiskLow, RiskMedium, RiskHigh}
+
+// ParseRiskLevel converts a string to a RiskLevel.
+// An empty string is treated as RiskLow.
+//
+// Parameters:
+//
+//	value - the risk level name.
+//
+// Returns:
+//
+//	RiskLevel - the parsed risk level.
+//	error - an error if the value is not a known risk level.
+func ParseRiskLevel(value string) (RiskLevel, error) {
+	switch RiskLevel(value) {
+	case "", RiskLow:
+		return RiskLow, nil
+	case RiskMedium, RiskHigh:
+		return RiskLevel(value), nil
+	default:
+		return "", fmt.Errorf("unknown risk level %q", value)
+	}
+}
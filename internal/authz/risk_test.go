@@ -0,0 +1,34 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRiskLevel(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected RiskLevel
+	}{
+		{value: "", expected: RiskLow},
+		{value: "low", expected: RiskLow},
+		{value: "medium", expected: RiskMedium},
+		{value: "high", expected: RiskHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			risk, err := ParseRiskLevel(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, risk)
+		})
+	}
+}
+
+// TestParseRiskLevel_Error_Unknown calls ParseRiskLevel with an unknown value, checking for an error.
+func TestParseRiskLevel_Error_Unknown(t *testing.T) {
+	risk, err := ParseRiskLevel("critical")
+	assert.Error(t, err)
+	assert.Empty(t, risk)
+}
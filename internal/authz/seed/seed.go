@@ -0,0 +1,195 @@
+// Package seed generates realistic, reproducible policy data for demos, benchmarks and load
+// tests. The same options and seed always produce the same groups, permissions and users.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// Options is the shape of the generated policy.
+type Options struct {
+	Groups      int
+	Users       int
+	Permissions int
+	// How many groups every user belongs to. A few popular groups get most of the users.
+	GroupsPerUser int
+	// How many permissions every group is granted.
+	PermissionsPerGroup int
+	// The seed of the random choices.
+	Seed uint64
+	// Prepended to every name, so repeated runs against one store do not collide.
+	Prefix string
+}
+
+// DefaultOptions generates a mid-sized organization.
+var DefaultOptions = Options{Groups: 100, Users: 1000, Permissions: 50, GroupsPerUser: 3, PermissionsPerGroup: 5, Seed: 1}
+
+// Validate checks the options describe a policy that can be generated.
+func (options Options) Validate() error {
+	if options.Groups <= 0 || options.Users <= 0 || options.Permissions <= 0 {
+		return errors.New("groups, users and permissions must be positive")
+	}
+	if options.GroupsPerUser < 0 || options.GroupsPerUser > options.Groups {
+		return fmt.Errorf("groups per user must be between 0 and %d", options.Groups)
+	}
+	if options.PermissionsPerGroup < 0 || options.PermissionsPerGroup > options.Permissions {
+		return fmt.Errorf("permissions per group must be between 0 and %d", options.Permissions)
+	}
+	return nil
+}
+
+// Group is a generated group with the indexes of its permissions and the names of its users.
+type Group struct {
+	Name        string
+	Permissions []int
+	Users       []string
+}
+
+// Plan is a generated policy, ready to be loaded into a store.
+type Plan struct {
+	Permissions []string
+	Groups      []Group
+	Users       []string
+}
+
+// Generate creates the plan of a policy with the given options.
+func Generate(options Options) (*Plan, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	random := rand.New(rand.NewPCG(options.Seed, 0))
+	plan := &Plan{
+		Permissions: names(options.Prefix, options.Permissions, resources, actions, "."),
+		Users:       names(options.Prefix, options.Users, firstNames, lastNames, "."),
+	}
+	for _, name := range names(options.Prefix, options.Groups, teams, roles, "-") {
+		permissions := random.Perm(options.Permissions)[:options.PermissionsPerGroup]
+		slices.Sort(permissions)
+		plan.Groups = append(plan.Groups, Group{Name: name, Permissions: permissions, Users: []string{}})
+	}
+
+	// group popularity follows a Zipf distribution, so a few groups such as "everyone"
+	// are large while most are small, as in real organizations
+	popularity := rand.NewZipf(random, 1.2, 1, uint64(options.Groups-1))
+	for _, user := range plan.Users {
+		for _, index := range memberships(random, popularity, options.Groups, options.GroupsPerUser) {
+			plan.Groups[index].Users = append(plan.Groups[index].Users, user)
+		}
+	}
+
+	return plan, nil
+}
+
+// memberships picks count distinct groups, favoring the popular ones.
+func memberships(random *rand.Rand, popularity *rand.Zipf, groups int, count int) []int {
+	picked := make([]int, 0, count)
+	for attempts := 0; len(picked) < count && attempts < count*10; attempts++ {
+		if index := int(popularity.Uint64()); !slices.Contains(picked, index) {
+			picked = append(picked, index)
+		}
+	}
+	// the tail of the distribution fills the groups the popular ones could not
+	for _, index := range random.Perm(groups) {
+		if len(picked) == count {
+			break
+		}
+		if !slices.Contains(picked, index) {
+			picked = append(picked, index)
+		}
+	}
+	return picked
+}
+
+// names combines the words of both lists into count distinct names, numbering them
+// once every combination is used.
+func names(prefix string, count int, first []string, second []string, separator string) []string {
+	combinations := len(first) * len(second)
+	generated := make([]string, 0, count)
+	for i := range count {
+		name := first[i%len(first)] + separator + second[(i/len(first))%len(second)]
+		if round := i / combinations; round > 0 {
+			name = fmt.Sprintf("%s%d", name, round+1)
+		}
+		generated = append(generated, prefix+name)
+	}
+	return generated
+}
+
+// Policy returns the policy of the plan, for benchmarks and demos not backed by a store.
+func (plan *Plan) Policy() *authz.Policy {
+	granted := make([][]string, len(plan.Permissions))
+	groups := make([]authz.Group, 0, len(plan.Groups))
+	for _, group := range plan.Groups {
+		groups = append(groups, *authz.NewGroup(group.Name, group.Users))
+		for _, permission := range group.Permissions {
+			granted[permission] = append(granted[permission], group.Name)
+		}
+	}
+
+	permissions := make([]authz.Permission, 0, len(plan.Permissions))
+	for i, name := range plan.Permissions {
+		permissions = append(permissions, *authz.NewPermission(name, granted[i]))
+	}
+	return authz.NewPolicy(permissions, groups)
+}
+
+// PolicyManager is the part of a policy store the plan is loaded with.
+type PolicyManager interface {
+	CreateGroup(ctx context.Context, groupName string) (int, error)
+	CreatePermission(ctx context.Context, permissionName string) (int, error)
+	UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error
+	UpdateGroupUsers(ctx context.Context, groupId int, users []string) error
+}
+
+// Dataset is a plan loaded into a store, with the ids the store assigned.
+type Dataset struct {
+	Users       []string
+	Permissions []string
+	GroupIds    []int
+	// The users of every group, by group id.
+	Members map[int][]string
+}
+
+// Load creates the permissions and groups of the plan in the store and adds the users to them.
+func Load(ctx context.Context, manager PolicyManager, plan *Plan) (*Dataset, error) {
+	dataset := &Dataset{Users: plan.Users, Permissions: plan.Permissions, Members: map[int][]string{}}
+
+	permissionIds := make([]int, 0, len(plan.Permissions))
+	for _, name := range plan.Permissions {
+		id, err := manager.CreatePermission(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("create permission %s: %w", name, err)
+		}
+		permissionIds = append(permissionIds, id)
+	}
+
+	for _, group := range plan.Groups {
+		id, err := manager.CreateGroup(ctx, group.Name)
+		if err != nil {
+			return nil, fmt.Errorf("create group %s: %w", group.Name, err)
+		}
+
+		granted := make([]int, 0, len(group.Permissions))
+		for _, permission := range group.Permissions {
+			granted = append(granted, permissionIds[permission])
+		}
+		if err := manager.UpdateGroupPermissions(ctx, id, granted); err != nil {
+			return nil, fmt.Errorf("grant permissions to group %s: %w", group.Name, err)
+		}
+		if err := manager.UpdateGroupUsers(ctx, id, group.Users); err != nil {
+			return nil, fmt.Errorf("add users to group %s: %w", group.Name, err)
+		}
+
+		dataset.GroupIds = append(dataset.GroupIds, id)
+		dataset.Members[id] = group.Users
+	}
+
+	return dataset, nil
+}
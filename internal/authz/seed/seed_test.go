@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestGenerate(t *testing.T) {
+	options := Options{Groups: 60, Users: 500, Permissions: 80, GroupsPerUser: 3, PermissionsPerGroup: 4, Seed: 7, Prefix: "demo-"}
+
+	plan, err := Generate(options)
+	assert.NoError(t, err)
+	assert.Len(t, plan.Permissions, 80)
+	assert.Len(t, plan.Groups, 60)
+	assert.Len(t, plan.Users, 500)
+	assert.Equal(t, "demo-recipes.read", plan.Permissions[0])
+	assert.Equal(t, "demo-kitchen-viewers", plan.Groups[0].Name)
+	assert.Equal(t, "demo-alice.smith", plan.Users[0])
+
+	// names stay distinct once every combination of words is used
+	assert.Equal(t, "demo-recipes.read2", plan.Permissions[72])
+	seen := map[string]bool{}
+	for _, name := range plan.Users {
+		assert.False(t, seen[name], name)
+		seen[name] = true
+	}
+
+	memberships := map[string]int{}
+	for _, group := range plan.Groups {
+		assert.Len(t, group.Permissions, 4)
+		for _, user := range group.Users {
+			memberships[user]++
+		}
+	}
+	assert.Len(t, memberships, 500)
+	for user, count := range memberships {
+		assert.Equal(t, 3, count, user)
+	}
+
+	again, err := Generate(options)
+	assert.NoError(t, err)
+	assert.Equal(t, plan, again)
+}
+
+func TestGenerate_InvalidOptions(t *testing.T) {
+	_, err := Generate(Options{Groups: 2, Users: 1, Permissions: 1, PermissionsPerGroup: 3})
+	assert.ErrorContains(t, err, "permissions per group")
+}
+
+func TestPlan_Policy(t *testing.T) {
+	plan := &Plan{
+		Permissions: []string{"recipes.read", "recipes.write"},
+		Groups:      []Group{{Name: "cooks", Permissions: []int{0, 1}, Users: []string{"alice"}}},
+		Users:       []string{"alice"},
+	}
+
+	result, err := plan.Policy().Evaluate("alice")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.read", "recipes.write"}, result.Permissions)
+}
+
+func TestLoad(t *testing.T) {
+	plan := &Plan{
+		Permissions: []string{"recipes.read", "recipes.write"},
+		Groups:      []Group{{Name: "cooks", Permissions: []int{1}, Users: []string{"alice", "bob"}}},
+		Users:       []string{"alice", "bob"},
+	}
+	manager := new(MockPolicyManager)
+	manager.On("CreatePermission", mock.Anything, "recipes.read").Return(1, nil)
+	manager.On("CreatePermission", mock.Anything, "recipes.write").Return(2, nil)
+	manager.On("CreateGroup", mock.Anything, "cooks").Return(10, nil)
+	manager.On("UpdateGroupPermissions", mock.Anything, 10, []int{2}).Return(nil)
+	manager.On("UpdateGroupUsers", mock.Anything, 10, []string{"alice", "bob"}).Return(nil)
+
+	dataset, err := Load(context.Background(), manager, plan)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10}, dataset.GroupIds)
+	assert.Equal(t, map[int][]string{10: {"alice", "bob"}}, dataset.Members)
+
+	manager.AssertExpectations(t)
+}
+
+func TestLoad_Error(t *testing.T) {
+	plan := &Plan{Permissions: []string{"recipes.read"}}
+	manager := new(MockPolicyManager)
+	manager.On("CreatePermission", mock.Anything, "recipes.read").Return(0, errors.New("connection refused"))
+
+	_, err := Load(context.Background(), manager, plan)
+	assert.ErrorContains(t, err, "create permission recipes.read")
+}
+
+func BenchmarkPolicy_Evaluate(b *testing.B) {
+	plan, err := Generate(DefaultOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+	policy := plan.Policy()
+
+	b.ResetTimer()
+	for i := range b.N {
+		if _, err := policy.Evaluate(plan.Users[i%len(plan.Users)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package seed
+
+// The words realistic names are made of.
+var (
+	resources = []string{"recipes", "menus", "orders", "inventory", "suppliers", "kitchens", "reviews",
+		"billing", "reports", "deliveries", "staff", "promotions"}
+	actions = []string{"read", "write", "delete", "publish", "approve", "export"}
+
+	teams = []string{"kitchen", "catering", "finance", "marketing", "support", "engineering", "logistics",
+		"purchasing", "quality", "sales"}
+	roles = []string{"viewers", "editors", "admins", "leads", "auditors"}
+
+	firstNames = []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi", "ivan", "judy",
+		"mallory", "niaj", "olivia", "peggy", "rupert", "sybil", "trent", "victor", "walter", "yasmin"}
+	lastNames = []string{"smith", "jones", "garcia", "chen", "kumar", "novak", "silva", "okafor", "tanaka",
+		"muller", "rossi", "haddad", "larsen", "kowalski", "nguyen"}
+)
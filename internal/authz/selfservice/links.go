@@ -0,0 +1,125 @@
+package selfservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrLinkNotFound is returned when no request link has the given id.
+	ErrLinkNotFound = errors.New("request link not found")
+	// ErrLinkExpired is returned when a request link is used after it expired.
+	ErrLinkExpired = errors.New("request link expired")
+	// ErrLinkRevoked is returned when a request link is used after it was revoked.
+	ErrLinkRevoked = errors.New("request link revoked")
+	// ErrInvalidToken is returned when a request link token is malformed or not signed by the Signer.
+	ErrInvalidToken = errors.New("invalid request link token")
+)
+
+// Link is a shareable link asking its recipients to request access to a self-service group.
+// Opening the link pre-fills the access request, which still goes through the approval workflow.
+type Link struct {
+	ID      string `json:"id"`
+	GroupID int    `json:"group_id"`
+	// The justification pre-filled in the requests, which the requesters may change.
+	Justification string    `json:"justification,omitempty"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	RevokedBy     string    `json:"revoked_by,omitempty"`
+	RevokedAt     time.Time `json:"revoked_at,omitzero"`
+}
+
+// Check returns ErrLinkRevoked or ErrLinkExpired when the link cannot be used at the given time.
+func (link *Link) Check(now time.Time) error {
+	if !link.RevokedAt.IsZero() {
+		return ErrLinkRevoked
+	}
+	if !now.Before(link.ExpiresAt) {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// LinkStore persists the request links, so they can be listed and revoked before they expire.
+type LinkStore interface {
+	// CreateLink records a new request link.
+	CreateLink(ctx context.Context, link Link) error
+	// GetLink returns the request link with the given id.
+	GetLink(ctx context.Context, id string) (*Link, error)
+	// ListLinks returns the request links to the group, most recent first.
+	ListLinks(ctx context.Context, groupId int) ([]Link, error)
+	// RevokeLink revokes the request link. Revoking a revoked link keeps the first revocation.
+	RevokeLink(ctx context.Context, id string, revokedBy string, revokedAt time.Time) error
+}
+
+// MinSignerKeySize is the minimum size in bytes of the key signing request link tokens.
+const MinSignerKeySize = 32
+
+// Signer signs the tokens of the request links with HMAC-SHA256, so tokens cannot be forged
+// and their expiry is checked before the link is looked up.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a new Signer with the given key of at least MinSignerKeySize bytes.
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) < MinSignerKeySize {
+		return nil, fmt.Errorf("request link key must hold at least %d bytes", MinSignerKeySize)
+	}
+	return &Signer{key: key}, nil
+}
+
+// Sign returns the token of the link, holding its id, group and expiry. Tokens are URL safe.
+func (signer *Signer) Sign(link Link) string {
+	payload := link.ID + "." + strconv.Itoa(link.GroupID) + "." + strconv.FormatInt(link.ExpiresAt.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signer.mac(encoded))
+}
+
+// Verify checks the signature and the expiry of the token and returns the id and the group of its link.
+// The link itself must still be looked up, since it may have been revoked.
+func (signer *Signer) Verify(token string, now time.Time) (string, int, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", 0, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signer.mac(encoded)) {
+		return "", 0, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", 0, ErrInvalidToken
+	}
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 3 {
+		return "", 0, ErrInvalidToken
+	}
+	groupId, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidToken
+	}
+	if now.Unix() >= expires {
+		return "", 0, ErrLinkExpired
+	}
+	return fields[0], groupId, nil
+}
+
+func (signer *Signer) mac(encoded string) []byte {
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,61 @@
+package selfservice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte(strings.Repeat("k", MinSignerKeySize))
+
+func TestSigner(t *testing.T) {
+	signer, err := NewSigner(testKey)
+	assert.NoError(t, err)
+	link := Link{ID: "0193a3f2-6d1c-7c4e-9a57-3f0e1b2c4d5e", GroupID: 10, ExpiresAt: enabled.Add(time.Hour)}
+	token := signer.Sign(link)
+
+	t.Run("valid", func(t *testing.T) {
+		id, groupId, err := signer.Verify(token, enabled)
+		assert.NoError(t, err)
+		assert.Equal(t, link.ID, id)
+		assert.Equal(t, 10, groupId)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, _, err := signer.Verify(token, link.ExpiresAt)
+		assert.ErrorIs(t, err, ErrLinkExpired)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		other := signer.Sign(Link{ID: link.ID, GroupID: 11, ExpiresAt: link.ExpiresAt})
+		payload, _, _ := strings.Cut(other, ".")
+		_, signature, _ := strings.Cut(token, ".")
+
+		for _, tampered := range []string{payload + "." + signature, token + "x", "", "not-a-token", "." + signature} {
+			_, _, err := signer.Verify(tampered, enabled)
+			assert.ErrorIs(t, err, ErrInvalidToken, tampered)
+		}
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other, _ := NewSigner([]byte(strings.Repeat("o", MinSignerKeySize)))
+		_, _, err := other.Verify(token, enabled)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("short key", func(t *testing.T) {
+		_, err := NewSigner(testKey[1:])
+		assert.Error(t, err)
+	})
+}
+
+func TestLink_Check(t *testing.T) {
+	link := Link{ExpiresAt: enabled.Add(time.Hour)}
+	assert.NoError(t, link.Check(enabled))
+	assert.ErrorIs(t, link.Check(enabled.Add(time.Hour)), ErrLinkExpired)
+
+	link.RevokedAt = enabled
+	assert.ErrorIs(t, link.Check(enabled), ErrLinkRevoked)
+}
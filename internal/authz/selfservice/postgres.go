@@ -0,0 +1,178 @@
+package selfservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var (
+	_ Store     = (*PostgresStore)(nil)
+	_ LinkStore = (*PostgresStore)(nil)
+)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Enable inserts the self-service settings of the group or replaces the existing ones.
+// A GroupNotFound error is returned when the group does not exist.
+func (selfService *PostgresStore) Enable(ctx context.Context, group Group) error {
+	_, err := selfService.db.Exec(ctx, `
+	INSERT INTO self_service_groups (group_id, description, enabled_by, enabled_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (group_id) DO UPDATE
+	SET description = EXCLUDED.description, enabled_by = EXCLUDED.enabled_by, enabled_at = EXCLUDED.enabled_at
+	`, group.GroupID, group.Description, group.EnabledBy, group.EnabledAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return store.NewGroupNotFoundError()
+	}
+	return err
+}
+
+// Disable deletes the self-service settings of the group.
+func (selfService *PostgresStore) Disable(ctx context.Context, groupId int) error {
+	tag, err := selfService.db.Exec(ctx, "DELETE FROM self_service_groups WHERE group_id = $1", groupId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Get returns the self-service settings of the group.
+func (selfService *PostgresStore) Get(ctx context.Context, groupId int) (*Group, error) {
+	group := Group{GroupID: groupId}
+	err := selfService.db.QueryRow(ctx, `
+	SELECT description, enabled_by, enabled_at FROM self_service_groups WHERE group_id = $1
+	`, groupId).Scan(&group.Description, &group.EnabledBy, &group.EnabledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List returns every group offered for self-service ordered by group id.
+func (selfService *PostgresStore) List(ctx context.Context) ([]Group, error) {
+	rows, err := selfService.db.Query(ctx, `
+	SELECT group_id, description, enabled_by, enabled_at FROM self_service_groups ORDER BY group_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []Group{}
+	for rows.Next() {
+		var group Group
+		if err := rows.Scan(&group.GroupID, &group.Description, &group.EnabledBy, &group.EnabledAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return groups, nil
+}
+
+// CreateLink inserts the request link. A GroupNotFound error is returned when the group does not exist.
+func (selfService *PostgresStore) CreateLink(ctx context.Context, link Link) error {
+	_, err := selfService.db.Exec(ctx, `
+	INSERT INTO self_service_links (id, group_id, justification, created_by, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`, link.ID, link.GroupID, link.Justification, link.CreatedBy, link.CreatedAt, link.ExpiresAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return store.NewGroupNotFoundError()
+	}
+	return err
+}
+
+const selectLinks = `
+SELECT id, group_id, justification, created_by, created_at, expires_at, revoked_by, revoked_at FROM self_service_links
+`
+
+// GetLink returns the request link with the given id.
+func (selfService *PostgresStore) GetLink(ctx context.Context, id string) (*Link, error) {
+	link, err := scanLink(selfService.db.QueryRow(ctx, selectLinks+"WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ListLinks returns the request links to the group, most recent first.
+func (selfService *PostgresStore) ListLinks(ctx context.Context, groupId int) ([]Link, error) {
+	rows, err := selfService.db.Query(ctx, selectLinks+"WHERE group_id = $1 ORDER BY created_at DESC, id DESC", groupId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return links, nil
+}
+
+// RevokeLink records the revocation of the request link, keeping the first one.
+func (selfService *PostgresStore) RevokeLink(ctx context.Context, id string, revokedBy string, revokedAt time.Time) error {
+	tag, err := selfService.db.Exec(ctx, `
+	UPDATE self_service_links SET revoked_by = COALESCE(revoked_by, $2), revoked_at = COALESCE(revoked_at, $3) WHERE id = $1
+	`, id, revokedBy, revokedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+func scanLink(row pgx.Row) (*Link, error) {
+	var link Link
+	var revokedBy pgtype.Text
+	var revokedAt pgtype.Timestamptz
+	err := row.Scan(&link.ID, &link.GroupID, &link.Justification, &link.CreatedBy, &link.CreatedAt, &link.ExpiresAt, &revokedBy, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	link.RevokedBy = revokedBy.String
+	link.RevokedAt = revokedAt.Time
+	return &link, nil
+}
@@ -0,0 +1,217 @@
+package selfservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var enabled = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPostgresStore_Enable(t *testing.T) {
+	ctx := context.Background()
+	group := Group{GroupID: 2, Description: "Recipe editors", EnabledBy: "admin", EnabledAt: enabled}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{2, "Recipe editors", "admin", enabled}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		err := NewPostgresStore(mockDb).Enable(ctx, group)
+		assert.NoError(t, err)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		err := NewPostgresStore(mockDb).Enable(ctx, group)
+		assert.Equal(t, store.NewGroupNotFoundError(), err)
+	})
+}
+
+func TestPostgresStore_Disable(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, "DELETE FROM self_service_groups WHERE group_id = $1", []any{2}).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).Disable(ctx, 2))
+	})
+
+	t.Run("not offered", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{2}).Return(pgconn.NewCommandTag("DELETE 0"), nil)
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).Disable(ctx, 2), ErrNotFound)
+	})
+}
+
+func TestPostgresStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{2}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*string)) = "Recipe editors"
+			*(dest[1].(*string)) = "admin"
+			*(dest[2].(*time.Time)) = enabled
+		}).Return(nil)
+
+		group, err := NewPostgresStore(mockDb).Get(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, &Group{GroupID: 2, Description: "Recipe editors", EnabledBy: "admin", EnabledAt: enabled}, group)
+	})
+
+	t.Run("not offered", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{2}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		group, err := NewPostgresStore(mockDb).Get(ctx, 2)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, group)
+	})
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any(nil)).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*int)) = 2
+		*(dest[1].(*string)) = ""
+		*(dest[2].(*string)) = "admin"
+		*(dest[3].(*time.Time)) = enabled
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	groups, err := NewPostgresStore(mockDb).List(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Group{{GroupID: 2, EnabledBy: "admin", EnabledAt: enabled}}, groups)
+
+	mockDb.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
+
+func TestPostgresStore_CreateLink(t *testing.T) {
+	ctx := context.Background()
+	link := Link{ID: "link-1", GroupID: 2, Justification: "new cooks", CreatedBy: "admin", CreatedAt: enabled, ExpiresAt: enabled.Add(time.Hour)}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{"link-1", 2, "new cooks", "admin", enabled, enabled.Add(time.Hour)}).
+			Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).CreateLink(ctx, link))
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+
+		assert.Equal(t, store.NewGroupNotFoundError(), NewPostgresStore(mockDb).CreateLink(ctx, link))
+	})
+}
+
+func TestPostgresStore_GetLink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("revoked", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"link-1"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*string)) = "link-1"
+			*(dest[1].(*int)) = 2
+			*(dest[3].(*string)) = "admin"
+			*(dest[4].(*time.Time)) = enabled
+			*(dest[5].(*time.Time)) = enabled.Add(time.Hour)
+			*(dest[6].(*pgtype.Text)) = pgtype.Text{String: "owner", Valid: true}
+			*(dest[7].(*pgtype.Timestamptz)) = pgtype.Timestamptz{Time: enabled.Add(time.Minute), Valid: true}
+		}).Return(nil)
+
+		link, err := NewPostgresStore(mockDb).GetLink(ctx, "link-1")
+		assert.NoError(t, err)
+		assert.Equal(t, &Link{ID: "link-1", GroupID: 2, CreatedBy: "admin", CreatedAt: enabled, ExpiresAt: enabled.Add(time.Hour),
+			RevokedBy: "owner", RevokedAt: enabled.Add(time.Minute)}, link)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{"link-1"}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		link, err := NewPostgresStore(mockDb).GetLink(ctx, "link-1")
+		assert.ErrorIs(t, err, ErrLinkNotFound)
+		assert.Nil(t, link)
+	})
+}
+
+func TestPostgresStore_ListLinks(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, mock.Anything, []any{2}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args[0].([]any)
+		*(dest[0].(*string)) = "link-1"
+		*(dest[1].(*int)) = 2
+		*(dest[5].(*time.Time)) = enabled
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	links, err := NewPostgresStore(mockDb).ListLinks(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{{ID: "link-1", GroupID: 2, ExpiresAt: enabled}}, links)
+
+	mockDb.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
+
+func TestPostgresStore_RevokeLink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{"link-1", "admin", enabled}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+
+		assert.NoError(t, NewPostgresStore(mockDb).RevokeLink(ctx, "link-1", "admin", enabled))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+		assert.ErrorIs(t, NewPostgresStore(mockDb).RevokeLink(ctx, "link-1", "admin", enabled), ErrLinkNotFound)
+	})
+}
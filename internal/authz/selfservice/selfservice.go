@@ -0,0 +1,33 @@
+// Package selfservice records which groups end users may request to join themselves.
+// Requests go through the approval workflow, and approved memberships are applied automatically.
+package selfservice
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the group is not offered for self-service.
+var ErrNotFound = errors.New("group is not offered for self-service")
+
+// Group is a group end users may request to join.
+type Group struct {
+	GroupID int `json:"group_id"`
+	// What membership is for, shown to the users browsing the groups.
+	Description string    `json:"description,omitempty"`
+	EnabledBy   string    `json:"enabled_by"`
+	EnabledAt   time.Time `json:"enabled_at"`
+}
+
+// Store persists the groups offered for self-service.
+type Store interface {
+	// Enable offers the group for self-service, or updates its description when it already is.
+	Enable(ctx context.Context, group Group) error
+	// Disable stops offering the group. Pending requests are left for the approvers to decide.
+	Disable(ctx context.Context, groupId int) error
+	// Get returns the self-service settings of the group.
+	Get(ctx context.Context, groupId int) (*Group, error)
+	// List returns every group offered for self-service ordered by group id.
+	List(ctx context.Context) ([]Group, error)
+}
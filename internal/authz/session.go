@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// Session answers the group and permission checks of a single user from one evaluation,
+// so a request checking many permissions evaluates the policy only once.
+// It reflects the policy at the time it was created and is safe for concurrent use.
+type Session struct {
+	user        string
+	groups      []string
+	permissions []string
+	groupSet    shared.Set[string]
+	permSet     shared.Set[string]
+	// The wildcard permissions held, matched by HasPermission when the set does not hold the name.
+	wildcards []string
+	// The permissions explicitly denied, overriding the held ones.
+	denied []string
+}
+
+// NewSession creates a new Session of the user from the result of evaluating them.
+func NewSession(user string, result *PolicyEvaluationResult) *Session {
+	session := &Session{
+		user:        user,
+		groups:      slices.Clone(result.Groups),
+		permissions: slices.Clone(result.Permissions),
+		groupSet:    shared.NewSet(result.Groups...),
+		permSet:     shared.NewSet(result.Permissions...),
+		denied:      slices.Clone(result.Denied),
+	}
+	for _, permission := range result.Permissions {
+		if IsWildcard(permission) {
+			session.wildcards = append(session.wildcards, permission)
+		}
+	}
+	return session
+}
+
+// Session evaluates the user once and returns a Session answering their checks.
+//
+// Parameters:
+//
+//	user - the username to be evaluated against the policy.
+//
+// Returns:
+//
+//	*Session - the session of the user.
+//	error - an error if the evaluation process encounters an issue.
+func (policy *Policy) Session(user string) (*Session, error) {
+	result, err := policy.Evaluate(user)
+	if err != nil {
+		return nil, err
+	}
+	return NewSession(user, result), nil
+}
+
+// Session returns a Session answering the checks of the user.
+func (compiled *CompiledPolicy) Session(user string) (*Session, error) {
+	result, err := compiled.Evaluate(user)
+	if err != nil {
+		return nil, err
+	}
+	return NewSession(user, result), nil
+}
+
+// User returns the user of the session.
+func (session *Session) User() string {
+	return session.user
+}
+
+// HasPermission reports whether the user holds the permission, directly, through an implication or a wildcard,
+// and is not explicitly denied it. The evaluation mode is ignored, like Policy.HasPermission.
+func (session *Session) HasPermission(permission string) bool {
+	if _, denied := DenyingPermission(session.denied, permission); denied {
+		return false
+	}
+	if session.permSet.Contains(permission) {
+		return true
+	}
+	_, granted := GrantingPermission(session.wildcards, permission)
+	return granted
+}
+
+// HasAnyPermission reports whether the user holds at least one of the permissions.
+func (session *Session) HasAnyPermission(permissions ...string) bool {
+	return slices.ContainsFunc(permissions, session.HasPermission)
+}
+
+// HasAllPermissions reports whether the user holds every one of the permissions.
+func (session *Session) HasAllPermissions(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !session.HasPermission(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsInGroup reports whether the user is a member of the group.
+func (session *Session) IsInGroup(group string) bool {
+	return session.groupSet.Contains(group)
+}
+
+// Result returns a copy of the evaluation the session answers from.
+func (session *Session) Result() *PolicyEvaluationResult {
+	result := NewPolicyEvaluationResult(slices.Clone(session.groups), slices.Clone(session.permissions))
+	result.Denied = slices.Clone(session.denied)
+	return result
+}
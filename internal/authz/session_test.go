@@ -0,0 +1,77 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sessionPolicy() *Policy {
+	return NewPolicy(
+		[]Permission{
+			{Name: "read", Groups: []string{"readers"}},
+			{Name: "write", Groups: []string{"writers"}, Implies: []string{"read"}},
+			{Name: "delete", Groups: []string{"admins"}},
+		},
+		[]Group{*NewGroup("readers", []string{"alice"}), *NewGroup("writers", []string{"bob"}), *NewGroup("admins", []string{"carol"})},
+	)
+}
+
+// TestPolicy_Session creates a session of a user, checking it answers group and permission checks.
+func TestPolicy_Session(t *testing.T) {
+	session, err := sessionPolicy().Session("bob")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "bob", session.User())
+	assert.True(t, session.IsInGroup("writers"))
+	assert.False(t, session.IsInGroup("readers"))
+	assert.True(t, session.HasPermission("write"))
+	assert.True(t, session.HasPermission("read"))
+	assert.False(t, session.HasPermission("delete"))
+	assert.True(t, session.HasAnyPermission("delete", "read"))
+	assert.False(t, session.HasAnyPermission())
+	assert.True(t, session.HasAllPermissions("read", "write"))
+	assert.False(t, session.HasAllPermissions("read", "delete"))
+	assert.ElementsMatch(t, []string{"write", "read"}, session.Result().Permissions)
+}
+
+// TestPolicy_Session_EmptyUser creates a session of an empty user, checking for an error.
+func TestPolicy_Session_EmptyUser(t *testing.T) {
+	session, err := sessionPolicy().Session("")
+	assert.Nil(t, session)
+	assert.EqualError(t, err, "user is empty")
+}
+
+// TestCompiledPolicy_Session creates a session from a compiled policy, checking it matches the policy.
+func TestCompiledPolicy_Session(t *testing.T) {
+	compiled, err := Compile(sessionPolicy())
+	assert.NoError(t, err)
+
+	session, err := compiled.Session("alice")
+	assert.NoError(t, err)
+	assert.True(t, session.HasPermission("read"))
+	assert.False(t, session.HasPermission("write"))
+}
+
+// BenchmarkSession_HasPermission checks many permissions of a user, comparing with evaluating the policy every time.
+func BenchmarkSession_HasPermission(b *testing.B) {
+	policy := sessionPolicy()
+	checks := []string{"read", "write", "delete", "read", "write", "delete"}
+
+	b.Run("policy", func(b *testing.B) {
+		for range b.N {
+			for range checks {
+				_, _ = policy.Evaluate("bob")
+			}
+		}
+	})
+
+	b.Run("session", func(b *testing.B) {
+		for range b.N {
+			session, _ := policy.Session("bob")
+			for _, check := range checks {
+				session.HasPermission(check)
+			}
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package standby
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// PolicyReader reads the current policy from the policy store.
+type PolicyReader interface {
+	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+}
+
+// Exporter periodically writes the policy read from the store to a standby file.
+type Exporter struct {
+	source   PolicyReader
+	path     string
+	interval time.Duration
+	logger   *slog.Logger
+	clock    clock.Clock
+}
+
+// NewExporter creates a new Exporter writing the policy read from source to the standby file
+// at the given path every interval.
+func NewExporter(source PolicyReader, path string, interval time.Duration, logger *slog.Logger) *Exporter {
+	return &Exporter{source: source, path: path, interval: interval, logger: logger, clock: clock.System()}
+}
+
+// Export reads the policy and replaces the standby file with it.
+func (exporter *Exporter) Export(ctx context.Context) error {
+	policy, err := exporter.source.ReadPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	return Write(exporter.path, policy, exporter.clock.Now())
+}
+
+// Run exports the policy immediately and then every interval until the context is done.
+// Failed exports are logged and leave the previous standby file in place.
+func (exporter *Exporter) Run(ctx context.Context) {
+	ticker := exporter.clock.NewTicker(exporter.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := exporter.Export(ctx); err != nil {
+			exporter.logger.Error("failed to write standby file", "path", exporter.path, "error", err)
+		} else {
+			exporter.logger.Debug("standby file written", "path", exporter.path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package standby
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// PolicyManager is the policy store interface served by the Manager.
+type PolicyManager = store.PolicyManager[int, int, string]
+
+// Manager is a read-only PolicyManager serving the policy loaded from a standby file.
+// Reading the policy succeeds, while every change and every listing needing database ids
+// fails with a ReadOnly PolicyStoreError.
+type Manager struct {
+	policy *authz.Policy
+}
+
+var _ PolicyManager = (*Manager)(nil)
+
+// NewManager creates a new Manager serving the given policy.
+func NewManager(policy *authz.Policy) *Manager {
+	return &Manager{policy: policy}
+}
+
+// ReadPolicy returns the standby policy.
+func (manager *Manager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return manager.policy, nil
+}
+
+// Health reports the store as degraded.
+func (manager *Manager) Health(ctx context.Context) (*store.Health, error) {
+	return &store.Health{Degraded: true}, nil
+}
+
+// Export returns the document of the standby policy, which has no metadata and no membership sources.
+func (manager *Manager) Export(ctx context.Context) (*store.PolicyDocument, error) {
+	return store.DocumentFromPolicy(manager.policy), nil
+}
+
+func (manager *Manager) Import(ctx context.Context, document *store.PolicyDocument) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) UpdateUserGroups(ctx context.Context, userId string, groups []int) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) CreateGroup(ctx context.Context, groupName string) (int, error) {
+	return 0, store.NewReadOnlyError()
+}
+
+func (manager *Manager) CreatePermission(ctx context.Context, permissionName string) (int, error) {
+	return 0, store.NewReadOnlyError()
+}
+
+func (manager *Manager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) SetPermissionDenials(ctx context.Context, permissionId int, groups []int, users []string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) SetPermissionCondition(ctx context.Context, permissionId int, condition string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) DeleteGroup(ctx context.Context, groupId int) (*store.GroupDeletion, error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) UpdateGroupMetadata(ctx context.Context, groupId int, patch store.MetadataPatch) (*store.GroupInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) UpdatePermissionMetadata(ctx context.Context, permissionId int, patch store.MetadataPatch) (*store.PermissionInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) DeleteUser(ctx context.Context, userId string) error {
+	return store.NewReadOnlyError()
+}
+
+func (manager *Manager) ListGroups(ctx context.Context) ([]store.GroupInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) ListPermissions(ctx context.Context) ([]store.PermissionInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) GetGroups(ctx context.Context, ids []int) ([]store.GroupInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) GetPermissions(ctx context.Context, ids []int) ([]store.PermissionInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) ListGroupsPage(ctx context.Context, page paging.Request) ([]store.GroupInfo[int], string, error) {
+	return nil, "", store.NewReadOnlyError()
+}
+
+func (manager *Manager) ListPermissionsPage(ctx context.Context, page paging.Request) ([]store.PermissionInfo[int], string, error) {
+	return nil, "", store.NewReadOnlyError()
+}
+
+func (manager *Manager) GetGroup(ctx context.Context, groupId int) (*store.GroupDetails[int, int, string], error) {
+	return nil, store.NewReadOnlyError()
+}
+
+func (manager *Manager) GetUserGroups(ctx context.Context, userId string) ([]store.GroupInfo[int], error) {
+	return nil, store.NewReadOnlyError()
+}
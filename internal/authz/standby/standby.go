@@ -0,0 +1,122 @@
+// Package standby keeps a checksummed copy of the policy on local disk, so the service
+// can keep evaluating users in a degraded read-only mode when the database is unavailable.
+package standby
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/policyfile"
+)
+
+// ErrChecksumMismatch is returned when a standby file does not match its recorded checksum.
+var ErrChecksumMismatch = errors.New("standby file checksum mismatch")
+
+// Snapshot is the document stored in a standby file.
+type Snapshot struct {
+	// The SHA-256 checksum of the policy document, hex encoded.
+	Checksum string `json:"checksum"`
+	// The time the snapshot was written.
+	WrittenAt time.Time `json:"written_at"`
+	// The canonical policy document.
+	Policy json.RawMessage `json:"policy"`
+}
+
+// Write stores the policy in a standby file at the given path.
+// The file is written to a temporary file in the same directory and renamed into place,
+// so readers never observe a partially written file.
+//
+// Parameters:
+//
+//	path - the standby file to replace.
+//	policy - the policy to store.
+//	writtenAt - the time recorded in the snapshot.
+//
+// Returns:
+//
+//	error - an error if the snapshot cannot be encoded or written.
+func Write(path string, policy *authz.Policy, writtenAt time.Time) error {
+	indented, err := policyfile.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	// the snapshot embeds the document compacted, so the checksum covers the compact form
+	var document bytes.Buffer
+	if err := json.Compact(&document, indented); err != nil {
+		return fmt.Errorf("encode standby file: %w", err)
+	}
+
+	checksum := sha256.Sum256(document.Bytes())
+	data, err := json.Marshal(Snapshot{
+		Checksum:  hex.EncodeToString(checksum[:]),
+		WrittenAt: writtenAt.UTC(),
+		Policy:    document.Bytes(),
+	})
+	if err != nil {
+		return fmt.Errorf("encode standby file: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	// removing the temporary file fails harmlessly once it has been renamed
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// Load reads the standby file at the given path and verifies its checksum.
+//
+// Returns:
+//
+//	*authz.Policy - the stored policy.
+//	time.Time - the time the snapshot was written.
+//	error - an error if the file cannot be read, is malformed or fails the checksum.
+func Load(path string) (*authz.Policy, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, time.Time{}, fmt.Errorf("decode standby file: %w", err)
+	}
+
+	checksum := sha256.Sum256(snapshot.Policy)
+	if hex.EncodeToString(checksum[:]) != snapshot.Checksum {
+		return nil, time.Time{}, ErrChecksumMismatch
+	}
+
+	policy, err := policyfile.Read(bytes.NewReader(snapshot.Policy))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return policy, snapshot.WrittenAt, nil
+}
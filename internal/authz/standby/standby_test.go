@@ -0,0 +1,138 @@
+package standby
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *authz.Policy {
+	return authz.NewPolicy(
+		[]authz.Permission{{Name: "write", Groups: []string{"admin"}, Risk: authz.RiskHigh}},
+		[]authz.Group{{Name: "admin", Users: []string{"adminuser"}}},
+	)
+}
+
+type policyReaderFunc func(ctx context.Context) (*authz.Policy, error)
+
+func (f policyReaderFunc) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	return f(ctx)
+}
+
+// TestWriteLoad writes a standby file and loads it back, checking for the same policy.
+func TestWriteLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "standby.json")
+	writtenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, Write(path, testPolicy(), writtenAt))
+
+	policy, loadedAt, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, writtenAt, loadedAt)
+	result, err := policy.Evaluate("adminuser")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"write"}, result.Permissions)
+	assert.Equal(t, authz.RiskHigh, policy.Permissions[0].Risk)
+
+	// the temporary file is renamed into place
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestWrite_Replace writes a standby file over an existing one, checking the new policy replaces it.
+func TestWrite_Replace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "standby.json")
+	assert.NoError(t, Write(path, testPolicy(), time.Now()))
+	assert.NoError(t, Write(path, authz.NewPolicy(nil, nil), time.Now()))
+
+	policy, _, err := Load(path)
+	assert.NoError(t, err)
+	assert.Empty(t, policy.Groups)
+}
+
+// TestLoad_Error_ChecksumMismatch loads a tampered standby file, checking for ErrChecksumMismatch.
+func TestLoad_Error_ChecksumMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "standby.json")
+	assert.NoError(t, Write(path, testPolicy(), time.Now()))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), "adminuser", "otheruser", 1)), 0o600))
+
+	policy, _, err := Load(path)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Nil(t, policy)
+}
+
+// TestLoad_Error_MissingFile loads a missing standby file, checking for an error.
+func TestLoad_Error_MissingFile(t *testing.T) {
+	policy, _, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Nil(t, policy)
+}
+
+// TestExporter_Export exports the policy read from the store, checking the standby file is written.
+func TestExporter_Export(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "standby.json")
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return testPolicy(), nil })
+	exporter := NewExporter(source, path, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.NoError(t, exporter.Export(context.Background()))
+
+	policy, _, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+}
+
+// TestExporter_Export_Error exports while the store fails, checking the previous standby file is kept.
+func TestExporter_Export_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "standby.json")
+	assert.NoError(t, Write(path, testPolicy(), time.Now()))
+	source := policyReaderFunc(func(ctx context.Context) (*authz.Policy, error) { return nil, errors.New("database is down") })
+	exporter := NewExporter(source, path, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Error(t, exporter.Export(context.Background()))
+
+	policy, _, err := Load(path)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+}
+
+// TestManager serves a standby policy, checking reads succeed and changes are refused.
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(testPolicy())
+
+	policy, err := manager.ReadPolicy(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, policy.Permissions, 1)
+
+	health, err := manager.Health(ctx)
+	assert.NoError(t, err)
+	assert.True(t, health.Degraded)
+
+	var storeErr *store.PolicyStoreError
+	err = manager.UpdateGroupUsers(ctx, 1, []string{"user"})
+	assert.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, store.ReadOnly, storeErr.Code)
+
+	_, err = manager.CreateGroup(ctx, "group")
+	assert.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, store.ReadOnly, storeErr.Code)
+
+	_, err = manager.ListGroups(ctx)
+	assert.ErrorAs(t, err, &storeErr)
+	assert.Equal(t, store.ReadOnly, storeErr.Code)
+}
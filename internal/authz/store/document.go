@@ -0,0 +1,236 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+)
+
+// PolicyDocument is the whole policy graph of a store, as exported and imported by a PolicyManager
+// to copy the policy between environments. Unlike the policy itself it carries the metadata of the
+// groups and permissions and the source of every membership, and it identifies everything by name
+// since ids differ between stores.
+type PolicyDocument struct {
+	Groups      []GroupDocument      `json:"groups" yaml:"groups"`
+	Permissions []PermissionDocument `json:"permissions" yaml:"permissions"`
+	Denials     []DenialDocument     `json:"denials,omitempty" yaml:"denials,omitempty"`
+}
+
+// GroupDocument is a group of a PolicyDocument with its members.
+type GroupDocument struct {
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Members     []MemberDocument  `json:"members" yaml:"members"`
+}
+
+// MemberDocument is a member of a group of a PolicyDocument.
+type MemberDocument struct {
+	User string `json:"user" yaml:"user"`
+	// What created the membership, the manual source when empty.
+	Source MembershipSource `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// PermissionDocument is a permission of a PolicyDocument with the groups it is granted to.
+type PermissionDocument struct {
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Risk        authz.RiskLevel   `json:"risk,omitempty" yaml:"risk,omitempty"`
+	Groups      []string          `json:"groups" yaml:"groups"`
+	Implies     []string          `json:"implies,omitempty" yaml:"implies,omitempty"`
+	// The condition the attributes of a request must meet for the grants to apply, see authz.Permission.Condition.
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+// DenialDocument is a denial of a PolicyDocument, explicitly denying one of its permissions
+// to groups and users, see authz.Denial.
+type DenialDocument struct {
+	Permission string   `json:"permission" yaml:"permission"`
+	Groups     []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Users      []string `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// Validate checks the document can be imported: names are set and unique, members, sources,
+// risk levels, conditions and labels are valid, grants, implications and denials name the groups and
+// permissions of the document, and implications form no cycle.
+func (document *PolicyDocument) Validate() error {
+	groups := map[string]bool{}
+	for _, group := range document.Groups {
+		if strings.TrimSpace(group.Name) == "" {
+			return errors.New("group name is empty")
+		}
+		if groups[group.Name] {
+			return fmt.Errorf("group %q is defined twice", group.Name)
+		}
+		groups[group.Name] = true
+		if err := validateLabels(group.Labels); err != nil {
+			return fmt.Errorf("group %q: %w", group.Name, err)
+		}
+
+		users := map[string]bool{}
+		for _, member := range group.Members {
+			user, err := NormalizeUserId(member.User)
+			if err != nil || user != member.User {
+				return fmt.Errorf("group %q: invalid member %q", group.Name, member.User)
+			}
+			if users[user] {
+				return fmt.Errorf("group %q: member %q is listed twice", group.Name, user)
+			}
+			users[user] = true
+			if _, err := ParseMembershipSource(string(member.Source)); err != nil {
+				return fmt.Errorf("group %q: %w", group.Name, err)
+			}
+		}
+	}
+
+	permissions := map[string]bool{}
+	for _, permission := range document.Permissions {
+		if strings.TrimSpace(permission.Name) == "" {
+			return errors.New("permission name is empty")
+		}
+		if permissions[permission.Name] {
+			return fmt.Errorf("permission %q is defined twice", permission.Name)
+		}
+		permissions[permission.Name] = true
+		if err := validateLabels(permission.Labels); err != nil {
+			return fmt.Errorf("permission %q: %w", permission.Name, err)
+		}
+		if _, err := authz.ParseRiskLevel(string(permission.Risk)); err != nil {
+			return fmt.Errorf("permission %q: %w", permission.Name, err)
+		}
+		if permission.Condition != "" {
+			if _, err := condition.Compile(permission.Condition); err != nil {
+				return fmt.Errorf("permission %q: %w", permission.Name, err)
+			}
+		}
+		for _, group := range permission.Groups {
+			if !groups[group] {
+				return fmt.Errorf("permission %q is granted to unknown group %q", permission.Name, group)
+			}
+		}
+	}
+
+	denied := map[string]bool{}
+	for _, denial := range document.Denials {
+		if !permissions[denial.Permission] {
+			return fmt.Errorf("unknown permission %q is denied", denial.Permission)
+		}
+		if denied[denial.Permission] {
+			return fmt.Errorf("permission %q is denied twice", denial.Permission)
+		}
+		denied[denial.Permission] = true
+		if err := validateDenial(denial, groups); err != nil {
+			return err
+		}
+	}
+
+	// implications are checked by the policy, which also rejects cycles
+	return document.Policy().ValidateImplications()
+}
+
+// Policy returns the policy described by the document, without the metadata and the membership sources.
+func (document *PolicyDocument) Policy() *authz.Policy {
+	policy := &authz.Policy{
+		Groups:      make([]authz.Group, 0, len(document.Groups)),
+		Permissions: make([]authz.Permission, 0, len(document.Permissions)),
+	}
+	for _, group := range document.Groups {
+		users := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			users = append(users, member.User)
+		}
+		policy.Groups = append(policy.Groups, authz.Group{Name: group.Name, Users: users})
+	}
+	for _, permission := range document.Permissions {
+		policy.Permissions = append(policy.Permissions, authz.Permission{
+			Name:      permission.Name,
+			Groups:    slices.Clone(permission.Groups),
+			Risk:      permission.Risk,
+			Implies:   slices.Clone(permission.Implies),
+			Condition: permission.Condition,
+		})
+	}
+	for _, denial := range document.Denials {
+		policy.Denials = append(policy.Denials, authz.Denial{
+			Permission: denial.Permission,
+			Groups:     slices.Clone(denial.Groups),
+			Users:      slices.Clone(denial.Users),
+		})
+	}
+	return policy
+}
+
+// DocumentFromPolicy returns the document describing the given policy, with no metadata
+// and every membership from the manual source. Wildcard denials are kept as they are, so
+// the document fails validation unless they name a permission of the policy.
+func DocumentFromPolicy(policy *authz.Policy) *PolicyDocument {
+	document := &PolicyDocument{
+		Groups:      make([]GroupDocument, 0, len(policy.Groups)),
+		Permissions: make([]PermissionDocument, 0, len(policy.Permissions)),
+	}
+	for _, group := range policy.Groups {
+		members := make([]MemberDocument, 0, len(group.Users))
+		for _, user := range group.Users {
+			members = append(members, MemberDocument{User: user})
+		}
+		document.Groups = append(document.Groups, GroupDocument{Name: group.Name, Members: members})
+	}
+	for _, permission := range policy.Permissions {
+		document.Permissions = append(document.Permissions, PermissionDocument{
+			Name:      permission.Name,
+			Risk:      permission.Risk,
+			Groups:    slices.Clone(permission.Groups),
+			Implies:   slices.Clone(permission.Implies),
+			Condition: permission.Condition,
+		})
+	}
+	for _, denial := range policy.Denials {
+		document.Denials = append(document.Denials, DenialDocument{
+			Permission: denial.Permission,
+			Groups:     slices.Clone(denial.Groups),
+			Users:      slices.Clone(denial.Users),
+		})
+	}
+	return document
+}
+
+// validateDenial checks the denial names known groups and valid users, each once.
+func validateDenial(denial DenialDocument, groups map[string]bool) error {
+	deniedGroups := map[string]bool{}
+	for _, group := range denial.Groups {
+		if !groups[group] {
+			return fmt.Errorf("permission %q is denied to unknown group %q", denial.Permission, group)
+		}
+		if deniedGroups[group] {
+			return fmt.Errorf("permission %q is denied twice to group %q", denial.Permission, group)
+		}
+		deniedGroups[group] = true
+	}
+	deniedUsers := map[string]bool{}
+	for _, user := range denial.Users {
+		normalized, err := NormalizeUserId(user)
+		if err != nil || normalized != user {
+			return fmt.Errorf("permission %q is denied to invalid user %q", denial.Permission, user)
+		}
+		if deniedUsers[user] {
+			return fmt.Errorf("permission %q is denied twice to user %q", denial.Permission, user)
+		}
+		deniedUsers[user] = true
+	}
+	return nil
+}
+
+// validateLabels checks no label has an empty key.
+func validateLabels(labels map[string]string) error {
+	for key := range labels {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("label key is empty")
+		}
+	}
+	return nil
+}
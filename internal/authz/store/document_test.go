@@ -0,0 +1,77 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDocument() *PolicyDocument {
+	return &PolicyDocument{
+		Groups: []GroupDocument{
+			{Name: "cooks", Description: "Kitchen staff", Labels: map[string]string{"team": "kitchen"},
+				Members: []MemberDocument{{User: "alice"}, {User: "bob", Source: SourceLDAP}}},
+			{Name: "editors", Members: []MemberDocument{}},
+		},
+		Permissions: []PermissionDocument{
+			{Name: "recipes.read", Groups: []string{"cooks", "editors"}},
+			{Name: "recipes.write", Risk: authz.RiskMedium, Groups: []string{"editors"}, Implies: []string{"recipes.read"}, Condition: "resource.owner == user"},
+		},
+		Denials: []DenialDocument{
+			{Permission: "recipes.write", Groups: []string{"cooks"}, Users: []string{"mallory"}},
+		},
+	}
+}
+
+func TestPolicyDocument_Validate(t *testing.T) {
+	assert.NoError(t, testDocument().Validate())
+
+	tests := []struct {
+		name   string
+		change func(document *PolicyDocument)
+		err    string
+	}{
+		{"empty group name", func(d *PolicyDocument) { d.Groups[0].Name = " " }, "group name is empty"},
+		{"duplicate group", func(d *PolicyDocument) { d.Groups[1].Name = "cooks" }, `group "cooks" is defined twice`},
+		{"invalid member", func(d *PolicyDocument) { d.Groups[0].Members[0].User = " alice" }, `invalid member " alice"`},
+		{"duplicate member", func(d *PolicyDocument) { d.Groups[0].Members[1].User = "alice" }, `member "alice" is listed twice`},
+		{"unknown source", func(d *PolicyDocument) { d.Groups[0].Members[0].Source = "ad" }, `unknown membership source "ad"`},
+		{"empty label key", func(d *PolicyDocument) { d.Groups[0].Labels[""] = "x" }, "label key is empty"},
+		{"duplicate permission", func(d *PolicyDocument) { d.Permissions[1].Name = "recipes.read" }, `permission "recipes.read" is defined twice`},
+		{"unknown risk", func(d *PolicyDocument) { d.Permissions[0].Risk = "critical" }, `unknown risk level "critical"`},
+		{"invalid condition", func(d *PolicyDocument) { d.Permissions[1].Condition = "resource.owner ==" }, `permission "recipes.write": condition "resource.owner =="`},
+		{"unknown group", func(d *PolicyDocument) { d.Permissions[0].Groups = []string{"chefs"} }, `granted to unknown group "chefs"`},
+		{"unknown denied permission", func(d *PolicyDocument) { d.Denials[0].Permission = "recipes.*" }, `unknown permission "recipes.*" is denied`},
+		{"duplicate denial", func(d *PolicyDocument) { d.Denials = append(d.Denials, d.Denials[0]) }, `permission "recipes.write" is denied twice`},
+		{"unknown denied group", func(d *PolicyDocument) { d.Denials[0].Groups = []string{"chefs"} }, `denied to unknown group "chefs"`},
+		{"duplicate denied group", func(d *PolicyDocument) { d.Denials[0].Groups = []string{"cooks", "cooks"} }, `denied twice to group "cooks"`},
+		{"invalid denied user", func(d *PolicyDocument) { d.Denials[0].Users = []string{" mallory"} }, `denied to invalid user " mallory"`},
+		{"implication cycle", func(d *PolicyDocument) { d.Permissions[0].Implies = []string{"recipes.write"} }, "recipes.read -> recipes.write -> recipes.read"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := testDocument()
+			test.change(document)
+			assert.ErrorContains(t, document.Validate(), test.err)
+		})
+	}
+}
+
+func TestPolicyDocument_Policy(t *testing.T) {
+	policy := testDocument().Policy()
+
+	assert.Equal(t, []authz.Group{{Name: "cooks", Users: []string{"alice", "bob"}}, {Name: "editors", Users: []string{}}}, policy.Groups)
+	assert.Equal(t, []authz.Permission{
+		{Name: "recipes.read", Groups: []string{"cooks", "editors"}},
+		{Name: "recipes.write", Risk: authz.RiskMedium, Groups: []string{"editors"}, Implies: []string{"recipes.read"}, Condition: "resource.owner == user"},
+	}, policy.Permissions)
+	assert.Equal(t, []authz.Denial{{Permission: "recipes.write", Groups: []string{"cooks"}, Users: []string{"mallory"}}}, policy.Denials)
+
+	// the document round trips through the policy without its metadata
+	document := DocumentFromPolicy(policy)
+	assert.Equal(t, []MemberDocument{{User: "alice"}, {User: "bob"}}, document.Groups[0].Members)
+	assert.Empty(t, document.Groups[0].Labels)
+	assert.Equal(t, testDocument().Permissions, document.Permissions)
+	assert.Equal(t, testDocument().Denials, document.Denials)
+}
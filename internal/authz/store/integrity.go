@@ -0,0 +1,23 @@
+package store
+
+// IntegrityProblem is a row breaking a consistency rule the store schema cannot enforce.
+type IntegrityProblem struct {
+	// The rule that is broken, such as "dangling_group_permissions".
+	Check string `json:"check"`
+	// The offending row or rows, such as "group 4".
+	Subject string `json:"subject"`
+	Detail  string `json:"detail"`
+}
+
+// IntegrityReport is the result of verifying the integrity of the store.
+type IntegrityReport struct {
+	// The rules that were verified.
+	Checks []string `json:"checks"`
+	// The broken rules, empty when the store is consistent.
+	Problems []IntegrityProblem `json:"problems"`
+}
+
+// OK reports whether no problem was found.
+func (report *IntegrityReport) OK() bool {
+	return len(report.Problems) == 0
+}
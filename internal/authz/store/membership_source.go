@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MembershipSource identifies what created a group membership, so memberships managed by
+// external synchronization can be told apart from those edited by administrators.
+type MembershipSource string
+
+const (
+	// SourceManual marks memberships edited by administrators. It is the default source.
+	SourceManual MembershipSource = "manual"
+	// SourceLDAP marks memberships synchronized from an LDAP directory.
+	SourceLDAP MembershipSource = "ldap"
+	// SourceSCIM marks memberships provisioned through SCIM.
+	SourceSCIM MembershipSource = "scim"
+	// SourceAPI marks memberships managed by automation calling the API.
+	SourceAPI MembershipSource = "api"
+)
+
+// MembershipSources lists every supported membership source.
+var MembershipSources = []MembershipSource{SourceManual, SourceLDAP, SourceSCIM, SourceAPI}
+
+// ParseMembershipSource validates the given value and returns the matching MembershipSource.
+// An empty value is the manual source.
+func ParseMembershipSource(value string) (MembershipSource, error) {
+	if value == "" {
+		return SourceManual, nil
+	}
+
+	source := MembershipSource(value)
+	if !slices.Contains(MembershipSources, source) {
+		return "", fmt.Errorf("unknown membership source %q", value)
+	}
+	return source, nil
+}
+
+type membershipSourceContextKey struct{}
+
+// WithMembershipSource returns a copy of the context attributing membership changes to the given source.
+func WithMembershipSource(ctx context.Context, source MembershipSource) context.Context {
+	return context.WithValue(ctx, membershipSourceContextKey{}, source)
+}
+
+// MembershipSourceFromContext returns the source membership changes are attributed to,
+// which is the manual source unless the context says otherwise.
+func MembershipSourceFromContext(ctx context.Context) MembershipSource {
+	source, ok := ctx.Value(membershipSourceContextKey{}).(MembershipSource)
+	if !ok {
+		return SourceManual
+	}
+	return source
+}
+
+// SourcePrecedence orders membership sources from the highest to the lowest precedence.
+// A change made by one source leaves the memberships of higher precedence sources untouched
+// and takes over the memberships of lower precedence sources it asserts.
+// Sources that are not listed rank below every listed source.
+// An empty precedence puts every source on equal footing.
+type SourcePrecedence []MembershipSource
+
+// ParseSourcePrecedence parses a comma separated list of membership sources, such as "ldap,scim,manual".
+func ParseSourcePrecedence(value string) (SourcePrecedence, error) {
+	precedence := SourcePrecedence{}
+	if strings.TrimSpace(value) == "" {
+		return precedence, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		source, err := ParseMembershipSource(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(precedence, source) {
+			return nil, fmt.Errorf("membership source %q is listed twice", source)
+		}
+		precedence = append(precedence, source)
+	}
+	return precedence, nil
+}
+
+// Protected returns the sources whose memberships the given source may not change.
+func (precedence SourcePrecedence) Protected(source MembershipSource) []MembershipSource {
+	return precedence.filter(func(rank int) bool { return rank < precedence.rank(source) })
+}
+
+// Overridden returns the sources whose memberships the given source takes over.
+func (precedence SourcePrecedence) Overridden(source MembershipSource) []MembershipSource {
+	return precedence.filter(func(rank int) bool { return rank > precedence.rank(source) })
+}
+
+func (precedence SourcePrecedence) rank(source MembershipSource) int {
+	if len(precedence) == 0 {
+		return 0
+	}
+	if index := slices.Index(precedence, source); index >= 0 {
+		return index
+	}
+	return len(precedence)
+}
+
+func (precedence SourcePrecedence) filter(keep func(rank int) bool) []MembershipSource {
+	sources := []MembershipSource{}
+	for _, source := range MembershipSources {
+		if keep(precedence.rank(source)) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
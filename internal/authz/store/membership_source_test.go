@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMembershipSource(t *testing.T) {
+	source, err := ParseMembershipSource("")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceManual, source)
+
+	source, err = ParseMembershipSource("ldap")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceLDAP, source)
+
+	_, err = ParseMembershipSource("LDAP")
+	assert.Error(t, err)
+}
+
+func TestMembershipSourceFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, SourceManual, MembershipSourceFromContext(ctx))
+	assert.Equal(t, SourceSCIM, MembershipSourceFromContext(WithMembershipSource(ctx, SourceSCIM)))
+}
+
+func TestParseSourcePrecedence(t *testing.T) {
+	precedence, err := ParseSourcePrecedence(" ldap, scim ,manual")
+	assert.NoError(t, err)
+	assert.Equal(t, SourcePrecedence{SourceLDAP, SourceSCIM, SourceManual}, precedence)
+
+	precedence, err = ParseSourcePrecedence("")
+	assert.NoError(t, err)
+	assert.Empty(t, precedence)
+
+	_, err = ParseSourcePrecedence("ldap,ldap")
+	assert.Error(t, err)
+
+	_, err = ParseSourcePrecedence("ldap,unknown")
+	assert.Error(t, err)
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	precedence := SourcePrecedence{SourceLDAP, SourceManual}
+
+	// listed sources protect higher ones and take over lower ones, unlisted sources rank last
+	assert.Equal(t, []MembershipSource{}, precedence.Protected(SourceLDAP))
+	assert.Equal(t, []MembershipSource{SourceManual, SourceSCIM, SourceAPI}, precedence.Overridden(SourceLDAP))
+	assert.Equal(t, []MembershipSource{SourceLDAP}, precedence.Protected(SourceManual))
+	assert.Equal(t, []MembershipSource{SourceSCIM, SourceAPI}, precedence.Overridden(SourceManual))
+	assert.Equal(t, []MembershipSource{SourceManual, SourceLDAP}, precedence.Protected(SourceAPI))
+	assert.Equal(t, []MembershipSource{}, precedence.Overridden(SourceAPI))
+
+	// without precedence every source is equal
+	assert.Equal(t, []MembershipSource{}, SourcePrecedence{}.Protected(SourceManual))
+	assert.Equal(t, []MembershipSource{}, SourcePrecedence{}.Overridden(SourceManual))
+}
@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/catalog"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// FolderStore is an in-memory implementation of the folder.Store interface. It keeps the folders
+// in the MemoryPolicyManager holding their groups and permissions, so the policies the manager
+// reads grant the permissions of the folders like the Postgres store does. Permission catalogs are
+// not stored, so no catalog can be moved into a folder.
+type FolderStore struct {
+	manager *MemoryPolicyManager
+}
+
+var _ folder.Store = (*FolderStore)(nil)
+
+type folderNode struct {
+	name        string
+	parentId    int
+	labels      map[string]string
+	admins      []string
+	inheritance authz.Inheritance
+	permissions shared.Set[int]
+}
+
+// NewFolderStore creates a new FolderStore keeping the folders of the manager.
+func NewFolderStore(manager *MemoryPolicyManager) *FolderStore {
+	return &FolderStore{manager: manager}
+}
+
+// Create creates the folder. An ErrNotFound error is returned when the parent folder does not exist.
+func (folders *FolderStore) Create(ctx context.Context, entry folder.Folder) (int, error) {
+	manager := folders.manager
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if err := manager.checkFolderName(0, entry.ParentID, entry.Name); err != nil {
+		return 0, err
+	}
+
+	manager.lastFolderId++
+	manager.folders[manager.lastFolderId] = &folderNode{
+		name:        entry.Name,
+		parentId:    entry.ParentID,
+		labels:      cloneLabels(entry.Labels),
+		admins:      slices.Clone(entry.Admins),
+		inheritance: entry.Inheritance,
+		permissions: shared.NewSet[int](),
+	}
+	manager.revision++
+	return manager.lastFolderId, nil
+}
+
+// List returns every folder ordered by id.
+func (folders *FolderStore) List(ctx context.Context) ([]folder.Folder, error) {
+	manager := folders.manager
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	list := []folder.Folder{}
+	for _, id := range slices.Sorted(maps.Keys(manager.folders)) {
+		node := manager.folders[id]
+		entry := folder.Folder{
+			ID:          id,
+			Name:        node.name,
+			ParentID:    node.parentId,
+			Labels:      cloneLabels(node.labels),
+			Admins:      append([]string{}, node.admins...),
+			Inheritance: node.inheritance,
+		}
+		if node.permissions.Len() > 0 {
+			entry.Permissions = shared.Sorted(node.permissions)
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+// Update replaces the folder, refusing to move it into its own subtree.
+func (folders *FolderStore) Update(ctx context.Context, entry folder.Folder) error {
+	if entry.ParentID == entry.ID {
+		return folder.ErrCycle
+	}
+
+	manager := folders.manager
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	node, ok := manager.folders[entry.ID]
+	if !ok {
+		return folder.ErrNotFound
+	}
+	for parent := entry.ParentID; parent != 0; parent = manager.folders[parent].parentId {
+		if parent == entry.ID {
+			return folder.ErrCycle
+		}
+		if _, ok := manager.folders[parent]; !ok {
+			return folder.ErrNotFound
+		}
+	}
+	if err := manager.checkFolderName(entry.ID, entry.ParentID, entry.Name); err != nil {
+		return err
+	}
+
+	node.name = entry.Name
+	node.parentId = entry.ParentID
+	node.labels = cloneLabels(entry.Labels)
+	node.admins = slices.Clone(entry.Admins)
+	node.inheritance = entry.Inheritance
+	manager.revision++
+	return nil
+}
+
+// Delete deletes the folder with its permissions. Folders still holding subfolders or groups are not deleted.
+func (folders *FolderStore) Delete(ctx context.Context, id int) error {
+	manager := folders.manager
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if _, ok := manager.folders[id]; !ok {
+		return folder.ErrNotFound
+	}
+	for _, node := range manager.folders {
+		if node.parentId == id {
+			return folder.ErrNotEmpty
+		}
+	}
+	for _, folderId := range manager.folderGroups {
+		if folderId == id {
+			return folder.ErrNotEmpty
+		}
+	}
+
+	delete(manager.folders, id)
+	manager.revision++
+	return nil
+}
+
+// AssignGroup moves the group into the folder. A GroupNotFound error is returned when the group
+// does not exist and ErrNotFound when the folder does not.
+func (folders *FolderStore) AssignGroup(ctx context.Context, groupId int, folderId int) error {
+	manager := folders.manager
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if folderId == 0 {
+		delete(manager.folderGroups, groupId)
+		manager.revision++
+		return nil
+	}
+	if _, ok := manager.groups[groupId]; !ok {
+		return store.NewGroupNotFoundError()
+	}
+	if _, ok := manager.folders[folderId]; !ok {
+		return folder.ErrNotFound
+	}
+
+	manager.folderGroups[groupId] = folderId
+	manager.revision++
+	return nil
+}
+
+// AssignCatalog moves the catalog out of any folder with folder id 0. No catalog is registered in
+// memory, so moving one into a folder fails with a catalog.ErrNotFound error.
+func (folders *FolderStore) AssignCatalog(ctx context.Context, application string, folderId int) error {
+	if folderId == 0 {
+		return nil
+	}
+	return catalog.ErrNotFound
+}
+
+// SetPermissions replaces the permissions granted by the folder. A PermissionNotFound error is
+// returned when a permission does not exist and ErrNotFound when the folder does not.
+func (folders *FolderStore) SetPermissions(ctx context.Context, folderId int, permissionIds []int) error {
+	manager := folders.manager
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	node, ok := manager.folders[folderId]
+	if !ok {
+		return folder.ErrNotFound
+	}
+	for _, permissionId := range permissionIds {
+		if _, ok := manager.permissions[permissionId]; !ok {
+			return store.NewPermissionNotFoundError()
+		}
+	}
+
+	node.permissions = shared.NewSet(permissionIds...)
+	manager.revision++
+	return nil
+}
+
+// Assignments returns the folder holding every group. No catalog is registered in memory.
+func (folders *FolderStore) Assignments(ctx context.Context) (*folder.Assignments, error) {
+	manager := folders.manager
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	return &folder.Assignments{Groups: maps.Clone(manager.folderGroups), Catalogs: map[string]int{}}, nil
+}
+
+// checkFolderName checks the parent folder exists and holds no other folder with the name.
+func (manager *MemoryPolicyManager) checkFolderName(id int, parentId int, name string) error {
+	if _, ok := manager.folders[parentId]; parentId != 0 && !ok {
+		return folder.ErrNotFound
+	}
+	for otherId, other := range manager.folders {
+		if otherId != id && other.parentId == parentId && other.name == name {
+			return folder.ErrNameExists
+		}
+	}
+	return nil
+}
+
+// policyFolders returns the folders of the policy ordered by id, with the names of their groups
+// and permissions sorted, like the Postgres store reads them.
+func (manager *MemoryPolicyManager) policyFolders() []authz.Folder {
+	nodes := []folder.Folder{}
+	for id, node := range manager.folders {
+		nodes = append(nodes, folder.Folder{ID: id, Name: node.name, ParentID: node.parentId})
+	}
+	slices.SortFunc(nodes, func(a, b folder.Folder) int { return a.ID - b.ID })
+
+	tree := folder.NewTree(nodes)
+	var policyFolders []authz.Folder
+	for _, node := range nodes {
+		names := []string{}
+		for _, parent := range tree.Path(node.ID) {
+			names = append(names, parent.Name)
+		}
+		policyFolder := authz.Folder{Path: strings.Join(names, "/"), Inheritance: manager.folders[node.ID].inheritance}
+		for groupId, folderId := range manager.folderGroups {
+			if folderId == node.ID {
+				policyFolder.Groups = append(policyFolder.Groups, manager.groups[groupId].name)
+			}
+		}
+		slices.Sort(policyFolder.Groups)
+		if grants := manager.permissionNames(manager.folders[node.ID].permissions); len(grants) > 0 {
+			policyFolder.Grants = grants
+		}
+		policyFolders = append(policyFolders, policyFolder)
+	}
+	return policyFolders
+}
+
+// pruneFolders removes the groups and permissions deleted since from the folders.
+func (manager *MemoryPolicyManager) pruneFolders() {
+	for groupId := range manager.folderGroups {
+		if _, ok := manager.groups[groupId]; !ok {
+			delete(manager.folderGroups, groupId)
+		}
+	}
+	for _, node := range manager.folders {
+		for permissionId := range node.permissions {
+			if _, ok := manager.permissions[permissionId]; !ok {
+				node.permissions.Remove(permissionId)
+			}
+		}
+	}
+}
@@ -0,0 +1,1022 @@
+// Package memory is an in-memory implementation of the policy store. It mirrors the Postgres
+// one, with versioned groups and permissions, unique names and concurrency errors, so unit tests
+// and examples can use a real store without mocks or a database container.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// MemoryPolicyManager is a thread-safe in-memory implementation of the PolicyManager interface.
+// Its folders are managed with a FolderStore, see NewFolderStore.
+type MemoryPolicyManager struct {
+	mu               sync.RWMutex
+	logger           *slog.Logger
+	reportNoChanges  bool
+	precedence       store.SourcePrecedence
+	groups           map[int]*group
+	permissions      map[int]*permission
+	lastGroupId      int
+	lastPermissionId int
+	folders          map[int]*folderNode
+	folderGroups     map[int]int
+	lastFolderId     int
+	revision         int64
+	// afterRead is called between reading the version of a group or permission and changing it,
+	// so tests can make the concurrent changes a Concurrency error reports.
+	afterRead func()
+}
+
+var _ store.PolicyManager[int, int, string] = (*MemoryPolicyManager)(nil)
+
+type group struct {
+	name    string
+	version int
+	store.Metadata
+	members map[string]store.MembershipSource
+	grants  shared.Set[int]
+}
+
+type permission struct {
+	name    string
+	version int
+	risk    authz.RiskLevel
+	store.Metadata
+	implies shared.Set[int]
+	// the condition of the grants, empty when they are unconditional
+	condition string
+	// the groups and users explicitly denied the permission
+	deniedGroups shared.Set[int]
+	deniedUsers  shared.Set[string]
+}
+
+// Option configures optional MemoryPolicyManager behavior.
+type Option func(*MemoryPolicyManager)
+
+// WithNoChangesError makes mutating operations that leave the store unchanged return
+// a NoChanges error instead of succeeding silently.
+func WithNoChangesError() Option {
+	return func(manager *MemoryPolicyManager) {
+		manager.reportNoChanges = true
+	}
+}
+
+// WithSourcePrecedence protects group memberships from changes made by lower precedence sources.
+// By default every source may change every membership.
+func WithSourcePrecedence(precedence store.SourcePrecedence) Option {
+	return func(manager *MemoryPolicyManager) {
+		manager.precedence = precedence
+	}
+}
+
+// NewMemoryPolicyManager creates a new empty MemoryPolicyManager instance.
+func NewMemoryPolicyManager(logger *slog.Logger, options ...Option) *MemoryPolicyManager {
+	manager := &MemoryPolicyManager{
+		logger: logger, groups: map[int]*group{}, permissions: map[int]*permission{},
+		folders: map[int]*folderNode{}, folderGroups: map[int]int{},
+	}
+	for _, option := range options {
+		option(manager)
+	}
+	return manager
+}
+
+// UpdateGroupPermissions updates the permissions for the specified group.
+// Duplicate permission ids are ignored.
+func (manager *MemoryPolicyManager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error {
+	logger := manager.logger.With("group_id", groupId, "operation", "UpdateGroupPermissions")
+	permissions = store.NormalizeIds(permissions)
+
+	return manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		for _, permissionId := range permissions {
+			if _, ok := manager.permissions[permissionId]; !ok {
+				logger.Error("permission not found", "permission_id", permissionId)
+				return false, store.NewPermissionNotFoundError()
+			}
+		}
+
+		group := manager.groups[groupId]
+		grants := shared.NewSet(permissions...)
+		if maps.Equal(group.grants, grants) {
+			return false, nil
+		}
+		group.grants = grants
+		return true, nil
+	})
+}
+
+// CreateGroup creates a new group.
+func (manager *MemoryPolicyManager) CreateGroup(ctx context.Context, groupName string) (int, error) {
+	logger := manager.logger.With("group_name", groupName, "operation", "CreateGroup")
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.groupNamed(groupName) != 0 {
+		logger.Error("group name already exists")
+		return 0, store.NewNameExistsError()
+	}
+
+	manager.lastGroupId++
+	manager.groups[manager.lastGroupId] = newGroup(groupName)
+	manager.revision++
+	return manager.lastGroupId, nil
+}
+
+// CreatePermission creates a new permission.
+func (manager *MemoryPolicyManager) CreatePermission(ctx context.Context, permissionName string) (int, error) {
+	logger := manager.logger.With("permission_name", permissionName, "operation", "CreatePermission")
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.permissionNamed(permissionName) != 0 {
+		logger.Error("permission name already exists")
+		return 0, store.NewNameExistsError()
+	}
+
+	manager.lastPermissionId++
+	manager.permissions[manager.lastPermissionId] = newPermission(permissionName)
+	manager.revision++
+	return manager.lastPermissionId, nil
+}
+
+// SetPermissionRisk changes the risk level of the permission with the specified id.
+func (manager *MemoryPolicyManager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) error {
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionRisk")
+
+	risk, err := authz.ParseRiskLevel(string(risk))
+	if err != nil {
+		logger.Error("invalid risk level", "error", err)
+		return store.NewInvalidArgumentError()
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	permission, ok := manager.permissions[permissionId]
+	if !ok {
+		logger.Error("permission not found")
+		return store.NewPermissionNotFoundError()
+	}
+	if permission.risk == risk {
+		return manager.noChanges(logger)
+	}
+
+	permission.risk = risk
+	permission.version++
+	manager.revision++
+	return nil
+}
+
+// SetPermissionImplications replaces the permissions implied by the permission with the specified id.
+// Duplicate permission ids are ignored. Implications that would make a permission imply itself,
+// directly or transitively, are rejected with an InvalidArgument error.
+func (manager *MemoryPolicyManager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) error {
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionImplications")
+	implied = store.NormalizeIds(implied)
+
+	return manager.versioned(logger, manager.permissionVersion(permissionId), func() (bool, error) {
+		for _, impliedId := range implied {
+			if _, ok := manager.permissions[impliedId]; !ok {
+				logger.Error("implied permission not found", "implied_id", impliedId)
+				return false, store.NewPermissionNotFoundError()
+			}
+		}
+
+		// reject implications leading back to the permission
+		reachable := shared.NewSet(implied...)
+		for queue := slices.Clone(implied); len(queue) > 0; queue = queue[1:] {
+			for next := range manager.permissions[queue[0]].implies {
+				if reachable.Insert(next) {
+					queue = append(queue, next)
+				}
+			}
+		}
+		if reachable.Contains(permissionId) {
+			logger.Error("permission implications form a cycle")
+			return false, store.NewInvalidArgumentError()
+		}
+
+		permission := manager.permissions[permissionId]
+		implies := shared.NewSet(implied...)
+		if maps.Equal(permission.implies, implies) {
+			return false, nil
+		}
+		permission.implies = implies
+		return true, nil
+	})
+}
+
+// SetPermissionDenials replaces the groups and users explicitly denied the permission with the specified id,
+// see authz.Denial. Duplicate group ids and users are ignored.
+func (manager *MemoryPolicyManager) SetPermissionDenials(ctx context.Context, permissionId int, groups []int, users []string) error {
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionDenials")
+	groups = store.NormalizeIds(groups)
+	users, err := store.NormalizeUserIds(users)
+	if err != nil {
+		logger.Error("invalid user ids", "error", err)
+		return err
+	}
+
+	return manager.versioned(logger, manager.permissionVersion(permissionId), func() (bool, error) {
+		for _, groupId := range groups {
+			if _, ok := manager.groups[groupId]; !ok {
+				logger.Error("denied group not found", "denied_group_id", groupId)
+				return false, store.NewGroupNotFoundError()
+			}
+		}
+
+		permission := manager.permissions[permissionId]
+		deniedGroups, deniedUsers := shared.NewSet(groups...), shared.NewSet(users...)
+		if maps.Equal(permission.deniedGroups, deniedGroups) && maps.Equal(permission.deniedUsers, deniedUsers) {
+			return false, nil
+		}
+		permission.deniedGroups, permission.deniedUsers = deniedGroups, deniedUsers
+		return true, nil
+	})
+}
+
+// SetPermissionCondition replaces the condition the attributes of a request must meet for the grants
+// of the permission with the specified id to apply, see authz.Permission.Condition. An empty condition
+// makes the grants unconditional; a condition that does not compile is rejected with an InvalidArgument error.
+func (manager *MemoryPolicyManager) SetPermissionCondition(ctx context.Context, permissionId int, condition string) error {
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionCondition")
+	condition, err := store.NormalizeCondition(condition)
+	if err != nil {
+		logger.Error("invalid condition", "error", err)
+		return err
+	}
+
+	return manager.versioned(logger, manager.permissionVersion(permissionId), func() (bool, error) {
+		permission := manager.permissions[permissionId]
+		if permission.condition == condition {
+			return false, nil
+		}
+		permission.condition = condition
+		return true, nil
+	})
+}
+
+// GrantPermission grants a single permission to the specified group, keeping the existing grants.
+func (manager *MemoryPolicyManager) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	logger := manager.logger.With("group_id", groupId, "permission_id", permissionId, "operation", "GrantPermission")
+
+	return manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		if _, ok := manager.permissions[permissionId]; !ok {
+			logger.Error("permission not found")
+			return false, store.NewPermissionNotFoundError()
+		}
+		return manager.groups[groupId].grants.Insert(permissionId), nil
+	})
+}
+
+// UpdateGroupUsers updates the users for the specified group.
+// User ids are trimmed and duplicates ignored; an empty user id is rejected.
+// Memberships are attributed to the source in the context; memberships of higher precedence
+// sources are kept even when omitted, see WithSourcePrecedence.
+func (manager *MemoryPolicyManager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	source := store.MembershipSourceFromContext(ctx)
+	logger := manager.logger.With("group_id", groupId, "source", source, "operation", "UpdateGroupUsers")
+
+	users, err := store.NormalizeUserIds(users)
+	if err != nil {
+		logger.Error("invalid user ids", "error", err)
+		return err
+	}
+
+	return manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		group := manager.groups[groupId]
+		changed := false
+		for _, userId := range users {
+			changed = manager.addMember(group, userId, source) || changed
+		}
+		for userId := range group.members {
+			if !slices.Contains(users, userId) {
+				changed = manager.removeMember(group, userId, source) || changed
+			}
+		}
+		return changed, nil
+	})
+}
+
+// AddGroupUser adds a single user to the specified group, keeping the existing members.
+// The membership is attributed to the source in the context; an existing membership is left
+// as it is, whatever its source.
+func (manager *MemoryPolicyManager) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	source := store.MembershipSourceFromContext(ctx)
+	logger := manager.logger.With("group_id", groupId, "user_id", userId, "source", source, "operation", "AddGroupUser")
+
+	userId, err := store.NormalizeUserId(userId)
+	if err != nil {
+		logger.Error("invalid user id", "error", err)
+		return err
+	}
+
+	return manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		group := manager.groups[groupId]
+		if _, ok := group.members[userId]; ok {
+			return false, nil
+		}
+		group.members[userId] = source
+		return true, nil
+	})
+}
+
+// UpdateUserGroups updates the groups for the specified user.
+// The user id is trimmed and duplicate group ids are ignored; an empty user id is rejected.
+// Memberships are attributed to the source in the context; memberships of higher precedence
+// sources are kept even when omitted, see WithSourcePrecedence. Like in Postgres, the versions
+// of the groups are left unchanged.
+func (manager *MemoryPolicyManager) UpdateUserGroups(ctx context.Context, userId string, groups []int) error {
+	source := store.MembershipSourceFromContext(ctx)
+	logger := manager.logger.With("user_id", userId, "source", source, "operation", "UpdateUserGroups")
+
+	userId, err := store.NormalizeUserId(userId)
+	if err != nil {
+		logger.Error("invalid user id", "error", err)
+		return err
+	}
+	groups = store.NormalizeIds(groups)
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, groupId := range groups {
+		if _, ok := manager.groups[groupId]; !ok {
+			logger.Error("group not found", "group_id", groupId)
+			return store.NewGroupNotFoundError()
+		}
+	}
+
+	changed := false
+	for groupId, group := range manager.groups {
+		if slices.Contains(groups, groupId) {
+			changed = manager.addMember(group, userId, source) || changed
+		} else {
+			changed = manager.removeMember(group, userId, source) || changed
+		}
+	}
+	if !changed {
+		return manager.noChanges(logger)
+	}
+
+	manager.revision++
+	return nil
+}
+
+// DeleteGroup deletes the group with the specified id together with its memberships, permission grants
+// and folder assignment.
+func (manager *MemoryPolicyManager) DeleteGroup(ctx context.Context, groupId int) (*store.GroupDeletion, error) {
+	logger := manager.logger.With("group_id", groupId, "operation", "DeleteGroup")
+
+	var deletion *store.GroupDeletion
+	err := manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		group := manager.groups[groupId]
+		deletion = &store.GroupDeletion{Members: len(group.members), Grants: group.grants.Len()}
+		delete(manager.groups, groupId)
+		for _, permission := range manager.permissions {
+			permission.deniedGroups.Remove(groupId)
+		}
+		manager.pruneFolders()
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("group deleted", "members", deletion.Members, "grants", deletion.Grants)
+	return deletion, nil
+}
+
+// ChangeGroupName changes the name of the group with the specified id.
+func (manager *MemoryPolicyManager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) error {
+	logger := manager.logger.With("group_id", groupId, "operation", "ChangeGroupName")
+
+	return manager.versioned(logger, manager.groupVersion(groupId), func() (bool, error) {
+		group := manager.groups[groupId]
+		if group.name == newGroupName {
+			return false, nil
+		}
+		if manager.groupNamed(newGroupName) != 0 {
+			logger.Error("group name already exists")
+			return false, store.NewNameExistsError()
+		}
+		group.name = newGroupName
+		return true, nil
+	})
+}
+
+// UpdateGroupMetadata applies the patch to the description and labels of the group with the
+// specified id and returns the group. The group version is unchanged.
+func (manager *MemoryPolicyManager) UpdateGroupMetadata(ctx context.Context, groupId int, patch store.MetadataPatch) (*store.GroupInfo[int], error) {
+	logger := manager.logger.With("group_id", groupId, "operation", "UpdateGroupMetadata")
+
+	if err := patch.Validate(); err != nil {
+		logger.Error("invalid metadata patch")
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	group, ok := manager.groups[groupId]
+	if !ok {
+		logger.Error("group not found")
+		return nil, store.NewGroupNotFoundError()
+	}
+	applyPatch(&group.Metadata, patch)
+	manager.revision++
+
+	info := group.info(groupId)
+	return &info, nil
+}
+
+// UpdatePermissionMetadata applies the patch to the description and labels of the permission
+// with the specified id and returns the permission, like UpdateGroupMetadata.
+func (manager *MemoryPolicyManager) UpdatePermissionMetadata(ctx context.Context, permissionId int, patch store.MetadataPatch) (*store.PermissionInfo[int], error) {
+	logger := manager.logger.With("permission_id", permissionId, "operation", "UpdatePermissionMetadata")
+
+	if err := patch.Validate(); err != nil {
+		logger.Error("invalid metadata patch")
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	permission, ok := manager.permissions[permissionId]
+	if !ok {
+		logger.Error("permission not found")
+		return nil, store.NewPermissionNotFoundError()
+	}
+	applyPatch(&permission.Metadata, patch)
+	manager.revision++
+
+	info := permission.info(permissionId)
+	return &info, nil
+}
+
+// DeleteUser deletes the user with the specified id from every group, whatever the membership source.
+// The user id is trimmed; an empty user id is rejected.
+func (manager *MemoryPolicyManager) DeleteUser(ctx context.Context, userId string) error {
+	logger := manager.logger.With("user_id", userId, "operation", "DeleteUser")
+
+	userId, err := store.NormalizeUserId(userId)
+	if err != nil {
+		logger.Error("invalid user id", "error", err)
+		return err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	deleted := false
+	for _, group := range manager.groups {
+		if _, ok := group.members[userId]; ok {
+			delete(group.members, userId)
+			deleted = true
+		}
+	}
+	if !deleted {
+		logger.Error("no user records found for deletion")
+		return store.NewNoUserRecordsDeletedError()
+	}
+
+	manager.revision++
+	return nil
+}
+
+// ReadPolicy returns the whole policy. Groups, permissions and denials are sorted by name,
+// group members by user id and permission grants, implications and denials by name, so reads are stable.
+// The folders are ordered by id and their permissions are granted to the groups they hold, like the
+// Postgres store does.
+func (manager *MemoryPolicyManager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	groups := []authz.Group{}
+	for _, group := range manager.sortedGroups() {
+		groups = append(groups, authz.Group{Name: group.name, Users: group.users()})
+	}
+
+	permissions := []authz.Permission{}
+	for _, entry := range manager.sortedPermissions() {
+		permission := authz.Permission{Name: entry.name, Groups: manager.grantedGroups(entry.id), Risk: entry.risk, Condition: entry.condition}
+		if implies := manager.permissionNames(entry.implies); len(implies) > 0 {
+			permission.Implies = implies
+		}
+		permissions = append(permissions, permission)
+	}
+
+	policy := authz.NewPolicy(permissions, groups)
+	for _, denial := range manager.denials() {
+		policy.Denials = append(policy.Denials, authz.Denial{Permission: denial.Permission, Groups: denial.Groups, Users: denial.Users})
+	}
+	policy.Folders = manager.policyFolders()
+	policy.ResolveFolders()
+	return policy, nil
+}
+
+// PolicyRevision returns the number of changes made to the policy so far.
+func (manager *MemoryPolicyManager) PolicyRevision(ctx context.Context) (int64, error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	return manager.revision, nil
+}
+
+// Export returns the whole policy graph with the metadata of the groups and permissions and the
+// source of every membership. Like ReadPolicy everything is sorted by name, so exports are stable.
+func (manager *MemoryPolicyManager) Export(ctx context.Context) (*store.PolicyDocument, error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	document := &store.PolicyDocument{Groups: []store.GroupDocument{}, Permissions: []store.PermissionDocument{}}
+	for _, group := range manager.sortedGroups() {
+		members := []store.MemberDocument{}
+		for _, userId := range group.users() {
+			members = append(members, store.MemberDocument{User: userId, Source: group.members[userId]})
+		}
+		document.Groups = append(document.Groups, store.GroupDocument{
+			Name:        group.name,
+			Description: group.Description,
+			Labels:      cloneLabels(group.Labels),
+			Members:     members,
+		})
+	}
+	for _, permission := range manager.sortedPermissions() {
+		implies := manager.permissionNames(permission.implies)
+		if len(implies) == 0 {
+			implies = nil
+		}
+		document.Permissions = append(document.Permissions, store.PermissionDocument{
+			Name:        permission.name,
+			Description: permission.Description,
+			Labels:      cloneLabels(permission.Labels),
+			Risk:        permission.risk,
+			Groups:      manager.grantedGroups(permission.id),
+			Implies:     implies,
+			Condition:   permission.condition,
+		})
+	}
+	document.Denials = manager.denials()
+
+	return document, nil
+}
+
+// Import replaces the whole policy with the given document at once: groups and permissions are
+// matched by name, so existing ones keep their ids and have their version bumped, those missing
+// from the document are deleted and leave their folders, and every membership, grant, implication and denial is replaced by
+// those of the document. An invalid document fails with an InvalidArgument error before the store is touched.
+func (manager *MemoryPolicyManager) Import(ctx context.Context, document *store.PolicyDocument) error {
+	logger := manager.logger.With("groups", len(document.Groups), "permissions", len(document.Permissions), "operation", "Import")
+
+	err := document.Validate()
+	if err != nil {
+		logger.Error("invalid policy document", "error", err)
+		return store.NewInvalidArgumentError()
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	groups := make(map[int]*group, len(document.Groups))
+	groupIds := make(map[string]int, len(document.Groups))
+	for _, entry := range document.Groups {
+		id := manager.groupNamed(entry.Name)
+		group := newGroup(entry.Name)
+		if id == 0 {
+			manager.lastGroupId++
+			id = manager.lastGroupId
+		} else {
+			group.version = manager.groups[id].version + 1
+		}
+		group.Description = entry.Description
+		group.Labels = cloneLabels(entry.Labels)
+		for _, member := range entry.Members {
+			// the document is validated, so the source parses
+			source, _ := store.ParseMembershipSource(string(member.Source))
+			group.members[member.User] = source
+		}
+		groups[id] = group
+		groupIds[entry.Name] = id
+	}
+
+	permissions := make(map[int]*permission, len(document.Permissions))
+	permissionIds := make(map[string]int, len(document.Permissions))
+	for _, entry := range document.Permissions {
+		id := manager.permissionNamed(entry.Name)
+		permission := newPermission(entry.Name)
+		if id == 0 {
+			manager.lastPermissionId++
+			id = manager.lastPermissionId
+		} else {
+			permission.version = manager.permissions[id].version + 1
+		}
+		permission.risk, _ = authz.ParseRiskLevel(string(entry.Risk))
+		permission.condition = entry.Condition
+		permission.Description = entry.Description
+		permission.Labels = cloneLabels(entry.Labels)
+		permissions[id] = permission
+		permissionIds[entry.Name] = id
+	}
+
+	// every name has an id once the groups and permissions are built
+	for _, entry := range document.Permissions {
+		id := permissionIds[entry.Name]
+		for _, groupName := range entry.Groups {
+			groups[groupIds[groupName]].grants.Add(id)
+		}
+		for _, implied := range entry.Implies {
+			permissions[id].implies.Add(permissionIds[implied])
+		}
+	}
+	for _, denial := range document.Denials {
+		permission := permissions[permissionIds[denial.Permission]]
+		for _, groupName := range denial.Groups {
+			permission.deniedGroups.Add(groupIds[groupName])
+		}
+		permission.deniedUsers.Add(denial.Users...)
+	}
+
+	manager.groups = groups
+	manager.permissions = permissions
+	manager.pruneFolders()
+	manager.revision++
+
+	logger.Info("policy imported")
+	return nil
+}
+
+// ListGroups returns all the groups ordered by name.
+func (manager *MemoryPolicyManager) ListGroups(ctx context.Context) ([]store.GroupInfo[int], error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	groups := []store.GroupInfo[int]{}
+	for _, group := range manager.sortedGroups() {
+		groups = append(groups, group.info(group.id))
+	}
+	return groups, nil
+}
+
+// GetGroups returns the groups with the given ids ordered by name.
+// Ids that match no group are left out of the result.
+func (manager *MemoryPolicyManager) GetGroups(ctx context.Context, ids []int) ([]store.GroupInfo[int], error) {
+	groups, err := manager.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(groups, func(group store.GroupInfo[int]) bool { return !slices.Contains(ids, group.ID) }), nil
+}
+
+// ListPermissions returns all the permissions ordered by name.
+func (manager *MemoryPolicyManager) ListPermissions(ctx context.Context) ([]store.PermissionInfo[int], error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	permissions := []store.PermissionInfo[int]{}
+	for _, permission := range manager.sortedPermissions() {
+		permissions = append(permissions, permission.info(permission.id))
+	}
+	return permissions, nil
+}
+
+// GetPermissions returns the permissions with the given ids ordered by name.
+// Ids that match no permission are left out of the result.
+func (manager *MemoryPolicyManager) GetPermissions(ctx context.Context, ids []int) ([]store.PermissionInfo[int], error) {
+	permissions, err := manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(permissions, func(permission store.PermissionInfo[int]) bool { return !slices.Contains(ids, permission.ID) }), nil
+}
+
+// ListGroupsPage returns a page of the groups ordered by id and the cursor of the next page,
+// empty for the last page.
+func (manager *MemoryPolicyManager) ListGroupsPage(ctx context.Context, page paging.Request) ([]store.GroupInfo[int], string, error) {
+	if _, err := store.AfterID(page.After); err != nil {
+		return nil, "", err
+	}
+	groups, err := manager.ListGroups(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return pageByID(groups, page, store.GroupKey)
+}
+
+// ListPermissionsPage returns a page of the permissions ordered by id and the cursor of the next page,
+// empty for the last page.
+func (manager *MemoryPolicyManager) ListPermissionsPage(ctx context.Context, page paging.Request) ([]store.PermissionInfo[int], string, error) {
+	if _, err := store.AfterID(page.After); err != nil {
+		return nil, "", err
+	}
+	permissions, err := manager.ListPermissions(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return pageByID(permissions, page, store.PermissionKey)
+}
+
+// pageByID returns the page of the items ordered by id. The first page resumes after id zero,
+// which orders the items by id even for a request without limit.
+func pageByID[T any](items []T, page paging.Request, key func(T) paging.Key) ([]T, string, error) {
+	if page.After == nil {
+		page.After = paging.Key{0}
+	}
+	return paging.Page(items, page, key)
+}
+
+// GetGroup returns the group with the specified id, with its members and the permissions granted to it.
+func (manager *MemoryPolicyManager) GetGroup(ctx context.Context, groupId int) (*store.GroupDetails[int, int, string], error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	group, ok := manager.groups[groupId]
+	if !ok {
+		manager.logger.Error("group not found", "group_id", groupId, "operation", "GetGroup")
+		return nil, store.NewGroupNotFoundError()
+	}
+
+	permissions := []store.PermissionInfo[int]{}
+	for _, permission := range manager.sortedPermissions() {
+		if group.grants.Contains(permission.id) {
+			permissions = append(permissions, permission.info(permission.id))
+		}
+	}
+	return &store.GroupDetails[int, int, string]{GroupInfo: group.info(groupId), Users: group.users(), Permissions: permissions}, nil
+}
+
+// GetUserGroups returns the groups the user is a member of ordered by name, none for an unknown user.
+func (manager *MemoryPolicyManager) GetUserGroups(ctx context.Context, userId string) ([]store.GroupInfo[int], error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	groups := []store.GroupInfo[int]{}
+	for _, group := range manager.sortedGroups() {
+		if _, ok := group.members[userId]; ok {
+			groups = append(groups, group.info(group.id))
+		}
+	}
+	return groups, nil
+}
+
+// Health always succeeds, the memory store has no database to reach and no schema version.
+func (manager *MemoryPolicyManager) Health(ctx context.Context) (*store.Health, error) {
+	return &store.Health{}, nil
+}
+
+// versioned changes a group or permission the way the Postgres manager does: the version is read
+// first, and the change fails with a Concurrency error when another change was made in between.
+// The change reports whether it changed anything, the version being bumped only when it did.
+func (manager *MemoryPolicyManager) versioned(logger *slog.Logger, version func() (*int, error), change func() (bool, error)) error {
+	manager.mu.RLock()
+	current, err := version()
+	var read int
+	if err == nil {
+		read = *current
+	}
+	manager.mu.RUnlock()
+	if err != nil {
+		logger.Error("failed to read version", "error", err)
+		return err
+	}
+
+	if manager.afterRead != nil {
+		manager.afterRead()
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	current, err = version()
+	if err != nil || *current != read {
+		logger.Error("failed to update version due to concurrency issue")
+		return store.NewConcurrencyError()
+	}
+
+	changed, err := change()
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return manager.noChanges(logger)
+	}
+
+	*current++
+	manager.revision++
+	return nil
+}
+
+// groupVersion returns the lookup of the version of the group for versioned.
+func (manager *MemoryPolicyManager) groupVersion(groupId int) func() (*int, error) {
+	return func() (*int, error) {
+		group, ok := manager.groups[groupId]
+		if !ok {
+			return nil, store.NewGroupNotFoundError()
+		}
+		return &group.version, nil
+	}
+}
+
+// permissionVersion returns the lookup of the version of the permission for versioned.
+func (manager *MemoryPolicyManager) permissionVersion(permissionId int) func() (*int, error) {
+	return func() (*int, error) {
+		permission, ok := manager.permissions[permissionId]
+		if !ok {
+			return nil, store.NewPermissionNotFoundError()
+		}
+		return &permission.version, nil
+	}
+}
+
+// addMember adds the user to the group or takes over the membership of a lower precedence source,
+// reporting whether the group changed.
+func (manager *MemoryPolicyManager) addMember(group *group, userId string, source store.MembershipSource) bool {
+	current, ok := group.members[userId]
+	if ok && !slices.Contains(manager.precedence.Overridden(source), current) {
+		return false
+	}
+	group.members[userId] = source
+	return true
+}
+
+// removeMember removes the user from the group unless a higher precedence source added them,
+// reporting whether the group changed.
+func (manager *MemoryPolicyManager) removeMember(group *group, userId string, source store.MembershipSource) bool {
+	current, ok := group.members[userId]
+	if !ok || slices.Contains(manager.precedence.Protected(source), current) {
+		return false
+	}
+	delete(group.members, userId)
+	return true
+}
+
+// groupNamed returns the id of the group with the given name, 0 when there is none.
+func (manager *MemoryPolicyManager) groupNamed(name string) int {
+	for id, group := range manager.groups {
+		if group.name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+// permissionNamed returns the id of the permission with the given name, 0 when there is none.
+func (manager *MemoryPolicyManager) permissionNamed(name string) int {
+	for id, permission := range manager.permissions {
+		if permission.name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+type groupEntry struct {
+	id int
+	*group
+}
+
+type permissionEntry struct {
+	id int
+	*permission
+}
+
+func (manager *MemoryPolicyManager) sortedGroups() []groupEntry {
+	entries := make([]groupEntry, 0, len(manager.groups))
+	for id, group := range manager.groups {
+		entries = append(entries, groupEntry{id: id, group: group})
+	}
+	slices.SortFunc(entries, func(a, b groupEntry) int { return cmp.Compare(a.name, b.name) })
+	return entries
+}
+
+func (manager *MemoryPolicyManager) sortedPermissions() []permissionEntry {
+	entries := make([]permissionEntry, 0, len(manager.permissions))
+	for id, permission := range manager.permissions {
+		entries = append(entries, permissionEntry{id: id, permission: permission})
+	}
+	slices.SortFunc(entries, func(a, b permissionEntry) int { return cmp.Compare(a.name, b.name) })
+	return entries
+}
+
+// grantedGroups returns the names of the groups granted the permission, sorted.
+func (manager *MemoryPolicyManager) grantedGroups(permissionId int) []string {
+	names := []string{}
+	for _, group := range manager.groups {
+		if group.grants.Contains(permissionId) {
+			names = append(names, group.name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// permissionNames returns the names of the permissions with the given ids, sorted.
+func (manager *MemoryPolicyManager) permissionNames(ids shared.Set[int]) []string {
+	names := []string{}
+	for id := range ids {
+		names = append(names, manager.permissions[id].name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// denials returns the denials of the permissions denied to any group or user, sorted by permission
+// with their groups and users sorted by name.
+func (manager *MemoryPolicyManager) denials() []store.DenialDocument {
+	var denials []store.DenialDocument
+	for _, permission := range manager.sortedPermissions() {
+		if permission.deniedGroups.Len() == 0 && permission.deniedUsers.Len() == 0 {
+			continue
+		}
+		denial := store.DenialDocument{Permission: permission.name}
+		for groupId := range permission.deniedGroups {
+			denial.Groups = append(denial.Groups, manager.groups[groupId].name)
+		}
+		slices.Sort(denial.Groups)
+		if permission.deniedUsers.Len() > 0 {
+			denial.Users = shared.Sorted(permission.deniedUsers)
+		}
+		denials = append(denials, denial)
+	}
+	return denials
+}
+
+// noChanges reports a mutating operation that left the store unchanged,
+// returning a NoChanges error when the manager is configured to do so.
+func (manager *MemoryPolicyManager) noChanges(logger *slog.Logger) error {
+	logger.Info("operation did not change anything")
+	if manager.reportNoChanges {
+		return store.NewNoChangesError()
+	}
+	return nil
+}
+
+func newGroup(name string) *group {
+	return &group{name: name, version: 1, members: map[string]store.MembershipSource{}, grants: shared.NewSet[int]()}
+}
+
+func newPermission(name string) *permission {
+	return &permission{
+		name: name, version: 1, risk: authz.RiskLow,
+		implies: shared.NewSet[int](), deniedGroups: shared.NewSet[int](), deniedUsers: shared.NewSet[string](),
+	}
+}
+
+// users returns the ids of the members of the group, sorted.
+func (group *group) users() []string {
+	users := make([]string, 0, len(group.members))
+	for userId := range group.members {
+		users = append(users, userId)
+	}
+	slices.Sort(users)
+	return users
+}
+
+func (group *group) info(id int) store.GroupInfo[int] {
+	metadata := store.Metadata{Description: group.Description, Labels: cloneLabels(group.Labels)}
+	return store.GroupInfo[int]{ID: id, Name: group.name, Version: group.version, Metadata: metadata}
+}
+
+func (permission *permission) info(id int) store.PermissionInfo[int] {
+	metadata := store.Metadata{Description: permission.Description, Labels: cloneLabels(permission.Labels)}
+	return store.PermissionInfo[int]{ID: id, Name: permission.name, Version: permission.version, Risk: permission.risk, Metadata: metadata}
+}
+
+// applyPatch applies the patch to the metadata with the semantics of a JSON merge patch.
+func applyPatch(metadata *store.Metadata, patch store.MetadataPatch) {
+	if patch.Description != nil {
+		metadata.Description = *patch.Description
+	}
+	if patch.ClearLabels {
+		metadata.Labels = nil
+	}
+	set, removed := patch.LabelChanges()
+	if metadata.Labels == nil {
+		metadata.Labels = map[string]string{}
+	}
+	maps.Copy(metadata.Labels, set)
+	for _, key := range removed {
+		delete(metadata.Labels, key)
+	}
+}
+
+// cloneLabels copies the labels so callers cannot change the stored ones, nil when there are none.
+func cloneLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return maps.Clone(labels)
+}
@@ -0,0 +1,407 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/store/storetest"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+)
+
+func newManager(options ...Option) *MemoryPolicyManager {
+	return NewMemoryPolicyManager(slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+}
+
+func assertPolicyStoreError(t *testing.T, err error, exp *store.PolicyStoreError) {
+	t.Helper()
+	var policyStoreError *store.PolicyStoreError
+	if assert.ErrorAs(t, err, &policyStoreError) {
+		assert.Equal(t, exp.Code, policyStoreError.Code)
+	}
+}
+
+// seed creates the groups and permissions of a small policy and returns their ids.
+func seed(t *testing.T, manager *MemoryPolicyManager) (int, int, int, int) {
+	t.Helper()
+	ctx := context.Background()
+	chefs, err := manager.CreateGroup(ctx, "chefs")
+	assert.NoError(t, err)
+	cooks, err := manager.CreateGroup(ctx, "cooks")
+	assert.NoError(t, err)
+	read, err := manager.CreatePermission(ctx, "recipes.read")
+	assert.NoError(t, err)
+	write, err := manager.CreatePermission(ctx, "recipes.write")
+	assert.NoError(t, err)
+	return chefs, cooks, read, write
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, cooks, read, write := seed(t, manager)
+	assert.Equal(t, []int{1, 2}, []int{chefs, cooks})
+	assert.Equal(t, []int{1, 2}, []int{read, write})
+
+	_, err := manager.CreateGroup(ctx, "chefs")
+	assertPolicyStoreError(t, err, store.NewNameExistsError())
+	_, err = manager.CreatePermission(ctx, "recipes.read")
+	assertPolicyStoreError(t, err, store.NewNameExistsError())
+
+	groups, err := manager.ListGroups(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupInfo[int]{{ID: chefs, Name: "chefs", Version: 1}, {ID: cooks, Name: "cooks", Version: 1}}, groups)
+
+	permissions, err := manager.GetPermissions(ctx, []int{write, 42})
+	assert.NoError(t, err)
+	assert.Equal(t, []store.PermissionInfo[int]{{ID: write, Name: "recipes.write", Version: 1, Risk: authz.RiskLow}}, permissions)
+}
+
+// TestReads pages through the groups and permissions and reads a group with its members and
+// permissions and the groups of a user.
+func TestReads(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, cooks, read, write := seed(t, manager)
+	// created last, but listed first by name
+	bakers, err := manager.CreateGroup(ctx, "bakers")
+	assert.NoError(t, err)
+	assert.NoError(t, manager.UpdateGroupPermissions(ctx, cooks, []int{write, read}))
+	assert.NoError(t, manager.UpdateGroupUsers(ctx, cooks, []string{"bob", "alice"}))
+	assert.NoError(t, manager.AddGroupUser(ctx, bakers, "alice"))
+
+	groups, next, err := manager.ListGroupsPage(ctx, paging.Request{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{chefs, cooks}, []int{groups[0].ID, groups[1].ID})
+	assert.Equal(t, paging.Encode(paging.Key{cooks}), next)
+	groups, next, err = manager.ListGroupsPage(ctx, paging.Request{Limit: 2, After: paging.Key{cooks}})
+	assert.NoError(t, err)
+	assert.Equal(t, bakers, groups[0].ID)
+	assert.Empty(t, next)
+	_, _, err = manager.ListGroupsPage(ctx, paging.Request{Limit: 2, After: paging.Key{"cooks"}})
+	assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+	permissions, next, err := manager.ListPermissionsPage(ctx, paging.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{read, write}, []int{permissions[0].ID, permissions[1].ID})
+	assert.Empty(t, next)
+
+	group, err := manager.GetGroup(ctx, cooks)
+	assert.NoError(t, err)
+	assert.Equal(t, &store.GroupDetails[int, int, string]{
+		GroupInfo: store.GroupInfo[int]{ID: cooks, Name: "cooks", Version: 3},
+		Users:     []string{"alice", "bob"},
+		Permissions: []store.PermissionInfo[int]{
+			{ID: read, Name: "recipes.read", Version: 1, Risk: authz.RiskLow},
+			{ID: write, Name: "recipes.write", Version: 1, Risk: authz.RiskLow},
+		},
+	}, group)
+	_, err = manager.GetGroup(ctx, 42)
+	assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+
+	groups, err = manager.GetUserGroups(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bakers", "cooks"}, []string{groups[0].Name, groups[1].Name})
+	groups, err = manager.GetUserGroups(ctx, "carol")
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestUpdateGroupPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("bumps the version", func(t *testing.T) {
+		manager := newManager()
+		chefs, _, read, write := seed(t, manager)
+
+		assert.NoError(t, manager.UpdateGroupPermissions(ctx, chefs, []int{read, write, read}))
+		assert.NoError(t, manager.GrantPermission(ctx, chefs, read))
+
+		groups, _ := manager.GetGroups(ctx, []int{chefs})
+		assert.Equal(t, 2, groups[0].Version)
+		policy, _ := manager.ReadPolicy(ctx)
+		assert.Equal(t, []string{"chefs"}, policy.Permissions[0].Groups)
+		assert.Equal(t, []string{"chefs"}, policy.Permissions[1].Groups)
+	})
+
+	t.Run("no changes error", func(t *testing.T) {
+		manager := newManager(WithNoChangesError())
+		chefs, _, read, _ := seed(t, manager)
+
+		assert.NoError(t, manager.GrantPermission(ctx, chefs, read))
+		assertPolicyStoreError(t, manager.UpdateGroupPermissions(ctx, chefs, []int{read}), store.NewNoChangesError())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		manager := newManager()
+		chefs, _, _, _ := seed(t, manager)
+
+		assertPolicyStoreError(t, manager.UpdateGroupPermissions(ctx, 42, []int{}), store.NewGroupNotFoundError())
+		assertPolicyStoreError(t, manager.UpdateGroupPermissions(ctx, chefs, []int{42}), store.NewPermissionNotFoundError())
+		assertPolicyStoreError(t, manager.GrantPermission(ctx, chefs, 42), store.NewPermissionNotFoundError())
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		manager := newManager()
+		chefs, _, read, _ := seed(t, manager)
+		manager.afterRead = func() {
+			manager.afterRead = nil
+			assert.NoError(t, manager.ChangeGroupName(ctx, chefs, "head chefs"))
+		}
+
+		assertPolicyStoreError(t, manager.UpdateGroupPermissions(ctx, chefs, []int{read}), store.NewConcurrencyError())
+		policy, _ := manager.ReadPolicy(ctx)
+		assert.Empty(t, policy.Permissions[0].Groups)
+	})
+}
+
+func TestConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, _, _, _ := seed(t, manager)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- manager.AddGroupUser(ctx, chefs, string(rune('a'+i)))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		if err == nil {
+			added++
+			continue
+		}
+		assert.True(t, store.IsConcurrency(err))
+	}
+	groups, _ := manager.GetGroups(ctx, []int{chefs})
+	policy, _ := manager.ReadPolicy(ctx)
+	assert.Len(t, policy.Groups[0].Users, added)
+	assert.Equal(t, 1+added, groups[0].Version)
+}
+
+func TestGroupUsers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("update and delete", func(t *testing.T) {
+		manager := newManager()
+		chefs, cooks, _, _ := seed(t, manager)
+
+		assert.NoError(t, manager.UpdateGroupUsers(ctx, chefs, []string{" bob ", "alice", "bob"}))
+		assert.NoError(t, manager.UpdateUserGroups(ctx, "carol", []int{chefs, cooks}))
+		_, err := store.NormalizeUserId(" ")
+		assert.Equal(t, err, manager.AddGroupUser(ctx, chefs, " "))
+		assertPolicyStoreError(t, manager.UpdateUserGroups(ctx, "carol", []int{42}), store.NewGroupNotFoundError())
+
+		policy, _ := manager.ReadPolicy(ctx)
+		assert.Equal(t, []authz.Group{{Name: "chefs", Users: []string{"alice", "bob", "carol"}}, {Name: "cooks", Users: []string{"carol"}}}, policy.Groups)
+
+		assert.NoError(t, manager.DeleteUser(ctx, "carol"))
+		assertPolicyStoreError(t, manager.DeleteUser(ctx, "carol"), store.NewNoUserRecordsDeletedError())
+		policy, _ = manager.ReadPolicy(ctx)
+		assert.Equal(t, []string{}, policy.Groups[1].Users)
+	})
+
+	t.Run("source precedence", func(t *testing.T) {
+		manager := newManager(WithSourcePrecedence(store.SourcePrecedence{store.SourceSCIM, store.SourceManual}))
+		chefs, _, _, _ := seed(t, manager)
+		scim := store.WithMembershipSource(ctx, store.SourceSCIM)
+
+		assert.NoError(t, manager.UpdateGroupUsers(scim, chefs, []string{"alice"}))
+		assert.NoError(t, manager.UpdateGroupUsers(ctx, chefs, []string{"bob"}))
+		assert.NoError(t, manager.UpdateGroupUsers(scim, chefs, []string{"alice", "bob"}))
+
+		document, _ := manager.Export(ctx)
+		assert.Equal(t, []store.MemberDocument{{User: "alice", Source: store.SourceSCIM}, {User: "bob", Source: store.SourceSCIM}}, document.Groups[0].Members)
+	})
+}
+
+func TestDeleteGroup(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, _, read, write := seed(t, manager)
+	assert.NoError(t, manager.UpdateGroupUsers(ctx, chefs, []string{"alice"}))
+	assert.NoError(t, manager.UpdateGroupPermissions(ctx, chefs, []int{read, write}))
+
+	deletion, err := manager.DeleteGroup(ctx, chefs)
+	assert.NoError(t, err)
+	assert.Equal(t, &store.GroupDeletion{Members: 1, Grants: 2}, deletion)
+
+	_, err = manager.DeleteGroup(ctx, chefs)
+	assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+	id, err := manager.CreateGroup(ctx, "chefs")
+	assert.NoError(t, err)
+	assert.NotEqual(t, chefs, id)
+}
+
+func TestChangeGroupName(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager(WithNoChangesError())
+	chefs, _, _, _ := seed(t, manager)
+
+	assertPolicyStoreError(t, manager.ChangeGroupName(ctx, chefs, "cooks"), store.NewNameExistsError())
+	assertPolicyStoreError(t, manager.ChangeGroupName(ctx, chefs, "chefs"), store.NewNoChangesError())
+	assert.NoError(t, manager.ChangeGroupName(ctx, chefs, "head chefs"))
+}
+
+func TestPermissions(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	_, _, read, write := seed(t, manager)
+	admin, _ := manager.CreatePermission(ctx, "recipes.admin")
+
+	assert.NoError(t, manager.SetPermissionRisk(ctx, admin, authz.RiskHigh))
+	assertPolicyStoreError(t, manager.SetPermissionRisk(ctx, admin, "extreme"), store.NewInvalidArgumentError())
+	assertPolicyStoreError(t, manager.SetPermissionRisk(ctx, 42, authz.RiskHigh), store.NewPermissionNotFoundError())
+
+	assert.NoError(t, manager.SetPermissionImplications(ctx, admin, []int{write}))
+	assert.NoError(t, manager.SetPermissionImplications(ctx, write, []int{read}))
+	assertPolicyStoreError(t, manager.SetPermissionImplications(ctx, read, []int{admin}), store.NewInvalidArgumentError())
+	assertPolicyStoreError(t, manager.SetPermissionImplications(ctx, read, []int{read}), store.NewInvalidArgumentError())
+	assertPolicyStoreError(t, manager.SetPermissionImplications(ctx, read, []int{42}), store.NewPermissionNotFoundError())
+
+	policy, _ := manager.ReadPolicy(ctx)
+	assert.Equal(t, []authz.Permission{
+		{Name: "recipes.admin", Groups: []string{}, Risk: authz.RiskHigh, Implies: []string{"recipes.write"}},
+		{Name: "recipes.read", Groups: []string{}, Risk: authz.RiskLow},
+		{Name: "recipes.write", Groups: []string{}, Risk: authz.RiskLow, Implies: []string{"recipes.read"}},
+	}, policy.Permissions)
+
+	permissions, _ := manager.GetPermissions(ctx, []int{admin})
+	assert.Equal(t, 3, permissions[0].Version)
+}
+
+func TestSetPermissionDenials(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager(WithNoChangesError())
+	chefs, cooks, read, write := seed(t, manager)
+
+	assert.NoError(t, manager.SetPermissionDenials(ctx, write, []int{cooks, chefs, cooks}, []string{" mallory", "carol"}))
+	assertPolicyStoreError(t, manager.SetPermissionDenials(ctx, write, []int{chefs, cooks}, []string{"carol", "mallory"}), store.NewNoChangesError())
+	assertPolicyStoreError(t, manager.SetPermissionDenials(ctx, write, []int{42}, nil), store.NewGroupNotFoundError())
+	assertPolicyStoreError(t, manager.SetPermissionDenials(ctx, 42, nil, []string{"carol"}), store.NewPermissionNotFoundError())
+	assert.Error(t, manager.SetPermissionDenials(ctx, read, nil, []string{" "}))
+
+	policy, _ := manager.ReadPolicy(ctx)
+	assert.Equal(t, []authz.Denial{{Permission: "recipes.write", Groups: []string{"chefs", "cooks"}, Users: []string{"carol", "mallory"}}}, policy.Denials)
+
+	// deleting a group deletes its denials, and a permission denied to nobody has no denial
+	_, err := manager.DeleteGroup(ctx, chefs)
+	assert.NoError(t, err)
+	policy, _ = manager.ReadPolicy(ctx)
+	assert.Equal(t, []string{"cooks"}, policy.Denials[0].Groups)
+	assert.NoError(t, manager.SetPermissionDenials(ctx, write, nil, nil))
+	policy, _ = manager.ReadPolicy(ctx)
+	assert.Empty(t, policy.Denials)
+}
+
+func TestSetPermissionCondition(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager(WithNoChangesError())
+	_, _, read, write := seed(t, manager)
+
+	assert.NoError(t, manager.SetPermissionCondition(ctx, write, " resource.owner == user "))
+	assertPolicyStoreError(t, manager.SetPermissionCondition(ctx, write, "resource.owner == user"), store.NewNoChangesError())
+	assertPolicyStoreError(t, manager.SetPermissionCondition(ctx, write, "resource.owner =="), store.NewInvalidArgumentError())
+	assertPolicyStoreError(t, manager.SetPermissionCondition(ctx, 42, "tenant == \"acme\""), store.NewPermissionNotFoundError())
+
+	policy, _ := manager.ReadPolicy(ctx)
+	assert.Equal(t, "resource.owner == user", policy.Permissions[1].Condition)
+	assert.Empty(t, policy.Permissions[0].Condition)
+	permissions, _ := manager.GetPermissions(ctx, []int{write})
+	assert.Equal(t, 2, permissions[0].Version)
+
+	// an empty condition makes the grants unconditional again
+	assert.NoError(t, manager.SetPermissionCondition(ctx, write, ""))
+	policy, _ = manager.ReadPolicy(ctx)
+	assert.Empty(t, policy.Permissions[1].Condition)
+	assertPolicyStoreError(t, manager.SetPermissionCondition(ctx, read, ""), store.NewNoChangesError())
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, _, read, _ := seed(t, manager)
+	description, team := "Head chefs", "kitchen"
+
+	group, err := manager.UpdateGroupMetadata(ctx, chefs, store.MetadataPatch{Description: &description, Labels: map[string]*string{"team": &team, "old": nil}})
+	assert.NoError(t, err)
+	assert.Equal(t, store.Metadata{Description: "Head chefs", Labels: map[string]string{"team": "kitchen"}}, group.Metadata)
+	assert.Equal(t, 1, group.Version)
+
+	group, err = manager.UpdateGroupMetadata(ctx, chefs, store.MetadataPatch{ClearLabels: true})
+	assert.NoError(t, err)
+	assert.Equal(t, store.Metadata{Description: "Head chefs"}, group.Metadata)
+
+	_, err = manager.UpdatePermissionMetadata(ctx, read, store.MetadataPatch{Labels: map[string]*string{" ": &team}})
+	assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+	_, err = manager.UpdatePermissionMetadata(ctx, 42, store.MetadataPatch{})
+	assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	document := &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: "chefs", Description: "Head chefs", Labels: map[string]string{"team": "kitchen"}, Members: []store.MemberDocument{{User: "alice", Source: store.SourceLDAP}}},
+			{Name: "guests", Members: []store.MemberDocument{}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes.read", Risk: authz.RiskLow, Groups: []string{"chefs", "guests"}},
+			{Name: "recipes.write", Risk: authz.RiskMedium, Groups: []string{"chefs"}, Implies: []string{"recipes.read"}, Condition: "resource.owner == user"},
+		},
+		Denials: []store.DenialDocument{
+			{Permission: "recipes.write", Groups: []string{"guests"}, Users: []string{"mallory"}},
+		},
+	}
+
+	manager := newManager()
+	chefs, cooks, _, write := seed(t, manager)
+	assert.NoError(t, manager.Import(ctx, document))
+
+	exported, err := manager.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, document, exported)
+
+	// existing groups and permissions keep their ids and have their version bumped
+	groups, _ := manager.ListGroups(ctx)
+	assert.Equal(t, chefs, groups[0].ID)
+	assert.Equal(t, 2, groups[0].Version)
+	assert.NotContains(t, []int{groups[0].ID, groups[1].ID}, cooks)
+	permissions, _ := manager.GetPermissions(ctx, []int{write})
+	assert.Equal(t, 2, permissions[0].Version)
+
+	invalid := &store.PolicyDocument{Groups: []store.GroupDocument{{Name: "chefs"}, {Name: "chefs"}}}
+	assertPolicyStoreError(t, manager.Import(ctx, invalid), store.NewInvalidArgumentError())
+}
+
+func TestPolicyRevision(t *testing.T) {
+	ctx := context.Background()
+	manager := newManager()
+	chefs, _, read, _ := seed(t, manager)
+
+	revision, err := manager.PolicyRevision(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), revision)
+
+	assert.NoError(t, manager.GrantPermission(ctx, chefs, read))
+	assert.NoError(t, manager.GrantPermission(ctx, chefs, read))
+	revision, _ = manager.PolicyRevision(ctx)
+	assert.Equal(t, int64(5), revision)
+}
+
+func TestFolders(t *testing.T) {
+	manager := newManager()
+	storetest.TestFolders(t, manager, NewFolderStore(manager))
+}
@@ -0,0 +1,45 @@
+package store
+
+import "strings"
+
+// Metadata describes a group or permission to the people managing the policy. It is not part
+// of the policy, so changing it leaves the version of the group or permission unchanged.
+type Metadata struct {
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// MetadataPatch is a partial update of Metadata with the semantics of a JSON merge patch
+// (RFC 7396), so clients change single fields without resending the whole metadata.
+type MetadataPatch struct {
+	// The new description, left unchanged when nil.
+	Description *string
+	// Remove every label before applying Labels.
+	ClearLabels bool
+	// The labels to set, or to remove when the value is nil.
+	Labels map[string]*string
+}
+
+// Validate checks the patch sets no label with an empty key.
+func (patch MetadataPatch) Validate() error {
+	for key := range patch.Labels {
+		if strings.TrimSpace(key) == "" {
+			return NewInvalidArgumentError()
+		}
+	}
+	return nil
+}
+
+// LabelChanges splits the labels of the patch into those to set and the keys to remove.
+func (patch MetadataPatch) LabelChanges() (map[string]string, []string) {
+	set := map[string]string{}
+	removed := []string{}
+	for key, value := range patch.Labels {
+		if value == nil {
+			removed = append(removed, key)
+			continue
+		}
+		set[key] = *value
+	}
+	return set, removed
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataPatch_LabelChanges(t *testing.T) {
+	kitchen := "kitchen"
+	patch := MetadataPatch{Labels: map[string]*string{"team": &kitchen, "legacy": nil}}
+
+	set, removed := patch.LabelChanges()
+	assert.Equal(t, map[string]string{"team": "kitchen"}, set)
+	assert.Equal(t, []string{"legacy"}, removed)
+}
+
+func TestMetadataPatch_Validate(t *testing.T) {
+	kitchen := "kitchen"
+
+	assert.NoError(t, MetadataPatch{Labels: map[string]*string{"team": &kitchen}}.Validate())
+	assert.Equal(t, NewInvalidArgumentError(), MetadataPatch{Labels: map[string]*string{"": &kitchen}}.Validate())
+}
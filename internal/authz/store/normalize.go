@@ -0,0 +1,53 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// NormalizeUserId trims surrounding whitespace from the user id.
+// An InvalidArgument error is returned when nothing is left.
+func NormalizeUserId(userId string) (string, error) {
+	userId = strings.TrimSpace(userId)
+	if userId == "" {
+		return "", NewInvalidArgumentError()
+	}
+	return userId, nil
+}
+
+// NormalizeUserIds trims every user id and removes duplicates, keeping the first occurrence.
+// A nil slice is returned as an empty one. An InvalidArgument error is returned when any
+// user id is empty, rather than silently dropping it.
+func NormalizeUserIds(userIds []string) ([]string, error) {
+	normalized := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		userId, err := NormalizeUserId(userId)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, userId)
+	}
+	return shared.Distinct(normalized), nil
+}
+
+// NormalizeIds removes duplicate ids, keeping the first occurrence.
+// A nil slice is returned as an empty one.
+func NormalizeIds[TId comparable](ids []TId) []TId {
+	return shared.Distinct(ids)
+}
+
+// NormalizeCondition trims surrounding whitespace from the condition of a permission, see
+// authz.Permission.Condition. An empty condition is kept, and an InvalidArgument error is
+// returned when the condition does not compile.
+func NormalizeCondition(source string) (string, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return "", nil
+	}
+	if _, err := condition.Compile(source); err != nil {
+		return "", NewInvalidArgumentError()
+	}
+	return source, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUserId(t *testing.T) {
+	userId, err := NormalizeUserId("  alice\t")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", userId)
+
+	for _, invalid := range []string{"", "   ", "\n"} {
+		_, err = NormalizeUserId(invalid)
+		assert.Equal(t, NewInvalidArgumentError(), err)
+	}
+}
+
+func TestNormalizeUserIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		userIds  []string
+		expected []string
+	}{
+		{name: "nil", userIds: nil, expected: []string{}},
+		{name: "duplicates", userIds: []string{"bob", "alice", "bob"}, expected: []string{"bob", "alice"}},
+		{name: "whitespace duplicates", userIds: []string{"alice", " alice "}, expected: []string{"alice"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, err := NormalizeUserIds(test.userIds)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, normalized)
+		})
+	}
+}
+
+func TestNormalizeUserIds_Error_Empty(t *testing.T) {
+	normalized, err := NormalizeUserIds([]string{"alice", " "})
+	assert.Equal(t, NewInvalidArgumentError(), err)
+	assert.Nil(t, normalized)
+}
+
+func TestNormalizeIds(t *testing.T) {
+	assert.Equal(t, []int{}, NormalizeIds[int](nil))
+	assert.Equal(t, []int{3, 1, 2}, NormalizeIds([]int{3, 1, 3, 2, 1}))
+}
+
+func TestNormalizeCondition(t *testing.T) {
+	condition, err := NormalizeCondition(" resource.owner == user\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "resource.owner == user", condition)
+
+	condition, err = NormalizeCondition("  ")
+	assert.NoError(t, err)
+	assert.Empty(t, condition)
+
+	_, err = NormalizeCondition("resource.owner ==")
+	assert.Equal(t, NewInvalidArgumentError(), err)
+}
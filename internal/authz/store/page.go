@@ -0,0 +1,66 @@
+package store
+
+import (
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// The stores page groups and permissions by id, the key the API uses for its own pages,
+// so a cursor returned by either resumes the same list.
+
+// GroupKey returns the paging key of a group.
+func GroupKey(group GroupInfo[int]) paging.Key {
+	return paging.Key{group.ID}
+}
+
+// PermissionKey returns the paging key of a permission.
+func PermissionKey(permission PermissionInfo[int]) paging.Key {
+	return paging.Key{permission.ID}
+}
+
+// AfterID returns the id a page of groups or permissions resumes after, zero for the first page,
+// or an InvalidArgument error when the key was not returned by a list of groups or permissions.
+func AfterID(after paging.Key) (int, error) {
+	if after == nil {
+		return 0, nil
+	}
+	if len(after) != 1 {
+		return 0, NewInvalidArgumentError()
+	}
+	id, ok := after[0].(int)
+	if !ok {
+		return 0, NewInvalidArgumentError()
+	}
+	return id, nil
+}
+
+// PolicyFilter selects the groups of a policy page. The zero filter selects every group.
+type PolicyFilter struct {
+	// GroupPrefix keeps the groups whose name starts with the prefix.
+	GroupPrefix string
+	// User keeps the groups the user is a member of.
+	User string
+}
+
+// Policy pages are ordered by group name, the order ReadPolicy returns the groups in.
+
+// PolicyGroupKey returns the paging key of a group of a policy page.
+func PolicyGroupKey(group authz.Group) paging.Key {
+	return paging.Key{group.Name}
+}
+
+// AfterName returns the group name a policy page resumes after, empty for the first page,
+// or an InvalidArgument error when the key was not returned by a policy page.
+func AfterName(after paging.Key) (string, error) {
+	if after == nil {
+		return "", nil
+	}
+	if len(after) != 1 {
+		return "", NewInvalidArgumentError()
+	}
+	name, ok := after[0].(string)
+	if !ok {
+		return "", NewInvalidArgumentError()
+	}
+	return name, nil
+}
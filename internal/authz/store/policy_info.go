@@ -0,0 +1,51 @@
+package store
+
+import (
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+)
+
+// GroupInfo describes a stored group without its members and permissions.
+type GroupInfo[TGroupId any] struct {
+	ID      TGroupId `json:"id"`
+	Name    string   `json:"name"`
+	Version int      `json:"version"`
+	Metadata
+}
+
+// PermissionInfo describes a stored permission without the groups it is granted to.
+type PermissionInfo[TPermissionId any] struct {
+	ID      TPermissionId   `json:"id"`
+	Name    string          `json:"name"`
+	Version int             `json:"version"`
+	Risk    authz.RiskLevel `json:"risk"`
+	Metadata
+}
+
+// GroupDetails describes a stored group with its members and the permissions granted to it.
+type GroupDetails[TGroupId any, TPermissionId any, TUserId any] struct {
+	GroupInfo[TGroupId]
+	// The members of the group, sorted.
+	Users []TUserId `json:"users"`
+	// The permissions granted to the group, ordered by name.
+	Permissions []PermissionInfo[TPermissionId] `json:"permissions"`
+}
+
+// GroupDeletion reports the dependent rows removed together with a group.
+type GroupDeletion struct {
+	// The number of group memberships removed.
+	Members int `json:"members"`
+	// The number of permission grants removed.
+	Grants int `json:"grants"`
+}
+
+// Health describes a successful health check of the policy store.
+type Health struct {
+	// The time the store took to answer the health check.
+	Latency time.Duration `json:"latency"`
+	// The version of the store schema.
+	SchemaVersion int `json:"schema_version"`
+	// Whether the store is serving a read-only copy of the policy instead of the database.
+	Degraded bool `json:"degraded,omitempty"`
+}
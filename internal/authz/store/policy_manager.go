@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
 )
 
 // PolicyManager defines the operations needed to manage the policy store.
@@ -11,10 +12,29 @@ type PolicyManager[TGroupId any, TPermissionId any, TUserId any] interface {
 	UpdateGroupPermissions(ctx context.Context, groupId TGroupId, permissions []TPermissionId) error
 	UpdateGroupUsers(ctx context.Context, groupId TGroupId, users []TUserId) error
 	UpdateUserGroups(ctx context.Context, userId TUserId, groups []TGroupId) error
+	AddGroupUser(ctx context.Context, groupId TGroupId, userId TUserId) error
 	CreateGroup(ctx context.Context, groupName string) (TGroupId, error)
 	CreatePermission(ctx context.Context, permissionName string) (TPermissionId, error)
-	DeleteGroup(ctx context.Context, groupId TGroupId) error
+	SetPermissionRisk(ctx context.Context, permissionId TPermissionId, risk authz.RiskLevel) error
+	SetPermissionImplications(ctx context.Context, permissionId TPermissionId, implied []TPermissionId) error
+	SetPermissionDenials(ctx context.Context, permissionId TPermissionId, groups []TGroupId, users []TUserId) error
+	SetPermissionCondition(ctx context.Context, permissionId TPermissionId, condition string) error
+	GrantPermission(ctx context.Context, groupId TGroupId, permissionId TPermissionId) error
+	DeleteGroup(ctx context.Context, groupId TGroupId) (*GroupDeletion, error)
 	ChangeGroupName(ctx context.Context, groupId TGroupId, newGroupName string) error
+	UpdateGroupMetadata(ctx context.Context, groupId TGroupId, patch MetadataPatch) (*GroupInfo[TGroupId], error)
+	UpdatePermissionMetadata(ctx context.Context, permissionId TPermissionId, patch MetadataPatch) (*PermissionInfo[TPermissionId], error)
 	DeleteUser(ctx context.Context, userId TUserId) error
 	ReadPolicy(ctx context.Context) (*authz.Policy, error)
+	Export(ctx context.Context) (*PolicyDocument, error)
+	Import(ctx context.Context, document *PolicyDocument) error
+	ListGroups(ctx context.Context) ([]GroupInfo[TGroupId], error)
+	ListPermissions(ctx context.Context) ([]PermissionInfo[TPermissionId], error)
+	GetGroups(ctx context.Context, ids []TGroupId) ([]GroupInfo[TGroupId], error)
+	GetPermissions(ctx context.Context, ids []TPermissionId) ([]PermissionInfo[TPermissionId], error)
+	ListGroupsPage(ctx context.Context, page paging.Request) ([]GroupInfo[TGroupId], string, error)
+	ListPermissionsPage(ctx context.Context, page paging.Request) ([]PermissionInfo[TPermissionId], string, error)
+	GetGroup(ctx context.Context, groupId TGroupId) (*GroupDetails[TGroupId, TPermissionId, TUserId], error)
+	GetUserGroups(ctx context.Context, userId TUserId) ([]GroupInfo[TGroupId], error)
+	Health(ctx context.Context) (*Health, error)
 }
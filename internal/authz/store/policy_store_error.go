@@ -1,5 +1,10 @@
 package store
 
+import (
+	"errors"
+	"fmt"
+)
+
 type ErrorCode int
 
 const (
@@ -9,6 +14,11 @@ const (
 	NameAlreadyExist
 	NoUserRecordsDeleted
 	DatabaseError
+	PermissionNotFound
+	InvalidArgument
+	NoChanges
+	SchemaMismatch
+	ReadOnly
 )
 
 type ErrordDescription string
@@ -20,6 +30,11 @@ const (
 	nameAlreadyExistsDescription    = "The name already exists"
 	noUserRecordsDeletedDescription = "No user records were deleted"
 	databaseErrorDescription        = "An error occurred while interacting with the database"
+	permissionNotFoundDescription   = "The permission was not found"
+	invalidArgumentDescription      = "The operation received an invalid argument"
+	noChangesDescription            = "The operation did not change anything"
+	schemaMismatchDescription       = "The database schema does not match the expected schema"
+	readOnlyDescription             = "The policy store is read-only"
 )
 
 // PolicyError represents an error that occurred during the policy store operations.
@@ -75,3 +90,71 @@ func NewDataBaseError() *PolicyStoreError {
 		Description: databaseErrorDescription,
 	}
 }
+
+func NewPermissionNotFoundError() *PolicyStoreError {
+	return &PolicyStoreError{
+		Code:        PermissionNotFound,
+		Description: permissionNotFoundDescription,
+	}
+}
+
+func NewInvalidArgumentError() *PolicyStoreError {
+	return &PolicyStoreError{
+		Code:        InvalidArgument,
+		Description: invalidArgumentDescription,
+	}
+}
+
+func NewNoChangesError() *PolicyStoreError {
+	return &PolicyStoreError{
+		Code:        NoChanges,
+		Description: noChangesDescription,
+	}
+}
+
+func NewSchemaMismatchError() *PolicyStoreError {
+	return &PolicyStoreError{
+		Code:        SchemaMismatch,
+		Description: schemaMismatchDescription,
+	}
+}
+
+func NewReadOnlyError() *PolicyStoreError {
+	return &PolicyStoreError{
+		Code:        ReadOnly,
+		Description: readOnlyDescription,
+	}
+}
+
+// IsNoChanges reports whether the error signals an operation that left the store unchanged.
+func IsNoChanges(err error) bool {
+	var storeErr *PolicyStoreError
+	return errors.As(err, &storeErr) && storeErr.Code == NoChanges
+}
+
+// IsConcurrency reports whether the error signals a change that raced with another one.
+func IsConcurrency(err error) bool {
+	var storeErr *PolicyStoreError
+	return errors.As(err, &storeErr) && storeErr.Code == Concurrency
+}
+
+// SchemaVersionError is returned when the database schema version is outside the range
+// supported by the running binary. It unwraps to a SchemaMismatch PolicyStoreError.
+type SchemaVersionError struct {
+	Found int
+	Min   int
+	Max   int
+}
+
+// Error describes the mismatch and how to resolve it.
+func (e *SchemaVersionError) Error() string {
+	if e.Found > e.Max {
+		return fmt.Sprintf("database schema version %d is newer than the supported versions %d to %d, upgrade the binary", e.Found, e.Min, e.Max)
+	}
+	return fmt.Sprintf("database schema version %d is older than the supported versions %d to %d, migrate the database", e.Found, e.Min, e.Max)
+}
+
+// Unwrap returns the matching PolicyStoreError.
+func (e *SchemaVersionError) Unwrap() error {
+	return NewSchemaMismatchError()
+}
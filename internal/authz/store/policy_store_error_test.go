@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,6 +57,41 @@ func TestPolicyStoreError_Error(t *testing.T) {
 			expectedDescription: databaseErrorDescription,
 			expectedCode:        DatabaseError,
 		},
+		{
+			name:                "PermissionNotFoundError",
+			err:                 NewPermissionNotFoundError(),
+			expectedMsg:         string(permissionNotFoundDescription),
+			expectedDescription: permissionNotFoundDescription,
+			expectedCode:        PermissionNotFound,
+		},
+		{
+			name:                "InvalidArgumentError",
+			err:                 NewInvalidArgumentError(),
+			expectedMsg:         string(invalidArgumentDescription),
+			expectedDescription: invalidArgumentDescription,
+			expectedCode:        InvalidArgument,
+		},
+		{
+			name:                "NoChangesError",
+			err:                 NewNoChangesError(),
+			expectedMsg:         string(noChangesDescription),
+			expectedDescription: noChangesDescription,
+			expectedCode:        NoChanges,
+		},
+		{
+			name:                "SchemaMismatchError",
+			err:                 NewSchemaMismatchError(),
+			expectedMsg:         string(schemaMismatchDescription),
+			expectedDescription: schemaMismatchDescription,
+			expectedCode:        SchemaMismatch,
+		},
+		{
+			name:                "ReadOnlyError",
+			err:                 NewReadOnlyError(),
+			expectedMsg:         string(readOnlyDescription),
+			expectedDescription: readOnlyDescription,
+			expectedCode:        ReadOnly,
+		},
 	}
 
 	for _, tt := range tests {
@@ -66,3 +102,29 @@ func TestPolicyStoreError_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNoChanges(t *testing.T) {
+	assert.True(t, IsNoChanges(NewNoChangesError()))
+	assert.True(t, IsNoChanges(fmt.Errorf("wrapped: %w", NewNoChangesError())))
+	assert.False(t, IsNoChanges(NewConcurrencyError()))
+	assert.False(t, IsNoChanges(nil))
+}
+
+func TestIsConcurrency(t *testing.T) {
+	assert.True(t, IsConcurrency(NewConcurrencyError()))
+	assert.True(t, IsConcurrency(fmt.Errorf("wrapped: %w", NewConcurrencyError())))
+	assert.False(t, IsConcurrency(NewNoChangesError()))
+	assert.False(t, IsConcurrency(nil))
+}
+
+func TestSchemaVersionError(t *testing.T) {
+	tooNew := &SchemaVersionError{Found: 3, Min: 1, Max: 2}
+	assert.Equal(t, "database schema version 3 is newer than the supported versions 1 to 2, upgrade the binary", tooNew.Error())
+
+	tooOld := &SchemaVersionError{Found: 0, Min: 1, Max: 2}
+	assert.Equal(t, "database schema version 0 is older than the supported versions 1 to 2, migrate the database", tooOld.Error())
+
+	var storeErr *PolicyStoreError
+	assert.ErrorAs(t, tooNew, &storeErr)
+	assert.Equal(t, SchemaMismatch, storeErr.Code)
+}
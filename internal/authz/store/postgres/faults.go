@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// ErrInjectedFault is the error returned by the database calls a FaultInjector fails.
+var ErrInjectedFault = errors.New("injected fault")
+
+// testMode reports whether the code runs in a test binary, where dropped commits are allowed.
+var testMode = testing.Testing
+
+// FaultCounts is the number of faults a FaultInjector injected so far.
+type FaultCounts struct {
+	Errors         int `json:"errors"`
+	Delays         int `json:"delays"`
+	DroppedCommits int `json:"dropped_commits"`
+}
+
+// FaultInjector is a pool of Postgres connections failing a share of the calls made to the
+// wrapped pool, for tests checking the layers above the store behave under failure.
+// Without options it passes every call through.
+type FaultInjector struct {
+	db         pgdb.DB
+	errorRate  float64
+	maxLatency time.Duration
+	dropRate   float64
+	mu         sync.Mutex
+	random     *rand.Rand
+	counts     FaultCounts
+}
+
+var _ pgdb.DB = (*FaultInjector)(nil)
+
+// FaultOption configures the faults a FaultInjector injects.
+type FaultOption func(*FaultInjector)
+
+// WithErrorRate fails the given share of the calls, between 0 and 1, with ErrInjectedFault
+// without running them. Calls made in a transaction fail too, as a dropped connection would.
+func WithErrorRate(rate float64) FaultOption {
+	return func(injector *FaultInjector) {
+		injector.errorRate = rate
+	}
+}
+
+// WithLatency delays every call by a random duration up to max, or until the context is done.
+func WithLatency(max time.Duration) FaultOption {
+	return func(injector *FaultInjector) {
+		injector.maxLatency = max
+	}
+}
+
+// WithDroppedCommits rolls back the given share of the transactions, between 0 and 1,
+// while reporting their commit as successful, so the changes are silently lost.
+// Losing data is only allowed in tests, see NewFaultInjector.
+func WithDroppedCommits(rate float64) FaultOption {
+	return func(injector *FaultInjector) {
+		injector.dropRate = rate
+	}
+}
+
+// WithFaultSeed seeds the choice of the failed calls, so a test failing once fails the same way again.
+func WithFaultSeed(seed uint64) FaultOption {
+	return func(injector *FaultInjector) {
+		injector.random = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// NewFaultInjector creates a new FaultInjector failing calls to db as configured by the options.
+// Dropped commits are refused outside test binaries.
+func NewFaultInjector(db pgdb.DB, options ...FaultOption) (*FaultInjector, error) {
+	injector := &FaultInjector{db: db, random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+	for _, option := range options {
+		option(injector)
+	}
+
+	for _, rate := range []float64{injector.errorRate, injector.dropRate} {
+		if rate < 0 || rate > 1 {
+			return nil, errors.New("fault rates must be between 0 and 1")
+		}
+	}
+	if injector.maxLatency < 0 {
+		return nil, errors.New("latency must not be negative")
+	}
+	if injector.dropRate > 0 && !testMode() {
+		return nil, errors.New("dropped commits are only injected in tests")
+	}
+	return injector, nil
+}
+
+// Faults returns the number of faults injected so far.
+func (injector *FaultInjector) Faults() FaultCounts {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	return injector.counts
+}
+
+// fault delays the call and reports whether it fails.
+func (injector *FaultInjector) fault(ctx context.Context) error {
+	injector.mu.Lock()
+	var delay time.Duration
+	if injector.maxLatency > 0 {
+		delay = time.Duration(injector.random.Int64N(int64(injector.maxLatency) + 1))
+		injector.counts.Delays++
+	}
+	failed := injector.errorRate > 0 && injector.random.Float64() < injector.errorRate
+	if failed {
+		injector.counts.Errors++
+	}
+	injector.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if failed {
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+// dropCommit reports whether the commit of a transaction is dropped.
+func (injector *FaultInjector) dropCommit() bool {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	dropped := injector.dropRate > 0 && injector.random.Float64() < injector.dropRate
+	if dropped {
+		injector.counts.DroppedCommits++
+	}
+	return dropped
+}
+
+func (injector *FaultInjector) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := injector.fault(ctx); err != nil {
+		return errorRow{err: err}
+	}
+	return injector.db.QueryRow(ctx, sql, args...)
+}
+
+func (injector *FaultInjector) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := injector.fault(ctx); err != nil {
+		return nil, err
+	}
+	return injector.db.Query(ctx, sql, args...)
+}
+
+func (injector *FaultInjector) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if err := injector.fault(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return injector.db.Exec(ctx, sql, args...)
+}
+
+func (injector *FaultInjector) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := injector.fault(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := injector.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultTx{Tx: tx, injector: injector}, nil
+}
+
+func (injector *FaultInjector) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := injector.fault(ctx); err != nil {
+		return errorBatchResults{err: err}
+	}
+	return injector.db.SendBatch(ctx, b)
+}
+
+// faultTx is a transaction of a FaultInjector, failing its statements and dropping its commit.
+type faultTx struct {
+	pgx.Tx
+	injector *FaultInjector
+}
+
+func (tx *faultTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := tx.injector.fault(ctx); err != nil {
+		return errorRow{err: err}
+	}
+	return tx.Tx.QueryRow(ctx, sql, args...)
+}
+
+func (tx *faultTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := tx.injector.fault(ctx); err != nil {
+		return nil, err
+	}
+	return tx.Tx.Query(ctx, sql, args...)
+}
+
+func (tx *faultTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := tx.injector.fault(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return tx.Tx.Exec(ctx, sql, args...)
+}
+
+func (tx *faultTx) Commit(ctx context.Context) error {
+	if err := tx.injector.fault(ctx); err != nil {
+		return err
+	}
+	if tx.injector.dropCommit() {
+		return tx.Tx.Rollback(ctx)
+	}
+	return tx.Tx.Commit(ctx)
+}
+
+// errorRow is a row failing to scan with the injected error.
+type errorRow struct {
+	err error
+}
+
+func (row errorRow) Scan(dest ...any) error {
+	return row.err
+}
+
+// errorBatchResults is a batch whose every result is the injected error.
+type errorBatchResults struct {
+	err error
+}
+
+func (results errorBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, results.err
+}
+
+func (results errorBatchResults) Query() (pgx.Rows, error) {
+	return nil, results.err
+}
+
+func (results errorBatchResults) QueryRow() pgx.Row {
+	return errorRow(results)
+}
+
+func (results errorBatchResults) Close() error {
+	return results.err
+}
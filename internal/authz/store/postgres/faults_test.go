@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// TestNewFaultInjector creates fault injectors with valid and invalid options.
+func TestNewFaultInjector(t *testing.T) {
+	mockDb := new(MockPgDb)
+
+	_, err := NewFaultInjector(mockDb, WithErrorRate(0.5), WithLatency(time.Millisecond), WithDroppedCommits(0.1))
+	assert.NoError(t, err)
+
+	_, err = NewFaultInjector(mockDb, WithErrorRate(1.5))
+	assert.EqualError(t, err, "fault rates must be between 0 and 1")
+	_, err = NewFaultInjector(mockDb, WithDroppedCommits(-0.1))
+	assert.EqualError(t, err, "fault rates must be between 0 and 1")
+	_, err = NewFaultInjector(mockDb, WithLatency(-time.Second))
+	assert.EqualError(t, err, "latency must not be negative")
+
+	testMode = func() bool { return false }
+	defer func() { testMode = testing.Testing }()
+	_, err = NewFaultInjector(mockDb, WithDroppedCommits(0.1))
+	assert.EqualError(t, err, "dropped commits are only injected in tests")
+	_, err = NewFaultInjector(mockDb, WithErrorRate(0.1))
+	assert.NoError(t, err)
+}
+
+// TestFaultInjector_Errors runs store operations while every call fails, checking they report a database error.
+func TestFaultInjector_Errors(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	injector, err := NewFaultInjector(mockDb, WithErrorRate(1))
+	assert.NoError(t, err)
+	manager := NewPostgresPolicyManager(injector, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err = manager.CreateGroup(ctx, "admins")
+	assert.Equal(t, store.NewDataBaseError(), err)
+	err = manager.UpdateGroupUsers(ctx, 1, []string{"alice"})
+	assert.Equal(t, store.NewDataBaseError(), err)
+	_, err = manager.ListGroups(ctx)
+	assert.Equal(t, store.NewDataBaseError(), err)
+
+	mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, FaultCounts{Errors: 3}, injector.Faults())
+}
+
+// TestFaultInjector_PassThrough runs a store operation without faults, checking the call reaches the database.
+func TestFaultInjector_PassThrough(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRow := new(MockRow)
+	injector, err := NewFaultInjector(mockDb)
+	assert.NoError(t, err)
+	manager := NewPostgresPolicyManager(injector, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	mockDb.On("QueryRow", ctx, "INSERT INTO groups (name, version) VALUES ($1, 1) RETURNING id", []any{"admins"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = 7
+	}).Return(nil)
+
+	id, err := manager.CreateGroup(ctx, "admins")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.Equal(t, FaultCounts{}, injector.Faults())
+}
+
+// TestFaultInjector_DroppedCommits commits transactions whose commit is dropped, checking they are rolled back silently.
+func TestFaultInjector_DroppedCommits(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockTx := new(MockTx)
+	injector, err := NewFaultInjector(mockDb, WithDroppedCommits(1))
+	assert.NoError(t, err)
+
+	mockDb.On("Begin", ctx).Return(mockTx, nil)
+	mockTx.On("Exec", ctx, "DELETE FROM group_users WHERE group_id = $1", []any{1}).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+	mockTx.On("Rollback", ctx).Return(nil)
+
+	tx, err := injector.Begin(ctx)
+	assert.NoError(t, err)
+	_, err = tx.Exec(ctx, "DELETE FROM group_users WHERE group_id = $1", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit(ctx))
+
+	mockTx.AssertNotCalled(t, "Commit", ctx)
+	mockTx.AssertExpectations(t)
+	assert.Equal(t, FaultCounts{DroppedCommits: 1}, injector.Faults())
+}
+
+// TestFaultInjector_Latency delays calls past the deadline of their context, checking the call gives up.
+func TestFaultInjector_Latency(t *testing.T) {
+	mockDb := new(MockPgDb)
+	injector, err := NewFaultInjector(mockDb, WithLatency(time.Hour), WithFaultSeed(1))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = injector.Exec(ctx, "SELECT 1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, FaultCounts{Delays: 1}, injector.Faults())
+	mockDb.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestFaultInjector_Seed fails calls with two injectors seeded alike, checking they fail the same calls.
+func TestFaultInjector_Seed(t *testing.T) {
+	failures := func() []bool {
+		injector, err := NewFaultInjector(new(MockPgDb), WithErrorRate(0.5), WithFaultSeed(42))
+		assert.NoError(t, err)
+		var failed []bool
+		for range 32 {
+			failed = append(failed, injector.fault(context.Background()) != nil)
+		}
+		return failed
+	}
+
+	first := failures()
+	assert.Equal(t, first, failures())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// ChangeChannel is the channel the database notifies every change to the policy on,
+// with the new policy revision as payload, see sql/authz_postgres.sql.
+const ChangeChannel = "policy_changed"
+
+// TenantChangeChannel returns the channel the changes to the policy of the tenant are notified on.
+func TenantChangeChannel(id tenant.ID) string {
+	if id.IsDefault() {
+		return ChangeChannel
+	}
+	return ChangeChannel + "_" + id.Schema()
+}
+
+// listenConn is a connection dedicated to receiving notifications.
+type listenConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Release()
+}
+
+// poolConn is a listenConn acquired from a pool.
+type poolConn struct {
+	*pgxpool.Conn
+}
+
+func (conn poolConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	return conn.Conn.Conn().WaitForNotification(ctx)
+}
+
+// ChangeCallback is called with the revision of the policy after it changed. The revision is 0
+// after the listener (re)connected, as changes may have been made while it was not listening.
+type ChangeCallback func(ctx context.Context, revision int64)
+
+// Listener receives the notifications of the policy changes committed to the database, by this
+// instance or any other, and calls the registered callbacks, so in-process caches and downstream
+// evaluators refresh as soon as a change is made rather than at their next poll.
+type Listener struct {
+	connect    func(ctx context.Context) (listenConn, error)
+	channel    string
+	logger     *slog.Logger
+	retryDelay time.Duration
+	clock      clock.Clock
+
+	mu        sync.Mutex
+	callbacks []ChangeCallback
+}
+
+// ListenerOption configures optional Listener settings.
+type ListenerOption func(*Listener)
+
+// WithTenantChannel listens for the changes to the policy of the tenant, instead of the default one.
+func WithTenantChannel(id tenant.ID) ListenerOption {
+	return func(listener *Listener) {
+		listener.channel = TenantChangeChannel(id)
+	}
+}
+
+// NewListener creates a new Listener holding a connection of the pool while it runs.
+func NewListener(pool *pgxpool.Pool, logger *slog.Logger, options ...ListenerOption) *Listener {
+	connect := func(ctx context.Context) (listenConn, error) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return poolConn{Conn: conn}, nil
+	}
+	listener := &Listener{connect: connect, channel: ChangeChannel, logger: logger, retryDelay: 5 * time.Second, clock: clock.System()}
+	for _, option := range options {
+		option(listener)
+	}
+	return listener
+}
+
+// OnChange registers a callback called after every change, in registration order.
+// Callbacks run on the goroutine of Run, so slow callbacks delay the next notifications.
+func (listener *Listener) OnChange(callback ChangeCallback) {
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	listener.callbacks = append(listener.callbacks, callback)
+}
+
+// Run listens for changes until the context is done, reconnecting after failures.
+func (listener *Listener) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := listener.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		listener.logger.Error("failed to listen for policy changes", "error", err)
+		select {
+		case <-ctx.Done():
+		case <-listener.clock.After(listener.retryDelay):
+		}
+	}
+}
+
+// listen subscribes to the change channel and calls the callbacks for every notification until
+// the connection fails or the context is done. The callbacks are called once right after
+// subscribing, as changes made before cannot be told apart from the ones already seen.
+func (listener *Listener) listen(ctx context.Context) error {
+	conn, err := listener.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+listener.channel); err != nil {
+		return err
+	}
+	listener.logger.Debug("listening for policy changes", "channel", listener.channel)
+	listener.notify(ctx, 0)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		revision, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			listener.logger.Warn("invalid policy change notification", "payload", notification.Payload)
+			revision = 0
+		}
+		listener.notify(ctx, revision)
+	}
+}
+
+func (listener *Listener) notify(ctx context.Context, revision int64) {
+	listener.mu.Lock()
+	callbacks := append([]ChangeCallback{}, listener.callbacks...)
+	listener.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(ctx, revision)
+	}
+}
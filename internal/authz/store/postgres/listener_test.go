@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+// fakeListenConn is a listenConn receiving the notifications sent to its channel
+// and failing once the channel is closed.
+type fakeListenConn struct {
+	notifications chan *pgconn.Notification
+	mu            sync.Mutex
+	statements    []string
+	released      bool
+}
+
+func (conn *fakeListenConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	conn.statements = append(conn.statements, sql)
+	return pgconn.NewCommandTag("LISTEN"), nil
+}
+
+func (conn *fakeListenConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case notification, ok := <-conn.notifications:
+		if !ok {
+			return nil, errors.New("connection closed")
+		}
+		return notification, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (conn *fakeListenConn) Release() {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	conn.released = true
+}
+
+// TestListener_Run receives notifications over two connections, checking the callbacks get every
+// revision and a zero revision after each subscription.
+func TestListener_Run(t *testing.T) {
+	fake := NewFakeClock(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	conns := []*fakeListenConn{
+		{notifications: make(chan *pgconn.Notification)},
+		{notifications: make(chan *pgconn.Notification)},
+	}
+	connects := 0
+	listener := &Listener{
+		connect: func(ctx context.Context) (listenConn, error) {
+			conn := conns[connects]
+			connects++
+			return conn, nil
+		},
+		channel:    ChangeChannel,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		retryDelay: time.Second,
+		clock:      fake,
+	}
+	revisions := make(chan int64)
+	listener.OnChange(func(ctx context.Context, revision int64) { revisions <- revision })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+
+	assert.Equal(t, int64(0), <-revisions)
+	conns[0].notifications <- &pgconn.Notification{Channel: ChangeChannel, Payload: "41"}
+	assert.Equal(t, int64(41), <-revisions)
+
+	// the failed connection is released and the listener reconnects after the retry delay
+	close(conns[0].notifications)
+	assert.Eventually(t, func() bool { return fake.Waiters() == 1 }, time.Second, time.Millisecond)
+	fake.Advance(time.Second)
+	assert.Equal(t, int64(0), <-revisions)
+	conns[1].notifications <- &pgconn.Notification{Channel: ChangeChannel, Payload: "42"}
+	assert.Equal(t, int64(42), <-revisions)
+
+	cancel()
+	<-done
+	for _, conn := range conns {
+		assert.True(t, conn.released)
+		assert.Equal(t, []string{"LISTEN policy_changed"}, conn.statements)
+	}
+}
+
+func TestTenantChangeChannel(t *testing.T) {
+	assert.Equal(t, "policy_changed", TenantChangeChannel(""))
+	assert.Equal(t, "policy_changed_tenant_recipes", TenantChangeChannel("recipes"))
+}
@@ -2,44 +2,74 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
-	"maps"
-	"slices"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
 	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// pgDb is an interface that represents a pool of Postgres connections.
-type pgDb interface {
-	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
-	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
-	Begin(ctx context.Context) (pgx.Tx, error)
-	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
-}
-
 // PostgresPolicyManager is a Postgres implementation of the PolicyManager interface.
 type PostgresPolicyManager struct {
-	db     pgDb
-	logger *slog.Logger
+	db              pgdb.DB
+	logger          *slog.Logger
+	reportNoChanges bool
+	precedence      store.SourcePrecedence
+	tracer          trace.Tracer
+}
+
+var _ store.PolicyManager[int, int, string] = (*PostgresPolicyManager)(nil)
+
+// Option configures optional PostgresPolicyManager behavior.
+type Option func(*PostgresPolicyManager)
+
+// WithNoChangesError makes mutating operations that leave the store unchanged return
+// a NoChanges error instead of succeeding silently.
+func WithNoChangesError() Option {
+	return func(manager *PostgresPolicyManager) {
+		manager.reportNoChanges = true
+	}
+}
+
+// WithSourcePrecedence protects group memberships from changes made by lower precedence sources.
+// By default every source may change every membership.
+func WithSourcePrecedence(precedence store.SourcePrecedence) Option {
+	return func(manager *PostgresPolicyManager) {
+		manager.precedence = precedence
+	}
 }
 
 // NewPostgresPolicyManager creates a new PostgresPolicyManager instance.
-func NewPostgresPolicyManager(db pgDb, logger *slog.Logger) *PostgresPolicyManager {
-	return &PostgresPolicyManager{db: db, logger: logger}
+func NewPostgresPolicyManager(db pgdb.DB, logger *slog.Logger, options ...Option) *PostgresPolicyManager {
+	manager := &PostgresPolicyManager{db: countingDb{DB: db}, logger: logger, tracer: tracing.Tracer(nil)}
+	for _, option := range options {
+		option(manager)
+	}
+	return manager
 }
 
 // UpdateGroupPermissions updates the permissions for the specified group.
-func (manager *PostgresPolicyManager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error {
+// Duplicate permission ids are ignored.
+func (manager *PostgresPolicyManager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) (err error) {
+	ctx, operation := manager.start(ctx, "UpdateGroupPermissions", tracing.GroupIDKey.Int(groupId), tracing.CountKey.Int(len(permissions)))
+	defer func() { operation.end(err) }()
 	logger := manager.logger.With("group_id", groupId, "operation", "UpdateGroupPermissions")
+	permissions = store.NormalizeIds(permissions)
 
 	var version int
-	err := manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	err = manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
 	if err != nil {
 		return versionError(err, logger)
 	}
@@ -53,7 +83,7 @@ func (manager *PostgresPolicyManager) UpdateGroupPermissions(ctx context.Context
 	defer rollback(tx, ctx, logger)
 
 	// merge the new permissions with the existing ones
-	_, err = tx.Exec(ctx, `
+	merged, err := tx.Exec(ctx, `
 	WITH new_permissions AS (SELECT unnest($1::int[]) AS permission_id)
 	MERGE INTO group_permissions gp
 	USING new_permissions np
@@ -67,6 +97,9 @@ func (manager *PostgresPolicyManager) UpdateGroupPermissions(ctx context.Context
 		logger.Error("failed to merge group permissions", "error", err)
 		return store.NewDataBaseError()
 	}
+	if merged.RowsAffected() == 0 {
+		return manager.noChanges(logger)
+	}
 
 	// update the group version
 	tags, err := tx.Exec(ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", groupId, version)
@@ -89,10 +122,12 @@ func (manager *PostgresPolicyManager) UpdateGroupPermissions(ctx context.Context
 }
 
 // CreateGroup creates a new group.
-func (manager *PostgresPolicyManager) CreateGroup(ctx context.Context, groupName string) (int, error) {
+func (manager *PostgresPolicyManager) CreateGroup(ctx context.Context, groupName string) (_ int, err error) {
+	ctx, operation := manager.start(ctx, "CreateGroup")
+	defer func() { operation.end(err) }()
 	logger := manager.logger.With("group_name", groupName, "operation", "CreateGroup")
 	var id int
-	err := manager.db.QueryRow(ctx, "INSERT INTO groups (name, version) VALUES ($1, 1) RETURNING id", groupName).Scan(&id)
+	err = manager.db.QueryRow(ctx, "INSERT INTO groups (name, version) VALUES ($1, 1) RETURNING id", groupName).Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -108,10 +143,12 @@ func (manager *PostgresPolicyManager) CreateGroup(ctx context.Context, groupName
 }
 
 // CreatePermission creates a new permission.
-func (manager *PostgresPolicyManager) CreatePermission(ctx context.Context, permissionName string) (int, error) {
+func (manager *PostgresPolicyManager) CreatePermission(ctx context.Context, permissionName string) (_ int, err error) {
+	ctx, operation := manager.start(ctx, "CreatePermission")
+	defer func() { operation.end(err) }()
 	logger := manager.logger.With("permission_name", permissionName, "operation", "CreatePermission")
 	var id int
-	err := manager.db.QueryRow(ctx, "INSERT INTO permissions (name, version) VALUES ($1, 1) RETURNING id", permissionName).Scan(&id)
+	err = manager.db.QueryRow(ctx, "INSERT INTO permissions (name, version) VALUES ($1, 1) RETURNING id", permissionName).Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -126,14 +163,124 @@ func (manager *PostgresPolicyManager) CreatePermission(ctx context.Context, perm
 	return id, nil
 }
 
-// UpdateGroupUsers updates the users for the specified group.
-func (manager *PostgresPolicyManager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
-	logger := manager.logger.With("group_id", groupId, "operation", "UpdateGroupUsers")
+// SetPermissionRisk changes the risk level of the permission with the specified id.
+func (manager *PostgresPolicyManager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) (err error) {
+	ctx, operation := manager.start(ctx, "SetPermissionRisk", tracing.PermissionIDKey.Int(permissionId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionRisk")
+
+	risk, err = authz.ParseRiskLevel(string(risk))
+	if err != nil {
+		logger.Error("invalid risk level", "error", err)
+		return store.NewInvalidArgumentError()
+	}
+
+	// update the risk only when it differs, reporting whether the permission exists at all
+	var found, updated bool
+	err = manager.db.QueryRow(ctx, `
+	WITH target AS (SELECT id, risk FROM permissions WHERE id = $2),
+	updated AS (
+		UPDATE permissions p SET risk = $1, version = p.version + 1
+		FROM target t WHERE p.id = t.id AND t.risk <> $1
+		RETURNING p.id
+	)
+	SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM updated)
+	`, string(risk), permissionId).Scan(&found, &updated)
+	if err != nil {
+		logger.Error("failed to update permission risk", "error", err)
+		return store.NewDataBaseError()
+	}
+	if !found {
+		logger.Error("permission not found")
+		return store.NewPermissionNotFoundError()
+	}
+	if !updated {
+		return manager.noChanges(logger)
+	}
+
+	return nil
+}
+
+// SetPermissionCondition replaces the condition the attributes of a request must meet for the grants
+// of the permission with the specified id to apply, see authz.Permission.Condition. An empty condition
+// makes the grants unconditional; a condition that does not compile is rejected with an InvalidArgument error.
+func (manager *PostgresPolicyManager) SetPermissionCondition(ctx context.Context, permissionId int, condition string) (err error) {
+	ctx, operation := manager.start(ctx, "SetPermissionCondition", tracing.PermissionIDKey.Int(permissionId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionCondition")
+
+	condition, err = store.NormalizeCondition(condition)
+	if err != nil {
+		logger.Error("invalid condition", "error", err)
+		return err
+	}
+
+	// update the condition only when it differs, reporting whether the permission exists at all
+	var found, updated bool
+	err = manager.db.QueryRow(ctx, `
+	WITH target AS (SELECT id, condition FROM permissions WHERE id = $2),
+	updated AS (
+		UPDATE permissions p SET condition = $1, version = p.version + 1
+		FROM target t WHERE p.id = t.id AND t.condition <> $1
+		RETURNING p.id
+	)
+	SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM updated)
+	`, condition, permissionId).Scan(&found, &updated)
+	if err != nil {
+		logger.Error("failed to update permission condition", "error", err)
+		return store.NewDataBaseError()
+	}
+	if !found {
+		logger.Error("permission not found")
+		return store.NewPermissionNotFoundError()
+	}
+	if !updated {
+		return manager.noChanges(logger)
+	}
+
+	return nil
+}
+
+// SetPermissionImplications replaces the permissions implied by the permission with the specified id.
+// Duplicate permission ids are ignored. Implications that would make a permission imply itself,
+// directly or transitively, are rejected with an InvalidArgument error.
+//
+// The cycle check reads the implications committed before the change, so concurrent changes
+// may together introduce a cycle; evaluation tolerates cycles.
+func (manager *PostgresPolicyManager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) (err error) {
+	ctx, operation := manager.start(ctx, "SetPermissionImplications", tracing.PermissionIDKey.Int(permissionId), tracing.CountKey.Int(len(implied)))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionImplications")
+	implied = store.NormalizeIds(implied)
 
 	var version int
-	err := manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	err = manager.db.QueryRow(ctx, "SELECT version FROM permissions WHERE id = $1", permissionId).Scan(&version)
 	if err != nil {
-		return versionError(err, logger)
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("permission not found")
+			return store.NewPermissionNotFoundError()
+		}
+		logger.Error("failed to query permission version", "error", err)
+		return store.NewDataBaseError()
+	}
+
+	// reject implications leading back to the permission
+	var cycle bool
+	err = manager.db.QueryRow(ctx, `
+	WITH RECURSIVE reachable(id) AS (
+		SELECT unnest($1::int[])
+		UNION
+		SELECT pi.implied_id FROM permission_implications pi JOIN reachable r ON pi.permission_id = r.id
+	)
+	SELECT EXISTS (SELECT 1 FROM reachable WHERE id = $2)
+	`, implied, permissionId).Scan(&cycle)
+	if err != nil {
+		logger.Error("failed to check permission implications", "error", err)
+		return store.NewDataBaseError()
+	}
+	if cycle {
+		logger.Error("permission implications form a cycle")
+		return store.NewInvalidArgumentError()
 	}
 
 	// start a new transaction
@@ -144,30 +291,39 @@ func (manager *PostgresPolicyManager) UpdateGroupUsers(ctx context.Context, grou
 	}
 	defer rollback(tx, ctx, logger)
 
-	// merge the new users with the existing ones
-	_, err = tx.Exec(ctx, `
-	WITH new_users AS (SELECT unnest($1::text[]) AS user_id)
-	MERGE INTO subjects sub
-	USING new_users nu
-	ON sub.group_id = $2 AND sub.id = nu.user_id
+	// merge the new implications with the existing ones
+	merged, err := tx.Exec(ctx, `
+	WITH new_implications AS (SELECT unnest($1::int[]) AS implied_id)
+	MERGE INTO permission_implications pi
+	USING new_implications ni
+	ON pi.permission_id = $2 AND pi.implied_id = ni.implied_id
 	WHEN NOT MATCHED BY TARGET THEN
-		INSERT (group_id, id) VALUES ($2, nu.user_id)
-	WHEN NOT MATCHED BY SOURCE AND sub.group_id = $2 THEN
+		INSERT (permission_id, implied_id) VALUES ($2, ni.implied_id)
+	WHEN NOT MATCHED BY SOURCE AND pi.permission_id = $2 THEN
 		DELETE;
-	`, users, groupId)
+	`, implied, permissionId)
 	if err != nil {
-		logger.Error("failed to merge group users", "error", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			logger.Error("implied permission not found")
+			return store.NewPermissionNotFoundError()
+		}
+
+		logger.Error("failed to merge permission implications", "error", err)
 		return store.NewDataBaseError()
 	}
+	if merged.RowsAffected() == 0 {
+		return manager.noChanges(logger)
+	}
 
-	// update the group version
-	tags, err := tx.Exec(ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", groupId, version)
+	// update the permission version
+	tags, err := tx.Exec(ctx, "UPDATE permissions SET version = version + 1 WHERE id = $1 AND version = $2", permissionId, version)
 	if err != nil {
-		logger.Error("failed to update group version", "error", err)
+		logger.Error("failed to update permission version", "error", err)
 		return store.NewDataBaseError()
 	}
 	if tags.RowsAffected() == 0 {
-		logger.Error("failed to update group version due to concurrency issue")
+		logger.Error("failed to update permission version due to concurrency issue")
 		return store.NewConcurrencyError()
 	}
 
@@ -180,188 +336,1454 @@ func (manager *PostgresPolicyManager) UpdateGroupUsers(ctx context.Context, grou
 	return nil
 }
 
-// UpdateUserGroups updates the groups for the specified user.
-func (manager *PostgresPolicyManager) UpdateUserGroups(ctx context.Context, userId string, groups []int) error {
-	logger := manager.logger.With("user_id", userId, "operation", "UpdateUserGroups")
+// SetPermissionDenials replaces the groups and users explicitly denied the permission with the specified id,
+// see authz.Denial. Duplicate group ids and users are ignored.
+func (manager *PostgresPolicyManager) SetPermissionDenials(ctx context.Context, permissionId int, groups []int, users []string) (err error) {
+	ctx, operation := manager.start(ctx, "SetPermissionDenials", tracing.PermissionIDKey.Int(permissionId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("permission_id", permissionId, "operation", "SetPermissionDenials")
+	groups = store.NormalizeIds(groups)
+	users, err = store.NormalizeUserIds(users)
+	if err != nil {
+		logger.Error("empty user id")
+		return err
+	}
+
+	var version int
+	err = manager.db.QueryRow(ctx, "SELECT version FROM permissions WHERE id = $1", permissionId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("permission not found")
+			return store.NewPermissionNotFoundError()
+		}
+		logger.Error("failed to query permission version", "error", err)
+		return store.NewDataBaseError()
+	}
+
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to start transaction", "error", err)
+		return store.NewDataBaseError()
+	}
+	defer rollback(tx, ctx, logger)
 
-	// merge the new groups with the existing ones
-	_, err := manager.db.Exec(ctx, `
+	// merge the new group denials with the existing ones
+	mergedGroups, err := tx.Exec(ctx, `
 	WITH new_groups AS (SELECT unnest($1::int[]) AS group_id)
-	MERGE INTO subjects sub
+	MERGE INTO permission_denials pd
 	USING new_groups ng
-	ON sub.group_id = ng.group_id AND sub.id = $2
+	ON pd.permission_id = $2 AND pd.group_id = ng.group_id
 	WHEN NOT MATCHED BY TARGET THEN
-		INSERT (id, group_id) VALUES ($2, ng.group_id)
-	WHEN NOT MATCHED BY SOURCE AND sub.id = $2 THEN
+		INSERT (permission_id, group_id) VALUES ($2, ng.group_id)
+	WHEN NOT MATCHED BY SOURCE AND pd.permission_id = $2 AND pd.group_id IS NOT NULL THEN
 		DELETE;
-	`, groups, userId)
+	`, groups, permissionId)
 	if err != nil {
-		logger.Error("failed to merge user groups", "error", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			logger.Error("denied group not found")
+			return store.NewGroupNotFoundError()
+		}
+
+		logger.Error("failed to merge group denials", "error", err)
 		return store.NewDataBaseError()
 	}
 
-	return nil
-}
-
-// DeleteGroup deletes the group with the specified id.
-func (manager *PostgresPolicyManager) DeleteGroup(ctx context.Context, groupId int) error {
-	logger := manager.logger.With("group_id", groupId, "operation", "DeleteGroup")
-
-	var version int
-	err := manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	// merge the new user denials with the existing ones
+	mergedUsers, err := tx.Exec(ctx, `
+	WITH new_users AS (SELECT unnest($1::varchar[]) AS user_id)
+	MERGE INTO permission_denials pd
+	USING new_users nu
+	ON pd.permission_id = $2 AND pd.user_id = nu.user_id
+	WHEN NOT MATCHED BY TARGET THEN
+		INSERT (permission_id, user_id) VALUES ($2, nu.user_id)
+	WHEN NOT MATCHED BY SOURCE AND pd.permission_id = $2 AND pd.user_id IS NOT NULL THEN
+		DELETE;
+	`, users, permissionId)
 	if err != nil {
-		return versionError(err, logger)
+		logger.Error("failed to merge user denials", "error", err)
+		return store.NewDataBaseError()
+	}
+	if mergedGroups.RowsAffected()+mergedUsers.RowsAffected() == 0 {
+		return manager.noChanges(logger)
 	}
 
-	tag, err := manager.db.Exec(ctx, "DELETE FROM groups WHERE id = $1 AND version = $2", groupId, version)
+	// update the permission version
+	tags, err := tx.Exec(ctx, "UPDATE permissions SET version = version + 1 WHERE id = $1 AND version = $2", permissionId, version)
 	if err != nil {
-		logger.Error("failed to delete group", "error", err)
+		logger.Error("failed to update permission version", "error", err)
 		return store.NewDataBaseError()
 	}
-	if tag.RowsAffected() == 0 {
-		logger.Error("failed to delete group due to concurrency issue")
+	if tags.RowsAffected() == 0 {
+		logger.Error("failed to update permission version due to concurrency issue")
 		return store.NewConcurrencyError()
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return store.NewDataBaseError()
+	}
+
 	return nil
 }
 
-// ChangeGroupName changes the name of the group with the specified id.
-func (manager *PostgresPolicyManager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) error {
-	logger := manager.logger.With("group_id", groupId, "operation", "ChangeGroupName")
+// GrantPermission grants a single permission to the specified group, keeping the existing grants.
+func (manager *PostgresPolicyManager) GrantPermission(ctx context.Context, groupId int, permissionId int) (err error) {
+	ctx, operation := manager.start(ctx, "GrantPermission", tracing.GroupIDKey.Int(groupId), tracing.PermissionIDKey.Int(permissionId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("group_id", groupId, "permission_id", permissionId, "operation", "GrantPermission")
 
-	// get the current version of the group
 	var version int
-	err := manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	err = manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
 	if err != nil {
 		return versionError(err, logger)
 	}
-	tag, err := manager.db.Exec(ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", newGroupName, groupId, version)
+
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
 	if err != nil {
-		logger.Error("failed to update group name", "error", err)
+		logger.Error("failed to start transaction", "error", err)
 		return store.NewDataBaseError()
 	}
-	if tag.RowsAffected() == 0 {
-		logger.Error("failed to update group name due to concurrency issue")
-		return store.NewConcurrencyError()
-	}
+	defer rollback(tx, ctx, logger)
 
-	return nil
-}
+	inserted, err := tx.Exec(ctx, "INSERT INTO group_permissions (group_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", groupId, permissionId)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			logger.Error("permission not found")
+			return store.NewPermissionNotFoundError()
+		}
 
-// DeleteUser deletes the user with the specified id.
-func (manager *PostgresPolicyManager) DeleteUser(ctx context.Context, userId string) error {
-	logger := manager.logger.With("user_id", userId, "operation", "DeleteUser")
+		logger.Error("failed to insert group permission", "error", err)
+		return store.NewDataBaseError()
+	}
+	if inserted.RowsAffected() == 0 {
+		return manager.noChanges(logger)
+	}
 
-	// delete the user from the database
-	tag, err := manager.db.Exec(ctx, "DELETE FROM subjects WHERE id = $1", userId)
+	// update the group version
+	tags, err := tx.Exec(ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", groupId, version)
 	if err != nil {
-		logger.Error("failed to delete user", "error", err)
+		logger.Error("failed to update group version", "error", err)
 		return store.NewDataBaseError()
 	}
-	if tag.RowsAffected() == 0 {
-		logger.Error("no user records found for deletion")
-		return store.NewNoUserRecordsDeletedError()
+	if tags.RowsAffected() == 0 {
+		logger.Error("failed to update group version due to concurrency issue")
+		return store.NewConcurrencyError()
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return store.NewDataBaseError()
 	}
 
 	return nil
 }
 
-func (manager *PostgresPolicyManager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
-	logger := manager.logger.With("operation", "ReadPolicy")
+// UpdateGroupUsers updates the users for the specified group.
+// User ids are trimmed and duplicates ignored; an empty user id is rejected.
+// Memberships are attributed to the source in the context; memberships of higher precedence
+// sources are kept even when omitted, see WithSourcePrecedence.
+func (manager *PostgresPolicyManager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) (err error) {
+	ctx, operation := manager.start(ctx, "UpdateGroupUsers", tracing.GroupIDKey.Int(groupId), tracing.CountKey.Int(len(users)))
+	defer func() { operation.end(err) }()
+	source, overridden, protected := manager.membershipSource(ctx)
+	logger := manager.logger.With("group_id", groupId, "source", source, "operation", "UpdateGroupUsers")
 
-	batch := pgx.Batch{}
-	batch.Queue("SELECT g.name , s.id FROM groups g LEFT JOIN subjects s on g.id = s.group_id;")
-	batch.Queue(`
-	SELECT p.name, g.name AS group_name 
-	FROM permissions p 
-	LEFT JOIN group_permissions gp ON p.id = gp.permission_id 
-	LEFT JOIN groups g ON g.id = gp.group_id;
-	`)
+	users, err = store.NormalizeUserIds(users)
+	if err != nil {
+		logger.Error("invalid user ids", "error", err)
+		return err
+	}
 
-	br := manager.db.SendBatch(ctx, &batch)
-	defer func() {
-		err := br.Close()
-		if err != nil {
-			logger.Error("failed to close batch results", "error", err)
-		}
-	}()
+	var version int
+	err = manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	if err != nil {
+		return versionError(err, logger)
+	}
 
-	// group users
-	rows, err := br.Query()
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
 	if err != nil {
-		logger.Error("failed to query group users", "error", err)
-		return nil, store.NewDataBaseError()
+		logger.Error("failed to start transaction", "error", err)
+		return store.NewDataBaseError()
 	}
+	defer rollback(tx, ctx, logger)
 
-	groups := make(map[string]authz.Group)
-	var groupName string
-	var userId pgtype.Text
-	for rows.Next() {
-		err = rows.Scan(&groupName, &userId)
-		if err != nil {
-			logger.Error("failed to scan group users", "error", err)
-			return nil, store.NewDefaultError()
-		}
+	// merge the new users with the existing ones, leaving protected memberships untouched
+	merged, err := tx.Exec(ctx, `
+	WITH new_users AS (SELECT unnest($1::text[]) AS user_id)
+	MERGE INTO subjects sub
+	USING new_users nu
+	ON sub.group_id = $2 AND sub.id = nu.user_id
+	WHEN MATCHED AND sub.source = ANY($4::text[]) THEN
+		UPDATE SET source = $3
+	WHEN NOT MATCHED BY TARGET THEN
+		INSERT (group_id, id, source) VALUES ($2, nu.user_id, $3)
+	WHEN NOT MATCHED BY SOURCE AND sub.group_id = $2 AND NOT sub.source = ANY($5::text[]) THEN
+		DELETE;
+	`, users, groupId, source, overridden, protected)
+	if err != nil {
+		logger.Error("failed to merge group users", "error", err)
+		return store.NewDataBaseError()
+	}
+	if merged.RowsAffected() == 0 {
+		return manager.noChanges(logger)
+	}
 
-		if group, ok := groups[groupName]; ok {
-			if userId.Valid {
-				group.Users = append(group.Users, userId.String)
-			}
-		} else {
-			users := []string{}
-			if userId.Valid {
-				users = append(users, userId.String)
-			}
-			groups[groupName] = authz.Group{Name: groupName, Users: users}
-		}
+	// update the group version
+	tags, err := tx.Exec(ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", groupId, version)
+	if err != nil {
+		logger.Error("failed to update group version", "error", err)
+		return store.NewDataBaseError()
+	}
+	if tags.RowsAffected() == 0 {
+		logger.Error("failed to update group version due to concurrency issue")
+		return store.NewConcurrencyError()
 	}
 
-	if rows.Err() != nil {
-		logger.Error("failed to read group users", "error", rows.Err())
-		return nil, store.NewDefaultError()
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return store.NewDataBaseError()
 	}
 
-	// permissions
-	rows, err = br.Query()
+	return nil
+}
+
+// AddGroupUser adds a single user to the specified group, keeping the existing members.
+// The membership is attributed to the source in the context; an existing membership is left
+// as it is, whatever its source.
+func (manager *PostgresPolicyManager) AddGroupUser(ctx context.Context, groupId int, userId string) (err error) {
+	ctx, operation := manager.start(ctx, "AddGroupUser", tracing.GroupIDKey.Int(groupId))
+	defer func() { operation.end(err) }()
+	source := store.MembershipSourceFromContext(ctx)
+	logger := manager.logger.With("group_id", groupId, "user_id", userId, "source", source, "operation", "AddGroupUser")
+
+	userId, err = store.NormalizeUserId(userId)
 	if err != nil {
-		logger.Error("failed to query permissions", "error", err)
-		return nil, store.NewDataBaseError()
+		logger.Error("invalid user id", "error", err)
+		return err
 	}
 
-	permissions := make(map[string]authz.Permission)
-	var permissionName string
-	var permissionGroup pgtype.Text
-	for rows.Next() {
-		err = rows.Scan(&permissionName, &permissionGroup)
-		if err != nil {
-			logger.Error("failed to scan permission groups", "error", err)
-			return nil, store.NewDefaultError()
-		}
+	var version int
+	err = manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	if err != nil {
+		return versionError(err, logger)
+	}
 
-		if permission, ok := permissions[permissionName]; ok {
-			if permissionGroup.Valid {
-				permission.Groups = append(permission.Groups, permissionGroup.String)
-			}
-		} else {
-			groups := []string{}
-			if permissionGroup.Valid {
-				groups = append(groups, permissionGroup.String)
-			}
-			permissions[permissionName] = authz.Permission{Name: permissionName, Groups: groups}
-		}
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to start transaction", "error", err)
+		return store.NewDataBaseError()
 	}
+	defer rollback(tx, ctx, logger)
 
-	if rows.Err() != nil {
-		logger.Error("failed to read permission groups", "error", rows.Err())
-		return nil, store.NewDefaultError()
+	inserted, err := tx.Exec(ctx, "INSERT INTO subjects (id, group_id, source) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", userId, groupId, string(source))
+	if err != nil {
+		logger.Error("failed to insert group user", "error", err)
+		return store.NewDataBaseError()
+	}
+	if inserted.RowsAffected() == 0 {
+		return manager.noChanges(logger)
 	}
 
-	policy := authz.NewPolicy(slices.Collect(maps.Values(permissions)), slices.Collect(maps.Values(groups)))
+	// update the group version
+	tags, err := tx.Exec(ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", groupId, version)
+	if err != nil {
+		logger.Error("failed to update group version", "error", err)
+		return store.NewDataBaseError()
+	}
+	if tags.RowsAffected() == 0 {
+		logger.Error("failed to update group version due to concurrency issue")
+		return store.NewConcurrencyError()
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return store.NewDataBaseError()
+	}
+
+	return nil
+}
+
+// UpdateUserGroups updates the groups for the specified user.
+// The user id is trimmed and duplicate group ids are ignored; an empty user id is rejected.
+// Memberships are attributed to the source in the context; memberships of higher precedence
+// sources are kept even when omitted, see WithSourcePrecedence.
+func (manager *PostgresPolicyManager) UpdateUserGroups(ctx context.Context, userId string, groups []int) (err error) {
+	ctx, operation := manager.start(ctx, "UpdateUserGroups", tracing.CountKey.Int(len(groups)))
+	defer func() { operation.end(err) }()
+	source, overridden, protected := manager.membershipSource(ctx)
+	logger := manager.logger.With("user_id", userId, "source", source, "operation", "UpdateUserGroups")
+
+	userId, err = store.NormalizeUserId(userId)
+	if err != nil {
+		logger.Error("invalid user id", "error", err)
+		return err
+	}
+	groups = store.NormalizeIds(groups)
+
+	// merge the new groups with the existing ones, leaving protected memberships untouched
+	merged, err := manager.db.Exec(ctx, `
+	WITH new_groups AS (SELECT unnest($1::int[]) AS group_id)
+	MERGE INTO subjects sub
+	USING new_groups ng
+	ON sub.group_id = ng.group_id AND sub.id = $2
+	WHEN MATCHED AND sub.source = ANY($4::text[]) THEN
+		UPDATE SET source = $3
+	WHEN NOT MATCHED BY TARGET THEN
+		INSERT (id, group_id, source) VALUES ($2, ng.group_id, $3)
+	WHEN NOT MATCHED BY SOURCE AND sub.id = $2 AND NOT sub.source = ANY($5::text[]) THEN
+		DELETE;
+	`, groups, userId, source, overridden, protected)
+	if err != nil {
+		logger.Error("failed to merge user groups", "error", err)
+		return store.NewDataBaseError()
+	}
+	if merged.RowsAffected() == 0 {
+		return manager.noChanges(logger)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes the group with the specified id together with its memberships and
+// permission grants, and records a tombstone for the deleted group in the same transaction.
+func (manager *PostgresPolicyManager) DeleteGroup(ctx context.Context, groupId int) (_ *store.GroupDeletion, err error) {
+	ctx, operation := manager.start(ctx, "DeleteGroup", tracing.GroupIDKey.Int(groupId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("group_id", groupId, "operation", "DeleteGroup")
+
+	var version int
+	err = manager.db.QueryRow(ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	if err != nil {
+		return nil, versionError(err, logger)
+	}
+
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to start transaction", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	defer rollback(tx, ctx, logger)
+
+	// remove the dependent rows explicitly so their counts can be reported
+	members, err := tx.Exec(ctx, "DELETE FROM subjects WHERE group_id = $1", groupId)
+	if err != nil {
+		logger.Error("failed to delete group users", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	grants, err := tx.Exec(ctx, "DELETE FROM group_permissions WHERE group_id = $1", groupId)
+	if err != nil {
+		logger.Error("failed to delete group permissions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	// a concurrent change rolls back the removed dependents as well
+	var name string
+	err = tx.QueryRow(ctx, "DELETE FROM groups WHERE id = $1 AND version = $2 RETURNING name", groupId, version).Scan(&name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to delete group due to concurrency issue")
+		return nil, store.NewConcurrencyError()
+	}
+	if err != nil {
+		logger.Error("failed to delete group", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	deletion := &store.GroupDeletion{Members: int(members.RowsAffected()), Grants: int(grants.RowsAffected())}
+	_, err = tx.Exec(ctx, "INSERT INTO group_tombstones (group_id, name, version, members, grants) VALUES ($1, $2, $3, $4, $5)",
+		groupId, name, version, deletion.Members, deletion.Grants)
+	if err != nil {
+		logger.Error("failed to record group tombstone", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	logger.Info("group deleted", "group_name", name, "members", deletion.Members, "grants", deletion.Grants)
+	return deletion, nil
+}
+
+// ChangeGroupName changes the name of the group with the specified id.
+func (manager *PostgresPolicyManager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) (err error) {
+	ctx, operation := manager.start(ctx, "ChangeGroupName", tracing.GroupIDKey.Int(groupId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("group_id", groupId, "operation", "ChangeGroupName")
+
+	// get the current version and name of the group
+	var version int
+	var groupName string
+	err = manager.db.QueryRow(ctx, "SELECT version, name FROM groups WHERE id = $1", groupId).Scan(&version, &groupName)
+	if err != nil {
+		return versionError(err, logger)
+	}
+	if groupName == newGroupName {
+		return manager.noChanges(logger)
+	}
+
+	tag, err := manager.db.Exec(ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", newGroupName, groupId, version)
+	if err != nil {
+		logger.Error("failed to update group name", "error", err)
+		return store.NewDataBaseError()
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("failed to update group name due to concurrency issue")
+		return store.NewConcurrencyError()
+	}
+
+	return nil
+}
+
+// UpdateGroupMetadata applies the patch to the description and labels of the group with the
+// specified id and returns the group. The change is made in a single statement, so concurrent
+// patches of different fields do not overwrite each other, and the group version is unchanged.
+func (manager *PostgresPolicyManager) UpdateGroupMetadata(ctx context.Context, groupId int, patch store.MetadataPatch) (_ *store.GroupInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "UpdateGroupMetadata", tracing.GroupIDKey.Int(groupId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("group_id", groupId, "operation", "UpdateGroupMetadata")
+
+	if err := patch.Validate(); err != nil {
+		logger.Error("invalid metadata patch")
+		return nil, err
+	}
+
+	set, removed := patch.LabelChanges()
+	var group store.GroupInfo[int]
+	err = manager.db.QueryRow(ctx, `
+	UPDATE groups SET description = COALESCE($2, description),
+		labels = (CASE WHEN $3 THEN '{}'::jsonb ELSE labels END || $4::jsonb) - $5::text[]
+	WHERE id = $1
+	RETURNING id, name, version, description, labels
+	`, groupId, patch.Description, patch.ClearLabels, set, removed).Scan(&group.ID, &group.Name, &group.Version, &group.Description, &group.Labels)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("group not found")
+		return nil, store.NewGroupNotFoundError()
+	}
+	if err != nil {
+		logger.Error("failed to update group metadata", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	return &group, nil
+}
+
+// UpdatePermissionMetadata applies the patch to the description and labels of the permission
+// with the specified id and returns the permission, like UpdateGroupMetadata.
+func (manager *PostgresPolicyManager) UpdatePermissionMetadata(ctx context.Context, permissionId int, patch store.MetadataPatch) (_ *store.PermissionInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "UpdatePermissionMetadata", tracing.PermissionIDKey.Int(permissionId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("permission_id", permissionId, "operation", "UpdatePermissionMetadata")
+
+	if err := patch.Validate(); err != nil {
+		logger.Error("invalid metadata patch")
+		return nil, err
+	}
+
+	set, removed := patch.LabelChanges()
+	var permission store.PermissionInfo[int]
+	var risk string
+	err = manager.db.QueryRow(ctx, `
+	UPDATE permissions SET description = COALESCE($2, description),
+		labels = (CASE WHEN $3 THEN '{}'::jsonb ELSE labels END || $4::jsonb) - $5::text[]
+	WHERE id = $1
+	RETURNING id, name, version, risk, description, labels
+	`, permissionId, patch.Description, patch.ClearLabels, set, removed).Scan(&permission.ID, &permission.Name, &permission.Version,
+		&risk, &permission.Description, &permission.Labels)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("permission not found")
+		return nil, store.NewPermissionNotFoundError()
+	}
+	if err != nil {
+		logger.Error("failed to update permission metadata", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	permission.Risk = authz.RiskLevel(risk)
+
+	return &permission, nil
+}
+
+// DeleteUser deletes the user with the specified id from every group, whatever the membership source.
+// The user id is trimmed; an empty user id is rejected.
+func (manager *PostgresPolicyManager) DeleteUser(ctx context.Context, userId string) (err error) {
+	ctx, operation := manager.start(ctx, "DeleteUser")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("user_id", userId, "operation", "DeleteUser")
+
+	userId, err = store.NormalizeUserId(userId)
+	if err != nil {
+		logger.Error("invalid user id", "error", err)
+		return err
+	}
+
+	// delete the user from the database
+	tag, err := manager.db.Exec(ctx, "DELETE FROM subjects WHERE id = $1", userId)
+	if err != nil {
+		logger.Error("failed to delete user", "error", err)
+		return store.NewDataBaseError()
+	}
+	if tag.RowsAffected() == 0 {
+		logger.Error("no user records found for deletion")
+		return store.NewNoUserRecordsDeletedError()
+	}
+
+	return nil
+}
+
+// ReadPolicy returns the whole policy. Groups and permissions are sorted by name,
+// group members by user id and permission grants and implications by name, so reads are stable.
+func (manager *PostgresPolicyManager) ReadPolicy(ctx context.Context) (_ *authz.Policy, err error) {
+	ctx, operation := manager.start(ctx, "ReadPolicy")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ReadPolicy")
+
+	batch := pgx.Batch{}
+	batch.Queue("SELECT g.name , s.id FROM groups g LEFT JOIN subjects s on g.id = s.group_id ORDER BY g.name, s.id;")
+	batch.Queue(`
+	SELECT p.name, g.name AS group_name, p.risk, p.condition, array(
+		SELECT i.name FROM permission_implications pi JOIN permissions i ON i.id = pi.implied_id
+		WHERE pi.permission_id = p.id ORDER BY i.name
+	) AS implies
+	FROM permissions p 
+	LEFT JOIN group_permissions gp ON p.id = gp.permission_id 
+	LEFT JOIN groups g ON g.id = gp.group_id
+	ORDER BY p.name, g.name;
+	`)
+	batch.Queue(`
+	SELECT f.id, f.name, f.parent_id, f.inheritance, array(
+		SELECT g.name FROM folder_groups fg JOIN groups g ON g.id = fg.group_id
+		WHERE fg.folder_id = f.id ORDER BY g.name
+	) AS groups, array(
+		SELECT p.name FROM folder_permissions fp JOIN permissions p ON p.id = fp.permission_id
+		WHERE fp.folder_id = f.id ORDER BY p.name
+	) AS grants
+	FROM folders f
+	ORDER BY f.id;
+	`)
+	batch.Queue(`
+	SELECT p.name, g.name AS group_name, d.user_id
+	FROM permission_denials d
+	JOIN permissions p ON p.id = d.permission_id
+	LEFT JOIN groups g ON g.id = d.group_id
+	ORDER BY p.name, g.name NULLS LAST, d.user_id;
+	`)
+
+	br := manager.db.SendBatch(ctx, &batch)
+	defer func() {
+		err := br.Close()
+		if err != nil {
+			logger.Error("failed to close batch results", "error", err)
+		}
+	}()
+
+	// group users
+	rows, err := br.Query()
+	if err != nil {
+		logger.Error("failed to query group users", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	// rows are ordered by group, so each group is appended once and then extended
+	groups := []authz.Group{}
+	var groupName string
+	var userId pgtype.Text
+	for rows.Next() {
+		err = rows.Scan(&groupName, &userId)
+		if err != nil {
+			logger.Error("failed to scan group users", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		if len(groups) == 0 || groups[len(groups)-1].Name != groupName {
+			groups = append(groups, authz.Group{Name: groupName, Users: []string{}})
+		}
+		if userId.Valid {
+			group := &groups[len(groups)-1]
+			group.Users = append(group.Users, userId.String)
+		}
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read group users", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	// permissions
+	rows, err = br.Query()
+	if err != nil {
+		logger.Error("failed to query permissions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	// rows are ordered by permission, so each permission is appended once and then extended
+	permissions := []authz.Permission{}
+	var permissionName string
+	var permissionGroup pgtype.Text
+	var permissionRisk, permissionCondition string
+	var permissionImplies []string
+	for rows.Next() {
+		err = rows.Scan(&permissionName, &permissionGroup, &permissionRisk, &permissionCondition, &permissionImplies)
+		if err != nil {
+			logger.Error("failed to scan permission groups", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		if len(permissions) == 0 || permissions[len(permissions)-1].Name != permissionName {
+			permission := authz.Permission{Name: permissionName, Groups: []string{}, Risk: authz.RiskLevel(permissionRisk), Condition: permissionCondition}
+			if len(permissionImplies) > 0 {
+				permission.Implies = permissionImplies
+			}
+			permissions = append(permissions, permission)
+		}
+		if permissionGroup.Valid {
+			permission := &permissions[len(permissions)-1]
+			permission.Groups = append(permission.Groups, permissionGroup.String)
+		}
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permission groups", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	// folders, whose paths are built once every folder is read
+	rows, err = br.Query()
+	if err != nil {
+		logger.Error("failed to query folders", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	nodes := []folder.Folder{}
+	policyFolders := []authz.Folder{}
+	for rows.Next() {
+		var node folder.Folder
+		var parentId pgtype.Int4
+		var policyFolder authz.Folder
+		err = rows.Scan(&node.ID, &node.Name, &parentId, &policyFolder.Inheritance, &policyFolder.Groups, &policyFolder.Grants)
+		if err != nil {
+			logger.Error("failed to scan folders", "error", err)
+			return nil, store.NewDefaultError()
+		}
+		node.ParentID = int(parentId.Int32)
+		nodes = append(nodes, node)
+		policyFolders = append(policyFolders, policyFolder)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read folders", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	// denials, ordered by permission so each permission is appended once and then extended
+	rows, err = br.Query()
+	if err != nil {
+		logger.Error("failed to query permission denials", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	var denials []authz.Denial
+	for rows.Next() {
+		var deniedPermission string
+		var deniedGroup, deniedUser pgtype.Text
+		err = rows.Scan(&deniedPermission, &deniedGroup, &deniedUser)
+		if err != nil {
+			logger.Error("failed to scan permission denials", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		if len(denials) == 0 || denials[len(denials)-1].Permission != deniedPermission {
+			denials = append(denials, authz.Denial{Permission: deniedPermission})
+		}
+		denial := &denials[len(denials)-1]
+		if deniedGroup.Valid {
+			denial.Groups = append(denial.Groups, deniedGroup.String)
+		}
+		if deniedUser.Valid {
+			denial.Users = append(denial.Users, deniedUser.String)
+		}
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permission denials", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	policy := authz.NewPolicy(permissions, groups)
+	policy.Denials = denials
+	tree := folder.NewTree(nodes)
+	for i, node := range nodes {
+		names := []string{}
+		for _, parent := range tree.Path(node.ID) {
+			names = append(names, parent.Name)
+		}
+		policyFolder := policyFolders[i]
+		policyFolder.Path = strings.Join(names, "/")
+		if len(policyFolder.Groups) == 0 {
+			policyFolder.Groups = nil
+		}
+		if len(policyFolder.Grants) == 0 {
+			policyFolder.Grants = nil
+		}
+		policy.Folders = append(policy.Folders, policyFolder)
+	}
+	policy.ResolveFolders()
 
 	return policy, nil
 }
 
+// ReadPolicyPage returns a page of the policy, ordered by group name like ReadPolicy, and the cursor
+// of the next page, empty for the last page. The page holds the groups selected by the filter with
+// all their members, and the permissions granted to those groups, listing only the groups of the page.
+// The groups are read with a keyset query resuming after the name of the cursor, so large policies are
+// read a page at a time instead of whole. Folders are left out. A request without limit returns the
+// remaining groups.
+func (manager *PostgresPolicyManager) ReadPolicyPage(ctx context.Context, page paging.Request, filter store.PolicyFilter) (_ *authz.Policy, _ string, err error) {
+	ctx, operation := manager.start(ctx, "ReadPolicyPage")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ReadPolicyPage")
+
+	after, err := store.AfterName(page.After)
+	if err != nil {
+		logger.Error("invalid cursor")
+		return nil, "", err
+	}
+
+	rows, err := manager.db.Query(ctx, `
+	SELECT g.id, g.name, array(SELECT s.id FROM subjects s WHERE s.group_id = g.id ORDER BY s.id)
+	FROM groups g
+	WHERE g.name > $1 AND starts_with(g.name, $2) AND ($3 = '' OR g.id IN (SELECT group_id FROM subjects WHERE id = $3))
+	ORDER BY g.name LIMIT $4
+	`, after, filter.GroupPrefix, filter.User, pageLimit(page))
+	if err != nil {
+		logger.Error("failed to query groups", "error", err)
+		return nil, "", store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	groups := []authz.Group{}
+	groupIds := []int{}
+	for rows.Next() {
+		var groupId int
+		group := authz.Group{Users: []string{}}
+		err = rows.Scan(&groupId, &group.Name, &group.Users)
+		if err != nil {
+			logger.Error("failed to scan groups", "error", err)
+			return nil, "", store.NewDefaultError()
+		}
+		groups = append(groups, group)
+		groupIds = append(groupIds, groupId)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read groups", "error", rows.Err())
+		return nil, "", store.NewDefaultError()
+	}
+
+	groups, next := paging.Trim(groups, page.Limit, store.PolicyGroupKey)
+	groupIds = groupIds[:len(groups)]
+
+	permissions, err := manager.queryPagePermissions(ctx, logger, groupIds)
+	if err != nil {
+		return nil, "", err
+	}
+	return authz.NewPolicy(permissions, groups), next, nil
+}
+
+// queryPagePermissions returns the permissions granted to the groups with the given ids ordered by name,
+// each listing only those groups.
+func (manager *PostgresPolicyManager) queryPagePermissions(ctx context.Context, logger *slog.Logger, groupIds []int) ([]authz.Permission, error) {
+	permissions := []authz.Permission{}
+	if len(groupIds) == 0 {
+		return permissions, nil
+	}
+
+	rows, err := manager.db.Query(ctx, `
+	SELECT p.name, p.risk, p.condition, array(
+		SELECT i.name FROM permission_implications pi JOIN permissions i ON i.id = pi.implied_id
+		WHERE pi.permission_id = p.id ORDER BY i.name
+	) AS implies, array(
+		SELECT g.name FROM group_permissions gp JOIN groups g ON g.id = gp.group_id
+		WHERE gp.permission_id = p.id AND gp.group_id = ANY($1) ORDER BY g.name
+	) AS groups
+	FROM permissions p
+	WHERE p.id IN (SELECT permission_id FROM group_permissions WHERE group_id = ANY($1))
+	ORDER BY p.name
+	`, groupIds)
+	if err != nil {
+		logger.Error("failed to query permissions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission authz.Permission
+		var risk string
+		err = rows.Scan(&permission.Name, &risk, &permission.Condition, &permission.Implies, &permission.Groups)
+		if err != nil {
+			logger.Error("failed to scan permissions", "error", err)
+			return nil, store.NewDefaultError()
+		}
+		permission.Risk = authz.RiskLevel(risk)
+		if len(permission.Implies) == 0 {
+			permission.Implies = nil
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permissions", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+	return permissions, nil
+}
+
+// PolicyRevision returns the number of changes made to the policy so far, bumped by the database
+// on every change whoever made it, so a cached policy can be checked with a single row read.
+func (manager *PostgresPolicyManager) PolicyRevision(ctx context.Context) (_ int64, err error) {
+	ctx, operation := manager.start(ctx, "PolicyRevision")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "PolicyRevision")
+
+	var revision int64
+	err = manager.db.QueryRow(ctx, "SELECT revision FROM policy_revision").Scan(&revision)
+	if err != nil {
+		logger.Error("failed to query policy revision", "error", err)
+		return 0, store.NewDataBaseError()
+	}
+	return revision, nil
+}
+
+// Export returns the whole policy graph with the metadata of the groups and permissions and the
+// source of every membership. Like ReadPolicy everything is sorted by name, so exports are stable.
+func (manager *PostgresPolicyManager) Export(ctx context.Context) (_ *store.PolicyDocument, err error) {
+	ctx, operation := manager.start(ctx, "Export")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "Export")
+
+	batch := pgx.Batch{}
+	batch.Queue(`
+	SELECT g.name, g.description, g.labels, s.id, s.source
+	FROM groups g LEFT JOIN subjects s ON g.id = s.group_id
+	ORDER BY g.name, s.id;
+	`)
+	batch.Queue(`
+	SELECT p.name, p.description, p.labels, p.risk, p.condition, array(
+		SELECT g.name FROM group_permissions gp JOIN groups g ON g.id = gp.group_id
+		WHERE gp.permission_id = p.id ORDER BY g.name
+	) AS groups, array(
+		SELECT i.name FROM permission_implications pi JOIN permissions i ON i.id = pi.implied_id
+		WHERE pi.permission_id = p.id ORDER BY i.name
+	) AS implies
+	FROM permissions p
+	ORDER BY p.name;
+	`)
+	batch.Queue(`
+	SELECT p.name, array(
+		SELECT g.name FROM permission_denials d JOIN groups g ON g.id = d.group_id
+		WHERE d.permission_id = p.id ORDER BY g.name
+	) AS groups, array(
+		SELECT d.user_id FROM permission_denials d
+		WHERE d.permission_id = p.id AND d.user_id IS NOT NULL ORDER BY d.user_id
+	) AS users
+	FROM permissions p
+	WHERE EXISTS (SELECT 1 FROM permission_denials d WHERE d.permission_id = p.id)
+	ORDER BY p.name;
+	`)
+
+	br := manager.db.SendBatch(ctx, &batch)
+	defer func() {
+		err := br.Close()
+		if err != nil {
+			logger.Error("failed to close batch results", "error", err)
+		}
+	}()
+
+	// groups and their members
+	rows, err := br.Query()
+	if err != nil {
+		logger.Error("failed to query groups", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	// rows are ordered by group, so each group is appended once and then extended
+	document := &store.PolicyDocument{Groups: []store.GroupDocument{}, Permissions: []store.PermissionDocument{}}
+	for rows.Next() {
+		var group store.GroupDocument
+		var userId, source pgtype.Text
+		err = rows.Scan(&group.Name, &group.Description, &group.Labels, &userId, &source)
+		if err != nil {
+			logger.Error("failed to scan group", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		if len(document.Groups) == 0 || document.Groups[len(document.Groups)-1].Name != group.Name {
+			if len(group.Labels) == 0 {
+				group.Labels = nil
+			}
+			group.Members = []store.MemberDocument{}
+			document.Groups = append(document.Groups, group)
+		}
+		if userId.Valid {
+			last := &document.Groups[len(document.Groups)-1]
+			last.Members = append(last.Members, store.MemberDocument{User: userId.String, Source: store.MembershipSource(source.String)})
+		}
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read groups", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	// permissions with their grants and implications
+	rows, err = br.Query()
+	if err != nil {
+		logger.Error("failed to query permissions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	for rows.Next() {
+		var permission store.PermissionDocument
+		var risk string
+		err = rows.Scan(&permission.Name, &permission.Description, &permission.Labels, &risk, &permission.Condition, &permission.Groups, &permission.Implies)
+		if err != nil {
+			logger.Error("failed to scan permission", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		permission.Risk = authz.RiskLevel(risk)
+		if len(permission.Labels) == 0 {
+			permission.Labels = nil
+		}
+		if permission.Groups == nil {
+			permission.Groups = []string{}
+		}
+		if len(permission.Implies) == 0 {
+			permission.Implies = nil
+		}
+		document.Permissions = append(document.Permissions, permission)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permissions", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	// denials of the permissions denied to any group or user
+	rows, err = br.Query()
+	if err != nil {
+		logger.Error("failed to query permission denials", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	for rows.Next() {
+		var denial store.DenialDocument
+		err = rows.Scan(&denial.Permission, &denial.Groups, &denial.Users)
+		if err != nil {
+			logger.Error("failed to scan permission denial", "error", err)
+			return nil, store.NewDefaultError()
+		}
+
+		if len(denial.Groups) == 0 {
+			denial.Groups = nil
+		}
+		if len(denial.Users) == 0 {
+			denial.Users = nil
+		}
+		document.Denials = append(document.Denials, denial)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permission denials", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	return document, nil
+}
+
+// Import replaces the whole policy with the given document in a single transaction: groups and
+// permissions are matched by name, so existing ones keep their ids and have their version bumped,
+// those missing from the document are deleted, deleted groups leaving a tombstone, and every
+// membership, grant, implication and denial is replaced by those of the document.
+// An invalid document fails with an InvalidArgument error before the store is touched.
+func (manager *PostgresPolicyManager) Import(ctx context.Context, document *store.PolicyDocument) (err error) {
+	ctx, operation := manager.start(ctx, "Import")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("groups", len(document.Groups), "permissions", len(document.Permissions), "operation", "Import")
+
+	err = document.Validate()
+	if err != nil {
+		logger.Error("invalid policy document", "error", err)
+		return store.NewInvalidArgumentError()
+	}
+	rows := flattenDocument(document)
+
+	// start a new transaction
+	tx, err := manager.db.Begin(ctx)
+	if err != nil {
+		logger.Error("failed to start transaction", "error", err)
+		return store.NewDataBaseError()
+	}
+	defer rollback(tx, ctx, logger)
+
+	// the statements run in order: dependents are cleared before the groups and permissions
+	// missing from the document are deleted, and recreated once every name has an id
+	statements := []struct {
+		step string
+		sql  string
+		args []any
+	}{
+		{"record group tombstones", `
+		INSERT INTO group_tombstones (group_id, name, version, members, grants)
+		SELECT g.id, g.name, g.version,
+			(SELECT count(*) FROM subjects s WHERE s.group_id = g.id),
+			(SELECT count(*) FROM group_permissions gp WHERE gp.group_id = g.id)
+		FROM groups g WHERE g.name <> ALL($1::text[])
+		`, []any{rows.groupNames}},
+		{"delete group users", "DELETE FROM subjects", nil},
+		{"delete group permissions", "DELETE FROM group_permissions", nil},
+		{"delete permission implications", "DELETE FROM permission_implications", nil},
+		{"delete permission denials", "DELETE FROM permission_denials", nil},
+		{"delete groups", "DELETE FROM groups WHERE name <> ALL($1::text[])", []any{rows.groupNames}},
+		{"delete permissions", "DELETE FROM permissions WHERE name <> ALL($1::text[])", []any{rows.permissionNames}},
+		{"upsert groups", `
+		INSERT INTO groups (name, version, description, labels)
+		SELECT name, 1, description, labels::jsonb FROM unnest($1::text[], $2::text[], $3::text[]) AS g(name, description, labels)
+		ON CONFLICT (name) DO UPDATE
+		SET description = EXCLUDED.description, labels = EXCLUDED.labels, version = groups.version + 1
+		`, []any{rows.groupNames, rows.groupDescriptions, rows.groupLabels}},
+		{"upsert permissions", `
+		INSERT INTO permissions (name, version, description, labels, risk, condition)
+		SELECT name, 1, description, labels::jsonb, risk, condition
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) AS p(name, description, labels, risk, condition)
+		ON CONFLICT (name) DO UPDATE
+		SET description = EXCLUDED.description, labels = EXCLUDED.labels, risk = EXCLUDED.risk, condition = EXCLUDED.condition,
+			version = permissions.version + 1
+		`, []any{rows.permissionNames, rows.permissionDescriptions, rows.permissionLabels, rows.permissionRisks, rows.permissionConditions}},
+		{"insert group users", `
+		INSERT INTO subjects (id, group_id, source)
+		SELECT m.user_id, g.id, m.source FROM unnest($1::text[], $2::text[], $3::text[]) AS m(group_name, user_id, source)
+		JOIN groups g ON g.name = m.group_name
+		`, []any{rows.memberGroups, rows.memberUsers, rows.memberSources}},
+		{"insert group permissions", `
+		INSERT INTO group_permissions (group_id, permission_id)
+		SELECT g.id, p.id FROM unnest($1::text[], $2::text[]) AS gp(permission_name, group_name)
+		JOIN groups g ON g.name = gp.group_name JOIN permissions p ON p.name = gp.permission_name
+		`, []any{rows.grantPermissions, rows.grantGroups}},
+		{"insert permission implications", `
+		INSERT INTO permission_implications (permission_id, implied_id)
+		SELECT p.id, i.id FROM unnest($1::text[], $2::text[]) AS pi(permission_name, implied_name)
+		JOIN permissions p ON p.name = pi.permission_name JOIN permissions i ON i.name = pi.implied_name
+		`, []any{rows.implyPermissions, rows.implyImplied}},
+		{"insert group denials", `
+		INSERT INTO permission_denials (permission_id, group_id)
+		SELECT p.id, g.id FROM unnest($1::text[], $2::text[]) AS pd(permission_name, group_name)
+		JOIN permissions p ON p.name = pd.permission_name JOIN groups g ON g.name = pd.group_name
+		`, []any{rows.denyGroupPermissions, rows.denyGroups}},
+		{"insert user denials", `
+		INSERT INTO permission_denials (permission_id, user_id)
+		SELECT p.id, pd.user_id FROM unnest($1::text[], $2::text[]) AS pd(permission_name, user_id)
+		JOIN permissions p ON p.name = pd.permission_name
+		`, []any{rows.denyUserPermissions, rows.denyUsers}},
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(ctx, statement.sql, statement.args...)
+		if err != nil {
+			logger.Error("failed to "+statement.step, "error", err)
+			return store.NewDataBaseError()
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		logger.Error("failed to commit transaction", "error", err)
+		return store.NewDataBaseError()
+	}
+
+	logger.Info("policy imported", "members", len(rows.memberUsers), "grants", len(rows.grantGroups), "implications", len(rows.implyImplied),
+		"denials", len(rows.denyGroups)+len(rows.denyUsers))
+	return nil
+}
+
+// documentRows is a validated PolicyDocument flattened into the parallel arrays unnested by Import.
+type documentRows struct {
+	groupNames, groupDescriptions, groupLabels                                 []string
+	memberGroups, memberUsers, memberSources                                   []string
+	permissionNames, permissionDescriptions, permissionLabels, permissionRisks []string
+	permissionConditions                                                       []string
+	grantPermissions, grantGroups                                              []string
+	implyPermissions, implyImplied                                             []string
+	denyGroupPermissions, denyGroups, denyUserPermissions, denyUsers           []string
+}
+
+func flattenDocument(document *store.PolicyDocument) *documentRows {
+	// empty arrays rather than NULL, so an empty document deletes everything
+	rows := &documentRows{
+		groupNames: []string{}, groupDescriptions: []string{}, groupLabels: []string{},
+		memberGroups: []string{}, memberUsers: []string{}, memberSources: []string{},
+		permissionNames: []string{}, permissionDescriptions: []string{}, permissionLabels: []string{}, permissionRisks: []string{},
+		permissionConditions: []string{}, grantPermissions: []string{}, grantGroups: []string{},
+		implyPermissions: []string{}, implyImplied: []string{},
+		denyGroupPermissions: []string{}, denyGroups: []string{}, denyUserPermissions: []string{}, denyUsers: []string{},
+	}
+	for _, group := range document.Groups {
+		rows.groupNames = append(rows.groupNames, group.Name)
+		rows.groupDescriptions = append(rows.groupDescriptions, group.Description)
+		rows.groupLabels = append(rows.groupLabels, labelsJSON(group.Labels))
+		for _, member := range group.Members {
+			// the document is validated, so the source parses
+			source, _ := store.ParseMembershipSource(string(member.Source))
+			rows.memberGroups = append(rows.memberGroups, group.Name)
+			rows.memberUsers = append(rows.memberUsers, member.User)
+			rows.memberSources = append(rows.memberSources, string(source))
+		}
+	}
+	for _, permission := range document.Permissions {
+		risk, _ := authz.ParseRiskLevel(string(permission.Risk))
+		rows.permissionNames = append(rows.permissionNames, permission.Name)
+		rows.permissionDescriptions = append(rows.permissionDescriptions, permission.Description)
+		rows.permissionLabels = append(rows.permissionLabels, labelsJSON(permission.Labels))
+		rows.permissionRisks = append(rows.permissionRisks, string(risk))
+		rows.permissionConditions = append(rows.permissionConditions, permission.Condition)
+		for _, group := range permission.Groups {
+			rows.grantPermissions = append(rows.grantPermissions, permission.Name)
+			rows.grantGroups = append(rows.grantGroups, group)
+		}
+		for _, implied := range permission.Implies {
+			rows.implyPermissions = append(rows.implyPermissions, permission.Name)
+			rows.implyImplied = append(rows.implyImplied, implied)
+		}
+	}
+	for _, denial := range document.Denials {
+		for _, group := range denial.Groups {
+			rows.denyGroupPermissions = append(rows.denyGroupPermissions, denial.Permission)
+			rows.denyGroups = append(rows.denyGroups, group)
+		}
+		for _, user := range denial.Users {
+			rows.denyUserPermissions = append(rows.denyUserPermissions, denial.Permission)
+			rows.denyUsers = append(rows.denyUsers, user)
+		}
+	}
+	return rows
+}
+
+// labelsJSON encodes labels for a jsonb column, an empty object when there are none.
+func labelsJSON(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(labels)
+	return string(encoded)
+}
+
+// ListGroups returns all the groups ordered by name.
+func (manager *PostgresPolicyManager) ListGroups(ctx context.Context) (_ []store.GroupInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "ListGroups")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ListGroups")
+	return manager.queryGroups(ctx, logger, selectGroups+" ORDER BY name")
+}
+
+// GetGroups returns the groups with the given ids ordered by name, in a single query.
+// Ids that match no group are left out of the result.
+func (manager *PostgresPolicyManager) GetGroups(ctx context.Context, ids []int) (_ []store.GroupInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "GetGroups", tracing.CountKey.Int(len(ids)))
+	defer func() { operation.end(err) }()
+	if len(ids) == 0 {
+		return []store.GroupInfo[int]{}, nil
+	}
+
+	logger := manager.logger.With("operation", "GetGroups")
+	return manager.queryGroups(ctx, logger, selectGroups+" WHERE id = ANY($1) ORDER BY name", ids)
+}
+
+const selectGroups = "SELECT id, name, version, description, labels FROM groups"
+
+func (manager *PostgresPolicyManager) queryGroups(ctx context.Context, logger *slog.Logger, sql string, args ...any) ([]store.GroupInfo[int], error) {
+	rows, err := manager.db.Query(ctx, sql, args...)
+	if err != nil {
+		logger.Error("failed to query groups", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	groups := []store.GroupInfo[int]{}
+	for rows.Next() {
+		var group store.GroupInfo[int]
+		err = rows.Scan(&group.ID, &group.Name, &group.Version, &group.Description, &group.Labels)
+		if err != nil {
+			logger.Error("failed to scan group", "error", err)
+			return nil, store.NewDefaultError()
+		}
+		groups = append(groups, group)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read groups", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	return groups, nil
+}
+
+// ListPermissions returns all the permissions ordered by name.
+func (manager *PostgresPolicyManager) ListPermissions(ctx context.Context) (_ []store.PermissionInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "ListPermissions")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ListPermissions")
+	return manager.queryPermissions(ctx, logger, selectPermissions+" ORDER BY name")
+}
+
+// GetPermissions returns the permissions with the given ids ordered by name, in a single query.
+// Ids that match no permission are left out of the result.
+func (manager *PostgresPolicyManager) GetPermissions(ctx context.Context, ids []int) (_ []store.PermissionInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "GetPermissions", tracing.CountKey.Int(len(ids)))
+	defer func() { operation.end(err) }()
+	if len(ids) == 0 {
+		return []store.PermissionInfo[int]{}, nil
+	}
+
+	logger := manager.logger.With("operation", "GetPermissions")
+	return manager.queryPermissions(ctx, logger, selectPermissions+" WHERE id = ANY($1) ORDER BY name", ids)
+}
+
+const selectPermissions = "SELECT id, name, version, risk, description, labels FROM permissions"
+
+func (manager *PostgresPolicyManager) queryPermissions(ctx context.Context, logger *slog.Logger, sql string, args ...any) ([]store.PermissionInfo[int], error) {
+	rows, err := manager.db.Query(ctx, sql, args...)
+	if err != nil {
+		logger.Error("failed to query permissions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	permissions := []store.PermissionInfo[int]{}
+	for rows.Next() {
+		var permission store.PermissionInfo[int]
+		var risk string
+		err = rows.Scan(&permission.ID, &permission.Name, &permission.Version, &risk, &permission.Description, &permission.Labels)
+		if err != nil {
+			logger.Error("failed to scan permission", "error", err)
+			return nil, store.NewDefaultError()
+		}
+		permission.Risk = authz.RiskLevel(risk)
+		permissions = append(permissions, permission)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read permissions", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	return permissions, nil
+}
+
+// ListGroupsPage returns a page of the groups ordered by id and the cursor of the next page, empty for
+// the last page. The page is read with a single query resuming after the id of the cursor, so large
+// policies are listed without reading every group. A request without limit returns the remaining groups.
+func (manager *PostgresPolicyManager) ListGroupsPage(ctx context.Context, page paging.Request) (_ []store.GroupInfo[int], _ string, err error) {
+	ctx, operation := manager.start(ctx, "ListGroupsPage")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ListGroupsPage")
+
+	after, err := store.AfterID(page.After)
+	if err != nil {
+		logger.Error("invalid cursor")
+		return nil, "", err
+	}
+	groups, err := manager.queryGroups(ctx, logger, selectGroups+" WHERE id > $1 ORDER BY id LIMIT $2", after, pageLimit(page))
+	if err != nil {
+		return nil, "", err
+	}
+
+	groups, next := paging.Trim(groups, page.Limit, store.GroupKey)
+	return groups, next, nil
+}
+
+// ListPermissionsPage returns a page of the permissions ordered by id and the cursor of the next page,
+// like ListGroupsPage.
+func (manager *PostgresPolicyManager) ListPermissionsPage(ctx context.Context, page paging.Request) (_ []store.PermissionInfo[int], _ string, err error) {
+	ctx, operation := manager.start(ctx, "ListPermissionsPage")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ListPermissionsPage")
+
+	after, err := store.AfterID(page.After)
+	if err != nil {
+		logger.Error("invalid cursor")
+		return nil, "", err
+	}
+	permissions, err := manager.queryPermissions(ctx, logger, selectPermissions+" WHERE id > $1 ORDER BY id LIMIT $2", after, pageLimit(page))
+	if err != nil {
+		return nil, "", err
+	}
+
+	permissions, next := paging.Trim(permissions, page.Limit, store.PermissionKey)
+	return permissions, next, nil
+}
+
+// pageLimit returns the number of rows a page query fetches: one more than the limit, telling
+// whether there is a next page, or nil, which Postgres reads as no limit, for a request without limit.
+func pageLimit(page paging.Request) *int {
+	if page.Limit <= 0 {
+		return nil
+	}
+	limit := page.Limit + 1
+	return &limit
+}
+
+// GetGroup returns the group with the specified id, with its members and the permissions granted to it.
+// The members are read along with the group; the permissions are read by a second query.
+//
+// Returns:
+//
+//	*store.GroupDetails[int, int, string] - the group.
+//	error - a GroupNotFound error if the group does not exist, or a DatabaseError if it cannot be read.
+func (manager *PostgresPolicyManager) GetGroup(ctx context.Context, groupId int) (_ *store.GroupDetails[int, int, string], err error) {
+	ctx, operation := manager.start(ctx, "GetGroup", tracing.GroupIDKey.Int(groupId))
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("group_id", groupId, "operation", "GetGroup")
+
+	var group store.GroupDetails[int, int, string]
+	err = manager.db.QueryRow(ctx, `
+	SELECT g.id, g.name, g.version, g.description, g.labels, array(SELECT s.id FROM subjects s WHERE s.group_id = g.id ORDER BY s.id)
+	FROM groups g WHERE g.id = $1
+	`, groupId).Scan(&group.ID, &group.Name, &group.Version, &group.Description, &group.Labels, &group.Users)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("group not found")
+		return nil, store.NewGroupNotFoundError()
+	}
+	if err != nil {
+		logger.Error("failed to query group", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	group.Permissions, err = manager.queryPermissions(ctx, logger,
+		selectPermissions+" WHERE id IN (SELECT permission_id FROM group_permissions WHERE group_id = $1) ORDER BY name", groupId)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetUserGroups returns the groups the user is a member of ordered by name, none for an unknown user.
+func (manager *PostgresPolicyManager) GetUserGroups(ctx context.Context, userId string) (_ []store.GroupInfo[int], err error) {
+	ctx, operation := manager.start(ctx, "GetUserGroups")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("user_id", userId, "operation", "GetUserGroups")
+	return manager.queryGroups(ctx, logger, selectGroups+" WHERE id IN (SELECT group_id FROM subjects WHERE id = $1) ORDER BY name", userId)
+}
+
+// The range of schema versions this manager supports, see sql/authz_postgres.sql.
+const (
+	MinSchemaVersion = 22
+	MaxSchemaVersion = 22
+)
+
+// requiredTables lists the tables the policy manager reads and writes.
+var requiredTables = []string{"schema_version", "groups", "permissions", "subjects", "group_permissions", "group_tombstones", "permission_implications", "policy_revision"}
+
+// CheckSchema reads the schema version stamped in the database and verifies this manager supports it.
+//
+// Returns:
+//
+//	int - the schema version.
+//	error - a SchemaVersionError if the version is not supported, a SchemaMismatch error
+//	if the database holds no schema version, or a DatabaseError if it cannot be read.
+func (manager *PostgresPolicyManager) CheckSchema(ctx context.Context) (_ int, err error) {
+	ctx, operation := manager.start(ctx, "CheckSchema")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "CheckSchema")
+
+	var version int
+	err = manager.db.QueryRow(ctx, "SELECT version FROM schema_version").Scan(&version)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UndefinedTable) {
+			logger.Error("database schema version is missing")
+			return 0, store.NewSchemaMismatchError()
+		}
+
+		logger.Error("failed to read schema version", "error", err)
+		return 0, store.NewDataBaseError()
+	}
+
+	if version < MinSchemaVersion || version > MaxSchemaVersion {
+		err := &store.SchemaVersionError{Found: version, Min: MinSchemaVersion, Max: MaxSchemaVersion}
+		logger.Error("unsupported database schema version", "error", err)
+		return version, err
+	}
+
+	return version, nil
+}
+
+// Health verifies the database is reachable, holds every table the manager relies on
+// and is stamped with a supported schema version.
+func (manager *PostgresPolicyManager) Health(ctx context.Context) (_ *store.Health, err error) {
+	ctx, operation := manager.start(ctx, "Health")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "Health")
+
+	start := time.Now()
+	var missing []string
+	err = manager.db.QueryRow(ctx, `
+	SELECT coalesce(array_agg(t.name), '{}')
+	FROM unnest($1::text[]) AS t(name)
+	WHERE to_regclass(t.name) IS NULL
+	`, requiredTables).Scan(&missing)
+	if err != nil {
+		logger.Error("failed to check database health", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	if len(missing) > 0 {
+		logger.Error("database schema is missing tables", "tables", missing)
+		return nil, store.NewSchemaMismatchError()
+	}
+
+	version, err := manager.CheckSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.Health{Latency: time.Since(start), SchemaVersion: version}, nil
+}
+
+// membershipSource returns the membership source of the context, the sources it takes over
+// and the sources it may not change, in the form the membership queries expect.
+func (manager *PostgresPolicyManager) membershipSource(ctx context.Context) (string, []string, []string) {
+	source := store.MembershipSourceFromContext(ctx)
+	return string(source), sourceNames(manager.precedence.Overridden(source)), sourceNames(manager.precedence.Protected(source))
+}
+
+func sourceNames(sources []store.MembershipSource) []string {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, string(source))
+	}
+	return names
+}
+
+// noChanges reports a mutating operation that left the store unchanged,
+// returning a NoChanges error when the manager is configured to do so.
+func (manager *PostgresPolicyManager) noChanges(logger *slog.Logger) error {
+	logger.Info("operation did not change anything")
+	if manager.reportNoChanges {
+		return store.NewNoChangesError()
+	}
+	return nil
+}
+
 func rollback(tx pgx.Tx, ctx context.Context, logger *slog.Logger) {
 	err := tx.Rollback(ctx)
 	if err != nil && err != pgx.ErrTxClosed {
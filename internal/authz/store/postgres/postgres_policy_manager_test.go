@@ -6,7 +6,11 @@ import (
 	"io"
 	"os"
 	"path"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"log/slog"
 
@@ -16,7 +20,12 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
 	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/store/storetest"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -33,6 +42,12 @@ func setupMockDbAndManager() (*MockPgDb, *MockTx, *MockRow, *PostgresPolicyManag
 	return mockDb, mockTx, mockRow, manager
 }
 
+func setupMockDbAndNoChangesManager() (*MockPgDb, *MockTx, *MockRow, *PostgresPolicyManager) {
+	mockDb, mockTx, mockRow, _ := setupMockDbAndManager()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return mockDb, mockTx, mockRow, NewPostgresPolicyManager(mockDb, logger, WithNoChangesError())
+}
+
 func setupMockQueryRow(mockDb *MockPgDb, mockRow *MockRow, ctx context.Context, groupId int, version int) {
 	mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{groupId}).Return(mockRow)
 	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
@@ -40,6 +55,14 @@ func setupMockQueryRow(mockDb *MockPgDb, mockRow *MockRow, ctx context.Context,
 	}).Return(nil)
 }
 
+func setupMockVersionAndName(mockDb *MockPgDb, mockRow *MockRow, ctx context.Context, groupId int, version int, name string) {
+	mockDb.On("QueryRow", ctx, "SELECT version, name FROM groups WHERE id = $1", []any{groupId}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = version
+		*(args[0].([]any)[1].(*string)) = name
+	}).Return(nil)
+}
+
 func assertPolicyStoreError(t *testing.T, err error, exp error) {
 	act := &store.PolicyStoreError{}
 	assert.ErrorAs(t, err, &act)
@@ -67,6 +90,48 @@ func TestUpdateGroupPermissions(t *testing.T) {
 		mockRow.AssertExpectations(t)
 	})
 
+	t.Run("no changes", func(t *testing.T) {
+		for _, expected := range []error{nil, store.NewNoChangesError()} {
+			mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+			if expected != nil {
+				mockDb, mockTx, mockRow, manager = setupMockDbAndNoChangesManager()
+			}
+
+			setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+			mockDb.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("MERGE 0"), nil).Once()
+			mockTx.On("Rollback", ctx).Return(nil)
+
+			err := manager.UpdateGroupPermissions(ctx, 1, []int{1, 2})
+			if expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assertPolicyStoreError(t, err, expected)
+			}
+
+			// the group version is left untouched
+			mockTx.AssertNumberOfCalls(t, "Exec", 1)
+			mockTx.AssertNotCalled(t, "Commit", ctx)
+		}
+	})
+
+	t.Run("ignores duplicate permissions", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("INSERT 0 1")
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{2, 1}, 1}).Return(mockTag, nil).Once()
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.UpdateGroupPermissions(ctx, 1, []int{2, 1, 2})
+		assert.NoError(t, err)
+
+		mockTx.AssertExpectations(t)
+	})
+
 	t.Run("group not found", func(t *testing.T) {
 		mockDb, _, mockRow, manager := setupMockDbAndManager()
 
@@ -144,11 +209,12 @@ func TestUpdateGroupPermissions(t *testing.T) {
 
 	t.Run("concurrency error", func(t *testing.T) {
 		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("INSERT 0 0")
+		mockTag := pgconn.NewCommandTag("UPDATE 0")
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
 		mockDb.On("Begin", ctx).Return(mockTx, nil)
-		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("MERGE 3"), nil)
 		mockTx.On("Rollback", ctx).Return(nil)
 
 		err := manager.UpdateGroupPermissions(ctx, 1, []int{1, 2, 3})
@@ -259,159 +325,338 @@ func TestCreatePermission(t *testing.T) {
 		mockRow.AssertExpectations(t)
 	})
 }
-func TestUpdateGroupUsers(t *testing.T) {
+func TestSetPermissionRisk(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("success", func(t *testing.T) {
-		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 1")
+	setupRiskRow := func(mockDb *MockPgDb, mockRow *MockRow, risk string, found bool, updated bool) {
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{risk, 1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*bool)) = found
+			*(args[0].([]any)[1].(*bool)) = updated
+		}).Return(nil)
+	}
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Begin", ctx).Return(mockTx, nil)
-		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
-		mockTx.On("Commit", ctx).Return(nil)
-		mockTx.On("Rollback", ctx).Return(nil)
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupRiskRow(mockDb, mockRow, "high", true, true)
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskHigh)
 		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
-		mockTx.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("group not found", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
+	t.Run("invalid risk level", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
 
-		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
-		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskLevel("critical"))
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
-		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupRiskRow(mockDb, mockRow, "low", false, false)
+
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskLow)
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
 
 		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on query row", func(t *testing.T) {
+	t.Run("unchanged", func(t *testing.T) {
 		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupRiskRow(mockDb, mockRow, "low", true, false)
 
-		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskLow)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unchanged with no changes error", func(t *testing.T) {
+		mockDb, _, mockRow, _ := setupMockDbAndManager()
+		manager := NewPostgresPolicyManager(mockDb, slog.New(slog.NewTextHandler(io.Discard, nil)), WithNoChangesError())
+		setupRiskRow(mockDb, mockRow, "low", true, false)
+
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskLow)
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
 		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		err := manager.SetPermissionRisk(ctx, 1, authz.RiskLow)
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
+		mockDb.AssertExpectations(t)
+	})
+}
+func TestSetPermissionCondition(t *testing.T) {
+	ctx := context.Background()
+
+	setupConditionRow := func(mockDb *MockPgDb, mockRow *MockRow, condition string, found bool, updated bool) {
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{condition, 1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*bool)) = found
+			*(args[0].([]any)[1].(*bool)) = updated
+		}).Return(nil)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupConditionRow(mockDb, mockRow, "resource.owner == user", true, true)
+
+		err := manager.SetPermissionCondition(ctx, 1, " resource.owner == user ")
+		assert.NoError(t, err)
+
 		mockDb.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on begin transaction", func(t *testing.T) {
-		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+	t.Run("remove condition", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupConditionRow(mockDb, mockRow, "", true, true)
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Begin", ctx).Return(mockTx, errors.New("db error"))
+		err := manager.SetPermissionCondition(ctx, 1, "")
+		assert.NoError(t, err)
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("invalid condition", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		err := manager.SetPermissionCondition(ctx, 1, "resource.owner ==")
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupConditionRow(mockDb, mockRow, "", false, false)
+
+		err := manager.SetPermissionCondition(ctx, 1, "")
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("unchanged with no changes error", func(t *testing.T) {
+		mockDb, _, mockRow, _ := setupMockDbAndManager()
+		manager := NewPostgresPolicyManager(mockDb, slog.New(slog.NewTextHandler(io.Discard, nil)), WithNoChangesError())
+		setupConditionRow(mockDb, mockRow, "", true, false)
+
+		err := manager.SetPermissionCondition(ctx, 1, "")
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		err := manager.SetPermissionCondition(ctx, 1, "")
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
 	})
+}
+func TestSetPermissionImplications(t *testing.T) {
+	ctx := context.Background()
+	versionQuery := "SELECT version FROM permissions WHERE id = $1"
+	versionUpdate := "UPDATE permissions SET version = version + 1 WHERE id = $1 AND version = $2"
+
+	setupRows := func(mockDb *MockPgDb, implied []int, cycle bool) {
+		versionRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, versionQuery, []any{1}).Return(versionRow)
+		versionRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 3
+		}).Return(nil)
 
-	t.Run("database error on exec merge users", func(t *testing.T) {
-		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 1")
+		cycleRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{implied, 1}).Return(cycleRow)
+		cycleRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*bool)) = cycle
+		}).Return(nil)
+	}
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+	t.Run("success", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+		setupRows(mockDb, []int{3, 2}, false)
 		mockDb.On("Begin", ctx).Return(mockTx, nil)
-		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, errors.New("db error"))
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{3, 2}, 1}).Return(pgconn.NewCommandTag("MERGE 2"), nil)
+		mockTx.On("Exec", ctx, versionUpdate, []any{1, 3}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
 		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		err := manager.SetPermissionImplications(ctx, 1, []int{3, 2, 3})
+		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
 		mockTx.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on exec update version", func(t *testing.T) {
-		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 1")
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, versionQuery, []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		err := manager.SetPermissionImplications(ctx, 1, []int{2})
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		setupRows(mockDb, []int{2}, true)
+
+		err := manager.SetPermissionImplications(ctx, 1, []int{2})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertExpectations(t)
+		mockDb.AssertNotCalled(t, "Begin", mock.Anything)
+	})
+
+	t.Run("implied permission not found", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+		setupRows(mockDb, []int{9}, false)
 		mockDb.On("Begin", ctx).Return(mockTx, nil)
-		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, errors.New("db error"))
-		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{9}, 1}).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
 		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		err := manager.SetPermissionImplications(ctx, 1, []int{9})
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
 
-		mockDb.AssertExpectations(t)
 		mockTx.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("concurrency error", func(t *testing.T) {
-		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 0")
+	t.Run("no changes", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndNoChangesManager()
+		setupRows(mockDb, []int{2}, false)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{2}, 1}).Return(pgconn.NewCommandTag("MERGE 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		err := manager.SetPermissionImplications(ctx, 1, []int{2})
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
+
+		mockTx.AssertNotCalled(t, "Exec", ctx, versionUpdate, mock.Anything)
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+		setupRows(mockDb, []int{2}, false)
 		mockDb.On("Begin", ctx).Return(mockTx, nil)
-		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
-		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{2}, 1}).Return(pgconn.NewCommandTag("MERGE 1"), nil).Once()
+		mockTx.On("Exec", ctx, versionUpdate, []any{1, 3}).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
 		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		err := manager.SetPermissionImplications(ctx, 1, []int{2})
 		assertPolicyStoreError(t, err, store.NewConcurrencyError())
-
-		mockDb.AssertExpectations(t)
-		mockTx.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
 	})
 }
-func TestUpdateUserGroups(t *testing.T) {
+
+func TestSetPermissionDenials(t *testing.T) {
 	ctx := context.Background()
+	versionQuery := "SELECT version FROM permissions WHERE id = $1"
+	versionUpdate := "UPDATE permissions SET version = version + 1 WHERE id = $1 AND version = $2"
+
+	setupVersion := func(mockDb *MockPgDb) {
+		versionRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, versionQuery, []any{1}).Return(versionRow)
+		versionRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 3
+		}).Return(nil)
+	}
 
 	t.Run("success", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("MERGE 1")
-
-		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1, 2, 3}, "user1"}).Return(mockTag, nil)
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+		setupVersion(mockDb)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{2}, 1}).Return(pgconn.NewCommandTag("MERGE 1"), nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]string{"mallory", "carol"}, 1}).Return(pgconn.NewCommandTag("MERGE 0"), nil)
+		mockTx.On("Exec", ctx, versionUpdate, []any{1, 3}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.UpdateUserGroups(ctx, "user1", []int{1, 2, 3})
+		err := manager.SetPermissionDenials(ctx, 1, []int{2, 2}, []string{" mallory", "carol", "mallory"})
 		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 	})
 
-	t.Run("database error on exec", func(t *testing.T) {
+	t.Run("empty user", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
 
-		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1, 2, 3}, "user1"}).Return(pgconn.CommandTag{}, errors.New("db error"))
+		err := manager.SetPermissionDenials(ctx, 1, nil, []string{" "})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
 
-		err := manager.UpdateUserGroups(ctx, "user1", []int{1, 2, 3})
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		mockDb.AssertExpectations(t)
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, versionQuery, []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		err := manager.SetPermissionDenials(ctx, 1, []int{2}, nil)
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+		setupVersion(mockDb)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{9}, 1}).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.SetPermissionDenials(ctx, 1, []int{9}, nil)
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndNoChangesManager()
+		setupVersion(mockDb)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]int{2}, 1}).Return(pgconn.NewCommandTag("MERGE 0"), nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]string{}, 1}).Return(pgconn.NewCommandTag("MERGE 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.SetPermissionDenials(ctx, 1, []int{2}, nil)
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
+
+		mockTx.AssertNotCalled(t, "Exec", ctx, versionUpdate, mock.Anything)
 	})
 }
-func TestDeleteGroup(t *testing.T) {
+
+func TestGrantPermission(t *testing.T) {
 	ctx := context.Background()
+	insert := "INSERT INTO group_permissions (group_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
 
 	t.Run("success", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("DELETE 1")
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "DELETE FROM groups WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{1, 2}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteGroup(ctx, 1)
+		err := manager.GrantPermission(ctx, 1, 2)
 		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
@@ -421,68 +666,103 @@ func TestDeleteGroup(t *testing.T) {
 		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
 		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
 
-		err := manager.DeleteGroup(ctx, 1)
+		err := manager.GrantPermission(ctx, 1, 2)
 		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
 
 		mockDb.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on query row", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
 
-		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
-		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{1, 2}).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation})
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteGroup(ctx, 1)
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		err := manager.GrantPermission(ctx, 1, 2)
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
 
 		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 	})
 
-	t.Run("database error on delete", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
+	t.Run("database error on insert", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "DELETE FROM groups WHERE id = $1 AND version = $2", []any{1, 1}).Return(pgconn.CommandTag{}, errors.New("db error"))
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{1, 2}).Return(pgconn.CommandTag{}, errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteGroup(ctx, 1)
+		err := manager.GrantPermission(ctx, 1, 2)
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 	})
 
 	t.Run("concurrency error", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("DELETE 0")
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "DELETE FROM groups WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{1, 2}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteGroup(ctx, 1)
+		err := manager.GrantPermission(ctx, 1, 2)
 		assertPolicyStoreError(t, err, store.NewConcurrencyError())
 
 		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 	})
-}
-func TestChangeGroupName(t *testing.T) {
-	ctx := context.Background()
 
-	t.Run("success", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 1")
+	t.Run("already granted", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndNoChangesManager()
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(mockTag, nil)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{1, 2}).Return(pgconn.NewCommandTag("INSERT 0 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
-		assert.NoError(t, err)
+		err := manager.GrantPermission(ctx, 1, 2)
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
 
-		mockDb.AssertExpectations(t)
-		mockRow.AssertExpectations(t)
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+}
+func TestAddGroupUser(t *testing.T) {
+	ctx := context.Background()
+	insert := "INSERT INTO subjects (id, group_id, source) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		ctx := store.WithMembershipSource(ctx, store.SourceAPI)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{"alice", 1, "api"}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.AddGroupUser(ctx, 1, " alice ")
+		assert.NoError(t, err)
+
+		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("empty user id", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		err := manager.AddGroupUser(ctx, 1, " ")
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("group not found", func(t *testing.T) {
@@ -491,511 +771,2574 @@ func TestChangeGroupName(t *testing.T) {
 		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
 		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
 
-		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		err := manager.AddGroupUser(ctx, 1, "alice")
 		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{"alice", 1, "manual"}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.AddGroupUser(ctx, 1, "alice")
+		assertPolicyStoreError(t, err, store.NewConcurrencyError())
+
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+
+	t.Run("already a member", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndNoChangesManager()
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, insert, []any{"alice", 1, "manual"}).Return(pgconn.NewCommandTag("INSERT 0 0"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.AddGroupUser(ctx, 1, "alice")
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
+
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+}
+
+func TestUpdateGroupUsers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on query row", func(t *testing.T) {
+	t.Run("normalizes users", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]string{"user1", "user2"}, 1, "manual", []string{}, []string{}}).Return(mockTag, nil).Once()
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.UpdateGroupUsers(ctx, 1, []string{" user1", "user2", "user1 "})
+		assert.NoError(t, err)
+
+		mockTx.AssertExpectations(t)
+	})
+
+	t.Run("source precedence", func(t *testing.T) {
+		mockDb, mockTx, mockRow, _ := setupMockDbAndManager()
+		precedence := store.SourcePrecedence{store.SourceLDAP, store.SourceSCIM, store.SourceManual}
+		manager := NewPostgresPolicyManager(mockDb, slog.New(slog.NewTextHandler(io.Discard, nil)), WithSourcePrecedence(precedence))
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
+		ctx := store.WithMembershipSource(ctx, store.SourceSCIM)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, []any{[]string{"user1"}, 1, "scim", []string{"manual", "api"}, []string{"ldap"}}).Return(mockTag, nil).Once()
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1"})
+		assert.NoError(t, err)
+
+		mockTx.AssertExpectations(t)
+	})
+
+	t.Run("empty user", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "  "})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
 		mockDb, _, mockRow, manager := setupMockDbAndManager()
 
 		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
-		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
 
-		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
 
 		mockDb.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error on exec update", func(t *testing.T) {
+	t.Run("database error on query row", func(t *testing.T) {
 		mockDb, _, mockRow, manager := setupMockDbAndManager()
 
-		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(pgconn.CommandTag{}, errors.New("db error"))
+		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
 
-		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("concurrency error", func(t *testing.T) {
-		mockDb, _, mockRow, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("UPDATE 0")
+	t.Run("database error on begin transaction", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
 
 		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
-		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(mockTag, nil)
+		mockDb.On("Begin", ctx).Return(mockTx, errors.New("db error"))
 
-		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
-		assertPolicyStoreError(t, err, store.NewConcurrencyError())
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
-}
-func TestDeleteUser(t *testing.T) {
-	ctx := context.Background()
 
-	t.Run("success", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("DELETE 1")
+	t.Run("database error on exec merge users", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
 
-		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(mockTag, nil)
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteUser(ctx, "user1")
-		assert.NoError(t, err)
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("database error", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
+	t.Run("database error on exec update version", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
 
-		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(pgconn.CommandTag{}, errors.New("db error"))
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, errors.New("db error"))
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(mockTag, nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteUser(ctx, "user1")
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
 	})
 
-	t.Run("no user records found for deletion", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
-		mockTag := pgconn.NewCommandTag("DELETE 0")
+	t.Run("concurrency error", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 0")
 
-		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(mockTag, nil)
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, "UPDATE groups SET version = version + 1 WHERE id = $1 AND version = $2", []any{1, 1}).Return(mockTag, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("MERGE 2"), nil)
+		mockTx.On("Rollback", ctx).Return(nil)
 
-		err := manager.DeleteUser(ctx, "user1")
-		assertPolicyStoreError(t, err, store.NewNoUserRecordsDeletedError())
+		err := manager.UpdateGroupUsers(ctx, 1, []string{"user1", "user2"})
+		assertPolicyStoreError(t, err, store.NewConcurrencyError())
 
 		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
 	})
 }
-func TestReadPolicy(t *testing.T) {
+func TestUpdateUserGroups(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("success", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
-		mockRowsPermissions := new(MockRows)
-
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
-		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
-		mockBatchResults.On("Close").Return(nil)
-
-		// Mock group users query
-		mockRowsGroups.On("Next").Return(true).Once()
-		mockRowsGroups.On("Next").Return(false).Once()
-		mockRowsGroups.On("Scan", mock.Anything, mock.Anything).
-			Run(func(args mock.Arguments) {
-				*(args[0].([]any)[0].(*string)) = "group1"
-				*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: "user1", Valid: true}
-			}).Return(nil)
-		mockRowsGroups.On("Err").Return(nil)
+		mockTag := pgconn.NewCommandTag("MERGE 1")
 
-		// Mock permissions query
-		mockRowsPermissions.On("Next").Return(true).Once()
-		mockRowsPermissions.On("Next").Return(false).Once()
-		mockRowsPermissions.On("Scan", mock.Anything, mock.Anything).
-			Run(func(args mock.Arguments) {
-				*(args[0].([]any)[0].(*string)) = "permission1"
-				*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: "group1", Valid: true}
-			}).Return(nil)
-		mockRowsPermissions.On("Err").Return(nil)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1, 2, 3}, "user1", "manual", []string{}, []string{}}).Return(mockTag, nil)
 
-		policy, err := manager.ReadPolicy(ctx)
+		err := manager.UpdateUserGroups(ctx, "user1", []int{1, 2, 3})
 		assert.NoError(t, err)
-		assert.NotNil(t, policy)
-		assert.Len(t, policy.Groups, 1)
-		assert.Len(t, policy.Permissions, 1)
-		assert.Equal(t, "group1", policy.Groups[0].Name)
-		assert.Equal(t, []string{"user1"}, policy.Groups[0].Users)
-		assert.Equal(t, "permission1", policy.Permissions[0].Name)
-		assert.Equal(t, []string{"group1"}, policy.Permissions[0].Groups)
 
 		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
-		mockRowsPermissions.AssertExpectations(t)
 	})
 
-	t.Run("database error on group users query", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
-
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, errors.New("db error")).Once()
-		mockBatchResults.On("Close").Return(nil)
+	t.Run("no changes", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndNoChangesManager()
 
-		policy, err := manager.ReadPolicy(ctx)
-		assertPolicyStoreError(t, err, store.NewDataBaseError())
-		assert.Nil(t, policy)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1}, "user1", "manual", []string{}, []string{}}).Return(pgconn.NewCommandTag("MERGE 0"), nil)
 
-		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
+		err := manager.UpdateUserGroups(ctx, "user1", []int{1})
+		assertPolicyStoreError(t, err, store.NewNoChangesError())
 	})
 
-	t.Run("error scanning group users", func(t *testing.T) {
+	t.Run("normalizes user and groups", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
-
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
-		mockBatchResults.On("Close").Return(nil)
+		mockTag := pgconn.NewCommandTag("MERGE 1")
 
-		mockRowsGroups.On("Next").Return(true).Once()
-		mockRowsGroups.On("Scan", mock.Anything, mock.Anything).Return(errors.New("scan error"))
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1, 2}, "user1", "manual", []string{}, []string{}}).Return(mockTag, nil)
 
-		policy, err := manager.ReadPolicy(ctx)
-		assertPolicyStoreError(t, err, store.NewDefaultError())
-		assert.Nil(t, policy)
+		err := manager.UpdateUserGroups(ctx, " user1 ", []int{1, 2, 1})
+		assert.NoError(t, err)
 
 		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
 	})
 
-	t.Run("error reading group users", func(t *testing.T) {
+	t.Run("empty user", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
 
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
-		mockBatchResults.On("Close").Return(nil)
+		err := manager.UpdateUserGroups(ctx, "", []int{1})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
 
-		mockRowsGroups.On("Next").Return(false).Once()
-		mockRowsGroups.On("Err").Return(errors.New("read error"))
+		mockDb.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		policy, err := manager.ReadPolicy(ctx)
-		assertPolicyStoreError(t, err, store.NewDefaultError())
-		assert.Nil(t, policy)
+	t.Run("database error on exec", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
 
-		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
+		mockDb.On("Exec", ctx, mock.Anything, []any{[]int{1, 2, 3}, "user1", "manual", []string{}, []string{}}).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		err := manager.UpdateUserGroups(ctx, "user1", []int{1, 2, 3})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockDb.AssertExpectations(t)
 	})
+}
+func TestDeleteGroup(t *testing.T) {
+	ctx := context.Background()
+	deleteGroupSql := "DELETE FROM groups WHERE id = $1 AND version = $2 RETURNING name"
+	tombstoneSql := "INSERT INTO group_tombstones (group_id, name, version, members, grants) VALUES ($1, $2, $3, $4, $5)"
 
-	t.Run("database error on permissions query", func(t *testing.T) {
+	setupDependents := func(mockTx *MockTx, subjectsErr error) {
+		mockTx.On("Exec", ctx, "DELETE FROM subjects WHERE group_id = $1", []any{1}).Return(pgconn.NewCommandTag("DELETE 2"), subjectsErr)
+		if subjectsErr == nil {
+			mockTx.On("Exec", ctx, "DELETE FROM group_permissions WHERE group_id = $1", []any{1}).Return(pgconn.NewCommandTag("DELETE 3"), nil)
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockDeleteRow := new(MockRow)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		setupDependents(mockTx, nil)
+		mockTx.On("QueryRow", ctx, deleteGroupSql, []any{1, 1}).Return(mockDeleteRow)
+		mockDeleteRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*string)) = "group1"
+		}).Return(nil)
+		mockTx.On("Exec", ctx, tombstoneSql, []any{1, "group1", 1, 2, 3}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		deletion, err := manager.DeleteGroup(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, &store.GroupDeletion{Members: 2, Grants: 3}, deletion)
+
+		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+		mockDeleteRow.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		deletion, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+		assert.Nil(t, deletion)
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("database error on query row", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, "SELECT version FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("database error on begin transaction", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, errors.New("db error"))
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("database error on delete dependents", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		setupDependents(mockTx, errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockTx.AssertExpectations(t)
+		mockTx.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error on delete", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockDeleteRow := new(MockRow)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		setupDependents(mockTx, nil)
+		mockTx.On("QueryRow", ctx, deleteGroupSql, []any{1, 1}).Return(mockDeleteRow)
+		mockDeleteRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockDeleteRow := new(MockRow)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		setupDependents(mockTx, nil)
+		mockTx.On("QueryRow", ctx, deleteGroupSql, []any{1, 1}).Return(mockDeleteRow)
+		mockDeleteRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewConcurrencyError())
+
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+
+	t.Run("database error on tombstone", func(t *testing.T) {
+		mockDb, mockTx, mockRow, manager := setupMockDbAndManager()
+		mockDeleteRow := new(MockRow)
+
+		setupMockQueryRow(mockDb, mockRow, ctx, 1, 1)
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		setupDependents(mockTx, nil)
+		mockTx.On("QueryRow", ctx, deleteGroupSql, []any{1, 1}).Return(mockDeleteRow)
+		mockDeleteRow.On("Scan", mock.Anything).Return(nil)
+		mockTx.On("Exec", ctx, tombstoneSql, mock.Anything).Return(pgconn.CommandTag{}, errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		_, err := manager.DeleteGroup(ctx, 1)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+}
+func TestChangeGroupName(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 1")
+
+		setupMockVersionAndName(mockDb, mockRow, ctx, 1, 1, "group-name")
+		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(mockTag, nil)
+
+		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		assert.NoError(t, err)
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, "SELECT version, name FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("database error on query row", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, "SELECT version, name FROM groups WHERE id = $1", []any{1}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("database error on exec update", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		setupMockVersionAndName(mockDb, mockRow, ctx, 1, 1, "group-name")
+		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("concurrency error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("UPDATE 0")
+
+		setupMockVersionAndName(mockDb, mockRow, ctx, 1, 1, "group-name")
+		mockDb.On("Exec", ctx, "UPDATE groups SET name = $1, version = version + 1 WHERE id = $2 AND version = $3", []any{"new-group-name", 1, 1}).Return(mockTag, nil)
+
+		err := manager.ChangeGroupName(ctx, 1, "new-group-name")
+		assertPolicyStoreError(t, err, store.NewConcurrencyError())
+
+		mockDb.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("same name", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		setupMockVersionAndName(mockDb, mockRow, ctx, 1, 1, "group-name")
+
+		err := manager.ChangeGroupName(ctx, 1, "group-name")
+		assert.NoError(t, err)
+
+		mockDb.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
+		mockTag := pgconn.NewCommandTag("DELETE 1")
 
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
-		mockBatchResults.On("Query").Return(mockRowsGroups, errors.New("db error")).Once()
-		mockBatchResults.On("Close").Return(nil)
+		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(mockTag, nil)
 
-		mockRowsGroups.On("Next").Return(false).Once()
-		mockRowsGroups.On("Err").Return(nil)
+		err := manager.DeleteUser(ctx, "user1")
+		assert.NoError(t, err)
 
-		policy, err := manager.ReadPolicy(ctx)
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		err := manager.DeleteUser(ctx, "user1")
 		assertPolicyStoreError(t, err, store.NewDataBaseError())
-		assert.Nil(t, policy)
 
 		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
 	})
 
-	t.Run("error scanning permissions", func(t *testing.T) {
+	t.Run("empty user", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		err := manager.DeleteUser(ctx, " ")
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("no user records found for deletion", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockTag := pgconn.NewCommandTag("DELETE 0")
+
+		mockDb.On("Exec", ctx, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(mockTag, nil)
+
+		err := manager.DeleteUser(ctx, "user1")
+		assertPolicyStoreError(t, err, store.NewNoUserRecordsDeletedError())
+
+		mockDb.AssertExpectations(t)
+	})
+}
+
+// emptyRows returns rows holding no row.
+func emptyRows() *MockRows {
+	rows := new(MockRows)
+	rows.On("Next").Return(false)
+	rows.On("Err").Return(nil)
+	return rows
+}
+
+func TestReadPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
 		mockDb, _, _, manager := setupMockDbAndManager()
 		mockBatchResults := new(MockBatchResults)
 		mockRowsGroups := new(MockRows)
 		mockRowsPermissions := new(MockRows)
+		mockRowsFolders := new(MockRows)
 
 		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
 		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
 		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsFolders, nil).Once()
+		mockBatchResults.On("Query").Return(emptyRows(), nil).Once()
 		mockBatchResults.On("Close").Return(nil)
+		mockRowsFolders.On("Next").Return(false).Once()
+		mockRowsFolders.On("Err").Return(nil)
 
+		// Mock group users query
+		mockRowsGroups.On("Next").Return(true).Once()
 		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Scan", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = "group1"
+				*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: "user1", Valid: true}
+			}).Return(nil)
 		mockRowsGroups.On("Err").Return(nil)
 
+		// Mock permissions query
 		mockRowsPermissions.On("Next").Return(true).Once()
+		mockRowsPermissions.On("Next").Return(false).Once()
 		mockRowsPermissions.On("Scan", mock.Anything, mock.Anything).
-			Return(errors.New("scan error"))
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = "permission1"
+				*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: "group1", Valid: true}
+				*(args[0].([]any)[2].(*string)) = "medium"
+				*(args[0].([]any)[3].(*string)) = "resource.owner == user"
+				*(args[0].([]any)[4].(*[]string)) = []string{"permission0"}
+			}).Return(nil)
+		mockRowsPermissions.On("Err").Return(nil)
 
 		policy, err := manager.ReadPolicy(ctx)
-		assertPolicyStoreError(t, err, store.NewDefaultError())
-		assert.Nil(t, policy)
+		assert.NoError(t, err)
+		assert.NotNil(t, policy)
+		assert.Len(t, policy.Groups, 1)
+		assert.Len(t, policy.Permissions, 1)
+		assert.Equal(t, "group1", policy.Groups[0].Name)
+		assert.Equal(t, []string{"user1"}, policy.Groups[0].Users)
+		assert.Equal(t, "permission1", policy.Permissions[0].Name)
+		assert.Equal(t, []string{"group1"}, policy.Permissions[0].Groups)
+		assert.Equal(t, authz.RiskMedium, policy.Permissions[0].Risk)
+		assert.Equal(t, []string{"permission0"}, policy.Permissions[0].Implies)
+		assert.Equal(t, "resource.owner == user", policy.Permissions[0].Condition)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+		mockRowsPermissions.AssertExpectations(t)
+	})
+
+	t.Run("multiple users and grants", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+		mockRowsPermissions := new(MockRows)
+		mockRowsFolders := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsFolders, nil).Once()
+		mockBatchResults.On("Query").Return(emptyRows(), nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+		mockRowsFolders.On("Next").Return(false).Once()
+		mockRowsFolders.On("Err").Return(nil)
+
+		// Mock group users query, ordered by group and user
+		groupRows := [][2]string{{"group1", "user1"}, {"group1", "user2"}, {"group2", ""}}
+		for _, row := range groupRows {
+			mockRowsGroups.On("Next").Return(true).Once()
+			mockRowsGroups.On("Scan", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*(args[0].([]any)[0].(*string)) = row[0]
+					*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: row[1], Valid: row[1] != ""}
+				}).Return(nil).Once()
+		}
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(nil)
+
+		// Mock permissions query, ordered by permission and group
+		permissionRows := [][2]string{{"permission1", "group1"}, {"permission1", "group2"}, {"permission2", ""}}
+		for _, row := range permissionRows {
+			mockRowsPermissions.On("Next").Return(true).Once()
+			mockRowsPermissions.On("Scan", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*(args[0].([]any)[0].(*string)) = row[0]
+					*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: row[1], Valid: row[1] != ""}
+					*(args[0].([]any)[2].(*string)) = "low"
+				}).Return(nil).Once()
+		}
+		mockRowsPermissions.On("Next").Return(false).Once()
+		mockRowsPermissions.On("Err").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []authz.Group{
+			{Name: "group1", Users: []string{"user1", "user2"}},
+			{Name: "group2", Users: []string{}},
+		}, policy.Groups)
+		assert.Equal(t, []authz.Permission{
+			{Name: "permission1", Groups: []string{"group1", "group2"}, Risk: authz.RiskLow},
+			{Name: "permission2", Groups: []string{}, Risk: authz.RiskLow},
+		}, policy.Permissions)
+		assert.Empty(t, policy.Folders)
+	})
+
+	t.Run("folder grants", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+		mockRowsPermissions := new(MockRows)
+		mockRowsFolders := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsFolders, nil).Once()
+		mockBatchResults.On("Query").Return(emptyRows(), nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		for _, name := range []string{"bakers", "cooks"} {
+			mockRowsGroups.On("Next").Return(true).Once()
+			mockRowsGroups.On("Scan", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*(args[0].([]any)[0].(*string)) = name
+				}).Return(nil).Once()
+		}
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(nil)
+
+		mockRowsPermissions.On("Next").Return(true).Once()
+		mockRowsPermissions.On("Scan", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = "recipes.read"
+				*(args[0].([]any)[2].(*string)) = "low"
+			}).Return(nil).Once()
+		mockRowsPermissions.On("Next").Return(false).Once()
+		mockRowsPermissions.On("Err").Return(nil)
+
+		// the kitchen folder grants recipes.read to cooks and, through inheritance, to bakers in kitchen/pastry
+		mockRowsFolders.On("Next").Return(true).Once()
+		mockRowsFolders.On("Scan", mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*int)) = 1
+				*(args[0].([]any)[1].(*string)) = "kitchen"
+				*(args[0].([]any)[4].(*[]string)) = []string{"cooks"}
+				*(args[0].([]any)[5].(*[]string)) = []string{"recipes.read"}
+			}).Return(nil).Once()
+		mockRowsFolders.On("Next").Return(true).Once()
+		mockRowsFolders.On("Scan", mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*int)) = 2
+				*(args[0].([]any)[1].(*string)) = "pastry"
+				*(args[0].([]any)[2].(*pgtype.Int4)) = pgtype.Int4{Int32: 1, Valid: true}
+				*(args[0].([]any)[3].(*authz.Inheritance)) = authz.InheritanceInherit
+				*(args[0].([]any)[4].(*[]string)) = []string{"bakers"}
+				*(args[0].([]any)[5].(*[]string)) = []string{}
+			}).Return(nil).Once()
+		mockRowsFolders.On("Next").Return(false).Once()
+		mockRowsFolders.On("Err").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []authz.Folder{
+			{Path: "kitchen", Groups: []string{"cooks"}, Grants: []string{"recipes.read"}},
+			{Path: "kitchen/pastry", Groups: []string{"bakers"}, Inheritance: authz.InheritanceInherit},
+		}, policy.Folders)
+		assert.Equal(t, []string{"cooks", "bakers"}, policy.Permissions[0].Groups)
+	})
+
+	t.Run("denials", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsDenials := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(emptyRows(), nil).Times(3)
+		mockBatchResults.On("Query").Return(mockRowsDenials, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		// Mock denials query, ordered by permission, group and user
+		denialRows := [][3]string{{"recipes.delete", "interns", ""}, {"recipes.delete", "", "mallory"}, {"recipes.write", "", "carol"}}
+		for _, row := range denialRows {
+			mockRowsDenials.On("Next").Return(true).Once()
+			mockRowsDenials.On("Scan", mock.Anything).
+				Run(func(args mock.Arguments) {
+					*(args[0].([]any)[0].(*string)) = row[0]
+					*(args[0].([]any)[1].(*pgtype.Text)) = pgtype.Text{String: row[1], Valid: row[1] != ""}
+					*(args[0].([]any)[2].(*pgtype.Text)) = pgtype.Text{String: row[2], Valid: row[2] != ""}
+				}).Return(nil).Once()
+		}
+		mockRowsDenials.On("Next").Return(false).Once()
+		mockRowsDenials.On("Err").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []authz.Denial{
+			{Permission: "recipes.delete", Groups: []string{"interns"}, Users: []string{"mallory"}},
+			{Permission: "recipes.write", Users: []string{"carol"}},
+		}, policy.Denials)
+		mockBatchResults.AssertExpectations(t)
+	})
+
+	t.Run("database error on denials query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(emptyRows(), nil).Times(3)
+		mockBatchResults.On("Query").Return((*MockRows)(nil), errors.New("db error")).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assert.Nil(t, policy)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+
+	t.Run("database error on folders query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRows := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRows, nil).Twice()
+		mockBatchResults.On("Query").Return(mockRows, errors.New("db error")).Once()
+		mockBatchResults.On("Close").Return(nil)
+		mockRows.On("Next").Return(false)
+		mockRows.On("Err").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, policy)
+	})
+
+	t.Run("database error on group users query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, errors.New("db error")).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+	})
+
+	t.Run("error scanning group users", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		mockRowsGroups.On("Next").Return(true).Once()
+		mockRowsGroups.On("Scan", mock.Anything, mock.Anything).Return(errors.New("scan error"))
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+	})
+
+	t.Run("error reading group users", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(errors.New("read error"))
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+	})
+
+	t.Run("database error on permissions query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsGroups, errors.New("db error")).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(nil)
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+	})
+
+	t.Run("error scanning permissions", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+		mockRowsPermissions := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(nil)
+
+		mockRowsPermissions.On("Next").Return(true).Once()
+		mockRowsPermissions.On("Scan", mock.Anything, mock.Anything).
+			Return(errors.New("scan error"))
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+		mockRowsPermissions.AssertExpectations(t)
+	})
+
+	t.Run("error reading permissions", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+		mockRowsPermissions := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		mockRowsGroups.On("Next").Return(false).Once()
+		mockRowsGroups.On("Err").Return(nil)
+		mockRowsPermissions.On("Next").Return(false).Once()
+		mockRowsPermissions.On("Err").Return(errors.New("read error"))
+
+		policy, err := manager.ReadPolicy(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, policy)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+		mockRowsPermissions.AssertExpectations(t)
+	})
+}
+func TestListGroups(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, "SELECT id, name, version, description, labels FROM groups ORDER BY name", []any(nil)).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "group1"
+			*(args[0].([]any)[2].(*int)) = 2
+			*(args[0].([]any)[3].(*string)) = "Recipe authors"
+			*(args[0].([]any)[4].(*map[string]string)) = map[string]string{"team": "kitchen"}
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		groups, err := manager.ListGroups(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []store.GroupInfo[int]{{ID: 1, Name: "group1", Version: 2,
+			Metadata: store.Metadata{Description: "Recipe authors", Labels: map[string]string{"team": "kitchen"}}}}, groups)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		groups, err := manager.ListGroups(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, groups)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("error scanning groups", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Scan", mock.Anything).Return(errors.New("scan error"))
+		mockRows.On("Close").Return()
+
+		groups, err := manager.ListGroups(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, groups)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("error reading groups", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Err").Return(errors.New("read error"))
+		mockRows.On("Close").Return()
+
+		groups, err := manager.ListGroups(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, groups)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+}
+
+func TestPolicyRevision(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, "SELECT revision FROM policy_revision", mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int64)) = 42
+		}).Return(nil)
+
+		revision, err := manager.PolicyRevision(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), revision)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, "SELECT revision FROM policy_revision", mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("connection reset"))
+
+		_, err := manager.PolicyRevision(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+		mockRowsGroups := new(MockRows)
+		mockRowsPermissions := new(MockRows)
+		mockRowsDenials := new(MockRows)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
+		mockBatchResults.On("Query").Return(mockRowsDenials, nil).Once()
+		mockBatchResults.On("Close").Return(nil)
+
+		// two members of the first group and an empty second group
+		members := []pgtype.Text{{String: "alice", Valid: true}, {String: "bob", Valid: true}, {}}
+		sources := []pgtype.Text{{String: "manual", Valid: true}, {String: "ldap", Valid: true}, {}}
+		groups := []string{"cooks", "cooks", "editors"}
+		mockRowsGroups.On("Next").Return(true).Times(3)
+		mockRowsGroups.On("Next").Return(false).Once()
+		row := 0
+		mockRowsGroups.On("Scan", mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = groups[row]
+				if row == 0 {
+					*(args[0].([]any)[1].(*string)) = "Kitchen staff"
+					*(args[0].([]any)[2].(*map[string]string)) = map[string]string{"team": "kitchen"}
+				}
+				*(args[0].([]any)[3].(*pgtype.Text)) = members[row]
+				*(args[0].([]any)[4].(*pgtype.Text)) = sources[row]
+				row++
+			}).Return(nil)
+		mockRowsGroups.On("Err").Return(nil)
+
+		mockRowsPermissions.On("Next").Return(true).Once()
+		mockRowsPermissions.On("Next").Return(false).Once()
+		mockRowsPermissions.On("Scan", mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = "recipes.write"
+				*(args[0].([]any)[3].(*string)) = "high"
+				*(args[0].([]any)[4].(*string)) = "resource.owner == user"
+				*(args[0].([]any)[5].(*[]string)) = []string{"editors"}
+				*(args[0].([]any)[6].(*[]string)) = []string{}
+			}).Return(nil)
+		mockRowsPermissions.On("Err").Return(nil)
+
+		// a permission denied to a user only
+		mockRowsDenials.On("Next").Return(true).Once()
+		mockRowsDenials.On("Next").Return(false).Once()
+		mockRowsDenials.On("Scan", mock.Anything).
+			Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*string)) = "recipes.write"
+				*(args[0].([]any)[1].(*[]string)) = []string{}
+				*(args[0].([]any)[2].(*[]string)) = []string{"mallory"}
+			}).Return(nil)
+		mockRowsDenials.On("Err").Return(nil)
+
+		document, err := manager.Export(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &store.PolicyDocument{
+			Groups: []store.GroupDocument{
+				{Name: "cooks", Description: "Kitchen staff", Labels: map[string]string{"team": "kitchen"},
+					Members: []store.MemberDocument{{User: "alice", Source: store.SourceManual}, {User: "bob", Source: store.SourceLDAP}}},
+				{Name: "editors", Members: []store.MemberDocument{}},
+			},
+			Permissions: []store.PermissionDocument{
+				{Name: "recipes.write", Risk: authz.RiskHigh, Groups: []string{"editors"}, Condition: "resource.owner == user"},
+			},
+			Denials: []store.DenialDocument{
+				{Permission: "recipes.write", Users: []string{"mallory"}},
+			},
+		}, document)
+
+		mockDb.AssertExpectations(t)
+		mockBatchResults.AssertExpectations(t)
+		mockRowsGroups.AssertExpectations(t)
+		mockRowsPermissions.AssertExpectations(t)
+		mockRowsDenials.AssertExpectations(t)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockBatchResults := new(MockBatchResults)
+
+		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
+		mockBatchResults.On("Query").Return(new(MockRows), errors.New("db error"))
+		mockBatchResults.On("Close").Return(nil)
+
+		document, err := manager.Export(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, document)
+	})
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	document := &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: "cooks", Labels: map[string]string{"team": "kitchen"}, Members: []store.MemberDocument{{User: "alice"}, {User: "bob", Source: store.SourceSCIM}}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes.read", Groups: []string{"cooks"}},
+			{Name: "recipes.write", Risk: authz.RiskHigh, Groups: []string{}, Implies: []string{"recipes.read"}, Condition: "resource.owner == user"},
+		},
+		Denials: []store.DenialDocument{
+			{Permission: "recipes.write", Groups: []string{"cooks"}, Users: []string{"mallory"}},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.Import(ctx, document)
+		assert.NoError(t, err)
+
+		// every statement runs, with the document flattened into arrays joined by name
+		mockTx.AssertNumberOfCalls(t, "Exec", 14)
+		mockTx.AssertCalled(t, "Exec", ctx, "DELETE FROM subjects", []any(nil))
+		mockTx.AssertCalled(t, "Exec", ctx, "DELETE FROM permission_denials", []any(nil))
+		mockTx.AssertCalled(t, "Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return strings.Contains(sql, "INSERT INTO permission_denials (permission_id, group_id)")
+		}),
+			[]any{[]string{"recipes.write"}, []string{"cooks"}})
+		mockTx.AssertCalled(t, "Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return strings.Contains(sql, "INSERT INTO permission_denials (permission_id, user_id)")
+		}),
+			[]any{[]string{"recipes.write"}, []string{"mallory"}})
+		mockTx.AssertCalled(t, "Exec", ctx, mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, "INSERT INTO subjects") }),
+			[]any{[]string{"cooks", "cooks"}, []string{"alice", "bob"}, []string{"manual", "scim"}})
+		mockTx.AssertCalled(t, "Exec", ctx, mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, "INSERT INTO permissions") }),
+			[]any{[]string{"recipes.read", "recipes.write"}, []string{"", ""}, []string{"{}", "{}"}, []string{"low", "high"}, []string{"", "resource.owner == user"}})
+		mockTx.AssertCalled(t, "Exec", ctx, mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, "INSERT INTO groups") }),
+			[]any{[]string{"cooks"}, []string{""}, []string{`{"team":"kitchen"}`}})
+		mockDb.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
+	})
+
+	t.Run("empty document", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("DELETE 0"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.Import(ctx, &store.PolicyDocument{})
+		assert.NoError(t, err)
+
+		// empty arrays rather than NULL, which would match no row
+		mockTx.AssertCalled(t, "Exec", ctx, "DELETE FROM groups WHERE name <> ALL($1::text[])", []any{[]string{}})
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		err := manager.Import(ctx, &store.PolicyDocument{Permissions: []store.PermissionDocument{{Name: "read", Groups: []string{"cooks"}}}})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "Begin", mock.Anything)
+	})
+
+	t.Run("database error on statement", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag(""), errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.Import(ctx, document)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+
+		mockTx.AssertNumberOfCalls(t, "Exec", 1)
+		mockTx.AssertNotCalled(t, "Commit", mock.Anything)
+	})
+
+	t.Run("database error on commit", func(t *testing.T) {
+		mockDb, mockTx, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Commit", ctx).Return(errors.New("db error"))
+		mockTx.On("Rollback", ctx).Return(nil)
+
+		err := manager.Import(ctx, document)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+}
+
+func setupMockSchemaVersion(mockDb *MockPgDb, mockRow *MockRow, ctx context.Context, version int, err error) {
+	mockDb.On("QueryRow", ctx, "SELECT version FROM schema_version", mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = version
+	}).Return(err)
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("supported", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, MinSchemaVersion, nil)
+
+		version, err := manager.CheckSchema(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, MinSchemaVersion, version)
+	})
+
+	t.Run("too new", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, MaxSchemaVersion+1, nil)
+
+		_, err := manager.CheckSchema(ctx)
+		var versionErr *store.SchemaVersionError
+		assert.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, &store.SchemaVersionError{Found: MaxSchemaVersion + 1, Min: MinSchemaVersion, Max: MaxSchemaVersion}, versionErr)
+		assert.Contains(t, err.Error(), "newer")
+	})
+
+	t.Run("too old", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, MinSchemaVersion-1, nil)
+
+		_, err := manager.CheckSchema(ctx)
+		assert.Contains(t, err.Error(), "older")
+	})
+
+	t.Run("missing version table", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, 0, &pgconn.PgError{Code: pgerrcode.UndefinedTable})
+
+		_, err := manager.CheckSchema(ctx)
+		assertPolicyStoreError(t, err, store.NewSchemaMismatchError())
+	})
+
+	t.Run("missing version row", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, 0, pgx.ErrNoRows)
+
+		_, err := manager.CheckSchema(ctx)
+		assertPolicyStoreError(t, err, store.NewSchemaMismatchError())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		setupMockSchemaVersion(mockDb, mockRow, ctx, 0, errors.New("connection refused"))
+
+		_, err := manager.CheckSchema(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+}
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("healthy", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockVersionRow := new(MockRow)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{requiredTables}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil)
+		setupMockSchemaVersion(mockDb, mockVersionRow, ctx, MaxSchemaVersion, nil)
+
+		health, err := manager.Health(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, MaxSchemaVersion, health.SchemaVersion)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("unsupported schema version", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockVersionRow := new(MockRow)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{requiredTables}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(nil)
+		setupMockSchemaVersion(mockDb, mockVersionRow, ctx, MaxSchemaVersion+1, nil)
+
+		health, err := manager.Health(ctx)
+		assertPolicyStoreError(t, err, store.NewSchemaMismatchError())
+		assert.Nil(t, health)
+	})
+
+	t.Run("missing tables", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{requiredTables}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*[]string)) = []string{"group_tombstones"}
+		}).Return(nil)
+
+		health, err := manager.Health(ctx)
+		assertPolicyStoreError(t, err, store.NewSchemaMismatchError())
+		assert.Nil(t, health)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("connection refused"))
+
+		health, err := manager.Health(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, health)
+	})
+}
+
+func TestListPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, "SELECT id, name, version, risk, description, labels FROM permissions ORDER BY name", []any(nil)).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "permission1"
+			*(args[0].([]any)[2].(*int)) = 1
+			*(args[0].([]any)[3].(*string)) = "high"
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		permissions, err := manager.ListPermissions(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []store.PermissionInfo[int]{{ID: 1, Name: "permission1", Version: 1, Risk: authz.RiskHigh}}, permissions)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		permissions, err := manager.ListPermissions(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, permissions)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("error scanning permissions", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Scan", mock.Anything).Return(errors.New("scan error"))
+		mockRows.On("Close").Return()
+
+		permissions, err := manager.ListPermissions(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, permissions)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+}
+
+func TestGetGroups(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, "SELECT id, name, version, description, labels FROM groups WHERE id = ANY($1) ORDER BY name", []any{[]int{1, 7}}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "group1"
+			*(args[0].([]any)[2].(*int)) = 2
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		groups, err := manager.GetGroups(ctx, []int{1, 7})
+		assert.NoError(t, err)
+		assert.Equal(t, []store.GroupInfo[int]{{ID: 1, Name: "group1", Version: 2}}, groups)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("no ids", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		groups, err := manager.GetGroups(ctx, []int{})
+		assert.NoError(t, err)
+		assert.Empty(t, groups)
+
+		mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		groups, err := manager.GetGroups(ctx, []int{1})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, groups)
+
+		mockDb.AssertExpectations(t)
+	})
+}
+
+func TestGetPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, "SELECT id, name, version, risk, description, labels FROM permissions WHERE id = ANY($1) ORDER BY name", []any{[]int{1}}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "permission1"
+			*(args[0].([]any)[2].(*int)) = 1
+			*(args[0].([]any)[3].(*string)) = "low"
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		permissions, err := manager.GetPermissions(ctx, []int{1})
+		assert.NoError(t, err)
+		assert.Equal(t, []store.PermissionInfo[int]{{ID: 1, Name: "permission1", Version: 1, Risk: authz.RiskLow}}, permissions)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("error scanning permissions", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Scan", mock.Anything).Return(errors.New("scan error"))
+		mockRows.On("Close").Return()
+
+		permissions, err := manager.GetPermissions(ctx, []int{1})
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, permissions)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+}
+
+func TestUpdateGroupMetadata(t *testing.T) {
+	ctx := context.Background()
+	description := "Recipe authors"
+	team := "kitchen"
+	patch := store.MetadataPatch{Description: &description, Labels: map[string]*string{"team": &team, "legacy": nil}}
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{1, &description, false, map[string]string{"team": "kitchen"}, []string{"legacy"}}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "cooks"
+			*(args[0].([]any)[2].(*int)) = 3
+			*(args[0].([]any)[3].(*string)) = description
+			*(args[0].([]any)[4].(*map[string]string)) = map[string]string{"team": "kitchen"}
+		}).Return(nil)
+
+		group, err := manager.UpdateGroupMetadata(ctx, 1, patch)
+		assert.NoError(t, err)
+		assert.Equal(t, &store.GroupInfo[int]{ID: 1, Name: "cooks", Version: 3,
+			Metadata: store.Metadata{Description: description, Labels: map[string]string{"team": "kitchen"}}}, group)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := manager.UpdateGroupMetadata(ctx, 1, patch)
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+	})
+
+	t.Run("empty label key", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		_, err := manager.UpdateGroupMetadata(ctx, 1, store.MetadataPatch{Labels: map[string]*string{" ": &team}})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "QueryRow", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUpdatePermissionMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("clear labels", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{2, (*string)(nil), true, map[string]string{}, []string{}}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 2
+			*(args[0].([]any)[1].(*string)) = "recipes.delete"
+			*(args[0].([]any)[2].(*int)) = 1
+			*(args[0].([]any)[3].(*string)) = "high"
+			*(args[0].([]any)[5].(*map[string]string)) = map[string]string{}
+		}).Return(nil)
+
+		permission, err := manager.UpdatePermissionMetadata(ctx, 2, store.MetadataPatch{ClearLabels: true})
+		assert.NoError(t, err)
+		assert.Equal(t, authz.RiskHigh, permission.Risk)
+		assert.Empty(t, permission.Labels)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("permission not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := manager.UpdatePermissionMetadata(ctx, 2, store.MetadataPatch{})
+		assertPolicyStoreError(t, err, store.NewPermissionNotFoundError())
+	})
+}
+
+// TestListGroupsPage reads a page of groups, fetching one group more than the limit to tell whether a next page exists.
+func TestListGroupsPage(t *testing.T) {
+	ctx := context.Background()
+	const query = "SELECT id, name, version, description, labels FROM groups WHERE id > $1 ORDER BY id LIMIT $2"
+
+	t.Run("next page", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+		limit := 2
+
+		mockDb.On("Query", ctx, query, []any{4, &limit}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Twice()
+		mockRows.On("Next").Return(false).Once()
+		id := 4
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			id++
+			*(args[0].([]any)[0].(*int)) = id
+			*(args[0].([]any)[1].(*string)) = "group" + strconv.Itoa(id)
+			*(args[0].([]any)[2].(*int)) = 1
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		groups, next, err := manager.ListGroupsPage(ctx, paging.Request{Limit: 1, After: paging.Key{4}})
+		assert.NoError(t, err)
+		assert.Equal(t, []store.GroupInfo[int]{{ID: 5, Name: "group5", Version: 1}}, groups)
+		assert.Equal(t, paging.Encode(paging.Key{5}), next)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("whole list", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, query, []any{0, (*int)(nil)}).Return(mockRows, nil)
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		groups, next, err := manager.ListGroupsPage(ctx, paging.Request{})
+		assert.NoError(t, err)
+		assert.Empty(t, groups)
+		assert.Empty(t, next)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		_, _, err := manager.ListGroupsPage(ctx, paging.Request{Limit: 1, After: paging.Key{"admins"}})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		groups, _, err := manager.ListGroupsPage(ctx, paging.Request{Limit: 1})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, groups)
+	})
+}
+
+func TestListPermissionsPage(t *testing.T) {
+	ctx := context.Background()
+	mockDb, _, _, manager := setupMockDbAndManager()
+	mockRows := new(MockRows)
+	limit := 11
+
+	mockDb.On("Query", ctx, "SELECT id, name, version, risk, description, labels FROM permissions WHERE id > $1 ORDER BY id LIMIT $2", []any{0, &limit}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = 1
+		*(args[0].([]any)[1].(*string)) = "recipes.read"
+		*(args[0].([]any)[2].(*int)) = 1
+		*(args[0].([]any)[3].(*string)) = "low"
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	permissions, next, err := manager.ListPermissionsPage(ctx, paging.Request{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []store.PermissionInfo[int]{{ID: 1, Name: "recipes.read", Version: 1, Risk: authz.RiskLow}}, permissions)
+	assert.Empty(t, next)
+
+	mockDb.AssertExpectations(t)
+}
+
+func TestGetGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{3}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 3
+			*(args[0].([]any)[1].(*string)) = "cooks"
+			*(args[0].([]any)[2].(*int)) = 2
+			*(args[0].([]any)[5].(*[]string)) = []string{"alice", "bob"}
+		}).Return(nil)
+		mockDb.On("Query", ctx, "SELECT id, name, version, risk, description, labels FROM permissions WHERE id IN (SELECT permission_id FROM group_permissions WHERE group_id = $1) ORDER BY name", []any{3}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 1
+			*(args[0].([]any)[1].(*string)) = "recipes.read"
+			*(args[0].([]any)[2].(*int)) = 1
+			*(args[0].([]any)[3].(*string)) = "low"
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		group, err := manager.GetGroup(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, &store.GroupDetails[int, int, string]{
+			GroupInfo:   store.GroupInfo[int]{ID: 3, Name: "cooks", Version: 2},
+			Users:       []string{"alice", "bob"},
+			Permissions: []store.PermissionInfo[int]{{ID: 1, Name: "recipes.read", Version: 1, Risk: authz.RiskLow}},
+		}, group)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("group not found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		group, err := manager.GetGroup(ctx, 3)
+		assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+		assert.Nil(t, group)
+
+		mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		_, err := manager.GetGroup(ctx, 3)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+}
+
+func TestGetUserGroups(t *testing.T) {
+	ctx := context.Background()
+	mockDb, _, _, manager := setupMockDbAndManager()
+	mockRows := new(MockRows)
+
+	mockDb.On("Query", ctx, "SELECT id, name, version, description, labels FROM groups WHERE id IN (SELECT group_id FROM subjects WHERE id = $1) ORDER BY name", []any{"alice"}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = 3
+		*(args[0].([]any)[1].(*string)) = "cooks"
+		*(args[0].([]any)[2].(*int)) = 2
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	groups, err := manager.GetUserGroups(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupInfo[int]{{ID: 3, Name: "cooks", Version: 2}}, groups)
+
+	mockDb.AssertExpectations(t)
+}
+
+// TestReadPolicyPage reads a page of groups by name and then the permissions granted to them.
+func TestReadPolicyPage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("next page", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		groupRows := new(MockRows)
+		permissionRows := new(MockRows)
+		limit := 2
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"admins", "c", "alice", &limit}).Return(groupRows, nil)
+		groupRows.On("Next").Return(true).Twice()
+		groupRows.On("Next").Return(false).Once()
+		groupRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 3
+			*(args[0].([]any)[1].(*string)) = "chefs"
+			*(args[0].([]any)[2].(*[]string)) = []string{"alice", "bob"}
+		}).Return(nil).Once()
+		groupRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 5
+			*(args[0].([]any)[1].(*string)) = "cooks"
+			*(args[0].([]any)[2].(*[]string)) = []string{"alice"}
+		}).Return(nil).Once()
+		groupRows.On("Err").Return(nil)
+		groupRows.On("Close").Return()
+
+		mockDb.On("Query", ctx, mock.Anything, []any{[]int{3}}).Return(permissionRows, nil)
+		permissionRows.On("Next").Return(true).Once()
+		permissionRows.On("Next").Return(false).Once()
+		permissionRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*string)) = "recipes.write"
+			*(args[0].([]any)[1].(*string)) = "high"
+			*(args[0].([]any)[3].(*[]string)) = []string{"recipes.read"}
+			*(args[0].([]any)[4].(*[]string)) = []string{"chefs"}
+		}).Return(nil)
+		permissionRows.On("Err").Return(nil)
+		permissionRows.On("Close").Return()
+
+		policy, next, err := manager.ReadPolicyPage(ctx, paging.Request{Limit: 1, After: paging.Key{"admins"}},
+			store.PolicyFilter{GroupPrefix: "c", User: "alice"})
+		assert.NoError(t, err)
+		assert.Equal(t, authz.NewPolicy(
+			[]authz.Permission{{Name: "recipes.write", Groups: []string{"chefs"}, Risk: authz.RiskHigh, Implies: []string{"recipes.read"}}},
+			[]authz.Group{{Name: "chefs", Users: []string{"alice", "bob"}}},
+		), policy)
+		assert.Equal(t, paging.Encode(paging.Key{"chefs"}), next)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("no groups", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"", "", "", (*int)(nil)}).Return(mockRows, nil).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		policy, next, err := manager.ReadPolicyPage(ctx, paging.Request{}, store.PolicyFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, authz.NewPolicy([]authz.Permission{}, []authz.Group{}), policy)
+		assert.Empty(t, next)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		_, _, err := manager.ReadPolicyPage(ctx, paging.Request{Limit: 1, After: paging.Key{4}}, store.PolicyFilter{})
+		assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+
+		mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error on query", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		policy, _, err := manager.ReadPolicyPage(ctx, paging.Request{Limit: 1}, store.PolicyFilter{})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, policy)
+	})
+}
+
+// Integration test for PostgresPolicyManager
+// This test suite requires a running docker environment and should be run with the `-run Integration` flag.
+// It runs against the PostgreSQL versions selected by $AUTHZ_TEST_POSTGRES_VERSIONS, such as "all" before
+// supporting a new version; note the MERGE statements need PostgreSQL 15. With $AUTHZ_TEST_POSTGRES_REUSE=true
+// the containers are kept running between runs.
+
+type PostgresPolicyManagerIntegrationTestSuite struct {
+	suite.Suite
+	version     string
+	pgContainer *PostgresContainer
+	manager     *PostgresPolicyManager
+	db          *pgxpool.Pool
+	ctx         context.Context
+}
+
+func TestPostgresPolicyManagerIntegrationTestSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// the suite runs against every selected version of PostgreSQL, in parallel as each has its own container
+	versions, err := PostgresVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		t.Run("postgres "+version, func(t *testing.T) {
+			if len(versions) > 1 {
+				t.Parallel()
+			}
+			suite.Run(t, &PostgresPolicyManagerIntegrationTestSuite{version: version})
+		})
+	}
+}
+
+func (suite *PostgresPolicyManagerIntegrationTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+	var err error
+	suite.pgContainer, err = CreatePostgresContainerVersion(suite.ctx, suite.version, "authz", path.Join("..", "..", "..", "..", "sql", "authz_postgres.sql"))
+	if err != nil {
+		suite.T().Fatalf("Failed to run Postgres container: %v", err)
+	}
+
+	suite.db, err = pgxpool.New(suite.ctx, suite.pgContainer.ConnectionString)
+	if err != nil {
+		suite.T().Fatalf("Failed to connect to Postgres: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil)) //slog.New(slog.NewTextHandler(io.Discard, nil))
+	suite.manager = NewPostgresPolicyManager(suite.db, logger)
+}
+
+func (suite *PostgresPolicyManagerIntegrationTestSuite) TearDownSuite() {
+	suite.db.Close()
+	if err := suite.pgContainer.Release(suite.ctx); err != nil {
+		suite.T().Fatalf("Failed to terminate Postgres container: %v", err)
+	}
+}
+
+func (suite *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupPermissions_Integration() {
+	t := suite.T()
+	db := suite.db
+	manager := suite.manager
+
+	// Setup test data
+	groupId, _ := addTestGroup(t, suite.ctx, db)
+	permissionId, _ := addTestPermission(t, suite.ctx, db)
+	permissions := []int{permissionId}
+
+	// Run the function
+	err := manager.UpdateGroupPermissions(suite.ctx, groupId, permissions)
+	assert.NoError(t, err)
+
+	// Verify the results
+	var affected int
+	db.QueryRow(suite.ctx,
+		"SELECT COUNT(*) FROM group_permissions WHERE group_id = $1 AND permission_id = $2",
+		groupId,
+		permissionId).Scan(&affected)
+
+	assert.Equal(t, len(permissions), affected)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestCreateGroup_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupName := uuid.NewString()
+
+	// Run the function
+	id, err := manager.CreateGroup(suit.ctx, groupName)
+	assert.NoError(t, err)
+
+	// Verify the results
+	var version int
+	var name string
+	err = db.QueryRow(suit.ctx,
+		"SELECT name, version FROM groups WHERE id = $1",
+		id).Scan(&name, &version)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, groupName, name)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestCreatePermission_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	permissionName := uuid.NewString()
+
+	// Run the function
+	id, err := manager.CreatePermission(suit.ctx, permissionName)
+	assert.NoError(t, err)
+
+	// Verify results
+	var version int
+	var name string
+	err = db.QueryRow(suit.ctx,
+		"SELECT name, version FROM permissions WHERE id = $1",
+		id).Scan(&name, &version)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, permissionName, name)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestHealth_Integration() {
+	t := suit.T()
+
+	health, err := suit.manager.Health(suit.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxSchemaVersion, health.SchemaVersion)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupUsers_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+	user1 := uuid.NewString()
+	user2 := uuid.NewString()
+	users := []string{user1, user2}
+
+	// Run the function
+	err := manager.UpdateGroupUsers(suit.ctx, groupId, users)
+	assert.NoError(t, err)
+
+	// Verify the results
+	var user string
+	count := 0
+	rows, err := db.Query(suit.ctx, "SELECT id FROM subjects WHERE group_id = $1", groupId)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	for rows.Next() {
+		rows.Scan(&user)
+		assert.Contains(t, users, user)
+		count++
+	}
+
+	assert.Equal(t, len(users), count)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupUsers_SourcePrecedence_Integration() {
+	t := suit.T()
+	db := suit.db
+	precedence := store.SourcePrecedence{store.SourceLDAP, store.SourceManual}
+	manager := NewPostgresPolicyManager(db, slog.New(slog.NewTextHandler(io.Discard, nil)), WithSourcePrecedence(precedence))
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+	synced := uuid.NewString()
+	manual := uuid.NewString()
+
+	// the sync adds its member, then an administrator replaces the members
+	err := manager.UpdateGroupUsers(store.WithMembershipSource(suit.ctx, store.SourceLDAP), groupId, []string{synced})
+	assert.NoError(t, err)
+	err = manager.UpdateGroupUsers(suit.ctx, groupId, []string{manual})
+	assert.NoError(t, err)
+
+	// the synced membership is protected from the manual edit
+	sources := map[string]string{}
+	rows, err := db.Query(suit.ctx, "SELECT id, source FROM subjects WHERE group_id = $1", groupId)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	for rows.Next() {
+		var user, source string
+		assert.NoError(t, rows.Scan(&user, &source))
+		sources[user] = source
+	}
+
+	assert.Equal(t, map[string]string{synced: "ldap", manual: "manual"}, sources)
+
+	// the sync takes over the manual membership it asserts
+	err = manager.UpdateGroupUsers(store.WithMembershipSource(suit.ctx, store.SourceLDAP), groupId, []string{synced, manual})
+	assert.NoError(t, err)
+
+	var source string
+	err = db.QueryRow(suit.ctx, "SELECT source FROM subjects WHERE group_id = $1 AND id = $2", groupId, manual).Scan(&source)
+	assert.NoError(t, err)
+	assert.Equal(t, "ldap", source)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupUsers_Duplicates_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+	user := uuid.NewString()
+
+	// Run the function with the same user twice, once padded with whitespace
+	err := manager.UpdateGroupUsers(suit.ctx, groupId, []string{user, " " + user + " "})
+	assert.NoError(t, err)
+
+	// Verify the results
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE group_id = $1 AND id = $2", groupId, user).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestDeleteGroup_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+
+	permissionId, _ := addTestPermission(t, suit.ctx, db)
+	addTestUser(t, suit.ctx, db, uuid.NewString(), groupId)
+	addTestUser(t, suit.ctx, db, uuid.NewString(), groupId)
+	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId)
+
+	// Run the function
+	deletion, err := manager.DeleteGroup(suit.ctx, groupId)
+	assert.NoError(t, err)
+	assert.Equal(t, &store.GroupDeletion{Members: 2, Grants: 1}, deletion)
+
+	// Verify the results
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM groups WHERE id = $1", groupId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE group_id = $1", groupId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	var members, grants int
+	err = db.QueryRow(suit.ctx, "SELECT members, grants FROM group_tombstones WHERE group_id = $1", groupId).Scan(&members, &grants)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, members)
+	assert.Equal(t, 1, grants)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestChangeGroupName_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	newGroupName := uuid.NewString()
+	assert.NotEqual(t, groupName, newGroupName)
+
+	// Run the function
+	err := manager.ChangeGroupName(suit.ctx, groupId, newGroupName)
+	assert.NoError(t, err)
+
+	// Verify the results
+	var name string
+	err = db.QueryRow(suit.ctx, "SELECT name FROM groups WHERE id = $1", groupId).Scan(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, newGroupName, name)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestDeleteUser_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId1, _ := addTestGroup(t, suit.ctx, db)
+	groupId2, _ := addTestGroup(t, suit.ctx, db)
+	userId := uuid.NewString()
+
+	addTestUser(t, suit.ctx, db, userId, groupId1)
+	addTestUser(t, suit.ctx, db, userId, groupId2)
+
+	// Run the function
+	err := manager.DeleteUser(suit.ctx, userId)
+	assert.NoError(t, err)
+
+	// Verify the results
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE id = $1", userId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestReadPolicy_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+
+	// Setup test data
+	userId := uuid.NewString()
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
+	addTestUser(t, suit.ctx, db, userId, groupId)
+	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId)
+
+	// Run the function
+	policy, err := manager.ReadPolicy(suit.ctx)
+	assert.NoError(t, err)
+
+	// Verify the results
+	assert.NotNil(t, policy)
+	assert.Condition(t, func() bool {
+		for _, group := range policy.Groups {
+			if group.Name == groupName {
+				if len(group.Users) == 1 && group.Users[0] == userId {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	assert.Condition(t, func() bool {
+		for _, permission := range policy.Permissions {
+			if permission.Name == permissionName {
+				if len(permission.Groups) == 1 && permission.Groups[0] == groupName {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestListGroups_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+
+	// Run the function
+	groups, err := manager.ListGroups(suit.ctx)
+	assert.NoError(t, err)
+
+	// Verify the results
+	assert.Contains(t, groups, store.GroupInfo[int]{ID: groupId, Name: groupName, Version: 1, Metadata: store.Metadata{Labels: map[string]string{}}})
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestListPermissions_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
+
+	// Run the function
+	permissions, err := manager.ListPermissions(suit.ctx)
+	assert.NoError(t, err)
+
+	// Verify the results
+	assert.Contains(t, permissions, store.PermissionInfo[int]{ID: permissionId, Name: permissionName, Version: 1, Risk: authz.RiskLow,
+		Metadata: store.Metadata{Labels: map[string]string{}}})
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestListGroupsPage_Integration() {
+	t := suit.T()
+	manager := suit.manager
+	first, _ := addTestGroup(t, suit.ctx, suit.db)
+	second, _ := addTestGroup(t, suit.ctx, suit.db)
+
+	groups, next, err := manager.ListGroupsPage(suit.ctx, paging.Request{Limit: 1, After: paging.Key{first - 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, first, groups[0].ID)
+	assert.Len(t, groups, 1)
+
+	after, err := paging.Decode(next)
+	assert.NoError(t, err)
+	groups, _, err = manager.ListGroupsPage(suit.ctx, paging.Request{Limit: 1, After: after})
+	assert.NoError(t, err)
+	assert.Equal(t, second, groups[0].ID)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestReadPolicyPage_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	otherId, _ := addTestGroup(t, suit.ctx, db)
+	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
+	user := "user-" + groupName
+	addTestUser(t, suit.ctx, db, user, groupId)
+	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId)
+	addTestGroupPermission(t, suit.ctx, db, otherId, permissionId)
+
+	policy, next, err := manager.ReadPolicyPage(suit.ctx, paging.Request{Limit: 10}, store.PolicyFilter{GroupPrefix: groupName, User: user})
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, []authz.Group{{Name: groupName, Users: []string{user}}}, policy.Groups)
+	assert.Equal(t, []authz.Permission{{Name: permissionName, Groups: []string{groupName}, Risk: authz.RiskLow}}, policy.Permissions)
+
+	policy, next, err = manager.ReadPolicyPage(suit.ctx, paging.Request{Limit: 1}, store.PolicyFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, policy.Groups, 1)
+	after, err := paging.Decode(next)
+	assert.NoError(t, err)
+	page, _, err := manager.ReadPolicyPage(suit.ctx, paging.Request{Limit: 1, After: after}, store.PolicyFilter{})
+	assert.NoError(t, err)
+	assert.Less(t, policy.Groups[0].Name, page.Groups[0].Name)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestGetGroup_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
+	addTestUser(t, suit.ctx, db, "user-"+groupName, groupId)
+	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId)
 
-		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
-		mockRowsPermissions.AssertExpectations(t)
-	})
+	group, err := manager.GetGroup(suit.ctx, groupId)
+	assert.NoError(t, err)
+	assert.Equal(t, groupName, group.Name)
+	assert.Equal(t, []string{"user-" + groupName}, group.Users)
+	assert.Equal(t, []store.PermissionInfo[int]{{ID: permissionId, Name: permissionName, Version: 1, Risk: authz.RiskLow,
+		Metadata: store.Metadata{Labels: map[string]string{}}}}, group.Permissions)
 
-	t.Run("error reading permissions", func(t *testing.T) {
-		mockDb, _, _, manager := setupMockDbAndManager()
-		mockBatchResults := new(MockBatchResults)
-		mockRowsGroups := new(MockRows)
-		mockRowsPermissions := new(MockRows)
+	groups, err := manager.GetUserGroups(suit.ctx, "user-"+groupName)
+	assert.NoError(t, err)
+	assert.Equal(t, []store.GroupInfo[int]{group.GroupInfo}, groups)
 
-		mockDb.On("SendBatch", ctx, mock.Anything).Return(mockBatchResults)
-		mockBatchResults.On("Query").Return(mockRowsGroups, nil).Once()
-		mockBatchResults.On("Query").Return(mockRowsPermissions, nil).Once()
-		mockBatchResults.On("Close").Return(nil)
+	_, err = manager.GetGroup(suit.ctx, -1)
+	assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+}
 
-		mockRowsGroups.On("Next").Return(false).Once()
-		mockRowsGroups.On("Err").Return(nil)
-		mockRowsPermissions.On("Next").Return(false).Once()
-		mockRowsPermissions.On("Err").Return(errors.New("read error"))
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestSetPermissionRisk_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	permissionId, _ := addTestPermission(t, suit.ctx, db)
 
-		policy, err := manager.ReadPolicy(ctx)
-		assertPolicyStoreError(t, err, store.NewDefaultError())
-		assert.Nil(t, policy)
+	// Run the function
+	err := manager.SetPermissionRisk(suit.ctx, permissionId, authz.RiskHigh)
+	assert.NoError(t, err)
 
-		mockDb.AssertExpectations(t)
-		mockBatchResults.AssertExpectations(t)
-		mockRowsGroups.AssertExpectations(t)
-		mockRowsPermissions.AssertExpectations(t)
-	})
+	// Verify the results
+	var risk string
+	err = db.QueryRow(suit.ctx, "SELECT risk FROM permissions WHERE id = $1", permissionId).Scan(&risk)
+	assert.NoError(t, err)
+	assert.Equal(t, "high", risk)
 }
 
-// Integration test for PostgresPolicyManager
-// This test suite requires a running docker environment and should be run with the `-run Integration` flag.
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestSetPermissionCondition_Integration() {
+	t := suit.T()
+	manager := suit.manager
+	groupId, _ := addTestGroup(t, suit.ctx, suit.db)
+	permissionId, permissionName := addTestPermission(t, suit.ctx, suit.db)
+	addTestGroupPermission(t, suit.ctx, suit.db, groupId, permissionId)
 
-type PostgresPolicyManagerIntegrationTestSuite struct {
-	suite.Suite
-	pgContainer *PostgresContainer
-	manager     *PostgresPolicyManager
-	db          *pgxpool.Pool
-	ctx         context.Context
-}
+	err := manager.SetPermissionCondition(suit.ctx, permissionId, "resource.owner == user")
+	assert.NoError(t, err)
 
-func TestPostgresPolicyManagerIntegrationTestSuite(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
+	// the condition is read back with the policy
+	policy, err := manager.ReadPolicy(suit.ctx)
+	assert.NoError(t, err)
+	index := slices.IndexFunc(policy.Permissions, func(permission authz.Permission) bool { return permission.Name == permissionName })
+	if assert.NotEqual(t, -1, index) {
+		assert.Equal(t, "resource.owner == user", policy.Permissions[index].Condition)
 	}
-
-	suite.Run(t, new(PostgresPolicyManagerIntegrationTestSuite))
 }
 
-func (suite *PostgresPolicyManagerIntegrationTestSuite) SetupSuite() {
-	suite.ctx = context.Background()
-	var err error
-	suite.pgContainer, err = CreatePostgresContainer(suite.ctx, "authz", path.Join("..", "..", "..", "..", "sql", "authz_postgres.sql"))
-	if err != nil {
-		suite.T().Fatalf("Failed to run Postgres container: %v", err)
-	}
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupMetadata_Integration() {
+	t := suit.T()
+	manager := suit.manager
+	groupId, _ := addTestGroup(t, suit.ctx, suit.db)
+	description := "Recipe authors"
+	kitchen, legacy := "kitchen", "yes"
 
-	suite.db, err = pgxpool.New(suite.ctx, suite.pgContainer.ConnectionString)
-	if err != nil {
-		suite.T().Fatalf("Failed to connect to Postgres: %v", err)
-	}
+	// Run the function
+	_, err := manager.UpdateGroupMetadata(suit.ctx, groupId, store.MetadataPatch{Labels: map[string]*string{"team": &kitchen, "legacy": &legacy}})
+	assert.NoError(t, err)
+	group, err := manager.UpdateGroupMetadata(suit.ctx, groupId, store.MetadataPatch{Description: &description, Labels: map[string]*string{"legacy": nil}})
+	assert.NoError(t, err)
 
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil)) //slog.New(slog.NewTextHandler(io.Discard, nil))
-	suite.manager = NewPostgresPolicyManager(suite.db, logger)
+	// Verify the results: the patches merged and the group version is unchanged
+	assert.Equal(t, description, group.Description)
+	assert.Equal(t, map[string]string{"team": "kitchen"}, group.Labels)
+	assert.Equal(t, 1, group.Version)
 }
 
-func (suite *PostgresPolicyManagerIntegrationTestSuite) TearDownSuite() {
-	suite.db.Close()
-	if err := suite.pgContainer.Terminate(suite.ctx); err != nil {
-		suite.T().Fatalf("Failed to terminate Postgres container: %v", err)
-	}
-}
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestSetPermissionImplications_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	adminId, _ := addTestPermission(t, suit.ctx, db)
+	writeId, _ := addTestPermission(t, suit.ctx, db)
+	readId, _ := addTestPermission(t, suit.ctx, db)
 
-func (suite *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupPermissions_Integration() {
-	t := suite.T()
-	db := suite.db
-	manager := suite.manager
+	// Run the function
+	assert.NoError(t, manager.SetPermissionImplications(suit.ctx, adminId, []int{writeId}))
+	assert.NoError(t, manager.SetPermissionImplications(suit.ctx, writeId, []int{readId}))
 
-	// Setup test data
-	groupId, _ := addTestGroup(t, suite.ctx, db)
-	permissionId, _ := addTestPermission(t, suite.ctx, db)
-	permissions := []int{permissionId}
+	// Verify the results
+	var count int
+	err := db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM permission_implications WHERE permission_id = $1", adminId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// a permission may not imply itself through other permissions
+	err = manager.SetPermissionImplications(suit.ctx, readId, []int{adminId})
+	assertPolicyStoreError(t, err, store.NewInvalidArgumentError())
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestSetPermissionDenials_Integration() {
+	t := suit.T()
+	db := suit.db
+	manager := suit.manager
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
+	user := uuid.NewString()
 
 	// Run the function
-	err := manager.UpdateGroupPermissions(suite.ctx, groupId, permissions)
-	assert.NoError(t, err)
+	assert.NoError(t, manager.SetPermissionDenials(suit.ctx, permissionId, []int{groupId}, []string{user}))
 
 	// Verify the results
-	var affected int
-	db.QueryRow(suite.ctx,
-		"SELECT COUNT(*) FROM group_permissions WHERE group_id = $1 AND permission_id = $2",
-		groupId,
-		permissionId).Scan(&affected)
+	policy, err := manager.ReadPolicy(suit.ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, policy.Denials, authz.Denial{Permission: permissionName, Groups: []string{groupName}, Users: []string{user}})
 
-	assert.Equal(t, len(permissions), affected)
+	// the denials are replaced
+	assert.NoError(t, manager.SetPermissionDenials(suit.ctx, permissionId, nil, []string{user}))
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM permission_denials WHERE permission_id = $1", permissionId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = manager.SetPermissionDenials(suit.ctx, permissionId, []int{-1}, nil)
+	assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestCreateGroup_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestFolders_Integration() {
+	storetest.TestFolders(suit.T(), suit.manager, folder.NewPostgresStore(suit.db))
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestValidateGroupVersions_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
-	groupName := uuid.NewString()
+	groupId, groupName := addTestGroup(t, suit.ctx, db)
+	since := time.Now().Add(-time.Minute)
+
+	// a member added through manual SQL, leaving the version unchanged
+	_, err := db.Exec(suit.ctx, "INSERT INTO subjects (id, group_id) VALUES ($1, $2)", uuid.NewString(), groupId)
+	assert.NoError(t, err)
 
 	// Run the function
-	id, err := manager.CreateGroup(suit.ctx, groupName)
+	report, err := manager.ValidateGroupVersions(suit.ctx, since, time.Minute)
 	assert.NoError(t, err)
+	assert.Contains(t, report.Drifts, store.VersionDrift{GroupID: groupId, Group: groupName, Version: 1, Reason: store.VersionUnaudited, Changes: 1})
 
 	// Verify the results
+	assert.NoError(t, manager.RepairGroupVersions(suit.ctx, report))
 	var version int
-	var name string
-	err = db.QueryRow(suit.ctx,
-		"SELECT name, version FROM groups WHERE id = $1",
-		id).Scan(&name, &version)
+	err = db.QueryRow(suit.ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, version)
-	assert.Equal(t, groupName, name)
+	assert.Equal(t, 2, version)
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestCreatePermission_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestGrantPermission_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
-	permissionName := uuid.NewString()
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+	permissionId1, _ := addTestPermission(t, suit.ctx, db)
+	permissionId2, _ := addTestPermission(t, suit.ctx, db)
+	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId1)
 
 	// Run the function
-	id, err := manager.CreatePermission(suit.ctx, permissionName)
+	err := manager.GrantPermission(suit.ctx, groupId, permissionId2)
 	assert.NoError(t, err)
 
-	// Verify results
-	var version int
-	var name string
-	err = db.QueryRow(suit.ctx,
-		"SELECT name, version FROM permissions WHERE id = $1",
-		id).Scan(&name, &version)
+	// Verify the results
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM group_permissions WHERE group_id = $1", groupId).Scan(&count)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, version)
-	assert.Equal(t, permissionName, name)
+	assert.Equal(t, 2, count)
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestUpdateGroupUsers_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestAddGroupUser_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
 	groupId, _ := addTestGroup(t, suit.ctx, db)
 	user1 := uuid.NewString()
 	user2 := uuid.NewString()
-	users := []string{user1, user2}
+	assert.NoError(t, manager.UpdateGroupUsers(suit.ctx, groupId, []string{user1}))
 
 	// Run the function
-	err := manager.UpdateGroupUsers(suit.ctx, groupId, users)
+	err := manager.AddGroupUser(suit.ctx, groupId, user2)
 	assert.NoError(t, err)
 
 	// Verify the results
-	var user string
-	count := 0
-	rows, err := db.Query(suit.ctx, "SELECT id FROM subjects WHERE group_id = $1", groupId)
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE group_id = $1", groupId).Scan(&count)
 	assert.NoError(t, err)
-	defer rows.Close()
-
-	for rows.Next() {
-		rows.Scan(&user)
-		assert.Contains(t, users, user)
-		count++
-	}
-
-	assert.Equal(t, len(users), count)
+	assert.Equal(t, 2, count)
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestDeleteGroup_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestPolicyChanges_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
 	groupId, _ := addTestGroup(t, suit.ctx, db)
+	user := uuid.NewString()
 
 	// Run the function
-	err := manager.DeleteGroup(suit.ctx, groupId)
+	assert.NoError(t, manager.AddGroupUser(suit.ctx, groupId, user))
+	revision, err := manager.PolicyRevision(suit.ctx)
 	assert.NoError(t, err)
+	assert.NoError(t, manager.DeleteUser(suit.ctx, user))
 
 	// Verify the results
-	var count int
-	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM groups WHERE id = $1", groupId).Scan(&count)
+	rows, err := db.Query(suit.ctx, "SELECT kind, revision FROM policy_changes WHERE group_id = $1 AND user_id = $2 ORDER BY id", groupId, user)
 	assert.NoError(t, err)
-	assert.Equal(t, 0, count)
+	defer rows.Close()
+	var kinds []string
+	var revisions []int64
+	for rows.Next() {
+		var kind string
+		var changeRevision int64
+		assert.NoError(t, rows.Scan(&kind, &changeRevision))
+		kinds = append(kinds, kind)
+		revisions = append(revisions, changeRevision)
+	}
+	assert.Equal(t, []string{"member_added", "member_removed"}, kinds)
+	assert.Equal(t, []int64{revision, revision + 1}, revisions)
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestChangeGroupName_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestPolicyChanges_Rename_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
 	groupId, groupName := addTestGroup(t, suit.ctx, db)
-	newGroupName := uuid.NewString()
-	assert.NotEqual(t, groupName, newGroupName)
+	since := time.Now().Add(-time.Minute)
 
-	// Run the function
-	err := manager.ChangeGroupName(suit.ctx, groupId, newGroupName)
+	// Run the function, renaming through manual SQL
+	renamed := groupName + "-renamed"
+	_, err := db.Exec(suit.ctx, "UPDATE groups SET name = $2 WHERE id = $1", groupId, renamed)
 	assert.NoError(t, err)
 
 	// Verify the results
-	var name string
-	err = db.QueryRow(suit.ctx, "SELECT name FROM groups WHERE id = $1", groupId).Scan(&name)
+	var kind, name, previousName string
+	err = db.QueryRow(suit.ctx, "SELECT kind, group_name, previous_name FROM policy_changes WHERE group_id = $1 ORDER BY id DESC LIMIT 1", groupId).Scan(&kind, &name, &previousName)
 	assert.NoError(t, err)
-	assert.Equal(t, newGroupName, name)
+	assert.Equal(t, "group_renamed", kind)
+	assert.Equal(t, renamed, name)
+	assert.Equal(t, groupName, previousName)
+
+	report, err := manager.ValidateGroupVersions(suit.ctx, since, time.Minute)
+	assert.NoError(t, err)
+	assert.Contains(t, report.Drifts, store.VersionDrift{GroupID: groupId, Group: renamed, Version: 1, Reason: store.VersionUnaudited, Changes: 1})
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestDeleteUser_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestFaultInjector_DroppedCommits_Integration() {
 	t := suit.T()
 	db := suit.db
-	manager := suit.manager
-	groupId1, _ := addTestGroup(t, suit.ctx, db)
-	groupId2, _ := addTestGroup(t, suit.ctx, db)
-	userId := uuid.NewString()
-
-	addTestUser(t, suit.ctx, db, userId, groupId1)
-	addTestUser(t, suit.ctx, db, userId, groupId2)
+	injector, err := NewFaultInjector(db, WithDroppedCommits(1))
+	assert.NoError(t, err)
+	manager := NewPostgresPolicyManager(injector, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	groupId, _ := addTestGroup(t, suit.ctx, db)
 
 	// Run the function
-	err := manager.DeleteUser(suit.ctx, userId)
+	err = manager.UpdateGroupUsers(suit.ctx, groupId, []string{uuid.NewString()})
 	assert.NoError(t, err)
 
-	// Verify the results
+	// Verify the change was lost
 	var count int
-	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE id = $1", userId).Scan(&count)
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE group_id = $1", groupId).Scan(&count)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
+	assert.Equal(t, 1, injector.Faults().DroppedCommits)
 }
 
-func (suit *PostgresPolicyManagerIntegrationTestSuite) TestReadPolicy_Integration() {
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestFaultInjector_Retry_Integration() {
+	t := suit.T()
+	db := suit.db
+	injector, err := NewFaultInjector(db, WithErrorRate(0.3), WithLatency(5*time.Millisecond), WithFaultSeed(7))
+	assert.NoError(t, err)
+	manager := NewPostgresPolicyManager(injector, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	groupId, _ := addTestGroup(t, suit.ctx, db)
+	users := []string{uuid.NewString(), uuid.NewString()}
+
+	// Retry the function until it succeeds
+	for attempt := 0; ; attempt++ {
+		err = manager.UpdateGroupUsers(suit.ctx, groupId, users)
+		if err == nil {
+			break
+		}
+		assert.Equal(t, store.NewDataBaseError(), err)
+		assert.Less(t, attempt, 50)
+	}
+
+	// Verify a failed attempt left nothing behind
+	var count int
+	err = db.QueryRow(suit.ctx, "SELECT COUNT(*) FROM subjects WHERE group_id = $1", groupId).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	var version int
+	err = db.QueryRow(suit.ctx, "SELECT version FROM groups WHERE id = $1", groupId).Scan(&version)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestImportExport_Integration() {
 	t := suit.T()
 	db := suit.db
 	manager := suit.manager
 
-	// Setup test data
-	userId := uuid.NewString()
-	groupId, groupName := addTestGroup(t, suit.ctx, db)
-	permissionId, permissionName := addTestPermission(t, suit.ctx, db)
-	addTestUser(t, suit.ctx, db, userId, groupId)
-	addTestGroupPermission(t, suit.ctx, db, groupId, permissionId)
+	// an existing group kept by the import and one missing from the document
+	keptId, kept := addTestGroup(t, suit.ctx, db)
+	removedId, _ := addTestGroup(t, suit.ctx, db)
+	addTestUser(t, suit.ctx, db, "dave", removedId)
+
+	document := &store.PolicyDocument{
+		Groups: []store.GroupDocument{
+			{Name: kept, Description: "Kitchen staff", Labels: map[string]string{"team": "kitchen"},
+				Members: []store.MemberDocument{{User: "alice", Source: store.SourceManual}, {User: "bob", Source: store.SourceLDAP}}},
+			{Name: "editors", Members: []store.MemberDocument{}},
+		},
+		Permissions: []store.PermissionDocument{
+			{Name: "recipes.read", Groups: []string{kept, "editors"}, Risk: authz.RiskLow},
+			{Name: "recipes.write", Description: "Edit recipes", Risk: authz.RiskHigh, Groups: []string{"editors"}, Implies: []string{"recipes.read"},
+				Condition: "resource.owner == user"},
+		},
+		Denials: []store.DenialDocument{
+			{Permission: "recipes.write", Groups: []string{kept}, Users: []string{"mallory"}},
+		},
+	}
+	err := manager.Import(suit.ctx, document)
+	assert.NoError(t, err)
 
-	// Run the function
-	policy, err := manager.ReadPolicy(suit.ctx)
+	exported, err := manager.Export(suit.ctx)
 	assert.NoError(t, err)
+	assert.Equal(t, document.Permissions, exported.Permissions)
+	assert.Equal(t, document.Denials, exported.Denials)
+	assert.ElementsMatch(t, document.Groups, exported.Groups)
 
-	// Verify the results
-	assert.NotNil(t, policy)
-	assert.Condition(t, func() bool {
-		for _, group := range policy.Groups {
-			if group.Name == groupName {
-				if len(group.Users) == 1 && group.Users[0] == userId {
-					return true
-				}
-			}
-		}
-		return false
-	})
-	assert.Condition(t, func() bool {
-		for _, permission := range policy.Permissions {
-			if permission.Name == permissionName {
-				if len(permission.Groups) == 1 && permission.Groups[0] == groupName {
-					return true
-				}
-			}
-		}
-		return false
-	})
+	// the kept group keeps its id and the removed one leaves a tombstone
+	var id, version int
+	err = db.QueryRow(suit.ctx, "SELECT id, version FROM groups WHERE name = $1", kept).Scan(&id, &version)
+	assert.NoError(t, err)
+	assert.Equal(t, keptId, id)
+	assert.Equal(t, 2, version)
+	var members int
+	err = db.QueryRow(suit.ctx, "SELECT members FROM group_tombstones WHERE group_id = $1", removedId).Scan(&members)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, members)
 }
 
 // Helper functions for test setup and data generation
@@ -1035,3 +3378,48 @@ func addTestGroupPermission(t *testing.T, ctx context.Context, db *pgxpool.Pool,
 		t.Fatalf("Failed to add test group permission: %v", err)
 	}
 }
+
+func (suit *PostgresPolicyManagerIntegrationTestSuite) TestTenants_Integration() {
+	t := suit.T()
+	script, err := os.ReadFile(path.Join("..", "..", "..", "..", "sql", "authz_postgres.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenants := NewTenants(suit.db.Config(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer tenants.Close()
+
+	_, err = tenants.Manager(suit.ctx, "unknown")
+	assert.ErrorIs(t, err, tenant.ErrNotProvisioned)
+	assert.NotContains(t, tenants.pools, tenant.ID("unknown"), "the pool of an unprovisioned tenant is closed")
+
+	for _, id := range []tenant.ID{"shop", "blog", "shop"} {
+		assert.NoError(t, tenants.Provision(suit.ctx, id, string(script)), "provisioning is idempotent")
+	}
+	ids, err := tenants.List(suit.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []tenant.ID{"blog", "shop"}, ids)
+
+	shop, err := tenants.Manager(suit.ctx, "shop")
+	assert.NoError(t, err)
+	blog, err := tenants.Manager(suit.ctx, "blog")
+	assert.NoError(t, err)
+	groupName := uuid.NewString()
+	_, err = shop.CreateGroup(suit.ctx, groupName)
+	assert.NoError(t, err)
+	_, err = blog.CreateGroup(suit.ctx, groupName)
+	assert.NoError(t, err, "group names are unique per tenant")
+
+	// Verify the results
+	for _, manager := range []*PostgresPolicyManager{shop, blog} {
+		policy, err := manager.ReadPolicy(suit.ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, policy.Groups, 1) {
+			assert.Equal(t, groupName, policy.Groups[0].Name)
+		}
+	}
+	policy, err := suit.manager.ReadPolicy(suit.ctx)
+	assert.NoError(t, err)
+	for _, group := range policy.Groups {
+		assert.NotEqual(t, groupName, group.Name, "the default tenant is isolated")
+	}
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+)
+
+// TenantPoolConfig returns a copy of the pool configuration whose connections set their search path
+// to the schema of the tenant, so the unqualified tables of every statement are the ones of the tenant.
+func TenantPoolConfig(config *pgxpool.Config, id tenant.ID) *pgxpool.Config {
+	config = config.Copy()
+	if config.ConnConfig.RuntimeParams == nil {
+		config.ConnConfig.RuntimeParams = map[string]string{}
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = id.Schema()
+	return config
+}
+
+// maxTenantConns caps the connections of the pool of every tenant opened by Tenants.
+const maxTenantConns = 4
+
+// tenantConnIdleTime is how long the connections of a tenant pool are kept unused before being closed.
+const tenantConnIdleTime = time.Minute
+
+// Tenants opens the PostgresPolicyManagers of the tenants sharing a database, each isolated in the
+// schema of its tenant, see TenantPoolConfig. The connection pool of a tenant is opened the first
+// time it is used and kept until Close. Each pool holds at most maxTenantConns connections, or the
+// MaxConns of the configuration when lower, and closes them once idle for a minute, so the tenants
+// not in use hold no connection and those in use at once hold at most maxTenantConns each.
+type Tenants struct {
+	config  *pgxpool.Config
+	logger  *slog.Logger
+	options []Option
+
+	mu    sync.Mutex
+	pools map[tenant.ID]*pgxpool.Pool
+}
+
+// NewTenants creates a new Tenants connecting with the given pool configuration. The managers
+// are created with the given options and log with the tenant attribute.
+func NewTenants(config *pgxpool.Config, logger *slog.Logger, options ...Option) *Tenants {
+	return &Tenants{config: config, logger: logger, options: options, pools: map[tenant.ID]*pgxpool.Pool{}}
+}
+
+// Pool returns the connection pool of the tenant, opening it when it is not open yet. The
+// other stores of the tenant, such as the approval requests and the audit events, use it.
+func (tenants *Tenants) Pool(ctx context.Context, id tenant.ID) (*pgxpool.Pool, error) {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	if pool, ok := tenants.pools[id]; ok {
+		return pool, nil
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, tenants.poolConfig(id))
+	if err != nil {
+		return nil, err
+	}
+	tenants.pools[id] = pool
+	return pool, nil
+}
+
+// poolConfig returns the configuration of the pool of the tenant, with its size capped.
+func (tenants *Tenants) poolConfig(id tenant.ID) *pgxpool.Config {
+	config := TenantPoolConfig(tenants.config, id)
+	config.MaxConns = min(config.MaxConns, maxTenantConns)
+	config.MinConns = 0
+	config.MaxConnIdleTime = min(config.MaxConnIdleTime, tenantConnIdleTime)
+	return config
+}
+
+// drop closes the pool of the tenant and forgets it, unless it was replaced meanwhile.
+func (tenants *Tenants) drop(id tenant.ID, pool *pgxpool.Pool) {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	if tenants.pools[id] == pool {
+		delete(tenants.pools, id)
+		pool.Close()
+	}
+}
+
+// Manager returns a PostgresPolicyManager reading and changing the policy of the tenant only.
+// It fails with tenant.ErrNotProvisioned when the schema of the tenant was not created, see Provision,
+// and then closes the pool of the tenant rather than keeping it for a tenant that may never exist.
+func (tenants *Tenants) Manager(ctx context.Context, id tenant.ID) (*PostgresPolicyManager, error) {
+	pool, err := tenants.Pool(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var provisioned bool
+	if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", id.Schema()+".schema_version").Scan(&provisioned); err != nil {
+		return nil, err
+	}
+	if !provisioned {
+		tenants.drop(id, pool)
+		return nil, fmt.Errorf("%w: %q", tenant.ErrNotProvisioned, id)
+	}
+	return NewPostgresPolicyManager(pool, tenants.logger.With("tenant", string(id)), tenants.options...), nil
+}
+
+// Provision creates the schema of the tenant and runs the schema script in it, usually the content of
+// sql/authz_postgres.sql, in a single transaction. The script is idempotent, so provisioning a tenant
+// again upgrades its schema.
+func (tenants *Tenants) Provision(ctx context.Context, id tenant.ID, script string) error {
+	pool, err := tenants.Pool(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the id is validated, so the schema name needs no quoting
+	if _, err := tx.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+id.Schema()); err != nil {
+		return fmt.Errorf("create the schema of tenant %q: %w", id, err)
+	}
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return fmt.Errorf("run the schema script for tenant %q: %w", id, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	tenants.logger.Info("provisioned tenant", "tenant", string(id), "schema", id.Schema())
+	return nil
+}
+
+// List returns the provisioned tenants, except the default one, ordered by id.
+func (tenants *Tenants) List(ctx context.Context) ([]tenant.ID, error) {
+	pool, err := tenants.Pool(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+	SELECT nspname FROM pg_namespace
+	WHERE starts_with(nspname, $1) AND to_regclass(quote_ident(nspname) || '.schema_version') IS NOT NULL
+	ORDER BY nspname
+	`, tenant.SchemaPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []tenant.ID{}
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		if id, ok := tenant.FromSchema(schema); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// Close closes the connection pools of every tenant.
+func (tenants *Tenants) Close() {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	for id, pool := range tenants.pools {
+		pool.Close()
+		delete(tenants.pools, id)
+	}
+}
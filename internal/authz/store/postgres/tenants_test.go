@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantPoolConfig(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://authz@localhost:5432/authz?application_name=authz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantConfig := TenantPoolConfig(config, "recipes")
+	assert.Equal(t, "tenant_recipes", tenantConfig.ConnConfig.RuntimeParams["search_path"])
+	assert.Equal(t, "authz", tenantConfig.ConnConfig.RuntimeParams["application_name"])
+	assert.NotContains(t, config.ConnConfig.RuntimeParams, "search_path", "the configuration is copied")
+
+	assert.Equal(t, "public", TenantPoolConfig(config, "").ConnConfig.RuntimeParams["search_path"])
+}
+
+func TestTenants_PoolConfig(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://authz@localhost:5432/authz?pool_max_conns=20&pool_min_conns=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenants := NewTenants(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tenantConfig := tenants.poolConfig("recipes")
+	assert.Equal(t, "tenant_recipes", tenantConfig.ConnConfig.RuntimeParams["search_path"])
+	assert.Equal(t, int32(maxTenantConns), tenantConfig.MaxConns)
+	assert.Equal(t, int32(0), tenantConfig.MinConns)
+	assert.Equal(t, tenantConnIdleTime, tenantConfig.MaxConnIdleTime)
+	assert.Equal(t, int32(20), config.MaxConns, "the configuration is copied")
+
+	config.MaxConns = 2
+	assert.Equal(t, int32(2), tenants.poolConfig("recipes").MaxConns, "smaller pools are kept")
+}
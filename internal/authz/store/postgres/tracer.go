@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// maxLoggedSQL is the length beyond which the logged statements are truncated.
+const maxLoggedSQL = 500
+
+// QueryStats summarizes the statements of a single SQL command, such as SELECT or MERGE.
+type QueryStats struct {
+	Queries int64 `json:"queries"`
+	Errors  int64 `json:"errors"`
+	// The statements taking at least the slow query threshold.
+	Slow int64 `json:"slow"`
+	// The rows returned or affected by the statements.
+	Rows int64 `json:"rows"`
+	// The mean and maximum time the statements took.
+	Mean string `json:"mean"`
+	Max  string `json:"max"`
+}
+
+type queryCounters struct {
+	queries int64
+	errors  int64
+	slow    int64
+	rows    int64
+	total   time.Duration
+	max     time.Duration
+}
+
+// QueryTracer is a pgx tracer logging and counting the SQL statements of a connection pool.
+// Statements taking at least the slow query threshold are logged as warnings, the others at
+// debug level, with their arguments redacted to their types so no secret or personal data
+// reaches the logs. Set it as the Tracer of the pgx connection configuration.
+type QueryTracer struct {
+	logger    *slog.Logger
+	threshold time.Duration
+	clock     clock.Clock
+	mutex     sync.Mutex
+	commands  map[string]*queryCounters
+}
+
+var (
+	_ pgx.QueryTracer = (*QueryTracer)(nil)
+	_ pgx.BatchTracer = (*QueryTracer)(nil)
+)
+
+// TracerOption configures optional QueryTracer settings.
+type TracerOption func(*QueryTracer)
+
+// WithTracerClock sets the clock timing the statements, the system clock by default.
+func WithTracerClock(clock clock.Clock) TracerOption {
+	return func(tracer *QueryTracer) {
+		tracer.clock = clock
+	}
+}
+
+// NewQueryTracer creates a new QueryTracer logging to logger the statements taking at least
+// threshold as slow queries. A threshold of 0 logs no statement as slow.
+func NewQueryTracer(logger *slog.Logger, threshold time.Duration, options ...TracerOption) *QueryTracer {
+	tracer := &QueryTracer{logger: logger, threshold: threshold, clock: clock.System(), commands: map[string]*queryCounters{}}
+	for _, option := range options {
+		option(tracer)
+	}
+	return tracer
+}
+
+type queryTraceKey struct{}
+
+// queryTrace is the statement being traced, or the last statement read of the batch being traced.
+type queryTrace struct {
+	sql     string
+	args    []any
+	started time.Time
+}
+
+// TraceQueryStart records the start of a Query, QueryRow or Exec call.
+func (tracer *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{sql: data.SQL, args: data.Args, started: tracer.clock.Now()})
+}
+
+// TraceQueryEnd logs and counts the statement once its rows are read.
+func (tracer *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	tracer.record(ctx, trace.sql, trace.args, data.CommandTag.RowsAffected(), tracer.clock.Now().Sub(trace.started), data.Err)
+}
+
+// TraceBatchStart records the start of a SendBatch call.
+func (tracer *QueryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{started: tracer.clock.Now()})
+}
+
+// TraceBatchQuery logs and counts a statement of the batch. The results of a batch are read in
+// order, so a statement is timed from the reading of the previous one.
+func (tracer *QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	now := tracer.clock.Now()
+	tracer.record(ctx, data.SQL, data.Args, data.CommandTag.RowsAffected(), now.Sub(trace.started), data.Err)
+	trace.started = now
+}
+
+// TraceBatchEnd does nothing, the statements of the batch are recorded as they are read.
+func (tracer *QueryTracer) TraceBatchEnd(context.Context, *pgx.Conn, pgx.TraceBatchEndData) {}
+
+func (tracer *QueryTracer) record(ctx context.Context, sql string, args []any, rows int64, elapsed time.Duration, err error) {
+	slow := tracer.threshold > 0 && elapsed >= tracer.threshold
+	command := sqlCommand(sql)
+
+	tracer.mutex.Lock()
+	stats, ok := tracer.commands[command]
+	if !ok {
+		stats = &queryCounters{}
+		tracer.commands[command] = stats
+	}
+	stats.queries++
+	if err != nil {
+		stats.errors++
+	}
+	if slow {
+		stats.slow++
+	}
+	stats.rows += rows
+	stats.total += elapsed
+	stats.max = max(stats.max, elapsed)
+	tracer.mutex.Unlock()
+
+	level, message := slog.LevelDebug, "sql query"
+	if slow {
+		level, message = slog.LevelWarn, "slow sql query"
+	}
+	if !tracer.logger.Enabled(ctx, level) {
+		return
+	}
+	attributes := []any{"sql", compactSQL(sql), "args", redactArgs(args), "rows", rows, "elapsed", elapsed}
+	if err != nil {
+		attributes = append(attributes, "error", err)
+	}
+	tracer.logger.Log(ctx, level, message, attributes...)
+}
+
+// Snapshot returns the statistics of every SQL command run so far, by command.
+func (tracer *QueryTracer) Snapshot() map[string]QueryStats {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	snapshot := make(map[string]QueryStats, len(tracer.commands))
+	for command, stats := range tracer.commands {
+		snapshot[command] = QueryStats{
+			Queries: stats.queries,
+			Errors:  stats.errors,
+			Slow:    stats.slow,
+			Rows:    stats.rows,
+			Mean:    (stats.total / time.Duration(stats.queries)).String(),
+			Max:     stats.max.String(),
+		}
+	}
+	return snapshot
+}
+
+// Collect returns the Snapshot. It is a diagnostics Collector.
+func (tracer *QueryTracer) Collect(ctx context.Context) (any, error) {
+	return tracer.Snapshot(), nil
+}
+
+// sqlCommand returns the command of a statement, its first keyword, so the statistics are kept per
+// command rather than per statement. Statements starting with a WITH clause are counted as WITH.
+func sqlCommand(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(strings.TrimSuffix(fields[0], ";"))
+}
+
+// compactSQL collapses the whitespace of a statement onto a single line and truncates it.
+func compactSQL(sql string) string {
+	compacted := strings.Join(strings.Fields(sql), " ")
+	if len(compacted) > maxLoggedSQL {
+		return compacted[:maxLoggedSQL] + "..."
+	}
+	return compacted
+}
+
+// redactArgs replaces the arguments of a statement with their types, and the length of slices.
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch value := reflect.ValueOf(arg); {
+		case arg == nil:
+			redacted[i] = "nil"
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8:
+			redacted[i] = fmt.Sprintf("%T(%d)", arg, value.Len())
+		default:
+			redacted[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupTracer(level slog.Level) (*QueryTracer, *FakeClock, *bytes.Buffer) {
+	output := &bytes.Buffer{}
+	fakeClock := NewFakeClock(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewJSONHandler(output, &slog.HandlerOptions{Level: level}))
+	return NewQueryTracer(logger, 100*time.Millisecond, WithTracerClock(fakeClock)), fakeClock, output
+}
+
+// traceQuery traces a statement taking elapsed to run.
+func traceQuery(tracer *QueryTracer, fakeClock *FakeClock, sql string, args []any, tag string, elapsed time.Duration, err error) {
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+	fakeClock.Advance(elapsed)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag(tag), Err: err})
+}
+
+func logRecords(t *testing.T, output *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestQueryTracer_SlowQuery(t *testing.T) {
+	tracer, fakeClock, output := setupTracer(slog.LevelInfo)
+
+	traceQuery(tracer, fakeClock, "SELECT id FROM groups WHERE name = $1", []any{"cooks"}, "SELECT 1", 10*time.Millisecond, nil)
+	traceQuery(tracer, fakeClock, "SELECT id\n\tFROM users\n\tWHERE id = ANY($1) AND secret = $2", []any{[]string{"alice", "bob"}, []byte("token")}, "SELECT 2", 250*time.Millisecond, nil)
+
+	records := logRecords(t, output)
+	if assert.Len(t, records, 1, "fast queries are logged at debug level") {
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, "slow sql query", records[0]["msg"])
+		assert.Equal(t, "SELECT id FROM users WHERE id = ANY($1) AND secret = $2", records[0]["sql"])
+		assert.Equal(t, []any{"[]string(2)", "[]uint8"}, records[0]["args"])
+		assert.EqualValues(t, 2, records[0]["rows"])
+		assert.NotContains(t, output.String(), "alice")
+		assert.NotContains(t, output.String(), "token")
+	}
+
+	assert.Equal(t, map[string]QueryStats{
+		"SELECT": {Queries: 2, Slow: 1, Rows: 3, Mean: "130ms", Max: "250ms"},
+	}, tracer.Snapshot())
+}
+
+func TestQueryTracer_Errors(t *testing.T) {
+	tracer, fakeClock, output := setupTracer(slog.LevelDebug)
+
+	traceQuery(tracer, fakeClock, "UPDATE groups SET version = version + 1 WHERE id = $1", []any{3}, "UPDATE 1", time.Millisecond, nil)
+	traceQuery(tracer, fakeClock, "update groups SET name = $1", []any{nil}, "", 2*time.Millisecond, errors.New("db error"))
+
+	records := logRecords(t, output)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "DEBUG", records[0]["level"])
+		assert.Equal(t, []any{"int"}, records[0]["args"])
+		assert.Equal(t, []any{"nil"}, records[1]["args"])
+		assert.Equal(t, "db error", records[1]["error"])
+	}
+
+	stats, err := tracer.Collect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]QueryStats{
+		"UPDATE": {Queries: 2, Errors: 1, Rows: 1, Mean: "1.5ms", Max: "2ms"},
+	}, stats)
+}
+
+func TestQueryTracer_Batch(t *testing.T) {
+	tracer, fakeClock, _ := setupTracer(slog.LevelInfo)
+
+	ctx := tracer.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{})
+	fakeClock.Advance(20 * time.Millisecond)
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "SELECT * FROM groups", CommandTag: pgconn.NewCommandTag("SELECT 4")})
+	fakeClock.Advance(200 * time.Millisecond)
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "WITH g AS (SELECT 1) SELECT * FROM g", CommandTag: pgconn.NewCommandTag("SELECT 1")})
+	tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+
+	assert.Equal(t, map[string]QueryStats{
+		"SELECT": {Queries: 1, Rows: 4, Mean: "20ms", Max: "20ms"},
+		"WITH":   {Queries: 1, Slow: 1, Rows: 1, Mean: "200ms", Max: "200ms"},
+	}, tracer.Snapshot())
+}
+
+func TestQueryTracer_NoThreshold(t *testing.T) {
+	output := &bytes.Buffer{}
+	tracer := NewQueryTracer(slog.New(slog.NewJSONHandler(output, nil)), 0)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	assert.Empty(t, output.String())
+	assert.Zero(t, tracer.Snapshot()["SELECT"].Slow)
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracerProvider sets the provider of the tracer recording a span for every operation of the
+// PostgresPolicyManager. By default the global provider is used, see otel.SetTracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(manager *PostgresPolicyManager) {
+		manager.tracer = tracing.Tracer(provider)
+	}
+}
+
+type operationKey struct{}
+
+// operation is the span of a PostgresPolicyManager operation, counting the rows its statements affect.
+type operation struct {
+	span trace.Span
+	rows atomic.Int64
+}
+
+// start starts the span of the named operation. The span must be ended with end. The context is
+// returned as is when the span is not recorded, as with the default no-op provider.
+func (manager *PostgresPolicyManager) start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, *operation) {
+	spanCtx, span := manager.tracer.Start(ctx, "PostgresPolicyManager."+name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attributes, attribute.String("db.system", "postgresql"))...))
+	op := &operation{span: span}
+	if !span.IsRecording() {
+		return ctx, op
+	}
+	return context.WithValue(spanCtx, operationKey{}, op), op
+}
+
+// end ends the span, recording the rows affected and the error returned by the operation, if any.
+func (op *operation) end(err error) {
+	if rows := op.rows.Load(); rows > 0 {
+		op.span.SetAttributes(tracing.RowsAffectedKey.Int64(rows))
+	}
+	tracing.End(op.span, err)
+}
+
+// countRows adds the rows affected by a statement to the operation of the context, if any.
+func countRows(ctx context.Context, tag pgconn.CommandTag) {
+	if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+		op.rows.Add(tag.RowsAffected())
+	}
+}
+
+// countingDb is a pgdb.DB counting the rows affected by the statements executed, in transactions too.
+type countingDb struct {
+	pgdb.DB
+}
+
+func (db countingDb) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tag, err := db.DB.Exec(ctx, sql, args...)
+	countRows(ctx, tag)
+	return tag, err
+}
+
+func (db countingDb) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := db.DB.Begin(ctx)
+	if err != nil {
+		return tx, err
+	}
+	return countingTx{Tx: tx}, nil
+}
+
+// countingTx is a pgx.Tx counting the rows affected by the statements executed.
+type countingTx struct {
+	pgx.Tx
+}
+
+func (tx countingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tag, err := tx.Tx.Exec(ctx, sql, args...)
+	countRows(ctx, tag)
+	return tag, err
+}
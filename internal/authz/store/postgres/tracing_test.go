@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/authz/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func setupTracedManager() (*MockPgDb, *SpanRecorder, *PostgresPolicyManager) {
+	mockDb := new(MockPgDb)
+	recorder := NewSpanRecorder()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return mockDb, recorder, NewPostgresPolicyManager(mockDb, logger, WithTracerProvider(recorder))
+}
+
+func TestTracing_RowsAffected(t *testing.T) {
+	mockDb, recorder, manager := setupTracedManager()
+	mockDb.On("Exec", mock.Anything, "DELETE FROM subjects WHERE id = $1", []any{"user1"}).Return(pgconn.NewCommandTag("DELETE 3"), nil)
+
+	err := manager.DeleteUser(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	span := recorder.Span("PostgresPolicyManager.DeleteUser")
+	if assert.NotNil(t, span) {
+		assert.True(t, span.Ended)
+		assert.Equal(t, trace.SpanKindClient, span.Kind)
+		assert.Equal(t, "postgresql", span.Attributes["db.system"].AsString())
+		assert.Equal(t, int64(3), span.Attributes[tracing.RowsAffectedKey].AsInt64())
+		assert.Equal(t, codes.Unset, span.Status)
+	}
+	// the statements run in the context of the span, so they are traced as its children
+	ctx := mockDb.Calls[0].Arguments.Get(0).(context.Context)
+	assert.Same(t, span, trace.SpanFromContext(ctx))
+}
+
+func TestTracing_Error(t *testing.T) {
+	mockDb, recorder, manager := setupTracedManager()
+	mockRow := new(MockRow)
+	mockDb.On("QueryRow", mock.Anything, "INSERT INTO groups (name, version) VALUES ($1, 1) RETURNING id", []any{"cooks"}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+
+	_, err := manager.CreateGroup(context.Background(), "cooks")
+	assertPolicyStoreError(t, err, store.NewNameExistsError())
+
+	span := recorder.Span("PostgresPolicyManager.CreateGroup")
+	if assert.NotNil(t, span) {
+		assert.True(t, span.Ended)
+		assert.Equal(t, codes.Error, span.Status)
+		assert.Equal(t, int64(store.NameAlreadyExist), span.Attributes[tracing.ErrorCodeKey].AsInt64())
+		assert.Len(t, span.Errors, 1)
+		assert.NotContains(t, span.Attributes, tracing.RowsAffectedKey)
+	}
+}
+
+func TestTracing_GroupID(t *testing.T) {
+	mockDb, recorder, manager := setupTracedManager()
+	mockRow := new(MockRow)
+	mockDb.On("QueryRow", mock.Anything, "SELECT version FROM groups WHERE id = $1", []any{7}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	_, err := manager.DeleteGroup(context.Background(), 7)
+	assertPolicyStoreError(t, err, store.NewGroupNotFoundError())
+
+	span := recorder.Span("PostgresPolicyManager.DeleteGroup")
+	if assert.NotNil(t, span) {
+		assert.Equal(t, int64(7), span.Attributes[tracing.GroupIDKey].AsInt64())
+		assert.Equal(t, int64(store.GroupNotFound), span.Attributes[tracing.ErrorCodeKey].AsInt64())
+	}
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// integrityChecks are the rules Verify checks. Every query returns the offending subject and a detail.
+// Referencing rows are checked even though foreign keys protect them, since restores and manual
+// repairs may load the data without the constraints.
+var integrityChecks = []struct {
+	name string
+	sql  string
+}{
+	{"dangling_group_permissions", `
+	SELECT format('grant of permission %s to group %s', gp.permission_id, gp.group_id),
+		CASE WHEN g.id IS NULL THEN 'group does not exist' ELSE 'permission does not exist' END
+	FROM group_permissions gp
+	LEFT JOIN groups g ON g.id = gp.group_id
+	LEFT JOIN permissions p ON p.id = gp.permission_id
+	WHERE g.id IS NULL OR p.id IS NULL
+	ORDER BY gp.group_id, gp.permission_id`},
+	{"dangling_subjects", `
+	SELECT format('membership of %L in group %s', s.id, s.group_id), 'group does not exist'
+	FROM subjects s LEFT JOIN groups g ON g.id = s.group_id
+	WHERE g.id IS NULL
+	ORDER BY s.group_id, s.id`},
+	{"dangling_implications", `
+	SELECT format('implication of permission %s by permission %s', pi.implied_id, pi.permission_id), 'permission does not exist'
+	FROM permission_implications pi
+	LEFT JOIN permissions p ON p.id = pi.permission_id
+	LEFT JOIN permissions i ON i.id = pi.implied_id
+	WHERE p.id IS NULL OR i.id IS NULL
+	ORDER BY pi.permission_id, pi.implied_id`},
+	{"invalid_versions", `
+	SELECT format('%s %s', kind, id), format('version is %s', coalesce(version::text, 'missing'))
+	FROM (
+		SELECT 'group' AS kind, id, version FROM groups
+		UNION ALL
+		SELECT 'permission', id, version FROM permissions
+	) entities
+	WHERE version IS NULL OR version < 1
+	ORDER BY kind, id`},
+	{"deleted_groups_present", `
+	SELECT format('group %s', g.id), format('deleted at %s, but still exists', t.deleted_at)
+	FROM group_tombstones t JOIN groups g ON g.id = t.group_id
+	ORDER BY g.id`},
+	{"duplicate_normalized_names", `
+	SELECT format('%ss %s', kind, string_agg(id::text, ', ' ORDER BY id)),
+		format('names %s only differ in case or surrounding whitespace', string_agg(quote_literal(name), ', ' ORDER BY id))
+	FROM (
+		SELECT 'group' AS kind, id, name FROM groups
+		UNION ALL
+		SELECT 'permission', id, name FROM permissions
+	) entities
+	GROUP BY kind, lower(btrim(name))
+	HAVING count(*) > 1
+	ORDER BY kind, min(id)`},
+	{"unnormalized_user_ids", `
+	SELECT format('membership of %L in group %s', id, group_id), 'user id is empty or has surrounding whitespace'
+	FROM subjects
+	WHERE id <> btrim(id) OR id = ''
+	ORDER BY group_id, id`},
+}
+
+// Verify checks the stored policy for the integrity problems the schema cannot prevent, such as
+// grants of deleted permissions, missing versions and names that only differ in case.
+func (manager *PostgresPolicyManager) Verify(ctx context.Context) (_ *store.IntegrityReport, err error) {
+	ctx, operation := manager.start(ctx, "Verify")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "Verify")
+
+	report := &store.IntegrityReport{Checks: []string{}, Problems: []store.IntegrityProblem{}}
+	for _, check := range integrityChecks {
+		report.Checks = append(report.Checks, check.name)
+
+		rows, err := manager.db.Query(ctx, check.sql)
+		if err != nil {
+			logger.Error("failed to run integrity check", "check", check.name, "error", err)
+			return nil, store.NewDataBaseError()
+		}
+
+		for rows.Next() {
+			problem := store.IntegrityProblem{Check: check.name}
+			if err := rows.Scan(&problem.Subject, &problem.Detail); err != nil {
+				rows.Close()
+				logger.Error("failed to scan integrity problem", "check", check.name, "error", err)
+				return nil, store.NewDefaultError()
+			}
+			report.Problems = append(report.Problems, problem)
+		}
+		rows.Close()
+
+		if rows.Err() != nil {
+			logger.Error("failed to read integrity problems", "check", check.name, "error", rows.Err())
+			return nil, store.NewDefaultError()
+		}
+	}
+
+	return report, nil
+}
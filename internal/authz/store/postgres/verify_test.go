@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("problems found", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		dangling := new(MockRows)
+		clean := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, "FROM group_permissions gp") }), []any(nil)).Return(dangling, nil)
+		mockDb.On("Query", ctx, mock.Anything, []any(nil)).Return(clean, nil)
+		dangling.On("Next").Return(true).Once()
+		dangling.On("Next").Return(false).Once()
+		dangling.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*string)) = "grant of permission 9 to group 4"
+			*(args[0].([]any)[1].(*string)) = "permission does not exist"
+		}).Return(nil)
+		dangling.On("Err").Return(nil)
+		dangling.On("Close").Return()
+		clean.On("Next").Return(false)
+		clean.On("Err").Return(nil)
+		clean.On("Close").Return()
+
+		report, err := manager.Verify(ctx)
+		assert.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Len(t, report.Checks, len(integrityChecks))
+		assert.Equal(t, []store.IntegrityProblem{{Check: "dangling_group_permissions",
+			Subject: "grant of permission 9 to group 4", Detail: "permission does not exist"}}, report.Problems)
+
+		mockDb.AssertNumberOfCalls(t, "Query", len(integrityChecks))
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(new(MockRows), errors.New("db error"))
+
+		report, err := manager.Verify(ctx)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, report)
+	})
+
+	t.Run("error scanning problems", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Scan", mock.Anything).Return(errors.New("scan error"))
+		mockRows.On("Close").Return()
+
+		report, err := manager.Verify(ctx)
+		assertPolicyStoreError(t, err, store.NewDefaultError())
+		assert.Nil(t, report)
+
+		mockRows.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// auditedChanges is the condition of the audit events recording a successful application change to
+// the policy, which the hooks.Audit hook records as "policy.<operation>" with the error in the details.
+const auditedChanges = "a.action LIKE 'policy.%' AND a.details->>'error' IS NULL"
+
+// ValidateGroupVersions finds the groups whose version cannot be trusted for optimistic concurrency:
+// the versions the application never writes, and the groups whose members, grants or name changed since
+// the given time without an audit event recording an application change to the group, to the user of the
+// change or to the whole policy within the window following it. Such changes were made outside the
+// application, such as through manual SQL, which may have left the version unchanged.
+//
+// The changes are read from the policy_changes log and the application changes from the audit_events
+// table, so the audit events must be recorded to Postgres, see serve -audit-log. The log identifies the
+// groups by id, so the changes of a group renamed since are validated too. Changes pruned from the log,
+// see serve -change-log-retention, are not.
+func (manager *PostgresPolicyManager) ValidateGroupVersions(ctx context.Context, since time.Time, window time.Duration) (_ *store.VersionReport, err error) {
+	ctx, operation := manager.start(ctx, "ValidateGroupVersions")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "ValidateGroupVersions")
+
+	report := &store.VersionReport{Since: since, Drifts: []store.VersionDrift{}}
+	err = manager.db.QueryRow(ctx, "SELECT count(*) FROM audit_events a WHERE a.recorded_at >= $1 AND "+auditedChanges, since).
+		Scan(&report.AuditEvents)
+	if err != nil {
+		logger.Error("failed to count audit events", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+
+	rows, err := manager.db.Query(ctx, `
+	WITH unaudited AS (
+		SELECT g.id, count(*) AS changes
+		FROM policy_changes c JOIN groups g ON g.id = c.group_id
+		WHERE c.changed_at >= $1 AND NOT EXISTS (
+			SELECT 1 FROM audit_events a
+			WHERE a.recorded_at BETWEEN c.changed_at AND c.changed_at + make_interval(secs => $2)
+			AND `+auditedChanges+`
+			AND (a.subject = 'group ' || g.id OR a.subject = 'user ' || c.user_id OR a.subject = '')
+		)
+		GROUP BY g.id
+	)
+	SELECT g.id, g.name, coalesce(g.version, 0), coalesce(u.changes, 0)
+	FROM groups g LEFT JOIN unaudited u ON u.id = g.id
+	WHERE g.version IS NULL OR g.version < 1 OR u.changes > 0
+	ORDER BY g.id
+	`, since, window.Seconds())
+	if err != nil {
+		logger.Error("failed to query group versions", "error", err)
+		return nil, store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var drift store.VersionDrift
+		if err := rows.Scan(&drift.GroupID, &drift.Group, &drift.Version, &drift.Changes); err != nil {
+			logger.Error("failed to scan group version", "error", err)
+			return nil, store.NewDefaultError()
+		}
+		drift.Reason = store.VersionUnaudited
+		if drift.Version < 1 {
+			drift.Reason = store.VersionInvalid
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to read group versions", "error", rows.Err())
+		return nil, store.NewDefaultError()
+	}
+
+	return report, nil
+}
+
+// RepairGroupVersions repairs the versions of the drifted groups of the report: invalid versions are
+// reset to 1 and the others are bumped, so clients holding a version read before the changes made outside
+// the application get a concurrency error rather than overwriting them. The report is updated with the
+// repaired versions; groups deleted since the validation are left out.
+func (manager *PostgresPolicyManager) RepairGroupVersions(ctx context.Context, report *store.VersionReport) (err error) {
+	ctx, operation := manager.start(ctx, "RepairGroupVersions")
+	defer func() { operation.end(err) }()
+	logger := manager.logger.With("operation", "RepairGroupVersions")
+	if report.OK() {
+		return nil
+	}
+
+	ids := make([]int, len(report.Drifts))
+	for i, drift := range report.Drifts {
+		ids[i] = drift.GroupID
+	}
+
+	rows, err := manager.db.Query(ctx, `
+	UPDATE groups SET version = CASE WHEN version IS NULL OR version < 1 THEN 1 ELSE version + 1 END
+	WHERE id = ANY($1)
+	RETURNING id, version
+	`, ids)
+	if err != nil {
+		logger.Error("failed to repair group versions", "error", err)
+		return store.NewDataBaseError()
+	}
+	defer rows.Close()
+
+	repaired := map[int]int{}
+	for rows.Next() {
+		var id, version int
+		if err := rows.Scan(&id, &version); err != nil {
+			logger.Error("failed to scan repaired group version", "error", err)
+			return store.NewDefaultError()
+		}
+		repaired[id] = version
+	}
+
+	if rows.Err() != nil {
+		logger.Error("failed to repair group versions", "error", rows.Err())
+		return store.NewDataBaseError()
+	}
+
+	for i, drift := range report.Drifts {
+		report.Drifts[i].RepairedTo = repaired[drift.GroupID]
+	}
+	logger.Info("repaired group versions", "groups", len(repaired))
+	return nil
+}
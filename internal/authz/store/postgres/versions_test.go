@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestValidateGroupVersions(t *testing.T) {
+	ctx := context.Background()
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	countQuery := mock.MatchedBy(func(sql string) bool { return strings.HasPrefix(sql, "SELECT count(*) FROM audit_events") })
+
+	t.Run("drifts found", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("QueryRow", ctx, countQuery, []any{since}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int)) = 12
+		}).Return(nil)
+		mockDb.On("Query", ctx, mock.Anything, []any{since, 60.0}).Return(mockRows, nil)
+		for _, row := range []store.VersionDrift{{GroupID: 3, Group: "cooks", Version: 0}, {GroupID: 4, Group: "bakers", Version: 2, Changes: 5}} {
+			mockRows.On("Next").Return(true).Once()
+			mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*int)) = row.GroupID
+				*(args[0].([]any)[1].(*string)) = row.Group
+				*(args[0].([]any)[2].(*int)) = row.Version
+				*(args[0].([]any)[3].(*int)) = row.Changes
+			}).Return(nil).Once()
+		}
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		report, err := manager.ValidateGroupVersions(ctx, since, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Equal(t, &store.VersionReport{Since: since, AuditEvents: 12, Drifts: []store.VersionDrift{
+			{GroupID: 3, Group: "cooks", Reason: store.VersionInvalid},
+			{GroupID: 4, Group: "bakers", Version: 2, Reason: store.VersionUnaudited, Changes: 5},
+		}}, report)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, mockRow, manager := setupMockDbAndManager()
+
+		mockDb.On("QueryRow", ctx, countQuery, []any{since}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		report, err := manager.ValidateGroupVersions(ctx, since, time.Minute)
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+		assert.Nil(t, report)
+	})
+}
+
+func TestRepairGroupVersions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{[]int{3, 4, 5}}).Return(mockRows, nil)
+		for _, row := range [][2]int{{3, 1}, {4, 3}} {
+			mockRows.On("Next").Return(true).Once()
+			mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*(args[0].([]any)[0].(*int)) = row[0]
+				*(args[0].([]any)[1].(*int)) = row[1]
+			}).Return(nil).Once()
+		}
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		report := &store.VersionReport{Drifts: []store.VersionDrift{
+			{GroupID: 3, Reason: store.VersionInvalid},
+			{GroupID: 4, Version: 2, Reason: store.VersionUnaudited},
+			{GroupID: 5, Version: 7, Reason: store.VersionUnaudited},
+		}}
+		assert.NoError(t, manager.RepairGroupVersions(ctx, report))
+		assert.Equal(t, 1, report.Drifts[0].RepairedTo)
+		assert.Equal(t, 3, report.Drifts[1].RepairedTo)
+		assert.Zero(t, report.Drifts[2].RepairedTo, "group 5 was deleted")
+	})
+
+	t.Run("nothing to repair", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		assert.NoError(t, manager.RepairGroupVersions(ctx, &store.VersionReport{}))
+		mockDb.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb, _, _, manager := setupMockDbAndManager()
+
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return((*MockRows)(nil), errors.New("db error"))
+
+		err := manager.RepairGroupVersions(ctx, &store.VersionReport{Drifts: []store.VersionDrift{{GroupID: 3}}})
+		assertPolicyStoreError(t, err, store.NewDataBaseError())
+	})
+}
@@ -0,0 +1,111 @@
+// Package storetest holds the contract tests of the policy stores, run against the in-memory store
+// by its unit tests and against Postgres by its integration tests, so both behave the same. The
+// tests name what they create uniquely, so they run against a database shared with other tests.
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/folder"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+)
+
+// TestFolders checks the folders kept by the folder store are read back by the manager sharing its
+// groups and permissions, with their permissions granted to the groups of the folder and of the
+// subfolders inheriting them.
+func TestFolders(t *testing.T, manager store.PolicyManager[int, int, string], folders folder.Store) {
+	ctx := context.Background()
+	suffix := uuid.NewString()
+	cooks, err := manager.CreateGroup(ctx, "cooks-"+suffix)
+	assert.NoError(t, err)
+	bakers, err := manager.CreateGroup(ctx, "bakers-"+suffix)
+	assert.NoError(t, err)
+	read, err := manager.CreatePermission(ctx, "recipes-"+suffix+".read")
+	assert.NoError(t, err)
+
+	kitchen, err := folders.Create(ctx, folder.Folder{Name: "kitchen-" + suffix})
+	assert.NoError(t, err)
+	pastry, err := folders.Create(ctx, folder.Folder{Name: "pastry", ParentID: kitchen, Inheritance: authz.InheritanceInherit})
+	assert.NoError(t, err)
+	_, err = folders.Create(ctx, folder.Folder{Name: "pastry", ParentID: kitchen})
+	assert.ErrorIs(t, err, folder.ErrNameExists)
+	_, err = folders.Create(ctx, folder.Folder{Name: "cellar", ParentID: -1})
+	assert.ErrorIs(t, err, folder.ErrNotFound)
+
+	assert.NoError(t, folders.SetPermissions(ctx, kitchen, []int{read}))
+	assertPolicyStoreError(t, folders.SetPermissions(ctx, kitchen, []int{-1}), store.NewPermissionNotFoundError())
+	assert.ErrorIs(t, folders.SetPermissions(ctx, -1, []int{read}), folder.ErrNotFound)
+	assert.NoError(t, folders.AssignGroup(ctx, cooks, kitchen))
+	assert.NoError(t, folders.AssignGroup(ctx, bakers, pastry))
+	assertPolicyStoreError(t, folders.AssignGroup(ctx, -1, kitchen), store.NewGroupNotFoundError())
+	assert.ErrorIs(t, folders.AssignGroup(ctx, cooks, -1), folder.ErrNotFound)
+
+	t.Run("read policy", func(t *testing.T) {
+		policy, err := manager.ReadPolicy(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, policy.Folders, authz.Folder{Path: "kitchen-" + suffix, Groups: []string{"cooks-" + suffix}, Grants: []string{"recipes-" + suffix + ".read"}})
+		assert.Contains(t, policy.Folders, authz.Folder{Path: "kitchen-" + suffix + "/pastry", Inheritance: authz.InheritanceInherit, Groups: []string{"bakers-" + suffix}})
+		for _, permission := range policy.Permissions {
+			if permission.Name == "recipes-"+suffix+".read" {
+				assert.ElementsMatch(t, []string{"cooks-" + suffix, "bakers-" + suffix}, permission.Groups)
+			}
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, err := folders.List(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, list, folder.Folder{ID: kitchen, Name: "kitchen-" + suffix, Admins: []string{}, Permissions: []int{read}})
+
+		assignments, err := folders.Assignments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, kitchen, assignments.Groups[cooks])
+		assert.Equal(t, pastry, assignments.Groups[bakers])
+	})
+
+	t.Run("update", func(t *testing.T) {
+		assert.ErrorIs(t, folders.Update(ctx, folder.Folder{ID: kitchen, Name: "kitchen-" + suffix, ParentID: pastry}), folder.ErrCycle)
+		assert.ErrorIs(t, folders.Update(ctx, folder.Folder{ID: kitchen, Name: "kitchen-" + suffix, ParentID: kitchen}), folder.ErrCycle)
+		assert.ErrorIs(t, folders.Update(ctx, folder.Folder{ID: -1, Name: "cellar"}), folder.ErrNotFound)
+		assert.NoError(t, folders.Update(ctx, folder.Folder{ID: pastry, Name: "bakery", ParentID: kitchen, Inheritance: authz.InheritanceBlock}))
+
+		policy, err := manager.ReadPolicy(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, policy.Folders, authz.Folder{Path: "kitchen-" + suffix + "/bakery", Inheritance: authz.InheritanceBlock, Groups: []string{"bakers-" + suffix}})
+		for _, permission := range policy.Permissions {
+			if permission.Name == "recipes-"+suffix+".read" {
+				assert.Equal(t, []string{"cooks-" + suffix}, permission.Groups)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.ErrorIs(t, folders.Delete(ctx, kitchen), folder.ErrNotEmpty)
+		assert.ErrorIs(t, folders.Delete(ctx, pastry), folder.ErrNotEmpty)
+		assert.ErrorIs(t, folders.Delete(ctx, -1), folder.ErrNotFound)
+
+		_, err := manager.DeleteGroup(ctx, bakers)
+		assert.NoError(t, err)
+		assert.NoError(t, folders.Delete(ctx, pastry))
+		assert.NoError(t, folders.AssignGroup(ctx, cooks, 0))
+		assert.NoError(t, folders.Delete(ctx, kitchen))
+
+		assignments, err := folders.Assignments(ctx)
+		assert.NoError(t, err)
+		assert.NotContains(t, assignments.Groups, cooks)
+		assert.NotContains(t, assignments.Groups, bakers)
+	})
+}
+
+func assertPolicyStoreError(t *testing.T, err error, exp *store.PolicyStoreError) {
+	t.Helper()
+	var policyStoreError *store.PolicyStoreError
+	if assert.ErrorAs(t, err, &policyStoreError) {
+		assert.Equal(t, exp.Code, policyStoreError.Code)
+	}
+}
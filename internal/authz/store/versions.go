@@ -0,0 +1,42 @@
+package store
+
+import "time"
+
+// The reasons a group version drifted.
+const (
+	// VersionInvalid means the version is missing or below 1, which the application never writes.
+	VersionInvalid = "invalid_version"
+	// VersionUnaudited means the members or grants of the group changed without an audit event
+	// recording an application change, such as through manual SQL, so the version may not have
+	// been bumped and clients holding it may overwrite the change.
+	VersionUnaudited = "unaudited_changes"
+)
+
+// VersionDrift is a group whose version cannot be trusted for optimistic concurrency.
+type VersionDrift struct {
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	// The version of the group, zero when missing.
+	Version int `json:"version"`
+	// Why the version drifted, VersionInvalid or VersionUnaudited.
+	Reason string `json:"reason"`
+	// The number of membership and grant changes no audit event records, for VersionUnaudited.
+	Changes int `json:"changes,omitempty"`
+	// The version the group was repaired to, zero when it was not repaired.
+	RepairedTo int `json:"repaired_to,omitempty"`
+}
+
+// VersionReport is the result of validating the group versions against the audit history.
+type VersionReport struct {
+	// The changes made since this time were validated.
+	Since time.Time `json:"since"`
+	// The number of application changes the audit history holds since then.
+	AuditEvents int `json:"audit_events"`
+	// The groups whose version drifted, empty when every version can be trusted.
+	Drifts []VersionDrift `json:"drifts"`
+}
+
+// OK reports whether no drift was found.
+func (report *VersionReport) OK() bool {
+	return len(report.Drifts) == 0
+}
@@ -0,0 +1,140 @@
+package syncreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const selectSummary = `
+	SELECT id, connector, started_at, finished_at, submitted_by, added, removed, skipped, errors
+	FROM sync_reports`
+
+// Create stores a new report and returns its id.
+func (store *PostgresStore) Create(ctx context.Context, report Report) (int, error) {
+	entries, err := json.Marshal(report.Entries)
+	if err != nil {
+		return 0, err
+	}
+	errs, err := json.Marshal(report.Errors)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = store.db.QueryRow(ctx, `
+	INSERT INTO sync_reports (connector, started_at, finished_at, submitted_by, added, removed, skipped, errors, entry_list, error_list)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id
+	`, report.Connector, report.StartedAt, report.FinishedAt, report.SubmittedBy,
+		report.Summary.Added, report.Summary.Removed, report.Summary.Skipped, report.Summary.Errors, entries, errs).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Get returns the report with the given id, including its entries and errors.
+func (store *PostgresStore) Get(ctx context.Context, id int) (*Report, error) {
+	var report Report
+	var entries, errs []byte
+	err := store.db.QueryRow(ctx, `
+	SELECT id, connector, started_at, finished_at, submitted_by, added, removed, skipped, errors, entry_list, error_list
+	FROM sync_reports WHERE id = $1
+	`, id).Scan(&report.ID, &report.Connector, &report.StartedAt, &report.FinishedAt, &report.SubmittedBy,
+		&report.Summary.Added, &report.Summary.Removed, &report.Summary.Skipped, &report.Summary.Errors, &entries, &errs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(entries, &report.Entries); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(errs, &report.Errors); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// List returns the summaries of the reports matching the filter, most recent first.
+func (store *PostgresStore) List(ctx context.Context, filter Filter) ([]Report, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	// the reports are listed by descending key, so the next ones have a lower key
+	var finishedAt *time.Time
+	var id *int
+	if filter.After != nil {
+		if len(filter.After) != 2 {
+			return nil, paging.ErrInvalidCursor
+		}
+		after, ok := filter.After[0].(time.Time)
+		afterId, idOk := filter.After[1].(int)
+		if !ok || !idOk {
+			return nil, paging.ErrInvalidCursor
+		}
+		finishedAt, id = &after, &afterId
+	}
+
+	rows, err := store.db.Query(ctx, selectSummary+`
+	WHERE ($1 = '' OR connector = $1)
+	AND ($3::timestamptz IS NULL OR (finished_at, id) < ($3, $4::int))
+	ORDER BY finished_at DESC, id DESC
+	LIMIT $2
+	`, filter.Connector, limit, finishedAt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+		err := rows.Scan(&report.ID, &report.Connector, &report.StartedAt, &report.FinishedAt, &report.SubmittedBy,
+			&report.Summary.Added, &report.Summary.Removed, &report.Summary.Skipped, &report.Summary.Errors)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return reports, nil
+}
+
+// Prune deletes the reports that finished before the given time and returns how many were deleted.
+func (store *PostgresStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	tag, err := store.db.Exec(ctx, "DELETE FROM sync_reports WHERE finished_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
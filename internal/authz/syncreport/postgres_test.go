@@ -0,0 +1,148 @@
+package syncreport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestPostgresStore_Create(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	mockRow := new(MockRow)
+	store := NewPostgresStore(mockDb)
+	report := validReport()
+	assert.NoError(t, report.Validate())
+	report.SubmittedBy = "sync-bot"
+
+	mockDb.On("QueryRow", ctx, mock.Anything, []any{"ldap", report.StartedAt, report.FinishedAt, "sync-bot", 2, 1, 1, 1,
+		[]byte(`[{"action":"added","user":"alice","group":"cooks"},{"action":"removed","user":"bob","group":"cooks"},{"action":"skipped","user":"carol","group":"cooks","reason":"protected"},{"action":"added","user":"dave","group":"bakers"}]`),
+		[]byte(`["group tasters not found"]`)}).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[0].([]any)[0].(*int)) = 5
+	}).Return(nil)
+
+	id, err := store.Create(ctx, report)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+
+	mockDb.AssertExpectations(t)
+	mockRow.AssertExpectations(t)
+}
+
+func TestPostgresStore_Get(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{5}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*int)) = 5
+			*(dest[1].(*string)) = "ldap"
+			*(dest[2].(*time.Time)) = started
+			*(dest[3].(*time.Time)) = started.Add(time.Minute)
+			*(dest[4].(*string)) = "sync-bot"
+			*(dest[5].(*int)) = 1
+			*(dest[9].(*[]byte)) = []byte(`[{"action":"added","user":"alice","group":"cooks"}]`)
+			*(dest[10].(*[]byte)) = []byte(`null`)
+		}).Return(nil)
+
+		report, err := store.Get(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, &Report{
+			ID:          5,
+			Connector:   "ldap",
+			StartedAt:   started,
+			FinishedAt:  started.Add(time.Minute),
+			SubmittedBy: "sync-bot",
+			Summary:     Summary{Added: 1},
+			Entries:     []Entry{{Action: ActionAdded, User: "alice", Group: "cooks"}},
+		}, report)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{5}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		report, err := store.Get(ctx, 5)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, report)
+	})
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("default limit", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRows := new(MockRows)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"", 100, (*time.Time)(nil), (*int)(nil)}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false)
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*int)) = 5
+			*(dest[1].(*string)) = "scim"
+		}).Return(nil)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		reports, err := store.List(ctx, Filter{})
+		assert.NoError(t, err)
+		assert.Equal(t, []Report{{ID: 5, Connector: "scim"}}, reports)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		store := NewPostgresStore(mockDb)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"ldap", 10, (*time.Time)(nil), (*int)(nil)}).Return(new(MockRows), errors.New("db error"))
+
+		reports, err := store.List(ctx, Filter{Connector: "ldap", Limit: 10})
+		assert.Error(t, err)
+		assert.Nil(t, reports)
+	})
+
+	t.Run("after", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRows := new(MockRows)
+		store := NewPostgresStore(mockDb)
+		finishedAt, id := started, 7
+
+		mockDb.On("Query", ctx, mock.Anything, []any{"", 10, &finishedAt, &id}).Return(mockRows, nil)
+		mockRows.On("Next").Return(false)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		reports, err := store.List(ctx, Filter{Limit: 10, After: ListKey(Report{ID: 7, FinishedAt: started})})
+		assert.NoError(t, err)
+		assert.Empty(t, reports)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		store := NewPostgresStore(new(MockPgDb))
+
+		for _, after := range []paging.Key{{}, {7}, {started, "7"}, {started, 7, 1}} {
+			_, err := store.List(ctx, Filter{After: after})
+			assert.ErrorIs(t, err, paging.ErrInvalidCursor)
+		}
+	})
+}
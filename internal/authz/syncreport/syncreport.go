@@ -0,0 +1,154 @@
+// Package syncreport records what external synchronization, such as LDAP, SCIM or GitOps
+// connectors, changed in the policy store during each run, so operators can audit it.
+package syncreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+)
+
+// Action is what a sync run did with a single entry.
+type Action string
+
+const (
+	ActionAdded   Action = "added"
+	ActionRemoved Action = "removed"
+	// ActionSkipped marks an entry the run left alone, such as a membership protected
+	// by a higher precedence source.
+	ActionSkipped Action = "skipped"
+)
+
+// ErrNotFound is returned when the report does not exist.
+var ErrNotFound = errors.New("sync report not found")
+
+// Entry is a single change, or skipped change, made by a sync run.
+type Entry struct {
+	Action Action `json:"action"`
+	User   string `json:"user,omitempty"`
+	Group  string `json:"group,omitempty"`
+	// Why the entry was skipped, or any other detail worth recording.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Summary counts the entries and errors of a report.
+type Summary struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Skipped int `json:"skipped"`
+	Errors  int `json:"errors"`
+}
+
+// Report describes a single sync run.
+type Report struct {
+	ID int `json:"id"`
+	// The connector that ran, such as "ldap".
+	Connector   string    `json:"connector"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	SubmittedBy string    `json:"submitted_by"`
+	Summary     Summary   `json:"summary"`
+	// The entries and errors of the run. Listings leave them out.
+	Entries []Entry  `json:"entries,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Filter selects the reports returned by Store.List.
+type Filter struct {
+	// Only list reports of this connector, when set.
+	Connector string
+	// The maximum number of reports to return, most recent first.
+	Limit int
+	// Only list the reports following the one with this key, see ListKey, to resume a listing.
+	After paging.Key
+}
+
+// ListKey returns the key reports are listed by, most recent first: their finish time and id.
+func ListKey(report Report) paging.Key {
+	return paging.Key{report.FinishedAt, report.ID}
+}
+
+// Store persists sync reports.
+type Store interface {
+	Create(ctx context.Context, report Report) (int, error)
+	Get(ctx context.Context, id int) (*Report, error)
+	List(ctx context.Context, filter Filter) ([]Report, error)
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// Validate checks that the report is complete and computes its summary.
+func (report *Report) Validate() error {
+	if report.Connector == "" {
+		return errors.New("connector is empty")
+	}
+	if report.StartedAt.IsZero() || report.FinishedAt.IsZero() {
+		return errors.New("start and finish times are required")
+	}
+	if report.FinishedAt.Before(report.StartedAt) {
+		return errors.New("finish time is before the start time")
+	}
+
+	summary := Summary{Errors: len(report.Errors)}
+	for i, entry := range report.Entries {
+		if entry.User == "" && entry.Group == "" {
+			return fmt.Errorf("entry %d: user and group are empty", i+1)
+		}
+		switch entry.Action {
+		case ActionAdded:
+			summary.Added++
+		case ActionRemoved:
+			summary.Removed++
+		case ActionSkipped:
+			summary.Skipped++
+		default:
+			return fmt.Errorf("entry %d: unknown action %q", i+1, entry.Action)
+		}
+	}
+
+	report.Summary = summary
+	return nil
+}
+
+// Retention periodically deletes the reports older than a maximum age.
+type Retention struct {
+	store  Store
+	maxAge time.Duration
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// NewRetention creates a new Retention deleting the reports older than maxAge from the store.
+func NewRetention(store Store, maxAge time.Duration, logger *slog.Logger) *Retention {
+	return &Retention{store: store, maxAge: maxAge, logger: logger, clock: clock.System()}
+}
+
+// Prune deletes the reports that finished before the retention period and returns how many were deleted.
+func (retention *Retention) Prune(ctx context.Context) (int, error) {
+	return retention.store.Prune(ctx, retention.clock.Now().Add(-retention.maxAge))
+}
+
+// Run prunes the reports immediately and then every interval until the context is done.
+func (retention *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := retention.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := retention.Prune(ctx)
+		if err != nil {
+			retention.logger.Error("failed to prune sync reports", "error", err)
+		} else if deleted > 0 {
+			retention.logger.Info("pruned sync reports", "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package syncreport
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var started = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func validReport() Report {
+	return Report{
+		Connector:  "ldap",
+		StartedAt:  started,
+		FinishedAt: started.Add(time.Minute),
+		Entries: []Entry{
+			{Action: ActionAdded, User: "alice", Group: "cooks"},
+			{Action: ActionRemoved, User: "bob", Group: "cooks"},
+			{Action: ActionSkipped, User: "carol", Group: "cooks", Reason: "protected"},
+			{Action: ActionAdded, User: "dave", Group: "bakers"},
+		},
+		Errors: []string{"group tasters not found"},
+	}
+}
+
+func TestReport_Validate(t *testing.T) {
+	t.Run("summary", func(t *testing.T) {
+		report := validReport()
+		assert.NoError(t, report.Validate())
+		assert.Equal(t, Summary{Added: 2, Removed: 1, Skipped: 1, Errors: 1}, report.Summary)
+	})
+
+	tests := []struct {
+		name   string
+		change func(report *Report)
+	}{
+		{"empty connector", func(report *Report) { report.Connector = "" }},
+		{"missing start", func(report *Report) { report.StartedAt = time.Time{} }},
+		{"finish before start", func(report *Report) { report.FinishedAt = started.Add(-time.Minute) }},
+		{"unknown action", func(report *Report) { report.Entries[0].Action = "renamed" }},
+		{"empty entry", func(report *Report) { report.Entries[0] = Entry{Action: ActionAdded} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := validReport()
+			tt.change(&report)
+			assert.Error(t, report.Validate())
+		})
+	}
+}
+
+func TestRetention_Prune(t *testing.T) {
+	ctx := context.Background()
+	mockDb := new(MockPgDb)
+	retention := NewRetention(NewPostgresStore(mockDb), 24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	retention.clock = NewFakeClock(started)
+
+	mockDb.On("Exec", ctx, mock.Anything, []any{started.Add(-24 * time.Hour)}).Return(pgconn.NewCommandTag("DELETE 3"), nil)
+
+	deleted, err := retention.Prune(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	mockDb.AssertExpectations(t)
+}
+
+// TestRetention_Run runs the retention on a fake clock, checking it prunes once per interval against the advanced time.
+func TestRetention_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockDb := new(MockPgDb)
+	clock := NewFakeClock(started)
+	retention := NewRetention(NewPostgresStore(mockDb), 24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	retention.clock = clock
+
+	pruned := make(chan time.Time)
+	mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		pruned <- args[2].([]any)[0].(time.Time)
+	}).Return(pgconn.NewCommandTag("DELETE 0"), nil)
+
+	done := make(chan struct{})
+	go func() {
+		retention.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	assert.Equal(t, started.Add(-24*time.Hour), <-pruned)
+	clock.Advance(time.Hour)
+	assert.Equal(t, started.Add(-23*time.Hour), <-pruned)
+
+	cancel()
+	<-done
+}
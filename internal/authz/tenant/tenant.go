@@ -0,0 +1,72 @@
+// Package tenant identifies the tenants sharing an authz deployment. Each tenant is an isolated
+// application whose policy, audit history and every other record are kept in a database schema of
+// its own, so the statements run for one tenant can neither read nor change the records of another.
+//
+// A serve process serves a single tenant, the one of the tenant setting, and a deployment runs one
+// process per tenant. The policy cache, the change listener, the Watch streams and the meta-policy
+// of the administration API all belong to the tenant of the process, so a tenant can neither load
+// nor lock out another, and each process is sized, scaled and upgraded for its own tenant. Only the
+// tenants subcommand opens the schemas of several tenants, with postgres.Tenants.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SchemaPrefix prefixes the database schema of every tenant.
+const SchemaPrefix = "tenant_"
+
+// DefaultSchema is the database schema of the deployments serving a single application.
+const DefaultSchema = "public"
+
+var (
+	// ErrInvalidID is returned for tenant ids that cannot name a database schema.
+	ErrInvalidID = errors.New("invalid tenant id")
+	// ErrNotProvisioned is returned for the tenants whose database schema was not created.
+	ErrNotProvisioned = errors.New("tenant is not provisioned")
+)
+
+// idPattern matches the tenant ids, which are used unquoted in schema and channel names.
+var idPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// ID identifies a tenant, such as "recipes". The empty ID is the default tenant of the
+// deployments serving a single application.
+type ID string
+
+// Parse validates a tenant id: a lowercase letter followed by at most 39 lowercase letters,
+// digits or underscores. The empty string parses to the default tenant.
+func Parse(id string) (ID, error) {
+	if id != "" && !idPattern.MatchString(id) {
+		return "", fmt.Errorf("%w %q: expected a lowercase letter followed by at most 39 lowercase letters, digits or underscores", ErrInvalidID, id)
+	}
+	return ID(id), nil
+}
+
+// IsDefault reports whether the id is the default tenant.
+func (id ID) IsDefault() bool {
+	return id == ""
+}
+
+// Schema returns the database schema holding the records of the tenant.
+func (id ID) Schema() string {
+	if id.IsDefault() {
+		return DefaultSchema
+	}
+	return SchemaPrefix + string(id)
+}
+
+// FromSchema returns the tenant whose records the database schema holds, false when the schema
+// is not the one of a tenant.
+func FromSchema(schema string) (ID, bool) {
+	if schema == DefaultSchema {
+		return "", true
+	}
+	id, ok := strings.CutPrefix(schema, SchemaPrefix)
+	if !ok || !idPattern.MatchString(id) {
+		return "", false
+	}
+	return ID(id), true
+}
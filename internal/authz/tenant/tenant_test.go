@@ -0,0 +1,36 @@
+package tenant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, valid := range []string{"", "recipes", "a", "shop_2"} {
+		id, err := Parse(valid)
+		assert.NoError(t, err, valid)
+		assert.Equal(t, ID(valid), id)
+	}
+
+	for _, invalid := range []string{"Recipes", "2shop", "_shop", "shop-eu", "shop eu", "a23456789012345678901234567890123456789012"} {
+		_, err := Parse(invalid)
+		assert.ErrorIs(t, err, ErrInvalidID, invalid)
+	}
+}
+
+func TestID_Schema(t *testing.T) {
+	assert.Equal(t, "public", ID("").Schema())
+	assert.Equal(t, "tenant_recipes", ID("recipes").Schema())
+
+	for _, id := range []ID{"", "recipes"} {
+		parsed, ok := FromSchema(id.Schema())
+		assert.True(t, ok)
+		assert.Equal(t, id, parsed)
+	}
+
+	for _, schema := range []string{"information_schema", "tenant_", "tenant_Shop", "audit"} {
+		_, ok := FromSchema(schema)
+		assert.False(t, ok, schema)
+	}
+}
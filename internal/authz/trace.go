@@ -0,0 +1,115 @@
+package authz
+
+import (
+	"slices"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/salmarsumi/recipes/internal/shared"
+)
+
+// TraceStepKind tells what a step of a traced evaluation considered.
+type TraceStepKind string
+
+const (
+	// TraceGroup is the membership of the user in a group.
+	TraceGroup TraceStepKind = "group"
+	// TracePermission is a permission granted directly to the groups of the user.
+	TracePermission TraceStepKind = "permission"
+	// TraceImplication is a permission granted because another granted permission implies it.
+	TraceImplication TraceStepKind = "implication"
+	// TraceDenial is a permission explicitly denied to the user or their groups, see Denial.
+	TraceDenial TraceStepKind = "denial"
+)
+
+// TraceStep records one group or permission considered during an evaluation and its outcome.
+type TraceStep struct {
+	Kind TraceStepKind `json:"kind"`
+	// The name of the group or permission.
+	Name    string `json:"name"`
+	Matched bool   `json:"matched"`
+	// What the match came through: the groups of the user granted or denied the permission,
+	// the granted permissions implying it, or the user denied it by name. Empty when the step did not match.
+	Via []string `json:"via,omitempty"`
+}
+
+// Tracer receives the steps of a traced evaluation, in the order they were considered.
+// Every group is reported first, then every permission, then the permissions gained through implications,
+// then every denial.
+type Tracer func(step TraceStep)
+
+// EvaluateTraced evaluates the user like Evaluate, reporting every group and permission considered
+// to the tracer, to explain why a permission was or was not granted. A nil tracer traces nothing.
+//
+// Parameters:
+//
+//	user - the username to be evaluated against the policy.
+//	tracer - the callback receiving the steps of the evaluation.
+//
+// Returns:
+//
+//	*PolicyEvaluationResult - the result of the policy evaluation.
+//	error - an error if the evaluation process encounters an issue.
+func (policy *Policy) EvaluateTraced(user string, tracer Tracer) (*PolicyEvaluationResult, error) {
+	return policy.EvaluateTracedWith(user, nil, tracer)
+}
+
+// EvaluateTracedWith evaluates the user for a request with the given attributes like EvaluateWith,
+// reporting every group and permission considered to the tracer like EvaluateTraced.
+func (policy *Policy) EvaluateTracedWith(user string, attributes condition.Attributes, tracer Tracer) (*PolicyEvaluationResult, error) {
+	result, err := policy.EvaluateWith(user, attributes)
+	if err != nil || tracer == nil {
+		return result, err
+	}
+
+	for _, group := range policy.Groups {
+		tracer(TraceStep{Kind: TraceGroup, Name: group.Name, Matched: slices.Contains(result.Groups, group.Name)})
+	}
+
+	direct := make(shared.Set[string], len(result.Permissions))
+	for _, permission := range policy.Permissions {
+		var via []string
+		for _, group := range permission.Groups {
+			if slices.Contains(result.Groups, group) {
+				via = append(via, group)
+			}
+		}
+		if holds, _ := permission.ConditionHolds(user, attributes); !holds {
+			via = nil
+		}
+		if len(via) > 0 {
+			direct.Add(permission.Name)
+		}
+		tracer(TraceStep{Kind: TracePermission, Name: permission.Name, Matched: len(via) > 0, Via: via})
+	}
+
+	// implications are expanded before the denials are applied, so a permission denied by name
+	// or by a wildcard still grants the permissions it implies
+	held := shared.NewSet(policy.expandImplied(direct.Values())...)
+	for _, name := range result.Permissions {
+		if direct.Contains(name) {
+			continue
+		}
+		var via []string
+		for _, permission := range policy.Permissions {
+			if slices.Contains(permission.Implies, name) && held.Contains(permission.Name) {
+				via = append(via, permission.Name)
+			}
+		}
+		tracer(TraceStep{Kind: TraceImplication, Name: name, Matched: true, Via: via})
+	}
+
+	for _, denial := range policy.Denials {
+		var via []string
+		for _, group := range denial.Groups {
+			if slices.Contains(result.Groups, group) {
+				via = append(via, group)
+			}
+		}
+		if slices.Contains(denial.Users, user) {
+			via = append(via, user)
+		}
+		tracer(TraceStep{Kind: TraceDenial, Name: denial.Permission, Matched: len(via) > 0, Via: via})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/condition"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPolicy_EvaluateTraced evaluates a user with a tracer, checking every group and permission is reported.
+func TestPolicy_EvaluateTraced(t *testing.T) {
+	var steps []TraceStep
+	result, err := sessionPolicy().EvaluateTraced("bob", func(step TraceStep) {
+		steps = append(steps, step)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"writers"}, result.Groups)
+	assert.Equal(t, []TraceStep{
+		{Kind: TraceGroup, Name: "readers"},
+		{Kind: TraceGroup, Name: "writers", Matched: true},
+		{Kind: TraceGroup, Name: "admins"},
+		{Kind: TracePermission, Name: "read"},
+		{Kind: TracePermission, Name: "write", Matched: true, Via: []string{"writers"}},
+		{Kind: TracePermission, Name: "delete"},
+		{Kind: TraceImplication, Name: "read", Matched: true, Via: []string{"write"}},
+	}, steps)
+}
+
+// TestPolicy_EvaluateTraced_NilTracer evaluates a user without a tracer, checking the result matches Evaluate.
+func TestPolicy_EvaluateTraced_NilTracer(t *testing.T) {
+	policy := sessionPolicy()
+	expected, err := policy.Evaluate("alice")
+	assert.NoError(t, err)
+
+	result, err := policy.EvaluateTraced("alice", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	_, err = policy.EvaluateTraced("", func(TraceStep) { t.Fatal("an invalid evaluation was traced") })
+	assert.EqualError(t, err, "user is empty")
+}
+
+// TestPolicy_EvaluateTraced_DeniedImplication evaluates a user denied an implying permission by a wildcard,
+// checking the implied permission is still traced through it.
+func TestPolicy_EvaluateTraced_DeniedImplication(t *testing.T) {
+	policy := NewPolicy(
+		[]Permission{
+			{Name: "recipes.read", Groups: []string{"readers"}},
+			{Name: "recipes.drafts.publish", Groups: []string{"editors"}, Implies: []string{"recipes.read"}},
+		},
+		[]Group{*NewGroup("readers", []string{"alice"}), *NewGroup("editors", []string{"bob"})},
+	)
+	policy.Denials = []Denial{*NewDenial("recipes.drafts.*", nil, []string{"bob"})}
+
+	var steps []TraceStep
+	result, err := policy.EvaluateTraced("bob", func(step TraceStep) {
+		steps = append(steps, step)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recipes.read"}, result.Permissions)
+	assert.Contains(t, steps, TraceStep{Kind: TraceImplication, Name: "recipes.read", Matched: true, Via: []string{"recipes.drafts.publish"}})
+	assert.Contains(t, steps, TraceStep{Kind: TraceDenial, Name: "recipes.drafts.*", Matched: true, Via: []string{"bob"}})
+}
+
+// TestPolicy_EvaluateTracedWith evaluates a user with request attributes and a tracer, checking the
+// conditional permissions are traced as matched only when their condition holds.
+func TestPolicy_EvaluateTracedWith(t *testing.T) {
+	var steps []TraceStep
+	result, err := conditionPolicy().EvaluateTracedWith("alice", condition.Attributes{"resource.owner": "alice"}, func(step TraceStep) {
+		steps = append(steps, step)
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"recipes.edit", "recipes.publish"}, result.Permissions)
+	assert.Contains(t, steps, TraceStep{Kind: TracePermission, Name: "recipes.edit", Matched: true, Via: []string{"cooks"}})
+	assert.Contains(t, steps, TraceStep{Kind: TracePermission, Name: "recipes.read"})
+}
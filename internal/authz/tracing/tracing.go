@@ -0,0 +1,52 @@
+// Package tracing holds what the OpenTelemetry instrumentation of the authorization service shares,
+// so the spans of the policy store and of the evaluations are named and annotated alike.
+package tracing
+
+import (
+	"errors"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracers of the service.
+const InstrumentationName = "github.com/salmarsumi/recipes/internal/authz"
+
+// The attributes of the spans.
+const (
+	GroupIDKey      = attribute.Key("authz.group_id")
+	PermissionIDKey = attribute.Key("authz.permission_id")
+	PermissionKey   = attribute.Key("authz.permission")
+	CountKey        = attribute.Key("authz.count")
+	AllowedKey      = attribute.Key("authz.allowed")
+	ReasonKey       = attribute.Key("authz.reason")
+	ErrorCodeKey    = attribute.Key("authz.error_code")
+	// RowsAffectedKey counts the rows the statements of the operation inserted, updated or deleted.
+	RowsAffectedKey = attribute.Key("db.rows_affected")
+)
+
+// Tracer returns the tracer of the service from the provider, or from the global provider when nil.
+func Tracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(InstrumentationName)
+}
+
+// End ends the span of an operation, recording its error if any. The code of a store.PolicyStoreError
+// is recorded as the ErrorCodeKey attribute, so the expected failures such as GroupNotFound can be told
+// apart from the database errors.
+func End(span trace.Span, err error) {
+	if err != nil {
+		var storeError *store.PolicyStoreError
+		if errors.As(err, &storeError) {
+			span.SetAttributes(ErrorCodeKey.Int(int(storeError.Code)))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestEnd(t *testing.T) {
+	recorder := NewSpanRecorder()
+	tracer := Tracer(recorder)
+
+	_, succeeded := tracer.Start(context.Background(), "succeeded")
+	End(succeeded, nil)
+	_, failed := tracer.Start(context.Background(), "failed")
+	End(failed, errors.New("db error"))
+	_, refused := tracer.Start(context.Background(), "refused")
+	End(refused, fmt.Errorf("import: %w", store.NewGroupNotFoundError()))
+
+	spans := recorder.Spans()
+	assert.True(t, spans[0].Ended)
+	assert.Equal(t, codes.Unset, spans[0].Status)
+
+	assert.True(t, spans[1].Ended)
+	assert.Equal(t, codes.Error, spans[1].Status)
+	assert.NotContains(t, spans[1].Attributes, ErrorCodeKey)
+
+	assert.Equal(t, codes.Error, spans[2].Status)
+	assert.Equal(t, int64(store.GroupNotFound), spans[2].Attributes[ErrorCodeKey].AsInt64())
+	assert.Len(t, spans[2].Errors, 1)
+}
+
+func TestTracer_GlobalProvider(t *testing.T) {
+	_, span := Tracer(nil).Start(context.Background(), "noop")
+	assert.False(t, span.IsRecording())
+	End(span, nil)
+}
@@ -0,0 +1,75 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/pgdb"
+)
+
+// PostgresStore is a Postgres implementation of the Store interface, reading the policy_changes table.
+type PostgresStore struct {
+	db pgdb.DB
+}
+
+var (
+	_ Store  = (*PostgresStore)(nil)
+	_ Pruner = (*PostgresStore)(nil)
+)
+
+// NewPostgresStore creates a new PostgresStore instance.
+func NewPostgresStore(db pgdb.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Changes returns at most limit changes logged after the change with the given id. The triggers
+// logging the changes hold the policy revision lock, so a change is never committed after one
+// with a greater id.
+func (store *PostgresStore) Changes(ctx context.Context, after int64, limit int) ([]Change, error) {
+	rows, err := store.db.Query(ctx, `
+	SELECT id, kind, revision, group_name, user_id, permission_name, previous_name, changed_at FROM policy_changes
+	WHERE id > $1 ORDER BY id LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []Change{}
+	for rows.Next() {
+		var change Change
+		err := rows.Scan(&change.ID, &change.Kind, &change.Revision, &change.Group, &change.User, &change.Permission,
+			&change.PreviousName, &change.Time)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return changes, nil
+}
+
+// LastChange returns the id of the last change at or before the policy revision, or of the last change logged.
+func (store *PostgresStore) LastChange(ctx context.Context, revision int64) (int64, error) {
+	var id int64
+	err := store.db.QueryRow(ctx, `
+	SELECT COALESCE(max(id), 0) FROM policy_changes WHERE $1 = 0 OR revision <= $1
+	`, revision).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Prune deletes the changes logged before the given time and returns how many were deleted.
+func (store *PostgresStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	tag, err := store.db.Exec(ctx, "DELETE FROM policy_changes WHERE changed_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
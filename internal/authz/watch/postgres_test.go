@@ -0,0 +1,107 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+func TestPostgresStore_Changes(t *testing.T) {
+	ctx := context.Background()
+	changedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRows := new(MockRows)
+
+		mockDb.On("Query", ctx, mock.Anything, []any{int64(7), 100}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args[0].([]any)
+			*(dest[0].(*int64)) = 8
+			*(dest[1].(*Kind)) = GroupRenamed
+			*(dest[2].(*int64)) = 3
+			*(dest[3].(*string)) = "cooks"
+			*(dest[4].(*string)) = ""
+			*(dest[5].(*string)) = ""
+			*(dest[6].(*string)) = "chefs"
+			*(dest[7].(*time.Time)) = changedAt
+		}).Return(nil).Once()
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return()
+
+		changes, err := NewPostgresStore(mockDb).Changes(ctx, 7, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, []Change{{ID: 8, Kind: GroupRenamed, Revision: 3, Group: "cooks", PreviousName: "chefs", Time: changedAt}}, changes)
+
+		mockDb.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Query", ctx, mock.Anything, mock.Anything).Return((*MockRows)(nil), errors.New("db error"))
+
+		_, err := NewPostgresStore(mockDb).Changes(ctx, 0, 100)
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestPostgresStore_LastChange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, []any{int64(3)}).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*(args[0].([]any)[0].(*int64)) = 8
+		}).Return(nil)
+
+		id, err := NewPostgresStore(mockDb).LastChange(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8), id)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockRow := new(MockRow)
+		mockDb.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(mockRow)
+		mockRow.On("Scan", mock.Anything).Return(errors.New("db error"))
+
+		_, err := NewPostgresStore(mockDb).LastChange(ctx, 0)
+		assert.EqualError(t, err, "db error")
+	})
+}
+
+func TestPostgresStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	before := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, []any{before}).Return(pgconn.NewCommandTag("DELETE 5"), nil)
+
+		deleted, err := NewPostgresStore(mockDb).Prune(ctx, before)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, deleted)
+
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockDb := new(MockPgDb)
+		mockDb.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("db error"))
+
+		_, err := NewPostgresStore(mockDb).Prune(ctx, before)
+		assert.EqualError(t, err, "db error")
+	})
+}
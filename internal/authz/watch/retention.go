@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// Pruner deletes the changes logged before a time, such as PostgresStore.
+type Pruner interface {
+	// Prune deletes the changes logged before the time and returns how many were deleted.
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// Retention periodically deletes the changes older than a maximum age. Watchers resuming after
+// a change deleted since miss the changes deleted after it, so the maximum age must exceed the
+// time they may stay disconnected.
+type Retention struct {
+	pruner Pruner
+	maxAge time.Duration
+	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// NewRetention creates a new Retention deleting the changes older than maxAge with the pruner.
+func NewRetention(pruner Pruner, maxAge time.Duration, logger *slog.Logger) *Retention {
+	return &Retention{pruner: pruner, maxAge: maxAge, logger: logger, clock: clock.System()}
+}
+
+// Prune deletes the changes logged before the retention period and returns how many were deleted.
+func (retention *Retention) Prune(ctx context.Context) (int, error) {
+	return retention.pruner.Prune(ctx, retention.clock.Now().Add(-retention.maxAge))
+}
+
+// Run prunes the changes immediately and then every interval until the context is done.
+func (retention *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := retention.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := retention.Prune(ctx)
+		if err != nil {
+			retention.logger.Error("failed to prune policy changes", "error", err)
+		} else if deleted > 0 {
+			retention.logger.Info("pruned policy changes", "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package watch reads the log of the group membership and permission grant changes, and of the group
+// and permission renames, so watchers such as downstream indexers and cache maintainers follow the
+// policy change by change. The log is filled by the triggers of the policy_changes table, whatever
+// instance or tool made the change, and pruned by Retention.
+package watch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is returned for a resume token that was not issued by EncodeToken.
+var ErrInvalidToken = errors.New("invalid resume token")
+
+// Kind is the kind of a change.
+type Kind string
+
+const (
+	MemberAdded   Kind = "member_added"
+	MemberRemoved Kind = "member_removed"
+	GrantAdded    Kind = "grant_added"
+	GrantRemoved  Kind = "grant_removed"
+	// GroupRenamed is a group renamed, to Group from PreviousName.
+	GroupRenamed Kind = "group_renamed"
+	// PermissionRenamed is a permission renamed, to Permission from PreviousName.
+	PermissionRenamed Kind = "permission_renamed"
+)
+
+// Change is a membership or a grant added or removed, or a group or a permission renamed.
+type Change struct {
+	// ID orders the changes in the order they were committed.
+	ID   int64
+	Kind Kind
+	// Revision is the policy revision the change is part of.
+	Revision int64
+	Group    string
+	// User is set for membership changes.
+	User string
+	// Permission is set for grant changes and permission renames.
+	Permission string
+	// PreviousName is the name of the group or permission before a rename.
+	PreviousName string
+	Time         time.Time
+}
+
+// Store reads the change log.
+type Store interface {
+	// Changes returns at most limit changes logged after the change with the given id, in order.
+	Changes(ctx context.Context, after int64, limit int) ([]Change, error)
+	// LastChange returns the id of the last change at or before the policy revision, or of the
+	// last change logged when the revision is zero. It returns zero when there is no such change.
+	LastChange(ctx context.Context, revision int64) (int64, error)
+}
+
+// tokenPrefix versions the format of the tokens, which callers must treat as opaque.
+const tokenPrefix = "c1:"
+
+// EncodeToken returns the token resuming after the change with the given id.
+func EncodeToken(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenPrefix + strconv.FormatInt(id, 10)))
+}
+
+// DecodeToken returns the id of the change the token resumes after.
+func DecodeToken(token string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	text, ok := strings.CutPrefix(string(decoded), tokenPrefix)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	id, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidToken
+	}
+	return id, nil
+}
+
+// Notifier wakes the watchers up when changes were made, so they read them at once rather than
+// at their next heartbeat. It is safe for concurrent use.
+type Notifier struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// NewNotifier creates a new Notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{changed: make(chan struct{})}
+}
+
+// Changed returns a channel closed at the next Notify. Watchers get it before reading the log,
+// so a change logged while they read is not missed.
+func (notifier *Notifier) Changed() <-chan struct{} {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	return notifier.changed
+}
+
+// Notify wakes up the watchers waiting on Changed.
+func (notifier *Notifier) Notify() {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	close(notifier.changed)
+	notifier.changed = make(chan struct{})
+}
@@ -0,0 +1,43 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	id, err := DecodeToken(EncodeToken(42))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	for _, token := range []string{"", "not base64!", EncodeToken(-1), "cjE6NDI"} {
+		_, err := DecodeToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+// TestNotifier checks a watcher waiting on Changed is woken up by the next Notify only.
+func TestNotifier(t *testing.T) {
+	notifier := NewNotifier()
+	changed := notifier.Changed()
+
+	select {
+	case <-changed:
+		t.Fatal("changed before Notify")
+	default:
+	}
+
+	notifier.Notify()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("not changed after Notify")
+	}
+
+	select {
+	case <-notifier.Changed():
+		t.Fatal("the next channel is already closed")
+	default:
+	}
+}
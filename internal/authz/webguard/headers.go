@@ -0,0 +1,92 @@
+package webguard
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ContentSecurityPolicy only allows the console to load its own assets and call its own API,
+// and forbids embedding it in frames of other sites.
+const ContentSecurityPolicy = "default-src 'self'; object-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'"
+
+// SecureHeaders wraps the handler so its responses carry the headers protecting browser
+// pages from framing, content sniffing and leaking URLs in referrers.
+// Over HTTPS the browser is also told to only use HTTPS for the host from then on.
+func SecureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Content-Security-Policy", ContentSecurityPolicy)
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "same-origin")
+		if isHTTPS(r) {
+			header.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SecureCookies wraps the handler so every cookie it sets is HttpOnly and SameSite=Lax
+// unless it asks for stricter settings, and Secure when the request was made over HTTPS.
+func SecureCookies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&cookieWriter{ResponseWriter: w, secure: isHTTPS(r)}, r)
+	})
+}
+
+// isHTTPS reports whether the request reached the service, or the reverse proxy in front of it, over HTTPS.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// cookieWriter hardens the cookies of the response before its header is written.
+type cookieWriter struct {
+	http.ResponseWriter
+	secure      bool
+	wroteHeader bool
+}
+
+func (writer *cookieWriter) WriteHeader(status int) {
+	if !writer.wroteHeader {
+		writer.wroteHeader = true
+		writer.harden()
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+func (writer *cookieWriter) Write(content []byte) (int, error) {
+	if !writer.wroteHeader {
+		writer.WriteHeader(http.StatusOK)
+	}
+	return writer.ResponseWriter.Write(content)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (writer *cookieWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+func (writer *cookieWriter) harden() {
+	header := writer.Header()
+	values := header.Values("Set-Cookie")
+	if len(values) == 0 {
+		return
+	}
+
+	header.Del("Set-Cookie")
+	for _, value := range values {
+		cookie, err := http.ParseSetCookie(value)
+		if err != nil {
+			// drop cookies that cannot be hardened rather than sending them as they are
+			continue
+		}
+		cookie.HttpOnly = true
+		if cookie.SameSite == http.SameSiteDefaultMode || cookie.SameSite == http.SameSiteNoneMode {
+			cookie.SameSite = http.SameSiteLaxMode
+		}
+		if writer.secure {
+			cookie.Secure = true
+		}
+		header.Add("Set-Cookie", cookie.String())
+	}
+}
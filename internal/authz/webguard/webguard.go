@@ -0,0 +1,109 @@
+// Package webguard hardens the browser facing parts of the service, such as the embedded
+// console, so they can be exposed behind a reverse proxy that authenticates users with cookies.
+// None of the protections need server side sessions.
+package webguard
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CrossOrigin rejects state changing requests sent by browsers from other origins,
+// which would otherwise be authenticated by the cookies of the user.
+// Browsers are recognized by the Sec-Fetch-Site and Origin headers they send.
+// Requests carrying neither, such as those of command line tools, are not cross-site
+// requests and are let through.
+type CrossOrigin struct {
+	trusted       []string
+	forwardedHost bool
+	logger        *slog.Logger
+}
+
+// Option configures optional CrossOrigin settings.
+type Option func(*CrossOrigin)
+
+// WithTrustedOrigins allows requests from the given origins, such as "https://admin.example.org".
+func WithTrustedOrigins(origins ...string) Option {
+	return func(protection *CrossOrigin) {
+		for _, origin := range origins {
+			protection.trusted = append(protection.trusted, strings.TrimSuffix(strings.ToLower(origin), "/"))
+		}
+	}
+}
+
+// WithForwardedHost compares the origin of requests with the X-Forwarded-Host header set by the
+// reverse proxy instead of the Host header. It must only be enabled behind such a proxy.
+func WithForwardedHost() Option {
+	return func(protection *CrossOrigin) {
+		protection.forwardedHost = true
+	}
+}
+
+// NewCrossOrigin creates a new CrossOrigin protection logging rejected requests to the given logger.
+func NewCrossOrigin(logger *slog.Logger, options ...Option) *CrossOrigin {
+	protection := &CrossOrigin{logger: logger}
+	for _, option := range options {
+		option(protection)
+	}
+	return protection
+}
+
+// Check returns an error when the request is a state changing cross-origin request.
+func (protection *CrossOrigin) Check(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	origin := strings.ToLower(r.Header.Get("Origin"))
+	if slices.Contains(protection.trusted, origin) {
+		return nil
+	}
+
+	switch site := r.Header.Get("Sec-Fetch-Site"); site {
+	case "":
+	case "same-origin", "none":
+		return nil
+	default:
+		return fmt.Errorf("cross-origin request from a %s context", site)
+	}
+
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("malformed origin %q", origin)
+	}
+	if !strings.EqualFold(parsed.Host, protection.host(r)) {
+		return fmt.Errorf("cross-origin request from %s", origin)
+	}
+	return nil
+}
+
+func (protection *CrossOrigin) host(r *http.Request) string {
+	if protection.forwardedHost {
+		if host, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Host"), ","); host != "" {
+			return strings.TrimSpace(host)
+		}
+	}
+	return r.Host
+}
+
+// Handler wraps the handler so cross-origin state changing requests are refused with 403.
+func (protection *CrossOrigin) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := protection.Check(r); err != nil {
+			protection.logger.Warn("cross-origin request refused", "error", err, "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"cross-origin request refused"}` + "\n"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package webguard
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveCrossOrigin(protection *CrossOrigin, method string, headers map[string]string) int {
+	request := httptest.NewRequest(method, "http://console.example.org/api/groups/1/users", nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	protection.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func newCrossOrigin(options ...Option) *CrossOrigin {
+	return NewCrossOrigin(slog.New(slog.NewTextHandler(io.Discard, nil)), options...)
+}
+
+// TestCrossOrigin sends requests with the headers of various browser contexts, checking only cross-origin changes are refused.
+func TestCrossOrigin(t *testing.T) {
+	tests := map[string]struct {
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		"safe method":        {http.MethodGet, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusOK},
+		"same origin fetch":  {http.MethodPut, map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+		"user navigation":    {http.MethodPost, map[string]string{"Sec-Fetch-Site": "none"}, http.StatusOK},
+		"cross-site fetch":   {http.MethodPut, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		"same-site fetch":    {http.MethodPut, map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		"matching origin":    {http.MethodPut, map[string]string{"Origin": "http://console.example.org"}, http.StatusOK},
+		"foreign origin":     {http.MethodPut, map[string]string{"Origin": "https://evil.example.org"}, http.StatusForbidden},
+		"non-browser client": {http.MethodDelete, nil, http.StatusOK},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.status, serveCrossOrigin(newCrossOrigin(), test.method, test.headers))
+		})
+	}
+}
+
+// TestCrossOrigin_TrustedOrigins sends a cross-site request from a trusted origin, checking it is let through.
+func TestCrossOrigin_TrustedOrigins(t *testing.T) {
+	protection := newCrossOrigin(WithTrustedOrigins("https://Admin.example.org/"))
+	headers := map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://admin.example.org"}
+
+	assert.Equal(t, http.StatusOK, serveCrossOrigin(protection, http.MethodPut, headers))
+}
+
+// TestCrossOrigin_ForwardedHost sends requests through a reverse proxy, checking the forwarded host is compared only when enabled.
+func TestCrossOrigin_ForwardedHost(t *testing.T) {
+	headers := map[string]string{"Origin": "https://authz.example.org", "X-Forwarded-Host": "authz.example.org"}
+
+	assert.Equal(t, http.StatusForbidden, serveCrossOrigin(newCrossOrigin(), http.MethodPut, headers))
+	assert.Equal(t, http.StatusOK, serveCrossOrigin(newCrossOrigin(WithForwardedHost()), http.MethodPut, headers))
+}
+
+// TestSecureHeaders serves a page over HTTPS, checking the protective headers are set.
+func TestSecureHeaders(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(recorder, request)
+
+	assert.Equal(t, ContentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	assert.NotEmpty(t, recorder.Header().Get("Strict-Transport-Security"))
+}
+
+// TestSecureCookies sets cookies over HTTPS and plain HTTP, checking their attributes are hardened.
+func TestSecureCookies(t *testing.T) {
+	handler := SecureCookies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", SameSite: http.SameSiteNoneMode})
+		http.SetCookie(w, &http.Cookie{Name: "preference", Value: "dark", SameSite: http.SameSiteStrictMode})
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-Forwarded-Proto", "https")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	cookies := recorder.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[1].SameSite)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, recorder.Result().Cookies()[0].Secure)
+	assert.True(t, recorder.Result().Cookies()[0].HttpOnly)
+}
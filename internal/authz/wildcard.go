@@ -0,0 +1,65 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission names form a hierarchy of dot separated segments, such as "recipes.drafts.publish".
+// A wildcard permission ends with the Wildcard segment and grants every permission below it:
+// "recipes.*" grants "recipes.read" and "recipes.drafts.publish", but neither "recipes" nor
+// "recipesbook.read". The Wildcard alone grants every permission. Wildcards are granted, implied
+// and evaluated like any other permission; only the checks match them against the checked names.
+const Wildcard = "*"
+
+// IsWildcard reports whether the permission name is a wildcard.
+func IsWildcard(name string) bool {
+	return name == Wildcard || strings.HasSuffix(name, "."+Wildcard)
+}
+
+// ValidateWildcard checks the wildcard of the permission name, if any, is its whole last segment.
+func ValidateWildcard(name string) error {
+	if !strings.Contains(name, Wildcard) || (IsWildcard(name) && strings.Count(name, Wildcard) == 1) {
+		return nil
+	}
+	return fmt.Errorf("permission %q: a wildcard must be the whole last segment of the name, such as recipes.*", name)
+}
+
+// MatchPermission reports whether holding the granted permission grants the checked one: the names
+// are the same, or the granted permission is a wildcard above the checked one in the hierarchy.
+func MatchPermission(granted string, checked string) bool {
+	if granted == checked {
+		return true
+	}
+	if !IsWildcard(granted) {
+		return false
+	}
+	prefix := strings.TrimSuffix(granted, Wildcard)
+	return len(checked) > len(prefix) && strings.HasPrefix(checked, prefix)
+}
+
+// GrantingPermission returns the held permission granting the checked one, or false when none does.
+// When several do the most specific takes precedence: the permission itself, then the wildcard
+// closest to it in the hierarchy, so "recipes.drafts.*" is reported over "recipes.*".
+func GrantingPermission(held []string, checked string) (string, bool) {
+	granting, found := "", false
+	for _, permission := range held {
+		if permission == checked {
+			return permission, true
+		}
+		if MatchPermission(permission, checked) && (!found || len(permission) > len(granting)) {
+			granting, found = permission, true
+		}
+	}
+	return granting, found
+}
+
+// defines reports whether the policy defines the permission, or a wildcard above it in the hierarchy.
+func (policy *Policy) defines(name string) bool {
+	for _, permission := range policy.Permissions {
+		if MatchPermission(permission.Name, name) {
+			return true
+		}
+	}
+	return false
+}
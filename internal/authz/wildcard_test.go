@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wildcardPolicy grants recipes.* to the cooks, recipes.drafts.* to the editors and * to the admins.
+func wildcardPolicy() *Policy {
+	return NewPolicy(
+		[]Permission{
+			*NewPermission("recipes.*", []string{"cooks"}),
+			*NewPermission("recipes.drafts.*", []string{"editors"}),
+			*NewPermission("recipes.read", []string{"readers"}),
+			*NewPermission("*", []string{"admins"}),
+			{Name: "billing.manage", Groups: []string{"accountants"}, Implies: []string{"billing.*"}},
+			*NewPermission("billing.*", nil),
+		},
+		[]Group{
+			*NewGroup("cooks", []string{"alice"}),
+			*NewGroup("editors", []string{"alice", "bob"}),
+			*NewGroup("readers", []string{"carol"}),
+			*NewGroup("admins", []string{"root"}),
+			*NewGroup("accountants", []string{"dave"}),
+		},
+	)
+}
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		granted, checked string
+		expected         bool
+	}{
+		{"recipes.read", "recipes.read", true},
+		{"recipes.*", "recipes.read", true},
+		{"recipes.*", "recipes.drafts.publish", true},
+		{"recipes.*", "recipes.*", true},
+		{"recipes.*", "recipes", false},
+		{"recipes.*", "recipesbook.read", false},
+		{"recipes.drafts.*", "recipes.read", false},
+		{"*", "recipes.read", true},
+		{"*", "*", true},
+		{"recipes.read", "recipes.*", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, MatchPermission(test.granted, test.checked), "%s grants %s", test.granted, test.checked)
+	}
+}
+
+// TestGrantingPermission checks the permission itself takes precedence, then the most specific wildcard.
+func TestGrantingPermission(t *testing.T) {
+	held := []string{"*", "recipes.*", "recipes.drafts.*", "recipes.drafts.publish"}
+
+	granting, ok := GrantingPermission(held, "recipes.drafts.publish")
+	assert.True(t, ok)
+	assert.Equal(t, "recipes.drafts.publish", granting)
+
+	granting, ok = GrantingPermission(held, "recipes.drafts.delete")
+	assert.True(t, ok)
+	assert.Equal(t, "recipes.drafts.*", granting)
+
+	granting, ok = GrantingPermission(held, "billing.read")
+	assert.True(t, ok)
+	assert.Equal(t, "*", granting)
+
+	_, ok = GrantingPermission(held[1:], "billing.read")
+	assert.False(t, ok)
+}
+
+func TestValidateWildcard(t *testing.T) {
+	for _, name := range []string{"recipes.read", "recipes.*", "*"} {
+		assert.NoError(t, ValidateWildcard(name), name)
+	}
+	for _, name := range []string{"recipes.*.read", "recipes.re*", "*.read", "recipes.**"} {
+		assert.Error(t, ValidateWildcard(name), name)
+	}
+}
+
+// TestPolicy_Check_Wildcard checks permissions granted through wildcards, checking the wildcard granting them is reported.
+func TestPolicy_Check_Wildcard(t *testing.T) {
+	policy := wildcardPolicy()
+
+	tests := []struct {
+		name       string
+		user       string
+		permission string
+		expected   CheckResult
+	}{
+		{"granted by name", "carol", "recipes.read", CheckResult{User: "carol", Permission: "recipes.read", Allowed: true, Mode: ModeDefaultDeny}},
+		{"granted by a wildcard", "alice", "recipes.write", CheckResult{User: "alice", Permission: "recipes.write", Allowed: true, Mode: ModeDefaultDeny, GrantedBy: "recipes.*"}},
+		{"most specific wildcard", "alice", "recipes.drafts.publish",
+			CheckResult{User: "alice", Permission: "recipes.drafts.publish", Allowed: true, Mode: ModeDefaultDeny, GrantedBy: "recipes.drafts.*"}},
+		{"granted by the root wildcard", "root", "billing.read", CheckResult{User: "root", Permission: "billing.read", Allowed: true, Mode: ModeDefaultDeny, GrantedBy: "*"}},
+		{"wildcard implied", "dave", "billing.read", CheckResult{User: "dave", Permission: "billing.read", Allowed: true, Mode: ModeDefaultDeny, GrantedBy: "billing.*"}},
+		{"outside the wildcard", "bob", "recipes.write", CheckResult{User: "bob", Permission: "recipes.write", Reason: DenialNoMatchingGroup, Mode: ModeDefaultDeny}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := policy.Check(test.user, test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, &test.expected, result)
+
+			allowed, err := policy.HasPermission(test.user, test.permission)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected.Allowed, allowed, "HasPermission")
+
+			session, err := policy.Session(test.user)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected.Allowed, session.HasPermission(test.permission), "Session.HasPermission")
+		})
+	}
+}
+
+// TestCompile_Wildcard compiles policies with wildcards, checking checks match them and misplaced wildcards are refused.
+func TestCompile_Wildcard(t *testing.T) {
+	compiled, err := Compile(wildcardPolicy())
+	assert.NoError(t, err)
+	assert.True(t, compiled.HasPermission("alice", "recipes.write"))
+	assert.False(t, compiled.HasPermission("bob", "recipes.write"))
+
+	_, err = Compile(NewPolicy([]Permission{*NewPermission("recipes.*.read", nil)}, nil))
+	assert.ErrorContains(t, err, "a wildcard must be the whole last segment")
+}
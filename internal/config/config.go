@@ -0,0 +1,146 @@
+// Package config loads the settings of the authz binary from an optional YAML file and from
+// environment variables, the variables overriding the file and the file overriding the defaults.
+// Command line flags are applied last by the subcommands, which use the loaded settings as their defaults.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/salmarsumi/recipes/internal/authz/tenant"
+	"gopkg.in/yaml.v3"
+)
+
+// The environment variables read by Load.
+const (
+	// FileEnv names the YAML file to read the settings from, no file is read when it is unset.
+	FileEnv        = "AUTHZ_CONFIG_FILE"
+	DatabaseURLEnv = "AUTHZ_DATABASE_URL"
+	MaxConnsEnv    = "AUTHZ_DB_MAX_CONNS"
+	MinConnsEnv    = "AUTHZ_DB_MIN_CONNS"
+	LogLevelEnv    = "AUTHZ_LOG_LEVEL"
+	ListenAddrEnv  = "AUTHZ_LISTEN_ADDR"
+	TenantEnv      = "AUTHZ_TENANT"
+)
+
+// Config holds the settings shared by the subcommands of the authz binary.
+type Config struct {
+	// The PostgreSQL connection string, empty when no store is configured.
+	DatabaseURL string `yaml:"database_url"`
+	// The maximum and minimum number of connections of the database pool.
+	MaxConns int32 `yaml:"max_conns"`
+	MinConns int32 `yaml:"min_conns"`
+	// The default log level, such as debug, info, warn or error.
+	LogLevel string `yaml:"log_level"`
+	// The address the serve subcommand listens on.
+	ListenAddr string `yaml:"listen_addr"`
+	// The tenant whose schema of the database the subcommands use, the default tenant when empty.
+	// A serve process serves this tenant only, so every tenant runs processes of its own.
+	Tenant string `yaml:"tenant"`
+}
+
+// Default returns the settings used when neither the file nor the environment set them.
+func Default() Config {
+	return Config{MaxConns: 10, MinConns: 0, LogLevel: "info", ListenAddr: ":8080"}
+}
+
+// Load returns the default settings overridden by the YAML file at the given path, unless the path
+// is empty, and then by the environment variables found by lookupEnv, usually os.LookupEnv.
+// The merged settings are validated.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := Default()
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if err := config.decode(file); err != nil {
+			return nil, fmt.Errorf("decode config file %s: %w", path, err)
+		}
+	}
+
+	if err := config.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks the pool sizes, the log level, the listen address and the tenant.
+func (config *Config) Validate() error {
+	if config.MaxConns < 1 {
+		return errors.New("max_conns must be at least 1")
+	}
+	if config.MinConns < 0 || config.MinConns > config.MaxConns {
+		return fmt.Errorf("min_conns must be between 0 and max_conns (%d)", config.MaxConns)
+	}
+	if _, err := config.Level(); err != nil {
+		return err
+	}
+	if _, _, err := net.SplitHostPort(config.ListenAddr); err != nil {
+		return fmt.Errorf("invalid listen_addr %q: %w", config.ListenAddr, err)
+	}
+	if _, err := tenant.Parse(config.Tenant); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Level returns the parsed log level.
+func (config *Config) Level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log_level %q", config.LogLevel)
+	}
+	return level, nil
+}
+
+// decode overrides the settings with those of a YAML document. Unknown settings are refused,
+// so a misspelled one is not silently ignored.
+func (config *Config) decode(r io.Reader) error {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides the settings with the environment variables that are set.
+func (config *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	if value, ok := lookupEnv(DatabaseURLEnv); ok {
+		config.DatabaseURL = value
+	}
+	if value, ok := lookupEnv(LogLevelEnv); ok {
+		config.LogLevel = value
+	}
+	if value, ok := lookupEnv(ListenAddrEnv); ok {
+		config.ListenAddr = value
+	}
+	if value, ok := lookupEnv(TenantEnv); ok {
+		config.Tenant = value
+	}
+
+	for name, conns := range map[string]*int32{MaxConnsEnv: &config.MaxConns, MinConnsEnv: &config.MinConns} {
+		value, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: expected a number", name, value)
+		}
+		*conns = int32(parsed)
+	}
+	return nil
+}
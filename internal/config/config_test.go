@@ -0,0 +1,90 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "authz.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, err := Load("", env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), *config)
+	level, err := config.Level()
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, level)
+}
+
+func TestLoad_FileAndEnvironment(t *testing.T) {
+	path := writeFile(t, "database_url: postgres://file/authz\nmax_conns: 20\nmin_conns: 2\nlog_level: debug\n")
+
+	config, err := Load(path, env(map[string]string{
+		DatabaseURLEnv: "postgres://env/authz",
+		MinConnsEnv:    " 5 ",
+		ListenAddrEnv:  "127.0.0.1:9090",
+		TenantEnv:      "recipes",
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Config{DatabaseURL: "postgres://env/authz", MaxConns: 20, MinConns: 5, LogLevel: "debug", ListenAddr: "127.0.0.1:9090", Tenant: "recipes"}, *config)
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	config, err := Load(writeFile(t, ""), env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), *config)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		err  string
+	}{
+		{name: "unknown setting", file: "database: postgres://file/authz\n", err: "field database not found"},
+		{name: "invalid number", env: map[string]string{MaxConnsEnv: "many"}, err: `invalid AUTHZ_DB_MAX_CONNS "many"`},
+		{name: "no connections", env: map[string]string{MaxConnsEnv: "0"}, err: "max_conns must be at least 1"},
+		{name: "too many idle connections", file: "max_conns: 2\nmin_conns: 3\n", err: "min_conns must be between 0 and max_conns (2)"},
+		{name: "invalid log level", env: map[string]string{LogLevelEnv: "loud"}, err: `invalid log_level "loud"`},
+		{name: "invalid listen address", file: "listen_addr: localhost\n", err: `invalid listen_addr "localhost"`},
+		{name: "invalid tenant", env: map[string]string{TenantEnv: "Recipes"}, err: `invalid tenant id "Recipes"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := ""
+			if test.file != "" {
+				path = writeFile(t, test.file)
+			}
+
+			_, err := Load(path, env(test.env))
+
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"), env(nil))
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
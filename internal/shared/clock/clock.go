@@ -0,0 +1,54 @@
+// Package clock abstracts the passing of time, so expirations, schedules, timestamps and
+// caches can be tested with a clock the test moves forward instead of sleeping.
+package clock
+
+import "time"
+
+// Clock tells the time and waits for it to pass.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel receiving the time once the delay has passed.
+	After(delay time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker receiving the time every interval, which must be positive.
+	NewTicker(interval time.Duration) Ticker
+}
+
+// Ticker delivers the time at intervals, dropping ticks for slow receivers like time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are delivered on.
+	C() <-chan time.Time
+	// Stop turns off the ticker. No more ticks are delivered after it returns.
+	Stop()
+}
+
+// System returns the Clock reading the system time.
+func System() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(delay time.Duration) <-chan time.Time {
+	return time.After(delay)
+}
+
+func (systemClock) NewTicker(interval time.Duration) Ticker {
+	return systemTicker{time.NewTicker(interval)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (ticker systemTicker) C() <-chan time.Time {
+	return ticker.ticker.C
+}
+
+func (ticker systemTicker) Stop() {
+	ticker.ticker.Stop()
+}
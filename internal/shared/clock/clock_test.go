@@ -0,0 +1,85 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/salmarsumi/recipes/internal/shared/testing"
+)
+
+var start = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func received(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case tick := <-c:
+		return tick, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// TestSystem reads the system clock, checking it follows the system time.
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := clock.System().Now()
+	assert.False(t, now.Before(before))
+
+	ticker := clock.System().NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	assert.False(t, (<-ticker.C()).Before(before))
+}
+
+// TestFakeClock_After waits on a fake clock, checking the timer fires once its delay has passed.
+func TestFakeClock_After(t *testing.T) {
+	fake := NewFakeClock(start)
+	after := fake.After(time.Minute)
+	assert.Equal(t, 1, fake.Waiters())
+
+	fake.Advance(59 * time.Second)
+	_, ok := received(after)
+	assert.False(t, ok)
+
+	fake.Advance(time.Second)
+	tick, ok := received(after)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), tick)
+	assert.Equal(t, start.Add(time.Minute), fake.Now())
+	assert.Equal(t, 0, fake.Waiters())
+
+	_, ok = received(fake.After(0))
+	assert.True(t, ok)
+}
+
+// TestFakeClock_NewTicker ticks a fake clock, checking ticks are due every interval and dropped for slow receivers.
+func TestFakeClock_NewTicker(t *testing.T) {
+	fake := NewFakeClock(start)
+	ticker := fake.NewTicker(time.Minute)
+
+	fake.Advance(time.Minute)
+	tick, ok := received(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), tick)
+
+	// three intervals pass before the receiver reads, delivering a single tick
+	fake.Advance(3 * time.Minute)
+	_, ok = received(ticker.C())
+	assert.True(t, ok)
+	_, ok = received(ticker.C())
+	assert.False(t, ok)
+
+	fake.Advance(30 * time.Second)
+	_, ok = received(ticker.C())
+	assert.False(t, ok)
+	fake.Advance(30 * time.Second)
+	_, ok = received(ticker.C())
+	assert.True(t, ok)
+
+	ticker.Stop()
+	assert.Equal(t, 0, fake.Waiters())
+	fake.Advance(time.Hour)
+	_, ok = received(ticker.C())
+	assert.False(t, ok)
+}
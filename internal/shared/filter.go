@@ -22,3 +22,32 @@ func Filter[T any, TResult any](input []T, predicate func(T) bool, selector func
 	}
 	return result
 }
+
+// FilterErr is the variant of Filter for predicates that can fail. It stops at the first
+// predicate error and returns it, rather than treating the failed element as kept or dropped.
+//
+// T represents any type.
+//
+// Parameters:
+//   - input: A slice of elements of type T to be filtered.
+//   - predicate: A function that takes an element of type T and returns a boolean and an error.
+//     The element is included in the result if the predicate returns true.
+//   - selector: A function that takes an element of type T and returns a result of type TResult.
+//     It is used to transform each element of the input slice that satisfies the predicate.
+//
+// Returns:
+//
+//	A slice of elements of type TResult that satisfy the predicate function,
+//	or nil and the first error returned by the predicate.
+func FilterErr[T any, TResult any](input []T, predicate func(T) (bool, error), selector func(T) TResult) (result []TResult, err error) {
+	for _, value := range input {
+		keep, err := predicate(value)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			result = append(result, selector(value))
+		}
+	}
+	return result, nil
+}
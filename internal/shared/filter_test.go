@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +68,33 @@ func TestFilter_Slice(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterErr(t *testing.T) {
+	errNegative := errors.New("negative")
+	predicate := func(n int) (bool, error) {
+		if n < 0 {
+			return false, errNegative
+		}
+		return n%2 == 0, nil
+	}
+	selector := func(n int) int {
+		return n * 10
+	}
+
+	t.Run("success", func(t *testing.T) {
+		result, err := FilterErr([]int{1, 2, 3, 4}, predicate, selector)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{20, 40}, result)
+	})
+
+	t.Run("predicate error", func(t *testing.T) {
+		evaluated := 0
+		result, err := FilterErr([]int{2, -1, 4}, func(n int) (bool, error) {
+			evaluated++
+			return predicate(n)
+		}, selector)
+		assert.ErrorIs(t, err, errNegative)
+		assert.Nil(t, result)
+		assert.Equal(t, 2, evaluated)
+	})
+}
@@ -0,0 +1,31 @@
+// Package id generates the identifiers of records such as audit events and job runs.
+//
+// Identifiers are UUIDv7 strings: they start with the millisecond they were generated at,
+// so sorting them sorts the records by time, and records can be paginated by id and
+// correlated with logs of the same period without an index on a separate timestamp.
+package id
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// New returns a new UUIDv7 identifier. Identifiers generated by the process increase
+// strictly, even within the same millisecond.
+func New() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// Time returns the time an identifier returned by New was generated at, to the millisecond.
+func Time(id string) (time.Time, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if parsed.Version() != 7 {
+		return time.Time{}, errors.New("not a UUIDv7 identifier")
+	}
+	return time.Unix(parsed.Time().UnixTime()).UTC(), nil
+}
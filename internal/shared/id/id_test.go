@@ -0,0 +1,34 @@
+package id
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNew generates identifiers in a row, checking they sort in the order they were generated.
+func TestNew(t *testing.T) {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = New()
+	}
+
+	assert.True(t, slices.IsSorted(ids))
+	assert.Len(t, slices.Compact(slices.Clone(ids)), len(ids))
+}
+
+// TestTime reads the time of generated and foreign identifiers, checking only UUIDv7 ones have a time.
+func TestTime(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	generated, err := Time(New())
+	assert.NoError(t, err)
+	assert.False(t, generated.Before(before))
+	assert.False(t, generated.After(time.Now()))
+
+	_, err = Time("6ba7b810-9dad-41d1-80b4-00c04fd430c8")
+	assert.EqualError(t, err, "not a UUIDv7 identifier")
+	_, err = Time("audit-1")
+	assert.Error(t, err)
+}
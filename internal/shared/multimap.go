@@ -0,0 +1,32 @@
+package shared
+
+// MultiMap maps keys of type K to a list of values of type V, keeping the values of
+// a key in the order they were added. The zero value is a nil map: it can be read but
+// Add panics, use NewMultiMap to create a MultiMap.
+type MultiMap[K comparable, V any] map[K][]V
+
+// NewMultiMap creates a new empty MultiMap sized for the given number of keys.
+func NewMultiMap[K comparable, V any](size int) MultiMap[K, V] {
+	return make(MultiMap[K, V], size)
+}
+
+// Add appends the values to the list of the key. Adding no values still records the key.
+func (multimap MultiMap[K, V]) Add(key K, values ...V) {
+	multimap[key] = append(multimap[key], values...)
+}
+
+// Get returns the values of the key, nil if the key is unknown.
+func (multimap MultiMap[K, V]) Get(key K) []V {
+	return multimap[key]
+}
+
+// Has reports whether the key is in the map, even with no values.
+func (multimap MultiMap[K, V]) Has(key K) bool {
+	_, ok := multimap[key]
+	return ok
+}
+
+// Len returns the number of keys in the map.
+func (multimap MultiMap[K, V]) Len() int {
+	return len(multimap)
+}
@@ -0,0 +1,21 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiMap(t *testing.T) {
+	multimap := NewMultiMap[string, string](2)
+	multimap.Add("readers", "alice")
+	multimap.Add("readers", "bob", "carol")
+	multimap.Add("writers")
+
+	assert.Equal(t, []string{"alice", "bob", "carol"}, multimap.Get("readers"))
+	assert.Nil(t, multimap.Get("writers"))
+	assert.True(t, multimap.Has("writers"))
+	assert.False(t, multimap.Has("admins"))
+	assert.Nil(t, multimap.Get("admins"))
+	assert.Equal(t, 2, multimap.Len())
+}
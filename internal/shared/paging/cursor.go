@@ -0,0 +1,124 @@
+package paging
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is the sort key of a listed item: values compared in order, each a string, an int or a time.
+// The last value should be unique, such as an id, so no two items share a key and a page
+// resuming after a key neither skips nor repeats items.
+type Key []any
+
+// Compare compares the keys value by value, returning -1, 0 or +1. Values of different types,
+// which only appear in a key decoded from a cursor of another list, compare by type.
+func (key Key) Compare(other Key) int {
+	for i := range min(len(key), len(other)) {
+		if c := compareValues(key[i], other[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(key), len(other))
+}
+
+func compareValues(a any, b any) int {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+	return cmp.Compare(typeRank(a), typeRank(b))
+}
+
+func typeRank(value any) int {
+	switch value.(type) {
+	case string:
+		return 1
+	case int:
+		return 2
+	case time.Time:
+		return 3
+	}
+	return 0
+}
+
+// matches reports whether the keys have the same number and types of values.
+func (key Key) matches(other Key) bool {
+	if len(key) != len(other) {
+		return false
+	}
+	for i := range key {
+		if typeRank(key[i]) != typeRank(other[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode returns the opaque cursor resuming a list after the item with the given key.
+// It panics on a value that is not a string, an int or a time, which is a programming error.
+func Encode(key Key) string {
+	values := make([]string, len(key))
+	for i, value := range key {
+		switch value := value.(type) {
+		case string:
+			values[i] = "s" + value
+		case int:
+			values[i] = "i" + strconv.Itoa(value)
+		case time.Time:
+			values[i] = "t" + value.UTC().Format(time.RFC3339Nano)
+		default:
+			panic(fmt.Sprintf("paging: unsupported key value %T", value))
+		}
+	}
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode returns the key a cursor returned by Encode resumes after.
+func Decode(cursor string) (Key, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	key := make(Key, len(values))
+	for i, value := range values {
+		if value == "" {
+			return nil, ErrInvalidCursor
+		}
+		switch value[0] {
+		case 's':
+			key[i] = value[1:]
+		case 'i':
+			if key[i], err = strconv.Atoi(value[1:]); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		case 't':
+			if key[i], err = time.Parse(time.RFC3339Nano, value[1:]); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,114 @@
+// Package paging splits the lists returned by the API and the stores into pages.
+//
+// A page is resumed with an opaque cursor encoding the sort key of the last item returned,
+// rather than an offset, so items added or removed between requests do not shift the pages.
+package paging
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+var (
+	// ErrInvalidLimit is returned for a limit that is not a positive number.
+	ErrInvalidLimit = errors.New("invalid limit")
+	// ErrInvalidCursor is returned for a cursor that was not returned by the same list.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Limits bounds the size of the pages of a list.
+type Limits struct {
+	// The size of a page when the client does not ask for one. Zero returns the whole list
+	// unless a limit or a cursor is given, for lists that were not paginated before.
+	Default int
+	// The largest page a client may ask for. Larger limits are lowered to it.
+	Max int
+}
+
+// Request is a page asked for by a client.
+type Request struct {
+	// The most items to return, zero for the whole list.
+	Limit int
+	// The key of the last item of the previous page, nil for the first page.
+	After Key
+}
+
+// Parse reads the limit and cursor query parameters of a list request, such as
+// ?limit=50&cursor=WyJpMTIiXQ, enforcing the limits.
+func Parse(query url.Values, limits Limits) (Request, error) {
+	request := Request{Limit: limits.Default}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if request.Limit, err = strconv.Atoi(limit); err != nil || request.Limit <= 0 {
+			return Request{}, ErrInvalidLimit
+		}
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		var err error
+		if request.After, err = Decode(cursor); err != nil {
+			return Request{}, err
+		}
+	}
+
+	if request.Limit == 0 && request.After != nil {
+		request.Limit = limits.Max
+	}
+	if limits.Max > 0 && request.Limit > limits.Max {
+		request.Limit = limits.Max
+	}
+	return request, nil
+}
+
+// Sort sorts the items by the keys returned by key, keeping the order of items with equal keys.
+func Sort[T any](items []T, key func(T) Key) {
+	slices.SortStableFunc(items, func(a, b T) int { return key(a).Compare(key(b)) })
+}
+
+// Page returns the page of the items asked for and the cursor of the next page, empty for the
+// last page. The items are sorted by key first; the slice passed in is left untouched.
+// A request for the whole list returns the items as they are.
+func Page[T any](items []T, request Request, key func(T) Key) ([]T, string, error) {
+	if request.Limit <= 0 && request.After == nil {
+		return items, "", nil
+	}
+	items = slices.Clone(items)
+	Sort(items, key)
+
+	if request.After != nil {
+		if len(items) > 0 && !key(items[0]).matches(request.After) {
+			return nil, "", ErrInvalidCursor
+		}
+		start, _ := slices.BinarySearchFunc(items, request.After, func(item T, after Key) int {
+			if key(item).Compare(after) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		items = items[start:]
+	}
+	page, next := Trim(items, request.Limit, key)
+	return page, next, nil
+}
+
+// Trim cuts items fetched by a store query to the limit and returns the cursor of the next page.
+// Queries fetch one item more than the limit, so there is a next page exactly when that item
+// was found. A zero limit keeps every item.
+func Trim[T any](items []T, limit int, key func(T) Key) ([]T, string) {
+	if limit <= 0 || len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, Encode(key(items[limit-1]))
+}
+
+// NextLink returns the Link header value pointing to the next page of the list requested
+// at the given URL, such as </api/groups?cursor=WyJpMTIiXQ&limit=50>; rel="next".
+func NextLink(requested *url.URL, cursor string) string {
+	next := *requested
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+	return "<" + next.RequestURI() + `>; rel="next"`
+}
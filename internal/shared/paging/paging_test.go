@@ -0,0 +1,120 @@
+package paging
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	name string
+	id   int
+}
+
+func itemKey(item item) Key {
+	return Key{item.name, item.id}
+}
+
+// TestEncode_Decode round-trips keys through cursors, checking every value type keeps its type.
+func TestEncode_Decode(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	key := Key{"cooks", 42, at}
+
+	decoded, err := Decode(Encode(key))
+	assert.NoError(t, err)
+	assert.Equal(t, key, decoded)
+
+	for _, cursor := range []string{"", "not base64!", "W10", "WyJ4MSJd", "WyJpYWJjIl0", "WyIiXQ"} {
+		_, err := Decode(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+	assert.Panics(t, func() { Encode(Key{1.5}) })
+}
+
+// TestKey_Compare compares keys, checking values are compared in order.
+func TestKey_Compare(t *testing.T) {
+	assert.Equal(t, -1, Key{"a", 2}.Compare(Key{"b", 1}))
+	assert.Equal(t, 1, Key{"a", 2}.Compare(Key{"a", 1}))
+	assert.Equal(t, 0, Key{"a", 2}.Compare(Key{"a", 2}))
+	assert.Equal(t, -1, Key{"a"}.Compare(Key{"a", 1}))
+	assert.Equal(t, -1, Key{time.Unix(1, 0)}.Compare(Key{time.Unix(2, 0)}))
+}
+
+// TestParse parses list requests, checking the limits are enforced.
+func TestParse(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+	parse := func(query string, limits Limits) (Request, error) {
+		values, err := url.ParseQuery(query)
+		assert.NoError(t, err)
+		return Parse(values, limits)
+	}
+
+	request, err := parse("", limits)
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Limit: 20}, request)
+
+	request, err = parse("limit=500&cursor="+Encode(Key{"cooks", 3}), limits)
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Limit: 100, After: Key{"cooks", 3}}, request)
+
+	// lists returned whole by default still page with the maximum once a cursor is given
+	request, err = parse("", Limits{Max: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, Request{}, request)
+	request, err = parse("cursor="+Encode(Key{3}), Limits{Max: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Limit: 100, After: Key{3}}, request)
+
+	for _, query := range []string{"limit=0", "limit=-5", "limit=ten"} {
+		_, err = parse(query, limits)
+		assert.ErrorIs(t, err, ErrInvalidLimit, query)
+	}
+	_, err = parse("cursor=nope", limits)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestPage pages through items, checking every item is returned once in key order.
+func TestPage(t *testing.T) {
+	items := []item{{"cooks", 3}, {"admins", 1}, {"bakers", 2}, {"admins", 4}, {"dishwashers", 5}}
+
+	var listed []item
+	request := Request{Limit: 2}
+	for pages := 0; ; pages++ {
+		assert.Less(t, pages, 3)
+		page, next, err := Page(items, request, itemKey)
+		assert.NoError(t, err)
+		listed = append(listed, page...)
+		if next == "" {
+			break
+		}
+		request.After, err = Decode(next)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []item{{"admins", 1}, {"admins", 4}, {"bakers", 2}, {"cooks", 3}, {"dishwashers", 5}}, listed)
+	assert.Equal(t, item{"cooks", 3}, items[0])
+
+	// the whole list is returned as is
+	page, next, err := Page(items, Request{}, itemKey)
+	assert.NoError(t, err)
+	assert.Equal(t, items, page)
+	assert.Empty(t, next)
+
+	// a cursor resumes after its key even when that item was removed since
+	page, _, err = Page(items, Request{Limit: 10, After: Key{"bakers", 9}}, itemKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{"cooks", 3}, {"dishwashers", 5}}, page)
+
+	_, _, err = Page(items, Request{Limit: 10, After: Key{3}}, itemKey)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// TestNextLink links to the next page of a request, checking the other parameters are kept.
+func TestNextLink(t *testing.T) {
+	requested, err := url.Parse("/api/sync/reports?connector=ldap&limit=2&cursor=old")
+	assert.NoError(t, err)
+
+	assert.Equal(t, `</api/sync/reports?connector=ldap&cursor=new&limit=2>; rel="next"`, NextLink(requested, "new"))
+}
@@ -0,0 +1,68 @@
+package shared
+
+import (
+	"iter"
+)
+
+// FilterSeq is the streaming version of Filter: it filters the elements of the input sequence
+// based on a predicate function and transforms the ones kept with a selector function.
+// Nothing is evaluated until the returned sequence is ranged over, and no intermediate slice
+// is built, so large collections can be processed one element at a time.
+//
+// Parameters:
+//   - input: A sequence of elements of type T to be filtered.
+//   - predicate: A function that takes an element of type T and returns a boolean.
+//     The element is included in the result if the predicate returns true.
+//   - selector: A function that takes an element of type T and returns a result of type TResult.
+//     It is used to transform each element of the input sequence that satisfies the predicate.
+//
+// Returns:
+//
+//	A sequence of elements of type TResult that satisfy the predicate function.
+func FilterSeq[T any, TResult any](input iter.Seq[T], predicate func(T) bool, selector func(T) TResult) iter.Seq[TResult] {
+	return func(yield func(TResult) bool) {
+		for value := range input {
+			if predicate(value) && !yield(selector(value)) {
+				return
+			}
+		}
+	}
+}
+
+// MapSeq lazily transforms every element of the input sequence with the selector function.
+//
+// Parameters:
+//   - input: A sequence of elements of type T to be transformed.
+//   - selector: A function that takes an element of type T and returns a result of type TResult.
+//
+// Returns:
+//
+//	A sequence of the transformed elements, in the order of the input sequence.
+func MapSeq[T any, TResult any](input iter.Seq[T], selector func(T) TResult) iter.Seq[TResult] {
+	return func(yield func(TResult) bool) {
+		for value := range input {
+			if !yield(selector(value)) {
+				return
+			}
+		}
+	}
+}
+
+// Reduce folds the elements of the input sequence into a single value, consuming the sequence.
+//
+// Parameters:
+//   - input: A sequence of elements of type T to be folded.
+//   - initial: The value the fold starts from, returned as is for an empty sequence.
+//   - accumulator: A function that takes the value folded so far and the next element
+//     and returns the new folded value.
+//
+// Returns:
+//
+//	The value folded from every element of the input sequence.
+func Reduce[T any, TResult any](input iter.Seq[T], initial TResult, accumulator func(TResult, T) TResult) TResult {
+	result := initial
+	for value := range input {
+		result = accumulator(result, value)
+	}
+	return result
+}
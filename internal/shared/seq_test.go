@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSeq(t *testing.T) {
+	evens := FilterSeq(slices.Values([]int{1, 2, 3, 4, 5, 6}), func(n int) bool {
+		return n%2 == 0
+	}, func(n int) int {
+		return n * 10
+	})
+	assert.Equal(t, []int{20, 40, 60}, slices.Collect(evens))
+	assert.Nil(t, slices.Collect(FilterSeq(slices.Values([]int{}), func(int) bool { return true }, func(n int) int { return n })))
+}
+
+// TestFilterSeq_Lazy stops ranging early, checking the remaining elements are never evaluated.
+func TestFilterSeq_Lazy(t *testing.T) {
+	evaluated := 0
+	seq := FilterSeq(slices.Values([]int{1, 2, 3, 4, 5, 6}), func(n int) bool {
+		evaluated++
+		return n%2 == 0
+	}, func(n int) int {
+		return n
+	})
+	assert.Equal(t, 0, evaluated)
+
+	for n := range seq {
+		assert.Equal(t, 2, n)
+		break
+	}
+	assert.Equal(t, 2, evaluated)
+}
+
+func TestMapSeq(t *testing.T) {
+	lengths := MapSeq(slices.Values([]string{"a", "bb", "ccc"}), func(s string) int { return len(s) })
+	assert.Equal(t, []int{1, 2, 3}, slices.Collect(lengths))
+
+	for n := range lengths {
+		assert.Equal(t, 1, n)
+		break
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := Reduce(slices.Values([]int{1, 2, 3, 4}), 0, func(total int, n int) int { return total + n })
+	assert.Equal(t, 10, sum)
+
+	joined := Reduce(slices.Values([]string{}), "empty", func(result string, s string) string { return result + s })
+	assert.Equal(t, "empty", joined)
+
+	// the stages compose without building intermediate slices
+	total := Reduce(MapSeq(FilterSeq(slices.Values([]int{1, 2, 3, 4}), func(n int) bool { return n > 2 }, func(n int) int { return n }),
+		func(n int) int { return n * n }), 0, func(total int, n int) int { return total + n })
+	assert.Equal(t, 25, total)
+}
@@ -0,0 +1,91 @@
+package shared
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Set is an unordered collection of distinct values of type T.
+// The zero value is a nil map: it can be read but Add panics, use NewSet to create a Set.
+type Set[T comparable] map[T]struct{}
+
+// NewSet creates a new Set holding the given values.
+func NewSet[T comparable](values ...T) Set[T] {
+	set := make(Set[T], len(values))
+	set.Add(values...)
+	return set
+}
+
+// Add adds the values to the set.
+func (set Set[T]) Add(values ...T) {
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+}
+
+// Insert adds the value to the set and reports whether it was not already present,
+// which is the check-then-add step of removing duplicates while keeping order.
+func (set Set[T]) Insert(value T) bool {
+	if _, ok := set[value]; ok {
+		return false
+	}
+	set[value] = struct{}{}
+	return true
+}
+
+// Remove removes the values from the set.
+func (set Set[T]) Remove(values ...T) {
+	for _, value := range values {
+		delete(set, value)
+	}
+}
+
+// Contains reports whether the value is in the set.
+func (set Set[T]) Contains(value T) bool {
+	_, ok := set[value]
+	return ok
+}
+
+// ContainsAny reports whether any of the values is in the set.
+func (set Set[T]) ContainsAny(values ...T) bool {
+	for _, value := range values {
+		if set.Contains(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of values in the set.
+func (set Set[T]) Len() int {
+	return len(set)
+}
+
+// Values returns the values of the set in no particular order.
+func (set Set[T]) Values() []T {
+	values := make([]T, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	return values
+}
+
+// Sorted returns the values of the set in ascending order.
+func Sorted[T cmp.Ordered](set Set[T]) []T {
+	values := set.Values()
+	slices.Sort(values)
+	return values
+}
+
+// Distinct returns the values without duplicates, keeping the first occurrence of each.
+// A nil slice is returned as an empty one.
+func Distinct[T comparable](values []T) []T {
+	distinct := make([]T, 0, len(values))
+	seen := make(Set[T], len(values))
+	for _, value := range values {
+		if seen.Insert(value) {
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
@@ -0,0 +1,37 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	set := NewSet("b", "a", "b")
+	assert.Equal(t, 2, set.Len())
+	assert.True(t, set.Contains("a"))
+	assert.False(t, set.Contains("c"))
+
+	set.Add("c")
+	assert.True(t, set.Insert("d"))
+	assert.False(t, set.Insert("d"))
+	set.Remove("a", "missing")
+
+	assert.Equal(t, []string{"b", "c", "d"}, Sorted(set))
+	assert.ElementsMatch(t, []string{"b", "c", "d"}, set.Values())
+	assert.True(t, set.ContainsAny("x", "c"))
+	assert.False(t, set.ContainsAny("x", "y"))
+	assert.False(t, set.ContainsAny())
+}
+
+func TestSet_Nil(t *testing.T) {
+	var set Set[int]
+	assert.Equal(t, 0, set.Len())
+	assert.False(t, set.Contains(1))
+	assert.Empty(t, set.Values())
+}
+
+func TestDistinct(t *testing.T) {
+	assert.Equal(t, []int{3, 1, 2}, Distinct([]int{3, 1, 3, 2, 1}))
+	assert.Equal(t, []string{}, Distinct[string](nil))
+}
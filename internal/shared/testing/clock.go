@@ -0,0 +1,117 @@
+package testing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/salmarsumi/recipes/internal/shared/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when the test advances it.
+// Timers and tickers fire as the time they wait for is reached.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// fakeWaiter is a timer, or a ticker when its interval is set.
+type fakeWaiter struct {
+	clock    *FakeClock
+	c        chan time.Time
+	at       time.Time
+	interval time.Duration
+}
+
+// NewFakeClock creates a new FakeClock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (fake *FakeClock) Now() time.Time {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.now
+}
+
+// After returns a channel receiving the time once the clock was advanced by the delay.
+func (fake *FakeClock) After(delay time.Duration) <-chan time.Time {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	waiter := &fakeWaiter{clock: fake, c: make(chan time.Time, 1), at: fake.now.Add(delay)}
+	if delay <= 0 {
+		waiter.c <- fake.now
+		return waiter.c
+	}
+	fake.waiters = append(fake.waiters, waiter)
+	return waiter.c
+}
+
+// NewTicker returns a ticker receiving the time every time the clock was advanced by the interval.
+func (fake *FakeClock) NewTicker(interval time.Duration) clock.Ticker {
+	if interval <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	waiter := &fakeWaiter{clock: fake, c: make(chan time.Time, 1), at: fake.now.Add(interval), interval: interval}
+	fake.waiters = append(fake.waiters, waiter)
+	return waiter
+}
+
+// Advance moves the clock forward by the duration, firing the timers and tickers that are due.
+// A ticker due several times fires once, like a time.Ticker whose receiver is slow.
+func (fake *FakeClock) Advance(duration time.Duration) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	fake.now = fake.now.Add(duration)
+	pending := fake.waiters[:0]
+	for _, waiter := range fake.waiters {
+		if waiter.at.After(fake.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		select {
+		case waiter.c <- fake.now:
+		default:
+		}
+		if waiter.interval > 0 {
+			for !waiter.at.After(fake.now) {
+				waiter.at = waiter.at.Add(waiter.interval)
+			}
+			pending = append(pending, waiter)
+		}
+	}
+	fake.waiters = pending
+}
+
+// Waiters returns the number of timers and tickers waiting, so a test can wait for the code
+// under test to start waiting before advancing the clock.
+func (fake *FakeClock) Waiters() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.waiters)
+}
+
+func (waiter *fakeWaiter) C() <-chan time.Time {
+	return waiter.c
+}
+
+func (waiter *fakeWaiter) Stop() {
+	fake := waiter.clock
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	for i, other := range fake.waiters {
+		if other == waiter {
+			fake.waiters = append(fake.waiters[:i], fake.waiters[i+1:]...)
+			return
+		}
+	}
+}
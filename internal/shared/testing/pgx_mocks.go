@@ -8,7 +8,7 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-// MockPgDb is a mock implementation of the pgPool interface
+// MockPgDb is a mock implementation of the pgdb.DB interface
 type MockPgDb struct {
 	mock.Mock
 }
@@ -16,6 +16,10 @@ type MockPgDb struct {
 func (m *MockPgDb) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
 	return m.Called(ctx, sql, args).Get(0).(pgx.Row)
 }
+func (m *MockPgDb) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	arguments := m.Called(ctx, sql, args)
+	return arguments.Get(0).(pgx.Rows), arguments.Error(1)
+}
 func (m *MockPgDb) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
 	args := m.Called(ctx, sql, arguments)
 	return args.Get(0).(pgconn.CommandTag), args.Error(1)
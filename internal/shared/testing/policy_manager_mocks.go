@@ -0,0 +1,132 @@
+package testing
+
+import (
+	"context"
+
+	"github.com/salmarsumi/recipes/internal/authz"
+	"github.com/salmarsumi/recipes/internal/authz/store"
+	"github.com/salmarsumi/recipes/internal/shared/paging"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPolicyManager is a mock implementation of the store.PolicyManager interface
+type MockPolicyManager struct {
+	mock.Mock
+}
+
+var _ store.PolicyManager[int, int, string] = (*MockPolicyManager)(nil)
+
+func (m *MockPolicyManager) UpdateGroupPermissions(ctx context.Context, groupId int, permissions []int) error {
+	return m.Called(ctx, groupId, permissions).Error(0)
+}
+func (m *MockPolicyManager) UpdateGroupUsers(ctx context.Context, groupId int, users []string) error {
+	return m.Called(ctx, groupId, users).Error(0)
+}
+func (m *MockPolicyManager) UpdateUserGroups(ctx context.Context, userId string, groups []int) error {
+	return m.Called(ctx, userId, groups).Error(0)
+}
+func (m *MockPolicyManager) CreateGroup(ctx context.Context, groupName string) (int, error) {
+	args := m.Called(ctx, groupName)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockPolicyManager) CreatePermission(ctx context.Context, permissionName string) (int, error) {
+	args := m.Called(ctx, permissionName)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockPolicyManager) SetPermissionRisk(ctx context.Context, permissionId int, risk authz.RiskLevel) error {
+	return m.Called(ctx, permissionId, risk).Error(0)
+}
+func (m *MockPolicyManager) SetPermissionImplications(ctx context.Context, permissionId int, implied []int) error {
+	return m.Called(ctx, permissionId, implied).Error(0)
+}
+func (m *MockPolicyManager) SetPermissionDenials(ctx context.Context, permissionId int, groups []int, users []string) error {
+	return m.Called(ctx, permissionId, groups, users).Error(0)
+}
+func (m *MockPolicyManager) SetPermissionCondition(ctx context.Context, permissionId int, condition string) error {
+	return m.Called(ctx, permissionId, condition).Error(0)
+}
+func (m *MockPolicyManager) AddGroupUser(ctx context.Context, groupId int, userId string) error {
+	return m.Called(ctx, groupId, userId).Error(0)
+}
+func (m *MockPolicyManager) GrantPermission(ctx context.Context, groupId int, permissionId int) error {
+	return m.Called(ctx, groupId, permissionId).Error(0)
+}
+func (m *MockPolicyManager) DeleteGroup(ctx context.Context, groupId int) (*store.GroupDeletion, error) {
+	args := m.Called(ctx, groupId)
+	deletion, _ := args.Get(0).(*store.GroupDeletion)
+	return deletion, args.Error(1)
+}
+func (m *MockPolicyManager) ChangeGroupName(ctx context.Context, groupId int, newGroupName string) error {
+	return m.Called(ctx, groupId, newGroupName).Error(0)
+}
+func (m *MockPolicyManager) UpdateGroupMetadata(ctx context.Context, groupId int, patch store.MetadataPatch) (*store.GroupInfo[int], error) {
+	args := m.Called(ctx, groupId, patch)
+	group, _ := args.Get(0).(*store.GroupInfo[int])
+	return group, args.Error(1)
+}
+func (m *MockPolicyManager) UpdatePermissionMetadata(ctx context.Context, permissionId int, patch store.MetadataPatch) (*store.PermissionInfo[int], error) {
+	args := m.Called(ctx, permissionId, patch)
+	permission, _ := args.Get(0).(*store.PermissionInfo[int])
+	return permission, args.Error(1)
+}
+func (m *MockPolicyManager) DeleteUser(ctx context.Context, userId string) error {
+	return m.Called(ctx, userId).Error(0)
+}
+func (m *MockPolicyManager) ReadPolicy(ctx context.Context) (*authz.Policy, error) {
+	args := m.Called(ctx)
+	policy, _ := args.Get(0).(*authz.Policy)
+	return policy, args.Error(1)
+}
+func (m *MockPolicyManager) Export(ctx context.Context) (*store.PolicyDocument, error) {
+	args := m.Called(ctx)
+	document, _ := args.Get(0).(*store.PolicyDocument)
+	return document, args.Error(1)
+}
+func (m *MockPolicyManager) Import(ctx context.Context, document *store.PolicyDocument) error {
+	return m.Called(ctx, document).Error(0)
+}
+func (m *MockPolicyManager) ListGroups(ctx context.Context) ([]store.GroupInfo[int], error) {
+	args := m.Called(ctx)
+	groups, _ := args.Get(0).([]store.GroupInfo[int])
+	return groups, args.Error(1)
+}
+func (m *MockPolicyManager) ListPermissions(ctx context.Context) ([]store.PermissionInfo[int], error) {
+	args := m.Called(ctx)
+	permissions, _ := args.Get(0).([]store.PermissionInfo[int])
+	return permissions, args.Error(1)
+}
+func (m *MockPolicyManager) GetGroups(ctx context.Context, ids []int) ([]store.GroupInfo[int], error) {
+	args := m.Called(ctx, ids)
+	groups, _ := args.Get(0).([]store.GroupInfo[int])
+	return groups, args.Error(1)
+}
+func (m *MockPolicyManager) GetPermissions(ctx context.Context, ids []int) ([]store.PermissionInfo[int], error) {
+	args := m.Called(ctx, ids)
+	permissions, _ := args.Get(0).([]store.PermissionInfo[int])
+	return permissions, args.Error(1)
+}
+func (m *MockPolicyManager) ListGroupsPage(ctx context.Context, page paging.Request) ([]store.GroupInfo[int], string, error) {
+	args := m.Called(ctx, page)
+	groups, _ := args.Get(0).([]store.GroupInfo[int])
+	return groups, args.String(1), args.Error(2)
+}
+func (m *MockPolicyManager) ListPermissionsPage(ctx context.Context, page paging.Request) ([]store.PermissionInfo[int], string, error) {
+	args := m.Called(ctx, page)
+	permissions, _ := args.Get(0).([]store.PermissionInfo[int])
+	return permissions, args.String(1), args.Error(2)
+}
+func (m *MockPolicyManager) GetGroup(ctx context.Context, groupId int) (*store.GroupDetails[int, int, string], error) {
+	args := m.Called(ctx, groupId)
+	group, _ := args.Get(0).(*store.GroupDetails[int, int, string])
+	return group, args.Error(1)
+}
+func (m *MockPolicyManager) GetUserGroups(ctx context.Context, userId string) ([]store.GroupInfo[int], error) {
+	args := m.Called(ctx, userId)
+	groups, _ := args.Get(0).([]store.GroupInfo[int])
+	return groups, args.Error(1)
+}
+func (m *MockPolicyManager) Health(ctx context.Context) (*store.Health, error) {
+	args := m.Called(ctx)
+	health, _ := args.Get(0).(*store.Health)
+	return health, args.Error(1)
+}
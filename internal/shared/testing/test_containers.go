@@ -2,14 +2,68 @@ package testing
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 )
 
+const (
+	// PostgresVersionsEnv selects the major versions of PostgreSQL the integration tests run against,
+	// comma separated such as "15,17", or "all" for every supported version. The tests run against
+	// DefaultPostgresVersion when it is unset.
+	PostgresVersionsEnv = "AUTHZ_TEST_POSTGRES_VERSIONS"
+	// PostgresReuseEnv keeps the PostgreSQL containers running when set to "true", so the next test
+	// suites and runs reuse them, each in a fresh database of its own.
+	PostgresReuseEnv = "AUTHZ_TEST_POSTGRES_REUSE"
+	// DefaultPostgresVersion is the major version of PostgreSQL the integration tests run against by default.
+	DefaultPostgresVersion = "17"
+)
+
+// SupportedPostgresVersions lists the major versions of PostgreSQL the integration tests can run against.
+var SupportedPostgresVersions = []string{"14", "15", "16", "17"}
+
 type PostgresContainer struct {
 	*postgres.PostgresContainer
 	ConnectionString string
+	// Whether the container is shared with other test suites and runs, see PostgresReuseEnv.
+	Reused bool
+}
+
+// PostgresVersions returns the major versions of PostgreSQL selected by PostgresVersionsEnv.
+func PostgresVersions() ([]string, error) {
+	return ParsePostgresVersions(os.Getenv(PostgresVersionsEnv))
+}
+
+// ParsePostgresVersions parses a comma separated list of major versions of PostgreSQL, or "all".
+// An empty list selects DefaultPostgresVersion.
+func ParsePostgresVersions(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return []string{DefaultPostgresVersion}, nil
+	case "all":
+		return slices.Clone(SupportedPostgresVersions), nil
+	}
+
+	var versions []string
+	for _, version := range strings.Split(value, ",") {
+		version = strings.TrimSpace(version)
+		if !slices.Contains(SupportedPostgresVersions, version) {
+			return nil, fmt.Errorf("unsupported PostgreSQL version %q in $%s, expected one of %s or all",
+				version, PostgresVersionsEnv, strings.Join(SupportedPostgresVersions, ","))
+		}
+		if !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
 }
 
 // CreatePostgresContainer creates and starts a PostgreSQL container using the specified
@@ -27,12 +81,48 @@ type PostgresContainer struct {
 //     connection string.
 //   - error: An error if the container creation or connection string retrieval fails.
 func CreatePostgresContainer(ctx context.Context, dbName string, initScript string) (*PostgresContainer, error) {
-	pgContainer, err := postgres.Run(ctx, "postgres:17-alpine",
-		postgres.WithInitScripts(initScript),
-		postgres.WithDatabase(dbName),
-		postgres.WithUsername(uuid.NewString()),
-		postgres.WithPassword(uuid.NewString()),
+	return CreatePostgresContainerVersion(ctx, DefaultPostgresVersion, dbName, initScript)
+}
+
+// CreatePostgresContainerVersion is CreatePostgresContainer for the given major version of PostgreSQL.
+// When PostgresReuseEnv is set, the container of the version is started once and reused: the database
+// is then created under a unique name prefixed with dbName, and the initialization script run in it.
+func CreatePostgresContainerVersion(ctx context.Context, version string, dbName string, initScript string) (*PostgresContainer, error) {
+	image := "postgres:" + version + "-alpine"
+	if os.Getenv(PostgresReuseEnv) != "true" {
+		pgContainer, err := postgres.Run(ctx, image,
+			postgres.WithInitScripts(initScript),
+			postgres.WithDatabase(dbName),
+			postgres.WithUsername(uuid.NewString()),
+			postgres.WithPassword(uuid.NewString()),
+			postgres.BasicWaitStrategies(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		connStr, err := pgContainer.ConnectionString(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &PostgresContainer{
+			PostgresContainer: pgContainer,
+			ConnectionString:  connStr,
+		}, nil
+	}
+
+	// a reused container keeps the credentials it was created with, so they are fixed
+	pgContainer, err := postgres.Run(ctx, image,
+		postgres.WithDatabase("postgres"),
+		postgres.WithUsername("authz"),
+		postgres.WithPassword("authz"),
 		postgres.BasicWaitStrategies(),
+		testcontainers.CustomizeRequestOption(func(request *testcontainers.GenericContainerRequest) error {
+			request.Name = "authz-test-postgres-" + version
+			request.Reuse = true
+			return nil
+		}),
 	)
 	if err != nil {
 		return nil, err
@@ -42,9 +132,55 @@ func CreatePostgresContainer(ctx context.Context, dbName string, initScript stri
 	if err != nil {
 		return nil, err
 	}
+	connStr, err = createDatabase(ctx, connStr, dbName+"_"+strings.ReplaceAll(uuid.NewString(), "-", ""), initScript)
+	if err != nil {
+		return nil, err
+	}
 
 	return &PostgresContainer{
 		PostgresContainer: pgContainer,
 		ConnectionString:  connStr,
+		Reused:            true,
 	}, nil
 }
+
+// Release terminates the container, unless it is reused by other test suites and runs.
+func (container *PostgresContainer) Release(ctx context.Context) error {
+	if container.Reused {
+		return nil
+	}
+	return container.Terminate(ctx)
+}
+
+// createDatabase creates the database in the server of the connection string and runs the
+// initialization script in it, returning the connection string of the new database.
+func createDatabase(ctx context.Context, connStr string, dbName string, initScript string) (string, error) {
+	script, err := os.ReadFile(initScript)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{dbName}.Sanitize()); err != nil {
+		return "", fmt.Errorf("create database %s: %w", dbName, err)
+	}
+
+	parsed, err := url.Parse(connStr)
+	if err != nil {
+		return "", err
+	}
+	parsed.Path = "/" + dbName
+	dbConn, err := pgx.Connect(ctx, parsed.String())
+	if err != nil {
+		return "", err
+	}
+	defer dbConn.Close(ctx)
+	if _, err := dbConn.Exec(ctx, string(script)); err != nil {
+		return "", fmt.Errorf("run %s in database %s: %w", initScript, dbName, err)
+	}
+	return parsed.String(), nil
+}
@@ -0,0 +1,24 @@
+package testing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePostgresVersions(t *testing.T) {
+	versions, err := ParsePostgresVersions("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultPostgresVersion}, versions)
+
+	versions, err = ParsePostgresVersions("all")
+	assert.NoError(t, err)
+	assert.Equal(t, SupportedPostgresVersions, versions)
+
+	versions, err = ParsePostgresVersions(" 15, 17,15 ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"15", "17"}, versions)
+
+	_, err = ParsePostgresVersions("12,17")
+	assert.EqualError(t, err, `unsupported PostgreSQL version "12" in $AUTHZ_TEST_POSTGRES_VERSIONS, expected one of 14,15,16,17 or all`)
+}
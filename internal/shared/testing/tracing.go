@@ -0,0 +1,131 @@
+package testing
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// SpanRecorder is a trace.TracerProvider recording the spans its tracers start, so tests can
+// check the instrumentation of the code under test without an OpenTelemetry SDK.
+type SpanRecorder struct {
+	embedded.TracerProvider
+
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+var _ trace.TracerProvider = (*SpanRecorder)(nil)
+
+// RecordedSpan is a span started by a tracer of a SpanRecorder.
+type RecordedSpan struct {
+	noop.Span
+
+	recorder   *SpanRecorder
+	Name       string
+	Kind       trace.SpanKind
+	Parent     *RecordedSpan
+	Attributes map[attribute.Key]attribute.Value
+	Errors     []error
+	Status     codes.Code
+	Ended      bool
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+
+	recorder *SpanRecorder
+}
+
+// NewSpanRecorder creates a new SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Tracer returns a tracer recording its spans in the recorder.
+func (recorder *SpanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: recorder}
+}
+
+// Spans returns the spans started so far, in order.
+func (recorder *SpanRecorder) Spans() []*RecordedSpan {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]*RecordedSpan(nil), recorder.spans...)
+}
+
+// Span returns the first span started with the name, or nil.
+func (recorder *SpanRecorder) Span(name string) *RecordedSpan {
+	for _, span := range recorder.Spans() {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (tracer recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &RecordedSpan{
+		recorder:   tracer.recorder,
+		Name:       name,
+		Kind:       config.SpanKind(),
+		Attributes: map[attribute.Key]attribute.Value{},
+	}
+	if parent, ok := trace.SpanFromContext(ctx).(*RecordedSpan); ok {
+		span.Parent = parent
+	}
+	span.SetAttributes(config.Attributes()...)
+
+	tracer.recorder.mu.Lock()
+	tracer.recorder.spans = append(tracer.recorder.spans, span)
+	tracer.recorder.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// IsRecording reports whether the span is not ended yet.
+func (span *RecordedSpan) IsRecording() bool {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	return !span.Ended
+}
+
+// SetAttributes records the attributes, replacing the values of the keys set before.
+func (span *RecordedSpan) SetAttributes(attributes ...attribute.KeyValue) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	for _, attr := range attributes {
+		span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError records the error.
+func (span *RecordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	span.Errors = append(span.Errors, err)
+}
+
+// SetStatus records the status code.
+func (span *RecordedSpan) SetStatus(code codes.Code, _ string) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	span.Status = code
+}
+
+// End marks the span ended.
+func (span *RecordedSpan) End(...trace.SpanEndOption) {
+	span.recorder.mu.Lock()
+	defer span.recorder.mu.Unlock()
+	span.Ended = true
+}
+
+// TracerProvider returns the recorder.
+func (span *RecordedSpan) TracerProvider() trace.TracerProvider {
+	return span.recorder
+}
@@ -1,23 +1,40 @@
+-- Create table for Schema Version, holding a single row with the version of this schema.
+-- Bump the version whenever the schema changes and update the supported range in the binary.
+CREATE TABLE IF Not EXISTS schema_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version INT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO schema_version (version) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
 -- Create table for Permission
 CREATE TABLE IF Not EXISTS permissions (
     id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     name VARCHAR(255) NOT NULL UNIQUE,
-    version INT
+    version INT,
+    risk VARCHAR(16) NOT NULL DEFAULT 'low' CHECK (risk IN ('low', 'medium', 'high')),
+    description TEXT NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}',
+    condition TEXT NOT NULL DEFAULT ''
 );
 
 -- Create table for Group
 CREATE TABLE IF Not EXISTS groups (
     id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     name VARCHAR(255) NOT NULL UNIQUE,
-    version INT
+    version INT,
+    description TEXT NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}'
 );
 
 -- Create table for Subject
 CREATE TABLE IF Not EXISTS subjects (
     id VARCHAR(255),
     group_id INT,
+    source VARCHAR(32) NOT NULL DEFAULT 'manual',
     PRIMARY KEY (id, group_id),
-    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+    FOREIGN KEY (group_id) REFERENCES groups(id)
 );
 
 -- Create table for Group Permission
@@ -25,7 +42,403 @@ CREATE TABLE IF Not EXISTS group_permissions (
     group_id INT,
     permission_id INT,
     PRIMARY KEY (group_id, permission_id),
+    FOREIGN KEY (group_id) REFERENCES groups(id),
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+-- Create table for Permission Implication, granting the implied permission along with the implying one
+CREATE TABLE IF Not EXISTS permission_implications (
+    permission_id INT,
+    implied_id INT,
+    PRIMARY KEY (permission_id, implied_id),
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (implied_id) REFERENCES permissions(id) ON DELETE CASCADE,
+    CHECK (permission_id <> implied_id)
+);
+
+-- Create table for Group Tombstone, recording deleted groups
+CREATE TABLE IF Not EXISTS group_tombstones (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    group_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version INT,
+    members INT NOT NULL,
+    grants INT NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table for Approval Request
+CREATE TABLE IF Not EXISTS approval_requests (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    group_id INT,
+    permission_id INT,
+    user_id VARCHAR(255),
+    requested_by VARCHAR(255) NOT NULL,
+    justification TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL,
+    decided_by VARCHAR(255),
+    decided_at TIMESTAMPTZ,
+    approvers TEXT[] NOT NULL DEFAULT '{}',
+    escalated_at TIMESTAMPTZ,
     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
     FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
 );
 
+CREATE INDEX IF NOT EXISTS approval_requests_requested_by ON approval_requests (requested_by);
+
+-- Create table for Sync Report, recording what each synchronization run changed
+CREATE TABLE IF Not EXISTS sync_reports (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    connector VARCHAR(64) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    submitted_by VARCHAR(255) NOT NULL,
+    added INT NOT NULL,
+    removed INT NOT NULL,
+    skipped INT NOT NULL,
+    errors INT NOT NULL,
+    entry_list JSONB NOT NULL,
+    error_list JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sync_reports_finished_at ON sync_reports (finished_at);
+
+-- Create table for Permission Catalog, recording the permissions each application declares
+CREATE TABLE IF Not EXISTS permission_catalogs (
+    application VARCHAR(255) PRIMARY KEY,
+    entries JSONB NOT NULL,
+    registered_by VARCHAR(255) NOT NULL,
+    registered_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create table for Self-Service Group, recording the groups end users may request to join
+CREATE TABLE IF Not EXISTS self_service_groups (
+    group_id INT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled_by VARCHAR(255) NOT NULL,
+    enabled_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Create table for Directory User, recording the attributes synchronized from the user registry
+CREATE TABLE IF Not EXISTS directory_users (
+    id VARCHAR(255) PRIMARY KEY,
+    manager VARCHAR(255),
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create table for Group Owner, recording who approves the requests to join each group
+CREATE TABLE IF Not EXISTS group_owners (
+    group_id INT,
+    user_id VARCHAR(255),
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Create table for Folder, organizing groups and permission catalogs in a hierarchy
+CREATE TABLE IF Not EXISTS folders (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id INT,
+    labels JSONB NOT NULL DEFAULT '{}',
+    admins TEXT[] NOT NULL DEFAULT '{}',
+    FOREIGN KEY (parent_id) REFERENCES folders(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS folders_parent_name ON folders (COALESCE(parent_id, 0), name);
+
+-- Create table for Folder Group, recording the folder holding each group
+CREATE TABLE IF Not EXISTS folder_groups (
+    group_id INT PRIMARY KEY,
+    folder_id INT NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders(id)
+);
+
+-- Create table for Folder Catalog, recording the folder holding each permission catalog
+CREATE TABLE IF Not EXISTS folder_catalogs (
+    application VARCHAR(255) PRIMARY KEY,
+    folder_id INT NOT NULL,
+    FOREIGN KEY (application) REFERENCES permission_catalogs(application) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders(id)
+);
+
+-- Create table for Folder Permission, recording the permissions granted to the groups of each folder
+CREATE TABLE IF Not EXISTS folder_permissions (
+    folder_id INT,
+    permission_id INT,
+    PRIMARY KEY (folder_id, permission_id),
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+-- Create table for Permission Denial, explicitly denying permissions to groups or to single users,
+-- overriding the grants. Every row denies the permission to exactly one group or one user.
+CREATE TABLE IF Not EXISTS permission_denials (
+    id SERIAL PRIMARY KEY,
+    permission_id INT NOT NULL,
+    group_id INT,
+    user_id VARCHAR(255),
+    CHECK ((group_id IS NULL) <> (user_id IS NULL)),
+    FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS permission_denials_group ON permission_denials (permission_id, group_id) WHERE group_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS permission_denials_user ON permission_denials (permission_id, user_id) WHERE user_id IS NOT NULL;
+
+-- Create table for Relationship Tuple, relating objects to users or to the usersets of other objects
+CREATE TABLE IF Not EXISTS relationship_tuples (
+    object_type VARCHAR(64) NOT NULL,
+    object_id VARCHAR(255) NOT NULL,
+    relation VARCHAR(64) NOT NULL,
+    subject_type VARCHAR(64) NOT NULL,
+    subject_id VARCHAR(255) NOT NULL,
+    subject_relation VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (object_type, object_id, relation, subject_type, subject_id, subject_relation)
+);
+
+CREATE INDEX IF NOT EXISTS relationship_tuples_subject ON relationship_tuples (subject_type, subject_id, subject_relation);
+
+-- Create table for Self-Service Link, recording the shareable links asking to request access to a group
+CREATE TABLE IF Not EXISTS self_service_links (
+    id VARCHAR(64) PRIMARY KEY,
+    group_id INT NOT NULL,
+    justification TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_by VARCHAR(255),
+    revoked_at TIMESTAMPTZ,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS self_service_links_group_id ON self_service_links (group_id);
+
+-- Create table for Decision Log, recording the permission decisions made by the service
+CREATE TABLE IF Not EXISTS decision_logs (
+    id UUID PRIMARY KEY,
+    decided_at TIMESTAMPTZ NOT NULL,
+    caller VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    permission VARCHAR(255) NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    mode VARCHAR(32) NOT NULL,
+    policy_version VARCHAR(64) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS decision_logs_user_decided_at ON decision_logs (user_id, decided_at);
+
+-- Create table for Audit Event, recording the policy changes and the other audited actions
+CREATE TABLE IF Not EXISTS audit_events (
+    id UUID PRIMARY KEY,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_subject_recorded_at ON audit_events (subject, recorded_at);
+CREATE INDEX IF NOT EXISTS audit_events_recorded_at ON audit_events (recorded_at, id);
+
+-- Create table for Policy Revision, holding a single row counting the changes to the policy,
+-- bumped by the triggers below so caches can tell whether the policy changed with a cheap query.
+-- Every bump is also notified on the policy_changed channel with the new revision as payload,
+-- delivered to the listeners once the changing transaction commits. The changes of a tenant, whose
+-- tables are in the tenant_<id> schema, are notified on the policy_changed_tenant_<id> channel.
+-- The trigger functions run with the search path they were created with, so they change the tables
+-- of their own schema whatever the search path of the changing session.
+CREATE TABLE IF Not EXISTS policy_revision (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    revision BIGINT NOT NULL
+);
+
+INSERT INTO policy_revision (revision) VALUES (0) ON CONFLICT (id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION bump_policy_revision() RETURNS TRIGGER AS $$
+DECLARE
+    bumped BIGINT;
+BEGIN
+    UPDATE policy_revision SET revision = revision + 1 RETURNING revision INTO bumped;
+    PERFORM pg_notify(CASE WHEN TG_TABLE_SCHEMA = 'public' THEN 'policy_changed' ELSE 'policy_changed_' || TG_TABLE_SCHEMA END, bumped::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SET search_path FROM CURRENT;
+
+CREATE OR REPLACE TRIGGER groups_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON groups
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER permissions_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON permissions
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER subjects_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON subjects
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER group_permissions_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON group_permissions
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER permission_implications_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON permission_implications
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER folders_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON folders
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER folder_groups_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON folder_groups
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER folder_permissions_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON folder_permissions
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+CREATE OR REPLACE TRIGGER permission_denials_policy_revision AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON permission_denials
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_policy_revision();
+
+-- Create table for Policy Change, logging every group membership and permission grant added or removed,
+-- and every group and permission renamed, so watchers can stream them. The triggers lock the policy
+-- revision before logging, which orders the changes of concurrent transactions by commit, and record the
+-- revision the change is part of: the one the statement bumps the revision to once its rows are logged.
+-- The changes identify the group and the permission by id, their names are the ones at the time of the
+-- change, and renames record the previous name. The changes are pruned past the retention of serve.
+CREATE TABLE IF Not EXISTS policy_changes (
+    id BIGSERIAL PRIMARY KEY,
+    revision BIGINT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    group_id INT,
+    group_name VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    permission_id INT,
+    permission_name VARCHAR(255) NOT NULL DEFAULT '',
+    previous_name VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS policy_changes_revision ON policy_changes (revision);
+CREATE INDEX IF NOT EXISTS policy_changes_changed_at ON policy_changes (changed_at);
+
+CREATE OR REPLACE FUNCTION log_policy_change() RETURNS TRIGGER AS $$
+DECLARE
+    next_revision BIGINT;
+    changed RECORD;
+    change_kind VARCHAR(32);
+BEGIN
+    SELECT revision + 1 INTO next_revision FROM policy_revision FOR UPDATE;
+    IF TG_OP = 'UPDATE' THEN
+        IF TG_TABLE_NAME = 'groups' THEN
+            INSERT INTO policy_changes (revision, kind, group_id, group_name, previous_name)
+            VALUES (next_revision, 'group_renamed', NEW.id, NEW.name, OLD.name);
+        ELSE
+            INSERT INTO policy_changes (revision, kind, group_name, permission_id, permission_name, previous_name)
+            VALUES (next_revision, 'permission_renamed', '', NEW.id, NEW.name, OLD.name);
+        END IF;
+        RETURN NULL;
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        change_kind := TG_ARGV[0] || '_added';
+    ELSE
+        changed := OLD;
+        change_kind := TG_ARGV[0] || '_removed';
+    END IF;
+
+    IF TG_TABLE_NAME = 'subjects' THEN
+        INSERT INTO policy_changes (revision, kind, group_id, group_name, user_id)
+        SELECT next_revision, change_kind, g.id, g.name, changed.id FROM groups g WHERE g.id = changed.group_id;
+    ELSE
+        INSERT INTO policy_changes (revision, kind, group_id, group_name, permission_id, permission_name)
+        SELECT next_revision, change_kind, g.id, g.name, p.id, p.name FROM groups g, permissions p
+        WHERE g.id = changed.group_id AND p.id = changed.permission_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql SET search_path FROM CURRENT;
+
+CREATE OR REPLACE TRIGGER subjects_policy_changes AFTER INSERT OR DELETE ON subjects
+    FOR EACH ROW EXECUTE FUNCTION log_policy_change('member');
+CREATE OR REPLACE TRIGGER group_permissions_policy_changes AFTER INSERT OR DELETE ON group_permissions
+    FOR EACH ROW EXECUTE FUNCTION log_policy_change('grant');
+CREATE OR REPLACE TRIGGER groups_policy_changes AFTER UPDATE OF name ON groups
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION log_policy_change('group');
+CREATE OR REPLACE TRIGGER permissions_policy_changes AFTER UPDATE OF name ON permissions
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION log_policy_change('permission');
+
+-- Version 1: rate the risk of permissions, for the databases created before the version was stamped
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS risk VARCHAR(16) NOT NULL DEFAULT 'low' CHECK (risk IN ('low', 'medium', 'high'));
+
+-- Version 2: record the source of every group membership, and request approvals without a group
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS source VARCHAR(32) NOT NULL DEFAULT 'manual';
+ALTER TABLE approval_requests ALTER COLUMN group_id DROP NOT NULL;
+UPDATE schema_version SET version = 2, applied_at = now() WHERE version < 2;
+
+-- Version 3: record sync reports
+UPDATE schema_version SET version = 3, applied_at = now() WHERE version < 3;
+
+-- Version 4: record permission implications
+UPDATE schema_version SET version = 4, applied_at = now() WHERE version < 4;
+
+-- Version 5: record permission catalogs
+UPDATE schema_version SET version = 5, applied_at = now() WHERE version < 5;
+
+-- Version 6: record self-service groups
+UPDATE schema_version SET version = 6, applied_at = now() WHERE version < 6;
+
+-- Version 7: route approval requests to managers and group owners
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS approvers TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE approval_requests ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMPTZ;
+UPDATE schema_version SET version = 7, applied_at = now() WHERE version < 7;
+
+-- Version 8: describe and label groups and permissions
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+UPDATE schema_version SET version = 8, applied_at = now() WHERE version < 8;
+
+-- Version 9: record decision logs
+UPDATE schema_version SET version = 9, applied_at = now() WHERE version < 9;
+
+-- Version 10: count the changes to the policy
+UPDATE schema_version SET version = 10, applied_at = now() WHERE version < 10;
+
+-- Version 11: notify the changes to the policy
+UPDATE schema_version SET version = 11, applied_at = now() WHERE version < 11;
+
+-- Version 12: count the aggregated decision logs
+ALTER TABLE decision_logs ADD COLUMN IF NOT EXISTS count BIGINT NOT NULL DEFAULT 1;
+UPDATE schema_version SET version = 12, applied_at = now() WHERE version < 12;
+
+-- Version 13: record self-service request links
+UPDATE schema_version SET version = 13, applied_at = now() WHERE version < 13;
+
+-- Version 14: organize groups and permission catalogs in folders
+UPDATE schema_version SET version = 14, applied_at = now() WHERE version < 14;
+
+-- Version 15: grant permissions to the groups of folders and control their inheritance
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS inheritance VARCHAR(16) NOT NULL DEFAULT '';
+UPDATE schema_version SET version = 15, applied_at = now() WHERE version < 15;
+
+-- Version 16: record relationship tuples for object-level sharing
+UPDATE schema_version SET version = 16, applied_at = now() WHERE version < 16;
+
+-- Version 17: log the membership and grant changes for watchers
+UPDATE schema_version SET version = 17, applied_at = now() WHERE version < 17;
+
+-- Version 18: record the audit events in Postgres
+UPDATE schema_version SET version = 18, applied_at = now() WHERE version < 18;
+
+-- Version 19: explicitly deny permissions to groups and users
+UPDATE schema_version SET version = 19, applied_at = now() WHERE version < 19;
+
+-- Version 20: isolate the tenants in schemas of their own
+UPDATE schema_version SET version = 20, applied_at = now() WHERE version < 20;
+
+-- Version 21: make the grants of permissions conditional on the attributes of requests
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
+UPDATE schema_version SET version = 21, applied_at = now() WHERE version < 21;
+
+-- Version 22: identify the groups and permissions of the logged changes by id and log their renames
+ALTER TABLE policy_changes ADD COLUMN IF NOT EXISTS group_id INT;
+ALTER TABLE policy_changes ADD COLUMN IF NOT EXISTS permission_id INT;
+ALTER TABLE policy_changes ADD COLUMN IF NOT EXISTS previous_name VARCHAR(255) NOT NULL DEFAULT '';
+-- the changes logged before are matched by their names, once
+UPDATE policy_changes c SET group_id = g.id FROM groups g
+WHERE c.group_id IS NULL AND g.name = c.group_name AND EXISTS (SELECT 1 FROM schema_version WHERE version < 22);
+UPDATE policy_changes c SET permission_id = p.id FROM permissions p
+WHERE c.permission_id IS NULL AND p.name = c.permission_name AND EXISTS (SELECT 1 FROM schema_version WHERE version < 22);
+CREATE INDEX IF NOT EXISTS policy_changes_group_id ON policy_changes (group_id);
+UPDATE schema_version SET version = 22, applied_at = now() WHERE version < 22;